@@ -34,6 +34,11 @@ type FAQEntry struct {
 	// MatchedQuestion is the actual question text that was matched in FAQ search
 	// Could be the standard question or one of the similar questions
 	MatchedQuestion string `json:"matched_question,omitempty"`
+	// ContentFormat tells how to render the answers: plain, markdown or html
+	ContentFormat string `json:"content_format"`
+	// UnindexedSimilarQuestions is the number of similar questions stored but not indexed
+	// because of the knowledge base's max_indexed_similar_questions limit
+	UnindexedSimilarQuestions int `json:"unindexed_similar_questions,omitempty"`
 }
 
 // FAQEntryPayload is used to create or update a FAQ entry.
@@ -49,6 +54,8 @@ type FAQEntryPayload struct {
 	TagName           string   `json:"tag_name,omitempty"`
 	IsEnabled         *bool    `json:"is_enabled,omitempty"`
 	IsRecommended     *bool    `json:"is_recommended,omitempty"`
+	// ContentFormat is the answer format (plain/markdown/html); empty uses the knowledge base default
+	ContentFormat *string `json:"content_format,omitempty"`
 }
 
 // FAQBatchUpsertPayload represents the request body for batch import (append/replace).
@@ -99,6 +106,36 @@ type FAQSearchRequest struct {
 	FirstPriorityTagIDs  []int64 `json:"first_priority_tag_ids"`  // First priority tag seq_ids, highest priority
 	SecondPriorityTagIDs []int64 `json:"second_priority_tag_ids"` // Second priority tag seq_ids, lower than first
 	OnlyRecommended      bool    `json:"only_recommended"`        // Only return recommended entries
+	// ResponseMode is "entries" (default) or "answers"; use SearchFAQAnswers for the answers mode
+	ResponseMode string `json:"response_mode,omitempty"`
+	// AnswerSeed makes random answer selection reproducible in the answers mode
+	AnswerSeed *int64 `json:"answer_seed,omitempty"`
+}
+
+// FAQAnswerMatch is the answer(s) to show for one matched FAQ entry, with its match score.
+type FAQAnswerMatch struct {
+	EntryID          int64    `json:"entry_id"`
+	StandardQuestion string   `json:"standard_question"`
+	MatchedQuestion  string   `json:"matched_question,omitempty"`
+	TagID            int64    `json:"tag_id"`
+	TagName          string   `json:"tag_name,omitempty"`
+	Answers          []string `json:"answers"`
+	Score            float64  `json:"score"`
+	MatchType        string   `json:"match_type,omitempty"`
+	ContentFormat    string   `json:"content_format"`
+}
+
+// FAQAnswerSearchResponse wraps the FAQ search results in the answers mode.
+type FAQAnswerSearchResponse struct {
+	Success bool             `json:"success"`
+	Data    []FAQAnswerMatch `json:"data"`
+	Partial bool             `json:"partial"`
+	// NoMatch is set when nothing cleared the threshold and the knowledge base configures a no-match fallback
+	NoMatch bool `json:"no_match,omitempty"`
+	// Fallback is the configured fallback entry's answer, returned together with NoMatch
+	Fallback *FAQAnswerMatch `json:"fallback,omitempty"`
+	Message  string          `json:"message,omitempty"`
+	Code     string          `json:"code,omitempty"`
 }
 
 // FAQEntriesPage contains paginated FAQ results.
@@ -134,8 +171,13 @@ type FAQTaskPayload struct {
 type FAQSearchResponse struct {
 	Success bool       `json:"success"`
 	Data    []FAQEntry `json:"data"`
-	Message string     `json:"message,omitempty"`
-	Code    string     `json:"code,omitempty"`
+	Partial bool       `json:"partial"`
+	// NoMatch is set when nothing cleared the threshold and the knowledge base configures a no-match fallback
+	NoMatch bool `json:"no_match,omitempty"`
+	// Fallback is the configured fallback entry, returned together with NoMatch
+	Fallback *FAQEntry `json:"fallback,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	Code     string    `json:"code,omitempty"`
 }
 
 // FAQEntryResponse wraps the single FAQ entry creation response.
@@ -249,6 +291,25 @@ func (c *Client) GetFAQEntry(ctx context.Context,
 	return response.Data, nil
 }
 
+// GetFAQEntryByStandardQuestion retrieves a single FAQ entry by its standard question.
+func (c *Client) GetFAQEntryByStandardQuestion(ctx context.Context,
+	knowledgeBaseID string, question string,
+) (*FAQEntry, error) {
+	path := fmt.Sprintf("/api/v1/knowledge-bases/%s/faq/entries/by-question", knowledgeBaseID)
+	query := url.Values{}
+	query.Add("standard_question", question)
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var response FAQEntryResponse
+	if err := parseResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
 // UpdateFAQEntry updates a single FAQ entry.
 func (c *Client) UpdateFAQEntry(ctx context.Context,
 	knowledgeBaseID string, entrySeqID int64, payload *FAQEntryPayload,
@@ -340,8 +401,10 @@ func (c *Client) DeleteFAQEntries(ctx context.Context,
 func (c *Client) SearchFAQEntries(ctx context.Context,
 	knowledgeBaseID string, payload *FAQSearchRequest,
 ) ([]FAQEntry, error) {
+	req := *payload
+	req.ResponseMode = ""
 	path := fmt.Sprintf("/api/v1/knowledge-bases/%s/faq/search", knowledgeBaseID)
-	resp, err := c.doRequest(ctx, http.MethodPost, path, payload, nil)
+	resp, err := c.doRequest(ctx, http.MethodPost, path, &req, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -354,20 +417,49 @@ func (c *Client) SearchFAQEntries(ctx context.Context,
 	return response.Data, nil
 }
 
+// SearchFAQAnswers performs hybrid FAQ search and returns, per matched entry, the answers
+// selected by the entry's answer strategy together with the match score.
+func (c *Client) SearchFAQAnswers(ctx context.Context,
+	knowledgeBaseID string, payload *FAQSearchRequest,
+) ([]FAQAnswerMatch, error) {
+	req := *payload
+	req.ResponseMode = "answers"
+	path := fmt.Sprintf("/api/v1/knowledge-bases/%s/faq/search", knowledgeBaseID)
+	resp, err := c.doRequest(ctx, http.MethodPost, path, &req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response FAQAnswerSearchResponse
+	if err := parseResponse(resp, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
 // ExportFAQEntries exports all FAQ entries from a knowledge base as CSV data.
-// The CSV format matches the import example format with 8 columns:
+// The CSV format matches the import example format with 9 columns:
 // 分类(必填), 问题(必填), 相似问题(选填-多个用##分隔), 反例问题(选填-多个用##分隔),
 // 机器人回答(必填-多个用##分隔), 是否全部回复(选填-默认FALSE), 是否停用(选填-默认FALSE),
-// 是否禁止被推荐(选填-默认False 可被推荐)
+// 是否禁止被推荐(选填-默认False 可被推荐), 答案格式(选填-plain/markdown/html)
 func (c *Client) ExportFAQEntries(ctx context.Context, knowledgeBaseID string) ([]byte, error) {
+	return c.ExportFAQEntriesAs(ctx, knowledgeBaseID, "csv")
+}
+
+// ExportFAQEntriesAs exports all FAQ entries from a knowledge base in the given format:
+// "csv" (with a UTF-8 BOM), "json" (an array of FAQEntry) or "xlsx" (same columns as the CSV).
+func (c *Client) ExportFAQEntriesAs(ctx context.Context, knowledgeBaseID string, format string) ([]byte, error) {
 	path := fmt.Sprintf("/api/v1/knowledge-bases/%s/faq/entries/export", knowledgeBaseID)
-	resp, err := c.doRequest(ctx, http.MethodGet, path, nil, nil)
+	query := url.Values{}
+	query.Add("format", format)
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil, query)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	// Read the raw CSV data from response body
+	// Read the raw export data from response body
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read export response: %w", err)
@@ -396,6 +488,8 @@ type FAQSuccessEntry struct {
 	TagID            int64  `json:"tag_id,omitempty"`   // Tag ID (seq_id)
 	TagName          string `json:"tag_name,omitempty"` // Tag name
 	StandardQuestion string `json:"standard_question"`  // Standard question
+	// UnindexedSimilarQuestions is the number of similar questions stored but not indexed
+	UnindexedSimilarQuestions int `json:"unindexed_similar_questions,omitempty"`
 }
 
 // FAQImportProgress represents the progress of an async FAQ import task.
@@ -425,6 +519,9 @@ type FAQImportProgress struct {
 	ImportedAt     time.Time `json:"imported_at,omitempty"`
 	DisplayStatus  string    `json:"display_status,omitempty"`
 	ProcessingTime int64     `json:"processing_time,omitempty"`
+
+	// UnindexedSimilarQuestions is the total number of similar questions stored but not indexed
+	UnindexedSimilarQuestions int `json:"unindexed_similar_questions,omitempty"`
 }
 
 // FAQImportProgressResponse wraps the FAQ import progress response.
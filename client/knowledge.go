@@ -204,6 +204,8 @@ type CreateKnowledgeFromURLRequest struct {
 	Title string `json:"title,omitempty"`
 	// TagID is the optional tag ID to associate with the knowledge entry
 	TagID string `json:"tag_id,omitempty"`
+	// ChunkingPreset is the optional tenant chunking preset overriding the knowledge base chunking config
+	ChunkingPreset string `json:"chunking_preset,omitempty"`
 }
 
 // CreateKnowledgeFromURL creates a knowledge entry from a URL.
@@ -405,6 +407,36 @@ func (c *Client) ReparseKnowledge(ctx context.Context, knowledgeID string) (*Kno
 	return &response.Data, nil
 }
 
+// CancelKnowledgeProcessing cancels the parsing of a pending or processing knowledge entry
+// The chunks created so far are removed and the knowledge is kept with status "cancelled",
+// it can be parsed again later with ReparseKnowledge.
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - knowledgeID: The ID of the knowledge entry to cancel
+//
+// Returns:
+//   - *Knowledge: The updated knowledge entry with status set to "cancelled"
+//   - error: Error information if the request fails
+func (c *Client) CancelKnowledgeProcessing(ctx context.Context, knowledgeID string) (*Knowledge, error) {
+	if knowledgeID == "" {
+		return nil, fmt.Errorf("knowledge ID cannot be empty")
+	}
+
+	path := fmt.Sprintf("/api/v1/knowledge/%s/cancel", knowledgeID)
+	resp, err := c.doRequest(ctx, http.MethodPost, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response KnowledgeResponse
+	if err := parseResponse(resp, &response); err != nil {
+		return nil, err
+	}
+
+	return &response.Data, nil
+}
+
 // UpdateChunk updates a chunk's information
 // Updates information for a specific chunk under a knowledge document
 // Parameters:
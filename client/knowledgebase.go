@@ -27,6 +27,7 @@ type KnowledgeBase struct {
 	VLMConfig             VLMConfig             `json:"vlm_config"`
 	StorageConfig         StorageConfig         `json:"cos_config"`
 	ExtractConfig         *ExtractConfig        `json:"extract_config"`
+	ChunkingPreset        string                `json:"chunking_preset,omitempty"` // Tenant chunking preset applied on creation
 	CreatedAt             time.Time             `json:"created_at"`
 	UpdatedAt             time.Time             `json:"updated_at"`
 	// Computed fields (not stored in database)
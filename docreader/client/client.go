@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"github.com/Tencent/WeKnora/docreader/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/resolver"
 )
 
@@ -81,6 +83,19 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
+// Ping checks whether the DocReader service is reachable and serving,
+// using the standard gRPC health service registered by the server.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := grpc_health_v1.NewHealthClient(c.conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("docreader status: %s", resp.GetStatus())
+	}
+	return nil
+}
+
 // SetDebug enables or disables debug logging
 func (c *Client) SetDebug(debug bool) {
 	c.debug = debug
@@ -83,10 +83,13 @@ def create_chunking_config(read_config: ReadConfig):
         list(read_config.separators) if read_config.separators else ["\n\n", "\n", "。"]
     )
     enable_multimodal = read_config.enable_multimodal or False
+    parse_timeout_seconds = max(read_config.parse_timeout_seconds, 0)
+    allow_partial_result = read_config.allow_partial_result
 
     logger.info(
         f"Using chunking config: size={chunk_size}, "
-        f"overlap={chunk_overlap}, multimodal={enable_multimodal}"
+        f"overlap={chunk_overlap}, multimodal={enable_multimodal}, "
+        f"timeout={parse_timeout_seconds}s, partial={allow_partial_result}"
     )
 
     # Extract storage config
@@ -127,6 +130,8 @@ def create_chunking_config(read_config: ReadConfig):
         enable_multimodal=enable_multimodal,
         storage_config=storage_config,
         vlm_config=vlm_config,
+        parse_timeout_seconds=parse_timeout_seconds,
+        allow_partial_result=allow_partial_result,
     )
 
 
@@ -188,7 +193,8 @@ class DocReaderServicer(docreader_pb2_grpc.DocReaderServicer):
                 response = ReadResponse(
                     chunks=[
                         self._convert_chunk_to_proto(chunk) for chunk in result.chunks
-                    ]
+                    ],
+                    partial=bool(result.metadata.get("partial")),
                 )
                 logger.info(f"Response size: {response.ByteSize()} bytes")
                 return response
@@ -304,7 +310,8 @@ class DocReaderServicer(docreader_pb2_grpc.DocReaderServicer):
                 response = ReadResponse(
                     chunks=[
                         self._convert_chunk_to_proto(chunk) for chunk in result.chunks
-                    ]
+                    ],
+                    partial=bool(result.metadata.get("partial")),
                 )
                 logger.info(f"Response size: {response.ByteSize()} bytes")
                 return response
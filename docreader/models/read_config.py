@@ -25,3 +25,9 @@ class ChunkingConfig:
 
     # VLM configuration for image captioning
     vlm_config: dict[str, str] = field(default_factory=dict)
+
+    # Parse timeout in seconds, 0 means no limit
+    parse_timeout_seconds: int = 0
+
+    # Whether to return the partial result instead of an error on timeout
+    allow_partial_result: bool = False
@@ -3,6 +3,8 @@ Parser module for WeKnora document processing system.
 
 This module provides document parsers for various file formats including:
 - Microsoft Word documents (.doc, .docx)
+- Microsoft PowerPoint presentations (.ppt, .pptx)
+- EPUB e-books
 - PDF documents
 - Markdown files
 - Plain text files
@@ -21,6 +23,7 @@ from .image_parser import ImageParser
 from .markdown_parser import MarkdownParser
 from .parser import Parser
 from .pdf_parser import PDFParser
+from .ppt_parser import PptParser
 from .text_parser import TextParser
 from .web_parser import WebParser
 
@@ -29,6 +32,7 @@ __all__ = [
     "Docx2Parser",  # Parser for .docx files (modern Word documents)
     "DocParser",  # Parser for .doc files (legacy Word documents)
     "PDFParser",  # Parser for PDF documents
+    "PptParser",  # Parser for .ppt files (legacy PowerPoint presentations)
     "MarkdownParser",  # Parser for Markdown text files
     "TextParser",  # Parser for plain text files
     "ImageParser",  # Parser for images with text content
@@ -163,6 +163,8 @@ class BaseParser(ABC):
         self.max_concurrent_tasks = max_concurrent_tasks
         self.max_chunks = max_chunks
         self.chunking_config = chunking_config
+        # Set when image processing is cut short by the parse timeout
+        self.timed_out = False
         self.storage = create_storage(
             self.chunking_config.storage_config if self.chunking_config else None
         )
@@ -395,6 +397,12 @@ class BaseParser(ABC):
         logger.info(
             f"Parsing document with {self.__class__.__name__}, bytes: {len(content)}"
         )
+        # Image processing stops at the deadline, the text is always kept whole
+        timeout = (
+            self.chunking_config.parse_timeout_seconds if self.chunking_config else 0
+        )
+        deadline = time.monotonic() + timeout if timeout > 0 else None
+        self.timed_out = False
         document = self.parse_into_text(content)
 
         logger.info(
@@ -450,12 +458,23 @@ class BaseParser(ABC):
                 logger.info(
                     f"Processing images in each chunk for file type: {file_ext}"
                 )
-                chunks = self.process_chunks_images(chunks, document.images)
+                chunks = self.process_chunks_images(
+                    chunks, document.images, deadline=deadline
+                )
             else:
                 logger.info(
                     f"Skipping image processing for unsupported file type: {file_ext}"
                 )
 
+        if self.timed_out:
+            if not self.chunking_config.allow_partial_result:
+                raise TimeoutError(f"Document parsing timed out after {timeout}s")
+            logger.warning(
+                f"Document parsing timed out after {timeout}s, "
+                f"returning {len(chunks)} chunks as partial result"
+            )
+            document.metadata["partial"] = True
+
         document.chunks = chunks
         return document
 
@@ -936,12 +955,17 @@ class BaseParser(ABC):
         return chunk
 
     def process_chunks_images(
-        self, chunks: List[Chunk], image_map: Dict[str, str] = {}
+        self,
+        chunks: List[Chunk],
+        image_map: Dict[str, str] = {},
+        deadline: Optional[float] = None,
     ) -> List[Chunk]:
         """Concurrent processing of images in all Chunks
 
         Args:
             chunks: List of document chunks
+            deadline: Optional time.monotonic() deadline; chunks not processed by
+                then are returned without images and self.timed_out is set
 
         Returns:
             List of processed document chunks
@@ -968,23 +992,39 @@ class BaseParser(ABC):
 
             # Create tasks for all Chunks
             tasks = [
-                process_with_limit(chunk, idx, len(chunks))
+                asyncio.ensure_future(process_with_limit(chunk, idx, len(chunks)))
                 for idx, chunk in enumerate(chunks)
             ]
 
-            # Execute all tasks concurrently
-            results = await asyncio.gather(*tasks, return_exceptions=True)
+            # Execute all tasks concurrently, until the deadline if any
+            timeout = None
+            if deadline is not None:
+                timeout = max(deadline - time.monotonic(), 0)
+            _, pending = await asyncio.wait(tasks, timeout=timeout)
+            if pending:
+                logger.warning(
+                    f"Image processing timed out, {len(pending)}/{len(chunks)} "
+                    "chunks are returned without images"
+                )
+                self.timed_out = True
+                for task in pending:
+                    task.cancel()
+                await asyncio.gather(*pending, return_exceptions=True)
 
-            # Handle possible exceptions
+            # Handle possible exceptions and unfinished chunks
             processed_chunks = []
-            for i, result in enumerate(results):
-                if isinstance(result, Exception):
-                    logger.error(f"Error processing Chunk {i + 1}: {str(result)}")
+            for i, task in enumerate(tasks):
+                if task.cancelled():
+                    # Keep original Chunk
+                    processed_chunks.append(chunks[i])
+                elif task.exception() is not None:
+                    logger.error(
+                        f"Error processing Chunk {i + 1}: {str(task.exception())}"
+                    )
                     # Keep original Chunk
-                    if i < len(chunks):
-                        processed_chunks.append(chunks[i])
+                    processed_chunks.append(chunks[i])
                 else:
-                    processed_chunks.append(result)
+                    processed_chunks.append(task.result())
 
             return processed_chunks
 
@@ -11,7 +11,9 @@ from docreader.parser.docx2_parser import Docx2Parser
 from docreader.parser.excel_parser import ExcelParser
 from docreader.parser.image_parser import ImageParser
 from docreader.parser.markdown_parser import MarkdownParser
+from docreader.parser.markitdown_parser import MarkitdownParser
 from docreader.parser.pdf_parser import PDFParser
+from docreader.parser.ppt_parser import PptParser
 from docreader.parser.text_parser import TextParser
 from docreader.parser.web_parser import WebParser
 
@@ -33,6 +35,10 @@ class Parser:
             "pdf": PDFParser,
             "md": MarkdownParser,
             "txt": TextParser,
+            # Presentation and e-book formats
+            "pptx": MarkitdownParser,
+            "ppt": PptParser,
+            "epub": MarkitdownParser,
             # Image formats - all use the same ImageParser
             "jpg": ImageParser,
             "jpeg": ImageParser,
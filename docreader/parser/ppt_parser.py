@@ -0,0 +1,75 @@
+import logging
+import os
+import shutil
+from typing import Optional
+
+from docreader.models.document import Document
+from docreader.parser.doc_parser import SandboxExecutor
+from docreader.parser.markitdown_parser import MarkitdownParser
+from docreader.utils.tempfile import TempDirContext, TempFileContext
+
+logger = logging.getLogger(__name__)
+
+
+class PptParser(MarkitdownParser):
+    """PPT (legacy PowerPoint) parser
+
+    Converts the presentation to PPTX with LibreOffice, then parses it with MarkItDown.
+    """
+
+    def __init__(self, *args, **kwargs):
+        # The converted content is PPTX, let MarkItDown treat it as such
+        kwargs["file_type"] = "pptx"
+        super().__init__(*args, **kwargs)
+        self.sandbox_executor = SandboxExecutor()
+
+    def parse_into_text(self, content: bytes) -> Document:
+        logger.info(f"Parsing PPT document, content size: {len(content)} bytes")
+
+        with TempFileContext(content, ".ppt") as temp_file_path:
+            pptx_content = self._try_convert_ppt_to_pptx(temp_file_path)
+        if not pptx_content:
+            logger.warning("Failed to convert PPT to PPTX")
+            return Document(content="")
+
+        logger.info("Successfully converted PPT to PPTX, using MarkitdownParser")
+        return super().parse_into_text(pptx_content)
+
+    def _try_convert_ppt_to_pptx(self, ppt_path: str) -> Optional[bytes]:
+        """Convert PPT file to PPTX format with LibreOffice/OpenOffice
+
+        Args:
+            ppt_path: PPT file path
+
+        Returns:
+            Byte stream of PPTX file content, or None if conversion fails
+        """
+        soffice_path = os.environ.get("LIBREOFFICE_PATH") or shutil.which("soffice")
+        if not soffice_path or not os.path.exists(soffice_path):
+            logger.warning("Failed to find soffice")
+            return None
+
+        with TempDirContext() as temp_dir:
+            cmd = [
+                soffice_path,
+                "--headless",
+                "--convert-to",
+                "pptx",
+                "--outdir",
+                temp_dir,
+                ppt_path,
+            ]
+            logger.info(f"Running command in sandbox: {' '.join(cmd)}")
+
+            stdout, stderr, returncode = self.sandbox_executor.execute_in_sandbox(cmd)
+            if returncode != 0:
+                logger.warning(
+                    f"Error converting PPT to PPTX: {stderr.decode('utf-8')}"
+                )
+                return None
+
+            for file in os.listdir(temp_dir):
+                if file.endswith(".pptx"):
+                    with open(os.path.join(temp_dir, file), "rb") as f:
+                        return f.read()
+        return None
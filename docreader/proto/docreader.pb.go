@@ -234,15 +234,17 @@ func (x *VLMConfig) GetInterfaceType() string {
 }
 
 type ReadConfig struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	ChunkSize        int32                  `protobuf:"varint,1,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`                      // 分块大小
-	ChunkOverlap     int32                  `protobuf:"varint,2,opt,name=chunk_overlap,json=chunkOverlap,proto3" json:"chunk_overlap,omitempty"`             // 分块重叠
-	Separators       []string               `protobuf:"bytes,3,rep,name=separators,proto3" json:"separators,omitempty"`                                      // 分隔符
-	EnableMultimodal bool                   `protobuf:"varint,4,opt,name=enable_multimodal,json=enableMultimodal,proto3" json:"enable_multimodal,omitempty"` // 多模态处理
-	StorageConfig    *StorageConfig         `protobuf:"bytes,5,opt,name=storage_config,json=storageConfig,proto3" json:"storage_config,omitempty"`           // 对象存储配置（通用）
-	VlmConfig        *VLMConfig             `protobuf:"bytes,6,opt,name=vlm_config,json=vlmConfig,proto3" json:"vlm_config,omitempty"`                       // VLM 配置
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	ChunkSize           int32                  `protobuf:"varint,1,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`                                 // 分块大小
+	ChunkOverlap        int32                  `protobuf:"varint,2,opt,name=chunk_overlap,json=chunkOverlap,proto3" json:"chunk_overlap,omitempty"`                        // 分块重叠
+	Separators          []string               `protobuf:"bytes,3,rep,name=separators,proto3" json:"separators,omitempty"`                                                 // 分隔符
+	EnableMultimodal    bool                   `protobuf:"varint,4,opt,name=enable_multimodal,json=enableMultimodal,proto3" json:"enable_multimodal,omitempty"`            // 多模态处理
+	StorageConfig       *StorageConfig         `protobuf:"bytes,5,opt,name=storage_config,json=storageConfig,proto3" json:"storage_config,omitempty"`                      // 对象存储配置（通用）
+	VlmConfig           *VLMConfig             `protobuf:"bytes,6,opt,name=vlm_config,json=vlmConfig,proto3" json:"vlm_config,omitempty"`                                  // VLM 配置
+	ParseTimeoutSeconds int32                  `protobuf:"varint,7,opt,name=parse_timeout_seconds,json=parseTimeoutSeconds,proto3" json:"parse_timeout_seconds,omitempty"` // 解析超时时间（秒），0 表示不限制
+	AllowPartialResult  bool                   `protobuf:"varint,8,opt,name=allow_partial_result,json=allowPartialResult,proto3" json:"allow_partial_result,omitempty"`    // 超时时是否返回已解析的部分结果，否则返回错误
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *ReadConfig) Reset() {
@@ -317,6 +319,20 @@ func (x *ReadConfig) GetVlmConfig() *VLMConfig {
 	return nil
 }
 
+func (x *ReadConfig) GetParseTimeoutSeconds() int32 {
+	if x != nil {
+		return x.ParseTimeoutSeconds
+	}
+	return 0
+}
+
+func (x *ReadConfig) GetAllowPartialResult() bool {
+	if x != nil {
+		return x.AllowPartialResult
+	}
+	return false
+}
+
 type CompareSplittersRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
@@ -806,8 +822,9 @@ func (x *Chunk) GetImages() []*Image {
 // 从URL读取文档响应
 type ReadResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Chunks        []*Chunk               `protobuf:"bytes,1,rep,name=chunks,proto3" json:"chunks,omitempty"` // 文档分块
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`   // 错误信息
+	Chunks        []*Chunk               `protobuf:"bytes,1,rep,name=chunks,proto3" json:"chunks,omitempty"`    // 文档分块
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`      // 错误信息
+	Partial       bool                   `protobuf:"varint,3,opt,name=partial,proto3" json:"partial,omitempty"` // 是否为部分结果（解析超时，部分分块的图片未完成解析）
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -856,6 +873,13 @@ func (x *ReadResponse) GetError() string {
 	return ""
 }
 
+func (x *ReadResponse) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
 var File_docreader_proto protoreflect.FileDescriptor
 
 const file_docreader_proto_rawDesc = "" +
@@ -876,7 +900,7 @@ const file_docreader_proto_rawDesc = "" +
 	"model_name\x18\x01 \x01(\tR\tmodelName\x12\x19\n" +
 	"\bbase_url\x18\x02 \x01(\tR\abaseUrl\x12\x17\n" +
 	"\aapi_key\x18\x03 \x01(\tR\x06apiKey\x12%\n" +
-	"\x0einterface_type\x18\x04 \x01(\tR\rinterfaceType\"\x93\x02\n" +
+	"\x0einterface_type\x18\x04 \x01(\tR\rinterfaceType\"\xf9\x02\n" +
 	"\n" +
 	"ReadConfig\x12\x1d\n" +
 	"\n" +
@@ -888,7 +912,9 @@ const file_docreader_proto_rawDesc = "" +
 	"\x11enable_multimodal\x18\x04 \x01(\bR\x10enableMultimodal\x12?\n" +
 	"\x0estorage_config\x18\x05 \x01(\v2\x18.docreader.StorageConfigR\rstorageConfig\x123\n" +
 	"\n" +
-	"vlm_config\x18\x06 \x01(\v2\x14.docreader.VLMConfigR\tvlmConfig\"q\n" +
+	"vlm_config\x18\x06 \x01(\v2\x14.docreader.VLMConfigR\tvlmConfig\x122\n" +
+	"\x15parse_timeout_seconds\x18\a \x01(\x05R\x13parseTimeoutSeconds\x120\n" +
+	"\x14allow_partial_result\x18\b \x01(\bR\x12allowPartialResult\"q\n" +
 	"\x17CompareSplittersRequest\x12\x12\n" +
 	"\x04text\x18\x01 \x01(\tR\x04text\x12\x1d\n" +
 	"\n" +
@@ -929,10 +955,11 @@ const file_docreader_proto_rawDesc = "" +
 	"\x03seq\x18\x02 \x01(\x05R\x03seq\x12\x14\n" +
 	"\x05start\x18\x03 \x01(\x05R\x05start\x12\x10\n" +
 	"\x03end\x18\x04 \x01(\x05R\x03end\x12(\n" +
-	"\x06images\x18\x05 \x03(\v2\x10.docreader.ImageR\x06images\"N\n" +
+	"\x06images\x18\x05 \x03(\v2\x10.docreader.ImageR\x06images\"h\n" +
 	"\fReadResponse\x12(\n" +
 	"\x06chunks\x18\x01 \x03(\v2\x10.docreader.ChunkR\x06chunks\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error*G\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x18\n" +
+	"\apartial\x18\x03 \x01(\bR\apartial*G\n" +
 	"\x0fStorageProvider\x12 \n" +
 	"\x1cSTORAGE_PROVIDER_UNSPECIFIED\x10\x00\x12\a\n" +
 	"\x03COS\x10\x01\x12\t\n" +
@@ -47,20 +47,24 @@ class VLMConfig(_message.Message):
     def __init__(self, model_name: _Optional[str] = ..., base_url: _Optional[str] = ..., api_key: _Optional[str] = ..., interface_type: _Optional[str] = ...) -> None: ...
 
 class ReadConfig(_message.Message):
-    __slots__ = ("chunk_size", "chunk_overlap", "separators", "enable_multimodal", "storage_config", "vlm_config")
+    __slots__ = ("chunk_size", "chunk_overlap", "separators", "enable_multimodal", "storage_config", "vlm_config", "parse_timeout_seconds", "allow_partial_result")
     CHUNK_SIZE_FIELD_NUMBER: _ClassVar[int]
     CHUNK_OVERLAP_FIELD_NUMBER: _ClassVar[int]
     SEPARATORS_FIELD_NUMBER: _ClassVar[int]
     ENABLE_MULTIMODAL_FIELD_NUMBER: _ClassVar[int]
     STORAGE_CONFIG_FIELD_NUMBER: _ClassVar[int]
     VLM_CONFIG_FIELD_NUMBER: _ClassVar[int]
+    PARSE_TIMEOUT_SECONDS_FIELD_NUMBER: _ClassVar[int]
+    ALLOW_PARTIAL_RESULT_FIELD_NUMBER: _ClassVar[int]
     chunk_size: int
     chunk_overlap: int
     separators: _containers.RepeatedScalarFieldContainer[str]
     enable_multimodal: bool
     storage_config: StorageConfig
     vlm_config: VLMConfig
-    def __init__(self, chunk_size: _Optional[int] = ..., chunk_overlap: _Optional[int] = ..., separators: _Optional[_Iterable[str]] = ..., enable_multimodal: bool = ..., storage_config: _Optional[_Union[StorageConfig, _Mapping]] = ..., vlm_config: _Optional[_Union[VLMConfig, _Mapping]] = ...) -> None: ...
+    parse_timeout_seconds: int
+    allow_partial_result: bool
+    def __init__(self, chunk_size: _Optional[int] = ..., chunk_overlap: _Optional[int] = ..., separators: _Optional[_Iterable[str]] = ..., enable_multimodal: bool = ..., storage_config: _Optional[_Union[StorageConfig, _Mapping]] = ..., vlm_config: _Optional[_Union[VLMConfig, _Mapping]] = ..., parse_timeout_seconds: _Optional[int] = ..., allow_partial_result: bool = ...) -> None: ...
 
 class CompareSplittersRequest(_message.Message):
     __slots__ = ("text", "chunk_size", "chunk_overlap")
@@ -149,9 +153,11 @@ class Chunk(_message.Message):
     def __init__(self, content: _Optional[str] = ..., seq: _Optional[int] = ..., start: _Optional[int] = ..., end: _Optional[int] = ..., images: _Optional[_Iterable[_Union[Image, _Mapping]]] = ...) -> None: ...
 
 class ReadResponse(_message.Message):
-    __slots__ = ("chunks", "error")
+    __slots__ = ("chunks", "error", "partial")
     CHUNKS_FIELD_NUMBER: _ClassVar[int]
     ERROR_FIELD_NUMBER: _ClassVar[int]
+    PARTIAL_FIELD_NUMBER: _ClassVar[int]
     chunks: _containers.RepeatedCompositeFieldContainer[Chunk]
     error: str
-    def __init__(self, chunks: _Optional[_Iterable[_Union[Chunk, _Mapping]]] = ..., error: _Optional[str] = ...) -> None: ...
+    partial: bool
+    def __init__(self, chunks: _Optional[_Iterable[_Union[Chunk, _Mapping]]] = ..., error: _Optional[str] = ..., partial: bool = ...) -> None: ...
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -380,6 +381,13 @@ func (r *chunkRepository) DeleteByKnowledgeList(ctx context.Context, tenantID ui
 	).Delete(&types.Chunk{}).Error
 }
 
+// DeleteChunksByKnowledgeBaseID deletes all chunks in a knowledge base
+func (r *chunkRepository) DeleteChunksByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) error {
+	return r.db.WithContext(ctx).Where(
+		"tenant_id = ? AND knowledge_base_id = ?", tenantID, kbID,
+	).Delete(&types.Chunk{}).Error
+}
+
 // DeleteChunksByTagID deletes all chunks with the specified tag ID
 // Returns the IDs of deleted chunks for index cleanup
 func (r *chunkRepository) DeleteChunksByTagID(ctx context.Context, tenantID uint64, kbID string, tagID string, excludeIDs []string) ([]string, error) {
@@ -440,6 +448,41 @@ func (r *chunkRepository) CountChunksByKnowledgeBaseID(
 	return count, err
 }
 
+// CountChunksByKnowledgeIDs counts chunks of the given types belonging to the given knowledge items
+func (r *chunkRepository) CountChunksByKnowledgeIDs(
+	ctx context.Context,
+	tenantID uint64,
+	knowledgeIDs []string,
+	chunkTypes []types.ChunkType,
+) (int64, error) {
+	if len(knowledgeIDs) == 0 {
+		return 0, nil
+	}
+	var count int64
+	query := r.db.WithContext(ctx).Model(&types.Chunk{}).
+		Where("tenant_id = ? AND knowledge_id IN ?", tenantID, knowledgeIDs)
+	if len(chunkTypes) > 0 {
+		query = query.Where("chunk_type IN ?", chunkTypes)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// HasChunksOfType reports whether a knowledge item has at least one chunk of the given type,
+// using an EXISTS query so the chunks are not loaded
+func (r *chunkRepository) HasChunksOfType(
+	ctx context.Context,
+	tenantID uint64,
+	knowledgeID string,
+	chunkType types.ChunkType,
+) (bool, error) {
+	var exists bool
+	subQuery := r.db.Model(&types.Chunk{}).Select("1").
+		Where("tenant_id = ? AND knowledge_id = ? AND chunk_type = ?", tenantID, knowledgeID, chunkType)
+	err := r.db.WithContext(ctx).Raw("SELECT EXISTS (?)", subQuery).Scan(&exists).Error
+	return exists, err
+}
+
 // DeleteUnindexedChunks by knowledge id and chunk index range
 func (r *chunkRepository) DeleteUnindexedChunks(
 	ctx context.Context,
@@ -502,6 +545,40 @@ func (r *chunkRepository) ListAllFAQChunksByKnowledgeID(
 	return allChunks, nil
 }
 
+// ListAnswerPendingFAQChunks lists a page of FAQ chunks of a knowledge imported without answers.
+// The answer_pending flag is omitted from the metadata unless set, so a key match is enough.
+func (r *chunkRepository) ListAnswerPendingFAQChunks(
+	ctx context.Context,
+	tenantID uint64,
+	knowledgeID string,
+	page *types.Pagination,
+) ([]*types.Chunk, int64, error) {
+	pendingFilter := "CAST(metadata AS CHAR) LIKE ?"
+	if r.db.Dialector.Name() == "postgres" {
+		pendingFilter = "metadata::text LIKE ?"
+	}
+	baseFilter := func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ? AND knowledge_id = ? AND chunk_type = ? AND status in (?)",
+			tenantID, knowledgeID, types.ChunkTypeFAQ,
+			[]int{int(types.ChunkStatusIndexed), int(types.ChunkStatusDefault)}).
+			Where(pendingFilter, `%"answer_pending"%`)
+	}
+
+	var total int64
+	if err := baseFilter(r.db.WithContext(ctx).Model(&types.Chunk{})).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var chunks []*types.Chunk
+	if err := baseFilter(r.db.WithContext(ctx)).
+		Order("updated_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&chunks).Error; err != nil {
+		return nil, 0, err
+	}
+	return chunks, total, nil
+}
+
 // ListAllFAQChunksWithMetadataByKnowledgeBaseID lists all FAQ chunks for a knowledge base ID
 // Returns ID and Metadata fields for duplicate question checking
 // Uses batch query to handle large datasets
@@ -517,7 +594,7 @@ func (r *chunkRepository) ListAllFAQChunksWithMetadataByKnowledgeBaseID(
 	for {
 		var batchChunks []*types.Chunk
 		if err := r.db.WithContext(ctx).
-			Select("id, metadata").
+			Select("id, tag_id, metadata").
 			Where("tenant_id = ? AND knowledge_base_id = ? AND chunk_type = ? AND status = ?",
 				tenantID, kbID, types.ChunkTypeFAQ, types.ChunkStatusIndexed).
 			Offset(offset).
@@ -544,38 +621,149 @@ func (r *chunkRepository) ListAllFAQChunksWithMetadataByKnowledgeBaseID(
 	return allChunks, nil
 }
 
-// ListAllFAQChunksForExport lists all FAQ chunks for export with full metadata, tag_id, is_enabled, and flags.
-// Uses batch query to handle large datasets.
-func (r *chunkRepository) ListAllFAQChunksForExport(
+// GetFAQChunkByStandardQuestion finds an indexed FAQ chunk of a knowledge base by exact standard question.
+// When several tags hold the same question the one with the smallest seq_id is returned.
+// Returns nil without error if no chunk matches.
+func (r *chunkRepository) GetFAQChunkByStandardQuestion(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+	question string,
+) (*types.Chunk, error) {
+	questionFilter := "metadata->>'$.standard_question' = ?"
+	if r.db.Dialector.Name() == "postgres" {
+		questionFilter = "metadata->>'standard_question' = ?"
+	}
+	var chunks []*types.Chunk
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND knowledge_base_id = ? AND chunk_type = ? AND status = ?",
+			tenantID, kbID, types.ChunkTypeFAQ, types.ChunkStatusIndexed).
+		Where(questionFilter, question).
+		Order("seq_id ASC").
+		Limit(1).
+		Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	return chunks[0], nil
+}
+
+// GetKnowledgeChunkStats aggregates chunk statistics of a knowledge item with aggregate queries
+func (r *chunkRepository) GetKnowledgeChunkStats(
 	ctx context.Context,
 	tenantID uint64,
 	knowledgeID string,
+) (*types.KnowledgeChunkStats, error) {
+	stats := &types.KnowledgeChunkStats{ChunkCountByType: make(map[string]int64)}
+
+	var typeCounts []struct {
+		ChunkType string
+		Count     int64
+	}
+	if err := r.db.WithContext(ctx).Model(&types.Chunk{}).
+		Select("chunk_type, COUNT(*) AS count").
+		Where("tenant_id = ? AND knowledge_id = ?", tenantID, knowledgeID).
+		Group("chunk_type").
+		Scan(&typeCounts).Error; err != nil {
+		return nil, err
+	}
+	for _, tc := range typeCounts {
+		stats.ChunkCountByType[tc.ChunkType] = tc.Count
+		stats.TotalChunks += tc.Count
+	}
+
+	// OCR 与描述子分块共享同一图片的 image_info，去重后即为图片数量
+	if err := r.db.WithContext(ctx).Model(&types.Chunk{}).
+		Where("tenant_id = ? AND knowledge_id = ? AND chunk_type IN ?", tenantID, knowledgeID,
+			[]string{types.ChunkTypeImageOCR, types.ChunkTypeImageCaption}).
+		Distinct("image_info").
+		Count(&stats.ImageCount).Error; err != nil {
+		return nil, err
+	}
+
+	questionsFilter := "CAST(metadata AS CHAR) LIKE ?"
+	if r.db.Dialector.Name() == "postgres" {
+		questionsFilter = "metadata::text LIKE ?"
+	}
+	if err := r.db.WithContext(ctx).Model(&types.Chunk{}).
+		Where("tenant_id = ? AND knowledge_id = ? AND chunk_type = ?", tenantID, knowledgeID, types.ChunkTypeText).
+		Where(questionsFilter, `%"generated_questions"%`).
+		Count(&stats.QuestionChunkCount).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ListGeneratedQuestionChunks lists a page of text chunks carrying generated questions, ordered by seq_id
+func (r *chunkRepository) ListGeneratedQuestionChunks(
+	ctx context.Context,
+	tenantID uint64,
+	kbID, knowledgeID, tagID string,
+	afterSeqID int64,
+	limit int,
 ) ([]*types.Chunk, error) {
-	const batchSize = 1000 // 每批查询1000条
+	questionsFilter := "CAST(metadata AS CHAR) LIKE ?"
+	if r.db.Dialector.Name() == "postgres" {
+		questionsFilter = "metadata::text LIKE ?"
+	}
+	query := r.db.WithContext(ctx).
+		Select("id, seq_id, knowledge_id, chunk_index, content, metadata").
+		Where("tenant_id = ? AND knowledge_base_id = ? AND chunk_type = ? AND seq_id > ?",
+			tenantID, kbID, types.ChunkTypeText, afterSeqID).
+		Where(questionsFilter, `%"generated_questions"%`)
+	if knowledgeID != "" {
+		query = query.Where("knowledge_id = ?", knowledgeID)
+	}
+	if tagID != "" {
+		query = query.Where("knowledge_id IN (?)", r.db.Model(&types.Knowledge{}).
+			Select("id").Where("tenant_id = ? AND knowledge_base_id = ? AND tag_id = ?", tenantID, kbID, tagID))
+	}
+	var chunks []*types.Chunk
+	if err := query.Order("seq_id").Limit(limit).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// ListTextChunksWithLinksByKnowledgeBaseID lists text chunks of a knowledge base whose metadata contains links
+// Returns ID, KnowledgeID, ChunkIndex and Metadata fields
+// Uses batch query to handle large datasets
+func (r *chunkRepository) ListTextChunksWithLinksByKnowledgeBaseID(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+) ([]*types.Chunk, error) {
+	const batchSize = 1000
 	var allChunks []*types.Chunk
 	offset := 0
 
+	linksFilter := "CAST(metadata AS CHAR) LIKE ?"
+	if r.db.Dialector.Name() == "postgres" {
+		linksFilter = "metadata::text LIKE ?"
+	}
+
 	for {
 		var batchChunks []*types.Chunk
 		if err := r.db.WithContext(ctx).
-			Select("id, metadata, tag_id, is_enabled, flags").
-			Where("tenant_id = ? AND knowledge_id = ? AND chunk_type = ? AND status = ?",
-				tenantID, knowledgeID, types.ChunkTypeFAQ, types.ChunkStatusIndexed).
-			Order("created_at ASC").
+			Select("id, knowledge_id, chunk_index, metadata").
+			Where("tenant_id = ? AND knowledge_base_id = ? AND chunk_type = ?",
+				tenantID, kbID, types.ChunkTypeText).
+			Where(linksFilter, `%"links"%`).
+			Order("knowledge_id, chunk_index").
 			Offset(offset).
 			Limit(batchSize).
 			Find(&batchChunks).Error; err != nil {
 			return nil, err
 		}
 
-		// 如果没有查询到数据，说明已经查询完毕
 		if len(batchChunks) == 0 {
 			break
 		}
 
 		allChunks = append(allChunks, batchChunks...)
 
-		// 如果返回的数据少于批次大小，说明已经是最后一批
 		if len(batchChunks) < batchSize {
 			break
 		}
@@ -586,6 +774,104 @@ func (r *chunkRepository) ListAllFAQChunksForExport(
 	return allChunks, nil
 }
 
+// ListFAQChunksMissingContentHash lists FAQ chunks of a knowledge base whose content_hash is empty.
+// These are typically chunks created before content hashing was introduced.
+func (r *chunkRepository) ListFAQChunksMissingContentHash(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+) ([]*types.Chunk, error) {
+	const batchSize = 1000 // 每批查询1000条
+	var allChunks []*types.Chunk
+	lastID := ""
+
+	// 按 ID 游标分页，避免回填过程中 offset 偏移
+	for {
+		var batchChunks []*types.Chunk
+		if err := r.db.WithContext(ctx).
+			Select("id, content, metadata").
+			Where("tenant_id = ? AND knowledge_base_id = ? AND chunk_type = ? AND (content_hash IS NULL OR content_hash = '') AND id > ?",
+				tenantID, kbID, types.ChunkTypeFAQ, lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&batchChunks).Error; err != nil {
+			return nil, err
+		}
+		if len(batchChunks) == 0 {
+			break
+		}
+
+		allChunks = append(allChunks, batchChunks...)
+		if len(batchChunks) < batchSize {
+			break
+		}
+		lastID = batchChunks[len(batchChunks)-1].ID
+	}
+
+	return allChunks, nil
+}
+
+// UpdateChunkContentHashes sets content_hash for multiple chunks in a single transaction
+func (r *chunkRepository) UpdateChunkContentHashes(ctx context.Context, tenantID uint64, hashes map[string]string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for chunkID, hash := range hashes {
+			if err := tx.Model(&types.Chunk{}).
+				Where("tenant_id = ? AND id = ?", tenantID, chunkID).
+				Update("content_hash", hash).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveChunks saves all fields of multiple chunks in a single transaction, either every chunk is
+// written or none is
+func (r *chunkRepository) SaveChunks(ctx context.Context, chunks []*types.Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, chunk := range chunks {
+			if err := tx.Save(chunk).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListFAQChunksForExport lists a page of indexed FAQ chunks for export, ordered by (created_at, id) and
+// starting after the (afterCreatedAt, afterID) cursor; an empty afterID starts from the beginning.
+// Keyset paging keeps entries edited during the export from being skipped or exported twice.
+// Returns ID, SeqID, KnowledgeID, KnowledgeBaseID, ChunkType, Metadata, TagID, IsEnabled, Flags,
+// CreatedAt and UpdatedAt fields
+func (r *chunkRepository) ListFAQChunksForExport(
+	ctx context.Context,
+	tenantID uint64,
+	knowledgeID string,
+	afterCreatedAt time.Time,
+	afterID string,
+	limit int,
+) ([]*types.Chunk, error) {
+	query := r.db.WithContext(ctx).
+		Select("id, seq_id, knowledge_id, knowledge_base_id, chunk_type, metadata, tag_id, is_enabled, flags, "+
+			"created_at, updated_at").
+		Where("tenant_id = ? AND knowledge_id = ? AND chunk_type = ? AND status = ?",
+			tenantID, knowledgeID, types.ChunkTypeFAQ, types.ChunkStatusIndexed)
+	if afterID != "" {
+		query = query.Where("(created_at > ? OR (created_at = ? AND id > ?))", afterCreatedAt, afterCreatedAt, afterID)
+	}
+	var chunks []*types.Chunk
+	if err := query.Order("created_at ASC, id ASC").Limit(limit).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
 // UpdateChunkFlagsBatch updates flags for multiple chunks in batch using SQL CASE expressions.
 // This is more efficient than updating chunks one by one.
 // setFlags: map of chunk ID to flags to set (OR operation)
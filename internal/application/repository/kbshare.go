@@ -108,6 +108,21 @@ func (r *kbShareRepository) ListByKnowledgeBase(ctx context.Context, kbID string
 	return shares, nil
 }
 
+// ListByKnowledgeBases lists all share records for multiple knowledge bases (batch).
+func (r *kbShareRepository) ListByKnowledgeBases(ctx context.Context, kbIDs []string) ([]*types.KnowledgeBaseShare, error) {
+	if len(kbIDs) == 0 {
+		return nil, nil
+	}
+	var shares []*types.KnowledgeBaseShare
+	err := r.db.WithContext(ctx).
+		Where("knowledge_base_id IN ?", kbIDs).
+		Find(&shares).Error
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
 // ListByOrganization lists all share records for an organization.
 // Excludes shares whose knowledge base has been soft-deleted.
 func (r *kbShareRepository) ListByOrganization(ctx context.Context, orgID string) ([]*types.KnowledgeBaseShare, error) {
@@ -2,12 +2,15 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var ErrKnowledgeNotFound = errors.New("knowledge not found")
@@ -59,6 +62,18 @@ func (r *knowledgeRepository) GetKnowledgeByIDOnly(ctx context.Context, id strin
 	return &knowledge, nil
 }
 
+// GetKnowledgeByIDsOnly returns knowledge by IDs without tenant filter (for permission resolution).
+func (r *knowledgeRepository) GetKnowledgeByIDsOnly(ctx context.Context, ids []string) ([]*types.Knowledge, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var knowledge []*types.Knowledge
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&knowledge).Error; err != nil {
+		return nil, err
+	}
+	return knowledge, nil
+}
+
 // ListKnowledgeByKnowledgeBaseID lists all knowledge in a knowledge base
 func (r *knowledgeRepository) ListKnowledgeByKnowledgeBaseID(
 	ctx context.Context, tenantID uint64, kbID string,
@@ -80,6 +95,8 @@ func (r *knowledgeRepository) ListPagedKnowledgeByKnowledgeBaseID(
 	tagID string,
 	keyword string,
 	fileType string,
+	extractedKeyword string,
+	fileHash string,
 ) ([]*types.Knowledge, int64, error) {
 	var knowledges []*types.Knowledge
 	var total int64
@@ -101,6 +118,12 @@ func (r *knowledgeRepository) ListPagedKnowledgeByKnowledgeBaseID(
 			query = query.Where("file_type = ?", fileType)
 		}
 	}
+	if extractedKeyword != "" {
+		query = filterByExtractedKeyword(query, extractedKeyword)
+	}
+	if fileHash != "" {
+		query = query.Where("file_hash = ?", fileHash)
+	}
 
 	// Query total count first
 	if err := query.Count(&total).Error; err != nil {
@@ -125,6 +148,12 @@ func (r *knowledgeRepository) ListPagedKnowledgeByKnowledgeBaseID(
 			dataQuery = dataQuery.Where("file_type = ?", fileType)
 		}
 	}
+	if extractedKeyword != "" {
+		dataQuery = filterByExtractedKeyword(dataQuery, extractedKeyword)
+	}
+	if fileHash != "" {
+		dataQuery = dataQuery.Where("file_hash = ?", fileHash)
+	}
 
 	if err := dataQuery.
 		Order("created_at DESC").
@@ -137,6 +166,19 @@ func (r *knowledgeRepository) ListPagedKnowledgeByKnowledgeBaseID(
 	return knowledges, total, nil
 }
 
+// filterByExtractedKeyword keeps knowledge whose extracted keywords or entities (stored in metadata)
+// contain the given value exactly
+func filterByExtractedKeyword(db *gorm.DB, value string) *gorm.DB {
+	// 根据数据库类型使用不同的 JSON 查询语法
+	if db.Dialector.Name() == "postgres" {
+		candidate, _ := json.Marshal([]string{value})
+		return db.Where("(metadata::jsonb -> ? @> ?::jsonb OR metadata::jsonb -> ? @> ?::jsonb)",
+			types.KnowledgeMetadataKeywordsKey, string(candidate), types.KnowledgeMetadataEntitiesKey, string(candidate))
+	}
+	return db.Where("(JSON_CONTAINS(metadata, JSON_ARRAY(?), ?) OR JSON_CONTAINS(metadata, JSON_ARRAY(?), ?))",
+		value, "$."+types.KnowledgeMetadataKeywordsKey, value, "$."+types.KnowledgeMetadataEntitiesKey)
+}
+
 // UpdateKnowledge updates knowledge
 func (r *knowledgeRepository) UpdateKnowledge(ctx context.Context, knowledge *types.Knowledge) error {
 	err := r.db.WithContext(ctx).Omit(omitFieldsOnUpdate...).Save(knowledge).Error
@@ -161,6 +203,13 @@ func (r *knowledgeRepository) DeleteKnowledgeList(ctx context.Context, tenantID
 	return r.db.WithContext(ctx).Where("tenant_id = ? AND id in ?", tenantID, ids).Delete(&types.Knowledge{}).Error
 }
 
+// DeleteKnowledgeByKnowledgeBaseID deletes all knowledge in a knowledge base
+func (r *knowledgeRepository) DeleteKnowledgeByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) error {
+	return r.db.WithContext(ctx).Where(
+		"tenant_id = ? AND knowledge_base_id = ?", tenantID, kbID,
+	).Delete(&types.Knowledge{}).Error
+}
+
 // GetKnowledgeBatch gets knowledge in batch
 func (r *knowledgeRepository) GetKnowledgeBatch(
 	ctx context.Context, tenantID uint64, ids []string,
@@ -244,6 +293,25 @@ func (r *knowledgeRepository) CheckKnowledgeExists(
 	return false, nil, nil
 }
 
+// ListKnowledgeByFileHashes lists the knowledge of a knowledge base whose file hash is one of hashes,
+// ignoring failed knowledge like CheckKnowledgeExists
+func (r *knowledgeRepository) ListKnowledgeByFileHashes(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+	hashes []string,
+) ([]*types.Knowledge, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	var knowledgeList []*types.Knowledge
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND knowledge_base_id = ? AND parse_status <> ?", tenantID, kbID, "failed").
+		Where("file_hash IN ?", hashes).
+		Find(&knowledgeList).Error
+	return knowledgeList, err
+}
+
 func (r *knowledgeRepository) AminusB(
 	ctx context.Context,
 	Atenant uint64, A string,
@@ -363,6 +431,10 @@ func (r *knowledgeRepository) SearchKnowledge(
 				aliases = []string{"%.doc"}
 			case "doc":
 				aliases = []string{"%.docx"}
+			case "pptx":
+				aliases = []string{"%.ppt"}
+			case "ppt":
+				aliases = []string{"%.pptx"}
 			case "jpg":
 				aliases = []string{"%.jpeg", "%.png"}
 			case "jpeg":
@@ -471,6 +543,10 @@ func (r *knowledgeRepository) SearchKnowledgeInScopes(
 				aliases = []string{"%.doc"}
 			case "doc":
 				aliases = []string{"%.docx"}
+			case "pptx":
+				aliases = []string{"%.ppt"}
+			case "ppt":
+				aliases = []string{"%.pptx"}
 			case "jpg":
 				aliases = []string{"%.jpeg", "%.png"}
 			case "jpeg":
@@ -531,3 +607,95 @@ func (r *knowledgeRepository) ListIDsByTagID(
 		Pluck("id", &ids).Error
 	return ids, err
 }
+
+// ListExpiredKnowledgeIDs returns the IDs among knowledgeIDs whose expiration time is not after now
+func (r *knowledgeRepository) ListExpiredKnowledgeIDs(
+	ctx context.Context, knowledgeIDs []string, now time.Time,
+) ([]string, error) {
+	if len(knowledgeIDs) == 0 {
+		return nil, nil
+	}
+	var ids []string
+	if err := r.db.WithContext(ctx).Model(&types.Knowledge{}).
+		Where("id IN ? AND expires_at IS NOT NULL AND expires_at <= ?", knowledgeIDs, now).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListKnowledgeExpiringBefore lists knowledge of all tenants expiring no later than before, earliest first
+func (r *knowledgeRepository) ListKnowledgeExpiringBefore(
+	ctx context.Context, before time.Time, pendingNotice bool, limit int,
+) ([]*types.Knowledge, error) {
+	query := r.db.WithContext(ctx).
+		Where("expires_at IS NOT NULL AND expires_at <= ? AND parse_status <> ?", before, types.ParseStatusDeleting)
+	if pendingNotice {
+		// 只取配置了到期通知地址的知识库，避免无需通知的知识占满每批的数量
+		webhookKBs := r.db.Model(&types.KnowledgeBase{}).Select("id")
+		if r.db.Dialector.Name() == "postgres" {
+			webhookKBs = webhookKBs.Where("COALESCE(retention_config::jsonb ->> 'expiry_webhook_url', '') <> ''")
+		} else {
+			webhookKBs = webhookKBs.Where(
+				"COALESCE(JSON_UNQUOTE(JSON_EXTRACT(retention_config, '$.expiry_webhook_url')), '') <> ''")
+		}
+		query = query.Where("expiry_notified_at IS NULL AND knowledge_base_id IN (?)", webhookKBs)
+	}
+	var knowledges []*types.Knowledge
+	if err := query.Order("expires_at ASC").Limit(limit).Find(&knowledges).Error; err != nil {
+		return nil, err
+	}
+	return knowledges, nil
+}
+
+// ListKnowledgeDueForRefresh lists url/file_url knowledge of all tenants whose scheduled refresh is due
+func (r *knowledgeRepository) ListKnowledgeDueForRefresh(
+	ctx context.Context, now time.Time, limit int,
+) ([]*types.Knowledge, error) {
+	var knowledges []*types.Knowledge
+	err := r.db.WithContext(ctx).
+		Where("refresh_interval_minutes > 0 AND next_refresh_at IS NOT NULL AND next_refresh_at <= ?", now).
+		Where("type IN ? AND parse_status IN ?",
+			[]string{"url", "file_url"}, []string{types.ParseStatusCompleted, types.ParseStatusFailed}).
+		Order("next_refresh_at ASC").Limit(limit).Find(&knowledges).Error
+	return knowledges, err
+}
+
+// ListFileNamesByPrefix lists the file names in the knowledge base starting with prefix
+func (r *knowledgeRepository) ListFileNamesByPrefix(
+	ctx context.Context, tenantID uint64, kbID string, prefix string,
+) ([]string, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	var names []string
+	err := r.db.WithContext(ctx).Model(&types.Knowledge{}).
+		Where("tenant_id = ? AND knowledge_base_id = ? AND file_name LIKE ?", tenantID, kbID, escaped+"%").
+		Pluck("file_name", &names).Error
+	return names, err
+}
+
+// SaveFAQImportArchive creates or replaces the archived progress of an FAQ import task
+func (r *knowledgeRepository) SaveFAQImportArchive(ctx context.Context, archive *types.FAQImportArchive) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(archive).Error
+}
+
+// GetFAQImportArchive returns the archived progress of an FAQ import task, nil when there is none.
+// The archive is stored under the tenant owning the knowledge base; callers check access to that knowledge base.
+func (r *knowledgeRepository) GetFAQImportArchive(ctx context.Context, taskID string) (*types.FAQImportArchive, error) {
+	var archive types.FAQImportArchive
+	err := r.db.WithContext(ctx).Where("task_id = ?", taskID).First(&archive).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// DeleteExpiredFAQImportArchives deletes the FAQ import archives of all tenants expired no later than before
+func (r *knowledgeRepository) DeleteExpiredFAQImportArchives(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at IS NOT NULL AND expires_at <= ?", before).
+		Delete(&types.FAQImportArchive{})
+	return result.RowsAffected, result.Error
+}
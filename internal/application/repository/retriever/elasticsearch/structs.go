@@ -15,6 +15,7 @@ type VectorEmbedding struct {
 	ChunkID         string    `json:"chunk_id"          gorm:"column:chunk_id"`             // Unique ID of the text chunk
 	KnowledgeID     string    `json:"knowledge_id"      gorm:"column:knowledge_id"`         // ID of the knowledge item
 	KnowledgeBaseID string    `json:"knowledge_base_id" gorm:"column:knowledge_base_id"`    // ID of the knowledge base
+	ChunkType       string    `json:"chunk_type"`                                           // Type of the chunk
 	Embedding       []float32 `json:"embedding"         gorm:"column:embedding;not null"`   // Vector embedding of the content
 	IsEnabled       bool      `json:"is_enabled"`                                           // Whether the chunk is enabled
 }
@@ -34,6 +35,7 @@ func ToDBVectorEmbedding(embedding *types.IndexInfo, additionalParams map[string
 		ChunkID:         embedding.ChunkID,
 		KnowledgeID:     embedding.KnowledgeID,
 		KnowledgeBaseID: embedding.KnowledgeBaseID,
+		ChunkType:       embedding.ChunkType,
 		IsEnabled:       true, // Default to enabled
 	}
 	// Add embedding data if available in additionalParams
@@ -65,6 +67,7 @@ func FromDBVectorEmbeddingWithScore(id string,
 		ChunkID:         embedding.ChunkID,
 		KnowledgeID:     embedding.KnowledgeID,
 		KnowledgeBaseID: embedding.KnowledgeBaseID,
+		ChunkType:       embedding.ChunkType,
 		Content:         embedding.Content,
 		Score:           embedding.Score,
 		MatchType:       matchType,
@@ -1062,6 +1062,7 @@ func (e *elasticsearchRepository) processSingleHit(ctx context.Context,
 	if st, ok := sourceObj["source_type"].(float64); ok {
 		sourceType = int(st)
 	}
+	chunkType, _ := sourceObj["chunk_type"].(string)
 
 	// Handle SourceID transformation for generated questions
 	// Generated questions have SourceID format: {chunkID}-{questionID}
@@ -1100,6 +1101,7 @@ func (e *elasticsearchRepository) processSingleHit(ctx context.Context,
 		KnowledgeBaseID: targetKnowledgeBaseID,
 		Content:         content,
 		SourceType:      typesLocal.SourceType(sourceType),
+		ChunkType:       chunkType,
 	}
 
 	return indexInfo, embedding, nil
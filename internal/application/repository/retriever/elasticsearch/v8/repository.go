@@ -575,6 +575,7 @@ func (e *elasticsearchRepository) CopyIndices(ctx context.Context,
 				ChunkID:         targetChunkID,
 				KnowledgeID:     targetKnowledgeID,
 				KnowledgeBaseID: targetKnowledgeBaseID,
+				ChunkType:       sourceDoc.ChunkType,
 			}
 
 			indexInfoList = append(indexInfoList, indexInfo)
@@ -146,6 +146,18 @@ func (g *pgRepository) DeleteByKnowledgeIDList(ctx context.Context, knowledgeIDL
 	return nil
 }
 
+// DeleteByKnowledgeBaseID deletes all indices of a knowledge base
+func (g *pgRepository) DeleteByKnowledgeBaseID(ctx context.Context, knowledgeBaseID string, dimension int, knowledgeType string) error {
+	logger.GetLogger(ctx).Infof("[Postgres] Deleting indices by knowledge base ID: %s", knowledgeBaseID)
+	result := g.db.WithContext(ctx).Where("knowledge_base_id = ?", knowledgeBaseID).Delete(&pgVector{})
+	if result.Error != nil {
+		logger.GetLogger(ctx).Errorf("[Postgres] Failed to delete indices by knowledge base ID: %v", result.Error)
+		return result.Error
+	}
+	logger.GetLogger(ctx).Infof("[Postgres] Successfully deleted %d indices by knowledge base ID", result.RowsAffected)
+	return nil
+}
+
 // Retrieve handles retrieval requests and routes to appropriate method
 func (g *pgRepository) Retrieve(ctx context.Context, params types.RetrieveParams) ([]*types.RetrieveResult, error) {
 	logger.GetLogger(ctx).Debugf("[Postgres] Processing retrieval request of type: %s", params.RetrieverType)
@@ -218,6 +230,7 @@ func (g *pgRepository) KeywordsRetrieve(ctx context.Context,
 			"knowledge_id",
 			"knowledge_base_id",
 			"tag_id",
+			"chunk_type",
 		}).
 		Limit(int(params.TopK)).
 		Find(&embeddingDBList).Error
@@ -347,11 +360,11 @@ func (g *pgRepository) VectorRetrieve(ctx context.Context,
 
 	querySQL := fmt.Sprintf(`
 		SELECT 
-			id, content, source_id, source_type, chunk_id, knowledge_id, knowledge_base_id, tag_id,
+			id, content, source_id, source_type, chunk_id, knowledge_id, knowledge_base_id, tag_id, chunk_type,
 			(1 - distance) as score
 		FROM (
 			SELECT 
-				id, content, source_id, source_type, chunk_id, knowledge_id, knowledge_base_id, tag_id,
+				id, content, source_id, source_type, chunk_id, knowledge_id, knowledge_base_id, tag_id, chunk_type,
 				embedding::halfvec(%d) <=> $1::halfvec as distance
 			FROM embeddings
 			%s
@@ -494,11 +507,12 @@ func (g *pgRepository) CopyIndices(ctx context.Context,
 			// Create new vector index, copy the content and vector of the source index
 			targetVector := &pgVector{
 				Content:         sourceVector.Content,
-				SourceID:        targetSourceID,        // Handle SourceID transformation properly
+				SourceID:        targetSourceID, // Handle SourceID transformation properly
 				SourceType:      sourceVector.SourceType,
 				ChunkID:         targetChunkID,         // Update to target chunk ID
 				KnowledgeID:     targetKnowledgeID,     // Update to target knowledge ID
 				KnowledgeBaseID: targetKnowledgeBaseID, // Update to target knowledge base ID
+				ChunkType:       sourceVector.ChunkType,
 				Dimension:       sourceVector.Dimension,
 				Embedding:       sourceVector.Embedding, // Copy the vector embedding directly, avoid recalculation
 			}
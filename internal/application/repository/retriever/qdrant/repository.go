@@ -26,6 +26,7 @@ const (
 	fieldKnowledgeID      = "knowledge_id"
 	fieldKnowledgeBaseID  = "knowledge_base_id"
 	fieldTagID            = "tag_id"
+	fieldChunkType        = "chunk_type"
 	fieldEmbedding        = "embedding"
 	fieldIsEnabled        = "is_enabled"
 )
@@ -322,6 +323,31 @@ func (q *qdrantRepository) DeleteByKnowledgeIDList(ctx context.Context,
 	return nil
 }
 
+// DeleteByKnowledgeBaseID removes all points of a knowledge base from the collection
+func (q *qdrantRepository) DeleteByKnowledgeBaseID(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string,
+) error {
+	log := logger.GetLogger(ctx)
+	collectionName := q.getCollectionName(dimension)
+	log.Infof("[Qdrant] Deleting indices by knowledge base ID from %s: %s", collectionName, knowledgeBaseID)
+
+	_, err := q.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: collectionName,
+		Points: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: []*qdrant.Condition{
+				qdrant.NewMatch(fieldKnowledgeBaseID, knowledgeBaseID),
+			},
+		}),
+	})
+	if err != nil {
+		log.Errorf("[Qdrant] Failed to delete by knowledge base ID: %v", err)
+		return fmt.Errorf("failed to delete by knowledge base ID: %w", err)
+	}
+
+	log.Infof("[Qdrant] Successfully deleted documents by knowledge base ID")
+	return nil
+}
+
 // DeleteBySourceIDList removes points from the collection based on source IDs
 func (q *qdrantRepository) DeleteBySourceIDList(ctx context.Context,
 	sourceIDList []string, dimension int, knowledgeType string,
@@ -589,6 +615,7 @@ func (q *qdrantRepository) VectorRetrieve(ctx context.Context,
 				KnowledgeID:     payload[fieldKnowledgeID].GetStringValue(),
 				KnowledgeBaseID: payload[fieldKnowledgeBaseID].GetStringValue(),
 				TagID:           payload[fieldTagID].GetStringValue(),
+				ChunkType:       payload[fieldChunkType].GetStringValue(),
 			},
 			Score: float64(point.Score),
 		}
@@ -682,6 +709,7 @@ func (q *qdrantRepository) KeywordsRetrieve(ctx context.Context,
 					KnowledgeID:     payload[fieldKnowledgeID].GetStringValue(),
 					KnowledgeBaseID: payload[fieldKnowledgeBaseID].GetStringValue(),
 					TagID:           payload[fieldTagID].GetStringValue(),
+					ChunkType:       payload[fieldChunkType].GetStringValue(),
 				},
 				Score: 1.0,
 			}
@@ -804,6 +832,7 @@ func (q *qdrantRepository) CopyIndices(ctx context.Context,
 				fieldChunkID:         targetChunkID,
 				fieldKnowledgeID:     targetKnowledgeID,
 				fieldKnowledgeBaseID: targetKnowledgeBaseID,
+				fieldChunkType:       payload[fieldChunkType].GetStringValue(),
 				fieldIsEnabled:       true,
 			})
 
@@ -865,6 +894,7 @@ func createPayload(embedding *QdrantVectorEmbedding) map[string]*qdrant.Value {
 		fieldKnowledgeID:     embedding.KnowledgeID,
 		fieldKnowledgeBaseID: embedding.KnowledgeBaseID,
 		fieldTagID:           embedding.TagID,
+		fieldChunkType:       embedding.ChunkType,
 		fieldIsEnabled:       embedding.IsEnabled,
 	}
 	return qdrant.NewValueMap(payload)
@@ -923,6 +953,7 @@ func toQdrantVectorEmbedding(embedding *types.IndexInfo, additionalParams map[st
 		KnowledgeID:     embedding.KnowledgeID,
 		KnowledgeBaseID: embedding.KnowledgeBaseID,
 		TagID:           embedding.TagID,
+		ChunkType:       embedding.ChunkType,
 		IsEnabled:       true, // Default to enabled
 	}
 	if additionalParams != nil && slices.Contains(slices.Collect(maps.Keys(additionalParams)), fieldEmbedding) {
@@ -930,6 +961,14 @@ func toQdrantVectorEmbedding(embedding *types.IndexInfo, additionalParams map[st
 			vector.Embedding = embeddingMap[embedding.SourceID]
 		}
 	}
+	// Get is_enabled from additionalParams if available
+	if additionalParams != nil {
+		if chunkEnabledMap, ok := additionalParams["chunk_enabled"].(map[string]bool); ok {
+			if enabled, exists := chunkEnabledMap[embedding.ChunkID]; exists {
+				vector.IsEnabled = enabled
+			}
+		}
+	}
 	return vector
 }
 
@@ -946,6 +985,7 @@ func fromQdrantVectorEmbedding(id string,
 		KnowledgeID:     embedding.KnowledgeID,
 		KnowledgeBaseID: embedding.KnowledgeBaseID,
 		TagID:           embedding.TagID,
+		ChunkType:       embedding.ChunkType,
 		Content:         embedding.Content,
 		Score:           embedding.Score,
 		MatchType:       matchType,
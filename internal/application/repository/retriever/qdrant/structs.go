@@ -21,6 +21,7 @@ type QdrantVectorEmbedding struct {
 	KnowledgeID     string    `json:"knowledge_id"`
 	KnowledgeBaseID string    `json:"knowledge_base_id"`
 	TagID           string    `json:"tag_id"`
+	ChunkType       string    `json:"chunk_type"`
 	Embedding       []float32 `json:"embedding"`
 	IsEnabled       bool      `json:"is_enabled"`
 }
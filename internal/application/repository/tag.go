@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -219,14 +220,67 @@ func (r *knowledgeTagRepository) BatchCountReferences(
 	return result, nil
 }
 
+// unusedTagScope restricts a tag query to tags that are not referenced by any
+// knowledge or chunk (excluding soft-deleted records).
+func unusedTagScope(tenantID uint64, kbID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Where("id NOT IN (SELECT DISTINCT tag_id FROM knowledges WHERE tenant_id = ? AND knowledge_base_id = ? AND tag_id IS NOT NULL AND tag_id != '' AND deleted_at IS NULL)", tenantID, kbID).
+			Where("id NOT IN (SELECT DISTINCT tag_id FROM chunks WHERE tenant_id = ? AND knowledge_base_id = ? AND tag_id IS NOT NULL AND tag_id != '' AND deleted_at IS NULL)", tenantID, kbID)
+	}
+}
+
 // DeleteUnusedTags deletes tags that are not referenced by any knowledge or chunk.
 // Returns the number of deleted tags.
 func (r *knowledgeTagRepository) DeleteUnusedTags(ctx context.Context, tenantID uint64, kbID string) (int64, error) {
-	// Delete tags that have no references in both knowledges and chunks tables (excluding soft-deleted records)
 	result := r.db.WithContext(ctx).
 		Where("tenant_id = ? AND knowledge_base_id = ?", tenantID, kbID).
-		Where("id NOT IN (SELECT DISTINCT tag_id FROM knowledges WHERE tenant_id = ? AND knowledge_base_id = ? AND tag_id IS NOT NULL AND tag_id != '' AND deleted_at IS NULL)", tenantID, kbID).
-		Where("id NOT IN (SELECT DISTINCT tag_id FROM chunks WHERE tenant_id = ? AND knowledge_base_id = ? AND tag_id IS NOT NULL AND tag_id != '' AND deleted_at IS NULL)", tenantID, kbID).
+		Scopes(unusedTagScope(tenantID, kbID)).
 		Delete(&types.KnowledgeTag{})
 	return result.RowsAffected, result.Error
 }
+
+// DeleteIfUnused deletes the tag only if it is still not referenced by any knowledge or chunk.
+// The reference check and the delete run in a single statement, so content tagged after an
+// earlier CountReferences call keeps the tag alive. Returns whether the tag was deleted.
+func (r *knowledgeTagRepository) DeleteIfUnused(ctx context.Context, tenantID uint64, kbID string, id string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND knowledge_base_id = ? AND id = ?", tenantID, kbID, id).
+		Scopes(unusedTagScope(tenantID, kbID)).
+		Delete(&types.KnowledgeTag{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReassignReferences moves all knowledges and chunks of fromTagID to toTagID in one transaction.
+// Returns the IDs of the reassigned chunks so that callers can sync retriever indices.
+func (r *knowledgeTagRepository) ReassignReferences(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+	fromTagID string,
+	toTagID string,
+) ([]string, error) {
+	var chunkIDs []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&types.Knowledge{}).
+			Where("tenant_id = ? AND knowledge_base_id = ? AND tag_id = ?", tenantID, kbID, fromTagID).
+			Updates(map[string]interface{}{"tag_id": toTagID, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&types.Chunk{}).
+			Where("tenant_id = ? AND knowledge_base_id = ? AND tag_id = ?", tenantID, kbID, fromTagID).
+			Pluck("id", &chunkIDs).Error; err != nil {
+			return err
+		}
+		if len(chunkIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&types.Chunk{}).
+			Where("tenant_id = ? AND knowledge_base_id = ? AND id IN ?", tenantID, kbID, chunkIDs).
+			Updates(map[string]interface{}{"tag_id": toTagID, "updated_at": time.Now()}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chunkIDs, nil
+}
@@ -121,3 +121,27 @@ func (r *tenantRepository) AdjustStorageUsed(ctx context.Context, tenantID uint6
 		return tx.Save(&tenant).Error
 	})
 }
+
+// RecomputeStorageUsed resets the storage used of a tenant to the total storage size of its knowledge in a
+// single statement, so AdjustStorageUsed calls running at the same time are not overwritten by a stale sum
+func (r *tenantRepository) RecomputeStorageUsed(ctx context.Context, tenantID uint64) (int64, int64, error) {
+	var result struct {
+		Previous int64
+		Actual   int64
+	}
+	tx := r.db.WithContext(ctx).Raw(`
+		UPDATE tenants AS t SET storage_used = (
+			SELECT COALESCE(SUM(storage_size), 0) FROM knowledges
+			WHERE knowledges.tenant_id = t.id AND knowledges.deleted_at IS NULL
+		)
+		FROM tenants AS old
+		WHERE t.id = ? AND old.id = t.id
+		RETURNING old.storage_used AS previous, t.storage_used AS actual`, tenantID).Scan(&result)
+	if tx.Error != nil {
+		return 0, 0, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return 0, 0, ErrTenantNotFound
+	}
+	return result.Previous, result.Actual, nil
+}
@@ -197,18 +197,7 @@ func (s *ChunkExtractService) Handle(ctx context.Context, t *asynq.Task) error {
 		return err
 	}
 
-	template := &types.PromptTemplateStructured{
-		Description: s.template.Description,
-		Tags:        kb.ExtractConfig.Tags,
-		Examples: []types.GraphData{
-			{
-				Text:     kb.ExtractConfig.Text,
-				Node:     kb.ExtractConfig.Nodes,
-				Relation: kb.ExtractConfig.Relations,
-			},
-		},
-	}
-	extractor := chatpipline.NewExtractor(chatModel, template)
+	extractor := chatpipline.NewExtractor(chatModel, graphExtractTemplate(s.template, kb))
 	graph, err := extractor.Extract(ctx, chunk.Content)
 	if err != nil {
 		return err
@@ -220,17 +209,43 @@ func (s *ChunkExtractService) Handle(ctx context.Context, t *asynq.Task) error {
 		return nil
 	}
 
+	if err = addChunkGraph(ctx, s.graphEngine, chunk, graph); err != nil {
+		logger.Errorf(ctx, "failed to add graph: %v", err)
+		return err
+	}
+	return nil
+}
+
+// graphExtractTemplate builds the graph extraction prompt template of a knowledge base from the
+// configured base template and the knowledge base's extract config
+func graphExtractTemplate(base *types.PromptTemplateStructured,
+	kb *types.KnowledgeBase,
+) *types.PromptTemplateStructured {
+	return &types.PromptTemplateStructured{
+		Description: base.Description,
+		Tags:        kb.ExtractConfig.Tags,
+		Examples: []types.GraphData{
+			{
+				Text:     kb.ExtractConfig.Text,
+				Node:     kb.ExtractConfig.Nodes,
+				Relation: kb.ExtractConfig.Relations,
+			},
+		},
+	}
+}
+
+// addChunkGraph links the nodes of a graph extracted from a chunk to the chunk and saves the graph
+// under the chunk's knowledge
+func addChunkGraph(ctx context.Context, graphEngine interfaces.RetrieveGraphRepository,
+	chunk *types.Chunk, graph *types.GraphData,
+) error {
 	for _, node := range graph.Node {
 		node.Chunks = []string{chunk.ID}
 	}
-	if err = s.graphEngine.AddGraph(ctx,
+	return graphEngine.AddGraph(ctx,
 		types.NameSpace{KnowledgeBase: chunk.KnowledgeBaseID, Knowledge: chunk.KnowledgeID},
 		[]*types.GraphData{graph},
-	); err != nil {
-		logger.Errorf(ctx, "failed to add graph: %v", err)
-		return err
-	}
-	return nil
+	)
 }
 
 // DataTableExtractPayload represents the table extract task payload
@@ -487,6 +502,8 @@ func (s *DataTableSummaryService) indexToVectorDB(
 			ChunkID:         chunk.ID,
 			KnowledgeID:     chunk.KnowledgeID,
 			KnowledgeBaseID: chunk.KnowledgeBaseID,
+			ChunkType:       chunk.ChunkType,
+			IsEnabled:       chunk.IsEnabled,
 		})
 	}
 
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeKnowledgeRepo keeps knowledge and FAQ import archives in memory
+type fakeKnowledgeRepo struct {
+	interfaces.KnowledgeRepository
+	knowledge map[string]*types.Knowledge
+	archives  map[string]*types.FAQImportArchive
+	// created records the knowledge created through the repository
+	created     []*types.Knowledge
+	hashQueries int
+}
+
+// newFakeKnowledgeRepo returns a repository serving the given knowledge
+func newFakeKnowledgeRepo(knowledge ...*types.Knowledge) *fakeKnowledgeRepo {
+	r := &fakeKnowledgeRepo{
+		knowledge: make(map[string]*types.Knowledge, len(knowledge)),
+		archives:  map[string]*types.FAQImportArchive{},
+	}
+	for _, k := range knowledge {
+		r.knowledge[k.ID] = k
+	}
+	return r
+}
+
+// CreateKnowledge stores k, assigning an ID derived from the file name when k has none
+func (r *fakeKnowledgeRepo) CreateKnowledge(_ context.Context, k *types.Knowledge) error {
+	if k.ID == "" {
+		k.ID = "new-" + k.FileName
+	}
+	r.knowledge[k.ID] = k
+	r.created = append(r.created, k)
+	return nil
+}
+
+func (r *fakeKnowledgeRepo) UpdateKnowledge(_ context.Context, k *types.Knowledge) error {
+	r.knowledge[k.ID] = k
+	return nil
+}
+
+func (r *fakeKnowledgeRepo) UpdateKnowledgeColumn(_ context.Context, _ string, _ string, _ interface{}) error {
+	return nil
+}
+
+func (r *fakeKnowledgeRepo) GetKnowledgeByID(_ context.Context, _ uint64, id string) (*types.Knowledge, error) {
+	return r.knowledge[id], nil
+}
+
+func (r *fakeKnowledgeRepo) GetKnowledgeBatch(_ context.Context, tenantID uint64, ids []string) ([]*types.Knowledge, error) {
+	var result []*types.Knowledge
+	for _, k := range r.sorted() {
+		if k.TenantID == tenantID && slices.Contains(ids, k.ID) {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeKnowledgeRepo) ListKnowledgeByKnowledgeBaseID(_ context.Context,
+	_ uint64, kbID string,
+) ([]*types.Knowledge, error) {
+	var result []*types.Knowledge
+	for _, k := range r.sorted() {
+		if k.KnowledgeBaseID == kbID {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeKnowledgeRepo) ListKnowledgeByFileHashes(_ context.Context, _ uint64, _ string, hashes []string,
+) ([]*types.Knowledge, error) {
+	r.hashQueries++
+	var result []*types.Knowledge
+	for _, k := range r.sorted() {
+		if slices.Contains(hashes, k.FileHash) {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeKnowledgeRepo) ListFileNamesByPrefix(_ context.Context, _ uint64, kbID string, prefix string) ([]string, error) {
+	var names []string
+	for _, k := range r.sorted() {
+		if k.KnowledgeBaseID == kbID && strings.HasPrefix(k.FileName, prefix) {
+			names = append(names, k.FileName)
+		}
+	}
+	return names, nil
+}
+
+// faqImportArchiveTaskIDSize is the size of faq_import_archives.task_id
+const faqImportArchiveTaskIDSize = 255
+
+// SaveFAQImportArchive stores archive, enforcing the task_id column size
+func (r *fakeKnowledgeRepo) SaveFAQImportArchive(_ context.Context, archive *types.FAQImportArchive) error {
+	if len(archive.TaskID) > faqImportArchiveTaskIDSize {
+		return fmt.Errorf("value too long for type character varying(%d)", faqImportArchiveTaskIDSize)
+	}
+	r.archives[archive.TaskID] = archive
+	return nil
+}
+
+func (r *fakeKnowledgeRepo) GetFAQImportArchive(_ context.Context, taskID string) (*types.FAQImportArchive, error) {
+	return r.archives[taskID], nil
+}
+
+// sorted returns the knowledge ordered by ID
+func (r *fakeKnowledgeRepo) sorted() []*types.Knowledge {
+	list := make([]*types.Knowledge, 0, len(r.knowledge))
+	for _, k := range r.knowledge {
+		list = append(list, k)
+	}
+	slices.SortFunc(list, func(a, b *types.Knowledge) int { return strings.Compare(a.ID, b.ID) })
+	return list
+}
+
+// fakeChunkRepo serves chunks from memory and records the chunks written through it
+type fakeChunkRepo struct {
+	interfaces.ChunkRepository
+	chunks []*types.Chunk
+	// saved holds the similar questions of every chunk passed to SaveChunks
+	saved   [][]string
+	updated int
+	// pages counts the FAQ export pages served, onPage runs before each of them,
+	// e.g. to edit entries during the export
+	pages  int
+	onPage func(page int)
+}
+
+func (r *fakeChunkRepo) CreateChunks(_ context.Context, chunks []*types.Chunk) error {
+	r.chunks = append(r.chunks, chunks...)
+	return nil
+}
+
+func (r *fakeChunkRepo) UpdateChunks(_ context.Context, chunks []*types.Chunk) error {
+	r.updated += len(chunks)
+	return nil
+}
+
+// SaveChunks records the similar questions of every saved chunk
+func (r *fakeChunkRepo) SaveChunks(_ context.Context, chunks []*types.Chunk) error {
+	for _, chunk := range chunks {
+		meta, err := chunk.FAQMetadata()
+		if err != nil {
+			return err
+		}
+		r.saved = append(r.saved, meta.SimilarQuestions)
+	}
+	return nil
+}
+
+func (r *fakeChunkRepo) GetChunkBySeqID(_ context.Context, _ uint64, seqID int64) (*types.Chunk, error) {
+	for _, chunk := range r.chunks {
+		if chunk.SeqID == seqID {
+			return chunk, nil
+		}
+	}
+	return nil, errors.New("chunk not found")
+}
+
+func (r *fakeChunkRepo) ListChunksBySeqID(_ context.Context, _ uint64, seqIDs []int64) ([]*types.Chunk, error) {
+	var result []*types.Chunk
+	for _, chunk := range r.chunks {
+		if slices.Contains(seqIDs, chunk.SeqID) {
+			result = append(result, chunk)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeChunkRepo) ListChunksByID(_ context.Context, tenantID uint64, ids []string) ([]*types.Chunk, error) {
+	var result []*types.Chunk
+	for _, chunk := range r.chunks {
+		if chunk.TenantID == tenantID && slices.Contains(ids, chunk.ID) {
+			result = append(result, chunk)
+		}
+	}
+	return result, nil
+}
+
+// ListChunksByIDOnly serves chunks by ID across tenants
+func (r *fakeChunkRepo) ListChunksByIDOnly(_ context.Context, ids []string) ([]*types.Chunk, error) {
+	var result []*types.Chunk
+	for _, chunk := range r.chunks {
+		if slices.Contains(ids, chunk.ID) {
+			result = append(result, chunk)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeChunkRepo) ListChunksByKnowledgeID(_ context.Context, _ uint64, knowledgeID string) ([]*types.Chunk, error) {
+	var result []*types.Chunk
+	for _, chunk := range r.chunks {
+		if chunk.KnowledgeID == knowledgeID {
+			result = append(result, chunk)
+		}
+	}
+	return result, nil
+}
+
+// ListPagedChunksByKnowledgeID pages the chunks of a knowledge, matching keyword against the
+// standard question of FAQ chunks and the content of other chunks
+func (r *fakeChunkRepo) ListPagedChunksByKnowledgeID(_ context.Context, _ uint64, knowledgeID string,
+	page *types.Pagination, _ []types.ChunkType, _ string, keyword string, _, _, _ string,
+) ([]*types.Chunk, int64, error) {
+	var result []*types.Chunk
+	for _, chunk := range r.chunks {
+		if chunk.KnowledgeID != knowledgeID {
+			continue
+		}
+		text := chunk.Content
+		if meta, _ := chunk.FAQMetadata(); meta != nil {
+			text = meta.StandardQuestion
+		}
+		if strings.Contains(text, keyword) {
+			result = append(result, chunk)
+		}
+	}
+	return pageSlice(result, page), int64(len(result)), nil
+}
+
+func (r *fakeChunkRepo) ListAllFAQChunksWithMetadataByKnowledgeBaseID(
+	_ context.Context, _ uint64, _ string,
+) ([]*types.Chunk, error) {
+	return r.chunks, nil
+}
+
+func (r *fakeChunkRepo) GetFAQChunkByStandardQuestion(
+	_ context.Context, _ uint64, _ string, question string,
+) (*types.Chunk, error) {
+	for _, chunk := range r.chunks {
+		meta, err := chunk.FAQMetadata()
+		if err != nil {
+			return nil, err
+		}
+		if meta != nil && meta.StandardQuestion == question {
+			return chunk, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListFAQChunksForExport pages indexed FAQ chunks by (created_at, id) like the export query
+func (r *fakeChunkRepo) ListFAQChunksForExport(_ context.Context, _ uint64, _ string,
+	afterCreatedAt time.Time, afterID string, limit int,
+) ([]*types.Chunk, error) {
+	r.pages++
+	if r.onPage != nil {
+		r.onPage(r.pages)
+	}
+	sorted := slices.Clone(r.chunks)
+	slices.SortFunc(sorted, func(a, b *types.Chunk) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	var page []*types.Chunk
+	for _, chunk := range sorted {
+		if chunk.Status != int(types.ChunkStatusIndexed) {
+			continue
+		}
+		if afterID != "" && (chunk.CreatedAt.Before(afterCreatedAt) ||
+			chunk.CreatedAt.Equal(afterCreatedAt) && chunk.ID <= afterID) {
+			continue
+		}
+		page = append(page, chunk)
+		if len(page) == limit {
+			break
+		}
+	}
+	return page, nil
+}
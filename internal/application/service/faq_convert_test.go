@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+)
+
+// fakeFAQConvertChatModel extracts one Q/A pair from every chunk, and fails for content containing "fail"
+type fakeFAQConvertChatModel struct {
+	fakeFailingChatModel
+	calls atomic.Int32
+}
+
+func (m *fakeFAQConvertChatModel) Chat(_ context.Context, messages []chat.Message, _ *chat.ChatOptions) (*types.ChatResponse, error) {
+	m.calls.Add(1)
+	content := messages[len(messages)-1].Content
+	if strings.Contains(content, "fail") {
+		return nil, errors.New("model unavailable")
+	}
+	if strings.Contains(content, "退款") {
+		return &types.ChatResponse{
+			Content: `[{"question": "如何退款？", "similar_questions": ["怎么退款"], "answer": "在订单页申请", "confidence": 0.9}]`,
+		}, nil
+	}
+	return &types.ChatResponse{
+		Content: `[{"question": "如何开票？", "answer": "联系客服", "confidence": 0.7}]`,
+	}, nil
+}
+
+type fakeFAQConvertModelService struct {
+	interfaces.ModelService
+	chatModel *fakeFAQConvertChatModel
+}
+
+func (s *fakeFAQConvertModelService) GetChatModel(_ context.Context, _ string) (chat.Chat, error) {
+	return s.chatModel, nil
+}
+
+func TestFAQConversionCommitsReviewedEntries(t *testing.T) {
+	svc, _ := newFAQImportTestService(t, nil)
+	redisClient, store := newFakeRedisClient()
+	svc.redisClient = redisClient
+	source := &types.Knowledge{
+		ID: "doc-1", TenantID: 1, KnowledgeBaseID: "kb-doc", Type: "file", Title: "售后手册",
+		ParseStatus: types.ParseStatusCompleted,
+	}
+	svc.repo.(*fakeKnowledgeRepo).knowledge[source.ID] = source
+	svc.chunkService = &fakeProcessChunkService{chunks: []*types.Chunk{
+		{ID: "c2", KnowledgeID: "doc-1", ChunkIndex: 1, ChunkType: types.ChunkTypeText, Content: "开票说明"},
+		{ID: "c1", KnowledgeID: "doc-1", ChunkIndex: 0, ChunkType: types.ChunkTypeText, Content: "退款流程"},
+		{ID: "c3", KnowledgeID: "doc-1", ChunkIndex: 2, ChunkType: types.ChunkTypeText, Content: "fail"},
+		{ID: "c4", KnowledgeID: "doc-1", ChunkIndex: 3, ChunkType: types.ChunkTypeImageOCR, Content: "退款"},
+	}}
+	chatModel := &fakeFAQConvertChatModel{}
+	svc.modelService = &fakeFAQConvertModelService{chatModel: chatModel}
+	svc.task = asynq.NewClient(asynq.RedisClientOpt{Addr: "127.0.0.1:1"})
+	defer svc.task.Close()
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, fakeRetrieveEngineTenant(1))
+
+	payload, _ := json.Marshal(types.FAQConvertPayload{
+		TenantID: 1, TaskID: "convert-1", KBID: "kb1",
+		SourceTenantID: 1, SourceKnowledgeID: source.ID, ChatModelID: "chat-model", ChatModelTenantID: 1,
+	})
+	if err := svc.ProcessFAQConversion(context.Background(), asynq.NewTask(types.TypeFAQConvert, payload)); err != nil {
+		t.Fatalf("ProcessFAQConversion() error = %v", err)
+	}
+	progress, err := svc.GetFAQConversionProgress(ctx, "convert-1")
+	if err != nil {
+		t.Fatalf("GetFAQConversionProgress() error = %v", err)
+	}
+	if progress.Status != types.KnowledgeTaskStatusCompleted || progress.Total != 3 ||
+		progress.Processed != 3 || progress.Failed != 1 || len(progress.Entries) != 2 {
+		t.Fatalf("unexpected progress %+v", progress)
+	}
+	if progress.Entries[0].StandardQuestion != "如何退款？" || progress.Entries[0].SourceChunkID != "c1" {
+		t.Fatalf("expected entries in document order, got %+v", progress.Entries)
+	}
+	extractCalls := chatModel.calls.Load()
+
+	// The task belongs to the FAQ knowledge base it was started for, and must have completed
+	if _, err := svc.CommitFAQConversion(ctx, "kb-other", "convert-1", nil, false); err == nil {
+		t.Fatal("expected committing to another knowledge base to fail")
+	}
+
+	// The reviewed entries are imported as they are, without extracting again
+	reviewed := []types.FAQEntryPayload{{StandardQuestion: "如何申请退款？", Answers: []string{"在订单页申请"}}}
+	_, err = svc.CommitFAQConversion(ctx, "kb1", "convert-1", reviewed, true)
+	if err == nil || !strings.Contains(err.Error(), "enqueue") {
+		t.Fatalf("expected the import to reach the task queue, got %v", err)
+	}
+	if got := chatModel.calls.Load(); got != extractCalls {
+		t.Fatalf("expected commit not to call the chat model, got %d more calls", got-extractCalls)
+	}
+	var imported []types.FAQImportProgress
+	for key, value := range store.values {
+		if strings.HasPrefix(key, faqImportProgressKeyPrefix) {
+			var p types.FAQImportProgress
+			if err := json.Unmarshal([]byte(value), &p); err != nil {
+				t.Fatalf("unmarshal import progress: %v", err)
+			}
+			imported = append(imported, p)
+		}
+	}
+	if len(imported) != 1 || imported[0].Total != len(reviewed) || !imported[0].DryRun {
+		t.Fatalf("expected one dry run import of the reviewed entries, got %+v", imported)
+	}
+
+	progress.Status = types.KnowledgeTaskStatusProcessing
+	if err := svc.saveFAQConversionProgress(ctx, progress); err != nil {
+		t.Fatalf("saveFAQConversionProgress() error = %v", err)
+	}
+	if _, err := svc.CommitFAQConversion(ctx, "kb1", "convert-1", nil, false); err == nil {
+		t.Fatal("expected committing an unfinished conversion to fail")
+	}
+}
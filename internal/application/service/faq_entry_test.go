@@ -0,0 +1,1065 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/tracing"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+// roundTripFAQEntry creates an entry from payload in an FAQ knowledge base with faqConfig through
+// CreateFAQEntry and reads it back with GetFAQEntry
+func roundTripFAQEntry(t *testing.T, faqConfig *types.FAQConfig, payload *types.FAQEntryPayload) *types.FAQEntry {
+	t.Helper()
+	svc, chunkService := newFAQImportTestService(t, faqConfig)
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, fakeRetrieveEngineTenant(1))
+
+	created, err := svc.CreateFAQEntry(ctx, "kb1", payload)
+	if err != nil {
+		t.Fatalf("CreateFAQEntry() error = %v", err)
+	}
+	svc.chunkRepo.(*fakeChunkRepo).chunks = chunkService.created
+
+	entry, err := svc.GetFAQEntry(ctx, "kb1", created.ID)
+	if err != nil {
+		t.Fatalf("GetFAQEntry() error = %v", err)
+	}
+	return entry
+}
+
+func TestFAQEntryAnswerOrderRoundTrip(t *testing.T) {
+	payload := func() *types.FAQEntryPayload {
+		return &types.FAQEntryPayload{
+			StandardQuestion: "如何重置密码",
+			SimilarQuestions: []string{"忘记密码怎么办", "密码找回", "忘记密码怎么办"},
+			Answers:          []string{"第三步：设置新密码", "第一步：打开设置", " 第二步：验证手机 ", "第一步：打开设置"},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		answerOrder     types.FAQAnswerOrder
+		expectedAnswers []string
+		expectedSimilar []string
+	}{
+		{
+			name:            "preserve order by default",
+			answerOrder:     "",
+			expectedAnswers: []string{"第三步：设置新密码", "第一步：打开设置", "第二步：验证手机"},
+			expectedSimilar: []string{"忘记密码怎么办", "密码找回"},
+		},
+		{
+			name:            "explicit preserve order",
+			answerOrder:     types.FAQAnswerOrderPreserve,
+			expectedAnswers: []string{"第三步：设置新密码", "第一步：打开设置", "第二步：验证手机"},
+			expectedSimilar: []string{"忘记密码怎么办", "密码找回"},
+		},
+		{
+			name:            "sorted order",
+			answerOrder:     types.FAQAnswerOrderSorted,
+			expectedAnswers: []string{"第一步：打开设置", "第三步：设置新密码", "第二步：验证手机"},
+			expectedSimilar: []string{"密码找回", "忘记密码怎么办"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := roundTripFAQEntry(t, &types.FAQConfig{AnswerOrder: tt.answerOrder}, payload())
+			if !slices.Equal(entry.Answers, tt.expectedAnswers) {
+				t.Errorf("answers = %v, want %v", entry.Answers, tt.expectedAnswers)
+			}
+			if !slices.Equal(entry.SimilarQuestions, tt.expectedSimilar) {
+				t.Errorf("similar questions = %v, want %v", entry.SimilarQuestions, tt.expectedSimilar)
+			}
+		})
+	}
+}
+
+func TestFAQContentHashIgnoresAnswerOrder(t *testing.T) {
+	a := &types.FAQChunkMetadata{StandardQuestion: "q", Answers: []string{"a1", "a2"}}
+	b := &types.FAQChunkMetadata{StandardQuestion: "q", Answers: []string{"a2", "a1"}}
+	if types.CalculateFAQContentHash(a) != types.CalculateFAQContentHash(b) {
+		t.Errorf("expected content hash to be independent of answer order")
+	}
+}
+
+func TestSameFAQIndexContentIgnoresTagAndStatus(t *testing.T) {
+	kb := &types.KnowledgeBase{Type: types.KnowledgeBaseTypeFAQ, FAQConfig: &types.FAQConfig{}}
+	s := &knowledgeService{}
+	build := func(meta *types.FAQChunkMetadata, tagID string, enabled bool) []*types.IndexInfo {
+		chunk := &types.Chunk{ID: "chunk-1", ChunkType: types.ChunkTypeFAQ, TagID: tagID, IsEnabled: enabled}
+		if err := chunk.SetFAQMetadata(meta); err != nil {
+			t.Fatalf("SetFAQMetadata() error = %v", err)
+		}
+		infos, err := s.buildFAQIndexInfoList(context.Background(), kb, chunk)
+		if err != nil {
+			t.Fatalf("buildFAQIndexInfoList() error = %v", err)
+		}
+		return infos
+	}
+	meta := &types.FAQChunkMetadata{StandardQuestion: "q", SimilarQuestions: []string{"s"}, Answers: []string{"a"}}
+
+	for _, mode := range []types.FAQQuestionIndexMode{
+		types.FAQQuestionIndexModeCombined, types.FAQQuestionIndexModeSeparate, types.FAQQuestionIndexModeHybrid,
+	} {
+		kb.FAQConfig.QuestionIndexMode = mode
+		old := build(meta, "", true)
+		if !sameFAQIndexContent(old, build(meta, "tag-1", false)) {
+			t.Errorf("%s: tag/status change should not require re-index", mode)
+		}
+		changed := &types.FAQChunkMetadata{StandardQuestion: "q", SimilarQuestions: []string{"s"}, Answers: []string{"b"}}
+		if sameFAQIndexContent(old, build(changed, "", true)) {
+			t.Errorf("%s: answer change should require re-index", mode)
+		}
+	}
+}
+
+func TestBuildFAQIndexInfoListHybridMode(t *testing.T) {
+	kb := &types.KnowledgeBase{Type: types.KnowledgeBaseTypeFAQ, FAQConfig: &types.FAQConfig{
+		IndexMode:         types.FAQIndexModeQuestionOnly,
+		QuestionIndexMode: types.FAQQuestionIndexModeHybrid,
+	}}
+	s := &knowledgeService{}
+	chunk := &types.Chunk{ID: "chunk-1", ChunkType: types.ChunkTypeFAQ}
+	meta := &types.FAQChunkMetadata{StandardQuestion: "q", SimilarQuestions: []string{"s1", "s2"}, Answers: []string{"a"}}
+	if err := chunk.SetFAQMetadata(meta); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+
+	infos, err := s.buildFAQIndexInfoList(context.Background(), kb, chunk)
+	if err != nil {
+		t.Fatalf("buildFAQIndexInfoList() error = %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("len(infos) = %d, want 2", len(infos))
+	}
+	if infos[0].SourceID != "chunk-1" || infos[0].Content != "q" {
+		t.Errorf("standard entry = {%s %q}, want {chunk-1 \"q\"}", infos[0].SourceID, infos[0].Content)
+	}
+	if infos[1].SourceID != "chunk-1-similar" || infos[1].Content != "s1\ns2" {
+		t.Errorf("similar entry = {%s %q}, want {chunk-1-similar \"s1\\ns2\"}", infos[1].SourceID, infos[1].Content)
+	}
+
+	meta.SimilarQuestions = nil
+	if err := chunk.SetFAQMetadata(meta); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+	infos, err = s.buildFAQIndexInfoList(context.Background(), kb, chunk)
+	if err != nil {
+		t.Fatalf("buildFAQIndexInfoList() error = %v", err)
+	}
+	if len(infos) != 1 {
+		t.Errorf("without similar questions len(infos) = %d, want 1", len(infos))
+	}
+}
+
+func TestBuildFAQIndexInfoListSeparateModeLimit(t *testing.T) {
+	kb := &types.KnowledgeBase{Type: types.KnowledgeBaseTypeFAQ, FAQConfig: &types.FAQConfig{
+		IndexMode:                  types.FAQIndexModeQuestionOnly,
+		QuestionIndexMode:          types.FAQQuestionIndexModeSeparate,
+		MaxIndexedSimilarQuestions: 2,
+	}}
+	s := &knowledgeService{}
+	chunk := &types.Chunk{ID: "chunk-1", ChunkType: types.ChunkTypeFAQ}
+	meta := &types.FAQChunkMetadata{
+		StandardQuestion: "q",
+		SimilarQuestions: []string{"s1", " S1 ", "s2", "s3"},
+		Answers:          []string{"a"},
+	}
+	if err := chunk.SetFAQMetadata(meta); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+
+	infos, err := s.buildFAQIndexInfoList(context.Background(), kb, chunk)
+	if err != nil {
+		t.Fatalf("buildFAQIndexInfoList() error = %v", err)
+	}
+	// The repeated " S1 " is skipped, positions are kept so source IDs still map to SimilarQuestions
+	var sourceIDs []string
+	for _, info := range infos {
+		sourceIDs = append(sourceIDs, info.SourceID)
+	}
+	if got := strings.Join(sourceIDs, ","); got != "chunk-1,chunk-1-0,chunk-1-2" {
+		t.Fatalf("source IDs = %s, want chunk-1,chunk-1-0,chunk-1-2", got)
+	}
+
+	entry, err := s.chunkToFAQEntry(chunk, kb, nil)
+	if err != nil {
+		t.Fatalf("chunkToFAQEntry() error = %v", err)
+	}
+	if entry.UnindexedSimilarQuestions != 2 {
+		t.Fatalf("UnindexedSimilarQuestions = %d, want 2", entry.UnindexedSimilarQuestions)
+	}
+	kb.FAQConfig.QuestionIndexMode = types.FAQQuestionIndexModeCombined
+	if got := faqUnindexedSimilarQuestionCount(kb, meta.SimilarQuestions); got != 0 {
+		t.Fatalf("combined mode: unindexed = %d, want 0", got)
+	}
+
+	kb.FAQConfig.MaxIndexedSimilarQuestions = 0
+	if positions, truncated := faqIndexedSimilarQuestionPositions(kb, meta.SimilarQuestions); len(positions) != 4 || truncated != 0 {
+		t.Fatalf("unlimited: positions = %v, truncated = %d", positions, truncated)
+	}
+}
+
+func TestFAQEntrySelectAnswers(t *testing.T) {
+	entry := &types.FAQEntry{ID: 7, Answers: []string{"a", "b", "c"}, AnswerStrategy: types.AnswerStrategyAll}
+	if got := entry.SelectAnswers(nil, 0); len(got) != 3 {
+		t.Fatalf("all strategy: expected 3 answers, got %v", got)
+	}
+	if got := entry.SelectAnswers(nil, 2); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("all strategy with limit: expected [a b], got %v", got)
+	}
+	capped := newFAQSearchResult(&types.FAQSearchRequest{ResponseMode: types.FAQSearchResponseModeAnswers, MaxAnswers: 2},
+		[]*types.FAQEntry{entry}, false)
+	if got := capped.Answers[0]; len(got.Answers) != 2 || got.TotalAnswers != 3 || !got.HasMoreAnswers {
+		t.Fatalf("unexpected capped answers: %+v", got)
+	}
+	if len(capped.Entries[0].Answers) != 3 {
+		t.Fatal("entries must keep all answers")
+	}
+
+	entry.AnswerStrategy = types.AnswerStrategyRandom
+	seed := int64(42)
+	first := entry.SelectAnswers(&seed, 2)
+	if len(first) != 1 {
+		t.Fatalf("random strategy: expected 1 answer, got %v", first)
+	}
+	for i := 0; i < 5; i++ {
+		if got := entry.SelectAnswers(&seed, 0); got[0] != first[0] {
+			t.Fatalf("seeded selection not reproducible: %v vs %v", got, first)
+		}
+	}
+
+	req := &types.FAQSearchRequest{ResponseMode: types.FAQSearchResponseModeAnswers, AnswerSeed: &seed}
+	entry.Score = 0.9
+	result := newFAQSearchResult(req, []*types.FAQEntry{entry}, false)
+	if len(result.Answers) != 1 || result.Answers[0].Score != 0.9 || result.Answers[0].Answers[0] != first[0] ||
+		result.Answers[0].HasMoreAnswers {
+		t.Fatalf("unexpected answers mode result: %+v", result.Answers)
+	}
+	if result := newFAQSearchResult(&types.FAQSearchRequest{}, []*types.FAQEntry{entry}, false); result.Answers != nil {
+		t.Fatal("entries mode must not populate answers")
+	}
+}
+
+func TestFAQEntryContentFormat(t *testing.T) {
+	kb := &types.KnowledgeBase{
+		ID:        "kb-1",
+		Type:      types.KnowledgeBaseTypeFAQ,
+		FAQConfig: &types.FAQConfig{IndexMode: types.FAQIndexModeQuestionOnly, AnswerContentFormat: types.AnswerContentFormatMarkdown},
+	}
+
+	entry := roundTripFAQEntry(t, kb.FAQConfig, &types.FAQEntryPayload{StandardQuestion: "q", Answers: []string{"**a**"}})
+	if entry.ContentFormat != types.AnswerContentFormatMarkdown {
+		t.Fatalf("expected KB default format markdown, got %q", entry.ContentFormat)
+	}
+
+	html := types.AnswerContentFormat("HTML")
+	entry = roundTripFAQEntry(t, kb.FAQConfig, &types.FAQEntryPayload{StandardQuestion: "q", Answers: []string{"<b>a</b>"}, ContentFormat: &html})
+	if entry.ContentFormat != types.AnswerContentFormatHTML {
+		t.Fatalf("expected entry format html, got %q", entry.ContentFormat)
+	}
+
+	invalid := types.AnswerContentFormat("rtf")
+	if _, err := sanitizeFAQEntryPayload(&types.FAQEntryPayload{StandardQuestion: "q", Answers: []string{"a"}, ContentFormat: &invalid}, false, nil); err == nil {
+		t.Fatal("expected invalid content format to be rejected")
+	}
+}
+
+func TestFAQImportAllowEmptyAnswers(t *testing.T) {
+	entry := types.FAQEntryPayload{StandardQuestion: "发票如何开具？", Answers: []string{" "}}
+	if err := validateFAQEntryPayloadBasic(&entry, false, nil); err == nil {
+		t.Fatal("expected entry without answers to be rejected by default")
+	}
+	if err := validateFAQEntryPayloadBasic(&entry, true, nil); err != nil {
+		t.Fatalf("expected entry without answers to be accepted, got %v", err)
+	}
+	if _, err := sanitizeFAQEntryPayload(&entry, false, nil); err == nil {
+		t.Fatal("expected sanitize to reject entry without answers by default")
+	}
+
+	meta, err := sanitizeFAQEntryPayload(&entry, true, nil)
+	if err != nil {
+		t.Fatalf("sanitizeFAQEntryPayload() error = %v", err)
+	}
+	chunk := &types.Chunk{ID: "chunk-1", ChunkType: types.ChunkTypeFAQ}
+	if err := chunk.SetFAQMetadata(meta); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+	kb := &types.KnowledgeBase{ID: "kb-1", Type: types.KnowledgeBaseTypeFAQ}
+	kb.EnsureDefaults()
+	pending, err := (&knowledgeService{}).chunkToFAQEntry(chunk, kb, nil)
+	if err != nil {
+		t.Fatalf("chunkToFAQEntry() error = %v", err)
+	}
+	if !pending.AnswerPending || len(pending.Answers) != 0 {
+		t.Fatalf("expected entry to be answer pending, got %+v", pending)
+	}
+
+	// Completing the answer through a regular update clears the flag
+	completed := roundTripFAQEntry(t, kb.FAQConfig, &types.FAQEntryPayload{StandardQuestion: entry.StandardQuestion, Answers: []string{"在订单页申请"}})
+	if completed.AnswerPending {
+		t.Fatal("expected entry with answers not to be answer pending")
+	}
+}
+
+func TestFAQLongStandardQuestion(t *testing.T) {
+	question := "我的订单一直没有发货。已经过去一周了，客服也联系不上！请问怎么申请退款？"
+	cfg := &types.FAQConfig{MaxStandardQuestionLength: 20}
+
+	entry := types.FAQEntryPayload{StandardQuestion: question, SimilarQuestions: []string{"如何退款"}, Answers: []string{"在订单页申请"}}
+	if _, err := sanitizeFAQEntryPayload(&entry, false, cfg); err == nil {
+		t.Fatal("expected overly long standard question to be rejected by default")
+	}
+	if err := validateFAQEntryPayloadBasic(&entry, false, cfg); err == nil {
+		t.Fatal("expected import validation to reject overly long standard question")
+	}
+	if _, err := sanitizeFAQEntryPayload(&entry, false, nil); err != nil {
+		t.Fatalf("expected default limit to accept the question, got %v", err)
+	}
+
+	cfg.LongQuestionStrategy = types.FAQLongQuestionStrategySplit
+	meta, err := sanitizeFAQEntryPayload(&entry, false, cfg)
+	if err != nil {
+		t.Fatalf("sanitizeFAQEntryPayload() error = %v", err)
+	}
+	want := []string{"已经过去一周了，客服也联系不上！", "请问怎么申请退款？", "如何退款"}
+	if meta.StandardQuestion != "我的订单一直没有发货。" || !slices.Equal(meta.SimilarQuestions, want) {
+		t.Fatalf("unexpected split result %q %q", meta.StandardQuestion, meta.SimilarQuestions)
+	}
+
+	// A single sentence above the limit cannot be split
+	entry.StandardQuestion = strings.Repeat("退款", 11)
+	if _, err := sanitizeFAQEntryPayload(&entry, false, cfg); err == nil {
+		t.Fatal("expected unsplittable question to be rejected")
+	}
+}
+
+func TestFAQDuplicateScopePerTag(t *testing.T) {
+	existing := &types.Chunk{ID: "chunk-1", TagID: "tag-a", ChunkType: types.ChunkTypeFAQ}
+	if err := existing.SetFAQMetadata(&types.FAQChunkMetadata{StandardQuestion: "如何退款", Answers: []string{"a"}}); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+	chunks := []*types.Chunk{existing}
+	meta := &types.FAQChunkMetadata{StandardQuestion: "如何退款", Answers: []string{"b"}}
+
+	kb := &types.KnowledgeBase{ID: "kb-1", FAQConfig: &types.FAQConfig{}}
+	if err := checkFAQQuestionDuplicateInChunks(chunks, "", newFAQDuplicateScope(kb, "tag-b"), meta); err == nil {
+		t.Fatal("expected duplicate across tags to be rejected with knowledge base scope")
+	}
+
+	kb.FAQConfig.DuplicateScope = types.FAQDuplicateScopeTag
+	if err := checkFAQQuestionDuplicateInChunks(chunks, "", newFAQDuplicateScope(kb, "tag-b"), meta); err != nil {
+		t.Fatalf("expected same question under another tag to be accepted, got %v", err)
+	}
+	if err := checkFAQQuestionDuplicateInChunks(chunks, "", newFAQDuplicateScope(kb, "tag-a"), meta); err == nil {
+		t.Fatal("expected duplicate within the same tag to be rejected")
+	}
+
+	// Import validation honors the same scope: tag-b exists, tag-c will be created on import
+	svc := &knowledgeService{
+		chunkRepo: &fakeChunkRepo{chunks: chunks},
+		tagRepo: &fakeTagRepo{tags: map[string]*types.KnowledgeTag{
+			"tag-a": {ID: "tag-a", KnowledgeBaseID: kb.ID, Name: "售后"},
+			"tag-b": {ID: "tag-b", KnowledgeBaseID: kb.ID, Name: "售前"},
+		}},
+	}
+	entries := []types.FAQEntryPayload{
+		{StandardQuestion: "如何退款", Answers: []string{"a"}, TagName: "售后"},
+		{StandardQuestion: "如何退款", Answers: []string{"b"}, TagName: "售前"},
+		{StandardQuestion: "如何退款", Answers: []string{"c"}, TagName: "新标签"},
+		{StandardQuestion: "如何退款", Answers: []string{"d"}, TagName: "新标签"},
+	}
+	toProcess, skipped, err := svc.calculateAppendOperations(context.Background(), 1, kb, entries, false)
+	if err != nil {
+		t.Fatalf("calculateAppendOperations() error = %v", err)
+	}
+	if len(toProcess) != 2 || toProcess[0].TagName != "售前" || toProcess[1].TagName != "新标签" {
+		t.Fatalf("expected entries under 售前 and 新标签 to be imported, got %+v", toProcess)
+	}
+	if len(skipped) != 2 || skipped[0].Index != 0 || skipped[1].Index != 3 {
+		t.Fatalf("expected entries 0 and 3 to be skipped, got %+v", skipped)
+	}
+}
+
+func TestFAQTagMoveDuplicate(t *testing.T) {
+	newChunk := func(id, tagID, question string) *types.Chunk {
+		chunk := &types.Chunk{ID: id, TagID: tagID, ChunkType: types.ChunkTypeFAQ}
+		if err := chunk.SetFAQMetadata(&types.FAQChunkMetadata{StandardQuestion: question, Answers: []string{"a"}}); err != nil {
+			t.Fatalf("SetFAQMetadata() error = %v", err)
+		}
+		return chunk
+	}
+	svc := &knowledgeService{chunkRepo: &fakeChunkRepo{chunks: []*types.Chunk{
+		newChunk("chunk-1", "tag-a", "如何退款"),
+		newChunk("chunk-2", "tag-b", "如何退款"),
+		newChunk("chunk-3", "tag-c", "如何退款"),
+		newChunk("chunk-4", "tag-c", "如何换货"),
+	}}}
+	moveTo := func(moves map[string]string) func(*types.Chunk) (string, bool) {
+		return func(chunk *types.Chunk) (string, bool) {
+			tagID, ok := moves[chunk.ID]
+			return tagID, ok
+		}
+	}
+	ctx := context.Background()
+
+	kb := &types.KnowledgeBase{ID: "kb-1", FAQConfig: &types.FAQConfig{}}
+	if err := svc.checkFAQTagMoveDuplicate(ctx, 1, kb, moveTo(map[string]string{"chunk-2": "tag-a"})); err != nil {
+		t.Fatalf("expected moves to be ignored with knowledge base scope, got %v", err)
+	}
+
+	kb.FAQConfig.DuplicateScope = types.FAQDuplicateScopeTag
+	if err := svc.checkFAQTagMoveDuplicate(ctx, 1, kb, moveTo(map[string]string{"chunk-2": "tag-a"})); err == nil {
+		t.Fatal("expected moving onto an existing question of the destination tag to be rejected")
+	}
+	if err := svc.checkFAQTagMoveDuplicate(ctx, 1, kb, moveTo(map[string]string{"chunk-4": "tag-a"})); err != nil {
+		t.Fatalf("expected a unique question to be moved, got %v", err)
+	}
+	// Entries moved together into an empty tag conflict with each other
+	if err := svc.checkFAQTagMoveDuplicate(ctx, 1, kb,
+		moveTo(map[string]string{"chunk-1": "tag-d", "chunk-2": "tag-d"})); err == nil {
+		t.Fatal("expected entries moved into the same tag to be checked against each other")
+	}
+	// Swapping tags keeps each question unique within its tag
+	if err := svc.checkFAQTagMoveDuplicate(ctx, 1, kb,
+		moveTo(map[string]string{"chunk-1": "tag-b", "chunk-2": "tag-a"})); err != nil {
+		t.Fatalf("expected swapped entries to be accepted, got %v", err)
+	}
+}
+
+func TestAddSimilarQuestionsBatchRestoresOnIndexFailure(t *testing.T) {
+	chunk := &types.Chunk{
+		ID: "chunk-1", SeqID: 7, KnowledgeID: "k1", KnowledgeBaseID: "kb-1", ChunkType: types.ChunkTypeFAQ,
+	}
+	if err := chunk.SetFAQMetadata(&types.FAQChunkMetadata{
+		StandardQuestion: "如何退款", SimilarQuestions: []string{"怎么退款"}, Answers: []string{"a"},
+	}); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+	chunkRepo := &fakeChunkRepo{chunks: []*types.Chunk{chunk}}
+	svc := &knowledgeService{
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb-1", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ, EmbeddingModelID: "missing",
+		}},
+		chunkRepo:    chunkRepo,
+		modelService: &fakeReembedModelService{},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	if _, err := svc.AddSimilarQuestionsBatch(ctx, "kb-1", map[int64][]string{7: {"退款流程"}}); err == nil {
+		t.Fatal("expected the index failure to be returned")
+	}
+	want := [][]string{{"怎么退款", "退款流程"}, {"怎么退款"}}
+	if len(chunkRepo.saved) != len(want) || !slices.Equal(chunkRepo.saved[0], want[0]) ||
+		!slices.Equal(chunkRepo.saved[1], want[1]) {
+		t.Fatalf("expected the entry to be saved and then restored, got %v", chunkRepo.saved)
+	}
+}
+
+func TestBuildFAQCloneChunksDisabledEntries(t *testing.T) {
+	srcKB := &types.KnowledgeBase{ID: "kb-src", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ}
+	dstKB := &types.KnowledgeBase{ID: "kb-dst", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ}
+	dstKnowledge := &types.Knowledge{ID: "knowledge-dst", KnowledgeBaseID: dstKB.ID}
+	srcChunks := []*types.Chunk{
+		{ID: "c1", Content: "q1", ChunkIndex: 0, IsEnabled: true, ContentHash: "h1"},
+		{ID: "c2", Content: "q2", ChunkIndex: 1, IsEnabled: false, ContentHash: "h2"},
+		{ID: "c3", Content: "q3", ChunkIndex: 2, IsEnabled: false, ContentHash: "h3"},
+	}
+	svc := &knowledgeService{}
+
+	if !(types.KBClonePayload{}).ShouldIncludeDisabled() {
+		t.Fatal("expected disabled entries to be cloned by default")
+	}
+
+	cloned := svc.buildFAQCloneChunks(context.Background(), srcKB, dstKB, dstKnowledge, srcChunks, true, map[string]string{})
+	if len(cloned) != 3 {
+		t.Fatalf("expected 3 cloned entries, got %d", len(cloned))
+	}
+	for i, chunk := range cloned {
+		if chunk.IsEnabled != srcChunks[i].IsEnabled {
+			t.Fatalf("entry %s: IsEnabled = %v, want %v", srcChunks[i].ID, chunk.IsEnabled, srcChunks[i].IsEnabled)
+		}
+		if chunk.KnowledgeID != dstKnowledge.ID || chunk.KnowledgeBaseID != dstKB.ID || chunk.ID == srcChunks[i].ID {
+			t.Fatalf("entry %s not rebound to the target knowledge base: %+v", srcChunks[i].ID, chunk)
+		}
+	}
+
+	excluded := false
+	payload := types.KBClonePayload{IncludeDisabled: &excluded}
+	cloned = svc.buildFAQCloneChunks(context.Background(), srcKB, dstKB, dstKnowledge, srcChunks,
+		payload.ShouldIncludeDisabled(), map[string]string{})
+	if len(cloned) != 1 || cloned[0].ContentHash != "h1" || !cloned[0].IsEnabled {
+		t.Fatalf("expected only the enabled entry to be cloned, got %+v", cloned)
+	}
+}
+
+func TestUpdateFAQEntryFieldsByQueryDryRun(t *testing.T) {
+	kb := &types.KnowledgeBase{ID: "kb-1", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ}
+	repo := &fakeChunkRepo{}
+	for i := 0; i < 130; i++ {
+		question := fmt.Sprintf("当前功能问题 %d", i)
+		if i%2 == 0 {
+			question = fmt.Sprintf("旧版功能问题 %d", i)
+		}
+		chunk := &types.Chunk{
+			ID: fmt.Sprintf("chunk-%d", i), KnowledgeID: "k-1", SeqID: int64(i + 1), ChunkType: types.ChunkTypeFAQ,
+		}
+		if err := chunk.SetFAQMetadata(&types.FAQChunkMetadata{StandardQuestion: question, Answers: []string{"a"}}); err != nil {
+			t.Fatalf("SetFAQMetadata() error = %v", err)
+		}
+		repo.chunks = append(repo.chunks, chunk)
+	}
+	svc := &knowledgeService{
+		kbService: &fakeTagKBService{kb: kb},
+		repo:      newFakeKnowledgeRepo(&types.Knowledge{ID: "k-1", KnowledgeBaseID: kb.ID, Type: types.KnowledgeTypeFAQ}),
+		chunkRepo: repo,
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	disabled := false
+	result, err := svc.UpdateFAQEntryFieldsByQuery(ctx, kb.ID, &types.FAQEntryQueryUpdate{
+		Keyword: "旧版", Update: types.FAQEntryFieldsUpdate{IsEnabled: &disabled}, DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("UpdateFAQEntryFieldsByQuery() error = %v", err)
+	}
+	if result.Matched != 65 || result.Updated != 0 || len(result.Entries) != 65 {
+		t.Fatalf("unexpected dry run result: matched=%d updated=%d preview=%d",
+			result.Matched, result.Updated, len(result.Entries))
+	}
+	for _, entry := range result.Entries {
+		if !strings.HasPrefix(entry.StandardQuestion, "旧版") {
+			t.Fatalf("unexpected entry in preview: %s", entry.StandardQuestion)
+		}
+	}
+
+	if _, err := svc.UpdateFAQEntryFieldsByQuery(ctx, kb.ID, &types.FAQEntryQueryUpdate{Keyword: "旧版"}); err == nil {
+		t.Fatalf("expected an update without fields to be rejected")
+	}
+}
+
+func TestBuildFAQImportProgressView(t *testing.T) {
+	progress := &types.FAQImportProgress{
+		TaskID:         "task-1",
+		SuccessCount:   3,
+		FailedCount:    45,
+		SuccessEntries: []types.FAQSuccessEntry{{Index: 0}, {Index: 1}, {Index: 2}},
+		SkippedEntries: []types.FAQSkippedEntry{{Index: 3}},
+	}
+	failed := make([]types.FAQFailedEntry, 45)
+	for i := range failed {
+		failed[i] = types.FAQFailedEntry{Index: 100 + i, Reason: fmt.Sprintf("reason %d", i)}
+	}
+
+	summary := buildFAQImportProgressView(progress, failed, types.FAQImportResultFilterSummary, nil)
+	if summary.Entries != nil || summary.SuccessEntries != nil || summary.SkippedEntries != nil || summary.FailedCount != 45 {
+		t.Fatalf("expected counts only, got %+v", summary)
+	}
+	if len(progress.SuccessEntries) != 3 {
+		t.Fatal("expected the stored progress to be left untouched")
+	}
+
+	view := buildFAQImportProgressView(progress, failed, types.FAQImportResultFilterFailed,
+		&types.Pagination{Page: 3, PageSize: 20})
+	page, ok := view.Entries.Data.([]types.FAQFailedEntry)
+	if !ok || view.Entries.Total != 45 || len(page) != 5 || page[0].Index != 140 {
+		t.Fatalf("unexpected failed entries page %+v", view.Entries)
+	}
+
+	view = buildFAQImportProgressView(progress, failed, types.FAQImportResultFilterSuccess,
+		&types.Pagination{Page: 2, PageSize: 20})
+	if entries := view.Entries.Data.([]types.FAQSuccessEntry); view.Entries.Total != 3 || len(entries) != 0 {
+		t.Fatalf("expected an empty page past the end, got %+v", view.Entries)
+	}
+}
+
+func TestFAQAnswerMatchHighlight(t *testing.T) {
+	kb := &types.KnowledgeBase{Type: types.KnowledgeBaseTypeFAQ, FAQConfig: &types.FAQConfig{
+		IndexMode:         types.FAQIndexModeQuestionAnswer,
+		QuestionIndexMode: types.FAQQuestionIndexModeSeparate,
+	}}
+	s := &knowledgeService{}
+	chunk := &types.Chunk{ID: "chunk-1", ChunkType: types.ChunkTypeFAQ}
+	meta := &types.FAQChunkMetadata{
+		StandardQuestion: "如何重置密码",
+		Answers:          []string{"请联系管理员。", "在登录页点击忘记密码，按短信验证码重置。"},
+	}
+	if err := chunk.SetFAQMetadata(meta); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+	infos, err := s.buildFAQIndexInfoList(context.Background(), kb, chunk)
+	if err != nil {
+		t.Fatalf("buildFAQIndexInfoList() error = %v", err)
+	}
+	var answerSourceID string
+	for _, info := range infos {
+		if info.Content == meta.Answers[1] {
+			answerSourceID = info.SourceID
+		}
+	}
+	if answerSourceID != "chunk-1-answer-1" {
+		t.Fatalf("expected the second answer to be indexed on its own, got %+v", infos)
+	}
+
+	query := "短信验证码"
+	entry := &types.FAQEntry{StandardQuestion: meta.StandardQuestion, Answers: meta.Answers, MatchedQuestion: meta.Answers[1]}
+	applyFAQMatchedSource(entry, chunk.ID, answerSourceID, query)
+	if entry.MatchedAnswerIndex == nil || *entry.MatchedAnswerIndex != 1 || entry.MatchedQuestion != "" {
+		t.Fatalf("expected a match in answer 1, got index %v, question %q", entry.MatchedAnswerIndex, entry.MatchedQuestion)
+	}
+	span := entry.MatchedAnswerHighlight
+	if span == nil || string([]rune(meta.Answers[1])[span.Start:span.End]) != query {
+		t.Fatalf("expected %q to be highlighted, got %+v", query, span)
+	}
+
+	kb.FAQConfig.IndexMode = types.FAQIndexModeQuestionOnly
+	infos, err = s.buildFAQIndexInfoList(context.Background(), kb, chunk)
+	if err != nil {
+		t.Fatalf("buildFAQIndexInfoList() error = %v", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected answers not to be indexed in question_only mode, got %d entries", len(infos))
+	}
+}
+
+func TestFAQImportProgressArchive(t *testing.T) {
+	repo := newFakeKnowledgeRepo()
+	svc := &knowledgeService{repo: repo, config: &config.Config{KnowledgeBase: &config.KnowledgeBaseConfig{}}}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	// Use an ID produced the same way as a real import, longer than a UUID
+	taskID := secutils.GenerateTaskID("faq_import", math.MaxUint64, "0b4c7f5e-8d7a-4f0c-9b5e-3a2d1c0f9e8d")
+	progress := &types.FAQImportProgress{
+		TaskID:           taskID,
+		KBID:             "kb-1",
+		Status:           types.FAQImportStatusCompleted,
+		FailedCount:      1,
+		FailedEntries:    []types.FAQFailedEntry{{Index: 3, Reason: "标准问重复"}},
+		FailedEntriesURL: "https://example.com/failed.csv",
+	}
+
+	svc.archiveFAQImportProgress(ctx, progress)
+	if len(repo.archives) != 0 {
+		t.Fatal("expected nothing to be archived while archiving is disabled")
+	}
+
+	svc.config.KnowledgeBase.FAQImportArchive = &config.FAQImportArchiveConfig{Enabled: true}
+	svc.archiveFAQImportProgress(ctx, progress)
+	archive := repo.archives[taskID]
+	if archive == nil || archive.TenantID != 1 || archive.ExpiresAt == nil ||
+		archive.ExpiresAt.Sub(archive.ArchivedAt) != defaultFAQImportArchiveRetention {
+		t.Fatalf("unexpected archive %+v", archive)
+	}
+
+	got, err := svc.GetFAQImportProgressArchived(ctx, taskID)
+	if err != nil {
+		t.Fatalf("GetFAQImportProgressArchived() error = %v", err)
+	}
+	if got.FailedEntriesURL != progress.FailedEntriesURL || len(got.FailedEntries) != 1 || got.FailedEntries[0].Reason != "标准问重复" {
+		t.Fatalf("expected failed entries and CSV URL to be archived, got %+v", got)
+	}
+	if got.KBID != "kb-1" {
+		t.Fatalf("expected the knowledge base to be kept for the access check, got %q", got.KBID)
+	}
+
+	// Imports into a shared knowledge base are archived under the owner tenant
+	sharedUser := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(2))
+	if _, err := svc.GetFAQImportProgressArchived(sharedUser, taskID); err != nil {
+		t.Fatalf("expected the archive to be found regardless of the caller tenant, got %v", err)
+	}
+	expired := archive.ArchivedAt.Add(-time.Minute)
+	archive.ExpiresAt = &expired
+	if _, err := svc.GetFAQImportProgressArchived(ctx, taskID); err == nil {
+		t.Fatal("expected an expired archive not to be found")
+	}
+}
+
+func TestFAQExportEncoders(t *testing.T) {
+	kb := &types.KnowledgeBase{ID: "kb-1", Type: types.KnowledgeBaseTypeFAQ, FAQConfig: &types.FAQConfig{}}
+	kb.EnsureDefaults()
+	tagMap := map[string]*types.KnowledgeTag{"tag-uuid": {ID: "tag-uuid", SeqID: 3, Name: "账号"}}
+	chunk := &types.Chunk{ID: "chunk-1", SeqID: 7, ChunkType: types.ChunkTypeFAQ, TagID: "tag-uuid"}
+	if err := chunk.SetFAQMetadata(&types.FAQChunkMetadata{
+		StandardQuestion: "如何重置密码, 步骤?",
+		Answers:          []string{"a", "b"},
+		AnswerStrategy:   types.AnswerStrategyAll,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	export := func(format types.FAQExportFormat) string {
+		var buf strings.Builder
+		enc, err := (&knowledgeService{}).newFAQExportEncoder(format, &buf, kb, tagMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.WriteChunk(chunk); err != nil {
+			t.Fatal(err)
+		}
+		// Chunks without FAQ metadata are skipped
+		if err := enc.WriteChunk(&types.Chunk{ID: "broken", ChunkType: types.ChunkTypeFAQ, Metadata: []byte("{")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	csv := export(types.FAQExportFormatCSV)
+	lines := strings.Split(strings.TrimSuffix(csv, "\n"), "\n")
+	if len(lines) != 2 || lines[1] != `账号,"如何重置密码, 步骤?",,,a##b,TRUE,TRUE,TRUE,` {
+		t.Fatalf("unexpected csv export: %q", csv)
+	}
+
+	var entries []types.FAQEntry
+	if err := json.Unmarshal([]byte(export(types.FAQExportFormatJSON)), &entries); err != nil {
+		t.Fatalf("invalid json export: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != 7 || entries[0].TagID != 3 || entries[0].TagName != "账号" ||
+		!slices.Equal(entries[0].Answers, []string{"a", "b"}) {
+		t.Fatalf("unexpected json export: %+v", entries)
+	}
+
+	xlsx := export(types.FAQExportFormatXLSX)
+	if !strings.HasPrefix(xlsx, "PK") || strings.HasPrefix(xlsx, "\xEF\xBB\xBF") {
+		t.Fatal("xlsx export must be a zip archive without BOM")
+	}
+}
+
+func TestExportFAQEntriesStreamPaging(t *testing.T) {
+	kb := &types.KnowledgeBase{ID: "kb-1", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeChunkRepo{}
+	total := faqExportPageSize + 10
+	for i := 0; i < total; i++ {
+		chunk := &types.Chunk{
+			// Pairs of entries share a creation time so the id tie-breaker is exercised across pages
+			ID: fmt.Sprintf("chunk-%05d", i), SeqID: int64(i + 1), ChunkType: types.ChunkTypeFAQ,
+			Status: int(types.ChunkStatusIndexed), IsEnabled: i != 3, TagID: "tag-1",
+			CreatedAt: base.Add(time.Duration(i/2) * time.Second), UpdatedAt: base,
+		}
+		if err := chunk.SetFAQMetadata(&types.FAQChunkMetadata{
+			StandardQuestion: fmt.Sprintf("问题 %d", i), Answers: []string{"a"},
+		}); err != nil {
+			t.Fatalf("SetFAQMetadata() error = %v", err)
+		}
+		repo.chunks = append(repo.chunks, chunk)
+	}
+	// Chunks still being indexed are not exported
+	pending := &types.Chunk{ID: "chunk-pending", ChunkType: types.ChunkTypeFAQ, IsEnabled: true, CreatedAt: base}
+	if err := pending.SetFAQMetadata(&types.FAQChunkMetadata{StandardQuestion: "未索引", Answers: []string{"a"}}); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+	repo.chunks = append(repo.chunks, pending)
+	// Entries from the first page and the second page are edited while the export is running
+	repo.onPage = func(page int) {
+		if page == 2 {
+			repo.chunks[0].UpdatedAt = base.Add(time.Hour)
+			repo.chunks[total-1].UpdatedAt = base.Add(time.Hour)
+		}
+	}
+
+	svc := &knowledgeService{
+		kbService: &fakeTagKBService{kb: kb},
+		repo:      newFakeKnowledgeRepo(&types.Knowledge{ID: "k-1", KnowledgeBaseID: kb.ID, Type: types.KnowledgeTypeFAQ}),
+		chunkRepo: repo,
+		tagRepo: &fakeTagRepo{tags: map[string]*types.KnowledgeTag{
+			"tag-1": {ID: "tag-1", KnowledgeBaseID: kb.ID, SeqID: 5, Name: "常见问题"},
+		}},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	var buf strings.Builder
+	if err := svc.ExportFAQEntriesStream(ctx, kb.ID, false, types.FAQExportFormatJSON, &buf); err != nil {
+		t.Fatalf("ExportFAQEntriesStream() error = %v", err)
+	}
+	var entries []types.FAQEntry
+	if err := json.Unmarshal([]byte(buf.String()), &entries); err != nil {
+		t.Fatalf("invalid json export: %v", err)
+	}
+	if repo.pages != 2 {
+		t.Fatalf("expected 2 pages, got %d", repo.pages)
+	}
+	if len(entries) != total-1 {
+		t.Fatalf("expected %d entries, got %d", total-1, len(entries))
+	}
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		if seen[entry.ChunkID] {
+			t.Fatalf("entry %s exported twice", entry.ChunkID)
+		}
+		seen[entry.ChunkID] = true
+		if i > 0 && entry.ChunkID < entries[i-1].ChunkID {
+			t.Fatalf("entries out of creation order at %d: %s after %s", i, entry.ChunkID, entries[i-1].ChunkID)
+		}
+		if entry.TagName != "常见问题" || entry.TagID != 5 {
+			t.Fatalf("unexpected tag on entry %s: %d %q", entry.ChunkID, entry.TagID, entry.TagName)
+		}
+	}
+	if seen["chunk-00003"] || seen["chunk-pending"] {
+		t.Fatal("disabled and unindexed entries must not be exported")
+	}
+}
+
+type countingEmbedder struct {
+	embedding.Embedder
+	calls [][]string
+}
+
+func (e *countingEmbedder) BatchEmbedWithPool(_ context.Context,
+	_ embedding.Embedder, texts []string,
+) ([][]float32, error) {
+	e.calls = append(e.calls, texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(strings.Fields(text)))}
+	}
+	return vectors, nil
+}
+
+func TestDedupEmbedderReusesIdenticalTexts(t *testing.T) {
+	inner := &countingEmbedder{}
+	embedder := newDedupEmbedder(inner)
+	ctx := context.Background()
+
+	vectors, err := embedder.BatchEmbedWithPool(ctx, embedder, []string{"如何退款", " 如何退款\n", "a b", "如何退款"})
+	if err != nil {
+		t.Fatalf("BatchEmbedWithPool() error = %v", err)
+	}
+	if len(inner.calls) != 1 || !slices.Equal(inner.calls[0], []string{"如何退款", "a b"}) {
+		t.Fatalf("inner calls = %q, want one call with distinct texts", inner.calls)
+	}
+	want := [][]float32{{1}, {1}, {2}, {1}}
+	for i := range want {
+		if !slices.Equal(vectors[i], want[i]) {
+			t.Fatalf("vectors[%d] = %v, want %v", i, vectors[i], want[i])
+		}
+	}
+	vectors[0][0] = 42
+	vectors[2][0] = 42
+	if vectors[1][0] != 1 {
+		t.Fatal("reused vector shares memory with the original")
+	}
+
+	vectors, err = embedder.BatchEmbedWithPool(ctx, embedder, []string{"a  b", "c", "如何退款"})
+	if err != nil {
+		t.Fatalf("BatchEmbedWithPool() error = %v", err)
+	}
+	if !slices.Equal(vectors[0], []float32{2}) || !slices.Equal(vectors[2], []float32{1}) {
+		t.Fatalf("vectors mutated by an earlier call leaked into the cache: %v", vectors)
+	}
+	if len(inner.calls) != 2 || !slices.Equal(inner.calls[1], []string{"c"}) {
+		t.Fatalf("inner calls = %q, want cached text skipped", inner.calls)
+	}
+	if got := embedder.Reused(); got != 4 {
+		t.Fatalf("Reused() = %d, want 4", got)
+	}
+}
+
+func TestGetFAQEntryByStandardQuestion(t *testing.T) {
+	existing := &types.Chunk{ID: "chunk-1", SeqID: 7, TagID: "tag-a", ChunkType: types.ChunkTypeFAQ}
+	if err := existing.SetFAQMetadata(&types.FAQChunkMetadata{StandardQuestion: "如何退款", Answers: []string{"a"}}); err != nil {
+		t.Fatalf("SetFAQMetadata() error = %v", err)
+	}
+	kb := &types.KnowledgeBase{ID: "kb-1", Type: types.KnowledgeBaseTypeFAQ, FAQConfig: &types.FAQConfig{}}
+	svc := &knowledgeService{
+		kbService: &fakeTagKBService{kb: kb},
+		chunkRepo: &fakeChunkRepo{chunks: []*types.Chunk{existing}},
+		tagRepo: &fakeTagRepo{tags: map[string]*types.KnowledgeTag{
+			"tag-a": {ID: "tag-a", SeqID: 3, KnowledgeBaseID: kb.ID, Name: "售后"},
+		}},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	entry, err := svc.GetFAQEntryByStandardQuestion(ctx, kb.ID, "  如何退款\n")
+	if err != nil {
+		t.Fatalf("GetFAQEntryByStandardQuestion() error = %v", err)
+	}
+	if entry.ID != 7 || entry.TagID != 3 || entry.TagName != "售后" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	_, err = svc.GetFAQEntryByStandardQuestion(ctx, kb.ID, "如何发货")
+	if appErr, ok := werrors.IsAppError(err); !ok || appErr.Code != werrors.ErrNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	_, err = svc.GetFAQEntryByStandardQuestion(ctx, kb.ID, "  ")
+	if appErr, ok := werrors.IsAppError(err); !ok || appErr.Code != werrors.ErrBadRequest {
+		t.Fatalf("expected bad request error, got %v", err)
+	}
+}
+
+// fakeFAQImportChunkService rejects every batch containing a chunk whose content mentions "坏"
+type fakeFAQImportChunkService struct {
+	interfaces.ChunkService
+	created []*types.Chunk
+}
+
+func (s *fakeFAQImportChunkService) CreateChunks(_ context.Context, chunks []*types.Chunk) error {
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Content, "坏") {
+			return errors.New("invalid chunk content")
+		}
+	}
+	for _, chunk := range chunks {
+		if chunk.SeqID == 0 {
+			chunk.SeqID = int64(len(s.created) + 1)
+		}
+		s.created = append(s.created, chunk)
+	}
+	return nil
+}
+
+func (s *fakeFAQImportChunkService) UpdateChunk(_ context.Context, _ *types.Chunk) error {
+	return nil
+}
+
+func (s *fakeFAQImportChunkService) UpdateChunks(_ context.Context, _ []*types.Chunk) error {
+	return nil
+}
+
+type fakeFAQImportTagService struct {
+	interfaces.KnowledgeTagService
+}
+
+func (s *fakeFAQImportTagService) FindOrCreateTagByName(_ context.Context,
+	kbID string, name string,
+) (*types.KnowledgeTag, error) {
+	return &types.KnowledgeTag{ID: "tag-" + name, KnowledgeBaseID: kbID, Name: name}, nil
+}
+
+// newFAQImportTestService returns a service importing into FAQ knowledge base kb1
+func newFAQImportTestService(t *testing.T, faqConfig *types.FAQConfig) (*knowledgeService, *fakeFAQImportChunkService) {
+	t.Helper()
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	faqKnowledge := &types.Knowledge{ID: "faq-1", TenantID: 1, KnowledgeBaseID: "kb1", Type: types.KnowledgeTypeFAQ}
+	chunkService := &fakeFAQImportChunkService{}
+	redisClient, _ := newFakeRedisClient()
+	svc := &knowledgeService{
+		repo: newFakeKnowledgeRepo(faqKnowledge),
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ, EmbeddingModelID: "embedding-model",
+			FAQConfig: faqConfig,
+		}},
+		modelService:   newFakeReembedModelService("embedding-model"),
+		chunkService:   chunkService,
+		chunkRepo:      &fakeChunkRepo{},
+		tagRepo:        &fakeTagRepo{tags: map[string]*types.KnowledgeTag{}},
+		tagService:     &fakeFAQImportTagService{},
+		tenantRepo:     &fakeStorageTenantRepo{tenant: fakeRetrieveEngineTenant(1)},
+		retrieveEngine: &fakeRetrieveEngineRegistry{engine: &fakeRetrieveEngine{}},
+		fileSvc:        &fakeOffloadFileService{files: map[string][]byte{}},
+		redisClient:    redisClient,
+	}
+	return svc, chunkService
+}
+
+// faqImportTestEntries returns good entries followed by bad ones that fail to be created
+func faqImportTestEntries(good, bad int) []types.FAQEntryPayload {
+	entries := make([]types.FAQEntryPayload, 0, good+bad)
+	for i := range good {
+		entries = append(entries, types.FAQEntryPayload{
+			StandardQuestion: fmt.Sprintf("好问题 %d", i), Answers: []string{"答案"},
+		})
+	}
+	for i := range bad {
+		entries = append(entries, types.FAQEntryPayload{
+			StandardQuestion: fmt.Sprintf("坏问题 %d", i), Answers: []string{"答案"},
+		})
+	}
+	return entries
+}
+
+func TestCheckFAQImportFailureBudget(t *testing.T) {
+	tests := []struct {
+		name                             string
+		batchFailures, failed, attempted int
+		wantExceeded                     bool
+	}{
+		{name: "within budget", batchFailures: 2, failed: 2, attempted: 10},
+		{name: "batch limit reached", batchFailures: 3, failed: 3, attempted: 100},
+		{name: "batch limit exceeded", batchFailures: 4, failed: 4, attempted: 100, wantExceeded: true},
+		{name: "rate at threshold", batchFailures: 1, failed: 2, attempted: 10},
+		{name: "rate exceeded", batchFailures: 1, failed: 3, attempted: 10, wantExceeded: true},
+		{name: "nothing attempted", attempted: 0},
+	}
+	for _, tt := range tests {
+		err := checkFAQImportFailureBudget(tt.batchFailures, 3, tt.failed, tt.attempted, 0.2)
+		if exceeded := errors.Is(err, ErrFAQImportFailureBudgetExceeded); exceeded != tt.wantExceeded {
+			t.Errorf("%s: checkFAQImportFailureBudget() error = %v, want exceeded %v", tt.name, err, tt.wantExceeded)
+		}
+	}
+}
+
+func TestExecuteFAQImportToleratesFailuresWithinBudget(t *testing.T) {
+	svc, chunkService := newFAQImportTestService(t, nil)
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, fakeRetrieveEngineTenant(1))
+
+	payload := &types.FAQBatchUpsertPayload{Entries: faqImportTestEntries(4, 1), Mode: types.FAQBatchModeAppend}
+	progress := &types.FAQImportProgress{TaskID: "task-1", KBID: "kb1"}
+	if err := svc.executeFAQImport(ctx, "task-1", "kb1", payload, 1, 0, progress, []int{0, 1, 2, 3, 4}); err != nil {
+		t.Fatalf("executeFAQImport() error = %v", err)
+	}
+	if len(chunkService.created) != 4 || len(progress.SuccessEntries) != 4 {
+		t.Fatalf("expected the good entries to be imported, got %d chunks and %d successes",
+			len(chunkService.created), len(progress.SuccessEntries))
+	}
+	if progress.FailedCount != 1 || len(progress.FailedEntries) != 1 || progress.FailedEntries[0].Index != 4 {
+		t.Fatalf("expected the bad entry to be recorded as failed, got %+v", progress.FailedEntries)
+	}
+}
+
+func TestExecuteFAQImportAbortsOverBudget(t *testing.T) {
+	svc, chunkService := newFAQImportTestService(t, &types.FAQConfig{ImportMaxBatchFailures: 1})
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, fakeRetrieveEngineTenant(1))
+
+	entries := faqImportTestEntries(3, 2)
+	payload := &types.FAQBatchUpsertPayload{Entries: entries, Mode: types.FAQBatchModeAppend}
+	progress := &types.FAQImportProgress{TaskID: "task-1", KBID: "kb1"}
+	err := svc.executeFAQImport(ctx, "task-1", "kb1", payload, 1, 0, progress, []int{0, 1, 2, 3, 4})
+	if !errors.Is(err, ErrFAQImportFailureBudgetExceeded) {
+		t.Fatalf("expected the failure budget to be exceeded, got %v", err)
+	}
+	// The batch is finished before aborting, so no chunk is left stored without an index
+	for _, chunk := range chunkService.created {
+		if chunk.Status != int(types.ChunkStatusIndexed) {
+			t.Fatalf("expected imported chunk %s to be indexed, got status %d", chunk.ID, chunk.Status)
+		}
+	}
+	if len(chunkService.created) != 3 || progress.FailedCount != 2 {
+		t.Fatalf("expected 3 imported and 2 failed entries, got %d and %d", len(chunkService.created), progress.FailedCount)
+	}
+
+	importPayload := &types.FAQImportPayload{TenantID: 1, TaskID: "task-1", KBID: "kb1", Entries: entries}
+	svc.abortFAQImport(ctx, importPayload, progress, len(entries), err)
+	aborted, getErr := svc.GetFAQImportProgress(ctx, "task-1")
+	if getErr != nil {
+		t.Fatalf("GetFAQImportProgress() error = %v", getErr)
+	}
+	if aborted.Status != types.FAQImportStatusPartial || aborted.Processed != len(entries) || aborted.Error == "" {
+		t.Fatalf("expected the import to be reported as partial, got %+v", aborted)
+	}
+	if aborted.FailedEntriesURL == "" {
+		t.Fatal("expected the failed entries to be exported")
+	}
+}
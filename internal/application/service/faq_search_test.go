@@ -0,0 +1,189 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// fakeFAQSearchKBService retrieves the FAQ chunks carrying one of the requested tags,
+// returning the MatchCount best scored ones like HybridSearch does
+type fakeFAQSearchKBService struct {
+	fakeTagKBService
+	chunks      []*types.Chunk
+	scores      map[string]float64
+	matchCounts []int
+	mu          sync.Mutex
+}
+
+func (s *fakeFAQSearchKBService) HybridSearch(_ context.Context, _ string,
+	params types.SearchParams,
+) ([]*types.SearchResult, error) {
+	s.mu.Lock()
+	s.matchCounts = append(s.matchCounts, params.MatchCount)
+	s.mu.Unlock()
+	var results []*types.SearchResult
+	for _, chunk := range s.chunks {
+		if slices.Contains(params.TagIDs, chunk.TagID) {
+			results = append(results, &types.SearchResult{ID: chunk.ID, Score: s.scores[chunk.ID]})
+		}
+	}
+	slices.SortFunc(results, func(a, b *types.SearchResult) int { return cmp.Compare(b.Score, a.Score) })
+	return results[:min(len(results), params.MatchCount)], nil
+}
+
+// TestFAQPrioritySearch_SecondPriorityDoesNotCrowdOutFirst searches with second priority hits
+// scoring much higher than first priority ones; first priority entries must still fill the results.
+func TestFAQPrioritySearch_SecondPriorityDoesNotCrowdOutFirst(t *testing.T) {
+	kb := &types.KnowledgeBase{ID: "kb-1", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ}
+	kbService := &fakeFAQSearchKBService{fakeTagKBService: fakeTagKBService{kb: kb}, scores: map[string]float64{
+		"first-1": 0.72, "first-2": 0.75, "first-3": 0.71, "first-4": 0.70,
+		"second-1": 0.99, "second-2": 0.98, "second-3": 0.97,
+	}}
+	for i, id := range []string{"first-1", "first-2", "first-3", "first-4", "second-1", "second-2", "second-3"} {
+		chunk := &types.Chunk{
+			ID: id, TenantID: 1, SeqID: int64(i + 1), ChunkType: types.ChunkTypeFAQ, IsEnabled: true,
+			TagID: "tag-" + strings.Split(id, "-")[0],
+		}
+		if err := chunk.SetFAQMetadata(&types.FAQChunkMetadata{StandardQuestion: id, Answers: []string{"a"}}); err != nil {
+			t.Fatalf("SetFAQMetadata() error = %v", err)
+		}
+		kbService.chunks = append(kbService.chunks, chunk)
+	}
+	svc := &knowledgeService{
+		kbService: kbService,
+		chunkRepo: &fakeChunkRepo{chunks: kbService.chunks},
+		tagRepo: &fakeTagRepo{tags: map[string]*types.KnowledgeTag{
+			"tag-first":  {ID: "tag-first", KnowledgeBaseID: kb.ID, SeqID: 1, Name: "优先"},
+			"tag-second": {ID: "tag-second", KnowledgeBaseID: kb.ID, SeqID: 2, Name: "其次"},
+		}},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	search := func(matchCount int) []*types.FAQEntry {
+		t.Helper()
+		req := &types.FAQSearchRequest{
+			QueryText: "退款", MatchCount: matchCount, FirstPriorityTagIDs: []int64{1}, SecondPriorityTagIDs: []int64{2},
+		}
+		result, err := svc.SearchFAQEntries(ctx, kb.ID, req)
+		if err != nil {
+			t.Fatalf("SearchFAQEntries() error = %v", err)
+		}
+		return result.Entries
+	}
+
+	entries := search(3)
+	want := []string{"first-2", "first-1", "first-3"}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.ChunkID != want[i] || entry.TagName != "优先" {
+			t.Errorf("entry %d: expected first priority %s, got %s (%s, score %.2f)",
+				i, want[i], entry.ChunkID, entry.TagName, entry.Score)
+		}
+	}
+	for _, matchCount := range kbService.matchCounts {
+		if matchCount <= 3 {
+			t.Fatalf("expected each priority to retrieve a candidate pool larger than the match count, got %d", matchCount)
+		}
+	}
+
+	// Second priority entries only follow once the first priority ones are used up
+	entries = search(6)
+	want = []string{"first-2", "first-1", "first-3", "first-4", "second-1", "second-2"}
+	got := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		got = append(got, entry.ChunkID)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFAQSearchCandidatePoolSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *types.FAQSearchRequest
+		expected int
+	}{
+		{
+			name:     "default multiplier",
+			req:      &types.FAQSearchRequest{MatchCount: 10},
+			expected: 10 * types.FAQSearchCandidatePoolMultiplier,
+		},
+		{
+			name:     "explicit pool",
+			req:      &types.FAQSearchRequest{MatchCount: 10, CandidatePoolSize: 100},
+			expected: 100,
+		},
+		{
+			name:     "pool smaller than match count",
+			req:      &types.FAQSearchRequest{MatchCount: 20, CandidatePoolSize: 5},
+			expected: 20,
+		},
+		{
+			name:     "capped",
+			req:      &types.FAQSearchRequest{MatchCount: 50, CandidatePoolSize: 1000},
+			expected: types.MaxFAQSearchCandidatePoolSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := faqSearchCandidatePoolSize(tt.req); got != tt.expected {
+				t.Errorf("faqSearchCandidatePoolSize() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFAQNoMatchFallback(t *testing.T) {
+	modes := []struct {
+		cfg      *types.FAQConfig
+		expected types.FAQNoMatchFallback
+	}{
+		{cfg: nil, expected: types.FAQNoMatchFallbackNone},
+		{cfg: &types.FAQConfig{}, expected: types.FAQNoMatchFallbackNone},
+		{cfg: &types.FAQConfig{NoMatchFallback: types.FAQNoMatchFallbackFlag}, expected: types.FAQNoMatchFallbackFlag},
+		{cfg: &types.FAQConfig{NoMatchFallback: types.FAQNoMatchFallbackEntry}, expected: types.FAQNoMatchFallbackFlag},
+		{
+			cfg:      &types.FAQConfig{NoMatchFallback: types.FAQNoMatchFallbackEntry, FallbackEntryID: 7},
+			expected: types.FAQNoMatchFallbackEntry,
+		},
+	}
+	for _, tt := range modes {
+		if got := tt.cfg.GetNoMatchFallback(); got != tt.expected {
+			t.Errorf("GetNoMatchFallback(%+v) = %q, want %q", tt.cfg, got, tt.expected)
+		}
+	}
+
+	req := &types.FAQSearchRequest{ResponseMode: types.FAQSearchResponseModeAnswers}
+	result := newFAQSearchResult(req, []*types.FAQEntry{}, false)
+	setFAQSearchFallback(req, result, nil)
+	if !result.NoMatch || result.Fallback != nil || result.FallbackAnswer != nil {
+		t.Fatalf("flag fallback must only mark no match: %+v", result)
+	}
+
+	fallback := &types.FAQEntry{ID: 7, StandardQuestion: "兜底", Answers: []string{"暂时无法回答"}}
+	result = newFAQSearchResult(req, []*types.FAQEntry{}, false)
+	setFAQSearchFallback(req, result, fallback)
+	if result.Fallback != fallback || result.FallbackAnswer == nil || result.FallbackAnswer.EntryID != 7 ||
+		len(result.FallbackAnswer.Answers) != 1 {
+		t.Fatalf("unexpected entry fallback: %+v", result)
+	}
+	if len(result.Entries) != 0 || len(result.Answers) != 0 {
+		t.Fatal("fallback must not be mixed into the matched results")
+	}
+
+	result = newFAQSearchResult(&types.FAQSearchRequest{}, []*types.FAQEntry{}, false)
+	setFAQSearchFallback(&types.FAQSearchRequest{}, result, fallback)
+	if result.Fallback != fallback || result.FallbackAnswer != nil {
+		t.Fatalf("entries mode must not resolve a fallback answer: %+v", result)
+	}
+}
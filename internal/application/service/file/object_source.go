@@ -0,0 +1,157 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// ObjectInfo describes an object in an external object storage bucket
+type ObjectInfo struct {
+	Key  string
+	Size int64
+}
+
+// ObjectSource provides read-only access to a user-owned bucket for knowledge import.
+// Unlike FileService it never writes to the bucket.
+type ObjectSource interface {
+	// ListObjects lists up to limit objects under prefix, directory markers excluded
+	ListObjects(ctx context.Context, prefix string, limit int) ([]ObjectInfo, error)
+	// StatObject returns the metadata of a single object
+	StatObject(ctx context.Context, key string) (ObjectInfo, error)
+	// GetObject opens the object for reading
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// s3ObjectSource reads objects from an S3 compatible bucket
+type s3ObjectSource struct {
+	client     *minio.Client
+	bucketName string
+}
+
+// NewS3ObjectSource creates an ObjectSource for an S3 compatible bucket.
+// transport is used for every request, so callers can restrict which addresses may be dialed.
+func NewS3ObjectSource(endpoint, region, accessKeyID, secretAccessKey, bucketName string, useSSL bool,
+	transport http.RoundTripper,
+) (ObjectSource, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Secure:    useSSL,
+		Region:    region,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize S3 client: %w", err)
+	}
+	return &s3ObjectSource{client: client, bucketName: bucketName}, nil
+}
+
+func (s *s3ObjectSource) ListObjects(ctx context.Context, prefix string, limit int) ([]ObjectInfo, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	objects := make([]ObjectInfo, 0)
+	for obj := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", obj.Err)
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		objects = append(objects, ObjectInfo{Key: obj.Key, Size: obj.Size})
+		if len(objects) >= limit {
+			break
+		}
+	}
+	return objects, nil
+}
+
+func (s *s3ObjectSource) StatObject(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucketName, key, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return ObjectInfo{Key: key, Size: info.Size}, nil
+}
+
+func (s *s3ObjectSource) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	return obj, nil
+}
+
+// cosObjectSource reads objects from a Tencent Cloud COS bucket
+type cosObjectSource struct {
+	client *cos.Client
+}
+
+// NewCosObjectSource creates an ObjectSource for a COS bucket, bucketName is in the form name-appid
+func NewCosObjectSource(bucketName, region, secretID, secretKey string) (ObjectSource, error) {
+	u, err := url.Parse(fmt.Sprintf("https://%s.cos.%s.tencentcos.cn/", bucketName, region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bucketURL: %w", err)
+	}
+	client := cos.NewClient(&cos.BaseURL{BucketURL: u}, &http.Client{
+		Transport: &cos.AuthorizationTransport{
+			SecretID:  secretID,
+			SecretKey: secretKey,
+		},
+	})
+	return &cosObjectSource{client: client}, nil
+}
+
+func (s *cosObjectSource) ListObjects(ctx context.Context, prefix string, limit int) ([]ObjectInfo, error) {
+	objects := make([]ObjectInfo, 0)
+	marker := ""
+	for {
+		result, _, err := s.client.Bucket.Get(ctx, &cos.BucketGetOptions{
+			Prefix:  prefix,
+			Marker:  marker,
+			MaxKeys: 1000,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		for _, obj := range result.Contents {
+			if strings.HasSuffix(obj.Key, "/") {
+				continue
+			}
+			objects = append(objects, ObjectInfo{Key: obj.Key, Size: obj.Size})
+			if len(objects) >= limit {
+				return objects, nil
+			}
+		}
+		if !result.IsTruncated || result.NextMarker == "" {
+			return objects, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+func (s *cosObjectSource) StatObject(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := s.client.Object.Head(ctx, key, nil)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return ObjectInfo{Key: key, Size: resp.ContentLength}, nil
+}
+
+func (s *cosObjectSource) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.client.Object.Get(ctx, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	return resp.Body, nil
+}
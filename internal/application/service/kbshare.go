@@ -451,6 +451,54 @@ func (s *kbShareService) HasKBPermission(ctx context.Context, kbID string, userI
 	return permission.HasPermission(requiredRole), nil
 }
 
+// HasKBPermissionBatch checks HasKBPermission for multiple knowledge bases with one query for shares
+// and one for the user's memberships, using the same effective-permission rules as CheckUserKBPermission.
+func (s *kbShareService) HasKBPermissionBatch(ctx context.Context,
+	kbIDs []string, userID string, requiredRole types.OrgMemberRole,
+) (map[string]bool, error) {
+	result := make(map[string]bool, len(kbIDs))
+	if len(kbIDs) == 0 {
+		return result, nil
+	}
+	shares, err := s.shareRepo.ListByKnowledgeBases(ctx, kbIDs)
+	if err != nil {
+		return nil, err
+	}
+	orgIDSet := make(map[string]struct{})
+	orgIDs := make([]string, 0)
+	for _, share := range shares {
+		if _, ok := orgIDSet[share.OrganizationID]; !ok {
+			orgIDSet[share.OrganizationID] = struct{}{}
+			orgIDs = append(orgIDs, share.OrganizationID)
+		}
+	}
+	members, err := s.orgRepo.ListMembersByUserForOrgs(ctx, userID, orgIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	highest := make(map[string]types.OrgMemberRole)
+	for _, share := range shares {
+		member, ok := members[share.OrganizationID]
+		if !ok {
+			continue // User is not a member of this org
+		}
+		// Effective permission is the lower of share permission and user's org role
+		effectivePermission := share.Permission
+		if !member.Role.HasPermission(share.Permission) {
+			effectivePermission = member.Role
+		}
+		if current, ok := highest[share.KnowledgeBaseID]; !ok || effectivePermission.HasPermission(current) {
+			highest[share.KnowledgeBaseID] = effectivePermission
+		}
+	}
+	for _, kbID := range kbIDs {
+		permission, isShared := highest[kbID]
+		result[kbID] = isShared && permission.HasPermission(requiredRole)
+	}
+	return result, nil
+}
+
 // GetKBSourceTenant gets the source tenant ID for a shared knowledge base
 func (s *kbShareService) GetKBSourceTenant(ctx context.Context, kbID string) (uint64, error) {
 	// First check if there are any shares for this KB
@@ -1,14 +1,21 @@
 package service
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
 	"context"
 	"crypto/md5"
+	"database/sql/driver"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"mime"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -17,13 +24,19 @@ import (
 	"runtime"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/Tencent/WeKnora/docreader/client"
 	"github.com/Tencent/WeKnora/docreader/proto"
+	chatpipline "github.com/Tencent/WeKnora/internal/application/service/chat_pipline"
+	"github.com/Tencent/WeKnora/internal/application/service/file"
 	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/config"
 	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
@@ -54,6 +67,10 @@ var (
 	ErrDuplicateURL = errors.New("URL already exists")
 	// ErrImageNotParse is returned when trying to update image information without enabling multimodel
 	ErrImageNotParse = errors.New("image not parse without enable multimodel")
+	// ErrFAQImportFailureBudgetExceeded is returned when an FAQ import aborts because too many entries failed
+	ErrFAQImportFailureBudgetExceeded = errors.New("FAQ import failure budget exceeded")
+	// ErrNoIndexableContent is returned in strict mode when a document produces no indexed chunk
+	ErrNoIndexableContent = errors.New("no indexable content produced")
 )
 
 // knowledgeService implements the knowledge service interface
@@ -72,15 +89,33 @@ type knowledgeService struct {
 	fileSvc         interfaces.FileService
 	modelService    interfaces.ModelService
 	task            *asynq.Client
+	taskInspector   *asynq.Inspector
 	graphEngine     interfaces.RetrieveGraphRepository
 	redisClient     *redis.Client
 	kbShareService  interfaces.KBShareService
+	// deleteSem bounds concurrent delete subtasks (vector/chunk/file/graph) across all in-flight deletes
+	deleteSem chan struct{}
+	// downloadSem bounds concurrent file_url downloads across all document processing tasks
+	downloadSem chan struct{}
 }
 
 const (
-	manualContentMaxLength = 200000
-	manualFileExtension    = ".md"
-	faqImportBatchSize     = 50 // 每批处理的FAQ条目数
+	// defaultManualContentMaxLength 未配置时手工知识内容允许的最大字符数
+	defaultManualContentMaxLength = 200000
+	manualFileExtension           = ".md"
+	faqImportBatchSize            = 50 // 每批处理的FAQ条目数
+	// defaultDeleteConcurrency 未配置时全局允许同时执行的知识删除子任务数
+	defaultDeleteConcurrency = 16
+	// defaultFileURLDownloadConcurrency 未配置时全局允许同时进行的 file_url 下载数
+	defaultFileURLDownloadConcurrency = 8
+	// defaultFileURLDownloadTimeout 未配置时单次 file_url 下载的超时时间
+	defaultFileURLDownloadTimeout = 60 * time.Second
+	// defaultFAQImportArchiveRetention 未配置时 FAQ 导入归档的保留时长
+	defaultFAQImportArchiveRetention = 30 * 24 * time.Hour
+	// fileURLProbeTimeout 识别文件下载的 HEAD 请求超时时间
+	fileURLProbeTimeout = 5 * time.Second
+	// defaultTaskPayloadInlineMaxSize 未配置时异步任务 payload 允许内联的最大字节数，超出时大字段转存到对象存储
+	defaultTaskPayloadInlineMaxSize = 50 * 1024
 )
 
 // NewKnowledgeService creates a new knowledge service instance
@@ -97,6 +132,7 @@ func NewKnowledgeService(
 	fileSvc interfaces.FileService,
 	modelService interfaces.ModelService,
 	task *asynq.Client,
+	taskInspector *asynq.Inspector,
 	graphEngine interfaces.RetrieveGraphRepository,
 	retrieveEngine interfaces.RetrieveEngineRegistry,
 	redisClient *redis.Client,
@@ -115,13 +151,65 @@ func NewKnowledgeService(
 		fileSvc:         fileSvc,
 		modelService:    modelService,
 		task:            task,
+		taskInspector:   taskInspector,
 		graphEngine:     graphEngine,
 		retrieveEngine:  retrieveEngine,
 		redisClient:     redisClient,
 		kbShareService:  kbShareService,
+		deleteSem:       make(chan struct{}, deleteConcurrency(config)),
+		downloadSem:     make(chan struct{}, fileURLDownloadConcurrency(config)),
 	}, nil
 }
 
+// deleteConcurrency returns the configured global limit of concurrent delete subtasks
+func deleteConcurrency(cfg *config.Config) int {
+	if cfg != nil && cfg.KnowledgeBase != nil && cfg.KnowledgeBase.DeleteConcurrency > 0 {
+		return cfg.KnowledgeBase.DeleteConcurrency
+	}
+	return defaultDeleteConcurrency
+}
+
+// manualContentMaxLength returns the configured maximum number of runes allowed in manual knowledge content
+func (s *knowledgeService) manualContentMaxLength() int {
+	if s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.ManualContentMaxLength > 0 {
+		return s.config.KnowledgeBase.ManualContentMaxLength
+	}
+	return defaultManualContentMaxLength
+}
+
+// taskPayloadInlineMaxSize returns the configured maximum size in bytes of an async task payload
+// kept inline; larger payloads offload their bulky fields to object storage
+func (s *knowledgeService) taskPayloadInlineMaxSize() int {
+	if s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.TaskPayloadInlineMaxSize > 0 {
+		return s.config.KnowledgeBase.TaskPayloadInlineMaxSize
+	}
+	return defaultTaskPayloadInlineMaxSize
+}
+
+// validateManualContentLength rejects manual content longer than the configured limit
+func (s *knowledgeService) validateManualContentLength(content string) error {
+	maxLength := s.manualContentMaxLength()
+	if len([]rune(content)) > maxLength {
+		return werrors.NewValidationError(fmt.Sprintf("内容长度超出限制（最多%d个字符）", maxLength))
+	}
+	return nil
+}
+
+// runDeleteSubtask runs fn while holding a slot of the global delete concurrency limit,
+// so that many concurrent deletes (e.g. during clone or resync) cannot overwhelm the backends.
+func (s *knowledgeService) runDeleteSubtask(ctx context.Context, fn func() error) error {
+	if s.deleteSem == nil {
+		return fn()
+	}
+	select {
+	case s.deleteSem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.deleteSem }()
+	return fn()
+}
+
 // GetRepository gets the knowledge repository
 // Parameters:
 //   - ctx: Context with authentication and request information
@@ -132,9 +220,10 @@ func (s *knowledgeService) GetRepository() interfaces.KnowledgeRepository {
 	return s.repo
 }
 
-// isKnowledgeDeleting checks if a knowledge entry is being deleted.
-// This is used to prevent async tasks from conflicting with deletion operations.
-func (s *knowledgeService) isKnowledgeDeleting(ctx context.Context, tenantID uint64, knowledgeID string) bool {
+// isKnowledgeProcessingAborted checks if a knowledge entry is being deleted or its processing was cancelled.
+// This is used to stop async tasks at their checkpoints without touching the parse status,
+// so they do not conflict with deletion or cancellation.
+func (s *knowledgeService) isKnowledgeProcessingAborted(ctx context.Context, tenantID uint64, knowledgeID string) bool {
 	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
 	if err != nil {
 		// If we can't find the knowledge, assume it's deleted
@@ -144,13 +233,187 @@ func (s *knowledgeService) isKnowledgeDeleting(ctx context.Context, tenantID uin
 	if knowledge == nil {
 		return true
 	}
-	return knowledge.ParseStatus == types.ParseStatusDeleting
+	return knowledge.ParseStatus == types.ParseStatusDeleting || knowledge.ParseStatus == types.ParseStatusCancelled
+}
+
+// failKnowledgeProcessing marks the knowledge as failed with the message. If the processing was cancelled
+// or the knowledge is being deleted in the meantime, the status is left alone so that cancelled items
+// stay cancelled and can be re-parsed later.
+func (s *knowledgeService) failKnowledgeProcessing(ctx context.Context, knowledge *types.Knowledge, message string) {
+	if s.isKnowledgeProcessingAborted(ctx, knowledge.TenantID, knowledge.ID) {
+		logger.Infof(ctx, "Knowledge %s was cancelled or is being deleted, not marking it as failed: %s",
+			knowledge.ID, message)
+		return
+	}
+	knowledge.ParseStatus = types.ParseStatusFailed
+	knowledge.ErrorMessage = message
+	knowledge.UpdatedAt = time.Now()
+	s.repo.UpdateKnowledge(ctx, knowledge)
+}
+
+const (
+	knowledgeOpLockKeyPrefix = "knowledge_op_lock:"
+	// knowledgeOpLockTTL bounds how long a crashed holder can block other operations
+	knowledgeOpLockTTL = 10 * time.Minute
+	// knowledgeProcessLockTTL bounds the lock once it is handed off to async processing,
+	// the processing task extends it again every time it (re)starts
+	knowledgeProcessLockTTL = time.Hour
+)
+
+// releaseKnowledgeLockScript deletes the lock only if it is still owned by the caller's token
+var releaseKnowledgeLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// extendKnowledgeLockScript resets the lock TTL only if it is still owned by the caller's token
+var extendKnowledgeLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// getKnowledgeOpLockKey returns the Redis key for the per-knowledge mutation lock
+func getKnowledgeOpLockKey(knowledgeID string) string {
+	return knowledgeOpLockKeyPrefix + knowledgeID
+}
+
+// knowledgeLock is a per-knowledge operation lock held by the caller, see lockKnowledge
+type knowledgeLock struct {
+	s           *knowledgeService
+	ctx         context.Context
+	knowledgeID string
+	token       string
+	handedOff   bool
+}
+
+// Unlock releases the lock, unless it has been handed off to async processing
+func (l *knowledgeLock) Unlock() {
+	if l.handedOff {
+		return
+	}
+	l.s.releaseKnowledgeLock(l.ctx, l.knowledgeID, l.token)
+}
+
+// HandOff passes the lock to the async processing that completes the operation and returns the
+// token the processing releases it with. Unlock becomes a no-op.
+func (l *knowledgeLock) HandOff() string {
+	l.handedOff = true
+	l.s.extendKnowledgeLock(l.ctx, l.knowledgeID, l.token)
+	return l.token
+}
+
+// lockKnowledge acquires the per-knowledge lock that serializes mutating operations
+// (reparse, delete, update, enable, reindex, re-embed, tag and image info updates) on the same document.
+// The lock must be released with Unlock when the operation finishes, or handed off with HandOff
+// when the operation completes in async processing. Another holder is reported as a conflict
+// error; if Redis can't be reached the operation is refused rather than run unlocked.
+func (s *knowledgeService) lockKnowledge(ctx context.Context, knowledgeID string) (*knowledgeLock, error) {
+	lock := &knowledgeLock{s: s, ctx: context.WithoutCancel(ctx), knowledgeID: knowledgeID}
+	if s.redisClient == nil {
+		return lock, nil
+	}
+
+	token := uuid.New().String()
+	acquired, err := s.redisClient.SetNX(ctx, getKnowledgeOpLockKey(knowledgeID), token, knowledgeOpLockTTL).Result()
+	if err != nil {
+		logger.Errorf(ctx, "Failed to acquire knowledge lock %s: %v", knowledgeID, err)
+		return nil, werrors.NewInternalServerError("获取知识操作锁失败，请稍后再试")
+	}
+	if !acquired {
+		logger.Warnf(ctx, "Knowledge %s is locked by another operation", knowledgeID)
+		return nil, werrors.NewConflictError("该知识正在执行其他操作，请稍后再试")
+	}
+	lock.token = token
+	return lock, nil
+}
+
+// isKnowledgeLockConflict reports whether err is lockKnowledge's error for a lock held by another operation
+func isKnowledgeLockConflict(err error) bool {
+	appErr, ok := werrors.IsAppError(err)
+	return ok && appErr.Code == werrors.ErrConflict
+}
+
+// canOverrideProcessingLock reports whether delete and cancel may go ahead without the lock: the lock
+// of a pending or processing knowledge is normally held by its own processing, which stops at its
+// next checkpoint once the status changes and then releases the lock
+func canOverrideProcessingLock(knowledge *types.Knowledge) bool {
+	return knowledge.ParseStatus == types.ParseStatusPending || knowledge.ParseStatus == types.ParseStatusProcessing
+}
+
+// lockKnowledgeList acquires the locks of several knowledge items, either all of them or none.
+// With overrideProcessing, items locked by their own processing are skipped, see canOverrideProcessingLock
+func (s *knowledgeService) lockKnowledgeList(ctx context.Context,
+	knowledgeList []*types.Knowledge, overrideProcessing bool,
+) (func(), error) {
+	locks := make([]*knowledgeLock, 0, len(knowledgeList))
+	unlockAll := func() {
+		for _, lock := range locks {
+			lock.Unlock()
+		}
+	}
+	for _, knowledge := range knowledgeList {
+		lock, err := s.lockKnowledge(ctx, knowledge.ID)
+		if err != nil {
+			if overrideProcessing && isKnowledgeLockConflict(err) && canOverrideProcessingLock(knowledge) {
+				continue
+			}
+			unlockAll()
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return unlockAll, nil
+}
+
+// releaseKnowledgeLock releases a lock handed off by HandOff; an empty token is ignored
+func (s *knowledgeService) releaseKnowledgeLock(ctx context.Context, knowledgeID, token string) {
+	if s.redisClient == nil || token == "" {
+		return
+	}
+	releaseCtx := context.WithoutCancel(ctx)
+	if err := releaseKnowledgeLockScript.Run(releaseCtx, s.redisClient,
+		[]string{getKnowledgeOpLockKey(knowledgeID)}, token).Err(); err != nil {
+		logger.Errorf(releaseCtx, "Failed to release knowledge lock %s: %v", knowledgeID, err)
+	}
+}
+
+// extendKnowledgeLock resets the TTL of a held lock to knowledgeProcessLockTTL
+func (s *knowledgeService) extendKnowledgeLock(ctx context.Context, knowledgeID, token string) {
+	if s.redisClient == nil || token == "" {
+		return
+	}
+	extended, err := extendKnowledgeLockScript.Run(ctx, s.redisClient,
+		[]string{getKnowledgeOpLockKey(knowledgeID)}, token, knowledgeProcessLockTTL.Milliseconds()).Int()
+	if err != nil {
+		logger.Errorf(ctx, "Failed to extend knowledge lock %s: %v", knowledgeID, err)
+		return
+	}
+	if extended == 0 {
+		logger.Warnf(ctx, "Knowledge lock %s is no longer held by this operation", knowledgeID)
+	}
 }
 
-// CreateKnowledgeFromFile creates a knowledge entry from an uploaded file
+// CreateKnowledgeFromFile creates a knowledge entry from an uploaded file.
+// Deprecated: use CreateKnowledgeFromFileWithResult, which does not report duplicates as errors.
 func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 	kbID string, file *multipart.FileHeader, metadata map[string]string, enableMultimodel *bool, customFileName string, tagID string,
+	expiresAt *time.Time, chunkingPreset string, nameConflict string,
 ) (*types.Knowledge, error) {
+	result, err := s.CreateKnowledgeFromFileWithResult(ctx,
+		kbID, file, metadata, enableMultimodel, customFileName, tagID, expiresAt, chunkingPreset, nameConflict)
+	return legacyKnowledgeCreateResult(result, err, types.NewDuplicateFileError)
+}
+
+// CreateKnowledgeFromFileWithResult creates a knowledge entry from an uploaded file. When the same file already
+// exists in the knowledge base the existing knowledge is returned as a duplicate instead of an error.
+func (s *knowledgeService) CreateKnowledgeFromFileWithResult(ctx context.Context,
+	kbID string, file *multipart.FileHeader, metadata map[string]string, enableMultimodel *bool, customFileName string, tagID string,
+	expiresAt *time.Time, chunkingPreset string, nameConflict string,
+) (*types.KnowledgeCreateResult, error) {
 	logger.Info(ctx, "Start creating knowledge from file")
 
 	// Use custom filename if provided, otherwise use original filename
@@ -169,35 +432,13 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
 		return nil, err
 	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
+	}
 
 	// 检查多模态配置完整性 - 只在图片文件时校验
-	// 检查是否为图片文件
-	if !IsImageType(getFileType(fileName)) {
-		logger.Info(ctx, "Non-image file with multimodal enabled, skipping COS/VLM validation")
-	} else {
-		// 检查COS配置
-		switch kb.StorageConfig.Provider {
-		case "cos":
-			if kb.StorageConfig.SecretID == "" || kb.StorageConfig.SecretKey == "" ||
-				kb.StorageConfig.Region == "" || kb.StorageConfig.BucketName == "" ||
-				kb.StorageConfig.AppID == "" {
-				logger.Error(ctx, "COS configuration incomplete for image multimodal processing")
-				return nil, werrors.NewBadRequestError("上传图片文件需要完整的对象存储配置信息, 请前往系统设置页面进行补全")
-			}
-		case "minio":
-			if kb.StorageConfig.BucketName == "" {
-				logger.Error(ctx, "MinIO configuration incomplete for image multimodal processing")
-				return nil, werrors.NewBadRequestError("上传图片文件需要完整的对象存储配置信息, 请前往系统设置页面进行补全")
-			}
-		}
-
-		// 检查VLM配置
-		if !kb.VLMConfig.Enabled || kb.VLMConfig.ModelID == "" {
-			logger.Error(ctx, "VLM model is not configured")
-			return nil, werrors.NewBadRequestError("上传图片文件需要设置VLM模型")
-		}
-
-		logger.Info(ctx, "Image multimodal configuration validation passed")
+	if err := s.validateImageMultimodalConfig(ctx, kb, getFileType(fileName)); err != nil {
+		return nil, err
 	}
 
 	// Validate file type
@@ -235,7 +476,7 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 			logger.Errorf(ctx, "Failed to update existing knowledge: %v", err)
 			return nil, err
 		}
-		return existingKnowledge, types.NewDuplicateFileError(existingKnowledge)
+		return types.NewDuplicateKnowledgeResult(existingKnowledge), nil
 	}
 
 	// Check storage quota
@@ -245,10 +486,47 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		return nil, types.NewStorageQuotaExceededError()
 	}
 
+	knowledge, err := s.createFileKnowledge(ctx, kb, file, fileName, hash, &types.KnowledgeFileUploadOptions{
+		Metadata:         metadata,
+		EnableMultimodel: enableMultimodel,
+		TagID:            tagID,
+		ExpiresAt:        expiresAt,
+		ChunkingPreset:   chunkingPreset,
+		NameConflict:     nameConflict,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &types.KnowledgeCreateResult{Knowledge: knowledge}, nil
+}
+
+// legacyKnowledgeCreateResult converts a create result into the (knowledge, error) pair of the original
+// create methods, which report a duplicate as a *types.DuplicateKnowledgeError carrying the existing knowledge
+func legacyKnowledgeCreateResult(result *types.KnowledgeCreateResult, err error,
+	duplicateError func(*types.Knowledge) *types.DuplicateKnowledgeError,
+) (*types.Knowledge, error) {
+	if err != nil {
+		return nil, err
+	}
+	if result.WasDuplicate {
+		return result.DuplicateOf, duplicateError(result.DuplicateOf)
+	}
+	return result.Knowledge, nil
+}
+
+// createFileKnowledge creates the knowledge record of an uploaded file that passed the type, duplicate and
+// quota checks, saves the file to storage and enqueues its document processing task
+func (s *knowledgeService) createFileKnowledge(ctx context.Context,
+	kb *types.KnowledgeBase, file *multipart.FileHeader, fileName string, hash string,
+	opts *types.KnowledgeFileUploadOptions,
+) (*types.Knowledge, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	kbID := kb.ID
+
 	// Convert metadata to JSON format if provided
 	var metadataJSON types.JSON
-	if metadata != nil {
-		metadataBytes, err := json.Marshal(metadata)
+	if opts.Metadata != nil {
+		metadataBytes, err := json.Marshal(opts.Metadata)
 		if err != nil {
 			logger.Errorf(ctx, "Failed to marshal metadata: %v", err)
 			return nil, err
@@ -262,13 +540,17 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		logger.Errorf(ctx, "Invalid filename: %s", fileName)
 		return nil, werrors.NewValidationError("文件名包含非法字符")
 	}
+	safeFilename, err := s.resolveKnowledgeFileName(ctx, tenantID, kbID, safeFilename, opts.NameConflict)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create knowledge record
 	logger.Info(ctx, "Creating knowledge record")
 	knowledge := &types.Knowledge{
 		TenantID:         tenantID,
 		KnowledgeBaseID:  kbID,
-		TagID:            tagID, // 设置分类ID，用于知识分类管理
+		TagID:            opts.TagID, // 设置分类ID，用于知识分类管理
 		Type:             "file",
 		Title:            safeFilename,
 		FileName:         safeFilename,
@@ -282,6 +564,13 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		EmbeddingModelID: kb.EmbeddingModelID,
 		Metadata:         metadataJSON,
 	}
+	if knowledge.ExpiresAt, err = resolveKnowledgeExpiry(kb, opts.ExpiresAt, knowledge.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := s.validateChunkingPreset(ctx, kb, opts.ChunkingPreset); err != nil {
+		return nil, err
+	}
+	knowledge.ChunkingPreset = opts.ChunkingPreset
 	// Save knowledge record to database
 	logger.Info(ctx, "Saving knowledge record to database")
 	if err := s.repo.CreateKnowledge(ctx, knowledge); err != nil {
@@ -306,12 +595,7 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 
 	// Enqueue document processing task to Asynq
 	logger.Info(ctx, "Enqueuing document processing task to Asynq")
-	enableMultimodelValue := false
-	if enableMultimodel != nil {
-		enableMultimodelValue = *enableMultimodel
-	} else {
-		enableMultimodelValue = kb.IsMultimodalEnabled()
-	}
+	enableMultimodelValue := s.resolveEnableMultimodel(ctx, kb, opts.EnableMultimodel)
 
 	// Check question generation config
 	enableQuestionGeneration := false
@@ -365,6 +649,182 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 	return knowledge, nil
 }
 
+// maxKnowledgeFileBatchSize 批量上传单次最多处理的文件数
+const maxKnowledgeFileBatchSize = 200
+
+// CreateKnowledgeFromFiles creates knowledge entries from a batch of uploaded files. All hashes are computed
+// first so duplicates are found with a single query, and the storage quota is checked against the aggregate
+// size of the new files so that a batch is never imported partially past the limit.
+func (s *knowledgeService) CreateKnowledgeFromFiles(ctx context.Context,
+	kbID string, files []*multipart.FileHeader, opts *types.KnowledgeFileUploadOptions,
+) ([]*types.KnowledgeFileUploadResult, error) {
+	if len(files) == 0 {
+		return nil, werrors.NewBadRequestError("至少上传一个文件")
+	}
+	if len(files) > maxKnowledgeFileBatchSize {
+		return nil, werrors.NewBadRequestError(fmt.Sprintf("单次最多上传 %d 个文件", maxKnowledgeFileBatchSize))
+	}
+	if opts == nil {
+		opts = &types.KnowledgeFileUploadOptions{}
+	}
+	logger.Infof(ctx, "Start creating knowledge from %d files, knowledge base ID: %s", len(files), kbID)
+
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil, err
+	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
+	}
+
+	results := make([]*types.KnowledgeFileUploadResult, len(files))
+	fail := func(i int, err error) {
+		results[i].Status = types.KnowledgeFileUploadStatusError
+		results[i].Error = err.Error()
+		if appErr, ok := werrors.IsAppError(err); ok {
+			results[i].Error = appErr.Message
+		}
+		logger.Warnf(ctx, "Failed to create knowledge from file %s: %v", secutils.SanitizeForLog(results[i].FileName), err)
+	}
+
+	// Step 1: validate every file and compute its hash before touching the database
+	hashes := make([]string, len(files))
+	for i, file := range files {
+		results[i] = &types.KnowledgeFileUploadResult{FileName: file.Filename}
+		if !isValidFileType(file.Filename) {
+			fail(i, ErrInvalidFileType)
+			continue
+		}
+		if err := s.validateImageMultimodalConfig(ctx, kb, getFileType(file.Filename)); err != nil {
+			fail(i, err)
+			continue
+		}
+		hash, err := calculateFileHash(file)
+		if err != nil {
+			fail(i, err)
+			continue
+		}
+		hashes[i] = hash
+	}
+
+	// Step 2: one existence query for the whole batch
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	lookup := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		if hash != "" {
+			lookup = append(lookup, hash)
+		}
+	}
+	existingList, err := s.repo.ListKnowledgeByFileHashes(ctx, tenantID, kbID, lookup)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to check knowledge existence: %v", err)
+		return nil, err
+	}
+	existing := make(map[string]*types.Knowledge, len(existingList))
+	for _, knowledge := range existingList {
+		if _, ok := existing[knowledge.FileHash]; !ok {
+			existing[knowledge.FileHash] = knowledge
+		}
+	}
+
+	// Files repeated within the batch are created once and reported as duplicates of the first one
+	pending := make([]int, 0, len(files))
+	firstInBatch := make(map[string]int)
+	repeats := make(map[int]int)
+	var totalSize int64
+	for i, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+		if knowledge, ok := existing[hash]; ok {
+			// Update creation time for existing knowledge, same as a single upload
+			if err := s.repo.UpdateKnowledgeColumn(ctx, knowledge.ID, "created_at", time.Now()); err != nil {
+				logger.Warnf(ctx, "Failed to update existing knowledge %s: %v", knowledge.ID, err)
+			}
+			results[i].Status = types.KnowledgeFileUploadStatusDuplicate
+			results[i].Knowledge = knowledge
+			continue
+		}
+		if first, ok := firstInBatch[hash]; ok {
+			repeats[i] = first
+			continue
+		}
+		firstInBatch[hash] = i
+		pending = append(pending, i)
+		totalSize += files[i].Size
+	}
+
+	// Step 3: check the storage quota against the aggregate size before creating anything
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if len(pending) > 0 && tenantInfo.StorageQuota > 0 && tenantInfo.StorageUsed+totalSize > tenantInfo.StorageQuota {
+		logger.Errorf(ctx, "Storage quota exceeded, batch of %d files needs %d bytes", len(pending), totalSize)
+		return nil, types.NewStorageQuotaExceededError()
+	}
+
+	// Step 4: create the records and enqueue the document processing tasks
+	created := 0
+	for _, i := range pending {
+		knowledge, err := s.createFileKnowledge(ctx, kb, files[i], files[i].Filename, hashes[i], opts)
+		if err != nil {
+			fail(i, err)
+			continue
+		}
+		results[i].Status = types.KnowledgeFileUploadStatusCreated
+		results[i].Knowledge = knowledge
+		created++
+	}
+	for i, first := range repeats {
+		results[i].Status = types.KnowledgeFileUploadStatusDuplicate
+		results[i].Knowledge = results[first].Knowledge
+		if results[first].Status == types.KnowledgeFileUploadStatusError {
+			results[i].Status = types.KnowledgeFileUploadStatusError
+			results[i].Error = results[first].Error
+		}
+	}
+
+	logger.Infof(ctx, "Batch file upload finished: files=%d, created=%d", len(files), created)
+	return results, nil
+}
+
+// resolveKnowledgeFileName applies the name conflict policy to a file name that may already be used
+// by another document of the knowledge base, returning the name to use.
+func (s *knowledgeService) resolveKnowledgeFileName(ctx context.Context,
+	tenantID uint64, kbID string, fileName string, policy string,
+) (string, error) {
+	switch policy {
+	case "", types.KnowledgeNameConflictAllow:
+		return fileName, nil
+	case types.KnowledgeNameConflictRename, types.KnowledgeNameConflictReject:
+	default:
+		return "", werrors.NewBadRequestError(fmt.Sprintf("不支持的重名处理方式: %s", policy))
+	}
+
+	ext := path.Ext(fileName)
+	stem := strings.TrimSuffix(fileName, ext)
+	names, err := s.repo.ListFileNamesByPrefix(ctx, tenantID, kbID, stem)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(names))
+	for _, name := range names {
+		taken[name] = true
+	}
+	if !taken[fileName] {
+		return fileName, nil
+	}
+	if policy == types.KnowledgeNameConflictReject {
+		return "", werrors.NewConflictError(fmt.Sprintf("知识库中已存在同名文档: %s", fileName))
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		if !taken[candidate] {
+			logger.Infof(ctx, "File name %s already exists in knowledge base %s, renamed to %s", fileName, kbID, candidate)
+			return candidate, nil
+		}
+	}
+}
+
 // CreateKnowledgeFromURL creates a knowledge entry from a URL source
 // tagID is optional - when provided, the knowledge will be assigned to the specified tag/category.
 // isFileURL reports whether the given URL should be treated as a direct file download.
@@ -381,15 +841,37 @@ func isFileURL(rawURL, fileName, fileType string) bool {
 	return fileName != "" || fileType != ""
 }
 
+// Deprecated: use CreateKnowledgeFromURLWithResult, which does not report duplicates as errors.
 func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
 	kbID string, rawURL string, fileName string, fileType string, enableMultimodel *bool, title string, tagID string,
+	expiresAt *time.Time, chunkingPreset string,
 ) (*types.Knowledge, error) {
+	result, err := s.CreateKnowledgeFromURLWithResult(ctx,
+		kbID, rawURL, fileName, fileType, enableMultimodel, title, tagID, expiresAt, chunkingPreset)
+	return legacyKnowledgeCreateResult(result, err, types.NewDuplicateURLError)
+}
+
+// CreateKnowledgeFromURLWithResult creates a knowledge entry from a web page or file URL. When the URL already
+// exists in the knowledge base the existing knowledge is returned as a duplicate instead of an error.
+func (s *knowledgeService) CreateKnowledgeFromURLWithResult(ctx context.Context,
+	kbID string, rawURL string, fileName string, fileType string, enableMultimodel *bool, title string, tagID string,
+	expiresAt *time.Time, chunkingPreset string,
+) (*types.KnowledgeCreateResult, error) {
 	logger.Info(ctx, "Start creating knowledge from URL")
 	logger.Infof(ctx, "Knowledge base ID: %s, URL: %s", kbID, rawURL)
 
 	// Route to file_url logic when the URL points to a downloadable file
 	if isFileURL(rawURL, fileName, fileType) {
-		return s.createKnowledgeFromFileURL(ctx, kbID, rawURL, fileName, fileType, enableMultimodel, title, tagID)
+		return s.createKnowledgeFromFileURL(
+			ctx, kbID, rawURL, fileName, fileType, enableMultimodel, title, tagID, expiresAt, chunkingPreset,
+		)
+	}
+	// The path has no file extension: ask the server whether it serves a file download
+	if probedName, probedType, ok := s.probeFileURL(ctx, rawURL); ok {
+		logger.Infof(ctx, "URL detected as file download by HEAD request, file type: %s", probedType)
+		return s.createKnowledgeFromFileURL(
+			ctx, kbID, rawURL, probedName, probedType, enableMultimodel, title, tagID, expiresAt, chunkingPreset,
+		)
 	}
 
 	url := rawURL
@@ -401,6 +883,9 @@ func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
 		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
 		return nil, err
 	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
+	}
 
 	// Validate URL format and security
 	logger.Info(ctx, "Validating URL")
@@ -415,6 +900,12 @@ func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
 		return nil, ErrInvalidURL
 	}
 
+	// Allow-list check: scheme / port / domain
+	if allowed, reason := s.checkURLImportAllowList(url); !allowed {
+		logger.Errorf(ctx, "URL rejected by import allow-list: %s, reason: %s", url, reason)
+		return nil, ErrInvalidURL
+	}
+
 	// Check if URL already exists in the knowledge base
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	logger.Infof(ctx, "Checking if URL exists, tenant ID: %d", tenantID)
@@ -437,7 +928,7 @@ func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
 			logger.Errorf(ctx, "Failed to update existing knowledge: %v", err)
 			return nil, err
 		}
-		return existingKnowledge, types.NewDuplicateURLError(existingKnowledge)
+		return types.NewDuplicateKnowledgeResult(existingKnowledge), nil
 	}
 
 	// Check storage quota
@@ -465,6 +956,13 @@ func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
 		TagID:            tagID, // 设置分类ID，用于知识分类管理
 	}
 
+	if knowledge.ExpiresAt, err = resolveKnowledgeExpiry(kb, expiresAt, knowledge.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := s.validateChunkingPreset(ctx, kb, chunkingPreset); err != nil {
+		return nil, err
+	}
+	knowledge.ChunkingPreset = chunkingPreset
 	// Save knowledge record
 	logger.Infof(ctx, "Saving knowledge record to database, ID: %s", knowledge.ID)
 	if err := s.repo.CreateKnowledge(ctx, knowledge); err != nil {
@@ -474,12 +972,7 @@ func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
 
 	// Enqueue URL processing task to Asynq
 	logger.Info(ctx, "Enqueuing URL processing task to Asynq")
-	enableMultimodelValue := false
-	if enableMultimodel != nil {
-		enableMultimodelValue = *enableMultimodel
-	} else {
-		enableMultimodelValue = kb.IsMultimodalEnabled()
-	}
+	enableMultimodelValue := s.resolveEnableMultimodel(ctx, kb, enableMultimodel)
 
 	// Check question generation config
 	enableQuestionGeneration := false
@@ -504,19 +997,19 @@ func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
 	payloadBytes, err := json.Marshal(taskPayload)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to marshal URL process task payload: %v", err)
-		return knowledge, nil
+		return &types.KnowledgeCreateResult{Knowledge: knowledge}, nil
 	}
 
 	task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
 	info, err := s.task.Enqueue(task)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to enqueue URL process task: %v", err)
-		return knowledge, nil
+		return &types.KnowledgeCreateResult{Knowledge: knowledge}, nil
 	}
 	logger.Infof(ctx, "Enqueued URL process task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, knowledge.ID)
 
 	logger.Infof(ctx, "Knowledge from URL created successfully, ID: %s", knowledge.ID)
-	return knowledge, nil
+	return &types.KnowledgeCreateResult{Knowledge: knowledge}, nil
 }
 
 // allowedFileURLExtensions defines the supported file extensions for file URL import
@@ -526,71 +1019,252 @@ var allowedFileURLExtensions = map[string]bool{
 	"pdf":  true,
 	"docx": true,
 	"doc":  true,
+	"pptx": true,
+	"ppt":  true,
+	"epub": true,
+	"jpg":  true,
+	"jpeg": true,
+	"png":  true,
+	"gif":  true,
+	"webp": true,
+	"bmp":  true,
 }
 
-// maxFileURLSize is the maximum allowed file size for file URL import (10MB)
-const maxFileURLSize = 10 * 1024 * 1024
+// fileURLContentTypeExtensions maps the Content-Type of a file download to its allowedFileURLExtensions entry
+var fileURLContentTypeExtensions = map[string]string{
+	"text/plain":         "txt",
+	"text/markdown":      "md",
+	"text/x-markdown":    "md",
+	"application/pdf":    "pdf",
+	"application/msword": "doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   "docx",
+	"application/vnd.ms-powerpoint":                                             "ppt",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": "pptx",
+	"application/epub+zip": "epub",
+	"image/jpeg":           "jpg",
+	"image/png":            "png",
+	"image/gif":            "gif",
+	"image/webp":           "webp",
+	"image/bmp":            "bmp",
+}
 
-// extractFileNameFromURL extracts the filename from a URL path
-func extractFileNameFromURL(rawURL string) string {
-	u, err := url.Parse(rawURL)
+// fileURLTypeFromHeader detects a supported file download from response headers. A Content-Disposition
+// file name with a supported extension wins, otherwise the Content-Type is mapped through
+// fileURLContentTypeExtensions. fileType is empty when the response is not a supported file.
+func fileURLTypeFromHeader(header http.Header) (fileName, fileType string) {
+	fileName = extractFileNameFromContentDisposition(header.Get("Content-Disposition"))
+	if ext := strings.ToLower(strings.TrimPrefix(path.Ext(fileName), ".")); allowedFileURLExtensions[ext] {
+		return fileName, ext
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
 	if err != nil {
-		return ""
+		return "", ""
 	}
-	base := path.Base(u.Path)
-	if base == "." || base == "/" {
-		return ""
+	if ext, ok := fileURLContentTypeExtensions[strings.ToLower(mediaType)]; ok {
+		return fileName, ext
 	}
-	return base
+	return "", ""
 }
 
-// extractFileNameFromContentDisposition extracts filename from Content-Disposition header
-func extractFileNameFromContentDisposition(header string) string {
-	// e.g. attachment; filename="document.pdf" or filename*=UTF-8''document.pdf
-	for _, part := range strings.Split(header, ";") {
-		part = strings.TrimSpace(part)
-		if strings.HasPrefix(strings.ToLower(part), "filename=") {
-			name := strings.TrimPrefix(part, "filename=")
-			name = strings.TrimPrefix(part[len("filename="):], "")
-			name = strings.Trim(name, `"'`)
-			if name != "" {
-				return name
-			}
-		}
+// probeFileURL sends a HEAD request to rawURL and reports whether it serves a supported file download,
+// see fileURLTypeFromHeader. Only done when file_url_download.probe_content_type is enabled and the URL
+// passes the SSRF and import allow-list checks; any failure returns ok=false so the caller keeps its
+// current behavior.
+func (s *knowledgeService) probeFileURL(ctx context.Context, rawURL string) (fileName, fileType string, ok bool) {
+	if s.config == nil || s.config.KnowledgeBase == nil || s.config.KnowledgeBase.FileURLDownload == nil ||
+		!s.config.KnowledgeBase.FileURLDownload.ProbeContentType {
+		return "", "", false
+	}
+	if !isValidURL(rawURL) || !secutils.IsValidURL(rawURL) {
+		return "", "", false
+	}
+	if safe, reason := secutils.IsSSRFSafeURL(rawURL); !safe {
+		logger.Warnf(ctx, "Skip HEAD request for URL rejected by SSRF protection: %s", reason)
+		return "", "", false
+	}
+	if allowed, reason := s.checkURLImportAllowList(rawURL); !allowed {
+		logger.Warnf(ctx, "Skip HEAD request for URL rejected by import allow-list: %s", reason)
+		return "", "", false
 	}
-	return ""
-}
-
-// createKnowledgeFromFileURL is the internal implementation for file URL knowledge creation.
-// Called by CreateKnowledgeFromURL when the URL is detected as a direct file download.
-func (s *knowledgeService) createKnowledgeFromFileURL(
-	ctx context.Context,
-	kbID string,
-	fileURL string,
-	fileName string,
-	fileType string,
-	enableMultimodel *bool,
-	title string,
-	tagID string,
-) (*types.Knowledge, error) {
-	logger.Info(ctx, "Start creating knowledge from file URL")
-	logger.Infof(ctx, "Knowledge base ID: %s, file URL: %s", kbID, fileURL)
 
-	// Get knowledge base configuration
-	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	client := secutils.NewSSRFSafeHTTPClient(secutils.SSRFSafeHTTPClientConfig{
+		Timeout:      fileURLProbeTimeout,
+		MaxRedirects: 3,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
-		return nil, err
+		return "", "", false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Warnf(ctx, "HEAD request for URL failed, falling back to extension detection: %v", err)
+		return "", "", false
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		logger.Warnf(ctx, "HEAD request for URL returned status %d, falling back to extension detection", resp.StatusCode)
+		return "", "", false
 	}
 
-	// Validate URL format and security (static check only, no HEAD request)
-	if !isValidURL(fileURL) || !secutils.IsValidURL(fileURL) {
-		logger.Error(ctx, "Invalid or unsafe file URL format")
-		return nil, ErrInvalidURL
+	fileName, fileType = fileURLTypeFromHeader(resp.Header)
+	return fileName, fileType, fileType != ""
+}
+
+// validateImageMultimodalConfig checks that the knowledge base has the object storage and VLM
+// configuration required to process an image file. Shared by file, url and file_url imports so
+// an image import fails fast with the same message instead of failing later in ProcessDocument.
+// When multimodal is globally disabled images are rejected right away, as they cannot be parsed as text.
+func (s *knowledgeService) validateImageMultimodalConfig(ctx context.Context,
+	kb *types.KnowledgeBase, fileType string,
+) error {
+	if !IsImageType(strings.ToLower(fileType)) {
+		return nil
 	}
-	if safe, reason := secutils.IsSSRFSafeURL(fileURL); !safe {
-		logger.Errorf(ctx, "File URL rejected for SSRF protection: %s, reason: %s", fileURL, reason)
-		return nil, ErrInvalidURL
+	if s.multimodalGloballyDisabled() {
+		logger.Warn(ctx, "Multimodal processing is globally disabled, rejecting image file")
+		return werrors.NewBadRequestError("多模态处理已被管理员临时关闭，暂时无法上传图片文件")
+	}
+	// 检查对象存储配置
+	switch kb.StorageConfig.Provider {
+	case "cos":
+		if kb.StorageConfig.SecretID == "" || kb.StorageConfig.SecretKey == "" ||
+			kb.StorageConfig.Region == "" || kb.StorageConfig.BucketName == "" ||
+			kb.StorageConfig.AppID == "" {
+			logger.Error(ctx, "COS configuration incomplete for image multimodal processing")
+			return werrors.NewBadRequestError("上传图片文件需要完整的对象存储配置信息, 请前往系统设置页面进行补全")
+		}
+	case "minio":
+		if kb.StorageConfig.BucketName == "" {
+			logger.Error(ctx, "MinIO configuration incomplete for image multimodal processing")
+			return werrors.NewBadRequestError("上传图片文件需要完整的对象存储配置信息, 请前往系统设置页面进行补全")
+		}
+	}
+	// 检查VLM配置
+	if !kb.VLMConfig.Enabled || kb.VLMConfig.ModelID == "" {
+		logger.Error(ctx, "VLM model is not configured")
+		return werrors.NewBadRequestError("上传图片文件需要设置VLM模型")
+	}
+	logger.Info(ctx, "Image multimodal configuration validation passed")
+	return nil
+}
+
+// resolveEnableMultimodel returns the per-request multimodal override, falling back to the knowledge base setting.
+// The global kill-switch forces it off.
+func (s *knowledgeService) resolveEnableMultimodel(ctx context.Context,
+	kb *types.KnowledgeBase, enableMultimodel *bool,
+) bool {
+	enabled := kb.IsMultimodalEnabled()
+	if enableMultimodel != nil {
+		enabled = *enableMultimodel
+	}
+	return s.applyMultimodalKillSwitch(ctx, enabled)
+}
+
+// multimodalGloballyDisabled reports whether multimodal (VLM) processing is switched off fleet-wide through
+// knowledge_base.disable_multimodal, e.g. during a VLM provider outage
+func (s *knowledgeService) multimodalGloballyDisabled() bool {
+	return s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.DisableMultimodal
+}
+
+// applyMultimodalKillSwitch returns enabled unless multimodal is globally disabled, in which case documents
+// are processed as text only
+func (s *knowledgeService) applyMultimodalKillSwitch(ctx context.Context, enabled bool) bool {
+	if enabled && s.multimodalGloballyDisabled() {
+		logger.Warn(ctx, "Multimodal processing is globally disabled (knowledge_base.disable_multimodal), processing as text only")
+		return false
+	}
+	return enabled
+}
+
+// fileURLHTTPClient is shared by all file_url downloads so connections are pooled and reused.
+// Timeouts are applied per request through the context, see fileURLDownloadTimeout.
+var fileURLHTTPClient = &http.Client{Transport: newFileURLTransport()}
+
+func newFileURLTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 64
+	transport.MaxIdleConnsPerHost = defaultFileURLDownloadConcurrency
+	transport.IdleConnTimeout = 90 * time.Second
+	return transport
+}
+
+// fileURLDownloadConcurrency returns the configured global limit of concurrent file_url downloads
+func fileURLDownloadConcurrency(cfg *config.Config) int {
+	if cfg != nil && cfg.KnowledgeBase != nil && cfg.KnowledgeBase.FileURLDownload != nil &&
+		cfg.KnowledgeBase.FileURLDownload.MaxConcurrent > 0 {
+		return cfg.KnowledgeBase.FileURLDownload.MaxConcurrent
+	}
+	return defaultFileURLDownloadConcurrency
+}
+
+// fileURLDownloadTimeout returns the download timeout for fileType: the per-type override if set,
+// otherwise the configured default timeout
+func (s *knowledgeService) fileURLDownloadTimeout(fileType string) time.Duration {
+	if s.config == nil || s.config.KnowledgeBase == nil || s.config.KnowledgeBase.FileURLDownload == nil {
+		return defaultFileURLDownloadTimeout
+	}
+	cfg := s.config.KnowledgeBase.FileURLDownload
+	if timeout := cfg.TimeoutByFileType[strings.ToLower(fileType)]; timeout > 0 {
+		return timeout
+	}
+	if cfg.Timeout > 0 {
+		return cfg.Timeout
+	}
+	return defaultFileURLDownloadTimeout
+}
+
+// extractFileNameFromURL extracts the filename from a URL path
+func extractFileNameFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	base := path.Base(u.Path)
+	if base == "." || base == "/" {
+		return ""
+	}
+	return base
+}
+
+// extractFileNameFromContentDisposition extracts filename from Content-Disposition header
+func extractFileNameFromContentDisposition(header string) string {
+	// e.g. attachment; filename="document.pdf" or filename*=UTF-8''document.pdf
+	for _, part := range strings.Split(header, ";") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(strings.ToLower(part), "filename=") {
+			name := strings.TrimPrefix(part, "filename=")
+			name = strings.TrimPrefix(part[len("filename="):], "")
+			name = strings.Trim(name, `"'`)
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// createKnowledgeFromFileURL is the internal implementation for file URL knowledge creation.
+// Called by CreateKnowledgeFromURLWithResult when the URL is detected as a direct file download.
+func (s *knowledgeService) createKnowledgeFromFileURL(
+	ctx context.Context,
+	kbID string,
+	fileURL string,
+	fileName string,
+	fileType string,
+	enableMultimodel *bool,
+	title string,
+	tagID string,
+	expiresAt *time.Time,
+	chunkingPreset string,
+) (*types.KnowledgeCreateResult, error) {
+	logger.Info(ctx, "Start creating knowledge from file URL")
+	logger.Infof(ctx, "Knowledge base ID: %s, file URL: %s", kbID, fileURL)
+
+	// Get knowledge base configuration
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil, err
 	}
 
 	// Resolve fileName: user-provided > extracted from URL path
@@ -598,19 +1272,48 @@ func (s *knowledgeService) createKnowledgeFromFileURL(
 		fileName = extractFileNameFromURL(fileURL)
 	}
 
-	// Resolve fileType: user-provided > inferred from fileName
+	// Resolve fileType: user-provided > inferred from fileName > detected from the response headers
+	userFileType := fileType != ""
 	if fileType == "" && fileName != "" {
 		fileType = getFileType(fileName)
 	}
+	if !userFileType && !allowedFileURLExtensions[strings.ToLower(fileType)] {
+		if _, probedType, ok := s.probeFileURL(ctx, fileURL); ok {
+			fileType = probedType
+		}
+	}
 
 	// Validate file extension against whitelist (if we can determine it)
 	if fileType != "" {
 		if !allowedFileURLExtensions[strings.ToLower(fileType)] {
 			logger.Errorf(ctx, "Unsupported file type for file URL import: %s", fileType)
-			return nil, werrors.NewBadRequestError(fmt.Sprintf("不支持的文件类型: %s，仅支持 txt, md, pdf, docx, doc", fileType))
+			return nil, werrors.NewBadRequestError(
+				fmt.Sprintf("不支持的文件类型: %s，仅支持 txt, md, pdf, docx, doc 及常见图片格式", fileType))
+		}
+	}
+
+	// 图片文件开启多模态时，提前校验对象存储与VLM配置；多模态被全局关闭时图片直接拒绝
+	enableMultimodelValue := s.resolveEnableMultimodel(ctx, kb, enableMultimodel)
+	if enableMultimodelValue || s.multimodalGloballyDisabled() {
+		if err := s.validateImageMultimodalConfig(ctx, kb, fileType); err != nil {
+			return nil, err
 		}
 	}
 
+	// Validate URL format and security (static check only, no HEAD request)
+	if !isValidURL(fileURL) || !secutils.IsValidURL(fileURL) {
+		logger.Error(ctx, "Invalid or unsafe file URL format")
+		return nil, ErrInvalidURL
+	}
+	if safe, reason := secutils.IsSSRFSafeURL(fileURL); !safe {
+		logger.Errorf(ctx, "File URL rejected for SSRF protection: %s, reason: %s", fileURL, reason)
+		return nil, ErrInvalidURL
+	}
+	if allowed, reason := s.checkURLImportAllowList(fileURL); !allowed {
+		logger.Errorf(ctx, "File URL rejected by import allow-list: %s, reason: %s", fileURL, reason)
+		return nil, ErrInvalidURL
+	}
+
 	// Use title as display name if fileName is still empty
 	displayName := fileName
 	if displayName == "" {
@@ -644,7 +1347,7 @@ func (s *knowledgeService) createKnowledgeFromFileURL(
 			logger.Errorf(ctx, "Failed to update existing knowledge: %v", err)
 			return nil, err
 		}
-		return existingKnowledge, types.NewDuplicateURLError(existingKnowledge)
+		return types.NewDuplicateKnowledgeResult(existingKnowledge), nil
 	}
 
 	// Check storage quota
@@ -676,19 +1379,19 @@ func (s *knowledgeService) createKnowledgeFromFileURL(
 		knowledge.Title = displayName
 	}
 
+	if knowledge.ExpiresAt, err = resolveKnowledgeExpiry(kb, expiresAt, knowledge.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := s.validateChunkingPreset(ctx, kb, chunkingPreset); err != nil {
+		return nil, err
+	}
+	knowledge.ChunkingPreset = chunkingPreset
 	if err := s.repo.CreateKnowledge(ctx, knowledge); err != nil {
 		logger.Errorf(ctx, "Failed to create knowledge record: %v", err)
 		return nil, err
 	}
 
 	// Build async task payload
-	enableMultimodelValue := false
-	if enableMultimodel != nil {
-		enableMultimodelValue = *enableMultimodel
-	} else {
-		enableMultimodelValue = kb.IsMultimodalEnabled()
-	}
-
 	enableQuestionGeneration := false
 	questionCount := 3
 	if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
@@ -713,1936 +1416,5882 @@ func (s *knowledgeService) createKnowledgeFromFileURL(
 	payloadBytes, err := json.Marshal(taskPayload)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to marshal file URL process task payload: %v", err)
-		return knowledge, nil
+		return &types.KnowledgeCreateResult{Knowledge: knowledge}, nil
 	}
 
 	task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
 	info, err := s.task.Enqueue(task)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to enqueue file URL process task: %v", err)
-		return knowledge, nil
+		return &types.KnowledgeCreateResult{Knowledge: knowledge}, nil
 	}
 	logger.Infof(ctx, "Enqueued file URL process task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, knowledge.ID)
 
 	logger.Infof(ctx, "Knowledge from file URL created successfully, ID: %s", knowledge.ID)
-	return knowledge, nil
+	return &types.KnowledgeCreateResult{Knowledge: knowledge}, nil
 }
 
-// CreateKnowledgeFromPassage creates a knowledge entry from text passages
-func (s *knowledgeService) CreateKnowledgeFromPassage(ctx context.Context,
-	kbID string, passage []string,
-) (*types.Knowledge, error) {
-	return s.createKnowledgeFromPassageInternal(ctx, kbID, passage, false)
+// validateChunkingPreset checks that the chunking preset exists in the tenant owning the knowledge base
+func (s *knowledgeService) validateChunkingPreset(ctx context.Context, kb *types.KnowledgeBase, name string) error {
+	if name == "" {
+		return nil
+	}
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, kb.TenantID)
+	if err != nil {
+		return err
+	}
+	if tenant.ChunkingPresets.Find(name) == nil {
+		return werrors.NewBadRequestError(fmt.Sprintf("分块预设「%s」不存在", name))
+	}
+	return nil
 }
 
-// CreateKnowledgeFromPassageSync creates a knowledge entry from text passages and waits for indexing to complete.
-func (s *knowledgeService) CreateKnowledgeFromPassageSync(ctx context.Context,
-	kbID string, passage []string,
-) (*types.Knowledge, error) {
-	return s.createKnowledgeFromPassageInternal(ctx, kbID, passage, true)
+// resolveKnowledgeExpiry returns the explicitly requested expiration time, or the knowledge base
+// default retention when none is given
+func resolveKnowledgeExpiry(kb *types.KnowledgeBase, expiresAt *time.Time, createdAt time.Time) (*time.Time, error) {
+	if expiresAt == nil {
+		return kb.RetentionConfig.DefaultExpiresAt(createdAt), nil
+	}
+	if !expiresAt.After(createdAt) {
+		return nil, werrors.NewBadRequestError("过期时间必须晚于当前时间")
+	}
+	return expiresAt, nil
 }
 
-// CreateKnowledgeFromManual creates or saves manual Markdown knowledge content.
-func (s *knowledgeService) CreateKnowledgeFromManual(ctx context.Context,
-	kbID string, payload *types.ManualKnowledgePayload,
-) (*types.Knowledge, error) {
-	logger.Info(ctx, "Start creating manual knowledge entry")
+// maxCloudStorageImportObjects 单次云存储导入最多处理的对象数
+const maxCloudStorageImportObjects = 100
 
-	if payload == nil {
-		return nil, werrors.NewBadRequestError("请求内容不能为空")
+var (
+	// cloudStorageBucketPattern 存储桶命名规则（S3 与 COS 的公共子集，COS 桶名包含 -appid 后缀）
+	cloudStorageBucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	// cloudStorageRegionPattern 地域名称只允许小写字母、数字和连字符，避免拼接出非预期的访问地址
+	cloudStorageRegionPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+)
+
+// parseCloudStoragePath splits s3://bucket/key or cos://bucket/key into scheme, bucket and object key.
+// An empty key or a key ending with "/" denotes a prefix.
+func parseCloudStoragePath(rawPath string) (scheme, bucket, key string, err error) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(rawPath), "://")
+	if !ok {
+		return "", "", "", werrors.NewBadRequestError("云存储路径格式错误，应为 s3://bucket/key 或 cos://bucket/key")
+	}
+	scheme = strings.ToLower(scheme)
+	if scheme != "s3" && scheme != "cos" {
+		return "", "", "", werrors.NewBadRequestError("仅支持 s3:// 和 cos:// 路径")
+	}
+	bucket, key, _ = strings.Cut(rest, "/")
+	if !cloudStorageBucketPattern.MatchString(bucket) || strings.Contains(bucket, "..") {
+		return "", "", "", werrors.NewBadRequestError("存储桶名称不合法")
+	}
+	if strings.ContainsAny(key, "\x00\r\n") {
+		return "", "", "", werrors.NewBadRequestError("对象路径包含非法字符")
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", "", "", werrors.NewBadRequestError("对象路径包含非法字符")
+		}
 	}
+	return scheme, bucket, key, nil
+}
 
-	cleanContent := secutils.CleanMarkdown(payload.Content)
-	if strings.TrimSpace(cleanContent) == "" {
-		return nil, werrors.NewValidationError("内容不能为空")
+// cloudStorageImportTimeout 单个云存储对象复制任务的超时时间
+const cloudStorageImportTimeout = 10 * time.Minute
+
+// newCloudObjectSource validates the credentials and builds a read-only client for the bucket.
+// Credentials must be supplied with every import: the platform's own storage credentials are never
+// reused, since they can read every tenant's files.
+func newCloudObjectSource(scheme, bucket string, creds *types.CloudStorageCredentials) (file.ObjectSource, error) {
+	if creds == nil || creds.SecretID == "" || creds.SecretKey == "" {
+		return nil, werrors.NewBadRequestError("访问凭证不完整，secret_id 和 secret_key 不能为空")
 	}
-	if len([]rune(cleanContent)) > manualContentMaxLength {
-		return nil, werrors.NewValidationError(fmt.Sprintf("内容长度超出限制（最多%d个字符）", manualContentMaxLength))
+	if creds.Region != "" && !cloudStorageRegionPattern.MatchString(creds.Region) {
+		return nil, werrors.NewBadRequestError("地域名称不合法")
 	}
 
-	safeTitle, ok := secutils.ValidateInput(payload.Title)
-	if !ok {
-		return nil, werrors.NewValidationError("标题包含非法字符或超出长度限制")
+	if scheme == "cos" {
+		if creds.Region == "" {
+			return nil, werrors.NewBadRequestError("cos:// 路径需要提供 region")
+		}
+		return file.NewCosObjectSource(bucket, creds.Region, creds.SecretID, creds.SecretKey)
 	}
 
-	status := strings.ToLower(strings.TrimSpace(payload.Status))
-	if status == "" {
-		status = types.ManualKnowledgeStatusDraft
+	endpoint := creds.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+		if creds.Region != "" {
+			endpoint = fmt.Sprintf("s3.%s.amazonaws.com", creds.Region)
+		}
 	}
-	if status != types.ManualKnowledgeStatusDraft && status != types.ManualKnowledgeStatusPublish {
-		return nil, werrors.NewValidationError("状态仅支持 draft 或 publish")
+	// minio 客户端只接受 host[:port]，不允许携带协议或路径
+	if u, err := url.Parse("//" + endpoint); err != nil || u.Host != endpoint || u.User != nil {
+		return nil, werrors.NewBadRequestError("endpoint 格式错误，应为 host[:port]")
+	}
+	useSSL := creds.UseSSL == nil || *creds.UseSSL
+	endpointURL := "https://" + endpoint
+	if !useSSL {
+		endpointURL = "http://" + endpoint
+	}
+	if safe, reason := secutils.IsSSRFSafeURL(endpointURL); !safe {
+		return nil, werrors.NewBadRequestError(fmt.Sprintf("endpoint 不可访问: %s", reason))
 	}
+	// 连接时再次校验解析出的地址，防止 DNS 重绑定
+	transport := secutils.NewSSRFSafeHTTPClient(secutils.DefaultSSRFSafeHTTPClientConfig()).Transport
+	return file.NewS3ObjectSource(endpoint, creds.Region, creds.SecretID, creds.SecretKey, bucket, useSSL, transport)
+}
 
+// CreateKnowledgeFromCloudStorage imports objects from a user-owned s3:// or cos:// bucket.
+// Objects are listed and validated in the request; each one is then copied into the configured
+// file storage by an asynq task and processed like an uploaded file, so private buckets can be
+// ingested without exposing a public URL.
+func (s *knowledgeService) CreateKnowledgeFromCloudStorage(ctx context.Context,
+	kbID string, req *types.CloudStorageImportRequest,
+) (*types.CloudStorageImportResult, error) {
 	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
 		return nil, err
 	}
-
-	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	now := time.Now()
-	title := safeTitle
-	if title == "" {
-		title = fmt.Sprintf("Knowledge-%s", now.Format("20060102-150405"))
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
 	}
 
-	fileName := ensureManualFileName(title)
-	meta := types.NewManualKnowledgeMetadata(cleanContent, status, 1)
-
-	knowledge := &types.Knowledge{
-		TenantID:         tenantID,
-		KnowledgeBaseID:  kbID,
-		Type:             types.KnowledgeTypeManual,
-		Title:            title,
-		Description:      "",
-		Source:           types.KnowledgeTypeManual,
-		ParseStatus:      types.ManualKnowledgeStatusDraft,
-		EnableStatus:     "disabled",
-		CreatedAt:        now,
-		UpdatedAt:        now,
-		EmbeddingModelID: kb.EmbeddingModelID,
-		FileName:         fileName,
-		FileType:         types.KnowledgeTypeManual,
-		TagID:            payload.TagID, // 设置分类ID，用于知识分类管理
-	}
-	if err := knowledge.SetManualMetadata(meta); err != nil {
-		logger.Errorf(ctx, "Failed to set manual metadata: %v", err)
+	scheme, bucket, key, err := parseCloudStoragePath(req.Path)
+	if err != nil {
 		return nil, err
 	}
-	knowledge.EnsureManualDefaults()
-
-	if status == types.ManualKnowledgeStatusPublish {
-		knowledge.ParseStatus = "pending"
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, werrors.NewBadRequestError("过期时间必须晚于当前时间")
 	}
-
-	if err := s.repo.CreateKnowledge(ctx, knowledge); err != nil {
-		logger.Errorf(ctx, "Failed to create manual knowledge record: %v", err)
+	source, err := newCloudObjectSource(scheme, bucket, req.Credentials)
+	if err != nil {
 		return nil, err
 	}
-
-	if status == types.ManualKnowledgeStatusPublish {
-		logger.Infof(ctx, "Manual knowledge created, scheduling indexing, ID: %s", knowledge.ID)
-		s.triggerManualProcessing(ctx, kb, knowledge, cleanContent, false)
+	sealedCredentials, err := sealTaskSecret(req.Credentials)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to seal cloud storage credentials: %v", err)
+		return nil, werrors.NewInternalServerError("无法创建导入任务")
 	}
 
-	return knowledge, nil
-}
-
-// createKnowledgeFromPassageInternal consolidates the common logic for creating knowledge from passages.
-// When syncMode is true, chunk processing is performed synchronously; otherwise, it's processed asynchronously.
-func (s *knowledgeService) createKnowledgeFromPassageInternal(ctx context.Context,
-	kbID string, passage []string, syncMode bool,
-) (*types.Knowledge, error) {
-	if syncMode {
-		logger.Info(ctx, "Start creating knowledge from passage (sync)")
+	var objects []file.ObjectInfo
+	if key == "" || strings.HasSuffix(key, "/") {
+		objects, err = source.ListObjects(ctx, key, maxCloudStorageImportObjects+1)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to list cloud storage objects %s: %v", secutils.SanitizeForLog(req.Path), err)
+			return nil, werrors.NewBadRequestError("访问对象存储失败，请检查路径和访问凭证")
+		}
+		if len(objects) == 0 {
+			return nil, werrors.NewBadRequestError("指定前缀下没有可导入的对象")
+		}
+		if len(objects) > maxCloudStorageImportObjects {
+			return nil, werrors.NewBadRequestError(
+				fmt.Sprintf("单次最多导入 %d 个对象，请缩小前缀范围", maxCloudStorageImportObjects))
+		}
 	} else {
-		logger.Info(ctx, "Start creating knowledge from passage")
+		obj, err := source.StatObject(ctx, key)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to stat cloud storage object %s: %v", secutils.SanitizeForLog(req.Path), err)
+			return nil, werrors.NewBadRequestError("访问对象存储失败，请检查路径和访问凭证")
+		}
+		objects = []file.ObjectInfo{obj}
 	}
-	logger.Infof(ctx, "Knowledge base ID: %s, passage count: %d", kbID, len(passage))
+	logger.Infof(ctx, "Importing %d objects from %s into knowledge base %s",
+		len(objects), secutils.SanitizeForLog(req.Path), kbID)
 
-	// 验证段落内容安全性
-	safePassages := make([]string, 0, len(passage))
-	for i, p := range passage {
-		safePassage, isValid := secutils.ValidateInput(p)
-		if !isValid {
-			logger.Errorf(ctx, "Invalid passage content at index %d", i)
-			return nil, werrors.NewValidationError(fmt.Sprintf("段落 %d 包含非法内容", i+1))
+	result := &types.CloudStorageImportResult{
+		Imported:   make([]*types.Knowledge, 0, len(objects)),
+		Duplicated: make(map[string]string),
+		Failed:     make([]*types.CloudStorageImportFailure, 0),
+	}
+	for _, obj := range objects {
+		knowledge, err := s.enqueueCloudStorageObject(ctx, kb, scheme, bucket, obj, sealedCredentials, req)
+		if err != nil {
+			var dupErr *types.DuplicateKnowledgeError
+			if errors.As(err, &dupErr) && knowledge != nil {
+				result.Duplicated[obj.Key] = knowledge.ID
+				continue
+			}
+			logger.Warnf(ctx, "Failed to import cloud storage object %s: %v", secutils.SanitizeForLog(obj.Key), err)
+			result.Failed = append(result.Failed, &types.CloudStorageImportFailure{Key: obj.Key, Error: err.Error()})
+			continue
 		}
-		safePassages = append(safePassages, safePassage)
+		result.Imported = append(result.Imported, knowledge)
 	}
+	logger.Infof(ctx, "Cloud storage import enqueued: imported=%d, duplicated=%d, failed=%d",
+		len(result.Imported), len(result.Duplicated), len(result.Failed))
+	return result, nil
+}
 
-	// Get knowledge base configuration
-	logger.Info(ctx, "Getting knowledge base configuration")
-	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+// enqueueCloudStorageObject validates one object, creates its pending knowledge and enqueues the copy task
+func (s *knowledgeService) enqueueCloudStorageObject(ctx context.Context,
+	kb *types.KnowledgeBase, scheme, bucket string, obj file.ObjectInfo, sealedCredentials string,
+	req *types.CloudStorageImportRequest,
+) (*types.Knowledge, error) {
+	fileName := path.Base(obj.Key)
+	if !isValidFileType(fileName) {
+		return nil, werrors.NewBadRequestError(fmt.Sprintf("不支持的文件类型: %s", getFileType(fileName)))
+	}
+	if err := s.validateImageMultimodalConfig(ctx, kb, getFileType(fileName)); err != nil {
 		return nil, err
 	}
+	if obj.Size > secutils.GetMaxFileSize() {
+		return nil, werrors.NewBadRequestError(fmt.Sprintf("文件大小不能超过%dMB", secutils.GetMaxFileSizeMB()))
+	}
+	safeFilename, isValid := secutils.ValidateInput(fileName)
+	if !isValid {
+		return nil, werrors.NewValidationError("文件名包含非法字符")
+	}
 
-	// Create knowledge record
-	if syncMode {
-		logger.Info(ctx, "Creating knowledge record (sync)")
-	} else {
-		logger.Info(ctx, "Creating knowledge record")
+	// 内容 hash 要在任务中读取对象后才能得到，这里先按文件名和大小去重
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	exists, existingKnowledge, err := s.repo.CheckKnowledgeExists(ctx, tenantID, kb.ID, &types.KnowledgeCheckParams{
+		Type:     "file",
+		FileName: safeFilename,
+		FileSize: obj.Size,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return existingKnowledge, types.NewDuplicateFileError(existingKnowledge)
+	}
+
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenantInfo.StorageQuota > 0 && tenantInfo.StorageUsed >= tenantInfo.StorageQuota {
+		return nil, types.NewStorageQuotaExceededError()
 	}
+
 	knowledge := &types.Knowledge{
 		ID:               uuid.New().String(),
-		TenantID:         ctx.Value(types.TenantIDContextKey).(uint64),
-		KnowledgeBaseID:  kbID,
-		Type:             "passage",
+		TenantID:         tenantID,
+		KnowledgeBaseID:  kb.ID,
+		TagID:            req.TagID,
+		Type:             "file",
+		Title:            safeFilename,
+		FileName:         safeFilename,
+		FileType:         getFileType(safeFilename),
+		FileSize:         obj.Size,
+		Source:           fmt.Sprintf("%s://%s/%s", scheme, bucket, obj.Key),
 		ParseStatus:      "pending",
 		EnableStatus:     "disabled",
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 		EmbeddingModelID: kb.EmbeddingModelID,
 	}
-
-	// Save knowledge record
-	logger.Infof(ctx, "Saving knowledge record to database, ID: %s", knowledge.ID)
+	if knowledge.ExpiresAt, err = resolveKnowledgeExpiry(kb, req.ExpiresAt, knowledge.CreatedAt); err != nil {
+		return nil, err
+	}
 	if err := s.repo.CreateKnowledge(ctx, knowledge); err != nil {
-		logger.Errorf(ctx, "Failed to create knowledge record: %v", err)
 		return nil, err
 	}
 
-	// Process passages
-	if syncMode {
-		logger.Info(ctx, "Processing passage synchronously")
-		s.processDocumentFromPassage(ctx, kb, knowledge, safePassages)
-		logger.Infof(ctx, "Knowledge from passage created successfully (sync), ID: %s", knowledge.ID)
-	} else {
-		// Enqueue passage processing task to Asynq
-		logger.Info(ctx, "Enqueuing passage processing task to Asynq")
-		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-
-		// Check question generation config
-		enableQuestionGeneration := false
-		questionCount := 3 // default
-		if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
-			enableQuestionGeneration = true
-			if kb.QuestionGenerationConfig.QuestionCount > 0 {
-				questionCount = kb.QuestionGenerationConfig.QuestionCount
-			}
-		}
-
-		taskPayload := types.DocumentProcessPayload{
-			TenantID:                 tenantID,
-			KnowledgeID:              knowledge.ID,
-			KnowledgeBaseID:          kbID,
-			Passages:                 safePassages,
-			EnableMultimodel:         false, // 文本段落不支持多模态
-			EnableQuestionGeneration: enableQuestionGeneration,
-			QuestionCount:            questionCount,
-		}
-
-		payloadBytes, err := json.Marshal(taskPayload)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to marshal passage process task payload: %v", err)
-			// 即使入队失败，也返回knowledge
-			return knowledge, nil
-		}
-
-		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
-		info, err := s.task.Enqueue(task)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to enqueue passage process task: %v", err)
-			return knowledge, nil
-		}
-		logger.Infof(ctx, "Enqueued passage process task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, knowledge.ID)
-		logger.Infof(ctx, "Knowledge from passage created successfully, ID: %s", knowledge.ID)
+	payloadBytes, err := json.Marshal(types.CloudStorageImportPayload{
+		TenantID:          tenantID,
+		KnowledgeID:       knowledge.ID,
+		KnowledgeBaseID:   kb.ID,
+		Scheme:            scheme,
+		Bucket:            bucket,
+		Key:               obj.Key,
+		SealedCredentials: sealedCredentials,
+		EnableMultimodel:  s.resolveEnableMultimodel(ctx, kb, req.EnableMultimodel),
+	})
+	if err == nil {
+		task := asynq.NewTask(types.TypeCloudStorageImport, payloadBytes,
+			asynq.Queue("default"), asynq.MaxRetry(3), asynq.Timeout(cloudStorageImportTimeout))
+		_, err = s.task.Enqueue(task)
 	}
-	return knowledge, nil
-}
-
-// GetKnowledgeByID retrieves a knowledge entry by its ID
-func (s *knowledgeService) GetKnowledgeByID(ctx context.Context, id string) (*types.Knowledge, error) {
-	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-
-	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, id)
 	if err != nil {
-		logger.ErrorWithFields(ctx, err, map[string]interface{}{
-			"knowledge_id": id,
-			"tenant_id":    tenantID,
-		})
-		return nil, err
+		logger.Errorf(ctx, "Failed to enqueue cloud storage import task: %v", err)
+		s.deleteUnsavedKnowledge(ctx, knowledge)
+		return nil, fmt.Errorf("failed to enqueue import task: %w", err)
 	}
-
-	logger.Infof(ctx, "Knowledge retrieved successfully, ID: %s, type: %s", knowledge.ID, knowledge.Type)
 	return knowledge, nil
 }
 
-// GetKnowledgeByIDOnly retrieves knowledge by ID without tenant filter (for permission resolution).
-func (s *knowledgeService) GetKnowledgeByIDOnly(ctx context.Context, id string) (*types.Knowledge, error) {
-	return s.repo.GetKnowledgeByIDOnly(ctx, id)
+// deleteUnsavedKnowledge removes a knowledge row whose file never reached file storage
+func (s *knowledgeService) deleteUnsavedKnowledge(ctx context.Context, knowledge *types.Knowledge) {
+	if err := s.repo.DeleteKnowledge(ctx, knowledge.TenantID, knowledge.ID); err != nil {
+		logger.Errorf(ctx, "Failed to delete knowledge %s without file: %v", knowledge.ID, err)
+	}
 }
 
-// ListKnowledgeByKnowledgeBaseID returns all knowledge entries in a knowledge base
-func (s *knowledgeService) ListKnowledgeByKnowledgeBaseID(ctx context.Context,
-	kbID string,
-) ([]*types.Knowledge, error) {
-	return s.repo.ListKnowledgeByKnowledgeBaseID(ctx, ctx.Value(types.TenantIDContextKey).(uint64), kbID)
-}
+// ProcessCloudStorageImport handles the cloud storage import task: it copies the object into
+// file storage and enqueues the document processing task, like an uploaded file
+func (s *knowledgeService) ProcessCloudStorageImport(ctx context.Context, t *asynq.Task) error {
+	var payload types.CloudStorageImportPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "failed to unmarshal cloud storage import task payload: %v", err)
+		return nil
+	}
+	ctx = logger.WithField(ctx, "cloud_storage_import", payload.KnowledgeID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	isLastRetry := retryCount >= maxRetry
 
-// ListPagedKnowledgeByKnowledgeBaseID returns paginated knowledge entries in a knowledge base
-func (s *knowledgeService) ListPagedKnowledgeByKnowledgeBaseID(ctx context.Context,
-	kbID string, page *types.Pagination, tagID string, keyword string, fileType string,
-) (*types.PageResult, error) {
-	knowledges, total, err := s.repo.ListPagedKnowledgeByKnowledgeBaseID(ctx,
-		ctx.Value(types.TenantIDContextKey).(uint64), kbID, page, tagID, keyword, fileType)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
+	if err != nil || knowledge == nil {
+		// 导入完成前知识已被删除
+		logger.Infof(ctx, "Knowledge %s no longer exists, skipping cloud storage import", payload.KnowledgeID)
+		return nil
+	}
+	if knowledge.ParseStatus != "pending" || knowledge.FilePath != "" {
+		return nil
+	}
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
 	if err != nil {
-		return nil, err
+		logger.Errorf(ctx, "failed to get knowledge base: %v", err)
+		return nil
 	}
 
-	return types.NewPageResult(total, page, knowledges), nil
-}
-
-// DeleteKnowledge deletes a knowledge entry and all related resources
-func (s *knowledgeService) DeleteKnowledge(ctx context.Context, id string) error {
-	// Get the knowledge entry
-	knowledge, err := s.repo.GetKnowledgeByID(ctx, ctx.Value(types.TenantIDContextKey).(uint64), id)
+	var creds types.CloudStorageCredentials
+	if err := openTaskSecret(payload.SealedCredentials, &creds); err != nil {
+		s.failCloudStorageImport(ctx, knowledge, err)
+		return nil
+	}
+	source, err := newCloudObjectSource(payload.Scheme, payload.Bucket, &creds)
+	if err != nil {
+		s.failCloudStorageImport(ctx, knowledge, err)
+		return nil
+	}
+	data, err := readCloudStorageObject(ctx, source, payload.Key)
 	if err != nil {
+		var appErr *werrors.AppError
+		if errors.As(err, &appErr) || isLastRetry {
+			s.failCloudStorageImport(ctx, knowledge, err)
+			return nil
+		}
 		return err
 	}
+	sum := md5.Sum(data)
+	hash := hex.EncodeToString(sum[:])
 
-	// Mark as deleting first to prevent async task conflicts
-	// This ensures that any running async tasks will detect the deletion and abort
-	originalStatus := knowledge.ParseStatus
-	knowledge.ParseStatus = types.ParseStatusDeleting
-	knowledge.UpdatedAt = time.Now()
-	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge failed to mark as deleting")
-		// Continue with deletion even if marking fails
-	} else {
-		logger.Infof(ctx, "Marked knowledge %s as deleting (previous status: %s)", id, originalStatus)
+	exists, existingKnowledge, err := s.repo.CheckKnowledgeExists(ctx, payload.TenantID, kb.ID,
+		&types.KnowledgeCheckParams{Type: "file", FileHash: hash})
+	if err != nil {
+		return err
 	}
-
-	wg := errgroup.Group{}
-	// Delete knowledge embeddings from vector store
-	wg.Go(func() error {
-		tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
-			s.retrieveEngine,
-			tenantInfo.GetEffectiveEngines(),
-		)
-		if err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
-			return err
-		}
-		embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, knowledge.EmbeddingModelID)
-		if err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
-			return err
-		}
-		if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), knowledge.Type); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
-			return err
-		}
-		return nil
-	})
-
-	// Delete all chunks associated with this knowledge
-	wg.Go(func() error {
-		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete chunks failed")
-			return err
-		}
+	if exists && existingKnowledge.ID != knowledge.ID {
+		logger.Infof(ctx, "Cloud storage object %s duplicates knowledge %s, dropping import",
+			secutils.SanitizeForLog(payload.Key), existingKnowledge.ID)
+		s.deleteUnsavedKnowledge(ctx, knowledge)
 		return nil
-	})
+	}
 
-	// Delete the physical file if it exists
-	wg.Go(func() error {
-		if knowledge.FilePath != "" {
-			if err := s.fileSvc.DeleteFile(ctx, knowledge.FilePath); err != nil {
-				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete file failed")
-			}
-		}
-		tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-		tenantInfo.StorageUsed -= knowledge.StorageSize
-		if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, -knowledge.StorageSize); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge update tenant storage used failed")
+	filePath, err := s.fileSvc.SaveBytes(ctx, data, payload.TenantID, knowledge.FileName, false)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to save cloud storage object %s: %v", secutils.SanitizeForLog(payload.Key), err)
+		if isLastRetry {
+			s.deleteUnsavedKnowledge(ctx, knowledge)
+			return nil
 		}
-		return nil
-	})
+		return err
+	}
+	knowledge.FilePath = filePath
+	knowledge.FileSize = int64(len(data))
+	knowledge.FileHash = hash
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		return err
+	}
 
-	// Delete the knowledge graph
-	wg.Go(func() error {
-		namespace := types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID}
-		if err := s.graphEngine.DelGraph(ctx, []types.NameSpace{namespace}); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge graph failed")
-			return err
+	enableQuestionGeneration := false
+	questionCount := 3
+	if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
+		enableQuestionGeneration = true
+		if kb.QuestionGenerationConfig.QuestionCount > 0 {
+			questionCount = kb.QuestionGenerationConfig.QuestionCount
 		}
-		return nil
+	}
+	payloadBytes, err := json.Marshal(types.DocumentProcessPayload{
+		TenantID:                 payload.TenantID,
+		KnowledgeID:              knowledge.ID,
+		KnowledgeBaseID:          kb.ID,
+		FilePath:                 filePath,
+		FileName:                 knowledge.FileName,
+		FileType:                 knowledge.FileType,
+		EnableMultimodel:         payload.EnableMultimodel,
+		EnableQuestionGeneration: enableQuestionGeneration,
+		QuestionCount:            questionCount,
 	})
-
-	if err = wg.Wait(); err != nil {
+	if err != nil {
 		return err
 	}
-	// Delete the knowledge entry itself from the database
-	return s.repo.DeleteKnowledge(ctx, ctx.Value(types.TenantIDContextKey).(uint64), id)
+	task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
+	if _, err := s.task.Enqueue(task); err != nil {
+		logger.Errorf(ctx, "Failed to enqueue document process task: %v", err)
+		return err
+	}
+
+	if slices.Contains([]string{"csv", "xlsx", "xls"}, knowledge.FileType) {
+		NewDataTableSummaryTask(ctx, s.task, payload.TenantID, knowledge.ID, kb.SummaryModelID, kb.EmbeddingModelID)
+	}
+	return nil
 }
 
-// DeleteKnowledgeList deletes a knowledge entry and all related resources
-func (s *knowledgeService) DeleteKnowledgeList(ctx context.Context, ids []string) error {
-	if len(ids) == 0 {
-		return nil
+// readCloudStorageObject reads an object, enforcing the upload size limit
+func readCloudStorageObject(ctx context.Context, source file.ObjectSource, key string) ([]byte, error) {
+	maxSize := secutils.GetMaxFileSize()
+	reader, err := source.GetObject(ctx, key)
+	if err != nil {
+		return nil, err
 	}
-	// 1. Get the knowledge entry
-	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-	knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, tenantInfo.ID, ids)
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to read object: %w", err)
 	}
+	if int64(len(data)) > maxSize {
+		return nil, werrors.NewBadRequestError(fmt.Sprintf("文件大小不能超过%dMB", secutils.GetMaxFileSizeMB()))
+	}
+	return data, nil
+}
 
-	// Mark all as deleting first to prevent async task conflicts
-	for _, knowledge := range knowledgeList {
-		knowledge.ParseStatus = types.ParseStatusDeleting
-		knowledge.UpdatedAt = time.Now()
-		if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).WithField("knowledge_id", knowledge.ID).
-				Errorf("DeleteKnowledgeList failed to mark as deleting")
-			// Continue with deletion even if marking fails
-		}
+// failCloudStorageImport marks a cloud storage import as failed with the reason
+func (s *knowledgeService) failCloudStorageImport(ctx context.Context, knowledge *types.Knowledge, cause error) {
+	logger.Warnf(ctx, "Cloud storage import of knowledge %s failed: %v", knowledge.ID, cause)
+	knowledge.ParseStatus = types.ParseStatusFailed
+	knowledge.ErrorMessage = cause.Error()
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		logger.Errorf(ctx, "Failed to mark cloud storage import %s as failed: %v", knowledge.ID, err)
 	}
-	logger.Infof(ctx, "Marked %d knowledge entries as deleting", len(knowledgeList))
+}
 
-	wg := errgroup.Group{}
-	// 2. Delete knowledge embeddings from vector store
-	wg.Go(func() error {
-		tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
+// CreateKnowledgeFromPassage creates a knowledge entry from text passages
+func (s *knowledgeService) CreateKnowledgeFromPassage(ctx context.Context,
+	kbID string, passage []string,
+) (*types.Knowledge, error) {
+	return s.createKnowledgeFromPassageInternal(ctx, kbID, passage, false)
+}
+
+// CreateKnowledgeFromPassageSync creates a knowledge entry from text passages and waits for indexing to complete.
+func (s *knowledgeService) CreateKnowledgeFromPassageSync(ctx context.Context,
+	kbID string, passage []string,
+) (*types.Knowledge, error) {
+	return s.createKnowledgeFromPassageInternal(ctx, kbID, passage, true)
+}
+
+// normalizeManualContent strips a leading UTF-8 BOM, converts CRLF/CR line endings to LF and
+// drops trailing blank lines, so the stored content, its length check and docreader offsets
+// are stable across edits and export round-trips.
+func normalizeManualContent(content string) string {
+	content = strings.TrimPrefix(content, "\uFEFF")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+	lines := strings.Split(content, "\n")
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return strings.Join(lines[:end], "\n")
+}
+
+// CreateKnowledgeFromManual creates or saves manual Markdown knowledge content.
+func (s *knowledgeService) CreateKnowledgeFromManual(ctx context.Context,
+	kbID string, payload *types.ManualKnowledgePayload,
+) (*types.Knowledge, error) {
+	logger.Info(ctx, "Start creating manual knowledge entry")
+
+	if payload == nil {
+		return nil, werrors.NewBadRequestError("请求内容不能为空")
+	}
+
+	cleanContent := secutils.CleanMarkdown(normalizeManualContent(payload.Content))
+	if strings.TrimSpace(cleanContent) == "" {
+		return nil, werrors.NewValidationError("内容不能为空")
+	}
+	if err := s.validateManualContentLength(cleanContent); err != nil {
+		return nil, err
+	}
+
+	safeTitle, ok := secutils.ValidateInput(payload.Title)
+	if !ok {
+		return nil, werrors.NewValidationError("标题包含非法字符或超出长度限制")
+	}
+
+	status := strings.ToLower(strings.TrimSpace(payload.Status))
+	if status == "" {
+		status = types.ManualKnowledgeStatusDraft
+	}
+	if status != types.ManualKnowledgeStatusDraft && status != types.ManualKnowledgeStatusPublish {
+		return nil, werrors.NewValidationError("状态仅支持 draft 或 publish")
+	}
+
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil, err
+	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	now := time.Now()
+	title := safeTitle
+	if title == "" {
+		title = fmt.Sprintf("Knowledge-%s", now.Format("20060102-150405"))
+	}
+
+	fileName := ensureManualFileName(title)
+	meta := types.NewManualKnowledgeMetadata(cleanContent, status, 1)
+	meta.EnableAfterPublish = payload.EnableAfterPublish
+
+	knowledge := &types.Knowledge{
+		TenantID:         tenantID,
+		KnowledgeBaseID:  kbID,
+		Type:             types.KnowledgeTypeManual,
+		Title:            title,
+		Description:      "",
+		Source:           types.KnowledgeTypeManual,
+		ParseStatus:      types.ManualKnowledgeStatusDraft,
+		EnableStatus:     "disabled",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		EmbeddingModelID: kb.EmbeddingModelID,
+		FileName:         fileName,
+		FileType:         types.KnowledgeTypeManual,
+		TagID:            payload.TagID, // 设置分类ID，用于知识分类管理
+	}
+	if err := knowledge.SetManualMetadata(meta); err != nil {
+		logger.Errorf(ctx, "Failed to set manual metadata: %v", err)
+		return nil, err
+	}
+	knowledge.EnsureManualDefaults()
+
+	if status == types.ManualKnowledgeStatusPublish {
+		knowledge.ParseStatus = "pending"
+	}
+
+	if knowledge.ExpiresAt, err = resolveKnowledgeExpiry(kb, payload.ExpiresAt, knowledge.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateKnowledge(ctx, knowledge); err != nil {
+		logger.Errorf(ctx, "Failed to create manual knowledge record: %v", err)
+		return nil, err
+	}
+
+	if status == types.ManualKnowledgeStatusPublish {
+		logger.Infof(ctx, "Manual knowledge created, scheduling indexing, ID: %s", knowledge.ID)
+		s.triggerManualProcessing(ctx, kb, knowledge, cleanContent, false)
+	}
+
+	return knowledge, nil
+}
+
+// createKnowledgeFromPassageInternal consolidates the common logic for creating knowledge from passages.
+// When syncMode is true, chunk processing is performed synchronously; otherwise, it's processed asynchronously.
+func (s *knowledgeService) createKnowledgeFromPassageInternal(ctx context.Context,
+	kbID string, passage []string, syncMode bool,
+) (*types.Knowledge, error) {
+	if syncMode {
+		logger.Info(ctx, "Start creating knowledge from passage (sync)")
+	} else {
+		logger.Info(ctx, "Start creating knowledge from passage")
+	}
+	logger.Infof(ctx, "Knowledge base ID: %s, passage count: %d", kbID, len(passage))
+
+	// 验证段落内容安全性
+	safePassages := make([]string, 0, len(passage))
+	for i, p := range passage {
+		safePassage, isValid := secutils.ValidateInput(p)
+		if !isValid {
+			logger.Errorf(ctx, "Invalid passage content at index %d", i)
+			return nil, werrors.NewValidationError(fmt.Sprintf("段落 %d 包含非法内容", i+1))
+		}
+		safePassages = append(safePassages, safePassage)
+	}
+
+	// Get knowledge base configuration
+	logger.Info(ctx, "Getting knowledge base configuration")
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil, err
+	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
+	}
+
+	// Create knowledge record
+	if syncMode {
+		logger.Info(ctx, "Creating knowledge record (sync)")
+	} else {
+		logger.Info(ctx, "Creating knowledge record")
+	}
+	knowledge := &types.Knowledge{
+		ID:               uuid.New().String(),
+		TenantID:         ctx.Value(types.TenantIDContextKey).(uint64),
+		KnowledgeBaseID:  kbID,
+		Type:             "passage",
+		ParseStatus:      "pending",
+		EnableStatus:     "disabled",
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+		EmbeddingModelID: kb.EmbeddingModelID,
+	}
+
+	knowledge.ExpiresAt = kb.RetentionConfig.DefaultExpiresAt(knowledge.CreatedAt)
+	// Save knowledge record
+	logger.Infof(ctx, "Saving knowledge record to database, ID: %s", knowledge.ID)
+	if err := s.repo.CreateKnowledge(ctx, knowledge); err != nil {
+		logger.Errorf(ctx, "Failed to create knowledge record: %v", err)
+		return nil, err
+	}
+
+	// Process passages
+	if syncMode {
+		logger.Info(ctx, "Processing passage synchronously")
+		s.processDocumentFromPassage(ctx, kb, knowledge, safePassages)
+		logger.Infof(ctx, "Knowledge from passage created successfully (sync), ID: %s", knowledge.ID)
+	} else {
+		// Enqueue passage processing task to Asynq
+		logger.Info(ctx, "Enqueuing passage processing task to Asynq")
+		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+		// Check question generation config
+		enableQuestionGeneration := false
+		questionCount := 3 // default
+		if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
+			enableQuestionGeneration = true
+			if kb.QuestionGenerationConfig.QuestionCount > 0 {
+				questionCount = kb.QuestionGenerationConfig.QuestionCount
+			}
+		}
+
+		taskPayload := types.DocumentProcessPayload{
+			TenantID:                 tenantID,
+			KnowledgeID:              knowledge.ID,
+			KnowledgeBaseID:          kbID,
+			Passages:                 safePassages,
+			EnableMultimodel:         false, // 文本段落不支持多模态
+			EnableQuestionGeneration: enableQuestionGeneration,
+			QuestionCount:            questionCount,
+		}
+
+		payloadBytes, err := s.marshalDocumentProcessPayload(ctx, &taskPayload)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to marshal passage process task payload: %v", err)
+			// 即使入队失败，也返回knowledge
+			return knowledge, nil
+		}
+
+		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
+		info, err := s.task.Enqueue(task)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to enqueue passage process task: %v", err)
+			s.deleteOffloadedTaskData(ctx, taskPayload.OffloadURL)
+			return knowledge, nil
+		}
+		logger.Infof(ctx, "Enqueued passage process task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, knowledge.ID)
+		logger.Infof(ctx, "Knowledge from passage created successfully, ID: %s", knowledge.ID)
+	}
+	return knowledge, nil
+}
+
+// GetKnowledgeByID retrieves a knowledge entry by its ID
+func (s *knowledgeService) GetKnowledgeByID(ctx context.Context, id string) (*types.Knowledge, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": id,
+			"tenant_id":    tenantID,
+		})
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Knowledge retrieved successfully, ID: %s, type: %s", knowledge.ID, knowledge.Type)
+	return knowledge, nil
+}
+
+// listTextChunksByOffset returns the text chunks of a knowledge ordered by their offset in the source document
+func (s *knowledgeService) listTextChunksByOffset(ctx context.Context, knowledgeID string) ([]*types.Chunk, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	if _, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID); err != nil {
+		return nil, err
+	}
+	chunks, err := s.chunkRepo.ListChunksByKnowledgeID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	textChunks := make([]*types.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.ChunkType == types.ChunkTypeText {
+			textChunks = append(textChunks, chunk)
+		}
+	}
+	sort.Slice(textChunks, func(i, j int) bool {
+		if textChunks[i].StartAt != textChunks[j].StartAt {
+			return textChunks[i].StartAt < textChunks[j].StartAt
+		}
+		return textChunks[i].ChunkIndex < textChunks[j].ChunkIndex
+	})
+	return textChunks, nil
+}
+
+// GetChunkByOffset returns the text chunk covering runeOffset ([StartAt, EndAt)).
+// With chunk overlap an offset may fall into two chunks, the earlier one is returned.
+func (s *knowledgeService) GetChunkByOffset(ctx context.Context,
+	knowledgeID string, runeOffset int,
+) (*types.Chunk, error) {
+	if runeOffset < 0 {
+		return nil, werrors.NewBadRequestError("偏移量不能为负数")
+	}
+	chunks, err := s.listTextChunksByOffset(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		if chunk.StartAt > runeOffset {
+			break
+		}
+		if runeOffset < chunk.EndAt {
+			return chunk, nil
+		}
+	}
+	return nil, ErrChunkNotFound
+}
+
+// ResolveCitations maps each cited rune range to the text chunks overlapping it, so the QA layer
+// can build precise citations. Spans not covered by any chunk resolve to an empty chunk list.
+func (s *knowledgeService) ResolveCitations(ctx context.Context,
+	knowledgeID string, spans []types.CitationSpan,
+) ([]*types.CitationResolution, error) {
+	for _, span := range spans {
+		if span.Start < 0 || span.End <= span.Start {
+			return nil, werrors.NewBadRequestError(fmt.Sprintf("无效的引用区间: [%d, %d)", span.Start, span.End))
+		}
+	}
+	chunks, err := s.listTextChunksByOffset(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*types.CitationResolution, 0, len(spans))
+	for _, span := range spans {
+		resolution := &types.CitationResolution{CitationSpan: span, ChunkIDs: []string{}}
+		for _, chunk := range chunks {
+			if chunk.StartAt >= span.End {
+				break
+			}
+			if chunk.EndAt > span.Start {
+				resolution.ChunkIDs = append(resolution.ChunkIDs, chunk.ID)
+			}
+		}
+		results = append(results, resolution)
+	}
+	return results, nil
+}
+
+// maxChunksWithKnowledgeBatch 单次批量展开分块所属知识的最大分块数
+const maxChunksWithKnowledgeBatch = 200
+
+// GetChunkWithKnowledge returns the chunk together with its parent knowledge metadata.
+// Chunks outside the caller's tenant and shared knowledge bases are reported as ErrChunkNotFound.
+func (s *knowledgeService) GetChunkWithKnowledge(ctx context.Context,
+	chunkID string,
+) (*types.ChunkWithKnowledge, error) {
+	results, err := s.GetChunksWithKnowledge(ctx, []string{chunkID})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrChunkNotFound
+	}
+	return results[0], nil
+}
+
+// GetChunksWithKnowledge expands a list of chunk IDs (e.g. retrieval results) into chunks with their
+// parent knowledge metadata using one chunk query and one knowledge batch lookup.
+// Only chunks whose knowledge belongs to the caller's tenant or to a knowledge base shared with the
+// user are returned; unknown or inaccessible IDs are skipped. Results follow the order of chunkIDs.
+func (s *knowledgeService) GetChunksWithKnowledge(ctx context.Context,
+	chunkIDs []string,
+) ([]*types.ChunkWithKnowledge, error) {
+	ids := make([]string, 0, len(chunkIDs))
+	seen := make(map[string]struct{}, len(chunkIDs))
+	for _, id := range chunkIDs {
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return []*types.ChunkWithKnowledge{}, nil
+	}
+	if len(ids) > maxChunksWithKnowledgeBatch {
+		return nil, werrors.NewBadRequestError(
+			fmt.Sprintf("单次最多查询 %d 个分块", maxChunksWithKnowledgeBatch))
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	chunks, err := s.chunkRepo.ListChunksByIDOnly(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	knowledgeIDs := make([]string, 0, len(chunks))
+	knowledgeIDSet := make(map[string]struct{}, len(chunks))
+	for _, chunk := range chunks {
+		if _, ok := knowledgeIDSet[chunk.KnowledgeID]; !ok {
+			knowledgeIDSet[chunk.KnowledgeID] = struct{}{}
+			knowledgeIDs = append(knowledgeIDs, chunk.KnowledgeID)
+		}
+	}
+	// 知识的可见性（本租户或共享知识库）决定分块是否可访问
+	knowledgeList, err := s.GetKnowledgeBatchWithSharedAccess(ctx, tenantID, knowledgeIDs)
+	if err != nil {
+		return nil, err
+	}
+	knowledgeByID := make(map[string]*types.Knowledge, len(knowledgeList))
+	for _, knowledge := range knowledgeList {
+		if knowledge != nil {
+			knowledgeByID[knowledge.ID] = knowledge
+		}
+	}
+	chunkByID := make(map[string]*types.Chunk, len(chunks))
+	for _, chunk := range chunks {
+		chunkByID[chunk.ID] = chunk
+	}
+
+	results := make([]*types.ChunkWithKnowledge, 0, len(ids))
+	for _, id := range ids {
+		chunk, ok := chunkByID[id]
+		if !ok {
+			continue
+		}
+		knowledge, ok := knowledgeByID[chunk.KnowledgeID]
+		if !ok || knowledge.TenantID != chunk.TenantID {
+			continue
+		}
+		results = append(results, &types.ChunkWithKnowledge{
+			Chunk: chunk,
+			Knowledge: &types.ChunkKnowledgeInfo{
+				ID:              knowledge.ID,
+				KnowledgeBaseID: knowledge.KnowledgeBaseID,
+				Title:           knowledge.Title,
+				Type:            knowledge.Type,
+				Source:          knowledge.Source,
+				FileName:        knowledge.FileName,
+				FileType:        knowledge.FileType,
+			},
+		})
+	}
+	return results, nil
+}
+
+// knowledgeDetailCacheTTL 知识详情统计的缓存时间，详情页读取频繁，短暂缓存即可
+const knowledgeDetailCacheTTL = 30 * time.Second
+
+func getKnowledgeDetailCacheKey(tenantID uint64, knowledgeID string) string {
+	return fmt.Sprintf("knowledge_detail:%d:%s", tenantID, knowledgeID)
+}
+
+// GetKnowledgeDetail returns the knowledge together with derived statistics (chunk counts by type,
+// image count, parse duration, question status). Statistics are computed with aggregate queries and
+// cached briefly; use GetKnowledgeByID when the statistics are not needed.
+func (s *knowledgeService) GetKnowledgeDetail(ctx context.Context, id string) (*types.KnowledgeDetail, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	cacheKey := getKnowledgeDetailCacheKey(tenantID, id)
+	if s.redisClient != nil {
+		if data, err := s.redisClient.Get(ctx, cacheKey).Bytes(); err == nil {
+			var detail types.KnowledgeDetail
+			if err := json.Unmarshal(data, &detail); err == nil && detail.Knowledge != nil {
+				return &detail, nil
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			logger.Warnf(ctx, "Failed to read knowledge detail cache: %v", err)
+		}
+	}
+
+	knowledge, err := s.GetKnowledgeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.chunkRepo.GetKnowledgeChunkStats(ctx, tenantID, knowledge.ID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to aggregate chunk stats for knowledge %s: %v", knowledge.ID, err)
+		return nil, err
+	}
+
+	detail := &types.KnowledgeDetail{
+		Knowledge:      knowledge,
+		Stats:          stats,
+		QuestionStatus: "none",
+	}
+	if knowledge.ProcessedAt != nil && knowledge.ProcessedAt.After(knowledge.CreatedAt) {
+		detail.ParseDurationMs = knowledge.ProcessedAt.Sub(knowledge.CreatedAt).Milliseconds()
+	}
+	if stats.QuestionChunkCount > 0 {
+		detail.QuestionStatus = "completed"
+	}
+
+	if s.redisClient != nil {
+		if data, err := json.Marshal(detail); err == nil {
+			if err := s.redisClient.Set(ctx, cacheKey, data, knowledgeDetailCacheTTL).Err(); err != nil {
+				logger.Warnf(ctx, "Failed to cache knowledge detail: %v", err)
+			}
+		}
+	}
+	return detail, nil
+}
+
+// GetKnowledgeByIDOnly retrieves knowledge by ID without tenant filter (for permission resolution).
+func (s *knowledgeService) GetKnowledgeByIDOnly(ctx context.Context, id string) (*types.Knowledge, error) {
+	return s.repo.GetKnowledgeByIDOnly(ctx, id)
+}
+
+// ListKnowledgeByKnowledgeBaseID returns all knowledge entries in a knowledge base
+func (s *knowledgeService) ListKnowledgeByKnowledgeBaseID(ctx context.Context,
+	kbID string,
+) ([]*types.Knowledge, error) {
+	return s.repo.ListKnowledgeByKnowledgeBaseID(ctx, ctx.Value(types.TenantIDContextKey).(uint64), kbID)
+}
+
+// ListPagedKnowledgeByKnowledgeBaseID returns paginated knowledge entries in a knowledge base
+func (s *knowledgeService) ListPagedKnowledgeByKnowledgeBaseID(ctx context.Context,
+	kbID string, page *types.Pagination, tagID string, keyword string, fileType string,
+	extractedKeyword string, fileHash string,
+) (*types.PageResult, error) {
+	knowledges, total, err := s.repo.ListPagedKnowledgeByKnowledgeBaseID(ctx,
+		ctx.Value(types.TenantIDContextKey).(uint64), kbID, page, tagID, keyword, fileType,
+		strings.TrimSpace(extractedKeyword), strings.ToLower(strings.TrimSpace(fileHash)))
+	if err != nil {
+		return nil, err
+	}
+	return types.NewPageResult(total, page, knowledges), nil
+}
+
+// effectiveTenantInfo returns the tenant that owns the data addressed by ctx. For a shared knowledge
+// base the handler sets TenantIDContextKey to the owner tenant while TenantInfoContextKey still holds
+// the caller, so the owner is loaded to use its retrieval engines and storage accounting.
+func (s *knowledgeService) effectiveTenantInfo(ctx context.Context) (*types.Tenant, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	if tenant, ok := ctx.Value(types.TenantInfoContextKey).(*types.Tenant); ok && tenant != nil && tenant.ID == tenantID {
+		return tenant, nil
+	}
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tenant == nil {
+		return nil, werrors.NewNotFoundError("租户不存在")
+	}
+	return tenant, nil
+}
+
+const (
+	// knowledgeRelevanceChunksPerDoc 按相关性检索文档时每个结果文档预留的分块检索数量
+	knowledgeRelevanceChunksPerDoc = 5
+	// maxKnowledgeRelevanceChunks 按相关性检索文档时单次分块检索数量上限
+	maxKnowledgeRelevanceChunks = 500
+	// knowledgeRelevanceSnippetRunes 命中片段的最大字符数
+	knowledgeRelevanceSnippetRunes = 200
+)
+
+// SearchKnowledgeByRelevance is document level search built on chunk retrieval: it runs hybrid search
+// over the chunks of the knowledge base, keeps the best scoring chunk per knowledge and returns the
+// knowledge ranked by that score, with the chunk as the matched snippet. Enough chunks are retrieved
+// to fill the requested page; documents whose chunks all rank below the retrieved pool are not found.
+func (s *knowledgeService) SearchKnowledgeByRelevance(ctx context.Context,
+	kbID string, query string, page *types.Pagination, tagID string, fileType string,
+) (*types.PageResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, werrors.NewBadRequestError("查询内容不能为空")
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	params := types.SearchParams{
+		QueryText:  query,
+		MatchCount: min((page.Offset()+page.Limit())*knowledgeRelevanceChunksPerDoc, maxKnowledgeRelevanceChunks),
+	}
+	if tagID != "" || fileType != "" {
+		knowledgeList, err := s.repo.ListKnowledgeByKnowledgeBaseID(ctx, tenantID, kbID)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range knowledgeList {
+			if knowledgeMatchesListFilter(k, tagID, fileType) {
+				params.KnowledgeIDs = append(params.KnowledgeIDs, k.ID)
+			}
+		}
+		if len(params.KnowledgeIDs) == 0 {
+			return types.NewPageResult(0, page, []*types.KnowledgeRelevanceHit{}), nil
+		}
+	}
+
+	results, err := s.kbService.HybridSearch(ctx, kbID, params)
+	if err != nil {
+		return nil, err
+	}
+	hits := rankKnowledgeByRelevance(results)
+
+	pageHits := pageSlice(hits, page)
+	knowledgeIDs := make([]string, 0, len(pageHits))
+	for _, hit := range pageHits {
+		knowledgeIDs = append(knowledgeIDs, hit.Knowledge.ID)
+	}
+	if len(knowledgeIDs) > 0 {
+		knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, tenantID, knowledgeIDs)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]*types.Knowledge, len(knowledgeList))
+		for _, k := range knowledgeList {
+			byID[k.ID] = k
+		}
+		for _, hit := range pageHits {
+			if k, ok := byID[hit.Knowledge.ID]; ok {
+				hit.Knowledge = k
+			}
+		}
+	}
+
+	logger.Infof(ctx, "Knowledge relevance search in kb %s matched %d chunks in %d knowledge",
+		kbID, len(results), len(hits))
+	return types.NewPageResult(int64(len(hits)), page, pageHits), nil
+}
+
+// knowledgeMatchesListFilter applies the tag and file type filters of ListPagedKnowledgeByKnowledgeBaseID
+func knowledgeMatchesListFilter(k *types.Knowledge, tagID string, fileType string) bool {
+	if tagID != "" && k.TagID != tagID {
+		return false
+	}
+	switch fileType {
+	case "":
+		return true
+	case "manual", "url":
+		return k.Type == fileType
+	default:
+		return k.FileType == fileType
+	}
+}
+
+// rankKnowledgeByRelevance aggregates chunk search results per knowledge, keeping the best scoring chunk,
+// and returns the knowledge ordered by that score. Hits only carry the knowledge ID until loaded.
+func rankKnowledgeByRelevance(results []*types.SearchResult) []*types.KnowledgeRelevanceHit {
+	byKnowledge := make(map[string]*types.KnowledgeRelevanceHit)
+	hits := make([]*types.KnowledgeRelevanceHit, 0)
+	for _, result := range results {
+		if result.KnowledgeID == "" {
+			continue
+		}
+		hit, ok := byKnowledge[result.KnowledgeID]
+		if !ok {
+			hit = &types.KnowledgeRelevanceHit{Knowledge: &types.Knowledge{ID: result.KnowledgeID}, Score: -1}
+			byKnowledge[result.KnowledgeID] = hit
+			hits = append(hits, hit)
+		}
+		hit.MatchedChunks++
+		if result.Score > hit.Score {
+			hit.Score = result.Score
+			hit.MatchedChunkID = result.ID
+			hit.Snippet = result.Content
+		}
+	}
+	for _, hit := range hits {
+		if runes := []rune(hit.Snippet); len(runes) > knowledgeRelevanceSnippetRunes {
+			hit.Snippet = string(runes[:knowledgeRelevanceSnippetRunes]) + "..."
+		}
+	}
+	slices.SortStableFunc(hits, func(a, b *types.KnowledgeRelevanceHit) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return hits
+}
+
+// DeleteKnowledge deletes a knowledge entry and all related resources
+func (s *knowledgeService) DeleteKnowledge(ctx context.Context, id string) error {
+	lock, lockErr := s.lockKnowledge(ctx, id)
+	if lockErr != nil {
+		if !isKnowledgeLockConflict(lockErr) {
+			return lockErr
+		}
+	} else {
+		defer lock.Unlock()
+	}
+
+	// Get the knowledge entry
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, ctx.Value(types.TenantIDContextKey).(uint64), id)
+	if err != nil {
+		return err
+	}
+	if lockErr != nil && !canOverrideProcessingLock(knowledge) {
+		return lockErr
+	}
+
+	// Mark as deleting first to prevent async task conflicts
+	// This ensures that any running async tasks will detect the deletion and abort
+	originalStatus := knowledge.ParseStatus
+	knowledge.ParseStatus = types.ParseStatusDeleting
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge failed to mark as deleting")
+		// Continue with deletion even if marking fails
+	} else {
+		logger.Infof(ctx, "Marked knowledge %s as deleting (previous status: %s)", id, originalStatus)
+	}
+
+	wg := errgroup.Group{}
+	// Delete knowledge embeddings from vector store
+	wg.Go(func() error {
+		return s.runDeleteSubtask(ctx, func() error {
+			tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+			retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
+				s.retrieveEngine,
+				tenantInfo.GetEffectiveEngines(),
+			)
+			if err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
+				return err
+			}
+			embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, knowledge.EmbeddingModelID)
+			if err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
+				return err
+			}
+			if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), knowledge.Type); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
+				return err
+			}
+			return nil
+		})
+	})
+
+	// Delete all chunks associated with this knowledge
+	wg.Go(func() error {
+		return s.runDeleteSubtask(ctx, func() error {
+			if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete chunks failed")
+				return err
+			}
+			return nil
+		})
+	})
+
+	// Delete the physical file if it exists
+	wg.Go(func() error {
+		return s.runDeleteSubtask(ctx, func() error {
+			if knowledge.FilePath != "" {
+				if err := s.fileSvc.DeleteFile(ctx, knowledge.FilePath); err != nil {
+					logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete file failed")
+				}
+			}
+			if knowledge.ParsedMarkdownPath != "" {
+				if err := s.fileSvc.DeleteFile(ctx, knowledge.ParsedMarkdownPath); err != nil {
+					logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete parsed markdown failed")
+				}
+			}
+			tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+			tenantInfo.StorageUsed -= knowledge.StorageSize
+			if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, -knowledge.StorageSize); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge update tenant storage used failed")
+			}
+			return nil
+		})
+	})
+
+	// Delete the knowledge graph
+	wg.Go(func() error {
+		return s.runDeleteSubtask(ctx, func() error {
+			namespace := types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID}
+			if err := s.graphEngine.DelGraph(ctx, []types.NameSpace{namespace}); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge graph failed")
+				return err
+			}
+			return nil
+		})
+	})
+
+	if err = wg.Wait(); err != nil {
+		return err
+	}
+	// Delete the knowledge entry itself from the database
+	return s.repo.DeleteKnowledge(ctx, ctx.Value(types.TenantIDContextKey).(uint64), id)
+}
+
+// DeleteKnowledgeList deletes a knowledge entry and all related resources
+func (s *knowledgeService) DeleteKnowledgeList(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	// 1. Get the knowledge entry
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, tenantInfo.ID, ids)
+	if err != nil {
+		return err
+	}
+	unlock, err := s.lockKnowledgeList(ctx, knowledgeList, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Mark all as deleting first to prevent async task conflicts
+	for _, knowledge := range knowledgeList {
+		knowledge.ParseStatus = types.ParseStatusDeleting
+		knowledge.UpdatedAt = time.Now()
+		if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+			logger.GetLogger(ctx).WithField("error", err).WithField("knowledge_id", knowledge.ID).
+				Errorf("DeleteKnowledgeList failed to mark as deleting")
+			// Continue with deletion even if marking fails
+		}
+	}
+	logger.Infof(ctx, "Marked %d knowledge entries as deleting", len(knowledgeList))
+
+	wg := errgroup.Group{}
+	// 2. Delete knowledge embeddings from vector store
+	wg.Go(func() error {
+		return s.runDeleteSubtask(ctx, func() error {
+			tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+			retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
+				s.retrieveEngine,
+				tenantInfo.GetEffectiveEngines(),
+			)
+			if err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
+				return err
+			}
+			// Group by EmbeddingModelID and Type
+			type groupKey struct {
+				EmbeddingModelID string
+				Type             string
+			}
+			group := map[groupKey][]string{}
+			for _, knowledge := range knowledgeList {
+				key := groupKey{EmbeddingModelID: knowledge.EmbeddingModelID, Type: knowledge.Type}
+				group[key] = append(group[key], knowledge.ID)
+			}
+			for key, knowledgeIDs := range group {
+				embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, key.EmbeddingModelID)
+				if err != nil {
+					logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge get embedding model failed")
+					return err
+				}
+				if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, knowledgeIDs, embeddingModel.GetDimensions(), key.Type); err != nil {
+					logger.GetLogger(ctx).
+						WithField("error", err).
+						Errorf("DeleteKnowledge delete knowledge embedding failed")
+					return err
+				}
+			}
+			return nil
+		})
+	})
+
+	// 3. Delete all chunks associated with this knowledge
+	wg.Go(func() error {
+		return s.runDeleteSubtask(ctx, func() error {
+			if err := s.chunkService.DeleteByKnowledgeList(ctx, ids); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete chunks failed")
+				return err
+			}
+			return nil
+		})
+	})
+
+	// 4. Delete the physical file if it exists
+	wg.Go(func() error {
+		return s.runDeleteSubtask(ctx, func() error {
+			storageAdjust := int64(0)
+			for _, knowledge := range knowledgeList {
+				if knowledge.FilePath != "" {
+					if err := s.fileSvc.DeleteFile(ctx, knowledge.FilePath); err != nil {
+						logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete file failed")
+					}
+				}
+				if knowledge.ParsedMarkdownPath != "" {
+					if err := s.fileSvc.DeleteFile(ctx, knowledge.ParsedMarkdownPath); err != nil {
+						logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete parsed markdown failed")
+					}
+				}
+				storageAdjust -= knowledge.StorageSize
+			}
+			tenantInfo.StorageUsed += storageAdjust
+			if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, storageAdjust); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge update tenant storage used failed")
+			}
+			return nil
+		})
+	})
+
+	// Delete the knowledge graph
+	wg.Go(func() error {
+		return s.runDeleteSubtask(ctx, func() error {
+			namespaces := []types.NameSpace{}
+			for _, knowledge := range knowledgeList {
+				namespaces = append(
+					namespaces,
+					types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID},
+				)
+			}
+			if err := s.graphEngine.DelGraph(ctx, namespaces); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge graph failed")
+				return err
+			}
+			return nil
+		})
+	})
+
+	if err = wg.Wait(); err != nil {
+		return err
+	}
+	// 5. Delete the knowledge entry itself from the database
+	return s.repo.DeleteKnowledgeList(ctx, tenantInfo.ID, ids)
+}
+
+const (
+	kbReadOnlyKeyPrefix = "kb_readonly:"
+	// kbReadOnlyTTL bounds how long a crashed reset can keep the knowledge base read-only
+	kbReadOnlyTTL = 30 * time.Minute
+)
+
+// getKBReadOnlyKey returns the Redis key marking a knowledge base as read-only
+func getKBReadOnlyKey(kbID string) string {
+	return kbReadOnlyKeyPrefix + kbID
+}
+
+// markKnowledgeBaseReadOnly marks a knowledge base read-only while a bulk operation runs.
+// It returns a function that lifts the mark, or a conflict error if the knowledge base is already read-only.
+// It fails closed: a Redis error is returned rather than running the bulk operation unmarked.
+func (s *knowledgeService) markKnowledgeBaseReadOnly(ctx context.Context, kbID string) (func(), error) {
+	if s.redisClient == nil {
+		return func() {}, nil
+	}
+
+	key := getKBReadOnlyKey(kbID)
+	token := uuid.New().String()
+	acquired, err := s.redisClient.SetNX(ctx, key, token, kbReadOnlyTTL).Result()
+	if err != nil {
+		logger.Errorf(ctx, "Failed to mark knowledge base %s read-only: %v", kbID, err)
+		return nil, werrors.NewInternalServerError("标记知识库只读失败，请稍后再试")
+	}
+	if !acquired {
+		return nil, werrors.NewConflictError("知识库正在执行其他批量操作，请稍后再试")
+	}
+
+	return func() {
+		releaseCtx := context.WithoutCancel(ctx)
+		if err := releaseKnowledgeLockScript.Run(releaseCtx, s.redisClient, []string{key}, token).Err(); err != nil {
+			logger.Errorf(releaseCtx, "Failed to lift read-only mark of knowledge base %s: %v", kbID, err)
+		}
+	}, nil
+}
+
+// ensureKnowledgeBaseWritable rejects writes to a knowledge base that is marked read-only
+func (s *knowledgeService) ensureKnowledgeBaseWritable(ctx context.Context, kbID string) error {
+	if s.redisClient == nil {
+		return nil
+	}
+	exists, err := s.redisClient.Exists(ctx, getKBReadOnlyKey(kbID)).Result()
+	if err != nil {
+		logger.Warnf(ctx, "Failed to check read-only mark of knowledge base %s: %v", kbID, err)
+		return nil
+	}
+	if exists > 0 {
+		return werrors.NewConflictError("知识库正在清空，暂时无法写入，请稍后再试")
+	}
+	return nil
+}
+
+// DeleteAllKnowledgeInKB deletes all knowledge of a knowledge base in bulk: vectors, chunks,
+// graph data, files and knowledge rows. The knowledge base is read-only while it runs.
+// Returns the number of deleted knowledge entries.
+func (s *knowledgeService) DeleteAllKnowledgeInKB(ctx context.Context, kbID string) (int, error) {
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return 0, err
+	}
+	// Shared knowledge bases are cleared with the owner tenant's engines and storage accounting
+	tenantInfo, err := s.effectiveTenantInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	release, err := s.markKnowledgeBaseReadOnly(ctx, kb.ID)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	knowledgeList, err := s.repo.ListKnowledgeByKnowledgeBaseID(ctx, tenantInfo.ID, kb.ID)
+	if err != nil {
+		return 0, err
+	}
+	logger.Infof(ctx, "Deleting all knowledge in knowledge base %s, count: %d", kb.ID, len(knowledgeList))
+	if len(knowledgeList) == 0 {
+		return 0, nil
+	}
+	unlock, err := s.lockKnowledgeList(ctx, knowledgeList, true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	// Mark all as deleting first so running processing tasks stop at their next checkpoint
+	for _, knowledge := range knowledgeList {
+		knowledge.ParseStatus = types.ParseStatusDeleting
+		knowledge.UpdatedAt = time.Now()
+		if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+			logger.GetLogger(ctx).WithField("error", err).WithField("knowledge_id", knowledge.ID).
+				Errorf("DeleteAllKnowledgeInKB failed to mark as deleting")
+		}
+	}
+
+	wg := errgroup.Group{}
+	// 1. Delete embeddings from vector store, one knowledge base scoped delete per embedding model and type
+	wg.Go(func() error {
+		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
 			s.retrieveEngine,
 			tenantInfo.GetEffectiveEngines(),
 		)
 		if err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
-			return err
+			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteAllKnowledgeInKB init retrieve engine failed")
+			return err
+		}
+		type groupKey struct {
+			EmbeddingModelID string
+			Type             string
+		}
+		group := map[groupKey][]string{}
+		for _, knowledge := range knowledgeList {
+			key := groupKey{EmbeddingModelID: knowledge.EmbeddingModelID, Type: knowledge.Type}
+			group[key] = append(group[key], knowledge.ID)
+		}
+		for key, knowledgeIDs := range group {
+			embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, key.EmbeddingModelID)
+			if err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteAllKnowledgeInKB get embedding model failed")
+				return err
+			}
+			if err := retrieveEngine.DeleteByKnowledgeBaseID(
+				ctx, kb.ID, knowledgeIDs, embeddingModel.GetDimensions(), key.Type,
+			); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteAllKnowledgeInKB delete embeddings failed")
+				return err
+			}
+		}
+		return nil
+	})
+
+	// 2. Delete all chunks of the knowledge base
+	wg.Go(func() error {
+		if err := s.chunkRepo.DeleteChunksByKnowledgeBaseID(ctx, tenantInfo.ID, kb.ID); err != nil {
+			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteAllKnowledgeInKB delete chunks failed")
+			return err
+		}
+		return nil
+	})
+
+	// 3. Delete physical files and release storage in one adjustment
+	wg.Go(func() error {
+		storageAdjust := int64(0)
+		for _, knowledge := range knowledgeList {
+			if knowledge.FilePath != "" {
+				if err := s.fileSvc.DeleteFile(ctx, knowledge.FilePath); err != nil {
+					logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteAllKnowledgeInKB delete file failed")
+				}
+			}
+			if knowledge.ParsedMarkdownPath != "" {
+				if err := s.fileSvc.DeleteFile(ctx, knowledge.ParsedMarkdownPath); err != nil {
+					logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteAllKnowledgeInKB delete parsed markdown failed")
+				}
+			}
+			storageAdjust -= knowledge.StorageSize
+		}
+		if storageAdjust != 0 {
+			tenantInfo.StorageUsed += storageAdjust
+			if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, storageAdjust); err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteAllKnowledgeInKB update tenant storage used failed")
+			}
+		}
+		return nil
+	})
+
+	// 4. Delete the knowledge graph
+	wg.Go(func() error {
+		namespaces := make([]types.NameSpace, 0, len(knowledgeList))
+		for _, knowledge := range knowledgeList {
+			namespaces = append(namespaces, types.NameSpace{KnowledgeBase: kb.ID, Knowledge: knowledge.ID})
+		}
+		if err := s.graphEngine.DelGraph(ctx, namespaces); err != nil {
+			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteAllKnowledgeInKB delete knowledge graph failed")
+			return err
+		}
+		return nil
+	})
+
+	if err := wg.Wait(); err != nil {
+		return 0, err
+	}
+	// 5. Delete all knowledge rows
+	if err := s.repo.DeleteKnowledgeByKnowledgeBaseID(ctx, tenantInfo.ID, kb.ID); err != nil {
+		return 0, err
+	}
+	logger.Infof(ctx, "Deleted all knowledge in knowledge base %s, count: %d", kb.ID, len(knowledgeList))
+	return len(knowledgeList), nil
+}
+
+// clonedEnableStatus resolves the enable status of a cloned knowledge under policy, and whether the
+// cloned chunks, which keep their source enabled flags, must be switched to match a changed status
+func clonedEnableStatus(src *types.Knowledge, policy types.CloneEnableStatusPolicy) (string, bool) {
+	srcStatus := src.EnableStatus
+	if srcStatus == "" {
+		srcStatus = "enabled"
+	}
+	switch policy {
+	case types.CloneEnableStatusEnableAll:
+		return "enabled", srcStatus != "enabled"
+	case types.CloneEnableStatusDisableAll:
+		return "disabled", srcStatus != "disabled"
+	default:
+		return srcStatus, false
+	}
+}
+
+func (s *knowledgeService) cloneKnowledge(
+	ctx context.Context,
+	src *types.Knowledge,
+	targetKB *types.KnowledgeBase,
+	includeDisabled bool,
+	policy types.CloneEnableStatusPolicy,
+) (err error) {
+	if src.ParseStatus != "completed" {
+		logger.GetLogger(ctx).WithField("knowledge_id", src.ID).Errorf("MoveKnowledge parse status is not completed")
+		return nil
+	}
+	enableStatus, syncChunks := clonedEnableStatus(src, policy)
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	dst := &types.Knowledge{
+		ID:               uuid.New().String(),
+		TenantID:         targetKB.TenantID,
+		KnowledgeBaseID:  targetKB.ID,
+		Type:             src.Type,
+		Title:            src.Title,
+		Description:      src.Description,
+		Source:           src.Source,
+		ParseStatus:      "processing",
+		EnableStatus:     "disabled",
+		EmbeddingModelID: targetKB.EmbeddingModelID,
+		FileName:         src.FileName,
+		FileType:         src.FileType,
+		FileSize:         src.FileSize,
+		FileHash:         src.FileHash,
+		FilePath:         src.FilePath,
+		StorageSize:      src.StorageSize,
+		Metadata:         src.Metadata,
+		ExpiresAt:        src.ExpiresAt,
+	}
+	defer func() {
+		if err != nil {
+			dst.ParseStatus = "failed"
+			dst.ErrorMessage = err.Error()
+			_ = s.repo.UpdateKnowledge(ctx, dst)
+			logger.GetLogger(ctx).WithField("error", err).Errorf("MoveKnowledge failed to move knowledge")
+		} else {
+			dst.ParseStatus = "completed"
+			dst.EnableStatus = enableStatus
+			_ = s.repo.UpdateKnowledge(ctx, dst)
+			logger.GetLogger(ctx).WithField("knowledge_id", dst.ID).Infof("MoveKnowledge move knowledge successfully")
+		}
+	}()
+
+	if err = s.repo.CreateKnowledge(ctx, dst); err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Errorf("MoveKnowledge create knowledge failed")
+		return
+	}
+	tenantInfo.StorageUsed += dst.StorageSize
+	if err = s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, dst.StorageSize); err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Errorf("MoveKnowledge update tenant storage used failed")
+		return
+	}
+	if err = s.CloneChunk(ctx, src, dst, includeDisabled); err != nil {
+		logger.GetLogger(ctx).WithField("knowledge_id", dst.ID).
+			WithField("error", err).Errorf("MoveKnowledge move chunks failed")
+		return
+	}
+	if syncChunks {
+		// 策略改变了启用状态时，同 EnableKnowledge 一样同步切换全部分块在数据库与索引中的状态
+		var chunks []*types.Chunk
+		chunks, err = s.chunkRepo.ListChunksByKnowledgeID(ctx, dst.TenantID, dst.ID)
+		if err != nil {
+			return
+		}
+		var retrieveEngine *retriever.CompositeRetrieveEngine
+		retrieveEngine, err = retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+		if err != nil {
+			return
+		}
+		if err = s.setChunksEnabled(ctx, retrieveEngine, chunks, enableStatus == "enabled"); err != nil {
+			logger.GetLogger(ctx).WithField("knowledge_id", dst.ID).
+				WithField("error", err).Errorf("MoveKnowledge sync chunk enabled status failed")
+			return
+		}
+	}
+	return
+}
+
+// processDocumentFromPassage handles asynchronous processing of text passages
+func (s *knowledgeService) processDocumentFromPassage(ctx context.Context,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, passage []string,
+) {
+	// Update status to processing
+	knowledge.ParseStatus = "processing"
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		return
+	}
+
+	// Convert passages to chunks
+	chunks := make([]*proto.Chunk, 0, len(passage))
+	start, end := 0, 0
+	for i, p := range passage {
+		if p == "" {
+			continue
+		}
+		end += len([]rune(p))
+		chunk := &proto.Chunk{
+			Content: p,
+			Seq:     int32(i),
+			Start:   int32(start),
+			End:     int32(end),
+		}
+		start = end
+		chunks = append(chunks, chunk)
+	}
+	// Process and store chunks
+	s.processChunks(ctx, kb, knowledge, chunks)
+}
+
+// ProcessChunksOptions contains options for processing chunks
+type ProcessChunksOptions struct {
+	EnableQuestionGeneration bool
+	QuestionCount            int
+	// KeepDisabled 索引完成后保持知识及其分块为禁用状态，需单独调用启用接口
+	KeepDisabled bool
+	// SkipSummary 跳过摘要生成，保留知识已有的摘要
+	SkipSummary bool
+	// PreservedQuestions 按分块内容 hash 保留的已生成问题，内容未变的文本分块直接复用并建立索引
+	PreservedQuestions map[string][]types.GeneratedQuestion
+	// LockToken 发起操作交接过来的知识锁，手工知识处理结束后释放
+	LockToken string
+	// PartialResult docreader 解析超时只返回了部分结果（部分分块的图片未解析），文档带警告完成
+	PartialResult bool
+}
+
+// chunkContentHash returns the key used to match chunks with identical content across re-parses.
+func chunkContentHash(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// collectGeneratedQuestions collects the generated questions of text chunks keyed by chunk content hash.
+func collectGeneratedQuestions(chunks []*types.Chunk) map[string][]types.GeneratedQuestion {
+	preserved := make(map[string][]types.GeneratedQuestion)
+	for _, chunk := range chunks {
+		if chunk.ChunkType != types.ChunkTypeText {
+			continue
+		}
+		meta, err := chunk.DocumentMetadata()
+		if err != nil || meta == nil || len(meta.GeneratedQuestions) == 0 {
+			continue
+		}
+		preserved[chunkContentHash(chunk.Content)] = meta.GeneratedQuestions
+	}
+	return preserved
+}
+
+// restoreGeneratedQuestions attaches preserved questions to text chunks whose content is unchanged.
+func restoreGeneratedQuestions(ctx context.Context,
+	chunks []*types.Chunk, preserved map[string][]types.GeneratedQuestion,
+) int {
+	restored := 0
+	for _, chunk := range chunks {
+		questions, ok := preserved[chunkContentHash(chunk.Content)]
+		if !ok {
+			continue
+		}
+		meta, err := chunk.DocumentMetadata()
+		if err != nil || meta == nil {
+			meta = &types.DocumentChunkMetadata{}
+		}
+		meta.GeneratedQuestions = questions
+		if err := chunk.SetDocumentMetadata(meta); err != nil {
+			logger.Warnf(ctx, "Failed to restore generated questions for chunk %s: %v", chunk.ID, err)
+			continue
+		}
+		restored++
+	}
+	return restored
+}
+
+// buildGeneratedQuestionIndexInfoList builds index entries for the generated questions of a chunk.
+func buildGeneratedQuestionIndexInfoList(chunk *types.Chunk) []*types.IndexInfo {
+	meta, err := chunk.DocumentMetadata()
+	if err != nil || meta == nil {
+		return nil
+	}
+	indexInfoList := make([]*types.IndexInfo, 0, len(meta.GeneratedQuestions))
+	for _, gq := range meta.GeneratedQuestions {
+		indexInfoList = append(indexInfoList, &types.IndexInfo{
+			Content:         gq.Question,
+			SourceID:        fmt.Sprintf("%s-%s", chunk.ID, gq.ID),
+			SourceType:      types.ChunkSourceType,
+			ChunkID:         chunk.ID,
+			KnowledgeID:     chunk.KnowledgeID,
+			KnowledgeBaseID: chunk.KnowledgeBaseID,
+			ChunkType:       chunk.ChunkType,
+			IsEnabled:       chunk.IsEnabled,
+		})
+	}
+	return indexInfoList
+}
+
+const (
+	// maxLinksPerChunk 单个分块最多保留的超链接数量
+	maxLinksPerChunk = 50
+	// maxLinkAnchorTextLength 锚文本最大字符数
+	maxLinkAnchorTextLength = 200
+)
+
+var (
+	markdownLinkRegex = regexp.MustCompile(`(!?)\[([^\]]*)\]\(<?([^)\s>]+)>?(?:\s+"[^"]*")?\)`)
+	htmlAnchorRegex   = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']+)["'][^>]*>(.*?)</a>`)
+	bareURLRegex      = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+	htmlTagRegex      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// normalizeLinkURL validates a hyperlink and returns its normalized form.
+// Only absolute http/https URLs are accepted; scheme and host are lowercased and the fragment is dropped.
+func normalizeLinkURL(raw string) (string, bool) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), ".,;:!?")
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String(), true
+}
+
+// extractChunkLinks extracts Markdown links, HTML anchors and bare URLs from chunk content.
+// Image links are skipped, URLs are normalized and deduplicated (the first non-empty anchor text wins).
+func extractChunkLinks(content string) []types.ChunkLink {
+	var links []types.ChunkLink
+	indexByURL := make(map[string]int)
+	add := func(rawURL, anchor string) {
+		normalized, ok := normalizeLinkURL(rawURL)
+		if !ok {
+			return
+		}
+		anchor = strings.Join(strings.Fields(htmlTagRegex.ReplaceAllString(anchor, "")), " ")
+		if runes := []rune(anchor); len(runes) > maxLinkAnchorTextLength {
+			anchor = string(runes[:maxLinkAnchorTextLength])
+		}
+		if i, exists := indexByURL[normalized]; exists {
+			if links[i].AnchorText == "" {
+				links[i].AnchorText = anchor
+			}
+			return
+		}
+		if len(links) >= maxLinksPerChunk {
+			return
+		}
+		indexByURL[normalized] = len(links)
+		links = append(links, types.ChunkLink{URL: normalized, AnchorText: anchor})
+	}
+
+	// 先提取带锚文本的链接，再把剩余部分中的裸链接补上
+	rest := markdownLinkRegex.ReplaceAllStringFunc(content, func(m string) string {
+		sub := markdownLinkRegex.FindStringSubmatch(m)
+		if sub[1] != "!" {
+			add(sub[3], sub[2])
+		}
+		return " "
+	})
+	rest = htmlAnchorRegex.ReplaceAllStringFunc(rest, func(m string) string {
+		sub := htmlAnchorRegex.FindStringSubmatch(m)
+		add(sub[1], sub[2])
+		return " "
+	})
+	for _, rawURL := range bareURLRegex.FindAllString(rest, -1) {
+		add(rawURL, "")
+	}
+	return links
+}
+
+// refreshChunkLinks re-extracts the links of a text chunk whose content was edited into its
+// metadata. Returns whether the links changed.
+func refreshChunkLinks(chunk *types.Chunk) (bool, error) {
+	meta, err := chunk.DocumentMetadata()
+	if err != nil {
+		return false, err
+	}
+	if meta == nil {
+		meta = &types.DocumentChunkMetadata{}
+	}
+	links := extractChunkLinks(chunk.Content)
+	if slices.Equal(meta.Links, links) {
+		return false, nil
+	}
+	meta.Links = links
+	return true, chunk.SetDocumentMetadata(meta)
+}
+
+// buildChunkLinkIndexInfoList builds link index entries (anchor text + URL) for a text chunk
+// when the knowledge base enables IndexLinkAnchors. Links without anchor text are not indexed.
+func buildChunkLinkIndexInfoList(kb *types.KnowledgeBase, chunk *types.Chunk) []*types.IndexInfo {
+	if !kb.ChunkingConfig.IndexLinkAnchors || chunk.ChunkType != types.ChunkTypeText {
+		return nil
+	}
+	meta, err := chunk.DocumentMetadata()
+	if err != nil || meta == nil {
+		return nil
+	}
+	var indexInfoList []*types.IndexInfo
+	for i, link := range meta.Links {
+		if link.AnchorText == "" {
+			continue
+		}
+		indexInfoList = append(indexInfoList, &types.IndexInfo{
+			Content:         link.AnchorText + "\n" + link.URL,
+			SourceID:        fmt.Sprintf("%s-link-%d", chunk.ID, i),
+			SourceType:      types.ChunkSourceType,
+			ChunkID:         chunk.ID,
+			KnowledgeID:     chunk.KnowledgeID,
+			KnowledgeBaseID: chunk.KnowledgeBaseID,
+			ChunkType:       chunk.ChunkType,
+			IsEnabled:       chunk.IsEnabled,
+		})
+	}
+	return indexInfoList
+}
+
+// buildChunkContentIndexInfoList builds the index entries for a chunk's own content. Oversized
+// chunks are split or truncated per the KB chunking config while the chunk keeps its original
+// content; the first part keeps chunk.ID as source ID and extra parts use "{chunk_id}-part-{n}".
+// Text chunk parts are rendered with the KB index content template using the knowledge title.
+func buildChunkContentIndexInfoList(kb *types.KnowledgeBase, chunk *types.Chunk, title string) ([]*types.IndexInfo, bool) {
+	parts, oversized := kb.ChunkingConfig.IndexContents(chunk.Content)
+	section := ""
+	if chunk.ChunkType == types.ChunkTypeText && kb.ChunkingConfig.UsesIndexSection() {
+		if meta, err := chunk.DocumentMetadata(); err == nil && meta != nil {
+			section = meta.Section
+		}
+	}
+	indexInfoList := make([]*types.IndexInfo, 0, len(parts))
+	for i, part := range parts {
+		sourceID := chunk.ID
+		if i > 0 {
+			sourceID = fmt.Sprintf("%s-part-%d", chunk.ID, i)
+		}
+		if chunk.ChunkType == types.ChunkTypeText {
+			part = kb.ChunkingConfig.RenderIndexContent(title, section, part)
+		}
+		indexInfoList = append(indexInfoList, &types.IndexInfo{
+			Content:         part,
+			SourceID:        sourceID,
+			SourceType:      types.ChunkSourceType,
+			ChunkID:         chunk.ID,
+			KnowledgeID:     chunk.KnowledgeID,
+			KnowledgeBaseID: chunk.KnowledgeBaseID,
+			ChunkType:       chunk.ChunkType,
+			IsEnabled:       chunk.IsEnabled,
+		})
+	}
+	return indexInfoList, oversized
+}
+
+// markdownHeading returns the text of a Markdown ATX heading line ("## 标题"), or "" if line is not a heading
+func markdownHeading(line string) string {
+	line = strings.TrimSpace(line)
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || (line[level] != ' ' && line[level] != '\t') {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(line[level:], "#"))
+}
+
+// assignChunkSections records in each text chunk's metadata the Markdown section heading it belongs to:
+// the heading the chunk starts with, otherwise the last heading seen in the preceding chunks.
+// textChunks must be in document order.
+func assignChunkSections(textChunks []*types.Chunk) error {
+	current := ""
+	for _, chunk := range textChunks {
+		section := current
+		firstLine := true
+		for _, line := range strings.Split(chunk.Content, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if heading := markdownHeading(line); heading != "" {
+				if firstLine {
+					section = heading
+				}
+				current = heading
+			}
+			firstLine = false
+		}
+		if section == "" {
+			continue
+		}
+		meta, err := chunk.DocumentMetadata()
+		if err != nil {
+			return err
+		}
+		if meta == nil {
+			meta = &types.DocumentChunkMetadata{}
+		}
+		meta.Section = section
+		if err := chunk.SetDocumentMetadata(meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropDocumentImages removes the images of all chunks when the document holds more than maxImages
+// images in total (maxImages <= 0 means unlimited). Returns whether the images were dropped.
+func dropDocumentImages(chunks []*proto.Chunk, maxImages int) bool {
+	if maxImages <= 0 {
+		return false
+	}
+	total := 0
+	for _, chunkData := range chunks {
+		total += len(chunkData.Images)
+	}
+	if total <= maxImages {
+		return false
+	}
+	for _, chunkData := range chunks {
+		chunkData.Images = nil
+	}
+	return true
+}
+
+// newKnowledgeProcessingReport starts the processing report of a document from the docreader chunks,
+// recording skipped empty chunks and how images were handled. totalImages is the image count before
+// dropDocumentImages ran; imagesDropped reports whether it removed them.
+func newKnowledgeProcessingReport(knowledgeID string, chunks []*proto.Chunk,
+	totalImages int, imagesDropped bool,
+) *types.KnowledgeProcessingReport {
+	report := &types.KnowledgeProcessingReport{
+		KnowledgeID:     knowledgeID,
+		GeneratedAt:     time.Now(),
+		ParsedChunks:    len(chunks),
+		TotalImages:     totalImages,
+		SkippedChunks:   make([]types.ChunkDiagnostic, 0),
+		OversizedChunks: make([]types.ChunkDiagnostic, 0),
+	}
+	if imagesDropped {
+		report.SkipImages(types.ProcessingSkipMaxImagesExceeded, totalImages)
+	}
+	for _, chunkData := range chunks {
+		if strings.TrimSpace(chunkData.Content) == "" {
+			report.AddSkippedChunk(types.ChunkDiagnostic{
+				ChunkIndex: int(chunkData.Seq),
+				ChunkType:  types.ChunkTypeText,
+				Length:     utf8.RuneCountInString(chunkData.Content),
+				Reason:     types.ProcessingSkipEmptyContent,
+			})
+			report.SkipImages(types.ProcessingSkipEmptyParentChunk, len(chunkData.Images))
+			continue
+		}
+		for _, img := range chunkData.Images {
+			if img.OcrText == "" && img.Caption == "" {
+				report.SkipImages(types.ProcessingSkipNoImageText, 1)
+				continue
+			}
+			report.ProcessedImages++
+		}
+	}
+	return report
+}
+
+// buildDocumentChunks converts docreader chunks into text chunks plus OCR/caption child chunks
+// for their images, sorted by ChunkIndex. Text chunks keep the docreader Seq as their index.
+func buildDocumentChunks(ctx context.Context, knowledge *types.Knowledge, chunks []*proto.Chunk) []*types.Chunk {
+	maxSeq := 0
+
+	// 统计图片相关的子Chunk数量，用于扩展insertChunks的容量
+	imageChunkCount := 0
+	for _, chunkData := range chunks {
+		if len(chunkData.Images) > 0 {
+			// 为每个图片的OCR和Caption分别创建一个Chunk
+			imageChunkCount += len(chunkData.Images) * 2
+		}
+		if int(chunkData.Seq) > maxSeq {
+			maxSeq = int(chunkData.Seq)
+		}
+	}
+
+	// 重新分配容量，考虑图片相关的Chunk
+	insertChunks := make([]*types.Chunk, 0, len(chunks)+imageChunkCount)
+	// 图片子Chunk使用单调递增的序号，排在所有文本Chunk之后，保证不会相互冲突
+	nextChildIndex := maxSeq + 1
+
+	for _, chunkData := range chunks {
+		if strings.TrimSpace(chunkData.Content) == "" {
+			continue
+		}
+
+		// 创建主文本Chunk
+		textChunk := &types.Chunk{
+			ID:              uuid.New().String(),
+			TenantID:        knowledge.TenantID,
+			KnowledgeID:     knowledge.ID,
+			KnowledgeBaseID: knowledge.KnowledgeBaseID,
+			Content:         chunkData.Content,
+			ChunkIndex:      int(chunkData.Seq),
+			IsEnabled:       true,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+			StartAt:         int(chunkData.Start),
+			EndAt:           int(chunkData.End),
+			ChunkType:       types.ChunkTypeText,
+		}
+		if links := extractChunkLinks(chunkData.Content); len(links) > 0 {
+			if err := textChunk.SetDocumentMetadata(&types.DocumentChunkMetadata{Links: links}); err != nil {
+				logger.Warnf(ctx, "Failed to set links metadata for chunk #%d: %v", chunkData.Seq, err)
+			}
+		}
+		var chunkImages []types.ImageInfo
+		insertChunks = append(insertChunks, textChunk)
+
+		// 处理图片信息
+		if len(chunkData.Images) > 0 {
+			logger.GetLogger(ctx).Infof("Processing %d images in chunk #%d", len(chunkData.Images), chunkData.Seq)
+
+			for i, img := range chunkData.Images {
+				// 保存图片信息到文本Chunk
+				imageInfo := types.ImageInfo{
+					URL:         img.Url,
+					OriginalURL: img.OriginalUrl,
+					StartPos:    int(img.Start),
+					EndPos:      int(img.End),
+					OCRText:     img.OcrText,
+					Caption:     img.Caption,
+				}
+				chunkImages = append(chunkImages, imageInfo)
+
+				// 将ImageInfo序列化为JSON
+				imageInfoJSON, err := json.Marshal([]types.ImageInfo{imageInfo})
+				if err != nil {
+					logger.GetLogger(ctx).WithField("error", err).Errorf("Failed to marshal image info to JSON")
+					continue
+				}
+
+				// 如果有OCR文本，创建OCR Chunk
+				if img.OcrText != "" {
+					ocrChunk := &types.Chunk{
+						ID:              uuid.New().String(),
+						TenantID:        knowledge.TenantID,
+						KnowledgeID:     knowledge.ID,
+						KnowledgeBaseID: knowledge.KnowledgeBaseID,
+						Content:         img.OcrText,
+						ChunkIndex:      nextChildIndex,
+						IsEnabled:       true,
+						CreatedAt:       time.Now(),
+						UpdatedAt:       time.Now(),
+						StartAt:         int(img.Start),
+						EndAt:           int(img.End),
+						ChunkType:       types.ChunkTypeImageOCR,
+						ParentChunkID:   textChunk.ID,
+						ImageInfo:       string(imageInfoJSON),
+					}
+					nextChildIndex++
+					insertChunks = append(insertChunks, ocrChunk)
+					logger.GetLogger(ctx).Infof("Created OCR chunk for image %d in chunk #%d", i, chunkData.Seq)
+				}
+
+				// 如果有图片描述，创建Caption Chunk
+				if img.Caption != "" {
+					captionChunk := &types.Chunk{
+						ID:              uuid.New().String(),
+						TenantID:        knowledge.TenantID,
+						KnowledgeID:     knowledge.ID,
+						KnowledgeBaseID: knowledge.KnowledgeBaseID,
+						Content:         img.Caption,
+						ChunkIndex:      nextChildIndex,
+						IsEnabled:       true,
+						CreatedAt:       time.Now(),
+						UpdatedAt:       time.Now(),
+						StartAt:         int(img.Start),
+						EndAt:           int(img.End),
+						ChunkType:       types.ChunkTypeImageCaption,
+						ParentChunkID:   textChunk.ID,
+						ImageInfo:       string(imageInfoJSON),
+					}
+					nextChildIndex++
+					insertChunks = append(insertChunks, captionChunk)
+					logger.GetLogger(ctx).Infof("Created caption chunk for image %d in chunk #%d", i, chunkData.Seq)
+				}
+			}
+
+			imageInfoJSON, err := json.Marshal(chunkImages)
+			if err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("Failed to marshal image info to JSON")
+				continue
+			}
+			textChunk.ImageInfo = string(imageInfoJSON)
+		}
+	}
+
+	// Sort chunks by index for proper ordering
+	sort.Slice(insertChunks, func(i, j int) bool {
+		return insertChunks[i].ChunkIndex < insertChunks[j].ChunkIndex
+	})
+	return insertChunks
+}
+
+// processChunks processes chunks and creates embeddings for knowledge content
+func (s *knowledgeService) processChunks(ctx context.Context,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, chunks []*proto.Chunk,
+	opts ...ProcessChunksOptions,
+) {
+	// Get options
+	var options ProcessChunksOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	ctx, span := tracing.ContextWithSpan(ctx, "knowledgeService.processChunks")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("tenant_id", int(knowledge.TenantID)),
+		attribute.String("knowledge_base_id", knowledge.KnowledgeBaseID),
+		attribute.String("knowledge_id", knowledge.ID),
+		attribute.String("embedding_model_id", kb.EmbeddingModelID),
+		attribute.Int("chunk_count", len(chunks)),
+	)
+
+	// Check if knowledge is being deleted or cancelled before processing
+	if s.isKnowledgeProcessingAborted(ctx, knowledge.TenantID, knowledge.ID) {
+		logger.Infof(ctx, "Knowledge is being deleted or cancelled, aborting chunk processing: %s", knowledge.ID)
+		span.AddEvent("aborted: knowledge is being deleted or cancelled")
+		return
+	}
+
+	// Get embedding model for vectorization
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks get embedding model failed")
+		span.RecordError(err)
+		return
+	}
+
+	// 幂等性处理：清理旧的chunks和索引数据，避免重复数据
+	logger.Infof(ctx, "Cleaning up existing chunks and index data for knowledge: %s", knowledge.ID)
+
+	// 删除旧的chunks
+	if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
+		logger.Warnf(ctx, "Failed to delete existing chunks (may not exist): %v", err)
+		// 不返回错误，继续处理（可能没有旧数据）
+	}
+
+	// 删除旧的索引数据
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err == nil {
+		if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), knowledge.Type); err != nil {
+			logger.Warnf(ctx, "Failed to delete existing index data (may not exist): %v", err)
+			// 不返回错误，继续处理（可能没有旧数据）
+		} else {
+			logger.Infof(ctx, "Successfully deleted existing index data for knowledge: %s", knowledge.ID)
+		}
+	}
+
+	// 删除知识图谱数据（如果存在）
+	namespace := types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID}
+	if err := s.graphEngine.DelGraph(ctx, []types.NameSpace{namespace}); err != nil {
+		logger.Warnf(ctx, "Failed to delete existing graph data (may not exist): %v", err)
+		// 不返回错误，继续处理
+	}
+
+	logger.Infof(ctx, "Cleanup completed, starting to process new chunks")
+
+	if s.parsedMarkdownEnabled() && !knowledge.IsManual() {
+		s.saveParsedMarkdown(ctx, knowledge, chunks)
+	}
+
+	// ========== DocReader 解析结果日志 ==========
+	logger.Infof(ctx, "[DocReader] ========== 解析结果概览 ==========")
+	logger.Infof(ctx, "[DocReader] 知识ID: %s, 知识库ID: %s", knowledge.ID, knowledge.KnowledgeBaseID)
+	logger.Infof(ctx, "[DocReader] 总Chunk数量: %d", len(chunks))
+
+	// 统计图片信息
+	totalImages := 0
+	chunksWithImages := 0
+	for _, chunkData := range chunks {
+		if len(chunkData.Images) > 0 {
+			chunksWithImages++
+			totalImages += len(chunkData.Images)
+		}
+	}
+	logger.Infof(ctx, "[DocReader] 包含图片的Chunk数: %d, 总图片数: %d", chunksWithImages, totalImages)
+
+	// 图片总数超过上限时整篇文档按纯文本处理，避免图片炸弹文档生成海量图片分块
+	maxImages := kb.ImageProcessingConfig.GetMaxImagesPerDocument()
+	imagesDropped := dropDocumentImages(chunks, maxImages)
+	if imagesDropped {
+		logger.Warnf(ctx, "[DocReader] 总图片数 %d 超过上限 %d，已跳过全部图片，按纯文本处理", totalImages, maxImages)
+		warning := fmt.Sprintf("文档包含 %d 张图片，超过上限 %d，已按纯文本处理（图片未解析）", totalImages, maxImages)
+		if knowledge.ParseWarning != "" {
+			warning = knowledge.ParseWarning + "；" + warning
+		}
+		knowledge.ParseWarning = warning
+	}
+	if options.PartialResult {
+		logger.Warnf(ctx, "[DocReader] 解析超时，按部分结果继续处理，部分图片未解析")
+		warning := "文档解析超时，已按部分结果完成，部分图片未解析"
+		if knowledge.ParseWarning != "" {
+			warning = knowledge.ParseWarning + "；" + warning
+		}
+		knowledge.ParseWarning = warning
+	}
+	report := newKnowledgeProcessingReport(knowledge.ID, chunks, totalImages, imagesDropped)
+
+	// 打印每个Chunk的详细信息
+	for idx, chunkData := range chunks {
+		contentPreview := chunkData.Content
+		if len(contentPreview) > 200 {
+			contentPreview = contentPreview[:200] + "..."
+		}
+		logger.Infof(ctx, "[DocReader] Chunk #%d (seq=%d): 内容长度=%d, 图片数=%d, 范围=[%d-%d]",
+			idx, chunkData.Seq, len(chunkData.Content), len(chunkData.Images), chunkData.Start, chunkData.End)
+		logger.Debugf(ctx, "[DocReader] Chunk #%d 内容预览: %s", idx, contentPreview)
+
+		// 打印图片详细信息
+		for imgIdx, img := range chunkData.Images {
+			logger.Infof(ctx, "[DocReader]   图片 #%d: URL=%s", imgIdx, img.Url)
+			logger.Infof(ctx, "[DocReader]   图片 #%d: OriginalURL=%s", imgIdx, img.OriginalUrl)
+			if img.Caption != "" {
+				captionPreview := img.Caption
+				if len(captionPreview) > 100 {
+					captionPreview = captionPreview[:100] + "..."
+				}
+				logger.Infof(ctx, "[DocReader]   图片 #%d: Caption=%s", imgIdx, captionPreview)
+			}
+			if img.OcrText != "" {
+				ocrPreview := img.OcrText
+				if len(ocrPreview) > 100 {
+					ocrPreview = ocrPreview[:100] + "..."
+				}
+				logger.Infof(ctx, "[DocReader]   图片 #%d: OCRText=%s", imgIdx, ocrPreview)
+			}
+			logger.Infof(ctx, "[DocReader]   图片 #%d: 位置=[%d-%d]", imgIdx, img.Start, img.End)
+		}
+	}
+	logger.Infof(ctx, "[DocReader] ========== 解析结果概览结束 ==========")
+
+	// Create chunk objects from proto chunks
+	insertChunks := buildDocumentChunks(ctx, knowledge, chunks)
+	// 暂缓发布的知识从写入起即为禁用状态，分块与索引在启用前都不会被检索到
+	if options.KeepDisabled {
+		for _, chunk := range insertChunks {
+			chunk.IsEnabled = false
+		}
+	}
+
+	// 仅为文本类型的Chunk设置前后关系
+	textChunks := make([]*types.Chunk, 0, len(chunks))
+	for _, chunk := range insertChunks {
+		if chunk.ChunkType == types.ChunkTypeText {
+			textChunks = append(textChunks, chunk)
+		}
+	}
+
+	// 设置文本Chunk之间的前后关系
+	for i, chunk := range textChunks {
+		if i > 0 {
+			textChunks[i-1].NextChunkID = chunk.ID
+		}
+		if i < len(textChunks)-1 {
+			textChunks[i+1].PreChunkID = chunk.ID
+		}
+	}
+
+	// 索引内容模板引用章节标题时，在分块元数据中记录其所在章节
+	if kb.ChunkingConfig.UsesIndexSection() {
+		if err := assignChunkSections(textChunks); err != nil {
+			logger.Warnf(ctx, "processChunks failed to assign chunk sections: %v", err)
+		}
+	}
+
+	// 重新解析时沿用内容未变化分块的已生成问题
+	if len(options.PreservedQuestions) > 0 {
+		restored := restoreGeneratedQuestions(ctx, textChunks, options.PreservedQuestions)
+		logger.Infof(ctx, "processChunks restored generated questions for %d chunks", restored)
+	}
+
+	// Create index information for each chunk (new generated questions are indexed by their own task)
+	indexInfoList := make([]*types.IndexInfo, 0, len(insertChunks))
+	oversizedChunks := 0
+	for _, chunk := range insertChunks {
+		// Image OCR/caption chunks and trivial text chunks may be kept for display only
+		if !kb.ImageProcessingConfig.ShouldIndexChunk(chunk.ChunkType) {
+			report.AddSkippedChunk(newChunkDiagnostic(chunk, types.ProcessingSkipExcludedByConfig))
+			continue
+		}
+		if !kb.ChunkingConfig.ShouldIndexChunk(chunk.ChunkType, chunk.Content) {
+			report.AddSkippedChunk(newChunkDiagnostic(chunk, types.ProcessingSkipBelowMinLength))
+			continue
+		}
+		// Add original chunk content to index, oversized chunks are split or truncated
+		contentInfos, oversized := buildChunkContentIndexInfoList(kb, chunk, knowledge.Title)
+		if oversized {
+			oversizedChunks++
+			logger.Warnf(ctx, "Chunk %s (index %d) has %d characters, exceeding max index tokens %d, indexed as %d part(s)",
+				chunk.ID, chunk.ChunkIndex, utf8.RuneCountInString(chunk.Content),
+				kb.ChunkingConfig.GetMaxIndexTokens(), len(contentInfos))
+			diagnostic := newChunkDiagnostic(chunk, "")
+			diagnostic.IndexParts = len(contentInfos)
+			report.AddOversizedChunk(diagnostic)
+		}
+		indexInfoList = append(indexInfoList, contentInfos...)
+		indexInfoList = append(indexInfoList, buildGeneratedQuestionIndexInfoList(chunk)...)
+		indexInfoList = append(indexInfoList, buildChunkLinkIndexInfoList(kb, chunk)...)
+	}
+	if oversizedChunks > 0 && kb.ChunkingConfig.OversizedChunkStrategy == types.OversizedChunkStrategyTruncate {
+		warning := fmt.Sprintf("%d 个超长分块仅索引了前 %d 个字符", oversizedChunks, kb.ChunkingConfig.GetMaxIndexTokens())
+		if knowledge.ParseWarning != "" {
+			warning = knowledge.ParseWarning + "；" + warning
+		}
+		knowledge.ParseWarning = warning
+	}
+	// 跳过摘要生成时，旧的摘要分块已随旧数据清理，用保留的摘要重新创建
+	if options.SkipSummary && strings.TrimSpace(knowledge.Description) != "" && len(textChunks) > 0 {
+		summaryChunk := newSummaryChunk(knowledge, knowledge.Description, insertChunks, textChunks[0].ID,
+			!options.KeepDisabled)
+		insertChunks = append(insertChunks, summaryChunk)
+		indexInfoList = append(indexInfoList, newSummaryIndexInfo(summaryChunk))
+	}
+	report.CreatedChunks = len(insertChunks)
+	s.setKnowledgeProcessingReport(ctx, knowledge, report)
+
+	// Initialize retrieval engine
+
+	// Calculate storage size required for embeddings
+	span.AddEvent("estimate storage size")
+	totalStorageSize := retrieveEngine.EstimateStorageSize(ctx, embeddingModel, indexInfoList)
+	if tenantInfo.StorageQuota > 0 {
+		// Re-fetch tenant storage information
+		tenantInfo, err = s.tenantRepo.GetTenantByID(ctx, tenantInfo.ID)
+		if err != nil {
+			s.failKnowledgeProcessing(ctx, knowledge, err.Error())
+			span.RecordError(err)
+			return
+		}
+		// Check if there's enough storage quota available
+		if tenantInfo.StorageUsed+totalStorageSize > tenantInfo.StorageQuota {
+			s.failKnowledgeProcessing(ctx, knowledge, "存储空间不足")
+			span.RecordError(errors.New("storage quota exceeded"))
+			return
+		}
+	}
+
+	// Check again if knowledge is being deleted or cancelled before writing to database
+	if s.isKnowledgeProcessingAborted(ctx, knowledge.TenantID, knowledge.ID) {
+		logger.Infof(ctx, "Knowledge is being deleted or cancelled, aborting before saving chunks: %s", knowledge.ID)
+		span.AddEvent("aborted: knowledge is being deleted or cancelled before saving")
+		return
+	}
+
+	// Save chunks to database
+	span.AddEvent("create chunks")
+	if err := s.chunkService.CreateChunks(ctx, insertChunks); err != nil {
+		s.failKnowledgeProcessing(ctx, knowledge, err.Error())
+		span.RecordError(err)
+		return
+	}
+
+	// Check again before batch indexing (this is a heavy operation)
+	if s.isKnowledgeProcessingAborted(ctx, knowledge.TenantID, knowledge.ID) {
+		logger.Infof(ctx, "Knowledge is being deleted or cancelled, cleaning up and aborting before indexing: %s", knowledge.ID)
+		// Clean up the chunks we just created
+		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
+			logger.Warnf(ctx, "Failed to cleanup chunks after deletion or cancellation detected: %v", err)
+		}
+		span.AddEvent("aborted: knowledge is being deleted or cancelled before indexing")
+		return
+	}
+
+	span.AddEvent("batch index")
+	var failedChunks map[string]error
+	if kb.ChunkingConfig.IsolateIndexFailures {
+		failedChunks, err = s.batchIndexIsolatingFailures(ctx, retrieveEngine, embeddingModel, kb.Type, indexInfoList)
+	} else {
+		err = retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList)
+	}
+	if err != nil {
+		s.failKnowledgeProcessing(ctx, knowledge, err.Error())
+
+		// delete failed chunks
+		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
+			logger.Errorf(ctx, "Delete chunks failed: %v", err)
+		}
+
+		// delete index
+		if err := retrieveEngine.DeleteByKnowledgeIDList(
+			ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), kb.Type,
+		); err != nil {
+			logger.Errorf(ctx, "Delete index failed: %v", err)
+		}
+		span.RecordError(err)
+		return
+	}
+	logger.GetLogger(ctx).Infof("processChunks batch index successfully, with %d index", len(indexInfoList))
+	if len(failedChunks) > 0 {
+		if warning := s.markIndexFailedChunks(ctx, insertChunks, failedChunks); warning != "" {
+			if knowledge.ParseWarning != "" {
+				warning = knowledge.ParseWarning + "；" + warning
+			}
+			knowledge.ParseWarning = warning
+		}
+		span.SetAttributes(attribute.Int("index_failed_chunks", len(failedChunks)))
+		for _, chunk := range insertChunks {
+			if _, ok := failedChunks[chunk.ID]; ok {
+				report.AddSkippedChunk(newChunkDiagnostic(chunk, types.ProcessingSkipIndexFailed))
+			}
+		}
+	}
+	report.IndexedChunks = indexedChunkCount(kb, insertChunks, failedChunks)
+	s.setKnowledgeProcessingReport(ctx, knowledge, report)
+
+	// 严格模式下没有任何分块写入检索索引时，文档按失败处理而不是以空内容完成
+	if kb.ChunkingConfig.RequireIndexableContent && report.IndexedChunks == 0 {
+		logger.Warnf(ctx, "processChunks produced no indexable content for knowledge %s (%d chunks)",
+			knowledge.ID, len(insertChunks))
+		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
+			logger.Warnf(ctx, "Failed to cleanup chunks of knowledge without indexable content: %v", err)
+		}
+		if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), kb.Type); err != nil {
+			logger.Warnf(ctx, "Failed to cleanup index of knowledge without indexable content: %v", err)
+		}
+		s.failKnowledgeProcessing(ctx, knowledge, ErrNoIndexableContent.Error())
+		span.RecordError(ErrNoIndexableContent)
+		return
+	}
+
+	logger.Infof(ctx, "processChunks create relationship rag task")
+	if kb.ExtractConfig != nil && kb.ExtractConfig.Enabled {
+		for _, chunk := range textChunks {
+			err := NewChunkExtractTask(ctx, s.task, chunk.TenantID, chunk.ID, kb.SummaryModelID)
+			if err != nil {
+				logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks create chunk extract task failed")
+				span.RecordError(err)
+			}
+		}
+	}
+
+	// Final check before marking as completed - if deleted or cancelled during processing, don't update status
+	if s.isKnowledgeProcessingAborted(ctx, knowledge.TenantID, knowledge.ID) {
+		logger.Infof(ctx, "Knowledge was deleted or cancelled during processing, skipping completion update: %s", knowledge.ID)
+		// Clean up the data we just created since the knowledge is being deleted or cancelled
+		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
+			logger.Warnf(ctx, "Failed to cleanup chunks after deletion or cancellation detected: %v", err)
+		}
+		if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), kb.Type); err != nil {
+			logger.Warnf(ctx, "Failed to cleanup index after deletion or cancellation detected: %v", err)
+		}
+		span.AddEvent("aborted: knowledge was deleted or cancelled during processing")
+		return
+	}
+
+	// Update knowledge status to completed
+	knowledge.ParseStatus = types.ParseStatusCompleted
+	if !options.KeepDisabled {
+		knowledge.EnableStatus = "enabled"
+	}
+	knowledge.StorageSize = totalStorageSize
+	now := time.Now()
+	knowledge.ProcessedAt = &now
+	knowledge.UpdatedAt = now
+
+	// Set summary status based on whether summary generation will be triggered,
+	// a skipped summary keeps its existing status
+	if !options.SkipSummary {
+		if len(textChunks) > 0 {
+			knowledge.SummaryStatus = types.SummaryStatusPending
+		} else {
+			knowledge.SummaryStatus = types.SummaryStatusNone
+		}
+	}
+
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks update knowledge failed")
+	}
+
+	// Enqueue question generation task if enabled (async, non-blocking)
+	if options.EnableQuestionGeneration && len(textChunks) > 0 {
+		questionCount := options.QuestionCount
+		if questionCount <= 0 {
+			questionCount = 3
+		}
+		if questionCount > 10 {
+			questionCount = 10
+		}
+		s.enqueueQuestionGenerationTask(ctx, knowledge.KnowledgeBaseID, knowledge.ID, questionCount)
+	}
+
+	// Enqueue summary generation task (async, non-blocking)
+	if !options.SkipSummary && len(textChunks) > 0 {
+		s.enqueueSummaryGenerationTask(ctx, knowledge.KnowledgeBaseID, knowledge.ID)
+	}
+
+	// Enqueue keyword/entity extraction task if enabled (async, non-blocking)
+	if kb.KeywordExtractionConfig.IsEnabled() && len(textChunks) > 0 {
+		s.enqueueKeywordExtractionTask(ctx, knowledge.KnowledgeBaseID, knowledge.ID)
+	}
+
+	// Update tenant's storage usage
+	tenantInfo.StorageUsed += totalStorageSize
+	if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, totalStorageSize); err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks update tenant storage used failed")
+	}
+	logger.GetLogger(ctx).Infof("processChunks successfully")
+}
+
+// newChunkDiagnostic describes a stored chunk for the processing report
+func newChunkDiagnostic(chunk *types.Chunk, reason types.ProcessingSkipReason) types.ChunkDiagnostic {
+	return types.ChunkDiagnostic{
+		ChunkIndex: chunk.ChunkIndex,
+		ChunkID:    chunk.ID,
+		ChunkType:  chunk.ChunkType,
+		Length:     utf8.RuneCountInString(chunk.Content),
+		Reason:     reason,
+	}
+}
+
+// setKnowledgeProcessingReport attaches the report to the knowledge; it is persisted with the next knowledge update
+func (s *knowledgeService) setKnowledgeProcessingReport(ctx context.Context,
+	knowledge *types.Knowledge, report *types.KnowledgeProcessingReport,
+) {
+	if err := knowledge.SetProcessingReport(report); err != nil {
+		logger.Warnf(ctx, "Failed to set processing report of knowledge %s: %v", knowledge.ID, err)
+	}
+}
+
+// GetKnowledgeProcessingReport returns the diagnostics recorded while chunking the knowledge at its last parse
+func (s *knowledgeService) GetKnowledgeProcessingReport(ctx context.Context,
+	knowledgeID string,
+) (*types.KnowledgeProcessingReport, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	report, err := knowledge.GetProcessingReport()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse processing report: %w", err)
+	}
+	if report == nil {
+		return nil, werrors.NewNotFoundError("该知识暂无处理报告，请重新解析后查看")
+	}
+	return report, nil
+}
+
+// indexedChunkCount returns the number of chunks whose content was written to the retrieval index:
+// chunks kept for display only (image chunks not indexed, text below MinIndexContentLength) and
+// chunks that failed indexing are not counted
+func indexedChunkCount(kb *types.KnowledgeBase, chunks []*types.Chunk, failed map[string]error) int {
+	count := 0
+	for _, chunk := range chunks {
+		if !kb.ImageProcessingConfig.ShouldIndexChunk(chunk.ChunkType) ||
+			!kb.ChunkingConfig.ShouldIndexChunk(chunk.ChunkType, chunk.Content) {
+			continue
+		}
+		if _, ok := failed[chunk.ID]; ok {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// buildSummaryMetadataIntro renders the metadata intro prepended to document content before
+// summarizing. Tenants can replace the built-in Chinese intro with their own template or disable it.
+func buildSummaryMetadataIntro(cfg *types.DocumentSummaryConfig, knowledge *types.Knowledge) string {
+	if cfg != nil && cfg.DisableMetadataIntro {
+		return ""
+	}
+	if cfg != nil && strings.TrimSpace(cfg.MetadataIntroTemplate) != "" {
+		return strings.NewReplacer(
+			"{{file_type}}", knowledge.FileType,
+			"{{file_name}}", knowledge.FileName,
+			"{{knowledge_type}}", knowledge.Type,
+		).Replace(cfg.MetadataIntroTemplate)
+	}
+
+	metadataIntro := fmt.Sprintf("文档类型: %s\n文件名称: %s\n", knowledge.FileType, knowledge.FileName)
+	// Add additional metadata if available
+	if knowledge.Type != "" {
+		metadataIntro += fmt.Sprintf("知识类型: %s\n", knowledge.Type)
+	}
+	return metadataIntro + "\n内容:\n"
+}
+
+// GetSummary generates a summary for knowledge content using an AI model
+func (s *knowledgeService) getSummary(ctx context.Context,
+	summaryModel chat.Chat, knowledge *types.Knowledge, chunks []*types.Chunk, summaryConfig *types.DocumentSummaryConfig,
+) (string, error) {
+	// Get knowledge info from the first chunk
+	if len(chunks) == 0 {
+		return "", fmt.Errorf("no chunks provided for summary generation")
+	}
+
+	// concat chunk contents
+	chunkContents := ""
+	allImageInfos := make([]*types.ImageInfo, 0)
+
+	// then, sort chunks by StartAt
+	sortedChunks := make([]*types.Chunk, len(chunks))
+	copy(sortedChunks, chunks)
+	sort.Slice(sortedChunks, func(i, j int) bool {
+		return sortedChunks[i].StartAt < sortedChunks[j].StartAt
+	})
+
+	// concat chunk contents and collect image infos
+	for _, chunk := range sortedChunks {
+		if chunk.EndAt > 4096 {
+			break
+		}
+		// Ensure we don't slice beyond the current content length
+		runes := []rune(chunkContents)
+		if chunk.StartAt <= len(runes) {
+			chunkContents = string(runes[:chunk.StartAt]) + chunk.Content
+		} else {
+			// If StartAt is beyond current content, just append
+			chunkContents = chunkContents + chunk.Content
+		}
+		if chunk.ImageInfo != "" {
+			var images []*types.ImageInfo
+			if err := json.Unmarshal([]byte(chunk.ImageInfo), &images); err == nil {
+				allImageInfos = append(allImageInfos, images...)
+			}
+		}
+	}
+	// remove markdown image syntax
+	re := regexp.MustCompile(`!\[[^\]]*\]\([^)]+\)`)
+	chunkContents = re.ReplaceAllString(chunkContents, "")
+	// collect all image infos
+	if len(allImageInfos) > 0 {
+		// add image infos to chunk contents
+		var imageAnnotations string
+		for _, img := range allImageInfos {
+			if img.Caption != "" {
+				imageAnnotations += fmt.Sprintf("\n[图片描述: %s]", img.Caption)
+			}
+			if img.OCRText != "" {
+				imageAnnotations += fmt.Sprintf("\n[图片文字: %s]", img.OCRText)
+			}
+		}
+
+		// concat chunk contents and image annotations
+		chunkContents = chunkContents + imageAnnotations
+	}
+
+	if len(chunkContents) < 300 {
+		return chunkContents, nil
+	}
+
+	// Prepare content with metadata for summary generation
+	contentWithMetadata := chunkContents
+
+	// Add knowledge metadata if available
+	if knowledge != nil {
+		contentWithMetadata = buildSummaryMetadataIntro(summaryConfig, knowledge) + contentWithMetadata
+	}
+
+	// Generate summary using AI model
+	thinking := false
+	summary, err := summaryModel.Chat(ctx, []chat.Message{
+		{
+			Role:    "system",
+			Content: s.config.Conversation.GenerateSummaryPrompt,
+		},
+		{
+			Role:    "user",
+			Content: contentWithMetadata,
+		},
+	}, &chat.ChatOptions{
+		Temperature: 0.3,
+		MaxTokens:   1024,
+		Thinking:    &thinking,
+	})
+	if err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Errorf("GetSummary failed")
+		return "", err
+	}
+	logger.GetLogger(ctx).WithField("summary", summary.Content).Infof("GetSummary success")
+	return summary.Content, nil
+}
+
+// enqueueQuestionGenerationTask enqueues an async task for question generation
+func (s *knowledgeService) enqueueQuestionGenerationTask(ctx context.Context,
+	kbID, knowledgeID string, questionCount int,
+) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	payload := types.QuestionGenerationPayload{
+		TenantID:        tenantID,
+		KnowledgeBaseID: kbID,
+		KnowledgeID:     knowledgeID,
+		QuestionCount:   questionCount,
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to marshal question generation payload: %v", err)
+		return
+	}
+
+	task := asynq.NewTask(types.TypeQuestionGeneration, payloadBytes, asynq.Queue("low"), asynq.MaxRetry(3))
+	info, err := s.task.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue question generation task: %v", err)
+		return
+	}
+	logger.Infof(ctx, "Enqueued question generation task: %s for knowledge: %s", info.ID, knowledgeID)
+}
+
+// enqueueSummaryGenerationTask enqueues an async task for summary generation
+func (s *knowledgeService) enqueueSummaryGenerationTask(ctx context.Context,
+	kbID, knowledgeID string,
+) error {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	payload := types.SummaryGenerationPayload{
+		TenantID:        tenantID,
+		KnowledgeBaseID: kbID,
+		KnowledgeID:     knowledgeID,
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to marshal summary generation payload: %v", err)
+		return err
+	}
+
+	task := asynq.NewTask(types.TypeSummaryGeneration, payloadBytes, asynq.Queue("low"), asynq.MaxRetry(3))
+	info, err := s.task.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue summary generation task: %v", err)
+		return err
+	}
+	logger.Infof(ctx, "Enqueued summary generation task: %s for knowledge: %s", info.ID, knowledgeID)
+	return nil
+}
+
+// enqueueKeywordExtractionTask enqueues an async task for document keyword/entity extraction
+func (s *knowledgeService) enqueueKeywordExtractionTask(ctx context.Context, kbID, knowledgeID string) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	payloadBytes, err := json.Marshal(types.KeywordExtractionPayload{
+		TenantID:        tenantID,
+		KnowledgeBaseID: kbID,
+		KnowledgeID:     knowledgeID,
+	})
+	if err != nil {
+		logger.Errorf(ctx, "Failed to marshal keyword extraction payload: %v", err)
+		return
+	}
+
+	task := asynq.NewTask(types.TypeKeywordExtraction, payloadBytes, asynq.Queue("low"), asynq.MaxRetry(3))
+	info, err := s.task.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue keyword extraction task: %v", err)
+		return
+	}
+	logger.Infof(ctx, "Enqueued keyword extraction task: %s for knowledge: %s", info.ID, knowledgeID)
+}
+
+const (
+	summaryRegenProgressKeyPrefix = "summary_regen_progress:"
+	summaryRegenProgressTTL       = 24 * time.Hour
+	summaryRegenConcurrency       = 10
+)
+
+// getSummaryRegenProgressKey returns the Redis key for storing summary regeneration progress
+func getSummaryRegenProgressKey(taskID string) string {
+	return summaryRegenProgressKeyPrefix + taskID
+}
+
+// RegenerateMissingSummaries re-enqueues summary generation for completed knowledge whose
+// summary failed or was never produced. Knowledge without text chunks is marked as
+// SummaryStatusNone instead of being retried. Work runs in the background; the returned
+// progress can be polled with GetSummaryRegenerationProgress.
+func (s *knowledgeService) RegenerateMissingSummaries(ctx context.Context,
+	kbID string,
+) (*types.SummaryRegenerationProgress, error) {
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	if kb.Type == types.KnowledgeBaseTypeFAQ {
+		return nil, werrors.NewBadRequestError("FAQ 知识库不支持生成摘要")
+	}
+	if kb.SummaryModelID == "" {
+		return nil, werrors.NewBadRequestError("知识库未配置摘要模型")
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledgeList, err := s.repo.ListKnowledgeByKnowledgeBaseID(ctx, tenantID, kbID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*types.Knowledge, 0)
+	for _, k := range knowledgeList {
+		if k.ParseStatus != types.ParseStatusCompleted {
+			continue
+		}
+		switch k.SummaryStatus {
+		case types.SummaryStatusFailed:
+			candidates = append(candidates, k)
+		case "", types.SummaryStatusNone:
+			if strings.TrimSpace(k.Description) == "" {
+				candidates = append(candidates, k)
+			}
+		}
+	}
+
+	now := time.Now().Unix()
+	progress := &types.SummaryRegenerationProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    secutils.GenerateTaskID("summary_regen", tenantID, kbID),
+			TenantID:  tenantID,
+			KBID:      kbID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			Total:     len(candidates),
+			Message:   "正在提交摘要生成任务...",
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	if len(candidates) == 0 {
+		progress.Status = types.KnowledgeTaskStatusCompleted
+		progress.Progress = 100
+		progress.Message = "没有需要补生成摘要的知识"
+	}
+	if err := s.saveSummaryRegenProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save summary regeneration progress: %v", err)
+	}
+	if len(candidates) == 0 {
+		return progress, nil
+	}
+
+	logger.Infof(ctx, "Regenerating missing summaries: kb_id=%s, candidates=%d, task_id=%s",
+		kbID, len(candidates), progress.TaskID)
+
+	newCtx := logger.CloneContext(ctx)
+	snapshot := *progress
+	go s.regenerateSummaries(newCtx, kbID, candidates, &snapshot)
+
+	return progress, nil
+}
+
+// regenerateSummaries enqueues summary generation for each candidate with bounded concurrency
+func (s *knowledgeService) regenerateSummaries(ctx context.Context,
+	kbID string, candidates []*types.Knowledge, progress *types.SummaryRegenerationProgress,
+) {
+	var mu sync.Mutex
+	record := func(enqueued, skipped, failed int) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Processed++
+		progress.Enqueued += enqueued
+		progress.Skipped += skipped
+		progress.Failed += failed
+		progress.Progress = progress.Processed * 100 / progress.Total
+		progress.UpdatedAt = time.Now().Unix()
+		if err := s.saveSummaryRegenProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to update summary regeneration progress: %v", err)
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(summaryRegenConcurrency)
+	for _, k := range candidates {
+		g.Go(func() error {
+			hasText, err := s.chunkRepo.HasChunksOfType(gctx, k.TenantID, k.ID, types.ChunkTypeText)
+			if err != nil {
+				logger.Errorf(gctx, "Failed to check text chunks of knowledge %s: %v", k.ID, err)
+				record(0, 0, 1)
+				return nil
+			}
+
+			// 没有文本分块的知识无法生成摘要，直接标记为无需摘要，避免反复重试
+			if !hasText {
+				if err := s.repo.UpdateKnowledgeColumn(gctx, k.ID, "summary_status", types.SummaryStatusNone); err != nil {
+					logger.Errorf(gctx, "Failed to mark summary status none for knowledge %s: %v", k.ID, err)
+					record(0, 0, 1)
+					return nil
+				}
+				record(0, 1, 0)
+				return nil
+			}
+
+			if err := s.repo.UpdateKnowledgeColumn(gctx, k.ID, "summary_status", types.SummaryStatusPending); err != nil {
+				logger.Warnf(gctx, "Failed to mark summary status pending for knowledge %s: %v", k.ID, err)
+			}
+			if err := s.enqueueSummaryGenerationTask(gctx, kbID, k.ID); err != nil {
+				if err := s.repo.UpdateKnowledgeColumn(gctx, k.ID, "summary_status", types.SummaryStatusFailed); err != nil {
+					logger.Warnf(gctx, "Failed to restore summary status for knowledge %s: %v", k.ID, err)
+				}
+				record(0, 0, 1)
+				return nil
+			}
+			record(1, 0, 0)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	progress.Status = types.KnowledgeTaskStatusCompleted
+	progress.Progress = 100
+	progress.Message = fmt.Sprintf("已提交 %d 个摘要生成任务，跳过 %d 个，失败 %d 个",
+		progress.Enqueued, progress.Skipped, progress.Failed)
+	progress.UpdatedAt = time.Now().Unix()
+	if err := s.saveSummaryRegenProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save final summary regeneration progress: %v", err)
+	}
+	logger.Infof(ctx, "Summary regeneration finished: task_id=%s, enqueued=%d, skipped=%d, failed=%d",
+		progress.TaskID, progress.Enqueued, progress.Skipped, progress.Failed)
+}
+
+// saveSummaryRegenProgress saves the summary regeneration progress to Redis
+func (s *knowledgeService) saveSummaryRegenProgress(ctx context.Context,
+	progress *types.SummaryRegenerationProgress,
+) error {
+	return s.saveTaskProgress(ctx, getSummaryRegenProgressKey(progress.TaskID), progress, summaryRegenProgressTTL)
+}
+
+// GetSummaryRegenerationProgress retrieves the progress of a summary regeneration task
+func (s *knowledgeService) GetSummaryRegenerationProgress(ctx context.Context,
+	taskID string,
+) (*types.SummaryRegenerationProgress, error) {
+	var progress types.SummaryRegenerationProgress
+	if err := s.loadTaskProgress(ctx, getSummaryRegenProgressKey(taskID), &progress); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Summary regeneration task not found")
+		}
+		return nil, err
+	}
+	return &progress, nil
+}
+
+const (
+	graphRebuildProgressKeyPrefix = "graph_rebuild_progress:"
+	graphRebuildProgressTTL       = 24 * time.Hour
+	graphRebuildConcurrency       = 5
+)
+
+// getGraphRebuildProgressKey returns the Redis key for storing knowledge graph rebuild progress
+func getGraphRebuildProgressKey(taskID string) string {
+	return graphRebuildProgressKeyPrefix + taskID
+}
+
+// RebuildKnowledgeGraph rebuilds the knowledge graph of a whole knowledge base, e.g. after graph
+// extraction was enabled on a knowledge base that already has indexed documents. Existing graph
+// data is deleted and the graph is extracted again from the text chunks of every parsed document.
+// Work runs in an asynq task; the returned progress can be polled with GetKnowledgeGraphRebuildProgress.
+func (s *knowledgeService) RebuildKnowledgeGraph(ctx context.Context,
+	kbID string,
+) (*types.KnowledgeGraphRebuildProgress, error) {
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	if kb.Type == types.KnowledgeBaseTypeFAQ {
+		return nil, werrors.NewBadRequestError("FAQ 知识库不支持知识图谱")
+	}
+	if kb.ExtractConfig == nil || !kb.ExtractConfig.Enabled {
+		return nil, werrors.NewBadRequestError("知识库未开启知识图谱抽取")
+	}
+	if strings.ToLower(os.Getenv("NEO4J_ENABLE")) != "true" {
+		return nil, werrors.NewBadRequestError("知识图谱服务未启用")
+	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	now := time.Now().Unix()
+	progress := &types.KnowledgeGraphRebuildProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    secutils.GenerateTaskID("graph_rebuild", tenantID, kbID),
+			TenantID:  tenantID,
+			KBID:      kbID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			Message:   "等待重建知识图谱...",
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	s.saveGraphRebuildProgress(ctx, progress)
+
+	logger.Infof(ctx, "Rebuilding knowledge graph: kb_id=%s, task_id=%s", kbID, progress.TaskID)
+
+	payloadBytes, err := json.Marshal(types.GraphRebuildPayload{
+		TenantID: tenantID,
+		TaskID:   progress.TaskID,
+		KBID:     kbID,
+	})
+	if err == nil {
+		task := asynq.NewTask(types.TypeGraphRebuild, payloadBytes,
+			asynq.TaskID(progress.TaskID), asynq.Queue("low"), asynq.MaxRetry(3))
+		_, err = s.task.Enqueue(task)
+	}
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue knowledge graph rebuild task: %v", err)
+		progress.Status = types.KnowledgeTaskStatusFailed
+		progress.Message = "提交知识图谱重建任务失败"
+		progress.UpdatedAt = time.Now().Unix()
+		s.saveGraphRebuildProgress(ctx, progress)
+		return nil, fmt.Errorf("failed to enqueue graph rebuild task: %w", err)
+	}
+	return progress, nil
+}
+
+// ProcessKnowledgeGraphRebuild handles the knowledge graph rebuild task. The graph data of all knowledge
+// in the knowledge base is deleted, then the graph of every text chunk of the parsed knowledge is
+// extracted with bounded concurrency, so the extraction model is not flooded by a large knowledge
+// base at once. The task completes once every extraction has finished.
+func (s *knowledgeService) ProcessKnowledgeGraphRebuild(ctx context.Context, t *asynq.Task) error {
+	var payload types.GraphRebuildPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal knowledge graph rebuild payload: %v", err)
+		return nil
+	}
+
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	isLastRetry := retryCount >= maxRetry
+
+	now := time.Now().Unix()
+	progress := &types.KnowledgeGraphRebuildProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    payload.TaskID,
+			TenantID:  payload.TenantID,
+			KBID:      payload.KBID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			Message:   "正在清理已有图谱数据...",
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	var previous types.KnowledgeGraphRebuildProgress
+	if err := s.loadTaskProgress(ctx, getGraphRebuildProgressKey(payload.TaskID), &previous); err == nil {
+		progress.CreatedAt = previous.CreatedAt
+	}
+	s.saveGraphRebuildProgress(ctx, progress)
+	handleError := func(err error) error {
+		if isLastRetry {
+			progress.Status = types.KnowledgeTaskStatusFailed
+			progress.Message = fmt.Sprintf("重建知识图谱失败: %v", err)
+			progress.UpdatedAt = time.Now().Unix()
+			s.saveGraphRebuildProgress(ctx, progress)
+		}
+		return err
+	}
+
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get tenant %d: %v", payload.TenantID, err)
+		return handleError(err)
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KBID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base %s: %v", payload.KBID, err)
+		return handleError(err)
+	}
+	if kb.ExtractConfig == nil || !kb.ExtractConfig.Enabled {
+		progress.Status = types.KnowledgeTaskStatusFailed
+		progress.Message = "知识库未开启知识图谱抽取"
+		progress.UpdatedAt = time.Now().Unix()
+		s.saveGraphRebuildProgress(ctx, progress)
+		return nil
+	}
+
+	knowledgeList, err := s.repo.ListKnowledgeByKnowledgeBaseID(ctx, payload.TenantID, payload.KBID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list knowledge for graph rebuild: %v", err)
+		return handleError(err)
+	}
+	namespaces := make([]types.NameSpace, 0, len(knowledgeList))
+	for _, k := range knowledgeList {
+		namespaces = append(namespaces, types.NameSpace{KnowledgeBase: kb.ID, Knowledge: k.ID})
+	}
+	if len(namespaces) > 0 {
+		if err := s.graphEngine.DelGraph(ctx, namespaces); err != nil {
+			logger.Errorf(ctx, "Failed to delete knowledge graph of kb %s: %v", kb.ID, err)
+			return handleError(fmt.Errorf("清理已有图谱数据失败: %w", err))
+		}
+	}
+
+	var chunks []*types.Chunk
+	for _, k := range knowledgeList {
+		if k.ParseStatus != types.ParseStatusCompleted {
+			continue
+		}
+		knowledgeChunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, k.ID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to list chunks for knowledge %s: %v", k.ID, err)
+			return handleError(err)
+		}
+		progress.Knowledge++
+		for _, chunk := range knowledgeChunks {
+			if chunk.ChunkType == types.ChunkTypeText {
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+	progress.Total = len(chunks)
+	if len(chunks) == 0 {
+		progress.Status = types.KnowledgeTaskStatusCompleted
+		progress.Progress = 100
+		progress.Message = "已清理图谱数据，没有需要重建的知识"
+		progress.UpdatedAt = time.Now().Unix()
+		s.saveGraphRebuildProgress(ctx, progress)
+		return nil
+	}
+
+	chatModel, err := s.modelService.GetChatModel(ctx, kb.SummaryModelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get chat model %s: %v", kb.SummaryModelID, err)
+		return handleError(err)
+	}
+	extractor := chatpipline.NewExtractor(chatModel, graphExtractTemplate(s.config.ExtractManager.ExtractGraph, kb))
+
+	progress.Message = fmt.Sprintf("正在为 %d 个知识的 %d 个分块抽取图谱...", progress.Knowledge, len(chunks))
+	progress.UpdatedAt = time.Now().Unix()
+	s.saveGraphRebuildProgress(ctx, progress)
+
+	var mu sync.Mutex
+	record := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Processed++
+		if err != nil {
+			progress.Failed++
+		}
+		progress.Progress = progress.Processed * 100 / progress.Total
+		progress.UpdatedAt = time.Now().Unix()
+		s.saveGraphRebuildProgress(ctx, progress)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(graphRebuildConcurrency)
+	for _, chunk := range chunks {
+		g.Go(func() error {
+			graph, err := extractor.Extract(gctx, chunk.Content)
+			if err == nil {
+				err = addChunkGraph(gctx, s.graphEngine, chunk, graph)
+			}
+			if err != nil {
+				logger.Errorf(gctx, "Failed to rebuild graph of chunk %s: %v", chunk.ID, err)
+			}
+			record(err)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	progress.Status = types.KnowledgeTaskStatusCompleted
+	progress.Progress = 100
+	progress.Message = fmt.Sprintf("%d 个分块中，已抽取图谱 %d 个，失败 %d 个",
+		progress.Total, progress.Processed-progress.Failed, progress.Failed)
+	progress.UpdatedAt = time.Now().Unix()
+	s.saveGraphRebuildProgress(ctx, progress)
+	logger.Infof(ctx, "Knowledge graph rebuild finished: task_id=%s, chunks=%d, failed=%d",
+		progress.TaskID, progress.Total, progress.Failed)
+	return nil
+}
+
+// saveGraphRebuildProgress saves the knowledge graph rebuild progress to Redis
+func (s *knowledgeService) saveGraphRebuildProgress(ctx context.Context,
+	progress *types.KnowledgeGraphRebuildProgress,
+) {
+	if err := s.saveTaskProgress(ctx, getGraphRebuildProgressKey(progress.TaskID), progress,
+		graphRebuildProgressTTL); err != nil {
+		logger.Warnf(ctx, "Failed to save graph rebuild progress: %v", err)
+	}
+}
+
+// GetKnowledgeGraphRebuildProgress retrieves the progress of a knowledge graph rebuild task
+func (s *knowledgeService) GetKnowledgeGraphRebuildProgress(ctx context.Context,
+	taskID string,
+) (*types.KnowledgeGraphRebuildProgress, error) {
+	var progress types.KnowledgeGraphRebuildProgress
+	if err := s.loadTaskProgress(ctx, getGraphRebuildProgressKey(taskID), &progress); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Knowledge graph rebuild task not found")
+		}
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// saveTaskProgress saves the progress of a background task to Redis
+func (s *knowledgeService) saveTaskProgress(ctx context.Context,
+	key string, progress any, ttl time.Duration,
+) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+	return s.redisClient.Set(ctx, key, data, ttl).Err()
+}
+
+// loadTaskProgress reads the progress of a background task from Redis into progress, redis.Nil is
+// returned unwrapped when the task does not exist
+func (s *knowledgeService) loadTaskProgress(ctx context.Context, key string, progress any) error {
+	data, err := s.redisClient.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return err
+		}
+		return fmt.Errorf("failed to get progress from Redis: %w", err)
+	}
+	if err := json.Unmarshal(data, progress); err != nil {
+		return fmt.Errorf("failed to unmarshal progress: %w", err)
+	}
+	return nil
+}
+
+// summaryFallbackMaxRunes 截断兜底时作为描述保存的最大字符数
+const summaryFallbackMaxRunes = 500
+
+// truncateSummaryFallback returns the first summaryFallbackMaxRunes characters of content
+func truncateSummaryFallback(content string) string {
+	runes := []rune(content)
+	if len(runes) > summaryFallbackMaxRunes {
+		return string(runes[:summaryFallbackMaxRunes])
+	}
+	return content
+}
+
+// ProcessSummaryGeneration handles async summary generation task
+func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asynq.Task) error {
+	var payload types.SummaryGenerationPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal summary generation payload: %v", err)
+		return nil // Don't retry on unmarshal error
+	}
+
+	logger.Infof(ctx, "Processing summary generation for knowledge: %s", payload.KnowledgeID)
+
+	// Set tenant context
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+
+	// Get knowledge base
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil
+	}
+
+	// Get knowledge
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge: %v", err)
+		return nil
+	}
+
+	// Update summary status to processing
+	knowledge.SummaryStatus = types.SummaryStatusProcessing
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		logger.Warnf(ctx, "Failed to update summary status to processing: %v", err)
+	}
+
+	// Helper function to mark summary as failed
+	markSummaryFailed := func() {
+		knowledge.SummaryStatus = types.SummaryStatusFailed
+		knowledge.UpdatedAt = time.Now()
+		if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+			logger.Warnf(ctx, "Failed to update summary status to failed: %v", err)
+		}
+	}
+
+	// Get text chunks for this knowledge
+	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, payload.KnowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get chunks: %v", err)
+		markSummaryFailed()
+		return nil
+	}
+
+	// Filter text chunks only
+	textChunks := make([]*types.Chunk, 0)
+	for _, chunk := range chunks {
+		if chunk.ChunkType == types.ChunkTypeText {
+			textChunks = append(textChunks, chunk)
+		}
+	}
+
+	if len(textChunks) == 0 {
+		logger.Infof(ctx, "No text chunks found for knowledge: %s", payload.KnowledgeID)
+		// Mark as completed since there's nothing to summarize
+		knowledge.SummaryStatus = types.SummaryStatusCompleted
+		knowledge.UpdatedAt = time.Now()
+		s.repo.UpdateKnowledge(ctx, knowledge)
+		return nil
+	}
+
+	// Sort chunks by ChunkIndex for proper ordering
+	sort.Slice(textChunks, func(i, j int) bool {
+		return textChunks[i].ChunkIndex < textChunks[j].ChunkIndex
+	})
+
+	// Initialize chat model for summary
+	chatModel, err := s.modelService.GetChatModel(ctx, kb.SummaryModelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get chat model: %v", err)
+		markSummaryFailed()
+		return fmt.Errorf("failed to get chat model: %w", err)
+	}
+
+	// Generate summary
+	// Tenant level summary configuration (metadata intro template), optional
+	var summaryConfig *types.DocumentSummaryConfig
+	if tenant, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID); err != nil {
+		logger.Warnf(ctx, "Failed to get tenant summary config, using defaults: %v", err)
+	} else {
+		summaryConfig = tenant.SummaryConfig
+	}
+
+	summary, err := s.getSummary(ctx, chatModel, knowledge, textChunks, summaryConfig)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to generate summary for knowledge %s: %v", payload.KnowledgeID, err)
+		switch summaryConfig.GetFallbackMode() {
+		case types.SummaryFallbackTruncate:
+			// Opt-in: use the beginning of the first chunk as the description
+			summary = truncateSummaryFallback(textChunks[0].Content)
+		case types.SummaryFallbackRetry:
+			retryCount, _ := asynq.GetRetryCount(ctx)
+			maxRetry, _ := asynq.GetMaxRetry(ctx)
+			if retryCount < maxRetry {
+				knowledge.SummaryStatus = types.SummaryStatusPending
+				knowledge.UpdatedAt = time.Now()
+				if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+					logger.Warnf(ctx, "Failed to update summary status to pending: %v", err)
+				}
+				return fmt.Errorf("failed to generate summary: %w", err)
+			}
+			markSummaryFailed()
+			return nil
+		default:
+			// Leave the description alone so the UI shows the summary as unavailable
+			markSummaryFailed()
+			return nil
+		}
+	}
+
+	// Update knowledge description
+	knowledge.Description = summary
+	knowledge.SummaryStatus = types.SummaryStatusCompleted
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		logger.Errorf(ctx, "Failed to update knowledge description: %v", err)
+		return fmt.Errorf("failed to update knowledge: %w", err)
+	}
+
+	// Create summary chunk and index it
+	if strings.TrimSpace(summary) != "" {
+		summaryChunk := newSummaryChunk(knowledge, summary, chunks, textChunks[0].ID,
+			knowledge.EnableStatus == "enabled")
+
+		// Save summary chunk
+		if err := s.chunkService.CreateChunks(ctx, []*types.Chunk{summaryChunk}); err != nil {
+			logger.Errorf(ctx, "Failed to create summary chunk: %v", err)
+			return fmt.Errorf("failed to create summary chunk: %w", err)
+		}
+
+		// Index summary chunk
+		tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to get tenant info: %v", err)
+			return fmt.Errorf("failed to get tenant info: %w", err)
 		}
-		// Group by EmbeddingModelID and Type
-		type groupKey struct {
-			EmbeddingModelID string
-			Type             string
+		ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+
+		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+		if err != nil {
+			logger.Errorf(ctx, "Failed to init retrieve engine: %v", err)
+			return fmt.Errorf("failed to init retrieve engine: %w", err)
 		}
-		group := map[groupKey][]string{}
-		for _, knowledge := range knowledgeList {
-			key := groupKey{EmbeddingModelID: knowledge.EmbeddingModelID, Type: knowledge.Type}
-			group[key] = append(group[key], knowledge.ID)
+
+		embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to get embedding model: %v", err)
+			return fmt.Errorf("failed to get embedding model: %w", err)
 		}
-		for key, knowledgeIDs := range group {
-			embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, key.EmbeddingModelID)
-			if err != nil {
-				logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge get embedding model failed")
-				return err
+
+		indexInfo := []*types.IndexInfo{newSummaryIndexInfo(summaryChunk)}
+
+		if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfo); err != nil {
+			logger.Errorf(ctx, "Failed to index summary chunk: %v", err)
+			return fmt.Errorf("failed to index summary chunk: %w", err)
+		}
+
+		logger.Infof(ctx, "Successfully created and indexed summary chunk for knowledge: %s", payload.KnowledgeID)
+	}
+
+	logger.Infof(ctx, "Successfully generated summary for knowledge: %s", payload.KnowledgeID)
+	return nil
+}
+
+// newSummaryChunk builds the summary chunk of a document, placed after its last chunk and
+// attached to its first text chunk. enabled follows the knowledge, so a knowledge kept disabled
+// doesn't become searchable through its summary.
+func newSummaryChunk(knowledge *types.Knowledge, summary string, chunks []*types.Chunk, parentChunkID string,
+	enabled bool,
+) *types.Chunk {
+	maxChunkIndex := 0
+	for _, chunk := range chunks {
+		if chunk.ChunkIndex > maxChunkIndex {
+			maxChunkIndex = chunk.ChunkIndex
+		}
+	}
+	now := time.Now()
+	return &types.Chunk{
+		ID:              uuid.New().String(),
+		TenantID:        knowledge.TenantID,
+		KnowledgeID:     knowledge.ID,
+		KnowledgeBaseID: knowledge.KnowledgeBaseID,
+		Content:         fmt.Sprintf("# 文档名称\n%s\n\n# 摘要\n%s", knowledge.FileName, summary),
+		ChunkIndex:      maxChunkIndex + 1,
+		IsEnabled:       enabled,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		ChunkType:       types.ChunkTypeSummary,
+		ParentChunkID:   parentChunkID,
+	}
+}
+
+// newSummaryIndexInfo builds the index entry of a summary chunk
+func newSummaryIndexInfo(chunk *types.Chunk) *types.IndexInfo {
+	return &types.IndexInfo{
+		Content:         chunk.Content,
+		SourceID:        chunk.ID,
+		SourceType:      types.ChunkSourceType,
+		ChunkID:         chunk.ID,
+		KnowledgeID:     chunk.KnowledgeID,
+		KnowledgeBaseID: chunk.KnowledgeBaseID,
+		ChunkType:       chunk.ChunkType,
+		IsEnabled:       chunk.IsEnabled,
+	}
+}
+
+const (
+	// keywordExtractionMaxRunes 提取关键词时送入模型的文档内容最大字符数
+	keywordExtractionMaxRunes = 6000
+	// maxExtractedKeywordRunes 单个关键词/实体的最大字符数，过长的视为无效结果
+	maxExtractedKeywordRunes = 32
+)
+
+// ProcessKeywordExtraction handles async document keyword/entity extraction task. The summary model
+// extracts the top keywords and entities of the document, which are stored in the knowledge metadata
+// and used by the keyword filter of the knowledge list.
+func (s *knowledgeService) ProcessKeywordExtraction(ctx context.Context, t *asynq.Task) error {
+	var payload types.KeywordExtractionPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal keyword extraction payload: %v", err)
+		return nil // Don't retry on unmarshal error
+	}
+
+	logger.Infof(ctx, "Processing keyword extraction for knowledge: %s", payload.KnowledgeID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil
+	}
+	// 任务入队后关闭了提取，直接跳过
+	if !kb.KeywordExtractionConfig.IsEnabled() {
+		return nil
+	}
+
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
+	if err != nil || knowledge == nil {
+		logger.Errorf(ctx, "Failed to get knowledge: %v", err)
+		return nil
+	}
+
+	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, payload.KnowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get chunks: %v", err)
+		return fmt.Errorf("failed to get chunks: %w", err)
+	}
+	content := keywordExtractionContent(chunks, keywordExtractionMaxRunes)
+	if strings.TrimSpace(content) == "" {
+		logger.Infof(ctx, "No text content found for keyword extraction: %s", payload.KnowledgeID)
+		return nil
+	}
+
+	chatModel, err := s.modelService.GetChatModel(ctx, kb.SummaryModelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get chat model: %v", err)
+		return fmt.Errorf("failed to get chat model: %w", err)
+	}
+
+	maxKeywords := kb.KeywordExtractionConfig.GetMaxKeywords()
+	prompt := strings.NewReplacer(
+		"{{max_keywords}}", strconv.Itoa(maxKeywords),
+		"{{doc_name}}", knowledge.FileName,
+		"{{content}}", content,
+	).Replace(defaultKeywordExtractionPrompt)
+
+	thinking := false
+	response, err := chatModel.Chat(ctx, []chat.Message{
+		{
+			Role:    "user",
+			Content: prompt,
+		},
+	}, &chat.ChatOptions{
+		Temperature: 0.1,
+		MaxTokens:   512,
+		Thinking:    &thinking,
+	})
+	if err != nil {
+		logger.Errorf(ctx, "Failed to extract keywords for knowledge %s: %v", payload.KnowledgeID, err)
+		return fmt.Errorf("failed to extract keywords: %w", err)
+	}
+
+	keywords, entities, err := parseExtractedKeywords(response.Content, maxKeywords)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to parse extracted keywords for knowledge %s: %v", payload.KnowledgeID, err)
+		return nil
+	}
+
+	// 重新读取知识，避免覆盖提取期间其他任务对 metadata 的修改，只更新 metadata 列
+	knowledge, err = s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
+	if err != nil || knowledge == nil {
+		logger.Errorf(ctx, "Failed to reload knowledge: %v", err)
+		return nil
+	}
+	if err := knowledge.SetExtractedKeywords(keywords, entities); err != nil {
+		logger.Warnf(ctx, "Failed to set extracted keywords for knowledge %s: %v", payload.KnowledgeID, err)
+		return nil
+	}
+	if err := s.repo.UpdateKnowledgeColumn(ctx, knowledge.ID, "metadata", knowledge.Metadata); err != nil {
+		logger.Errorf(ctx, "Failed to save extracted keywords: %v", err)
+		return fmt.Errorf("failed to save extracted keywords: %w", err)
+	}
+
+	logger.Infof(ctx, "Extracted %d keywords and %d entities for knowledge: %s",
+		len(keywords), len(entities), payload.KnowledgeID)
+	return nil
+}
+
+// keywordExtractionContent concatenates the text chunks in document order, up to maxRunes runes
+func keywordExtractionContent(chunks []*types.Chunk, maxRunes int) string {
+	textChunks := make([]*types.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.ChunkType == types.ChunkTypeText {
+			textChunks = append(textChunks, chunk)
+		}
+	}
+	sort.Slice(textChunks, func(i, j int) bool {
+		return textChunks[i].ChunkIndex < textChunks[j].ChunkIndex
+	})
+
+	var builder strings.Builder
+	remaining := maxRunes
+	for _, chunk := range textChunks {
+		runes := []rune(chunk.Content)
+		if len(runes) > remaining {
+			runes = runes[:remaining]
+		}
+		builder.WriteString(string(runes))
+		builder.WriteString("\n")
+		remaining -= len(runes)
+		if remaining <= 0 {
+			break
+		}
+	}
+	return builder.String()
+}
+
+// parseExtractedKeywords parses the JSON answer of the keyword extraction prompt, tolerating text
+// around the JSON object (e.g. markdown code fences). Values are trimmed and deduplicated case
+// insensitively; overlong values are dropped and each list is capped at limit.
+func parseExtractedKeywords(content string, limit int) (keywords []string, entities []string, err error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, nil, fmt.Errorf("no JSON object in response")
+	}
+	var result struct {
+		Keywords []string `json:"keywords"`
+		Entities []string `json:"entities"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON in response: %w", err)
+	}
+
+	normalize := func(values []string) []string {
+		seen := make(map[string]struct{}, len(values))
+		normalized := make([]string, 0, min(len(values), limit))
+		for _, value := range values {
+			value = strings.TrimSpace(value)
+			if value == "" || utf8.RuneCountInString(value) > maxExtractedKeywordRunes {
+				continue
 			}
-			if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, knowledgeIDs, embeddingModel.GetDimensions(), key.Type); err != nil {
-				logger.GetLogger(ctx).
-					WithField("error", err).
-					Errorf("DeleteKnowledge delete knowledge embedding failed")
-				return err
+			key := strings.ToLower(value)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			normalized = append(normalized, value)
+			if len(normalized) >= limit {
+				break
 			}
 		}
+		return normalized
+	}
+	return normalize(result.Keywords), normalize(result.Entities), nil
+}
+
+// Default prompt for document keyword/entity extraction
+const defaultKeywordExtractionPrompt = `你是一个专业的文档标注助手。请从下面的文档中提取最能代表文档主题的关键词，以及文档中出现的重要实体。
+
+文档名称：{{doc_name}}
+文档内容：
+{{content}}
+
+## 要求
+- 关键词（keywords）：概括文档主题的词语，最多 {{max_keywords}} 个，按重要程度排序
+- 实体（entities）：文档中出现的人名、组织、产品、地点等专有名称，最多 {{max_keywords}} 个，按重要程度排序
+- 每个关键词或实体应简短（一般不超过 10 个字），不要使用句子
+- 不要编造文档中没有的内容
+
+## 输出格式
+只输出如下 JSON，不要输出其他内容：
+{"keywords": ["关键词1", "关键词2"], "entities": ["实体1", "实体2"]}`
+
+// ProcessQuestionGeneration handles async question generation task
+func (s *knowledgeService) ProcessQuestionGeneration(ctx context.Context, t *asynq.Task) error {
+	ctx, span := tracing.ContextWithSpan(ctx, "knowledgeService.ProcessQuestionGeneration")
+	defer span.End()
+
+	var payload types.QuestionGenerationPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal question generation payload: %v", err)
+		return nil // Don't retry on unmarshal error
+	}
+
+	logger.Infof(ctx, "Processing question generation for knowledge: %s", payload.KnowledgeID)
+
+	// Set tenant context
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+
+	// Get knowledge base
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil
+	}
+
+	// Get knowledge
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge: %v", err)
 		return nil
-	})
+	}
 
-	// 3. Delete all chunks associated with this knowledge
-	wg.Go(func() error {
-		if err := s.chunkService.DeleteByKnowledgeList(ctx, ids); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete chunks failed")
-			return err
-		}
+	// Get text chunks for this knowledge
+	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, payload.KnowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get chunks: %v", err)
 		return nil
-	})
+	}
 
-	// 4. Delete the physical file if it exists
-	wg.Go(func() error {
-		storageAdjust := int64(0)
-		for _, knowledge := range knowledgeList {
-			if knowledge.FilePath != "" {
-				if err := s.fileSvc.DeleteFile(ctx, knowledge.FilePath); err != nil {
-					logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete file failed")
-				}
-			}
-			storageAdjust -= knowledge.StorageSize
-		}
-		tenantInfo.StorageUsed += storageAdjust
-		if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, storageAdjust); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge update tenant storage used failed")
+	// Filter text chunks only, chunks too short to be indexed get no questions either
+	textChunks := make([]*types.Chunk, 0)
+	for _, chunk := range chunks {
+		if chunk.ChunkType == types.ChunkTypeText && kb.ChunkingConfig.ShouldIndexChunk(chunk.ChunkType, chunk.Content) {
+			textChunks = append(textChunks, chunk)
 		}
-		return nil
-	})
+	}
 
-	// Delete the knowledge graph
-	wg.Go(func() error {
-		namespaces := []types.NameSpace{}
-		for _, knowledge := range knowledgeList {
-			namespaces = append(
-				namespaces,
-				types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID},
-			)
-		}
-		if err := s.graphEngine.DelGraph(ctx, namespaces); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge graph failed")
-			return err
-		}
+	if len(textChunks) == 0 {
+		logger.Infof(ctx, "No text chunks found for knowledge: %s", payload.KnowledgeID)
 		return nil
+	}
+
+	// Sort chunks by StartAt for context building
+	sort.Slice(textChunks, func(i, j int) bool {
+		return textChunks[i].StartAt < textChunks[j].StartAt
 	})
 
-	if err = wg.Wait(); err != nil {
-		return err
+	// Initialize chat model
+	chatModel, err := s.modelService.GetChatModel(ctx, kb.SummaryModelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get chat model: %v", err)
+		return fmt.Errorf("failed to get chat model: %w", err)
 	}
-	// 5. Delete the knowledge entry itself from the database
-	return s.repo.DeleteKnowledgeList(ctx, tenantInfo.ID, ids)
-}
 
-func (s *knowledgeService) cloneKnowledge(
-	ctx context.Context,
-	src *types.Knowledge,
-	targetKB *types.KnowledgeBase,
-) (err error) {
-	if src.ParseStatus != "completed" {
-		logger.GetLogger(ctx).WithField("knowledge_id", src.ID).Errorf("MoveKnowledge parse status is not completed")
-		return nil
+	// Initialize embedding model and retrieval engine
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get embedding model: %v", err)
+		return fmt.Errorf("failed to get embedding model: %w", err)
 	}
-	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-	dst := &types.Knowledge{
-		ID:               uuid.New().String(),
-		TenantID:         targetKB.TenantID,
-		KnowledgeBaseID:  targetKB.ID,
-		Type:             src.Type,
-		Title:            src.Title,
-		Description:      src.Description,
-		Source:           src.Source,
-		ParseStatus:      "processing",
-		EnableStatus:     "disabled",
-		EmbeddingModelID: targetKB.EmbeddingModelID,
-		FileName:         src.FileName,
-		FileType:         src.FileType,
-		FileSize:         src.FileSize,
-		FileHash:         src.FileHash,
-		FilePath:         src.FilePath,
-		StorageSize:      src.StorageSize,
-		Metadata:         src.Metadata,
+
+	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get tenant info: %v", err)
+		return fmt.Errorf("failed to get tenant info: %w", err)
 	}
-	defer func() {
-		if err != nil {
-			dst.ParseStatus = "failed"
-			dst.ErrorMessage = err.Error()
-			_ = s.repo.UpdateKnowledge(ctx, dst)
-			logger.GetLogger(ctx).WithField("error", err).Errorf("MoveKnowledge failed to move knowledge")
-		} else {
-			dst.ParseStatus = "completed"
-			dst.EnableStatus = "enabled"
-			_ = s.repo.UpdateKnowledge(ctx, dst)
-			logger.GetLogger(ctx).WithField("knowledge_id", dst.ID).Infof("MoveKnowledge move knowledge successfully")
-		}
-	}()
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
 
-	if err = s.repo.CreateKnowledge(ctx, dst); err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Errorf("MoveKnowledge create knowledge failed")
-		return
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err != nil {
+		logger.Errorf(ctx, "Failed to init retrieve engine: %v", err)
+		return fmt.Errorf("failed to init retrieve engine: %w", err)
 	}
-	tenantInfo.StorageUsed += dst.StorageSize
-	if err = s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, dst.StorageSize); err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Errorf("MoveKnowledge update tenant storage used failed")
-		return
+
+	questionCount := payload.QuestionCount
+	if questionCount <= 0 {
+		questionCount = 3
 	}
-	if err = s.CloneChunk(ctx, src, dst); err != nil {
-		logger.GetLogger(ctx).WithField("knowledge_id", dst.ID).
-			WithField("error", err).Errorf("MoveKnowledge move chunks failed")
-		return
+	if questionCount > 10 {
+		questionCount = 10
 	}
-	return
-}
 
-// processDocumentFromPassage handles asynchronous processing of text passages
-func (s *knowledgeService) processDocumentFromPassage(ctx context.Context,
-	kb *types.KnowledgeBase, knowledge *types.Knowledge, passage []string,
-) {
-	// Update status to processing
-	knowledge.ParseStatus = "processing"
-	knowledge.UpdatedAt = time.Now()
-	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
-		return
+	// Generate and index questions window by window, so a failure late in a large document
+	// keeps the work of earlier windows and memory stays bounded by the window size
+	windowSize := kb.QuestionGenerationConfig.GetIndexWindowSize()
+	concurrency := kb.QuestionGenerationConfig.GetConcurrency()
+	totalIndexed := 0
+	for start := 0; start < len(textChunks); start += windowSize {
+		end := min(start+windowSize, len(textChunks))
+		indexed, err := s.generateQuestionWindow(ctx, chatModel, embeddingModel, retrieveEngine,
+			knowledge, textChunks, start, end, questionCount, concurrency)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to index generated questions for chunks [%d, %d): %v", start, end, err)
+			return fmt.Errorf("failed to index questions: %w", err)
+		}
+		totalIndexed += indexed
+		logger.Infof(ctx, "Question generation progress for knowledge %s: %d/%d chunks, %d questions indexed",
+			payload.KnowledgeID, end, len(textChunks), totalIndexed)
 	}
 
-	// Convert passages to chunks
-	chunks := make([]*proto.Chunk, 0, len(passage))
-	start, end := 0, 0
-	for i, p := range passage {
-		if p == "" {
-			continue
-		}
-		end += len([]rune(p))
-		chunk := &proto.Chunk{
-			Content: p,
-			Seq:     int32(i),
-			Start:   int32(start),
-			End:     int32(end),
-		}
-		start = end
-		chunks = append(chunks, chunk)
+	if totalIndexed > 0 {
+		logger.Infof(ctx, "Successfully indexed %d generated questions for knowledge: %s", totalIndexed, payload.KnowledgeID)
 	}
-	// Process and store chunks
-	s.processChunks(ctx, kb, knowledge, chunks)
-}
 
-// ProcessChunksOptions contains options for processing chunks
-type ProcessChunksOptions struct {
-	EnableQuestionGeneration bool
-	QuestionCount            int
+	return nil
 }
 
-// processChunks processes chunks and creates embeddings for knowledge content
-func (s *knowledgeService) processChunks(ctx context.Context,
-	kb *types.KnowledgeBase, knowledge *types.Knowledge, chunks []*proto.Chunk,
-	opts ...ProcessChunksOptions,
-) {
-	// Get options
-	var options ProcessChunksOptions
-	if len(opts) > 0 {
-		options = opts[0]
+// generateQuestionWindow generates questions for textChunks[start:end] with up to concurrency
+// model calls in flight, indexes them and then persists them to chunk metadata.
+// Chunks that already carry generated questions (e.g. from an earlier attempt of the same task) are skipped.
+// Index entries are removed again if the chunk update fails, so metadata and index stay consistent.
+func (s *knowledgeService) generateQuestionWindow(ctx context.Context,
+	chatModel chat.Chat, embeddingModel embedding.Embedder, retrieveEngine *retriever.CompositeRetrieveEngine,
+	knowledge *types.Knowledge, textChunks []*types.Chunk, start, end, questionCount, concurrency int,
+) (int, error) {
+	generated := make([][]string, end-start)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i := start; i < end; i++ {
+		chunk := textChunks[i]
+		if meta, err := chunk.DocumentMetadata(); err == nil && meta != nil && len(meta.GeneratedQuestions) > 0 {
+			continue
+		}
+		// Build context from adjacent chunks
+		var prevContent, nextContent string
+		if i > 0 {
+			prevContent = textChunks[i-1].Content
+			// Limit context size
+			if len(prevContent) > 500 {
+				prevContent = prevContent[len(prevContent)-500:]
+			}
+		}
+		if i < len(textChunks)-1 {
+			nextContent = textChunks[i+1].Content
+			// Limit context size
+			if len(nextContent) > 500 {
+				nextContent = nextContent[:500]
+			}
+		}
+		g.Go(func() error {
+			questions, err := s.generateQuestionsWithContext(gctx, chatModel, chunk.Content, prevContent, nextContent, knowledge.Title, questionCount)
+			if err != nil {
+				logger.Warnf(gctx, "Failed to generate questions for chunk %s: %v", chunk.ID, err)
+				return nil
+			}
+			generated[i-start] = questions
+			return nil
+		})
 	}
+	_ = g.Wait()
 
-	ctx, span := tracing.ContextWithSpan(ctx, "knowledgeService.processChunks")
-	defer span.End()
-	span.SetAttributes(
-		attribute.Int("tenant_id", int(knowledge.TenantID)),
-		attribute.String("knowledge_base_id", knowledge.KnowledgeBaseID),
-		attribute.String("knowledge_id", knowledge.ID),
-		attribute.String("embedding_model_id", kb.EmbeddingModelID),
-		attribute.Int("chunk_count", len(chunks)),
-	)
+	var indexInfoList []*types.IndexInfo
+	var updatedChunks []*types.Chunk
+	for i, questions := range generated {
+		if len(questions) == 0 {
+			continue
+		}
+		chunk := textChunks[start+i]
 
-	// Check if knowledge is being deleted before processing
-	if s.isKnowledgeDeleting(ctx, knowledge.TenantID, knowledge.ID) {
-		logger.Infof(ctx, "Knowledge is being deleted, aborting chunk processing: %s", knowledge.ID)
-		span.AddEvent("aborted: knowledge is being deleted")
-		return
-	}
+		// Update chunk metadata with unique IDs for each question
+		generatedQuestions := make([]types.GeneratedQuestion, len(questions))
+		for j, question := range questions {
+			// The "q{n}" shape identifies question hits, see types.IndexWithScore.RetrievalWeightKey
+			questionID := fmt.Sprintf("q%d", time.Now().UnixNano()+int64(j))
+			generatedQuestions[j] = types.GeneratedQuestion{
+				ID:       questionID,
+				Question: question,
+			}
+		}
+		// Keep other metadata (e.g. extracted links) when replacing generated questions
+		meta, err := chunk.DocumentMetadata()
+		if err != nil || meta == nil {
+			meta = &types.DocumentChunkMetadata{}
+		}
+		meta.GeneratedQuestions = generatedQuestions
+		if err := chunk.SetDocumentMetadata(meta); err != nil {
+			logger.Warnf(ctx, "Failed to set document metadata for chunk %s: %v", chunk.ID, err)
+			continue
+		}
+		updatedChunks = append(updatedChunks, chunk)
 
-	// Get embedding model for vectorization
-	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
-	if err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks get embedding model failed")
-		span.RecordError(err)
-		return
+		// Create index entries for generated questions
+		for _, gq := range generatedQuestions {
+			sourceID := fmt.Sprintf("%s-%s", chunk.ID, gq.ID)
+			indexInfoList = append(indexInfoList, &types.IndexInfo{
+				Content:         gq.Question,
+				SourceID:        sourceID,
+				SourceType:      types.ChunkSourceType,
+				ChunkID:         chunk.ID,
+				KnowledgeID:     knowledge.ID,
+				KnowledgeBaseID: knowledge.KnowledgeBaseID,
+				ChunkType:       chunk.ChunkType,
+				IsEnabled:       chunk.IsEnabled,
+			})
+		}
+		logger.Debugf(ctx, "Generated %d questions for chunk %s", len(questions), chunk.ID)
 	}
-
-	// 幂等性处理：清理旧的chunks和索引数据，避免重复数据
-	logger.Infof(ctx, "Cleaning up existing chunks and index data for knowledge: %s", knowledge.ID)
-
-	// 删除旧的chunks
-	if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
-		logger.Warnf(ctx, "Failed to delete existing chunks (may not exist): %v", err)
-		// 不返回错误，继续处理（可能没有旧数据）
+	if len(indexInfoList) == 0 {
+		return 0, nil
 	}
 
-	// 删除旧的索引数据
-	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
-	if err == nil {
-		if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), knowledge.Type); err != nil {
-			logger.Warnf(ctx, "Failed to delete existing index data (may not exist): %v", err)
-			// 不返回错误，继续处理（可能没有旧数据）
-		} else {
-			logger.Infof(ctx, "Successfully deleted existing index data for knowledge: %s", knowledge.ID)
+	if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList); err != nil {
+		return 0, err
+	}
+	if err := s.chunkService.UpdateChunks(ctx, updatedChunks); err != nil {
+		sourceIDs := make([]string, 0, len(indexInfoList))
+		for _, info := range indexInfoList {
+			sourceIDs = append(sourceIDs, info.SourceID)
 		}
+		if delErr := retrieveEngine.DeleteBySourceIDList(ctx, sourceIDs, embeddingModel.GetDimensions(), knowledge.Type); delErr != nil {
+			logger.Warnf(ctx, "Failed to roll back question index entries: %v", delErr)
+		}
+		return 0, fmt.Errorf("failed to update chunks: %w", err)
 	}
+	return len(indexInfoList), nil
+}
 
-	// 删除知识图谱数据（如果存在）
-	namespace := types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID}
-	if err := s.graphEngine.DelGraph(ctx, []types.NameSpace{namespace}); err != nil {
-		logger.Warnf(ctx, "Failed to delete existing graph data (may not exist): %v", err)
-		// 不返回错误，继续处理
+// maxIsolatedIndexFailures 隔离索引失败分块时允许跳过的分块数上限，超过时视为系统性错误（如嵌入服务不可用），整篇文档失败
+const maxIsolatedIndexFailures = 20
+
+// batchIndexIsolatingFailures indexes indexInfoList through BatchIndex, but when indexing fails it clears the
+// knowledge's partial index and bisects the entries down to single ones, so that only the chunks whose
+// entries keep failing are left out. Returns the failing chunk IDs with their errors; an error is returned
+// when every chunk fails or more than maxIsolatedIndexFailures chunks fail, as that is not chunk specific.
+func (s *knowledgeService) batchIndexIsolatingFailures(ctx context.Context,
+	retrieveEngine *retriever.CompositeRetrieveEngine, embeddingModel embedding.Embedder, kbType string,
+	indexInfoList []*types.IndexInfo,
+) (map[string]error, error) {
+	batchErr := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList)
+	if batchErr == nil || len(indexInfoList) == 0 {
+		return nil, batchErr
+	}
+	logger.Warnf(ctx, "Batch index failed, isolating failing chunks: %v", batchErr)
+
+	// 清理失败批次可能已写入的部分索引，避免重试时重复写入
+	knowledgeID := indexInfoList[0].KnowledgeID
+	if err := retrieveEngine.DeleteByKnowledgeIDList(
+		ctx, []string{knowledgeID}, embeddingModel.GetDimensions(), kbType,
+	); err != nil {
+		return nil, fmt.Errorf("failed to clear partial index before isolating failures: %w (batch error: %v)", err, batchErr)
 	}
 
-	logger.Infof(ctx, "Cleanup completed, starting to process new chunks")
+	failed, err := isolateIndexFailures(indexInfoList, maxIsolatedIndexFailures,
+		func(batch []*types.IndexInfo) error {
+			return retrieveEngine.BatchIndex(ctx, embeddingModel, batch)
+		})
+	if err != nil {
+		return nil, err
+	}
 
-	// ========== DocReader 解析结果日志 ==========
-	logger.Infof(ctx, "[DocReader] ========== 解析结果概览 ==========")
-	logger.Infof(ctx, "[DocReader] 知识ID: %s, 知识库ID: %s", knowledge.ID, knowledge.KnowledgeBaseID)
-	logger.Infof(ctx, "[DocReader] 总Chunk数量: %d", len(chunks))
+	// 分块的部分索引条目可能已写入（如生成的问题），失败分块整体不参与检索
+	failedIDs := make([]string, 0, len(failed))
+	for chunkID := range failed {
+		failedIDs = append(failedIDs, chunkID)
+	}
+	if err := retrieveEngine.DeleteByChunkIDList(ctx, failedIDs, embeddingModel.GetDimensions(), kbType); err != nil {
+		logger.Warnf(ctx, "Failed to delete partial index of failed chunks: %v", err)
+	}
+	logger.Warnf(ctx, "Indexed knowledge %s skipping %d failing chunk(s)", knowledgeID, len(failed))
+	return failed, nil
+}
 
-	// 统计图片信息
-	totalImages := 0
-	chunksWithImages := 0
-	for _, chunkData := range chunks {
-		if len(chunkData.Images) > 0 {
-			chunksWithImages++
-			totalImages += len(chunkData.Images)
+// isolateIndexFailures indexes entries with index, bisecting every failing batch until single entries
+// remain, and returns the failing chunk IDs with their errors. It gives up once more than maxFailures
+// chunks have failed, or when every chunk failed.
+func isolateIndexFailures(entries []*types.IndexInfo, maxFailures int,
+	index func([]*types.IndexInfo) error,
+) (map[string]error, error) {
+	failed := make(map[string]error)
+	var bisect func(batch []*types.IndexInfo) error
+	bisect = func(batch []*types.IndexInfo) error {
+		err := index(batch)
+		if err == nil {
+			return nil
+		}
+		if len(batch) == 1 {
+			if _, ok := failed[batch[0].ChunkID]; !ok {
+				failed[batch[0].ChunkID] = err
+			}
+			if len(failed) > maxFailures {
+				return fmt.Errorf("more than %d chunks failed to index, last error: %w", maxFailures, err)
+			}
+			return nil
+		}
+		mid := len(batch) / 2
+		if err := bisect(batch[:mid]); err != nil {
+			return err
 		}
+		return bisect(batch[mid:])
+	}
+	if err := bisect(entries); err != nil {
+		return nil, err
 	}
-	logger.Infof(ctx, "[DocReader] 包含图片的Chunk数: %d, 总图片数: %d", chunksWithImages, totalImages)
 
-	// 打印每个Chunk的详细信息
-	for idx, chunkData := range chunks {
-		contentPreview := chunkData.Content
-		if len(contentPreview) > 200 {
-			contentPreview = contentPreview[:200] + "..."
+	chunkIDs := make(map[string]struct{})
+	for _, entry := range entries {
+		chunkIDs[entry.ChunkID] = struct{}{}
+	}
+	if len(failed) > 0 && len(failed) == len(chunkIDs) {
+		for _, err := range failed {
+			return nil, fmt.Errorf("all chunks failed to index: %w", err)
 		}
-		logger.Infof(ctx, "[DocReader] Chunk #%d (seq=%d): 内容长度=%d, 图片数=%d, 范围=[%d-%d]",
-			idx, chunkData.Seq, len(chunkData.Content), len(chunkData.Images), chunkData.Start, chunkData.End)
-		logger.Debugf(ctx, "[DocReader] Chunk #%d 内容预览: %s", idx, contentPreview)
+	}
+	return failed, nil
+}
 
-		// 打印图片详细信息
-		for imgIdx, img := range chunkData.Images {
-			logger.Infof(ctx, "[DocReader]   图片 #%d: URL=%s", imgIdx, img.Url)
-			logger.Infof(ctx, "[DocReader]   图片 #%d: OriginalURL=%s", imgIdx, img.OriginalUrl)
-			if img.Caption != "" {
-				captionPreview := img.Caption
-				if len(captionPreview) > 100 {
-					captionPreview = captionPreview[:100] + "..."
-				}
-				logger.Infof(ctx, "[DocReader]   图片 #%d: Caption=%s", imgIdx, captionPreview)
-			}
-			if img.OcrText != "" {
-				ocrPreview := img.OcrText
-				if len(ocrPreview) > 100 {
-					ocrPreview = ocrPreview[:100] + "..."
-				}
-				logger.Infof(ctx, "[DocReader]   图片 #%d: OCRText=%s", imgIdx, ocrPreview)
-			}
-			logger.Infof(ctx, "[DocReader]   图片 #%d: 位置=[%d-%d]", imgIdx, img.Start, img.End)
+// markIndexFailedChunks marks the chunks that could not be indexed as stored only, recording the reason
+// in their metadata, and returns the parse warning listing them
+func (s *knowledgeService) markIndexFailedChunks(ctx context.Context,
+	chunks []*types.Chunk, failed map[string]error,
+) string {
+	updated := make([]*types.Chunk, 0, len(failed))
+	positions := make([]string, 0, len(failed))
+	for _, chunk := range chunks {
+		indexErr, ok := failed[chunk.ID]
+		if !ok {
+			continue
+		}
+		meta, err := chunk.DocumentMetadata()
+		if err != nil || meta == nil {
+			meta = &types.DocumentChunkMetadata{}
 		}
+		meta.IndexError = indexErr.Error()
+		if err := chunk.SetDocumentMetadata(meta); err != nil {
+			logger.Warnf(ctx, "Failed to record index error of chunk %s: %v", chunk.ID, err)
+		}
+		chunk.Status = int(types.ChunkStatusStored)
+		updated = append(updated, chunk)
+		positions = append(positions, fmt.Sprintf("#%d", chunk.ChunkIndex+1))
 	}
-	logger.Infof(ctx, "[DocReader] ========== 解析结果概览结束 ==========")
+	if err := s.chunkService.UpdateChunks(ctx, updated); err != nil {
+		logger.Warnf(ctx, "Failed to mark %d chunks as index failed: %v", len(updated), err)
+	}
+	if len(positions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d 个分块写入检索索引失败，已跳过（仅保存，不参与检索）：%s",
+		len(positions), strings.Join(positions, ", "))
+}
 
-	// Create chunk objects from proto chunks
-	maxSeq := 0
+// generateQuestionsWithContext generates questions for a chunk with surrounding context
+func (s *knowledgeService) generateQuestionsWithContext(ctx context.Context,
+	chatModel chat.Chat, content, prevContent, nextContent, docName string, questionCount int,
+) ([]string, error) {
+	if content == "" || questionCount <= 0 {
+		return nil, nil
+	}
 
-	// 统计图片相关的子Chunk数量，用于扩展insertChunks的容量
-	imageChunkCount := 0
-	for _, chunkData := range chunks {
-		if len(chunkData.Images) > 0 {
-			// 为每个图片的OCR和Caption分别创建一个Chunk
-			imageChunkCount += len(chunkData.Images) * 2
+	// Build prompt with context
+	prompt := s.config.Conversation.GenerateQuestionsPrompt
+	if prompt == "" {
+		prompt = defaultQuestionGenerationPrompt
+	}
+
+	// Build context section
+	var contextSection string
+	if prevContent != "" || nextContent != "" {
+		contextSection = "## 上下文信息（仅供参考，帮助理解主要内容）\n"
+		if prevContent != "" {
+			contextSection += fmt.Sprintf("【前文】%s\n", prevContent)
 		}
-		if int(chunkData.Seq) > maxSeq {
-			maxSeq = int(chunkData.Seq)
+		if nextContent != "" {
+			contextSection += fmt.Sprintf("【后文】%s\n", nextContent)
 		}
+		contextSection += "\n"
 	}
 
-	// 重新分配容量，考虑图片相关的Chunk
-	insertChunks := make([]*types.Chunk, 0, len(chunks)+imageChunkCount)
+	// Replace placeholders
+	prompt = strings.ReplaceAll(prompt, "{{question_count}}", fmt.Sprintf("%d", questionCount))
+	prompt = strings.ReplaceAll(prompt, "{{content}}", content)
+	prompt = strings.ReplaceAll(prompt, "{{context}}", contextSection)
+	prompt = strings.ReplaceAll(prompt, "{{doc_name}}", docName)
 
-	for _, chunkData := range chunks {
-		if strings.TrimSpace(chunkData.Content) == "" {
+	thinking := false
+	response, err := chatModel.Chat(ctx, []chat.Message{
+		{
+			Role:    "user",
+			Content: prompt,
+		},
+	}, &chat.ChatOptions{
+		Temperature: 0.7,
+		MaxTokens:   512,
+		Thinking:    &thinking,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate questions: %w", err)
+	}
+
+	// Parse response
+	lines := strings.Split(response.Content, "\n")
+	questions := make([]string, 0, questionCount)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
 			continue
 		}
-
-		// 创建主文本Chunk
-		textChunk := &types.Chunk{
-			ID:              uuid.New().String(),
-			TenantID:        knowledge.TenantID,
-			KnowledgeID:     knowledge.ID,
-			KnowledgeBaseID: knowledge.KnowledgeBaseID,
-			Content:         chunkData.Content,
-			ChunkIndex:      int(chunkData.Seq),
-			IsEnabled:       true,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
-			StartAt:         int(chunkData.Start),
-			EndAt:           int(chunkData.End),
-			ChunkType:       types.ChunkTypeText,
+		line = strings.TrimLeft(line, "0123456789.-*) ")
+		line = strings.TrimSpace(line)
+		if line != "" && len(line) > 5 {
+			questions = append(questions, line)
+			if len(questions) >= questionCount {
+				break
+			}
 		}
-		var chunkImages []types.ImageInfo
-		insertChunks = append(insertChunks, textChunk)
-
-		// 处理图片信息
-		if len(chunkData.Images) > 0 {
-			logger.GetLogger(ctx).Infof("Processing %d images in chunk #%d", len(chunkData.Images), chunkData.Seq)
+	}
 
-			for i, img := range chunkData.Images {
-				// 保存图片信息到文本Chunk
-				imageInfo := types.ImageInfo{
-					URL:         img.Url,
-					OriginalURL: img.OriginalUrl,
-					StartPos:    int(img.Start),
-					EndPos:      int(img.End),
-					OCRText:     img.OcrText,
-					Caption:     img.Caption,
-				}
-				chunkImages = append(chunkImages, imageInfo)
+	return questions, nil
+}
 
-				// 将ImageInfo序列化为JSON
-				imageInfoJSON, err := json.Marshal([]types.ImageInfo{imageInfo})
-				if err != nil {
-					logger.GetLogger(ctx).WithField("error", err).Errorf("Failed to marshal image info to JSON")
-					continue
-				}
+// Default prompt for question generation with context support
+const defaultQuestionGenerationPrompt = `你是一个专业的问题生成助手。你的任务是根据给定的【主要内容】生成用户可能会问的相关问题。
 
-				// 如果有OCR文本，创建OCR Chunk
-				if img.OcrText != "" {
-					ocrChunk := &types.Chunk{
-						ID:              uuid.New().String(),
-						TenantID:        knowledge.TenantID,
-						KnowledgeID:     knowledge.ID,
-						KnowledgeBaseID: knowledge.KnowledgeBaseID,
-						Content:         img.OcrText,
-						ChunkIndex:      maxSeq + i*100 + 1, // 使用不冲突的索引方式
-						IsEnabled:       true,
-						CreatedAt:       time.Now(),
-						UpdatedAt:       time.Now(),
-						StartAt:         int(img.Start),
-						EndAt:           int(img.End),
-						ChunkType:       types.ChunkTypeImageOCR,
-						ParentChunkID:   textChunk.ID,
-						ImageInfo:       string(imageInfoJSON),
-					}
-					insertChunks = append(insertChunks, ocrChunk)
-					logger.GetLogger(ctx).Infof("Created OCR chunk for image %d in chunk #%d", i, chunkData.Seq)
-				}
+{{context}}
+## 主要内容（请基于此内容生成问题）
+文档名称：{{doc_name}}
+文档内容：
+{{content}}
 
-				// 如果有图片描述，创建Caption Chunk
-				if img.Caption != "" {
-					captionChunk := &types.Chunk{
-						ID:              uuid.New().String(),
-						TenantID:        knowledge.TenantID,
-						KnowledgeID:     knowledge.ID,
-						KnowledgeBaseID: knowledge.KnowledgeBaseID,
-						Content:         img.Caption,
-						ChunkIndex:      maxSeq + i*100 + 2, // 使用不冲突的索引方式
-						IsEnabled:       true,
-						CreatedAt:       time.Now(),
-						UpdatedAt:       time.Now(),
-						StartAt:         int(img.Start),
-						EndAt:           int(img.End),
-						ChunkType:       types.ChunkTypeImageCaption,
-						ParentChunkID:   textChunk.ID,
-						ImageInfo:       string(imageInfoJSON),
-					}
-					insertChunks = append(insertChunks, captionChunk)
-					logger.GetLogger(ctx).Infof("Created caption chunk for image %d in chunk #%d", i, chunkData.Seq)
-				}
-			}
+## 核心要求
+- 生成的问题必须与【主要内容】直接相关
+- 问题中禁止使用任何代词或指代词（如"它"、"这个"、"该文档"、"本文"、"文中"、"其"等），必须用具体名称替代
+- 问题必须是完整独立的，脱离上下文也能被理解
+- 问题应该是用户在实际场景中可能会提出的自然问题
+- 问题应该多样化，覆盖内容的不同方面
+- 每个问题应该简洁明了，长度控制在30字以内
+- 生成的问题数量为 {{question_count}} 个
 
-			imageInfoJSON, err := json.Marshal(chunkImages)
-			if err != nil {
-				logger.GetLogger(ctx).WithField("error", err).Errorf("Failed to marshal image info to JSON")
-				continue
-			}
-			textChunk.ImageInfo = string(imageInfoJSON)
-		}
+## 问题类型建议
+- 定义类：什么是...？...是什么？
+- 原因类：为什么...？...的原因是什么？
+- 方法类：如何...？怎样...？
+- 比较类：...和...有什么区别？
+- 应用类：...可以用于什么场景？
+
+## 输出格式
+直接输出问题列表，每行一个问题，不要有序号或其他前缀。`
+
+// GetKnowledgeFile retrieves the physical file associated with a knowledge entry
+func (s *knowledgeService) GetKnowledgeFile(ctx context.Context, id string) (io.ReadCloser, string, error) {
+	// Get knowledge record
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, "", err
 	}
 
-	// Sort chunks by index for proper ordering
-	sort.Slice(insertChunks, func(i, j int) bool {
-		return insertChunks[i].ChunkIndex < insertChunks[j].ChunkIndex
-	})
+	// Get the file from storage
+	file, err := s.fileSvc.GetFile(ctx, knowledge.FilePath)
+	if err != nil {
+		return nil, "", err
+	}
 
-	// 仅为文本类型的Chunk设置前后关系
-	textChunks := make([]*types.Chunk, 0, len(chunks))
-	for _, chunk := range insertChunks {
-		if chunk.ChunkType == types.ChunkTypeText {
-			textChunks = append(textChunks, chunk)
-		}
+	return file, knowledge.FileName, nil
+}
+
+func (s *knowledgeService) UpdateKnowledge(ctx context.Context, knowledge *types.Knowledge) error {
+	lock, err := s.lockKnowledge(ctx, knowledge.ID)
+	if err != nil {
+		return err
 	}
+	defer lock.Unlock()
 
-	// 设置文本Chunk之间的前后关系
-	for i, chunk := range textChunks {
-		if i > 0 {
-			textChunks[i-1].NextChunkID = chunk.ID
-		}
-		if i < len(textChunks)-1 {
-			textChunks[i+1].PreChunkID = chunk.ID
+	record, err := s.repo.GetKnowledgeByID(ctx, ctx.Value(types.TenantIDContextKey).(uint64), knowledge.ID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge record: %v", err)
+		return err
+	}
+	// if need other fields update, please add here
+	if knowledge.Title != "" {
+		record.Title = knowledge.Title
+	}
+	if knowledge.FileName != "" {
+		safeFilename, isValid := secutils.ValidateInput(knowledge.FileName)
+		if !isValid {
+			return werrors.NewValidationError("文件名包含非法字符")
 		}
+		record.FileName = safeFilename
 	}
+	logger.Infof(ctx, "Safe filename: %s", knowledge.FileName)
 
-	// Create index information for each chunk (without generated questions for now)
-	indexInfoList := make([]*types.IndexInfo, 0, len(insertChunks))
-	for _, chunk := range insertChunks {
-		// Add original chunk content to index
-		indexInfoList = append(indexInfoList, &types.IndexInfo{
-			Content:         chunk.Content,
-			SourceID:        chunk.ID,
-			SourceType:      types.ChunkSourceType,
-			ChunkID:         chunk.ID,
-			KnowledgeID:     knowledge.ID,
-			KnowledgeBaseID: knowledge.KnowledgeBaseID,
-		})
+	// Update knowledge record in the repository
+	if err := s.repo.UpdateKnowledge(ctx, record); err != nil {
+		logger.Errorf(ctx, "Failed to update knowledge: %v", err)
+		return err
 	}
+	logger.Infof(ctx, "Knowledge updated successfully, ID: %s", knowledge.ID)
+	return nil
+}
 
-	// Initialize retrieval engine
+// UpdateManualKnowledge updates manual Markdown knowledge content.
+func (s *knowledgeService) UpdateManualKnowledge(ctx context.Context,
+	knowledgeID string, payload *types.ManualKnowledgePayload,
+) (*types.Knowledge, error) {
+	logger.Info(ctx, "Start updating manual knowledge entry")
+	if payload == nil {
+		return nil, werrors.NewBadRequestError("请求内容不能为空")
+	}
 
-	// Calculate storage size required for embeddings
-	span.AddEvent("estimate storage size")
-	totalStorageSize := retrieveEngine.EstimateStorageSize(ctx, embeddingModel, indexInfoList)
-	if tenantInfo.StorageQuota > 0 {
-		// Re-fetch tenant storage information
-		tenantInfo, err = s.tenantRepo.GetTenantByID(ctx, tenantInfo.ID)
-		if err != nil {
-			knowledge.ParseStatus = types.ParseStatusFailed
-			knowledge.ErrorMessage = err.Error()
-			knowledge.UpdatedAt = time.Now()
-			s.repo.UpdateKnowledge(ctx, knowledge)
-			span.RecordError(err)
-			return
-		}
-		// Check if there's enough storage quota available
-		if tenantInfo.StorageUsed+totalStorageSize > tenantInfo.StorageQuota {
-			knowledge.ParseStatus = types.ParseStatusFailed
-			knowledge.ErrorMessage = "存储空间不足"
-			knowledge.UpdatedAt = time.Now()
-			s.repo.UpdateKnowledge(ctx, knowledge)
-			span.RecordError(errors.New("storage quota exceeded"))
-			return
-		}
+	lock, err := s.lockKnowledge(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
 	}
+	defer lock.Unlock()
 
-	// Check again if knowledge is being deleted before writing to database
-	if s.isKnowledgeDeleting(ctx, knowledge.TenantID, knowledge.ID) {
-		logger.Infof(ctx, "Knowledge is being deleted, aborting before saving chunks: %s", knowledge.ID)
-		span.AddEvent("aborted: knowledge is being deleted before saving")
-		return
+	cleanContent := secutils.CleanMarkdown(normalizeManualContent(payload.Content))
+	if strings.TrimSpace(cleanContent) == "" {
+		return nil, werrors.NewValidationError("内容不能为空")
+	}
+	if err := s.validateManualContentLength(cleanContent); err != nil {
+		return nil, err
 	}
 
-	// Save chunks to database
-	span.AddEvent("create chunks")
-	if err := s.chunkService.CreateChunks(ctx, insertChunks); err != nil {
-		knowledge.ParseStatus = types.ParseStatusFailed
-		knowledge.ErrorMessage = err.Error()
-		knowledge.UpdatedAt = time.Now()
-		s.repo.UpdateKnowledge(ctx, knowledge)
-		span.RecordError(err)
-		return
+	safeTitle, ok := secutils.ValidateInput(payload.Title)
+	if !ok {
+		return nil, werrors.NewValidationError("标题包含非法字符或超出长度限制")
 	}
 
-	// Check again before batch indexing (this is a heavy operation)
-	if s.isKnowledgeDeleting(ctx, knowledge.TenantID, knowledge.ID) {
-		logger.Infof(ctx, "Knowledge is being deleted, cleaning up and aborting before indexing: %s", knowledge.ID)
-		// Clean up the chunks we just created
-		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
-			logger.Warnf(ctx, "Failed to cleanup chunks after deletion detected: %v", err)
-		}
-		span.AddEvent("aborted: knowledge is being deleted before indexing")
-		return
+	status := strings.ToLower(strings.TrimSpace(payload.Status))
+	if status == "" {
+		status = types.ManualKnowledgeStatusDraft
+	}
+	if status != types.ManualKnowledgeStatusDraft && status != types.ManualKnowledgeStatusPublish {
+		return nil, werrors.NewValidationError("状态仅支持 draft 或 publish")
 	}
 
-	span.AddEvent("batch index")
-	err = retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList)
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	existing, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
 	if err != nil {
-		knowledge.ParseStatus = types.ParseStatusFailed
-		knowledge.ErrorMessage = err.Error()
-		knowledge.UpdatedAt = time.Now()
-		s.repo.UpdateKnowledge(ctx, knowledge)
+		logger.Errorf(ctx, "Failed to load knowledge: %v", err)
+		return nil, err
+	}
+	if !existing.IsManual() {
+		return nil, werrors.NewBadRequestError("仅支持手工知识的在线编辑")
+	}
 
-		// delete failed chunks
-		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
-			logger.Errorf(ctx, "Delete chunks failed: %v", err)
-		}
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, existing.KnowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base for manual update: %v", err)
+		return nil, err
+	}
 
-		// delete index
-		if err := retrieveEngine.DeleteByKnowledgeIDList(
-			ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), kb.Type,
-		); err != nil {
-			logger.Errorf(ctx, "Delete index failed: %v", err)
+	var version int
+	enableAfterPublish := payload.EnableAfterPublish
+	if meta, err := existing.ManualMetadata(); err == nil && meta != nil {
+		version = meta.Version + 1
+		// 未显式指定时沿用上一次的发布启用策略
+		if enableAfterPublish == nil {
+			enableAfterPublish = meta.EnableAfterPublish
 		}
-		span.RecordError(err)
-		return
+	} else {
+		version = 1
 	}
-	logger.GetLogger(ctx).Infof("processChunks batch index successfully, with %d index", len(indexInfoList))
 
-	logger.Infof(ctx, "processChunks create relationship rag task")
-	if kb.ExtractConfig != nil && kb.ExtractConfig.Enabled {
-		for _, chunk := range textChunks {
-			err := NewChunkExtractTask(ctx, s.task, chunk.TenantID, chunk.ID, kb.SummaryModelID)
-			if err != nil {
-				logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks create chunk extract task failed")
-				span.RecordError(err)
-			}
-		}
+	meta := types.NewManualKnowledgeMetadata(cleanContent, status, version)
+	meta.EnableAfterPublish = enableAfterPublish
+	if err := existing.SetManualMetadata(meta); err != nil {
+		logger.Errorf(ctx, "Failed to set manual metadata during update: %v", err)
+		return nil, err
 	}
 
-	// Final check before marking as completed - if deleted during processing, don't update status
-	if s.isKnowledgeDeleting(ctx, knowledge.TenantID, knowledge.ID) {
-		logger.Infof(ctx, "Knowledge was deleted during processing, skipping completion update: %s", knowledge.ID)
-		// Clean up the data we just created since the knowledge is being deleted
-		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
-			logger.Warnf(ctx, "Failed to cleanup chunks after deletion detected: %v", err)
-		}
-		if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), kb.Type); err != nil {
-			logger.Warnf(ctx, "Failed to cleanup index after deletion detected: %v", err)
+	if safeTitle != "" {
+		existing.Title = safeTitle
+	} else if existing.Title == "" {
+		existing.Title = fmt.Sprintf("手工知识-%s", time.Now().Format("20060102-150405"))
+	}
+	existing.FileName = ensureManualFileName(existing.Title)
+	existing.FileType = types.KnowledgeTypeManual
+	existing.Type = types.KnowledgeTypeManual
+	existing.Source = types.KnowledgeTypeManual
+	existing.EnableStatus = "disabled"
+	existing.UpdatedAt = time.Now()
+
+	if err := s.cleanupKnowledgeResources(ctx, existing); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": knowledgeID,
+		})
+		return nil, err
+	}
+
+	existing.EmbeddingModelID = kb.EmbeddingModelID
+
+	if status == types.ManualKnowledgeStatusDraft {
+		existing.ParseStatus = types.ManualKnowledgeStatusDraft
+		existing.Description = ""
+		existing.ProcessedAt = nil
+
+		if err := s.repo.UpdateKnowledge(ctx, existing); err != nil {
+			logger.Errorf(ctx, "Failed to persist manual draft: %v", err)
+			return nil, err
 		}
-		span.AddEvent("aborted: knowledge was deleted during processing")
-		return
+		return existing, nil
+	}
+
+	existing.ParseStatus = "pending"
+	existing.Description = ""
+	existing.ProcessedAt = nil
+
+	if err := s.repo.UpdateKnowledge(ctx, existing); err != nil {
+		logger.Errorf(ctx, "Failed to persist manual knowledge before indexing: %v", err)
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Manual knowledge updated, scheduling indexing, ID: %s", existing.ID)
+	s.triggerManualProcessing(ctx, kb, existing, cleanContent, false, ProcessChunksOptions{
+		LockToken: lock.HandOff(),
+	})
+	return existing, nil
+}
+
+// EnableKnowledge enables a knowledge item that was indexed but kept disabled (e.g. manual knowledge
+// published with enable_after_publish=false), switching its chunks on in both the database and the index.
+func (s *knowledgeService) EnableKnowledge(ctx context.Context, knowledgeID string) (*types.Knowledge, error) {
+	lock, err := s.lockKnowledge(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to load knowledge for enable: %v", err)
+		return nil, err
+	}
+	if knowledge.ParseStatus != types.ParseStatusCompleted {
+		return nil, werrors.NewBadRequestError("仅支持启用解析完成的知识")
+	}
+	if knowledge.EnableStatus == "enabled" {
+		return knowledge, nil
+	}
+
+	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, knowledge.ID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list chunks for enable: %v", err)
+		return nil, err
+	}
+	tenantInfo, err := s.effectiveTenantInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setChunksEnabled(ctx, retrieveEngine, chunks, true); err != nil {
+		logger.Errorf(ctx, "Failed to enable chunks of knowledge %s: %v", knowledge.ID, err)
+		return nil, err
 	}
 
-	// Update knowledge status to completed
-	knowledge.ParseStatus = types.ParseStatusCompleted
 	knowledge.EnableStatus = "enabled"
-	knowledge.StorageSize = totalStorageSize
-	now := time.Now()
-	knowledge.ProcessedAt = &now
-	knowledge.UpdatedAt = now
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		logger.Errorf(ctx, "Failed to update knowledge enable status: %v", err)
+		return nil, err
+	}
+	logger.Infof(ctx, "Knowledge %s enabled, %d chunks", knowledge.ID, len(chunks))
+	return knowledge, nil
+}
 
-	// Set summary status based on whether summary generation will be triggered
-	if len(textChunks) > 0 {
-		knowledge.SummaryStatus = types.SummaryStatusPending
+// CancelKnowledgeProcessing aborts the parsing of a pending or processing knowledge item.
+// The knowledge is marked as cancelled, which the processing checkpoints detect and stop at,
+// and the chunks, index and graph data created so far are removed. The record and its file are
+// kept so the knowledge can be re-parsed later.
+func (s *knowledgeService) CancelKnowledgeProcessing(ctx context.Context, knowledgeID string) (*types.Knowledge, error) {
+	lock, lockErr := s.lockKnowledge(ctx, knowledgeID)
+	if lockErr != nil {
+		if !isKnowledgeLockConflict(lockErr) {
+			return nil, lockErr
+		}
 	} else {
-		knowledge.SummaryStatus = types.SummaryStatusNone
+		defer lock.Unlock()
 	}
 
-	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks update knowledge failed")
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to load knowledge for cancel: %v", err)
+		return nil, err
 	}
-
-	// Enqueue question generation task if enabled (async, non-blocking)
-	if options.EnableQuestionGeneration && len(textChunks) > 0 {
-		questionCount := options.QuestionCount
-		if questionCount <= 0 {
-			questionCount = 3
-		}
-		if questionCount > 10 {
-			questionCount = 10
-		}
-		s.enqueueQuestionGenerationTask(ctx, knowledge.KnowledgeBaseID, knowledge.ID, questionCount)
+	if knowledge.Type == types.KnowledgeTypeFAQ {
+		return nil, werrors.NewBadRequestError("FAQ 知识不支持取消解析")
+	}
+	if knowledge.ParseStatus != types.ParseStatusPending && knowledge.ParseStatus != types.ParseStatusProcessing {
+		return nil, werrors.NewBadRequestError("仅支持取消等待解析或解析中的知识")
+	}
+	// Only pending or processing knowledge gets here, see canOverrideProcessingLock
+	if lockErr != nil {
+		logger.Infof(ctx, "Cancelling knowledge %s while its processing holds the lock", knowledgeID)
 	}
 
-	// Enqueue summary generation task (async, non-blocking)
-	if len(textChunks) > 0 {
-		s.enqueueSummaryGenerationTask(ctx, knowledge.KnowledgeBaseID, knowledge.ID)
+	// Mark as cancelled first so running tasks stop at their next checkpoint
+	originalStatus := knowledge.ParseStatus
+	knowledge.ParseStatus = types.ParseStatusCancelled
+	knowledge.ErrorMessage = ""
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		logger.Errorf(ctx, "Failed to mark knowledge as cancelled: %v", err)
+		return nil, err
 	}
+	logger.Infof(ctx, "Marked knowledge %s as cancelled (previous status: %s)", knowledge.ID, originalStatus)
 
-	// Update tenant's storage usage
-	tenantInfo.StorageUsed += totalStorageSize
-	if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, totalStorageSize); err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks update tenant storage used failed")
+	// Data written by a task that passed its last checkpoint before the cancellation is cleaned up here,
+	// later writes are cleaned up by the task itself
+	if err := s.cleanupKnowledgeResources(ctx, knowledge); err != nil {
+		logger.Warnf(ctx, "Failed to cleanup resources of cancelled knowledge %s: %v", knowledge.ID, err)
 	}
-	logger.GetLogger(ctx).Infof("processChunks successfully")
+	return knowledge, nil
 }
 
-// GetSummary generates a summary for knowledge content using an AI model
-func (s *knowledgeService) getSummary(ctx context.Context,
-	summaryModel chat.Chat, knowledge *types.Knowledge, chunks []*types.Chunk,
-) (string, error) {
-	// Get knowledge info from the first chunk
+// setChunksEnabled 同步更新分块在数据库与检索引擎中的启用状态
+func (s *knowledgeService) setChunksEnabled(ctx context.Context,
+	retrieveEngine *retriever.CompositeRetrieveEngine, chunks []*types.Chunk, enabled bool,
+) error {
 	if len(chunks) == 0 {
-		return "", fmt.Errorf("no chunks provided for summary generation")
+		return nil
+	}
+	chunkStatusMap := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		chunk.IsEnabled = enabled
+		chunkStatusMap[chunk.ID] = enabled
+	}
+	if err := s.chunkRepo.UpdateChunks(ctx, chunks); err != nil {
+		return err
 	}
+	return retrieveEngine.BatchUpdateChunkEnabledStatus(ctx, chunkStatusMap)
+}
 
-	// concat chunk contents
-	chunkContents := ""
-	allImageInfos := make([]*types.ImageInfo, 0)
+// ReparseKnowledge deletes existing document content and re-parses the knowledge asynchronously.
+// This method reuses the logic from UpdateManualKnowledge for resource cleanup and async parsing.
+func (s *knowledgeService) ReparseKnowledge(ctx context.Context, knowledgeID string) (*types.Knowledge, error) {
+	return s.ReparseKnowledgeWithOptions(ctx, knowledgeID, types.ReparseOptions{})
+}
 
-	// then, sort chunks by StartAt
-	sortedChunks := make([]*types.Chunk, len(chunks))
-	copy(sortedChunks, chunks)
-	sort.Slice(sortedChunks, func(i, j int) bool {
-		return sortedChunks[i].StartAt < sortedChunks[j].StartAt
-	})
+const (
+	reparseCooldownKeyPrefix = "knowledge_reparse_cooldown:"
+	// defaultReparseCooldown 未配置时同一知识两次重新解析请求之间的最短间隔
+	defaultReparseCooldown = 10 * time.Second
+)
 
-	// concat chunk contents and collect image infos
-	for _, chunk := range sortedChunks {
-		if chunk.EndAt > 4096 {
-			break
-		}
-		// Ensure we don't slice beyond the current content length
-		runes := []rune(chunkContents)
-		if chunk.StartAt <= len(runes) {
-			chunkContents = string(runes[:chunk.StartAt]) + chunk.Content
-		} else {
-			// If StartAt is beyond current content, just append
-			chunkContents = chunkContents + chunk.Content
-		}
-		if chunk.ImageInfo != "" {
-			var images []*types.ImageInfo
-			if err := json.Unmarshal([]byte(chunk.ImageInfo), &images); err == nil {
-				allImageInfos = append(allImageInfos, images...)
-			}
-		}
+// getReparseCooldownKey returns the Redis key marking a recently started reparse of a knowledge
+func getReparseCooldownKey(tenantID uint64, knowledgeID string) string {
+	return fmt.Sprintf("%s%d:%s", reparseCooldownKeyPrefix, tenantID, knowledgeID)
+}
+
+// reparseCooldown returns the configured reparse cooldown; a negative value disables it
+func (s *knowledgeService) reparseCooldown() time.Duration {
+	if s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.ReparseCooldown != 0 {
+		return s.config.KnowledgeBase.ReparseCooldown
 	}
-	// remove markdown image syntax
-	re := regexp.MustCompile(`!\[[^\]]*\]\([^)]+\)`)
-	chunkContents = re.ReplaceAllString(chunkContents, "")
-	// collect all image infos
-	if len(allImageInfos) > 0 {
-		// add image infos to chunk contents
-		var imageAnnotations string
-		for _, img := range allImageInfos {
-			if img.Caption != "" {
-				imageAnnotations += fmt.Sprintf("\n[图片描述: %s]", img.Caption)
-			}
-			if img.OCRText != "" {
-				imageAnnotations += fmt.Sprintf("\n[图片文字: %s]", img.OCRText)
-			}
-		}
+	return defaultReparseCooldown
+}
 
-		// concat chunk contents and image annotations
-		chunkContents = chunkContents + imageAnnotations
+// startReparseCooldown records the start of a reparse and rejects the request if another reparse
+// of the same knowledge started within the cooldown window (e.g. a double-clicked button).
+// The returned function clears the cooldown so a failed request can be retried immediately.
+func (s *knowledgeService) startReparseCooldown(ctx context.Context, knowledgeID string) (func(), error) {
+	cooldown := s.reparseCooldown()
+	if s.redisClient == nil || cooldown <= 0 {
+		return func() {}, nil
 	}
 
-	if len(chunkContents) < 300 {
-		return chunkContents, nil
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	key := getReparseCooldownKey(tenantID, knowledgeID)
+	acquired, err := s.redisClient.SetNX(ctx, key, time.Now().Unix(), cooldown).Result()
+	if err != nil {
+		// Redis 异常时不阻塞重新解析
+		logger.Errorf(ctx, "Failed to set reparse cooldown for knowledge %s: %v", knowledgeID, err)
+		return func() {}, nil
+	}
+	if !acquired {
+		remaining, err := s.redisClient.PTTL(ctx, key).Result()
+		if err != nil || remaining <= 0 {
+			remaining = cooldown
+		}
+		seconds := int(math.Ceil(remaining.Seconds()))
+		logger.Warnf(ctx, "Reparse of knowledge %s rejected by cooldown, %ds remaining", knowledgeID, seconds)
+		return nil, werrors.NewConflictError(
+			fmt.Sprintf("该知识正在重新解析或刚刚重新解析过，请 %d 秒后再试", seconds),
+		).WithDetails(map[string]int{"retry_after_seconds": seconds})
 	}
 
-	// Prepare content with metadata for summary generation
-	contentWithMetadata := chunkContents
+	return func() {
+		if err := s.redisClient.Del(context.WithoutCancel(ctx), key).Err(); err != nil {
+			logger.Warnf(ctx, "Failed to clear reparse cooldown for knowledge %s: %v", knowledgeID, err)
+		}
+	}, nil
+}
 
-	// Add knowledge metadata if available
-	if knowledge != nil {
-		metadataIntro := fmt.Sprintf("文档类型: %s\n文件名称: %s\n", knowledge.FileType, knowledge.FileName)
+const (
+	chunkSnapshotKeyPrefix  = "knowledge_chunk_snapshot:"
+	defaultChunkSnapshotTTL = 24 * time.Hour
+)
 
-		// Add additional metadata if available
-		if knowledge.Type != "" {
-			metadataIntro += fmt.Sprintf("知识类型: %s\n", knowledge.Type)
-		}
+// getChunkSnapshotKey returns the Redis key for storing the pre-reparse chunk snapshot of a knowledge
+func getChunkSnapshotKey(tenantID uint64, knowledgeID string) string {
+	return fmt.Sprintf("%s%d:%s", chunkSnapshotKeyPrefix, tenantID, knowledgeID)
+}
+
+// chunkSnapshotEnabled reports whether pre-reparse chunk snapshots are configured and can be stored
+func (s *knowledgeService) chunkSnapshotEnabled() bool {
+	return s.redisClient != nil && s.config != nil && s.config.KnowledgeBase != nil &&
+		s.config.KnowledgeBase.ChunkSnapshot != nil && s.config.KnowledgeBase.ChunkSnapshot.Enabled
+}
 
-		// Prepend metadata to content
-		contentWithMetadata = metadataIntro + "\n内容:\n" + contentWithMetadata
+// chunkSnapshotTTL returns how long chunk snapshots are retained
+func (s *knowledgeService) chunkSnapshotTTL() time.Duration {
+	if ttl := s.config.KnowledgeBase.ChunkSnapshot.TTL; ttl > 0 {
+		return ttl
 	}
+	return defaultChunkSnapshotTTL
+}
 
-	// Generate summary using AI model
-	thinking := false
-	summary, err := summaryModel.Chat(ctx, []chat.Message{
-		{
-			Role:    "system",
-			Content: s.config.Conversation.GenerateSummaryPrompt,
-		},
-		{
-			Role:    "user",
-			Content: contentWithMetadata,
-		},
-	}, &chat.ChatOptions{
-		Temperature: 0.3,
-		MaxTokens:   1024,
-		Thinking:    &thinking,
+// buildChunkSnapshot keeps the content and boundaries of chunks, ordered by chunk index.
+func buildChunkSnapshot(knowledge *types.Knowledge, chunkingConfig types.ChunkingConfig,
+	chunks []*types.Chunk, now time.Time, ttl time.Duration,
+) *types.KnowledgeChunkSnapshot {
+	items := make([]types.KnowledgeChunkItem, 0, len(chunks))
+	for _, chunk := range chunks {
+		items = append(items, types.KnowledgeChunkItem{
+			ChunkIndex:    chunk.ChunkIndex,
+			ChunkType:     chunk.ChunkType,
+			ParentChunkID: chunk.ParentChunkID,
+			StartAt:       chunk.StartAt,
+			EndAt:         chunk.EndAt,
+			Content:       chunk.Content,
+		})
+	}
+	slices.SortStableFunc(items, func(a, b types.KnowledgeChunkItem) int {
+		return cmp.Compare(a.ChunkIndex, b.ChunkIndex)
 	})
-	if err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Errorf("GetSummary failed")
-		return "", err
+	return &types.KnowledgeChunkSnapshot{
+		KnowledgeID:    knowledge.ID,
+		ParseStatus:    knowledge.ParseStatus,
+		ChunkingConfig: chunkingConfig,
+		Chunks:         items,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(ttl),
 	}
-	logger.GetLogger(ctx).WithField("summary", summary.Content).Infof("GetSummary success")
-	return summary.Content, nil
 }
 
-// enqueueQuestionGenerationTask enqueues an async task for question generation
-func (s *knowledgeService) enqueueQuestionGenerationTask(ctx context.Context,
-	kbID, knowledgeID string, questionCount int,
+// saveChunkSnapshot stores the chunks of a knowledge before they are wiped by a reparse.
+// Failures are logged only; a missing snapshot must never block the reparse itself.
+func (s *knowledgeService) saveChunkSnapshot(ctx context.Context,
+	knowledge *types.Knowledge, kb *types.KnowledgeBase, chunks []*types.Chunk,
 ) {
-	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	payload := types.QuestionGenerationPayload{
-		TenantID:        tenantID,
-		KnowledgeBaseID: kbID,
-		KnowledgeID:     knowledgeID,
-		QuestionCount:   questionCount,
+	if len(chunks) == 0 {
+		return
 	}
-
-	payloadBytes, err := json.Marshal(payload)
+	ttl := s.chunkSnapshotTTL()
+	snapshot := buildChunkSnapshot(knowledge, kb.ChunkingConfig, chunks, time.Now(), ttl)
+	data, err := json.Marshal(snapshot)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to marshal question generation payload: %v", err)
+		logger.Warnf(ctx, "Failed to marshal chunk snapshot for knowledge %s: %v", knowledge.ID, err)
 		return
 	}
-
-	task := asynq.NewTask(types.TypeQuestionGeneration, payloadBytes, asynq.Queue("low"), asynq.MaxRetry(3))
-	info, err := s.task.Enqueue(task)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to enqueue question generation task: %v", err)
+	key := getChunkSnapshotKey(knowledge.TenantID, knowledge.ID)
+	if err := s.redisClient.Set(ctx, key, data, ttl).Err(); err != nil {
+		logger.Warnf(ctx, "Failed to save chunk snapshot for knowledge %s: %v", knowledge.ID, err)
 		return
 	}
-	logger.Infof(ctx, "Enqueued question generation task: %s for knowledge: %s", info.ID, knowledgeID)
+	logger.Infof(ctx, "Saved snapshot of %d chunks for knowledge %s, ttl %s", len(chunks), knowledge.ID, ttl)
 }
 
-// enqueueSummaryGenerationTask enqueues an async task for summary generation
-func (s *knowledgeService) enqueueSummaryGenerationTask(ctx context.Context,
-	kbID, knowledgeID string,
-) {
+// GetKnowledgeChunkSnapshot returns the chunks saved before the last reparse of a knowledge
+func (s *knowledgeService) GetKnowledgeChunkSnapshot(ctx context.Context,
+	knowledgeID string,
+) (*types.KnowledgeChunkSnapshot, error) {
+	if !s.chunkSnapshotEnabled() {
+		return nil, werrors.NewBadRequestError("未开启重新解析分块快照")
+	}
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	payload := types.SummaryGenerationPayload{
-		TenantID:        tenantID,
-		KnowledgeBaseID: kbID,
-		KnowledgeID:     knowledgeID,
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		return nil, err
 	}
 
-	payloadBytes, err := json.Marshal(payload)
+	data, err := s.redisClient.Get(ctx, getChunkSnapshotKey(knowledge.TenantID, knowledge.ID)).Bytes()
 	if err != nil {
-		logger.Errorf(ctx, "Failed to marshal summary generation payload: %v", err)
-		return
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("分块快照不存在或已过期")
+		}
+		return nil, fmt.Errorf("failed to get chunk snapshot from Redis: %w", err)
+	}
+	var snapshot types.KnowledgeChunkSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chunk snapshot: %w", err)
 	}
+	return &snapshot, nil
+}
 
-	task := asynq.NewTask(types.TypeSummaryGeneration, payloadBytes, asynq.Queue("low"), asynq.MaxRetry(3))
-	info, err := s.task.Enqueue(task)
+// parsedMarkdownEnabled reports whether the full parsed markdown of documents is kept in storage
+func (s *knowledgeService) parsedMarkdownEnabled() bool {
+	return s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.StoreParsedMarkdown
+}
+
+// buildParsedMarkdown reconstructs the document text from docreader chunks. Chunks are ordered by
+// Seq and the overlap between neighbours ([Start, End) in runes) is skipped; chunks without usable
+// boundaries are joined with a blank line.
+func buildParsedMarkdown(chunks []*proto.Chunk) string {
+	ordered := make([]*proto.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk != nil {
+			ordered = append(ordered, chunk)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Seq < ordered[j].Seq })
+
+	var sb strings.Builder
+	prevEnd := int32(-1)
+	for _, chunk := range ordered {
+		content := chunk.Content
+		if chunk.End <= chunk.Start || prevEnd < 0 {
+			if sb.Len() > 0 {
+				sb.WriteString("\n\n")
+			}
+		} else if chunk.Start < prevEnd {
+			runes := []rune(content)
+			skip := int(prevEnd - chunk.Start)
+			if skip >= len(runes) {
+				content = ""
+			} else {
+				content = string(runes[skip:])
+			}
+		}
+		sb.WriteString(content)
+		if chunk.End > chunk.Start {
+			if chunk.End > prevEnd {
+				prevEnd = chunk.End
+			}
+		} else {
+			prevEnd = -1
+		}
+	}
+	return sb.String()
+}
+
+// saveParsedMarkdown stores the full parsed markdown of a knowledge and replaces the copy of the
+// previous parse. Failures are logged only; they must never fail the parse itself.
+func (s *knowledgeService) saveParsedMarkdown(ctx context.Context,
+	knowledge *types.Knowledge, chunks []*proto.Chunk,
+) {
+	markdown := buildParsedMarkdown(chunks)
+	if markdown == "" {
+		return
+	}
+	path, err := s.fileSvc.SaveBytes(ctx, []byte(markdown), knowledge.TenantID, knowledge.ID+".md", false)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to enqueue summary generation task: %v", err)
+		logger.Warnf(ctx, "Failed to save parsed markdown for knowledge %s: %v", knowledge.ID, err)
 		return
 	}
-	logger.Infof(ctx, "Enqueued summary generation task: %s for knowledge: %s", info.ID, knowledgeID)
+	if old := knowledge.ParsedMarkdownPath; old != "" && old != path {
+		if err := s.fileSvc.DeleteFile(ctx, old); err != nil {
+			logger.Warnf(ctx, "Failed to delete previous parsed markdown %s: %v", old, err)
+		}
+	}
+	knowledge.ParsedMarkdownPath = path
+	logger.Infof(ctx, "Saved parsed markdown of knowledge %s (%d bytes)", knowledge.ID, len(markdown))
 }
 
-// ProcessSummaryGeneration handles async summary generation task
-func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asynq.Task) error {
-	var payload types.SummaryGenerationPayload
-	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
-		logger.Errorf(ctx, "Failed to unmarshal summary generation payload: %v", err)
-		return nil // Don't retry on unmarshal error
+// GetKnowledgeMarkdown returns the full markdown extracted at the last parse of a knowledge
+func (s *knowledgeService) GetKnowledgeMarkdown(ctx context.Context,
+	knowledgeID string,
+) (*types.KnowledgeMarkdown, error) {
+	if !s.parsedMarkdownEnabled() {
+		return nil, werrors.NewBadRequestError("未开启解析结果 Markdown 存储")
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	if knowledge.ParsedMarkdownPath == "" {
+		return nil, werrors.NewNotFoundError("该知识暂无解析结果 Markdown，请在开启后重新解析")
 	}
 
-	logger.Infof(ctx, "Processing summary generation for knowledge: %s", payload.KnowledgeID)
+	reader, err := s.fileSvc.GetFile(ctx, knowledge.ParsedMarkdownPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parsed markdown: %w", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parsed markdown: %w", err)
+	}
+	return &types.KnowledgeMarkdown{KnowledgeID: knowledge.ID, Content: string(data)}, nil
+}
 
-	// Set tenant context
-	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+// ReparseKnowledgeWithOptions re-parses the knowledge like ReparseKnowledge, optionally skipping
+// summary and/or question generation to avoid re-spending LLM tokens on minor content fixes.
+// Skipped summaries are kept as-is; skipped questions are carried over to chunks whose content is unchanged.
+// Data table summaries are part of the chunks and are always regenerated.
+func (s *knowledgeService) ReparseKnowledgeWithOptions(ctx context.Context,
+	knowledgeID string, opts types.ReparseOptions,
+) (*types.Knowledge, error) {
+	releaseCooldown, err := s.startReparseCooldown(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	knowledge, err := s.reparseKnowledge(ctx, knowledgeID, opts)
+	if err != nil {
+		// A failed request should not block the user from retrying right away
+		releaseCooldown()
+		return nil, err
+	}
+	return knowledge, nil
+}
+
+// reparseKnowledge performs the cleanup and schedules the async re-parse for ReparseKnowledgeWithOptions.
+func (s *knowledgeService) reparseKnowledge(ctx context.Context,
+	knowledgeID string, opts types.ReparseOptions,
+) (*types.Knowledge, error) {
+	logger.Infof(ctx, "Start re-parsing knowledge, skip summary: %v, skip question generation: %v",
+		opts.SkipSummary, opts.SkipQuestionGeneration)
 
-	// Get knowledge base
-	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
+	lock, err := s.lockKnowledge(ctx, knowledgeID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
-		return nil
+		return nil, err
 	}
+	defer lock.Unlock()
 
-	// Get knowledge
-	knowledge, err := s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	existing, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge: %v", err)
-		return nil
+		logger.Errorf(ctx, "Failed to load knowledge: %v", err)
+		return nil, err
 	}
 
-	// Update summary status to processing
-	knowledge.SummaryStatus = types.SummaryStatusProcessing
-	knowledge.UpdatedAt = time.Now()
-	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
-		logger.Warnf(ctx, "Failed to update summary status to processing: %v", err)
+	// Get knowledge base configuration
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, existing.KnowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base for reparse: %v", err)
+		return nil, err
 	}
 
-	// Helper function to mark summary as failed
-	markSummaryFailed := func() {
-		knowledge.SummaryStatus = types.SummaryStatusFailed
-		knowledge.UpdatedAt = time.Now()
-		if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
-			logger.Warnf(ctx, "Failed to update summary status to failed: %v", err)
+	// Parsing overrides are remembered on the document: new overrides are merged into the stored
+	// profile and the result is reused by every later reparse until reset. One-off overrides only
+	// travel with this run's task payload and leave the stored profile untouched
+	var profile, runProfile *types.KnowledgeParseProfile
+	if existing.IsManual() {
+		if !opts.ParseOverrides.IsEmpty() {
+			return nil, werrors.NewBadRequestError("手工知识不支持解析参数覆盖")
+		}
+	} else {
+		if !opts.ResetParseProfile {
+			profile = existing.ParseProfile()
+		}
+		profile = profile.Merge(opts.ParseOverrides)
+		if err := profile.Validate(kb.ChunkingConfig); err != nil {
+			return nil, werrors.NewValidationError("解析参数不合法").WithDetails(err.Error())
+		}
+		if opts.ParseOverridesOnce {
+			runProfile = profile
 		}
 	}
 
-	// Get text chunks for this knowledge
-	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, payload.KnowledgeID)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to get chunks: %v", err)
-		markSummaryFailed()
-		return nil
+	// Collect generated questions before the chunks are removed so unchanged chunks can reuse them,
+	// and snapshot the old chunks for comparison when enabled
+	var preservedQuestions map[string][]types.GeneratedQuestion
+	snapshotEnabled := s.chunkSnapshotEnabled()
+	if opts.SkipQuestionGeneration || snapshotEnabled {
+		oldChunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, existing.ID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to list chunks before reparse: %v", err)
+			return nil, err
+		}
+		if opts.SkipQuestionGeneration {
+			preservedQuestions = collectGeneratedQuestions(oldChunks)
+		}
+		if snapshotEnabled {
+			s.saveChunkSnapshot(ctx, existing, kb, oldChunks)
+		}
 	}
 
-	// Filter text chunks only
-	textChunks := make([]*types.Chunk, 0)
-	for _, chunk := range chunks {
-		if chunk.ChunkType == types.ChunkTypeText {
-			textChunks = append(textChunks, chunk)
+	// Step 1: Clean up existing resources (chunks, embeddings, graph data)
+	logger.Infof(ctx, "Cleaning up existing resources for knowledge: %s", knowledgeID)
+	if err := s.cleanupKnowledgeResources(ctx, existing); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": knowledgeID,
+		})
+		return nil, err
+	}
+
+	// Step 2: Update knowledge status and metadata
+	existing.ParseStatus = "pending"
+	existing.EnableStatus = "disabled"
+	if !opts.SkipSummary {
+		existing.Description = ""
+	}
+	existing.ProcessedAt = nil
+	existing.EmbeddingModelID = kb.EmbeddingModelID
+	existing.ErrorMessage = ""
+	existing.ParseWarning = ""
+	existing.ProcessingReport = nil
+	if !existing.IsManual() && runProfile == nil {
+		if err := existing.SetParseProfile(profile); err != nil {
+			logger.Warnf(ctx, "Failed to store parse profile of knowledge %s: %v", existing.ID, err)
 		}
 	}
 
-	if len(textChunks) == 0 {
-		logger.Infof(ctx, "No text chunks found for knowledge: %s", payload.KnowledgeID)
-		// Mark as completed since there's nothing to summarize
-		knowledge.SummaryStatus = types.SummaryStatusCompleted
-		knowledge.UpdatedAt = time.Now()
-		s.repo.UpdateKnowledge(ctx, knowledge)
-		return nil
+	if err := s.repo.UpdateKnowledge(ctx, existing); err != nil {
+		logger.Errorf(ctx, "Failed to update knowledge status before reparse: %v", err)
+		return nil, err
 	}
 
-	// Sort chunks by ChunkIndex for proper ordering
-	sort.Slice(textChunks, func(i, j int) bool {
-		return textChunks[i].ChunkIndex < textChunks[j].ChunkIndex
-	})
+	// Step 3: Trigger async re-parsing based on knowledge type
+	logger.Infof(ctx, "Knowledge status updated, scheduling async reparse, ID: %s, Type: %s", existing.ID, existing.Type)
 
-	// Initialize chat model for summary
-	chatModel, err := s.modelService.GetChatModel(ctx, kb.SummaryModelID)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to get chat model: %v", err)
-		markSummaryFailed()
-		return fmt.Errorf("failed to get chat model: %w", err)
+	// For manual knowledge, extract content from metadata and trigger manual processing
+	if existing.IsManual() {
+		meta, err := existing.ManualMetadata()
+		if err != nil || meta == nil {
+			logger.Errorf(ctx, "Failed to get manual metadata for reparse: %v", err)
+			return nil, werrors.NewBadRequestError("无法获取手工知识内容")
+		}
+		s.triggerManualProcessing(ctx, kb, existing, meta.Content, false, ProcessChunksOptions{
+			SkipSummary:        opts.SkipSummary,
+			PreservedQuestions: preservedQuestions,
+			LockToken:          lock.HandOff(),
+		})
+		return existing, nil
 	}
 
-	// Generate summary
-	summary, err := s.getSummary(ctx, chatModel, knowledge, textChunks)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to generate summary for knowledge %s: %v", payload.KnowledgeID, err)
-		// Use first chunk content as fallback
-		if len(textChunks) > 0 {
-			summary = textChunks[0].Content
-			if len(summary) > 500 {
-				summary = summary[:500]
+	// For file-based knowledge, enqueue document processing task
+	if existing.FilePath != "" {
+		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+		// Determine multimodal setting
+		enableMultimodel := s.applyMultimodalKillSwitch(ctx, profile.ResolveEnableMultimodel(kb.IsMultimodalEnabled()))
+
+		// Check question generation config
+		enableQuestionGeneration := false
+		questionCount := 3 // default
+		if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
+			enableQuestionGeneration = true
+			if kb.QuestionGenerationConfig.QuestionCount > 0 {
+				questionCount = kb.QuestionGenerationConfig.QuestionCount
 			}
 		}
-	}
+		if opts.SkipQuestionGeneration {
+			enableQuestionGeneration = false
+		}
 
-	// Update knowledge description
-	knowledge.Description = summary
-	knowledge.SummaryStatus = types.SummaryStatusCompleted
-	knowledge.UpdatedAt = time.Now()
-	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
-		logger.Errorf(ctx, "Failed to update knowledge description: %v", err)
-		return fmt.Errorf("failed to update knowledge: %w", err)
+		taskPayload := types.DocumentProcessPayload{
+			TenantID:                 tenantID,
+			KnowledgeID:              existing.ID,
+			KnowledgeBaseID:          existing.KnowledgeBaseID,
+			FilePath:                 existing.FilePath,
+			FileName:                 existing.FileName,
+			FileType:                 getFileType(existing.FileName),
+			EnableMultimodel:         enableMultimodel,
+			EnableQuestionGeneration: enableQuestionGeneration,
+			QuestionCount:            questionCount,
+			SkipSummary:              opts.SkipSummary,
+			PreservedQuestions:       preservedQuestions,
+			ParseProfile:             runProfile,
+			LockToken:                lock.token,
+		}
+
+		payloadBytes, err := s.marshalDocumentProcessPayload(ctx, &taskPayload)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to marshal reparse task payload: %v", err)
+			return existing, nil
+		}
+
+		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
+		info, err := s.task.Enqueue(task)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to enqueue reparse task: %v", err)
+			s.deleteOffloadedTaskData(ctx, taskPayload.OffloadURL)
+			return existing, nil
+		}
+		// The document processing task now completes the reparse and releases the lock when done
+		lock.HandOff()
+		logger.Infof(ctx, "Enqueued reparse task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, existing.ID)
+
+		// For data tables (csv, xlsx, xls), also enqueue summary task
+		if slices.Contains([]string{"csv", "xlsx", "xls"}, getFileType(existing.FileName)) {
+			NewDataTableSummaryTask(ctx, s.task, tenantID, existing.ID, kb.SummaryModelID, kb.EmbeddingModelID)
+		}
+
+		return existing, nil
 	}
 
-	// Create summary chunk and index it
-	if strings.TrimSpace(summary) != "" {
-		// Get max chunk index
-		maxChunkIndex := 0
-		for _, chunk := range chunks {
-			if chunk.ChunkIndex > maxChunkIndex {
-				maxChunkIndex = chunk.ChunkIndex
+	// For file-URL-based knowledge, enqueue document processing task with FileURL field
+	if existing.Type == "file_url" && existing.Source != "" {
+		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+		enableMultimodel := s.applyMultimodalKillSwitch(ctx, profile.ResolveEnableMultimodel(kb.IsMultimodalEnabled()))
+
+		// Check question generation config
+		enableQuestionGeneration := false
+		questionCount := 3
+		if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
+			enableQuestionGeneration = true
+			if kb.QuestionGenerationConfig.QuestionCount > 0 {
+				questionCount = kb.QuestionGenerationConfig.QuestionCount
 			}
 		}
-
-		summaryChunk := &types.Chunk{
-			ID:              uuid.New().String(),
-			TenantID:        knowledge.TenantID,
-			KnowledgeID:     knowledge.ID,
-			KnowledgeBaseID: knowledge.KnowledgeBaseID,
-			Content:         fmt.Sprintf("# 文档名称\n%s\n\n# 摘要\n%s", knowledge.FileName, summary),
-			ChunkIndex:      maxChunkIndex + 1,
-			IsEnabled:       true,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
-			StartAt:         0,
-			EndAt:           0,
-			ChunkType:       types.ChunkTypeSummary,
-			ParentChunkID:   textChunks[0].ID,
+		if opts.SkipQuestionGeneration {
+			enableQuestionGeneration = false
 		}
 
-		// Save summary chunk
-		if err := s.chunkService.CreateChunks(ctx, []*types.Chunk{summaryChunk}); err != nil {
-			logger.Errorf(ctx, "Failed to create summary chunk: %v", err)
-			return fmt.Errorf("failed to create summary chunk: %w", err)
+		taskPayload := types.DocumentProcessPayload{
+			TenantID:                 tenantID,
+			KnowledgeID:              existing.ID,
+			KnowledgeBaseID:          existing.KnowledgeBaseID,
+			FileURL:                  existing.Source,
+			FileName:                 existing.FileName,
+			FileType:                 existing.FileType,
+			EnableMultimodel:         enableMultimodel,
+			EnableQuestionGeneration: enableQuestionGeneration,
+			QuestionCount:            questionCount,
+			SkipSummary:              opts.SkipSummary,
+			PreservedQuestions:       preservedQuestions,
+			ParseProfile:             runProfile,
+			LockToken:                lock.token,
 		}
 
-		// Index summary chunk
-		tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+		payloadBytes, err := s.marshalDocumentProcessPayload(ctx, &taskPayload)
 		if err != nil {
-			logger.Errorf(ctx, "Failed to get tenant info: %v", err)
-			return fmt.Errorf("failed to get tenant info: %w", err)
+			logger.Errorf(ctx, "Failed to marshal file URL reparse task payload: %v", err)
+			return existing, nil
 		}
-		ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
 
-		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
+		info, err := s.task.Enqueue(task)
 		if err != nil {
-			logger.Errorf(ctx, "Failed to init retrieve engine: %v", err)
-			return fmt.Errorf("failed to init retrieve engine: %w", err)
+			logger.Errorf(ctx, "Failed to enqueue file URL reparse task: %v", err)
+			s.deleteOffloadedTaskData(ctx, taskPayload.OffloadURL)
+			return existing, nil
 		}
+		// The document processing task now completes the reparse and releases the lock when done
+		lock.HandOff()
+		logger.Infof(ctx, "Enqueued file URL reparse task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, existing.ID)
 
-		embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to get embedding model: %v", err)
-			return fmt.Errorf("failed to get embedding model: %w", err)
+		return existing, nil
+	}
+
+	// For URL-based knowledge, enqueue URL processing task
+	if existing.Type == "url" && existing.Source != "" {
+		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+		enableMultimodel := s.applyMultimodalKillSwitch(ctx, profile.ResolveEnableMultimodel(kb.IsMultimodalEnabled()))
+
+		// Check question generation config
+		enableQuestionGeneration := false
+		questionCount := 3
+		if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
+			enableQuestionGeneration = true
+			if kb.QuestionGenerationConfig.QuestionCount > 0 {
+				questionCount = kb.QuestionGenerationConfig.QuestionCount
+			}
+		}
+		if opts.SkipQuestionGeneration {
+			enableQuestionGeneration = false
 		}
 
-		indexInfo := []*types.IndexInfo{{
-			Content:         summaryChunk.Content,
-			SourceID:        summaryChunk.ID,
-			SourceType:      types.ChunkSourceType,
-			ChunkID:         summaryChunk.ID,
-			KnowledgeID:     knowledge.ID,
-			KnowledgeBaseID: knowledge.KnowledgeBaseID,
-		}}
+		taskPayload := types.DocumentProcessPayload{
+			TenantID:                 tenantID,
+			KnowledgeID:              existing.ID,
+			KnowledgeBaseID:          existing.KnowledgeBaseID,
+			URL:                      existing.Source,
+			EnableMultimodel:         enableMultimodel,
+			EnableQuestionGeneration: enableQuestionGeneration,
+			QuestionCount:            questionCount,
+			SkipSummary:              opts.SkipSummary,
+			PreservedQuestions:       preservedQuestions,
+			ParseProfile:             runProfile,
+			LockToken:                lock.token,
+		}
 
-		if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfo); err != nil {
-			logger.Errorf(ctx, "Failed to index summary chunk: %v", err)
-			return fmt.Errorf("failed to index summary chunk: %w", err)
+		payloadBytes, err := s.marshalDocumentProcessPayload(ctx, &taskPayload)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to marshal URL reparse task payload: %v", err)
+			return existing, nil
 		}
 
-		logger.Infof(ctx, "Successfully created and indexed summary chunk for knowledge: %s", payload.KnowledgeID)
+		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
+		info, err := s.task.Enqueue(task)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to enqueue URL reparse task: %v", err)
+			s.deleteOffloadedTaskData(ctx, taskPayload.OffloadURL)
+			return existing, nil
+		}
+		// The document processing task now completes the reparse and releases the lock when done
+		lock.HandOff()
+		logger.Infof(ctx, "Enqueued URL reparse task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, existing.ID)
+
+		return existing, nil
 	}
 
-	logger.Infof(ctx, "Successfully generated summary for knowledge: %s", payload.KnowledgeID)
-	return nil
+	logger.Warnf(ctx, "Knowledge %s has no parseable content (no file, URL, or manual content)", knowledgeID)
+	return existing, nil
 }
 
-// ProcessQuestionGeneration handles async question generation task
-func (s *knowledgeService) ProcessQuestionGeneration(ctx context.Context, t *asynq.Task) error {
-	ctx, span := tracing.ContextWithSpan(ctx, "knowledgeService.ProcessQuestionGeneration")
-	defer span.End()
-
-	var payload types.QuestionGenerationPayload
-	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
-		logger.Errorf(ctx, "Failed to unmarshal question generation payload: %v", err)
-		return nil // Don't retry on unmarshal error
+// ReembedKnowledge re-embeds the existing chunks of a knowledge item with a new embedding model.
+// Unlike ReparseKnowledge it skips the docreader step entirely: chunks are kept as-is and only
+// their vectors are rebuilt, which is much cheaper when only the embedding model changed.
+// The request is validated synchronously, the re-embedding itself runs as an asynq task.
+func (s *knowledgeService) ReembedKnowledge(ctx context.Context,
+	knowledgeID string, newModelID string,
+) (*types.Knowledge, error) {
+	knowledge, _, _, err := s.prepareReembed(ctx, knowledgeID, newModelID)
+	if err != nil {
+		return nil, err
 	}
 
-	logger.Infof(ctx, "Processing question generation for knowledge: %s", payload.KnowledgeID)
-
-	// Set tenant context
-	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
-
-	// Get knowledge base
-	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	payloadBytes, err := json.Marshal(types.KnowledgeReembedPayload{
+		TenantID:    tenantID,
+		KnowledgeID: knowledge.ID,
+		ModelID:     newModelID,
+	})
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
-		return nil
+		return nil, err
 	}
-
-	// Get knowledge
-	knowledge, err := s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
+	task := asynq.NewTask(types.TypeKnowledgeReembed, payloadBytes, asynq.Queue("default"), asynq.MaxRetry(3))
+	info, err := s.task.Enqueue(task)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge: %v", err)
+		logger.Errorf(ctx, "Failed to enqueue knowledge reembed task: %v", err)
+		return nil, fmt.Errorf("failed to enqueue reembed task: %w", err)
+	}
+	logger.Infof(ctx, "Enqueued knowledge reembed task: id=%s knowledge_id=%s model_id=%s",
+		info.ID, knowledge.ID, newModelID)
+	return knowledge, nil
+}
+
+// ProcessKnowledgeReembed handles the knowledge reembed task enqueued by ReembedKnowledge
+func (s *knowledgeService) ProcessKnowledgeReembed(ctx context.Context, t *asynq.Task) error {
+	var payload types.KnowledgeReembedPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal knowledge reembed payload: %v", err)
 		return nil
 	}
 
-	// Get text chunks for this knowledge
-	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, payload.KnowledgeID)
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get chunks: %v", err)
-		return nil
+		logger.Errorf(ctx, "Failed to get tenant %d: %v", payload.TenantID, err)
+		return err
 	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
 
-	// Filter text chunks only
-	textChunks := make([]*types.Chunk, 0)
-	for _, chunk := range chunks {
-		if chunk.ChunkType == types.ChunkTypeText {
-			textChunks = append(textChunks, chunk)
+	if _, err := s.reembedKnowledge(ctx, payload.KnowledgeID, payload.ModelID); err != nil {
+		if _, ok := werrors.IsAppError(err); ok {
+			// The knowledge or model changed since the task was submitted, retrying cannot help
+			logger.Warnf(ctx, "Skip re-embedding knowledge %s: %v", payload.KnowledgeID, err)
+			return nil
 		}
+		return err
 	}
+	return nil
+}
 
-	if len(textChunks) == 0 {
-		logger.Infof(ctx, "No text chunks found for knowledge: %s", payload.KnowledgeID)
-		return nil
+// prepareReembed loads the knowledge and both embedding models of a re-embed request and checks
+// the vector store can hold the new dimensions
+func (s *knowledgeService) prepareReembed(ctx context.Context,
+	knowledgeID string, newModelID string,
+) (*types.Knowledge, embedding.Embedder, embedding.Embedder, error) {
+	if newModelID == "" {
+		return nil, nil, nil, werrors.NewBadRequestError("模型ID不能为空")
 	}
 
-	// Sort chunks by StartAt for context building
-	sort.Slice(textChunks, func(i, j int) bool {
-		return textChunks[i].StartAt < textChunks[j].StartAt
-	})
-
-	// Initialize chat model
-	chatModel, err := s.modelService.GetChatModel(ctx, kb.SummaryModelID)
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get chat model: %v", err)
-		return fmt.Errorf("failed to get chat model: %w", err)
+		logger.Errorf(ctx, "Failed to load knowledge for reembed: %v", err)
+		return nil, nil, nil, err
+	}
+	if knowledge.ParseStatus != types.ParseStatusCompleted {
+		return nil, nil, nil, werrors.NewBadRequestError("仅支持对解析完成的知识重新向量化")
 	}
 
-	// Initialize embedding model and retrieval engine
-	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	oldModel, err := s.modelService.GetEmbeddingModel(ctx, knowledge.EmbeddingModelID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get embedding model: %v", err)
-		return fmt.Errorf("failed to get embedding model: %w", err)
+		logger.Errorf(ctx, "Failed to get current embedding model for reembed: %v", err)
+		return nil, nil, nil, err
+	}
+	newModel, err := s.modelService.GetEmbeddingModel(ctx, newModelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get new embedding model for reembed: %v", err)
+		return nil, nil, nil, werrors.NewBadRequestError("目标嵌入模型不存在或不可用")
 	}
 
-	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	// 共享知识库的索引位于所有者租户的检索引擎中
+	tenantInfo, err := s.effectiveTenantInfo(ctx)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get tenant info: %v", err)
-		return fmt.Errorf("failed to get tenant info: %w", err)
+		return nil, nil, nil, err
 	}
-	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+	if oldModel.GetDimensions() != newModel.GetDimensions() &&
+		!supportsMixedDimensions(tenantInfo.GetEffectiveEngines()) {
+		return nil, nil, nil, werrors.NewBadRequestError(fmt.Sprintf(
+			"嵌入模型维度不一致（%d -> %d），当前向量存储不支持混合维度",
+			oldModel.GetDimensions(), newModel.GetDimensions(),
+		))
+	}
+	return knowledge, oldModel, newModel, nil
+}
 
-	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+// reembedKnowledge rebuilds the index of a knowledge item with a new embedding model and records
+// the model on the knowledge
+func (s *knowledgeService) reembedKnowledge(ctx context.Context,
+	knowledgeID string, newModelID string,
+) (*types.Knowledge, error) {
+	lock, err := s.lockKnowledge(ctx, knowledgeID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to init retrieve engine: %v", err)
-		return fmt.Errorf("failed to init retrieve engine: %w", err)
+		return nil, err
 	}
+	defer lock.Unlock()
 
-	questionCount := payload.QuestionCount
-	if questionCount <= 0 {
-		questionCount = 3
+	knowledge, oldModel, newModel, err := s.prepareReembed(ctx, knowledgeID, newModelID)
+	if err != nil {
+		return nil, err
 	}
-	if questionCount > 10 {
-		questionCount = 10
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, knowledge.KnowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base for reembed: %v", err)
+		return nil, err
+	}
+	tenantInfo, err := s.effectiveTenantInfo(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate questions for each chunk with context
-	var indexInfoList []*types.IndexInfo
-	for i, chunk := range textChunks {
-		// Build context from adjacent chunks
-		var prevContent, nextContent string
-		if i > 0 {
-			prevContent = textChunks[i-1].Content
-			// Limit context size
-			if len(prevContent) > 500 {
-				prevContent = prevContent[len(prevContent)-500:]
-			}
-		}
-		if i < len(textChunks)-1 {
-			nextContent = textChunks[i+1].Content
-			// Limit context size
-			if len(nextContent) > 500 {
-				nextContent = nextContent[:500]
-			}
-		}
-
-		questions, err := s.generateQuestionsWithContext(ctx, chatModel, chunk.Content, prevContent, nextContent, knowledge.Title, questionCount)
-		if err != nil {
-			logger.Warnf(ctx, "Failed to generate questions for chunk %s: %v", chunk.ID, err)
-			continue
-		}
-
-		if len(questions) == 0 {
-			continue
-		}
-
-		// Update chunk metadata with unique IDs for each question
-		generatedQuestions := make([]types.GeneratedQuestion, len(questions))
-		for j, question := range questions {
-			questionID := fmt.Sprintf("q%d", time.Now().UnixNano()+int64(j))
-			generatedQuestions[j] = types.GeneratedQuestion{
-				ID:       questionID,
-				Question: question,
-			}
-		}
-		meta := &types.DocumentChunkMetadata{
-			GeneratedQuestions: generatedQuestions,
-		}
-		if err := chunk.SetDocumentMetadata(meta); err != nil {
-			logger.Warnf(ctx, "Failed to set document metadata for chunk %s: %v", chunk.ID, err)
-			continue
-		}
-
-		// Update chunk in database
-		if err := s.chunkService.UpdateChunk(ctx, chunk); err != nil {
-			logger.Warnf(ctx, "Failed to update chunk %s: %v", chunk.ID, err)
-			continue
-		}
-
-		// Create index entries for generated questions
-		for _, gq := range generatedQuestions {
-			sourceID := fmt.Sprintf("%s-%s", chunk.ID, gq.ID)
-			indexInfoList = append(indexInfoList, &types.IndexInfo{
-				Content:         gq.Question,
-				SourceID:        sourceID,
-				SourceType:      types.ChunkSourceType,
-				ChunkID:         chunk.ID,
-				KnowledgeID:     knowledge.ID,
-				KnowledgeBaseID: knowledge.KnowledgeBaseID,
-			})
-		}
-		logger.Debugf(ctx, "Generated %d questions for chunk %s", len(questions), chunk.ID)
+	indexCount, err := s.rebuildKnowledgeIndex(ctx, kb, knowledge, tenantInfo.GetEffectiveEngines(), oldModel, newModel)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to index chunks with new embedding model: %v", err)
+		return nil, err
 	}
 
-	// Index generated questions
-	if len(indexInfoList) > 0 {
-		if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList); err != nil {
-			logger.Errorf(ctx, "Failed to index generated questions: %v", err)
-			return fmt.Errorf("failed to index questions: %w", err)
-		}
-		logger.Infof(ctx, "Successfully indexed %d generated questions for knowledge: %s", len(indexInfoList), payload.KnowledgeID)
+	knowledge.EmbeddingModelID = newModelID
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		logger.Errorf(ctx, "Failed to update knowledge embedding model: %v", err)
+		return nil, err
 	}
 
-	return nil
+	logger.Infof(ctx, "Knowledge %s re-embedded with model %s, %d index entries",
+		knowledge.ID, newModelID, indexCount)
+	return knowledge, nil
 }
 
-// generateQuestionsWithContext generates questions for a chunk with surrounding context
-func (s *knowledgeService) generateQuestionsWithContext(ctx context.Context,
-	chatModel chat.Chat, content, prevContent, nextContent, docName string, questionCount int,
-) ([]string, error) {
-	if content == "" || questionCount <= 0 {
-		return nil, nil
-	}
-
-	// Build prompt with context
-	prompt := s.config.Conversation.GenerateQuestionsPrompt
-	if prompt == "" {
-		prompt = defaultQuestionGenerationPrompt
+// rebuildKnowledgeIndex rebuilds the vector/keyword index of a knowledge item from its chunks in the database
+// with model, replacing the entries written with oldModel. All vectors are computed before the old entries
+// are removed, so an embedding failure leaves the existing index untouched; if writing the new entries
+// fails the old ones are restored on a best-effort basis. Returns the number of index entries written.
+func (s *knowledgeService) rebuildKnowledgeIndex(ctx context.Context,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, engines []types.RetrieverEngineParams,
+	oldModel, model embedding.Embedder,
+) (int, error) {
+	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, knowledge.ID)
+	if err != nil {
+		return 0, fmt.Errorf("list chunks: %w", err)
 	}
 
-	// Build context section
-	var contextSection string
-	if prevContent != "" || nextContent != "" {
-		contextSection = "## 上下文信息（仅供参考，帮助理解主要内容）\n"
-		if prevContent != "" {
-			contextSection += fmt.Sprintf("【前文】%s\n", prevContent)
-		}
-		if nextContent != "" {
-			contextSection += fmt.Sprintf("【后文】%s\n", nextContent)
-		}
-		contextSection += "\n"
+	indexInfoList, err := s.buildReembedIndexInfoList(ctx, kb, knowledge, chunks)
+	if err != nil {
+		return 0, err
 	}
 
-	// Replace placeholders
-	prompt = strings.ReplaceAll(prompt, "{{question_count}}", fmt.Sprintf("%d", questionCount))
-	prompt = strings.ReplaceAll(prompt, "{{content}}", content)
-	prompt = strings.ReplaceAll(prompt, "{{context}}", contextSection)
-	prompt = strings.ReplaceAll(prompt, "{{doc_name}}", docName)
-
-	thinking := false
-	response, err := chatModel.Chat(ctx, []chat.Message{
-		{
-			Role:    "user",
-			Content: prompt,
-		},
-	}, &chat.ChatOptions{
-		Temperature: 0.7,
-		MaxTokens:   512,
-		Thinking:    &thinking,
-	})
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, engines)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate questions: %w", err)
+		return 0, err
 	}
 
-	// Parse response
-	lines := strings.Split(response.Content, "\n")
-	questions := make([]string, 0, questionCount)
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	// Embed everything up front, the index writes below are then served from the cache
+	cached := newDedupEmbedder(model)
+	if retrieveEngine.SupportRetriever(types.VectorRetrieverType) && len(indexInfoList) > 0 {
+		contents := make([]string, len(indexInfoList))
+		for i, info := range indexInfoList {
+			contents[i] = info.Content
 		}
-		line = strings.TrimLeft(line, "0123456789.-*) ")
-		line = strings.TrimSpace(line)
-		if line != "" && len(line) > 5 {
-			questions = append(questions, line)
-			if len(questions) >= questionCount {
-				break
-			}
+		if _, err := cached.BatchEmbedWithPool(ctx, cached, contents); err != nil {
+			return 0, fmt.Errorf("embed chunks: %w", err)
 		}
 	}
 
-	return questions, nil
-}
-
-// Default prompt for question generation with context support
-const defaultQuestionGenerationPrompt = `你是一个专业的问题生成助手。你的任务是根据给定的【主要内容】生成用户可能会问的相关问题。
-
-{{context}}
-## 主要内容（请基于此内容生成问题）
-文档名称：{{doc_name}}
-文档内容：
-{{content}}
-
-## 核心要求
-- 生成的问题必须与【主要内容】直接相关
-- 问题中禁止使用任何代词或指代词（如"它"、"这个"、"该文档"、"本文"、"文中"、"其"等），必须用具体名称替代
-- 问题必须是完整独立的，脱离上下文也能被理解
-- 问题应该是用户在实际场景中可能会提出的自然问题
-- 问题应该多样化，覆盖内容的不同方面
-- 每个问题应该简洁明了，长度控制在30字以内
-- 生成的问题数量为 {{question_count}} 个
-
-## 问题类型建议
-- 定义类：什么是...？...是什么？
-- 原因类：为什么...？...的原因是什么？
-- 方法类：如何...？怎样...？
-- 比较类：...和...有什么区别？
-- 应用类：...可以用于什么场景？
+	if err := retrieveEngine.DeleteByKnowledgeIDList(
+		ctx, []string{knowledge.ID}, oldModel.GetDimensions(), knowledge.Type,
+	); err != nil {
+		return 0, fmt.Errorf("delete old index: %w", err)
+	}
+	if err := retrieveEngine.BatchIndex(ctx, cached, indexInfoList); err != nil {
+		if oldModel.GetModelID() != model.GetModelID() {
+			if cleanupErr := retrieveEngine.DeleteByKnowledgeIDList(
+				ctx, []string{knowledge.ID}, model.GetDimensions(), knowledge.Type,
+			); cleanupErr != nil {
+				logger.Warnf(ctx, "Failed to clean up partial index of knowledge %s: %v", knowledge.ID, cleanupErr)
+			}
+		}
+		if restoreErr := retrieveEngine.BatchIndex(ctx, oldModel, indexInfoList); restoreErr != nil {
+			logger.Errorf(ctx, "Failed to restore index of knowledge %s with model %s: %v",
+				knowledge.ID, oldModel.GetModelID(), restoreErr)
+		}
+		return 0, err
+	}
+	return len(indexInfoList), nil
+}
 
-## 输出格式
-直接输出问题列表，每行一个问题，不要有序号或其他前缀。`
+// ReindexKnowledge rebuilds the index of a knowledge item from the chunks stored in the database with its
+// current embedding model. It neither calls docreader nor touches the stored file, and is meant to recover
+// a vector store that was wiped or migrated while the chunk data is intact.
+func (s *knowledgeService) ReindexKnowledge(ctx context.Context, knowledgeID string) (*types.Knowledge, error) {
+	lock, err := s.lockKnowledge(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
 
-// GetKnowledgeFile retrieves the physical file associated with a knowledge entry
-func (s *knowledgeService) GetKnowledgeFile(ctx context.Context, id string) (io.ReadCloser, string, error) {
-	// Get knowledge record
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, id)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
 	if err != nil {
-		return nil, "", err
+		logger.Errorf(ctx, "Failed to load knowledge for reindex: %v", err)
+		return nil, err
+	}
+	if knowledge.ParseStatus != types.ParseStatusCompleted {
+		return nil, werrors.NewBadRequestError("仅支持对解析完成的知识重建索引")
 	}
 
-	// Get the file from storage
-	file, err := s.fileSvc.GetFile(ctx, knowledge.FilePath)
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, knowledge.KnowledgeBaseID)
 	if err != nil {
-		return nil, "", err
+		logger.Errorf(ctx, "Failed to get knowledge base for reindex: %v", err)
+		return nil, err
+	}
+	modelID := knowledge.EmbeddingModelID
+	if modelID == "" {
+		modelID = kb.EmbeddingModelID
+	}
+	model, err := s.modelService.GetEmbeddingModel(ctx, modelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get embedding model for reindex: %v", err)
+		return nil, werrors.NewBadRequestError("知识的嵌入模型不存在或不可用").WithDetails(err.Error())
 	}
 
-	return file, knowledge.FileName, nil
-}
-
-func (s *knowledgeService) UpdateKnowledge(ctx context.Context, knowledge *types.Knowledge) error {
-	record, err := s.repo.GetKnowledgeByID(ctx, ctx.Value(types.TenantIDContextKey).(uint64), knowledge.ID)
+	// 共享知识库的索引位于所有者租户的检索引擎中
+	tenantInfo, err := s.effectiveTenantInfo(ctx)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge record: %v", err)
-		return err
+		return nil, err
 	}
-	// if need other fields update, please add here
-	if knowledge.Title != "" {
-		record.Title = knowledge.Title
+	indexCount, err := s.rebuildKnowledgeIndex(ctx, kb, knowledge, tenantInfo.GetEffectiveEngines(), model, model)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to rebuild index of knowledge %s: %v", knowledge.ID, err)
+		return nil, err
 	}
-	if knowledge.FileName != "" {
-		safeFilename, isValid := secutils.ValidateInput(knowledge.FileName)
-		if !isValid {
-			return werrors.NewValidationError("文件名包含非法字符")
+
+	if knowledge.EmbeddingModelID != modelID {
+		knowledge.EmbeddingModelID = modelID
+		knowledge.UpdatedAt = time.Now()
+		if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+			logger.Errorf(ctx, "Failed to update knowledge embedding model after reindex: %v", err)
+			return nil, err
 		}
-		record.FileName = safeFilename
 	}
-	logger.Infof(ctx, "Safe filename: %s", knowledge.FileName)
 
-	// Update knowledge record in the repository
-	if err := s.repo.UpdateKnowledge(ctx, record); err != nil {
-		logger.Errorf(ctx, "Failed to update knowledge: %v", err)
-		return err
-	}
-	logger.Infof(ctx, "Knowledge updated successfully, ID: %s", knowledge.ID)
-	return nil
+	logger.Infof(ctx, "Knowledge %s reindexed with model %s, %d index entries", knowledge.ID, modelID, indexCount)
+	return knowledge, nil
 }
 
-// UpdateManualKnowledge updates manual Markdown knowledge content.
-func (s *knowledgeService) UpdateManualKnowledge(ctx context.Context,
-	knowledgeID string, payload *types.ManualKnowledgePayload,
-) (*types.Knowledge, error) {
-	logger.Info(ctx, "Start updating manual knowledge entry")
-	if payload == nil {
-		return nil, werrors.NewBadRequestError("请求内容不能为空")
+// buildReembedIndexInfoList 根据已有分块重建索引信息，分块的启用状态随索引信息一并写入
+func (s *knowledgeService) buildReembedIndexInfoList(ctx context.Context,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, chunks []*types.Chunk,
+) ([]*types.IndexInfo, error) {
+	indexInfoList := make([]*types.IndexInfo, 0, len(chunks))
+	for _, chunk := range chunks {
+		if knowledge.Type == types.KnowledgeTypeFAQ {
+			faqInfos, err := s.buildFAQIndexInfoList(ctx, kb, chunk)
+			if err != nil {
+				return nil, err
+			}
+			indexInfoList = append(indexInfoList, faqInfos...)
+		} else {
+			switch chunk.ChunkType {
+			case types.ChunkTypeEntity, types.ChunkTypeRelationship, types.ChunkTypeWebSearch:
+				// Graph and web search chunks are not stored in the vector index
+				continue
+			}
+			if !kb.ImageProcessingConfig.ShouldIndexChunk(chunk.ChunkType) ||
+				!kb.ChunkingConfig.ShouldIndexChunk(chunk.ChunkType, chunk.Content) {
+				continue
+			}
+			contentInfos, _ := buildChunkContentIndexInfoList(kb, chunk, knowledge.Title)
+			indexInfoList = append(indexInfoList, contentInfos...)
+			// Generated questions are indexed alongside their source chunk
+			indexInfoList = append(indexInfoList, buildGeneratedQuestionIndexInfoList(chunk)...)
+			indexInfoList = append(indexInfoList, buildChunkLinkIndexInfoList(kb, chunk)...)
+		}
 	}
+	return indexInfoList, nil
+}
 
-	cleanContent := secutils.CleanMarkdown(payload.Content)
-	if strings.TrimSpace(cleanContent) == "" {
-		return nil, werrors.NewValidationError("内容不能为空")
-	}
-	if len([]rune(cleanContent)) > manualContentMaxLength {
-		return nil, werrors.NewValidationError(fmt.Sprintf("内容长度超出限制（最多%d个字符）", manualContentMaxLength))
-	}
+const (
+	embeddingAlignProgressKeyPrefix = "embedding_align_progress:"
+	embeddingAlignProgressTTL       = 24 * time.Hour
+	embeddingAlignConcurrency       = 3
+)
 
-	safeTitle, ok := secutils.ValidateInput(payload.Title)
-	if !ok {
-		return nil, werrors.NewValidationError("标题包含非法字符或超出长度限制")
+// getEmbeddingAlignProgressKey returns the Redis key for storing embedding model alignment progress
+func getEmbeddingAlignProgressKey(taskID string) string {
+	return embeddingAlignProgressKeyPrefix + taskID
+}
+
+// misalignedEmbeddingKnowledge returns the parsed knowledge whose embedding model differs from modelID.
+// Knowledge still pending or processing is embedded with the knowledge base's model anyway, and failed
+// knowledge has no index to fix.
+func misalignedEmbeddingKnowledge(knowledgeList []*types.Knowledge, modelID string) []*types.Knowledge {
+	misaligned := make([]*types.Knowledge, 0)
+	for _, k := range knowledgeList {
+		if k.ParseStatus == types.ParseStatusCompleted && k.EmbeddingModelID != modelID {
+			misaligned = append(misaligned, k)
+		}
 	}
+	return misaligned
+}
 
-	status := strings.ToLower(strings.TrimSpace(payload.Status))
-	if status == "" {
-		status = types.ManualKnowledgeStatusDraft
+// AlignKnowledgeEmbeddingModels re-embeds the knowledge of a knowledge base whose EmbeddingModelID no
+// longer matches the knowledge base's, e.g. left behind by a model switch or manual edits. Mixing models
+// in one knowledge base silently breaks retrieval, as query vectors only match one dimension. Work runs
+// in an asynq task via reembedKnowledge; the returned progress can be polled with
+// GetEmbeddingModelAlignProgress.
+func (s *knowledgeService) AlignKnowledgeEmbeddingModels(ctx context.Context,
+	kbID string,
+) (*types.EmbeddingModelAlignProgress, error) {
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, err
 	}
-	if status != types.ManualKnowledgeStatusDraft && status != types.ManualKnowledgeStatusPublish {
-		return nil, werrors.NewValidationError("状态仅支持 draft 或 publish")
+	if kb.EmbeddingModelID == "" {
+		return nil, werrors.NewBadRequestError("知识库未配置嵌入模型")
+	}
+	if _, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID); err != nil {
+		logger.Errorf(ctx, "Failed to get embedding model of kb %s: %v", kbID, err)
+		return nil, werrors.NewBadRequestError("知识库的嵌入模型不存在或不可用").WithDetails(err.Error())
+	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
 	}
 
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	existing, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+	knowledgeList, err := s.repo.ListKnowledgeByKnowledgeBaseID(ctx, tenantID, kbID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to load knowledge: %v", err)
 		return nil, err
 	}
-	if !existing.IsManual() {
-		return nil, werrors.NewBadRequestError("仅支持手工知识的在线编辑")
+	misaligned := misalignedEmbeddingKnowledge(knowledgeList, kb.EmbeddingModelID)
+
+	now := time.Now().Unix()
+	progress := &types.EmbeddingModelAlignProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    secutils.GenerateTaskID("embedding_align", tenantID, kbID),
+			TenantID:  tenantID,
+			KBID:      kbID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			Total:     len(misaligned),
+			Message:   fmt.Sprintf("发现 %d 个嵌入模型不一致的知识，正在重新向量化...", len(misaligned)),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		EmbeddingModelID: kb.EmbeddingModelID,
+	}
+	if len(misaligned) == 0 {
+		progress.Status = types.KnowledgeTaskStatusCompleted
+		progress.Progress = 100
+		progress.Message = "所有知识的嵌入模型均与知识库一致"
+	}
+	if err := s.saveEmbeddingAlignProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save embedding align progress: %v", err)
 	}
 
-	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, existing.KnowledgeBaseID)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge base for manual update: %v", err)
-		return nil, err
+	logger.Infof(ctx, "Aligning knowledge embedding models: kb_id=%s, model=%s, misaligned=%d, task_id=%s",
+		kbID, kb.EmbeddingModelID, len(misaligned), progress.TaskID)
+	if len(misaligned) == 0 {
+		return progress, nil
 	}
 
-	var version int
-	if meta, err := existing.ManualMetadata(); err == nil && meta != nil {
-		version = meta.Version + 1
-	} else {
-		version = 1
+	payloadBytes, err := json.Marshal(types.EmbeddingAlignPayload{
+		TenantID: tenantID,
+		TaskID:   progress.TaskID,
+		KBID:     kbID,
+	})
+	if err == nil {
+		task := asynq.NewTask(types.TypeEmbeddingAlign, payloadBytes,
+			asynq.TaskID(progress.TaskID), asynq.Queue("low"), asynq.MaxRetry(3))
+		_, err = s.task.Enqueue(task)
+	}
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue embedding align task: %v", err)
+		progress.Status = types.KnowledgeTaskStatusFailed
+		progress.Message = "提交嵌入模型对齐任务失败"
+		progress.UpdatedAt = time.Now().Unix()
+		if saveErr := s.saveEmbeddingAlignProgress(ctx, progress); saveErr != nil {
+			logger.Warnf(ctx, "Failed to save embedding align progress: %v", saveErr)
+		}
+		return nil, fmt.Errorf("failed to enqueue embedding align task: %w", err)
 	}
+	return progress, nil
+}
 
-	meta := types.NewManualKnowledgeMetadata(cleanContent, status, version)
-	if err := existing.SetManualMetadata(meta); err != nil {
-		logger.Errorf(ctx, "Failed to set manual metadata during update: %v", err)
-		return nil, err
+// ProcessEmbeddingModelAlign handles the embedding model align task. The misaligned knowledge is listed
+// again when the task runs, and each is re-embedded with bounded concurrency, so the embedding model is
+// not flooded by a large knowledge base at once.
+func (s *knowledgeService) ProcessEmbeddingModelAlign(ctx context.Context, t *asynq.Task) error {
+	var payload types.EmbeddingAlignPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal embedding align payload: %v", err)
+		return nil
 	}
 
-	if safeTitle != "" {
-		existing.Title = safeTitle
-	} else if existing.Title == "" {
-		existing.Title = fmt.Sprintf("手工知识-%s", time.Now().Format("20060102-150405"))
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	isLastRetry := retryCount >= maxRetry
+
+	now := time.Now().Unix()
+	progress := &types.EmbeddingModelAlignProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    payload.TaskID,
+			TenantID:  payload.TenantID,
+			KBID:      payload.KBID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	var previous types.EmbeddingModelAlignProgress
+	if err := s.loadTaskProgress(ctx, getEmbeddingAlignProgressKey(payload.TaskID), &previous); err == nil {
+		progress.CreatedAt = previous.CreatedAt
+	}
+	handleError := func(err error) error {
+		if isLastRetry {
+			progress.Status = types.KnowledgeTaskStatusFailed
+			progress.Message = fmt.Sprintf("嵌入模型对齐失败: %v", err)
+			progress.UpdatedAt = time.Now().Unix()
+			if saveErr := s.saveEmbeddingAlignProgress(ctx, progress); saveErr != nil {
+				logger.Warnf(ctx, "Failed to save embedding align progress: %v", saveErr)
+			}
+		}
+		return err
 	}
-	existing.FileName = ensureManualFileName(existing.Title)
-	existing.FileType = types.KnowledgeTypeManual
-	existing.Type = types.KnowledgeTypeManual
-	existing.Source = types.KnowledgeTypeManual
-	existing.EnableStatus = "disabled"
-	existing.UpdatedAt = time.Now()
 
-	if err := s.cleanupKnowledgeResources(ctx, existing); err != nil {
-		logger.ErrorWithFields(ctx, err, map[string]interface{}{
-			"knowledge_id": knowledgeID,
-		})
-		return nil, err
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get tenant %d: %v", payload.TenantID, err)
+		return handleError(err)
 	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
 
-	existing.EmbeddingModelID = kb.EmbeddingModelID
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KBID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base %s: %v", payload.KBID, err)
+		return handleError(err)
+	}
+	knowledgeList, err := s.repo.ListKnowledgeByKnowledgeBaseID(ctx, payload.TenantID, payload.KBID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list knowledge for embedding align: %v", err)
+		return handleError(err)
+	}
+	modelID := kb.EmbeddingModelID
+	misaligned := misalignedEmbeddingKnowledge(knowledgeList, modelID)
+	progress.EmbeddingModelID = modelID
+	progress.Total = len(misaligned)
+	progress.Message = fmt.Sprintf("发现 %d 个嵌入模型不一致的知识，正在重新向量化...", len(misaligned))
+
+	var mu sync.Mutex
+	record := func(knowledgeID string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Processed++
+		if err != nil {
+			progress.Failed++
+			if progress.FailedKnowledge == nil {
+				progress.FailedKnowledge = make(map[string]string)
+			}
+			progress.FailedKnowledge[knowledgeID] = err.Error()
+		} else {
+			progress.Reembedded++
+		}
+		progress.Progress = progress.Processed * 100 / progress.Total
+		progress.UpdatedAt = time.Now().Unix()
+		if err := s.saveEmbeddingAlignProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to update embedding align progress: %v", err)
+		}
+	}
 
-	if status == types.ManualKnowledgeStatusDraft {
-		existing.ParseStatus = types.ManualKnowledgeStatusDraft
-		existing.Description = ""
-		existing.ProcessedAt = nil
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(embeddingAlignConcurrency)
+	for _, k := range misaligned {
+		g.Go(func() error {
+			_, err := s.reembedKnowledge(gctx, k.ID, modelID)
+			if err != nil {
+				logger.Errorf(gctx, "Failed to re-embed knowledge %s from model %s to %s: %v",
+					k.ID, k.EmbeddingModelID, modelID, err)
+			}
+			record(k.ID, err)
+			return nil
+		})
+	}
+	_ = g.Wait()
 
-		if err := s.repo.UpdateKnowledge(ctx, existing); err != nil {
-			logger.Errorf(ctx, "Failed to persist manual draft: %v", err)
-			return nil, err
-		}
-		return existing, nil
+	progress.Status = types.KnowledgeTaskStatusCompleted
+	progress.Progress = 100
+	progress.Message = fmt.Sprintf("%d 个嵌入模型不一致的知识中，已重新向量化 %d 个，失败 %d 个",
+		progress.Total, progress.Reembedded, progress.Failed)
+	progress.UpdatedAt = time.Now().Unix()
+	if err := s.saveEmbeddingAlignProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save final embedding align progress: %v", err)
 	}
+	logger.Infof(ctx, "Embedding model alignment finished: task_id=%s, misaligned=%d, reembedded=%d, failed=%d",
+		progress.TaskID, progress.Total, progress.Reembedded, progress.Failed)
+	return nil
+}
 
-	existing.ParseStatus = "pending"
-	existing.Description = ""
-	existing.ProcessedAt = nil
+// saveEmbeddingAlignProgress saves the embedding model alignment progress to Redis
+func (s *knowledgeService) saveEmbeddingAlignProgress(ctx context.Context,
+	progress *types.EmbeddingModelAlignProgress,
+) error {
+	return s.saveTaskProgress(ctx, getEmbeddingAlignProgressKey(progress.TaskID), progress, embeddingAlignProgressTTL)
+}
 
-	if err := s.repo.UpdateKnowledge(ctx, existing); err != nil {
-		logger.Errorf(ctx, "Failed to persist manual knowledge before indexing: %v", err)
+// GetEmbeddingModelAlignProgress retrieves the progress of an embedding model alignment task
+func (s *knowledgeService) GetEmbeddingModelAlignProgress(ctx context.Context,
+	taskID string,
+) (*types.EmbeddingModelAlignProgress, error) {
+	var progress types.EmbeddingModelAlignProgress
+	if err := s.loadTaskProgress(ctx, getEmbeddingAlignProgressKey(taskID), &progress); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Embedding model alignment task not found")
+		}
 		return nil, err
 	}
-
-	logger.Infof(ctx, "Manual knowledge updated, scheduling indexing, ID: %s", existing.ID)
-	s.triggerManualProcessing(ctx, kb, existing, cleanContent, false)
-	return existing, nil
+	return &progress, nil
 }
 
-// ReparseKnowledge deletes existing document content and re-parses the knowledge asynchronously.
-// This method reuses the logic from UpdateManualKnowledge for resource cleanup and async parsing.
-func (s *knowledgeService) ReparseKnowledge(ctx context.Context, knowledgeID string) (*types.Knowledge, error) {
-	logger.Info(ctx, "Start re-parsing knowledge")
+const (
+	knowledgeReindexProgressKeyPrefix = "knowledge_reindex_progress:"
+	knowledgeReindexProgressTTL       = 24 * time.Hour
+	knowledgeReindexConcurrency       = 3
+)
 
-	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	existing, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+// getKnowledgeReindexProgressKey returns the Redis key for storing knowledge base reindex progress
+func getKnowledgeReindexProgressKey(taskID string) string {
+	return knowledgeReindexProgressKeyPrefix + taskID
+}
+
+// ReindexKnowledgeBase rebuilds the index of every parsed knowledge in a knowledge base from its stored
+// chunks, see ReindexKnowledge. Work runs in an asynq task; the returned progress can be polled with
+// GetKnowledgeReindexProgress.
+func (s *knowledgeService) ReindexKnowledgeBase(ctx context.Context,
+	kbID string,
+) (*types.KnowledgeReindexProgress, error) {
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to load knowledge: %v", err)
+		return nil, err
+	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
 		return nil, err
 	}
 
-	// Get knowledge base configuration
-	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, existing.KnowledgeBaseID)
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	completed, err := s.listCompletedKnowledge(ctx, tenantID, kbID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge base for reparse: %v", err)
 		return nil, err
 	}
 
-	// Step 1: Clean up existing resources (chunks, embeddings, graph data)
-	logger.Infof(ctx, "Cleaning up existing resources for knowledge: %s", knowledgeID)
-	if err := s.cleanupKnowledgeResources(ctx, existing); err != nil {
-		logger.ErrorWithFields(ctx, err, map[string]interface{}{
-			"knowledge_id": knowledgeID,
-		})
-		return nil, err
+	now := time.Now().Unix()
+	progress := &types.KnowledgeReindexProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    secutils.GenerateTaskID("knowledge_reindex", tenantID, kbID),
+			TenantID:  tenantID,
+			KBID:      kbID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			Total:     len(completed),
+			Message:   fmt.Sprintf("正在为 %d 个知识重建索引...", len(completed)),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	if len(completed) == 0 {
+		progress.Status = types.KnowledgeTaskStatusCompleted
+		progress.Progress = 100
+		progress.Message = "没有解析完成的知识需要重建索引"
+	}
+	if err := s.saveKnowledgeReindexProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save knowledge reindex progress: %v", err)
 	}
 
-	// Step 2: Update knowledge status and metadata
-	existing.ParseStatus = "pending"
-	existing.EnableStatus = "disabled"
-	existing.Description = ""
-	existing.ProcessedAt = nil
-	existing.EmbeddingModelID = kb.EmbeddingModelID
-	existing.ErrorMessage = ""
+	logger.Infof(ctx, "Reindexing knowledge base: kb_id=%s, knowledge=%d, task_id=%s",
+		kbID, len(completed), progress.TaskID)
+	if len(completed) == 0 {
+		return progress, nil
+	}
+
+	payloadBytes, err := json.Marshal(types.KnowledgeReindexPayload{
+		TenantID: tenantID,
+		TaskID:   progress.TaskID,
+		KBID:     kbID,
+	})
+	if err == nil {
+		task := asynq.NewTask(types.TypeKnowledgeReindex, payloadBytes,
+			asynq.TaskID(progress.TaskID), asynq.Queue("low"), asynq.MaxRetry(3))
+		_, err = s.task.Enqueue(task)
+	}
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue knowledge reindex task: %v", err)
+		progress.Status = types.KnowledgeTaskStatusFailed
+		progress.Message = "提交重建索引任务失败"
+		progress.UpdatedAt = time.Now().Unix()
+		if saveErr := s.saveKnowledgeReindexProgress(ctx, progress); saveErr != nil {
+			logger.Warnf(ctx, "Failed to save knowledge reindex progress: %v", saveErr)
+		}
+		return nil, fmt.Errorf("failed to enqueue reindex task: %w", err)
+	}
+	return progress, nil
+}
 
-	if err := s.repo.UpdateKnowledge(ctx, existing); err != nil {
-		logger.Errorf(ctx, "Failed to update knowledge status before reparse: %v", err)
+// listCompletedKnowledge returns the knowledge of a knowledge base whose parsing has completed
+func (s *knowledgeService) listCompletedKnowledge(ctx context.Context,
+	tenantID uint64, kbID string,
+) ([]*types.Knowledge, error) {
+	knowledgeList, err := s.repo.ListKnowledgeByKnowledgeBaseID(ctx, tenantID, kbID)
+	if err != nil {
 		return nil, err
 	}
-
-	// Step 3: Trigger async re-parsing based on knowledge type
-	logger.Infof(ctx, "Knowledge status updated, scheduling async reparse, ID: %s, Type: %s", existing.ID, existing.Type)
-
-	// For manual knowledge, extract content from metadata and trigger manual processing
-	if existing.IsManual() {
-		meta, err := existing.ManualMetadata()
-		if err != nil || meta == nil {
-			logger.Errorf(ctx, "Failed to get manual metadata for reparse: %v", err)
-			return nil, werrors.NewBadRequestError("无法获取手工知识内容")
+	completed := make([]*types.Knowledge, 0, len(knowledgeList))
+	for _, k := range knowledgeList {
+		if k.ParseStatus == types.ParseStatusCompleted {
+			completed = append(completed, k)
 		}
-		s.triggerManualProcessing(ctx, kb, existing, meta.Content, false)
-		return existing, nil
 	}
+	return completed, nil
+}
 
-	// For file-based knowledge, enqueue document processing task
-	if existing.FilePath != "" {
-		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+// ProcessKnowledgeReindex handles the knowledge base reindex task. Each knowledge is reindexed with
+// bounded concurrency, so the embedding model is not flooded by a large knowledge base at once.
+func (s *knowledgeService) ProcessKnowledgeReindex(ctx context.Context, t *asynq.Task) error {
+	var payload types.KnowledgeReindexPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal knowledge reindex payload: %v", err)
+		return nil
+	}
 
-		// Determine multimodal setting
-		enableMultimodel := kb.IsMultimodalEnabled()
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	isLastRetry := retryCount >= maxRetry
 
-		// Check question generation config
-		enableQuestionGeneration := false
-		questionCount := 3 // default
-		if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
-			enableQuestionGeneration = true
-			if kb.QuestionGenerationConfig.QuestionCount > 0 {
-				questionCount = kb.QuestionGenerationConfig.QuestionCount
+	now := time.Now().Unix()
+	progress := &types.KnowledgeReindexProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    payload.TaskID,
+			TenantID:  payload.TenantID,
+			KBID:      payload.KBID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	if previous, err := s.loadKnowledgeReindexProgress(ctx, payload.TaskID); err == nil {
+		progress.CreatedAt = previous.CreatedAt
+	}
+	handleError := func(err error) error {
+		if isLastRetry {
+			progress.Status = types.KnowledgeTaskStatusFailed
+			progress.Message = fmt.Sprintf("重建索引失败: %v", err)
+			progress.UpdatedAt = time.Now().Unix()
+			if saveErr := s.saveKnowledgeReindexProgress(ctx, progress); saveErr != nil {
+				logger.Warnf(ctx, "Failed to save knowledge reindex progress: %v", saveErr)
 			}
 		}
+		return err
+	}
 
-		taskPayload := types.DocumentProcessPayload{
-			TenantID:                 tenantID,
-			KnowledgeID:              existing.ID,
-			KnowledgeBaseID:          existing.KnowledgeBaseID,
-			FilePath:                 existing.FilePath,
-			FileName:                 existing.FileName,
-			FileType:                 getFileType(existing.FileName),
-			EnableMultimodel:         enableMultimodel,
-			EnableQuestionGeneration: enableQuestionGeneration,
-			QuestionCount:            questionCount,
-		}
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get tenant %d: %v", payload.TenantID, err)
+		return handleError(err)
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
 
-		payloadBytes, err := json.Marshal(taskPayload)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to marshal reparse task payload: %v", err)
-			return existing, nil
-		}
+	knowledgeList, err := s.listCompletedKnowledge(ctx, payload.TenantID, payload.KBID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list knowledge for reindex: %v", err)
+		return handleError(err)
+	}
+	progress.Total = len(knowledgeList)
+	progress.Message = fmt.Sprintf("正在为 %d 个知识重建索引...", len(knowledgeList))
 
-		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
-		info, err := s.task.Enqueue(task)
+	var mu sync.Mutex
+	record := func(knowledgeID string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Processed++
 		if err != nil {
-			logger.Errorf(ctx, "Failed to enqueue reparse task: %v", err)
-			return existing, nil
+			progress.Failed++
+			if progress.FailedKnowledge == nil {
+				progress.FailedKnowledge = make(map[string]string)
+			}
+			progress.FailedKnowledge[knowledgeID] = err.Error()
+		} else {
+			progress.Reindexed++
 		}
-		logger.Infof(ctx, "Enqueued reparse task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, existing.ID)
-
-		// For data tables (csv, xlsx, xls), also enqueue summary task
-		if slices.Contains([]string{"csv", "xlsx", "xls"}, getFileType(existing.FileName)) {
-			NewDataTableSummaryTask(ctx, s.task, tenantID, existing.ID, kb.SummaryModelID, kb.EmbeddingModelID)
+		progress.Progress = progress.Processed * 100 / progress.Total
+		progress.UpdatedAt = time.Now().Unix()
+		if err := s.saveKnowledgeReindexProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to update knowledge reindex progress: %v", err)
 		}
-
-		return existing, nil
 	}
 
-	// For file-URL-based knowledge, enqueue document processing task with FileURL field
-	if existing.Type == "file_url" && existing.Source != "" {
-		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-
-		enableMultimodel := kb.IsMultimodalEnabled()
-
-		// Check question generation config
-		enableQuestionGeneration := false
-		questionCount := 3
-		if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
-			enableQuestionGeneration = true
-			if kb.QuestionGenerationConfig.QuestionCount > 0 {
-				questionCount = kb.QuestionGenerationConfig.QuestionCount
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(knowledgeReindexConcurrency)
+	for _, k := range knowledgeList {
+		g.Go(func() error {
+			_, err := s.ReindexKnowledge(gctx, k.ID)
+			if err != nil {
+				logger.Errorf(gctx, "Failed to reindex knowledge %s: %v", k.ID, err)
 			}
-		}
+			record(k.ID, err)
+			return nil
+		})
+	}
+	_ = g.Wait()
 
-		taskPayload := types.DocumentProcessPayload{
-			TenantID:                 tenantID,
-			KnowledgeID:              existing.ID,
-			KnowledgeBaseID:          existing.KnowledgeBaseID,
-			FileURL:                  existing.Source,
-			FileName:                 existing.FileName,
-			FileType:                 existing.FileType,
-			EnableMultimodel:         enableMultimodel,
-			EnableQuestionGeneration: enableQuestionGeneration,
-			QuestionCount:            questionCount,
-		}
+	progress.Status = types.KnowledgeTaskStatusCompleted
+	progress.Progress = 100
+	progress.Message = fmt.Sprintf("%d 个知识中，已重建索引 %d 个，失败 %d 个",
+		progress.Total, progress.Reindexed, progress.Failed)
+	progress.UpdatedAt = time.Now().Unix()
+	if err := s.saveKnowledgeReindexProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save final knowledge reindex progress: %v", err)
+	}
+	logger.Infof(ctx, "Knowledge base reindex finished: task_id=%s, total=%d, reindexed=%d, failed=%d",
+		progress.TaskID, progress.Total, progress.Reindexed, progress.Failed)
+	return nil
+}
 
-		payloadBytes, err := json.Marshal(taskPayload)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to marshal file URL reparse task payload: %v", err)
-			return existing, nil
-		}
+// saveKnowledgeReindexProgress saves the knowledge base reindex progress to Redis
+func (s *knowledgeService) saveKnowledgeReindexProgress(ctx context.Context,
+	progress *types.KnowledgeReindexProgress,
+) error {
+	return s.saveTaskProgress(ctx, getKnowledgeReindexProgressKey(progress.TaskID), progress,
+		knowledgeReindexProgressTTL)
+}
 
-		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
-		info, err := s.task.Enqueue(task)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to enqueue file URL reparse task: %v", err)
-			return existing, nil
+// GetKnowledgeReindexProgress retrieves the progress of a knowledge base reindex task
+func (s *knowledgeService) GetKnowledgeReindexProgress(ctx context.Context,
+	taskID string,
+) (*types.KnowledgeReindexProgress, error) {
+	progress, err := s.loadKnowledgeReindexProgress(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Knowledge reindex task not found")
 		}
-		logger.Infof(ctx, "Enqueued file URL reparse task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, existing.ID)
+		return nil, err
+	}
+	return progress, nil
+}
 
-		return existing, nil
+// loadKnowledgeReindexProgress reads the knowledge base reindex progress from Redis
+func (s *knowledgeService) loadKnowledgeReindexProgress(ctx context.Context,
+	taskID string,
+) (*types.KnowledgeReindexProgress, error) {
+	var progress types.KnowledgeReindexProgress
+	if err := s.loadTaskProgress(ctx, getKnowledgeReindexProgressKey(taskID), &progress); err != nil {
+		return nil, err
 	}
+	return &progress, nil
+}
 
-	// For URL-based knowledge, enqueue URL processing task
-	if existing.Type == "url" && existing.Source != "" {
-		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+// processingQueueNames are the asynq queues served by the worker (see router.NewAsynqServer)
+var processingQueueNames = []string{"critical", "default", "low"}
 
-		enableMultimodel := kb.IsMultimodalEnabled()
+// processingTaskTypes are the knowledge processing task types counted separately in queue stats
+var processingTaskTypes = []string{
+	types.TypeDocumentProcess,
+	types.TypeFAQImport,
+	types.TypeSummaryGeneration,
+	types.TypeQuestionGeneration,
+}
 
-		// Check question generation config
-		enableQuestionGeneration := false
-		questionCount := 3
-		if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
-			enableQuestionGeneration = true
-			if kb.QuestionGenerationConfig.QuestionCount > 0 {
-				questionCount = kb.QuestionGenerationConfig.QuestionCount
-			}
-		}
+const (
+	processingQueueStatsPageSize = 500
+	// processingQueueStatsMaxTasks bounds how many tasks of one state are scanned per queue when counting by type
+	processingQueueStatsMaxTasks = 10000
+)
 
-		taskPayload := types.DocumentProcessPayload{
-			TenantID:                 tenantID,
-			KnowledgeID:              existing.ID,
-			KnowledgeBaseID:          existing.KnowledgeBaseID,
-			URL:                      existing.Source,
-			EnableMultimodel:         enableMultimodel,
-			EnableQuestionGeneration: enableQuestionGeneration,
-			QuestionCount:            questionCount,
+// GetProcessingQueueStats returns the backlog of the asynq queues with per task type counts of the
+// knowledge processing tasks, so dashboards can alert on stuck or growing queues
+func (s *knowledgeService) GetProcessingQueueStats(ctx context.Context) (*types.ProcessingQueueStats, error) {
+	if s.taskInspector == nil {
+		return nil, werrors.NewInternalServerError("任务队列检查器未初始化")
+	}
+	queues, err := s.taskInspector.Queues()
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list asynq queues: %v", err)
+		return nil, err
+	}
+
+	stats := &types.ProcessingQueueStats{
+		Queues:      make([]*types.ProcessingQueueStat, 0, len(processingQueueNames)),
+		GeneratedAt: time.Now().Unix(),
+	}
+	for _, queue := range processingQueueNames {
+		queueStat := &types.ProcessingQueueStat{Queue: queue}
+		byType := make(map[string]*types.ProcessingTaskTypeStat, len(processingTaskTypes))
+		for _, taskType := range processingTaskTypes {
+			typeStat := &types.ProcessingTaskTypeStat{TaskType: taskType}
+			queueStat.TaskTypes = append(queueStat.TaskTypes, typeStat)
+			byType[taskType] = typeStat
+		}
+		stats.Queues = append(stats.Queues, queueStat)
+		// A queue is only created in Redis once a task was enqueued to it
+		if !slices.Contains(queues, queue) {
+			continue
 		}
 
-		payloadBytes, err := json.Marshal(taskPayload)
+		info, err := s.taskInspector.GetQueueInfo(queue)
 		if err != nil {
-			logger.Errorf(ctx, "Failed to marshal URL reparse task payload: %v", err)
-			return existing, nil
+			logger.Errorf(ctx, "Failed to get info of asynq queue %s: %v", queue, err)
+			return nil, err
+		}
+		queueStat.Paused = info.Paused
+		queueStat.Pending = info.Pending
+		queueStat.Active = info.Active
+		queueStat.Retry = info.Retry
+		queueStat.Dead = info.Archived
+		// asynq measures the queue latency by the oldest pending task
+		queueStat.OldestPendingAgeSeconds = info.Latency.Seconds()
+
+		counters := []struct {
+			list func(string, ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+			inc  func(*types.ProcessingTaskTypeStat)
+		}{
+			{s.taskInspector.ListPendingTasks, func(st *types.ProcessingTaskTypeStat) { st.Pending++ }},
+			{s.taskInspector.ListActiveTasks, func(st *types.ProcessingTaskTypeStat) { st.Active++ }},
+			{s.taskInspector.ListRetryTasks, func(st *types.ProcessingTaskTypeStat) { st.Retry++ }},
+			{s.taskInspector.ListArchivedTasks, func(st *types.ProcessingTaskTypeStat) { st.Dead++ }},
+		}
+		for _, counter := range counters {
+			complete, err := countQueueTasksByType(queue, counter.list, func(task *asynq.TaskInfo) {
+				if typeStat, ok := byType[task.Type]; ok {
+					counter.inc(typeStat)
+				}
+			})
+			if err != nil {
+				logger.Errorf(ctx, "Failed to list tasks of asynq queue %s: %v", queue, err)
+				return nil, err
+			}
+			if !complete {
+				queueStat.Truncated = true
+			}
 		}
+	}
+	return stats, nil
+}
 
-		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
-		info, err := s.task.Enqueue(task)
+// countQueueTasksByType pages through the tasks returned by list and passes each one to visit. It stops after
+// processingQueueStatsMaxTasks tasks and reports whether all tasks were visited.
+func countQueueTasksByType(queue string,
+	list func(string, ...asynq.ListOption) ([]*asynq.TaskInfo, error), visit func(*asynq.TaskInfo),
+) (bool, error) {
+	for page := 1; (page-1)*processingQueueStatsPageSize < processingQueueStatsMaxTasks; page++ {
+		tasks, err := list(queue, asynq.PageSize(processingQueueStatsPageSize), asynq.Page(page))
 		if err != nil {
-			logger.Errorf(ctx, "Failed to enqueue URL reparse task: %v", err)
-			return existing, nil
+			if errors.Is(err, asynq.ErrQueueNotFound) {
+				return true, nil
+			}
+			return false, err
+		}
+		for _, task := range tasks {
+			visit(task)
+		}
+		if len(tasks) < processingQueueStatsPageSize {
+			return true, nil
 		}
-		logger.Infof(ctx, "Enqueued URL reparse task: id=%s queue=%s knowledge_id=%s", info.ID, info.Queue, existing.ID)
-
-		return existing, nil
 	}
+	return false, nil
+}
 
-	logger.Warnf(ctx, "Knowledge %s has no parseable content (no file, URL, or manual content)", knowledgeID)
-	return existing, nil
+// supportsMixedDimensions reports whether all vector engines can store embeddings of
+// different dimensions side by side (postgres keeps a dimension column, qdrant uses
+// one collection per dimension).
+func supportsMixedDimensions(engines []types.RetrieverEngineParams) bool {
+	for _, engine := range engines {
+		if engine.RetrieverType != types.VectorRetrieverType {
+			continue
+		}
+		switch engine.RetrieverEngineType {
+		case types.PostgresRetrieverEngineType, types.QdrantRetrieverEngineType:
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 // isValidFileType checks if a file type is supported
 func isValidFileType(filename string) bool {
 	switch strings.ToLower(getFileType(filename)) {
-	case "pdf", "txt", "docx", "doc", "md", "markdown", "png", "jpg", "jpeg", "gif", "csv", "xlsx", "xls":
+	case "pdf", "txt", "docx", "doc", "md", "markdown", "png", "jpg", "jpeg", "gif", "csv", "xlsx", "xls",
+		"pptx", "ppt", "epub":
 		return true
 	default:
 		return false
@@ -2667,6 +7316,76 @@ func isValidURL(url string) bool {
 	return false
 }
 
+// checkURLImportAllowList checks the URL against the configured scheme/port/domain allow-list.
+// Empty lists impose no restriction, keeping the default http/https behavior.
+func (s *knowledgeService) checkURLImportAllowList(rawURL string) (bool, string) {
+	if s.config == nil || s.config.KnowledgeBase == nil || s.config.KnowledgeBase.URLImport == nil {
+		return true, ""
+	}
+	allowList := s.config.KnowledgeBase.URLImport
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, "failed to parse URL"
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+
+	if len(allowList.AllowedSchemes) > 0 {
+		allowed := false
+		for _, sc := range allowList.AllowedSchemes {
+			if strings.EqualFold(strings.TrimSpace(sc), scheme) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, fmt.Sprintf("scheme %q is not in allow-list", scheme)
+		}
+	}
+
+	if len(allowList.AllowedPorts) > 0 {
+		port := u.Port()
+		if port == "" {
+			switch scheme {
+			case "http":
+				port = "80"
+			case "https":
+				port = "443"
+			}
+		}
+		allowed := false
+		for _, p := range allowList.AllowedPorts {
+			if fmt.Sprintf("%d", p) == port {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, fmt.Sprintf("port %q is not in allow-list", port)
+		}
+	}
+
+	if len(allowList.AllowedDomains) > 0 {
+		allowed := false
+		for _, d := range allowList.AllowedDomains {
+			d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "*.")
+			if d == "" {
+				continue
+			}
+			if host == d || strings.HasSuffix(host, "."+d) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, fmt.Sprintf("domain %q is not in allow-list", host)
+		}
+	}
+
+	return true, ""
+}
+
 // GetKnowledgeBatch retrieves multiple knowledge entries by their IDs
 func (s *knowledgeService) GetKnowledgeBatch(ctx context.Context,
 	tenantID uint64, ids []string,
@@ -2703,16 +7422,45 @@ func (s *knowledgeService) GetKnowledgeBatchWithSharedAccess(ctx context.Context
 	if !ok || userID == "" {
 		return ownList, nil
 	}
+	missingIDs := make([]string, 0, len(ids))
 	for _, id := range ids {
-		if foundSet[id] {
-			continue
+		if !foundSet[id] {
+			missingIDs = append(missingIDs, id)
 		}
-		k, err := s.repo.GetKnowledgeByIDOnly(ctx, id)
-		if err != nil || k == nil || k.KnowledgeBaseID == "" {
+	}
+	if len(missingIDs) == 0 {
+		return ownList, nil
+	}
+	// Resolve the remaining IDs and their KB permissions in batch instead of per ID
+	others, err := s.repo.GetKnowledgeByIDsOnly(ctx, missingIDs)
+	if err != nil || len(others) == 0 {
+		return ownList, nil
+	}
+	kbIDSet := make(map[string]struct{})
+	kbIDs := make([]string, 0)
+	for _, k := range others {
+		if k == nil || k.KnowledgeBaseID == "" {
 			continue
 		}
-		hasPermission, err := s.kbShareService.HasKBPermission(ctx, k.KnowledgeBaseID, userID, types.OrgRoleViewer)
-		if err != nil || !hasPermission {
+		if _, ok := kbIDSet[k.KnowledgeBaseID]; !ok {
+			kbIDSet[k.KnowledgeBaseID] = struct{}{}
+			kbIDs = append(kbIDs, k.KnowledgeBaseID)
+		}
+	}
+	permissions, err := s.kbShareService.HasKBPermissionBatch(ctx, kbIDs, userID, types.OrgRoleViewer)
+	if err != nil {
+		return ownList, nil
+	}
+	otherByID := make(map[string]*types.Knowledge, len(others))
+	for _, k := range others {
+		if k != nil {
+			otherByID[k.ID] = k
+		}
+	}
+	// Keep the requested order for shared items
+	for _, id := range missingIDs {
+		k, ok := otherByID[id]
+		if !ok || k.KnowledgeBaseID == "" || foundSet[k.ID] || !permissions[k.KnowledgeBaseID] {
 			continue
 		}
 		foundSet[k.ID] = true
@@ -2769,13 +7517,25 @@ func (s *knowledgeService) CloneKnowledgeBase(ctx context.Context, srcID, dstID
 	}
 	logger.Infof(ctx, "Knowledge after update to add: %d, delete: %d", len(addKnowledge), len(delKnowledge))
 
-	batch := 10
+	err = s.deleteCloneStaleKnowledge(ctx, delKnowledge, nil)
+	if err != nil {
+		logger.Errorf(ctx, "delete total knowledge %d: %v", len(delKnowledge), err)
+		return err
+	}
+
+	// Copy context out of auto-stop task
 	g, gctx := errgroup.WithContext(ctx)
-	for ids := range slices.Chunk(delKnowledge, batch) {
+	g.SetLimit(s.cloneAddConcurrency())
+	for _, knowledge := range addKnowledge {
 		g.Go(func() error {
-			err := s.DeleteKnowledgeList(gctx, ids)
+			srcKn, err := s.repo.GetKnowledgeByID(gctx, srcKB.TenantID, knowledge)
+			if err != nil {
+				logger.Errorf(gctx, "get knowledge %s: %v", knowledge, err)
+				return err
+			}
+			err = s.cloneKnowledge(gctx, srcKn, dstKB, types.DefaultCloneIncludeDisabled, types.CloneEnableStatusPreserve)
 			if err != nil {
-				logger.Errorf(gctx, "delete partial knowledge %v: %w", ids, err)
+				logger.Errorf(gctx, "clone knowledge %s: %v", knowledge, err)
 				return err
 			}
 			return nil
@@ -2783,34 +7543,149 @@ func (s *knowledgeService) CloneKnowledgeBase(ctx context.Context, srcID, dstID
 	}
 	err = g.Wait()
 	if err != nil {
-		logger.Errorf(ctx, "delete total knowledge %d: %v", len(delKnowledge), err)
+		logger.Errorf(ctx, "add total knowledge %d: %v", len(addKnowledge), err)
 		return err
 	}
+	return nil
+}
 
-	// Copy context out of auto-stop task
-	g, gctx = errgroup.WithContext(ctx)
-	g.SetLimit(batch)
-	for _, knowledge := range addKnowledge {
+const (
+	// cloneDeleteBatchSize 复制知识库时每次删除的知识数
+	cloneDeleteBatchSize = 10
+	// defaultCloneDeleteConcurrency 未配置时删除阶段同时执行的批次数
+	defaultCloneDeleteConcurrency = 10
+	// defaultCloneAddConcurrency 未配置时复制阶段同时复制的知识数
+	defaultCloneAddConcurrency = 10
+)
+
+// cloneDeleteConcurrency returns the number of delete batches run at the same time when cloning a knowledge base
+func (s *knowledgeService) cloneDeleteConcurrency() int {
+	if s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.CloneDeleteConcurrency > 0 {
+		return s.config.KnowledgeBase.CloneDeleteConcurrency
+	}
+	return defaultCloneDeleteConcurrency
+}
+
+// cloneAddConcurrency returns the number of knowledge cloned (and re-embedded) at the same time
+// when cloning a knowledge base
+func (s *knowledgeService) cloneAddConcurrency() int {
+	if s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.CloneAddConcurrency > 0 {
+		return s.config.KnowledgeBase.CloneAddConcurrency
+	}
+	return defaultCloneAddConcurrency
+}
+
+// deleteCloneStaleKnowledge deletes the target knowledge that no longer exists in the clone source,
+// in batches of cloneDeleteBatchSize with at most cloneDeleteConcurrency batches at a time.
+// onBatchDone, if set, is called concurrently with the size of every deleted batch.
+func (s *knowledgeService) deleteCloneStaleKnowledge(ctx context.Context,
+	knowledgeIDs []string, onBatchDone func(deleted int),
+) error {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.cloneDeleteConcurrency())
+	for ids := range slices.Chunk(knowledgeIDs, cloneDeleteBatchSize) {
 		g.Go(func() error {
-			srcKn, err := s.repo.GetKnowledgeByID(gctx, srcKB.TenantID, knowledge)
+			err := s.DeleteKnowledgeList(gctx, ids)
 			if err != nil {
-				logger.Errorf(gctx, "get knowledge %s: %w", knowledge, err)
+				logger.Errorf(gctx, "delete partial knowledge %v: %v", ids, err)
 				return err
 			}
-			err = s.cloneKnowledge(gctx, srcKn, dstKB)
-			if err != nil {
-				logger.Errorf(gctx, "clone knowledge %s: %w", knowledge, err)
-				return err
+			if onBatchDone != nil {
+				onBatchDone(len(ids))
 			}
 			return nil
 		})
 	}
-	err = g.Wait()
+	return g.Wait()
+}
+
+// PreviewCloneKnowledgeBase computes the same file_hash diff as CloneKnowledgeBase and estimates
+// its cost, without creating, copying or deleting anything. An empty dstID previews a clone into
+// a new knowledge base.
+func (s *knowledgeService) PreviewCloneKnowledgeBase(ctx context.Context,
+	srcID, dstID string,
+) (*types.KBClonePreview, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	srcKB, err := s.kbService.GetKnowledgeBaseByID(ctx, srcID)
 	if err != nil {
-		logger.Errorf(ctx, "add total knowledge %d: %v", len(addKnowledge), err)
-		return err
+		return nil, err
 	}
-	return nil
+	if srcKB.TenantID != tenantID {
+		return nil, werrors.NewForbiddenError("无权访问源知识库")
+	}
+	if srcKB.Type == types.KnowledgeBaseTypeFAQ {
+		return nil, werrors.NewBadRequestError("FAQ 知识库按条目同步，暂不支持预览")
+	}
+	dstTenantID := tenantID
+	if dstID != "" {
+		dstKB, err := s.kbService.GetKnowledgeBaseByID(ctx, dstID)
+		if err != nil {
+			return nil, err
+		}
+		if dstKB.TenantID != tenantID {
+			return nil, werrors.NewForbiddenError("无权访问目标知识库")
+		}
+		dstTenantID = dstKB.TenantID
+	}
+
+	addKnowledge, err := s.repo.AminusB(ctx, srcKB.TenantID, srcKB.ID, dstTenantID, dstID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge to add: %v", err)
+		return nil, err
+	}
+	delKnowledge, err := s.repo.AminusB(ctx, dstTenantID, dstID, srcKB.TenantID, srcKB.ID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge to delete: %v", err)
+		return nil, err
+	}
+
+	preview := &types.KBClonePreview{
+		SourceID:            srcID,
+		TargetID:            dstID,
+		AddKnowledgeIDs:     make([]string, 0, len(addKnowledge)),
+		DeleteKnowledgeIDs:  delKnowledge,
+		SkippedKnowledgeIDs: make([]string, 0),
+	}
+
+	// cloneKnowledge skips knowledge that has not finished parsing, so only completed items count
+	for ids := range slices.Chunk(addKnowledge, 500) {
+		knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, srcKB.TenantID, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range knowledgeList {
+			if k.ParseStatus != "completed" {
+				preview.SkippedKnowledgeIDs = append(preview.SkippedKnowledgeIDs, k.ID)
+				continue
+			}
+			preview.AddKnowledgeIDs = append(preview.AddKnowledgeIDs, k.ID)
+			preview.AddStorageBytes += k.StorageSize
+		}
+	}
+	for ids := range slices.Chunk(delKnowledge, 500) {
+		knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, dstTenantID, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range knowledgeList {
+			preview.DeleteStorageBytes += k.StorageSize
+		}
+	}
+
+	if preview.AddChunkCount, err = s.chunkRepo.CountChunksByKnowledgeIDs(
+		ctx, srcKB.TenantID, preview.AddKnowledgeIDs, cloneChunkTypes,
+	); err != nil {
+		return nil, err
+	}
+	if preview.DeleteChunkCount, err = s.chunkRepo.CountChunksByKnowledgeIDs(
+		ctx, dstTenantID, delKnowledge, nil,
+	); err != nil {
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Clone preview from %s to %s: add %d (skip %d), delete %d",
+		srcID, dstID, len(preview.AddKnowledgeIDs), len(preview.SkippedKnowledgeIDs), len(delKnowledge))
+	return preview, nil
 }
 
 func (s *knowledgeService) updateChunkVector(ctx context.Context, kbID string, chunks []*types.Chunk) error {
@@ -2824,23 +7699,56 @@ func (s *knowledgeService) updateChunkVector(ctx context.Context, kbID string, c
 		return err
 	}
 
+	// Knowledge titles are only needed when the index content template is set
+	titles := make(map[string]string)
+	if sourceKB.ChunkingConfig.IndexContentTemplate != "" {
+		knowledgeIDs := make([]string, 0, len(chunks))
+		for _, chunk := range chunks {
+			if !slices.Contains(knowledgeIDs, chunk.KnowledgeID) {
+				knowledgeIDs = append(knowledgeIDs, chunk.KnowledgeID)
+			}
+		}
+		knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, sourceKB.TenantID, knowledgeIDs)
+		if err != nil {
+			return err
+		}
+		for _, knowledge := range knowledgeList {
+			titles[knowledge.ID] = knowledge.Title
+		}
+	}
+
 	// Initialize composite retrieve engine from tenant configuration
 	indexInfo := make([]*types.IndexInfo, 0, len(chunks))
 	ids := make([]string, 0, len(chunks))
+	var linksChanged []*types.Chunk
 	for _, chunk := range chunks {
 		if chunk.KnowledgeBaseID != kbID {
 			logger.Warnf(ctx, "Knowledge base ID mismatch: %s != %s", chunk.KnowledgeBaseID, kbID)
 			continue
 		}
-		indexInfo = append(indexInfo, &types.IndexInfo{
-			Content:         chunk.Content,
-			SourceID:        chunk.ID,
-			SourceType:      types.ChunkSourceType,
-			ChunkID:         chunk.ID,
-			KnowledgeID:     chunk.KnowledgeID,
-			KnowledgeBaseID: chunk.KnowledgeBaseID,
-		})
+		// The links of edited text chunks follow the new content, so do their link index entries
+		if chunk.ChunkType == types.ChunkTypeText {
+			changed, err := refreshChunkLinks(chunk)
+			if err != nil {
+				logger.Warnf(ctx, "Failed to refresh links of chunk %s: %v", chunk.ID, err)
+			} else if changed {
+				linksChanged = append(linksChanged, chunk)
+			}
+		}
+		// Old vectors are always removed, excluded image chunks are simply not re-indexed
 		ids = append(ids, chunk.ID)
+		if !sourceKB.ImageProcessingConfig.ShouldIndexChunk(chunk.ChunkType) ||
+			!sourceKB.ChunkingConfig.ShouldIndexChunk(chunk.ChunkType, chunk.Content) {
+			continue
+		}
+		contentInfos, _ := buildChunkContentIndexInfoList(sourceKB, chunk, titles[chunk.KnowledgeID])
+		indexInfo = append(indexInfo, contentInfos...)
+		indexInfo = append(indexInfo, buildChunkLinkIndexInfoList(sourceKB, chunk)...)
+	}
+	if len(linksChanged) > 0 {
+		if err := s.chunkService.UpdateChunks(ctx, linksChanged); err != nil {
+			return err
+		}
 	}
 
 	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
@@ -2855,6 +7763,10 @@ func (s *knowledgeService) updateChunkVector(ctx context.Context, kbID string, c
 		return err
 	}
 
+	if len(indexInfo) == 0 {
+		return nil
+	}
+
 	// Index updated chunk content with new vector representation
 	err = retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfo)
 	if err != nil {
@@ -2869,6 +7781,12 @@ func (s *knowledgeService) UpdateImageInfo(
 	chunkID string,
 	imageInfo string,
 ) error {
+	lock, err := s.lockKnowledge(ctx, knowledgeID)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	var images []*types.ImageInfo
 	if err := json.Unmarshal([]byte(imageInfo), &images); err != nil {
 		logger.Errorf(ctx, "Failed to unmarshal image info: %v", err)
@@ -2898,6 +7816,11 @@ func (s *knowledgeService) UpdateImageInfo(
 	}
 	logger.Infof(ctx, "Found %d chunks with parent chunk ID: %s", len(chunkChildren), chunkID)
 
+	// Children left behind by earlier edits (image replaced, or created twice) are removed instead of
+	// being kept in the index next to the up-to-date ones
+	chunkChildren, redundant := classifyImageChildChunks(
+		map[string]struct{}{image.OriginalURL: {}}, chunkChildren)
+
 	// Iterate through each chunk and update its content based on the image information
 	updateChunk := []*types.Chunk{chunk}
 	var addChunk []*types.Chunk
@@ -2999,31 +7922,385 @@ func (s *knowledgeService) UpdateImageInfo(
 		}
 	}
 
-	// Update the chunk vector
-	err = s.updateChunkVector(ctx, chunk.KnowledgeBaseID, append(updateChunk, addChunk...))
-	if err != nil {
-		logger.ErrorWithFields(ctx, err, map[string]interface{}{
-			"chunk_id":     chunk.ID,
-			"knowledge_id": chunk.KnowledgeID,
-		})
-		return err
+	// Update the chunk vector
+	err = s.updateChunkVector(ctx, chunk.KnowledgeBaseID, append(updateChunk, addChunk...))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"chunk_id":     chunk.ID,
+			"knowledge_id": chunk.KnowledgeID,
+		})
+		return err
+	}
+
+	if len(redundant) > 0 {
+		if err := s.deleteImageChildChunks(ctx, chunk.KnowledgeBaseID, redundant); err != nil {
+			logger.ErrorWithFields(ctx, err, map[string]interface{}{
+				"chunk_id":       chunk.ID,
+				"redundant_size": len(redundant),
+			})
+			return err
+		}
+		logger.Infof(ctx, "Removed %d orphaned or duplicate image child chunks of chunk %s", len(redundant), chunk.ID)
+	}
+
+	// Update the knowledge file hash
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge: %v", err)
+		return err
+	}
+	fileHash := calculateStr(knowledgeID, knowledge.FileHash, imageInfo)
+	knowledge.FileHash = fileHash
+	err = s.repo.UpdateKnowledge(ctx, knowledge)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to update knowledge file hash: %v", err)
+	}
+
+	logger.Infof(ctx, "Updated chunk successfully, chunk ID: %s, knowledge ID: %s", chunk.ID, chunk.KnowledgeID)
+	return nil
+}
+
+// chunkImageURLs returns the original URLs of the images recorded on a chunk
+func chunkImageURLs(chunk *types.Chunk) map[string]struct{} {
+	urls := make(map[string]struct{})
+	var images []*types.ImageInfo
+	if chunk.ImageInfo == "" || json.Unmarshal([]byte(chunk.ImageInfo), &images) != nil {
+		return urls
+	}
+	for _, image := range images {
+		if image != nil {
+			urls[image.OriginalURL] = struct{}{}
+		}
+	}
+	return urls
+}
+
+// classifyImageChildChunks splits the children of a chunk into the ones to keep and the redundant image
+// OCR/caption children: those whose image is not in parentImageURLs (orphaned), and extra children of
+// the same type for the same image (duplicate, the earliest created one is kept).
+// Children of other types are always kept. redundant maps each removed child to its reason.
+func classifyImageChildChunks(parentImageURLs map[string]struct{}, children []*types.Chunk,
+) (kept []*types.Chunk, redundant map[*types.Chunk]string) {
+	ordered := slices.Clone(children)
+	slices.SortStableFunc(ordered, func(a, b *types.Chunk) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	redundant = make(map[*types.Chunk]string)
+	seen := make(map[string]struct{})
+	for _, child := range ordered {
+		if child.ChunkType != types.ChunkTypeImageOCR && child.ChunkType != types.ChunkTypeImageCaption {
+			continue
+		}
+		url := imageChunkURL(child)
+		if _, ok := parentImageURLs[url]; !ok || url == "" {
+			redundant[child] = types.ImageChunkIssueOrphaned
+			continue
+		}
+		key := string(child.ChunkType) + "\x00" + url
+		if _, ok := seen[key]; ok {
+			redundant[child] = types.ImageChunkIssueDuplicate
+			continue
+		}
+		seen[key] = struct{}{}
+	}
+	for _, child := range children {
+		if _, ok := redundant[child]; !ok {
+			kept = append(kept, child)
+		}
+	}
+	return kept, redundant
+}
+
+// imageChunkURL returns the original URL of the image an OCR/caption child chunk was generated from
+func imageChunkURL(chunk *types.Chunk) string {
+	var images []*types.ImageInfo
+	if err := json.Unmarshal([]byte(chunk.ImageInfo), &images); err != nil || len(images) == 0 || images[0] == nil {
+		return ""
+	}
+	return images[0].OriginalURL
+}
+
+// deleteImageChildChunks removes image child chunks from the database and the retrieve engines
+func (s *knowledgeService) deleteImageChildChunks(ctx context.Context,
+	kbID string, chunks map[*types.Chunk]string,
+) error {
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return err
+	}
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(chunks))
+	for chunk := range chunks {
+		ids = append(ids, chunk.ID)
+	}
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err != nil {
+		return err
+	}
+	if err := retrieveEngine.DeleteByChunkIDList(ctx, ids, embeddingModel.GetDimensions(), kb.Type); err != nil {
+		return err
+	}
+	return s.chunkService.DeleteChunks(ctx, ids)
+}
+
+// ListOrphanedImageChunks lists the image OCR/caption child chunks of a knowledge that are orphaned
+// (their image is no longer on the parent chunk, or the parent is gone) or duplicated, for diagnosis.
+// The chunks are only reported; UpdateImageInfo removes them for the chunk it updates.
+func (s *knowledgeService) ListOrphanedImageChunks(ctx context.Context,
+	knowledgeID string,
+) ([]*types.OrphanedImageChunk, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	if _, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID); err != nil {
+		return nil, err
+	}
+	chunks, err := s.chunkRepo.ListChunksByKnowledgeID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkByID := make(map[string]*types.Chunk, len(chunks))
+	childrenByParent := make(map[string][]*types.Chunk)
+	parentIDs := make([]string, 0)
+	for _, chunk := range chunks {
+		chunkByID[chunk.ID] = chunk
+		if chunk.ChunkType != types.ChunkTypeImageOCR && chunk.ChunkType != types.ChunkTypeImageCaption {
+			continue
+		}
+		if _, ok := childrenByParent[chunk.ParentChunkID]; !ok {
+			parentIDs = append(parentIDs, chunk.ParentChunkID)
+		}
+		childrenByParent[chunk.ParentChunkID] = append(childrenByParent[chunk.ParentChunkID], chunk)
+	}
+
+	result := make([]*types.OrphanedImageChunk, 0)
+	for _, parentID := range parentIDs {
+		parentURLs := map[string]struct{}{}
+		if parent, ok := chunkByID[parentID]; ok {
+			parentURLs = chunkImageURLs(parent)
+		}
+		children := childrenByParent[parentID]
+		_, redundant := classifyImageChildChunks(parentURLs, children)
+		for _, child := range children {
+			reason, ok := redundant[child]
+			if !ok {
+				continue
+			}
+			result = append(result, &types.OrphanedImageChunk{
+				ChunkID:       child.ID,
+				ParentChunkID: child.ParentChunkID,
+				ChunkType:     child.ChunkType,
+				ImageURL:      imageChunkURL(child),
+				Content:       child.Content,
+				Reason:        reason,
+			})
+		}
+	}
+	return result, nil
+}
+
+// ValidateChunkChain walks the PreChunkID/NextChunkID links of a knowledge's text chunks and reports
+// dangling pointers, asymmetric links, forks, cycles, unreachable chunks and out-of-order links.
+// With repair set and anomalies found, the chain is rebuilt in ChunkIndex order, the same way
+// processChunks links chunks, and the report keeps the anomalies found before the repair.
+func (s *knowledgeService) ValidateChunkChain(ctx context.Context,
+	knowledgeID string, repair bool,
+) (*types.ChunkChainReport, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := s.chunkRepo.ListChunksByKnowledgeID(ctx, tenantID, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	textChunks := make([]*types.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.ChunkType == types.ChunkTypeText {
+			textChunks = append(textChunks, chunk)
+		}
+	}
+
+	report := checkChunkChain(textChunks)
+	report.KnowledgeID = knowledgeID
+	if !repair || report.Valid {
+		return report, nil
+	}
+
+	if err := s.ensureKnowledgeBaseWritable(ctx, knowledge.KnowledgeBaseID); err != nil {
+		return nil, err
+	}
+	changed := rebuildChunkChain(textChunks)
+	if len(changed) > 0 {
+		if err := s.chunkService.UpdateChunks(ctx, changed); err != nil {
+			logger.Errorf(ctx, "Failed to repair chunk chain of knowledge %s: %v", knowledgeID, err)
+			return nil, err
+		}
+	}
+	report.Repaired = true
+	report.RepairedChunks = len(changed)
+	logger.Infof(ctx, "Repaired chunk chain of knowledge %s: %d anomalies, %d chunks relinked",
+		knowledgeID, report.AnomalyCount, len(changed))
+	return report, nil
+}
+
+// checkChunkChain validates the Pre/Next links among chunks, which are expected to form a single chain
+func checkChunkChain(chunks []*types.Chunk) *types.ChunkChainReport {
+	report := &types.ChunkChainReport{TextChunks: len(chunks), Anomalies: make([]*types.ChunkChainAnomaly, 0)}
+	add := func(issue string, chunk *types.Chunk, related string, detail string) {
+		report.AnomalyCount++
+		if len(report.Anomalies) < types.MaxChunkChainAnomalies {
+			report.Anomalies = append(report.Anomalies, &types.ChunkChainAnomaly{
+				Type:           issue,
+				ChunkID:        chunk.ID,
+				ChunkIndex:     chunk.ChunkIndex,
+				RelatedChunkID: related,
+				Detail:         detail,
+			})
+		}
+	}
+
+	ordered := sortedByChunkIndex(chunks)
+	byID := make(map[string]*types.Chunk, len(chunks))
+	for _, chunk := range ordered {
+		byID[chunk.ID] = chunk
+	}
+
+	// 指针有效性、对称性与分叉
+	nextRefs := make(map[string]string)
+	preRefs := make(map[string]string)
+	for _, chunk := range ordered {
+		if chunk.NextChunkID != "" {
+			if next, ok := byID[chunk.NextChunkID]; !ok {
+				add(types.ChunkChainIssueDangling, chunk, chunk.NextChunkID, "next chunk does not exist")
+			} else {
+				if next.PreChunkID != chunk.ID {
+					add(types.ChunkChainIssueBroken, chunk, next.ID, "next chunk does not point back")
+				}
+				if next.ChunkIndex <= chunk.ChunkIndex {
+					add(types.ChunkChainIssueOutOfOrder, chunk, next.ID, "next chunk has a lower or equal chunk index")
+				}
+				if other, ok := nextRefs[next.ID]; ok {
+					add(types.ChunkChainIssueFork, chunk, next.ID, "next chunk is also the next of "+other)
+				} else {
+					nextRefs[next.ID] = chunk.ID
+				}
+			}
+		}
+		if chunk.PreChunkID != "" {
+			if pre, ok := byID[chunk.PreChunkID]; !ok {
+				add(types.ChunkChainIssueDangling, chunk, chunk.PreChunkID, "previous chunk does not exist")
+			} else {
+				if pre.NextChunkID != chunk.ID {
+					add(types.ChunkChainIssueBroken, chunk, pre.ID, "previous chunk does not point forward")
+				}
+				if other, ok := preRefs[pre.ID]; ok {
+					add(types.ChunkChainIssueFork, chunk, pre.ID, "previous chunk is also the previous of "+other)
+				} else {
+					preRefs[pre.ID] = chunk.ID
+				}
+			}
+		}
+	}
+
+	// 沿下一个分块指针检测环：每个分块只有一个 next，按访问状态着色即可
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(chunks))
+	for _, start := range ordered {
+		path := make([]*types.Chunk, 0)
+		cur := start
+		for cur != nil && state[cur.ID] == unvisited {
+			state[cur.ID] = visiting
+			path = append(path, cur)
+			cur = byID[cur.NextChunkID]
+		}
+		if cur != nil && state[cur.ID] == visiting {
+			add(types.ChunkChainIssueCycle, cur, path[len(path)-1].ID, "following next chunks returns to this chunk")
+		}
+		for _, chunk := range path {
+			state[chunk.ID] = done
+		}
+	}
+
+	// 从链首（ChunkIndex 最小的无前驱分块）出发，无法到达的分块为孤立分块
+	if len(ordered) > 0 {
+		reachable := make(map[string]struct{}, len(ordered))
+		for _, head := range ordered {
+			if head.PreChunkID != "" {
+				continue
+			}
+			for cur := head; cur != nil; cur = byID[cur.NextChunkID] {
+				if _, seen := reachable[cur.ID]; seen {
+					break
+				}
+				reachable[cur.ID] = struct{}{}
+			}
+			break
+		}
+		for _, chunk := range ordered {
+			if _, ok := reachable[chunk.ID]; !ok {
+				add(types.ChunkChainIssueOrphan, chunk, "", "chunk is not reachable from the head of the chain")
+			}
+		}
 	}
 
-	// Update the knowledge file hash
-	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge: %v", err)
-		return err
-	}
-	fileHash := calculateStr(knowledgeID, knowledge.FileHash, imageInfo)
-	knowledge.FileHash = fileHash
-	err = s.repo.UpdateKnowledge(ctx, knowledge)
-	if err != nil {
-		logger.Warnf(ctx, "Failed to update knowledge file hash: %v", err)
+	report.Valid = report.AnomalyCount == 0
+	return report
+}
+
+// rebuildChunkChain relinks chunks in ChunkIndex order and returns the chunks whose links changed
+func rebuildChunkChain(chunks []*types.Chunk) []*types.Chunk {
+	ordered := sortedByChunkIndex(chunks)
+	changed := make([]*types.Chunk, 0)
+	for i, chunk := range ordered {
+		pre, next := "", ""
+		if i > 0 {
+			pre = ordered[i-1].ID
+		}
+		if i < len(ordered)-1 {
+			next = ordered[i+1].ID
+		}
+		if chunk.PreChunkID == pre && chunk.NextChunkID == next {
+			continue
+		}
+		chunk.PreChunkID = pre
+		chunk.NextChunkID = next
+		changed = append(changed, chunk)
 	}
+	return changed
+}
 
-	logger.Infof(ctx, "Updated chunk successfully, chunk ID: %s, knowledge ID: %s", chunk.ID, chunk.KnowledgeID)
-	return nil
+// sortedByChunkIndex returns a copy of chunks ordered by ChunkIndex, then creation time and ID
+func sortedByChunkIndex(chunks []*types.Chunk) []*types.Chunk {
+	ordered := slices.Clone(chunks)
+	slices.SortStableFunc(ordered, func(a, b *types.Chunk) int {
+		if a.ChunkIndex != b.ChunkIndex {
+			return a.ChunkIndex - b.ChunkIndex
+		}
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return ordered
+}
+
+// cloneChunkTypes are the chunk types copied when cloning knowledge into another knowledge base
+var cloneChunkTypes = []types.ChunkType{
+	types.ChunkTypeText, types.ChunkTypeSummary,
+	types.ChunkTypeImageCaption, types.ChunkTypeImageOCR,
 }
 
 // CloneChunk clone chunks from one knowledge to another
@@ -3041,16 +8318,14 @@ func (s *knowledgeService) UpdateImageInfo(
 // and updating the vector database representation of the moved chunks.
 // It also ensures that the chunk's relationships (like pre and next chunk IDs) are maintained
 // by mapping the source chunk IDs to the new target chunk IDs.
-func (s *knowledgeService) CloneChunk(ctx context.Context, src, dst *types.Knowledge) error {
+// Disabled chunks keep IsEnabled=false in the target; they are skipped entirely when
+// includeDisabled is false, and links pointing at them are cleared.
+func (s *knowledgeService) CloneChunk(ctx context.Context, src, dst *types.Knowledge, includeDisabled bool) error {
 	chunkPage := 1
 	chunkPageSize := 100
 	srcTodst := map[string]string{}
 	tagIDMapping := map[string]string{} // srcTagID -> dstTagID
 	targetChunks := make([]*types.Chunk, 0, 10)
-	chunkType := []types.ChunkType{
-		types.ChunkTypeText, types.ChunkTypeSummary,
-		types.ChunkTypeImageCaption, types.ChunkTypeImageOCR,
-	}
 	for {
 		sourceChunks, _, err := s.chunkRepo.ListPagedChunksByKnowledgeID(ctx,
 			src.TenantID,
@@ -3059,7 +8334,7 @@ func (s *knowledgeService) CloneChunk(ctx context.Context, src, dst *types.Knowl
 				Page:     chunkPage,
 				PageSize: chunkPageSize,
 			},
-			chunkType,
+			cloneChunkTypes,
 			"",
 			"",
 			"",
@@ -3075,6 +8350,9 @@ func (s *knowledgeService) CloneChunk(ctx context.Context, src, dst *types.Knowl
 		}
 		now := time.Now()
 		for _, sourceChunk := range sourceChunks {
+			if !includeDisabled && !sourceChunk.IsEnabled {
+				continue
+			}
 			// Map TagID to target knowledge base
 			targetTagID := ""
 			if sourceChunk.TagID != "" {
@@ -3169,32 +8447,9 @@ func (s *knowledgeService) ListFAQEntries(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if this is a shared knowledge base access
-	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	effectiveTenantID := tenantID
-
-	// If the kb belongs to a different tenant, check for shared access
-	if kb.TenantID != tenantID {
-		// Get user ID from context
-		userIDVal := ctx.Value(types.UserIDContextKey)
-		if userIDVal == nil {
-			return nil, werrors.NewForbiddenError("无权访问该知识库")
-		}
-		userID := userIDVal.(string)
-
-		// Check if user has at least viewer permission through organization sharing
-		hasPermission, err := s.kbShareService.HasKBPermission(ctx, kbID, userID, types.OrgRoleViewer)
-		if err != nil || !hasPermission {
-			return nil, werrors.NewForbiddenError("无权访问该知识库")
-		}
-
-		// Use the source tenant ID for data access
-		sourceTenantID, err := s.kbShareService.GetKBSourceTenant(ctx, kbID)
-		if err != nil {
-			return nil, werrors.NewForbiddenError("无权访问该知识库")
-		}
-		effectiveTenantID = sourceTenantID
+	effectiveTenantID, err := s.faqReadTenantID(ctx, kb)
+	if err != nil {
+		return nil, err
 	}
 
 	faqKnowledge, err := s.findFAQKnowledge(ctx, effectiveTenantID, kb.ID)
@@ -3222,7 +8477,72 @@ func (s *knowledgeService) ListFAQEntries(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	return s.buildFAQEntryPage(ctx, effectiveTenantID, kb, chunks, total, page)
+}
+
+// ListAnswerPendingFAQEntries lists the FAQ entries imported without answers, so they can be completed.
+func (s *knowledgeService) ListAnswerPendingFAQEntries(ctx context.Context,
+	kbID string, page *types.Pagination,
+) (*types.PageResult, error) {
+	if page == nil {
+		page = &types.Pagination{}
+	}
+	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	effectiveTenantID, err := s.faqReadTenantID(ctx, kb)
+	if err != nil {
+		return nil, err
+	}
+	faqKnowledge, err := s.findFAQKnowledge(ctx, effectiveTenantID, kb.ID)
+	if err != nil {
+		return nil, err
+	}
+	if faqKnowledge == nil {
+		return types.NewPageResult(0, page, []*types.FAQEntry{}), nil
+	}
+
+	chunks, total, err := s.chunkRepo.ListAnswerPendingFAQChunks(ctx, effectiveTenantID, faqKnowledge.ID, page)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildFAQEntryPage(ctx, effectiveTenantID, kb, chunks, total, page)
+}
+
+// faqReadTenantID returns the tenant owning the FAQ data to read: the caller's tenant, or the source
+// tenant when the knowledge base is shared with the caller through an organization.
+func (s *knowledgeService) faqReadTenantID(ctx context.Context, kb *types.KnowledgeBase) (uint64, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	if kb.TenantID == tenantID {
+		return tenantID, nil
+	}
+
+	// Get user ID from context
+	userIDVal := ctx.Value(types.UserIDContextKey)
+	if userIDVal == nil {
+		return 0, werrors.NewForbiddenError("无权访问该知识库")
+	}
+	userID := userIDVal.(string)
+
+	// Check if user has at least viewer permission through organization sharing
+	hasPermission, err := s.kbShareService.HasKBPermission(ctx, kb.ID, userID, types.OrgRoleViewer)
+	if err != nil || !hasPermission {
+		return 0, werrors.NewForbiddenError("无权访问该知识库")
+	}
+
+	// Use the source tenant ID for data access
+	sourceTenantID, err := s.kbShareService.GetKBSourceTenant(ctx, kb.ID)
+	if err != nil {
+		return 0, werrors.NewForbiddenError("无权访问该知识库")
+	}
+	return sourceTenantID, nil
+}
 
+// buildFAQEntryPage converts a page of FAQ chunks to entries with their tag seq_id and name
+func (s *knowledgeService) buildFAQEntryPage(ctx context.Context, effectiveTenantID uint64,
+	kb *types.KnowledgeBase, chunks []*types.Chunk, total int64, page *types.Pagination,
+) (*types.PageResult, error) {
 	// Build tag ID to name and seq_id mapping for all unique tag IDs (batch query)
 	tagNameMap := make(map[string]string)
 	tagSeqIDMap := make(map[string]int64)
@@ -3246,22 +8566,438 @@ func (s *knowledgeService) ListFAQEntries(ctx context.Context,
 		}
 	}
 
-	kb.EnsureDefaults()
-	entries := make([]*types.FAQEntry, 0, len(chunks))
-	for _, chunk := range chunks {
-		entry, err := s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
-		if err != nil {
-			return nil, err
-		}
-		// Set tag name from mapping
-		if chunk.TagID != "" {
-			entry.TagName = tagNameMap[chunk.TagID]
-		}
-		entries = append(entries, entry)
+	kb.EnsureDefaults()
+	entries := make([]*types.FAQEntry, 0, len(chunks))
+	for _, chunk := range chunks {
+		entry, err := s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
+		if err != nil {
+			return nil, err
+		}
+		// Set tag name from mapping
+		if chunk.TagID != "" {
+			entry.TagName = tagNameMap[chunk.TagID]
+		}
+		entries = append(entries, entry)
+	}
+	return types.NewPageResult(total, page, entries), nil
+}
+
+// faqConversionConcurrency limits concurrent LLM calls when extracting Q/A pairs from a document
+const faqConversionConcurrency = 5
+
+// faqExtractionItem is a single Q/A pair returned by the chat model
+type faqExtractionItem struct {
+	Question         string   `json:"question"`
+	SimilarQuestions []string `json:"similar_questions"`
+	Answer           string   `json:"answer"`
+	Confidence       float64  `json:"confidence"`
+}
+
+const (
+	faqConversionProgressKeyPrefix = "faq_convert_progress:"
+	faqConversionProgressTTL       = 24 * time.Hour
+)
+
+// getFAQConversionProgressKey returns the Redis key for storing FAQ conversion progress
+func getFAQConversionProgressKey(taskID string) string {
+	return faqConversionProgressKeyPrefix + taskID
+}
+
+// ConvertDocumentToFAQ starts extracting Q/A pairs from a document knowledge with the chat model for a FAQ
+// knowledge base. Extraction runs in an asynq task, see ProcessFAQConversion; the returned progress can be
+// polled with GetFAQConversionProgress and carries the extracted entries once completed. Nothing is
+// imported until the reviewed entries are committed with CommitFAQConversion.
+func (s *knowledgeService) ConvertDocumentToFAQ(ctx context.Context, sourceCtx context.Context,
+	sourceKnowledgeID string, targetFAQKbID string,
+) (*types.FAQConversionProgress, error) {
+	// 源知识可能位于共享知识库中，按调用方对源知识的访问权限解析出的租户读取
+	sourceTenantID := sourceCtx.Value(types.TenantIDContextKey).(uint64)
+
+	source, err := s.repo.GetKnowledgeByID(sourceCtx, sourceTenantID, sourceKnowledgeID)
+	if err != nil {
+		return nil, werrors.NewNotFoundError("源知识不存在")
+	}
+	if source.Type == types.KnowledgeTypeFAQ {
+		return nil, werrors.NewBadRequestError("源知识已是 FAQ 知识")
+	}
+	if source.ParseStatus != types.ParseStatusCompleted {
+		return nil, werrors.NewBadRequestError("源知识尚未解析完成")
+	}
+
+	targetKB, err := s.validateFAQKnowledgeBase(ctx, targetFAQKbID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer the target FAQ knowledge base's model, fall back to the source knowledge base
+	chatModelID := targetKB.SummaryModelID
+	modelCtx := ctx
+	if chatModelID == "" {
+		sourceKB, err := s.kbService.GetKnowledgeBaseByID(sourceCtx, source.KnowledgeBaseID)
+		if err != nil {
+			return nil, err
+		}
+		chatModelID = sourceKB.SummaryModelID
+		modelCtx = sourceCtx
+	}
+	if chatModelID == "" {
+		return nil, werrors.NewBadRequestError("知识库未配置对话模型，无法抽取问答对")
+	}
+	if _, err := s.modelService.GetChatModel(modelCtx, chatModelID); err != nil {
+		return nil, err
+	}
+
+	textChunks, err := s.listFAQConversionChunks(sourceCtx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(textChunks) == 0 {
+		return nil, werrors.NewBadRequestError("源知识没有可用的文本内容")
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	now := time.Now().Unix()
+	progress := &types.FAQConversionProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    secutils.GenerateTaskID("faq_convert", tenantID, targetKB.ID),
+			TenantID:  tenantID,
+			KBID:      targetKB.ID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			Total:     len(textChunks),
+			Message:   fmt.Sprintf("正在从 %d 个分块中抽取问答对...", len(textChunks)),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		SourceKnowledgeID: source.ID,
+	}
+	if err := s.saveFAQConversionProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save FAQ conversion progress: %v", err)
+	}
+
+	payloadBytes, err := json.Marshal(types.FAQConvertPayload{
+		TenantID:          tenantID,
+		TaskID:            progress.TaskID,
+		KBID:              targetKB.ID,
+		SourceTenantID:    sourceTenantID,
+		SourceKnowledgeID: source.ID,
+		ChatModelID:       chatModelID,
+		ChatModelTenantID: modelCtx.Value(types.TenantIDContextKey).(uint64),
+	})
+	if err == nil {
+		task := asynq.NewTask(types.TypeFAQConvert, payloadBytes,
+			asynq.TaskID(progress.TaskID), asynq.Queue("low"), asynq.MaxRetry(3))
+		_, err = s.task.Enqueue(task)
+	}
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue FAQ conversion task: %v", err)
+		progress.Status = types.KnowledgeTaskStatusFailed
+		progress.Message = "提交问答对抽取任务失败"
+		progress.UpdatedAt = time.Now().Unix()
+		if saveErr := s.saveFAQConversionProgress(ctx, progress); saveErr != nil {
+			logger.Warnf(ctx, "Failed to save FAQ conversion progress: %v", saveErr)
+		}
+		return nil, fmt.Errorf("failed to enqueue FAQ conversion task: %w", err)
+	}
+
+	logger.Infof(ctx, "FAQ conversion task submitted: source=%s target_kb=%s chunks=%d task_id=%s",
+		source.ID, targetKB.ID, len(textChunks), progress.TaskID)
+	return progress, nil
+}
+
+// listFAQConversionChunks returns the non-empty text chunks of a knowledge in document order
+func (s *knowledgeService) listFAQConversionChunks(ctx context.Context, knowledgeID string) ([]*types.Chunk, error) {
+	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	textChunks := make([]*types.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.ChunkType == types.ChunkTypeText && strings.TrimSpace(chunk.Content) != "" {
+			textChunks = append(textChunks, chunk)
+		}
+	}
+	sort.Slice(textChunks, func(i, j int) bool {
+		return textChunks[i].ChunkIndex < textChunks[j].ChunkIndex
+	})
+	return textChunks, nil
+}
+
+// ProcessFAQConversion handles the FAQ conversion task: it extracts Q/A pairs chunk by chunk with bounded
+// concurrency and stores the entries in the progress for review. A repeated standard question keeps its
+// most confident pair.
+func (s *knowledgeService) ProcessFAQConversion(ctx context.Context, t *asynq.Task) error {
+	var payload types.FAQConvertPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal FAQ conversion payload: %v", err)
+		return nil
+	}
+
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	isLastRetry := retryCount >= maxRetry
+
+	now := time.Now().Unix()
+	progress := &types.FAQConversionProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    payload.TaskID,
+			TenantID:  payload.TenantID,
+			KBID:      payload.KBID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		SourceKnowledgeID: payload.SourceKnowledgeID,
+	}
+	var previous types.FAQConversionProgress
+	if err := s.loadTaskProgress(ctx, getFAQConversionProgressKey(payload.TaskID), &previous); err == nil {
+		progress.CreatedAt = previous.CreatedAt
+	}
+	handleError := func(err error) error {
+		if isLastRetry {
+			progress.Status = types.KnowledgeTaskStatusFailed
+			progress.Message = fmt.Sprintf("问答对抽取失败: %v", err)
+			progress.UpdatedAt = time.Now().Unix()
+			if saveErr := s.saveFAQConversionProgress(ctx, progress); saveErr != nil {
+				logger.Warnf(ctx, "Failed to save FAQ conversion progress: %v", saveErr)
+			}
+		}
+		return err
+	}
+
+	sourceTenant, err := s.tenantRepo.GetTenantByID(ctx, payload.SourceTenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get tenant %d: %v", payload.SourceTenantID, err)
+		return handleError(err)
+	}
+	sourceCtx := context.WithValue(ctx, types.TenantIDContextKey, payload.SourceTenantID)
+	sourceCtx = context.WithValue(sourceCtx, types.TenantInfoContextKey, sourceTenant)
+	modelCtx := context.WithValue(ctx, types.TenantIDContextKey, payload.ChatModelTenantID)
+
+	source, err := s.repo.GetKnowledgeByID(sourceCtx, payload.SourceTenantID, payload.SourceKnowledgeID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get source knowledge %s: %v", payload.SourceKnowledgeID, err)
+		return handleError(err)
+	}
+	chatModel, err := s.modelService.GetChatModel(modelCtx, payload.ChatModelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get chat model %s: %v", payload.ChatModelID, err)
+		return handleError(err)
+	}
+	textChunks, err := s.listFAQConversionChunks(sourceCtx, source.ID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list chunks of knowledge %s: %v", source.ID, err)
+		return handleError(err)
+	}
+	progress.Total = len(textChunks)
+	progress.Message = fmt.Sprintf("正在从 %d 个分块中抽取问答对...", len(textChunks))
+	if err := s.saveFAQConversionProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save FAQ conversion progress: %v", err)
+	}
+
+	// Extract Q/A pairs chunk by chunk, keeping results in document order
+	extracted := make([][]faqExtractionItem, len(textChunks))
+	var mu sync.Mutex
+	record := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Processed++
+		if err != nil {
+			progress.Failed++
+		}
+		progress.Progress = progress.Processed * 100 / progress.Total
+		progress.UpdatedAt = time.Now().Unix()
+		if err := s.saveFAQConversionProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to update FAQ conversion progress: %v", err)
+		}
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(faqConversionConcurrency)
+	for i, chunk := range textChunks {
+		g.Go(func() error {
+			items, err := s.extractFAQPairs(gctx, chatModel, chunk.Content, source.Title)
+			if err != nil {
+				logger.Warnf(gctx, "ProcessFAQConversion: failed to extract Q/A pairs from chunk %s: %v", chunk.ID, err)
+			} else {
+				extracted[i] = items
+			}
+			record(err)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	entries := buildFAQExtractedEntries(textChunks, extracted)
+	progress.Progress = 100
+	progress.UpdatedAt = time.Now().Unix()
+	if len(entries) == 0 {
+		progress.Status = types.KnowledgeTaskStatusFailed
+		progress.Message = "未能从文档中抽取到问答对"
+	} else {
+		var totalConfidence float64
+		for _, entry := range entries {
+			totalConfidence += entry.Confidence
+		}
+		progress.Status = types.KnowledgeTaskStatusCompleted
+		progress.Entries = entries
+		progress.AverageConfidence = totalConfidence / float64(len(entries))
+		progress.Message = fmt.Sprintf("已抽取 %d 个问答对，%d 个分块抽取失败，请审阅后提交导入",
+			len(entries), progress.Failed)
+	}
+	if err := s.saveFAQConversionProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save final FAQ conversion progress: %v", err)
+	}
+	logger.Infof(ctx, "FAQ conversion finished: task_id=%s source=%s entries=%d failed_chunks=%d",
+		progress.TaskID, source.ID, len(entries), progress.Failed)
+	return nil
+}
+
+// buildFAQExtractedEntries builds FAQ entries from the Q/A pairs extracted per chunk, keeping the most
+// confident pair for a repeated standard question
+func buildFAQExtractedEntries(chunks []*types.Chunk, extracted [][]faqExtractionItem) []types.FAQExtractedEntry {
+	entries := make([]types.FAQExtractedEntry, 0)
+	entryIndex := make(map[string]int)
+	for i, items := range extracted {
+		for _, item := range items {
+			question := strings.TrimSpace(item.Question)
+			answer := strings.TrimSpace(item.Answer)
+			if question == "" || answer == "" {
+				continue
+			}
+			similar := make([]string, 0, len(item.SimilarQuestions))
+			for _, q := range item.SimilarQuestions {
+				q = strings.TrimSpace(q)
+				if q != "" && q != question && !slices.Contains(similar, q) {
+					similar = append(similar, q)
+				}
+			}
+			entry := types.FAQExtractedEntry{
+				FAQEntryPayload: types.FAQEntryPayload{
+					StandardQuestion: question,
+					SimilarQuestions: similar,
+					Answers:          []string{answer},
+				},
+				Confidence:    min(max(item.Confidence, 0), 1),
+				SourceChunkID: chunks[i].ID,
+			}
+			if idx, exists := entryIndex[question]; exists {
+				if entry.Confidence > entries[idx].Confidence {
+					entries[idx] = entry
+				}
+				continue
+			}
+			entryIndex[question] = len(entries)
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// CommitFAQConversion imports the entries of a completed FAQ conversion into its FAQ knowledge base through
+// the regular UpsertFAQEntries path, without extracting again. entries are the entries as reviewed by the
+// user; when empty, the extracted entries are imported as they are. With dryRun the entries are only
+// validated. Returns the FAQ import task ID.
+func (s *knowledgeService) CommitFAQConversion(ctx context.Context, kbID string, taskID string,
+	entries []types.FAQEntryPayload, dryRun bool,
+) (string, error) {
+	progress, err := s.GetFAQConversionProgress(ctx, taskID)
+	if err != nil {
+		return "", err
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	if progress.KBID != kbID || progress.TenantID != tenantID {
+		return "", werrors.NewNotFoundError("FAQ conversion task not found")
+	}
+	if progress.Status != types.KnowledgeTaskStatusCompleted {
+		return "", werrors.NewBadRequestError("问答对抽取任务尚未完成")
+	}
+	if len(entries) == 0 {
+		entries = make([]types.FAQEntryPayload, 0, len(progress.Entries))
+		for _, entry := range progress.Entries {
+			entries = append(entries, entry.FAQEntryPayload)
+		}
+	}
+
+	importTaskID, err := s.UpsertFAQEntries(ctx, kbID, &types.FAQBatchUpsertPayload{
+		Entries: entries,
+		Mode:    types.FAQBatchModeAppend,
+		DryRun:  dryRun,
+	})
+	if err != nil {
+		return "", err
+	}
+	logger.Infof(ctx, "FAQ conversion committed: task_id=%s kb_id=%s entries=%d dry_run=%v import_task=%s",
+		taskID, kbID, len(entries), dryRun, importTaskID)
+	return importTaskID, nil
+}
+
+// saveFAQConversionProgress saves the FAQ conversion progress to Redis
+func (s *knowledgeService) saveFAQConversionProgress(ctx context.Context,
+	progress *types.FAQConversionProgress,
+) error {
+	return s.saveTaskProgress(ctx, getFAQConversionProgressKey(progress.TaskID), progress, faqConversionProgressTTL)
+}
+
+// GetFAQConversionProgress retrieves the progress of a FAQ conversion task
+func (s *knowledgeService) GetFAQConversionProgress(ctx context.Context,
+	taskID string,
+) (*types.FAQConversionProgress, error) {
+	var progress types.FAQConversionProgress
+	if err := s.loadTaskProgress(ctx, getFAQConversionProgressKey(taskID), &progress); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("FAQ conversion task not found")
+		}
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// extractFAQPairs asks the chat model to extract Q/A pairs from a piece of document content
+func (s *knowledgeService) extractFAQPairs(ctx context.Context,
+	chatModel chat.Chat, content, docName string,
+) ([]faqExtractionItem, error) {
+	prompt := strings.ReplaceAll(defaultFAQExtractionPrompt, "{{doc_name}}", docName)
+	prompt = strings.ReplaceAll(prompt, "{{content}}", content)
+
+	thinking := false
+	response, err := chatModel.Chat(ctx, []chat.Message{
+		{
+			Role:    "user",
+			Content: prompt,
+		},
+	}, &chat.ChatOptions{
+		Temperature: 0.1,
+		MaxTokens:   2048,
+		Thinking:    &thinking,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract Q/A pairs: %w", err)
 	}
-	return types.NewPageResult(total, page, entries), nil
+
+	var items []faqExtractionItem
+	if err := common.ParseLLMJsonResponse(response.Content, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse Q/A pairs: %w", err)
+	}
+	return items, nil
 }
 
+// Default prompt for extracting FAQ entries from document content
+const defaultFAQExtractionPrompt = `你是一个专业的问答对抽取助手。请从给定的文档内容中抽取已经存在的问答对（Q/A）。
+
+## 文档内容
+文档名称：{{doc_name}}
+{{content}}
+
+## 要求
+- 只抽取文档中明确存在的问题及其答案，不要编造内容
+- 答案应完整保留原文信息，可以去除多余的格式符号
+- 如果原文中存在同一问题的其他问法，放入 similar_questions
+- confidence 表示你对该问答对抽取准确性的把握，取值 0 到 1
+- 如果内容中没有问答对，返回空数组 []
+
+## 输出格式
+只输出 JSON 数组，不要输出其他内容：
+[{"question": "问题", "similar_questions": ["其他问法"], "answer": "答案", "confidence": 0.9}]`
+
 // UpsertFAQEntries imports or appends FAQ entries asynchronously.
 // Returns task ID (UUID) for tracking import progress.
 func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
@@ -3282,6 +9018,9 @@ func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
 	if err != nil {
 		return "", err
 	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return "", err
+	}
 
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 
@@ -3293,14 +9032,20 @@ func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
 
 	var knowledgeID string
 
-	// 检查是否有正在进行的导入任务（通过Redis）
-	runningTaskID, err := s.getRunningFAQImportTaskID(ctx, kbID)
+	// 检查是否有正在进行的导入任务（通过Redis），对应的 asynq 任务已不存在或已结束时视为残留锁直接清除
+	runningInfo, err := s.getRunningFAQImportInfo(ctx, kbID)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to check running import task: %v", err)
 		// 检查失败不影响导入，继续执行
-	} else if runningTaskID != "" {
-		logger.Warnf(ctx, "Import task already running for KB %s: %s", kbID, runningTaskID)
-		return "", werrors.NewBadRequestError(fmt.Sprintf("该知识库已有导入任务正在进行中（任务ID: %s），请等待完成后再试", runningTaskID))
+	} else if runningInfo != nil {
+		if !s.isFAQImportLockStale(ctx, runningInfo) {
+			logger.Warnf(ctx, "Import task already running for KB %s: %s", kbID, runningInfo.TaskID)
+			return "", werrors.NewBadRequestError(fmt.Sprintf("该知识库已有导入任务正在进行中（任务ID: %s），请等待完成后再试", runningInfo.TaskID))
+		}
+		logger.Warnf(ctx, "Clearing stale FAQ import lock for KB %s: %s", kbID, runningInfo.TaskID)
+		if err := s.clearRunningFAQImportTaskID(ctx, kbID); err != nil {
+			logger.Errorf(ctx, "Failed to clear stale FAQ import lock: %v", err)
+		}
 	}
 
 	// 确保 FAQ knowledge 存在
@@ -3359,31 +9104,23 @@ func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
 		Mode:        payload.Mode,
 		DryRun:      payload.DryRun,
 		EnqueuedAt:  enqueuedAt,
+
+		AllowEmptyAnswers: payload.AllowEmptyAnswers,
 	}
 
-	// 阈值：超过 200 条或序列化后超过 50KB 时使用对象存储
-	const (
-		entryCountThreshold  = 200
-		payloadSizeThreshold = 50 * 1024 // 50KB
-	)
+	// 阈值：超过 200 条或序列化后超过内联大小上限（默认 50KB）时使用对象存储
+	const entryCountThreshold = 200
+	payloadSizeThreshold := s.taskPayloadInlineMaxSize()
 
+	// 上传到私有桶（主桶），任务处理完成后清理
+	fileName := fmt.Sprintf("faq_import_entries_%s_%d.json", taskID, enqueuedAt)
 	entryCount := len(payload.Entries)
 	if entryCount > entryCountThreshold {
 		// 数据量较大，上传到对象存储
-		entriesData, err := json.Marshal(payload.Entries)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to marshal FAQ entries: %v", err)
-			return "", fmt.Errorf("failed to marshal entries: %w", err)
-		}
-
-		logger.Infof(ctx, "FAQ entries size: %d bytes, uploading to object storage", len(entriesData))
-
-		// 上传到私有桶（主桶），任务处理完成后清理
-		fileName := fmt.Sprintf("faq_import_entries_%s_%d.json", taskID, enqueuedAt)
-		entriesURL, err := s.fileSvc.SaveBytes(ctx, entriesData, tenantID, fileName, false)
+		entriesURL, err := s.offloadTaskData(ctx, tenantID, fileName, payload.Entries)
 		if err != nil {
 			logger.Errorf(ctx, "Failed to upload FAQ entries to object storage: %v", err)
-			return "", fmt.Errorf("failed to upload entries: %w", err)
+			return "", err
 		}
 
 		logger.Infof(ctx, "FAQ entries uploaded to: %s", entriesURL)
@@ -3403,12 +9140,10 @@ func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
 	// 再次检查 payload 大小
 	if len(payloadBytes) > payloadSizeThreshold && taskPayload.EntriesURL == "" {
 		// payload 太大但还没上传，现在上传
-		entriesData, _ := json.Marshal(payload.Entries)
-		fileName := fmt.Sprintf("faq_import_entries_%s_%d.json", taskID, enqueuedAt)
-		entriesURL, err := s.fileSvc.SaveBytes(ctx, entriesData, tenantID, fileName, false)
+		entriesURL, err := s.offloadTaskData(ctx, tenantID, fileName, payload.Entries)
 		if err != nil {
 			logger.Errorf(ctx, "Failed to upload FAQ entries to object storage: %v", err)
-			return "", fmt.Errorf("failed to upload entries: %w", err)
+			return "", err
 		}
 
 		logger.Infof(ctx, "FAQ entries uploaded to (size exceeded): %s", entriesURL)
@@ -3428,18 +9163,21 @@ func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
 
 	// 使用 taskID:enqueuedAt 作为 asynq 的唯一任务标识
 	// 这样同一个用户 TaskID 的不同次提交不会冲突
-	asynqTaskID := fmt.Sprintf("%s:%d", taskID, enqueuedAt)
+	asynqTaskID := (&runningFAQImportInfo{TaskID: taskID, EnqueuedAt: enqueuedAt}).asynqTaskID()
 
 	task := asynq.NewTask(
 		types.TypeFAQImport,
 		payloadBytes,
 		asynq.TaskID(asynqTaskID),
-		asynq.Queue("default"),
+		asynq.Queue(faqImportQueue),
 		asynq.MaxRetry(maxRetry),
 	)
 	info, err := s.task.Enqueue(task)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to enqueue FAQ import task: %v", err)
+		if clearErr := s.clearRunningFAQImportTaskID(ctx, kbID); clearErr != nil {
+			logger.Errorf(ctx, "Failed to clear running FAQ import task ID: %v", clearErr)
+		}
 		return "", fmt.Errorf("failed to enqueue task: %w", err)
 	}
 	logger.Infof(ctx, "Enqueued FAQ import task: id=%s queue=%s task_id=%s dry_run=%v", info.ID, info.Queue, taskID, payload.DryRun)
@@ -3517,6 +9255,38 @@ func csvEscape(s string) string {
 	return s
 }
 
+// generateSkippedEntriesCSV 生成跳过条目的 CSV 文件并上传
+func (s *knowledgeService) generateSkippedEntriesCSV(ctx context.Context,
+	tenantID uint64, taskID string, skippedEntries []types.FAQSkippedEntry,
+) (string, error) {
+	var buf strings.Builder
+
+	// 写入 BOM 以支持 Excel 正确识别 UTF-8
+	buf.WriteString("\xEF\xBB\xBF")
+	buf.WriteString("行号,跳过原因类型,跳过原因,问题,重复的问题\n")
+	for _, entry := range skippedEntries {
+		buf.WriteString(fmt.Sprintf("%d,%s,%s,%s,%s\n",
+			entry.Index+1,
+			entry.ReasonCode,
+			csvEscape(entry.Reason),
+			csvEscape(entry.StandardQuestion),
+			csvEscape(entry.DuplicateQuestion)))
+	}
+
+	fileName := fmt.Sprintf("faq_import_skipped_%s.csv", taskID)
+	filePath, err := s.fileSvc.SaveBytes(ctx, []byte(buf.String()), tenantID, fileName, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to save CSV file: %w", err)
+	}
+	fileURL, err := s.fileSvc.GetFileURL(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file URL: %w", err)
+	}
+
+	logger.Infof(ctx, "Generated skipped entries CSV: %s, entries: %d", fileURL, len(skippedEntries))
+	return fileURL, nil
+}
+
 // saveFAQImportResultToDatabase 保存FAQ导入结果统计到数据库
 func (s *knowledgeService) saveFAQImportResultToDatabase(ctx context.Context,
 	payload *types.FAQImportPayload, progress *types.FAQImportProgress, originalTotalEntries int,
@@ -3551,6 +9321,9 @@ func (s *knowledgeService) saveFAQImportResultToDatabase(ctx context.Context,
 	if progress.FailedCount > 0 && progress.FailedEntriesURL != "" {
 		importResult.FailedEntriesURL = progress.FailedEntriesURL
 	}
+	importResult.SkippedEntries = progress.SkippedEntries
+	importResult.SkippedEntriesURL = progress.SkippedEntriesURL
+	importResult.UnindexedSimilarQuestions = progress.UnindexedSimilarQuestions
 
 	// 设置导入结果到Knowledge的metadata中
 	if err := knowledge.SetLastFAQImportResult(importResult); err != nil {
@@ -3591,6 +9364,19 @@ func buildFAQFailedEntry(idx int, reason string, entry *types.FAQEntryPayload) t
 	}
 }
 
+// buildFAQSkippedEntry 构建 FAQSkippedEntry
+func buildFAQSkippedEntry(idx int, code types.FAQSkipReason, reason string,
+	entry *types.FAQEntryPayload, duplicateQuestion string,
+) types.FAQSkippedEntry {
+	return types.FAQSkippedEntry{
+		Index:             idx,
+		ReasonCode:        code,
+		Reason:            reason,
+		StandardQuestion:  strings.TrimSpace(entry.StandardQuestion),
+		DuplicateQuestion: duplicateQuestion,
+	}
+}
+
 // executeFAQDryRunValidation 执行 FAQ dry run 验证，返回通过验证的条目索引
 func (s *knowledgeService) executeFAQDryRunValidation(ctx context.Context,
 	payload *types.FAQImportPayload, progress *types.FAQImportProgress,
@@ -3600,11 +9386,25 @@ func (s *knowledgeService) executeFAQDryRunValidation(ctx context.Context,
 	// 用于记录已通过基本验证和重复检查的条目索引，后续进行安全检查
 	validEntryIndices := make([]int, 0, len(entries))
 
+	// 按知识库配置确定查重范围
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KBID)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to get knowledge base for dry run, checking duplicates knowledge base wide: %v", err)
+		kb = nil
+	}
+	scope := s.newFAQImportQuestionScope(payload.TenantID, kb)
+	var faqConfig *types.FAQConfig
+	if kb != nil {
+		faqConfig = kb.FAQConfig
+	}
+
 	// 根据模式选择不同的验证逻辑
 	if payload.Mode == types.FAQBatchModeAppend {
-		validEntryIndices = s.validateEntriesForAppendModeWithProgress(ctx, payload.TenantID, payload.KBID, entries, progress)
+		validEntryIndices = s.validateEntriesForAppendModeWithProgress(ctx, payload.TenantID, payload.KBID, scope, entries,
+			payload.AllowEmptyAnswers, faqConfig, progress)
 	} else {
-		validEntryIndices = s.validateEntriesForReplaceModeWithProgress(ctx, entries, progress)
+		validEntryIndices = s.validateEntriesForReplaceModeWithProgress(ctx, scope, entries, payload.AllowEmptyAnswers,
+			faqConfig, progress)
 	}
 
 	return validEntryIndices
@@ -3613,7 +9413,8 @@ func (s *knowledgeService) executeFAQDryRunValidation(ctx context.Context,
 // validateEntriesForAppendModeWithProgress 验证 Append 模式下的条目（带进度更新）
 // 注意：验证阶段不更新 Processed，只有实际导入时才更新
 func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.Context,
-	tenantID uint64, kbID string, entries []types.FAQEntryPayload, progress *types.FAQImportProgress,
+	tenantID uint64, kbID string, scope *faqImportQuestionScope, entries []types.FAQEntryPayload,
+	allowEmptyAnswers bool, faqConfig *types.FAQConfig, progress *types.FAQImportProgress,
 ) []int {
 	validIndices := make([]int, 0, len(entries))
 
@@ -3631,12 +9432,13 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 		if err != nil || meta == nil {
 			continue
 		}
+		scopeKey := scope.chunkKey(chunk)
 		if meta.StandardQuestion != "" {
-			existingQuestions[meta.StandardQuestion] = true
+			existingQuestions[scopedFAQQuestion(scopeKey, meta.StandardQuestion)] = true
 		}
 		for _, q := range meta.SimilarQuestions {
 			if q != "" {
-				existingQuestions[q] = true
+				existingQuestions[scopedFAQQuestion(scopeKey, q)] = true
 			}
 		}
 	}
@@ -3646,23 +9448,25 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 
 	for i, entry := range entries {
 		// 验证条目基本格式
-		if err := validateFAQEntryPayloadBasic(&entry); err != nil {
+		if err := validateFAQEntryPayloadBasic(&entry, allowEmptyAnswers, faqConfig); err != nil {
 			progress.FailedCount++
 			progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, err.Error(), &entry))
 			continue
 		}
 
 		standardQ := strings.TrimSpace(entry.StandardQuestion)
+		scopeKey := scope.entryKey(ctx, &entry)
+		standardKey := scopedFAQQuestion(scopeKey, standardQ)
 
 		// 检查标准问是否与已有知识库重复
-		if existingQuestions[standardQ] {
+		if existingQuestions[standardKey] {
 			progress.FailedCount++
 			progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, "标准问与知识库中已有问题重复", &entry))
 			continue
 		}
 
 		// 检查标准问是否与同批次重复
-		if firstIdx, exists := batchQuestions[standardQ]; exists {
+		if firstIdx, exists := batchQuestions[standardKey]; exists {
 			progress.FailedCount++
 			progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, fmt.Sprintf("标准问与批次内第 %d 条重复", firstIdx+1), &entry))
 			continue
@@ -3675,13 +9479,13 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 			if q == "" {
 				continue
 			}
-			if existingQuestions[q] {
+			if existingQuestions[scopedFAQQuestion(scopeKey, q)] {
 				progress.FailedCount++
 				progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, fmt.Sprintf("相似问 \"%s\" 与知识库中已有问题重复", q), &entry))
 				hasDuplicate = true
 				break
 			}
-			if firstIdx, exists := batchQuestions[q]; exists {
+			if firstIdx, exists := batchQuestions[scopedFAQQuestion(scopeKey, q)]; exists {
 				progress.FailedCount++
 				progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, fmt.Sprintf("相似问 \"%s\" 与批次内第 %d 条重复", q, firstIdx+1), &entry))
 				hasDuplicate = true
@@ -3693,11 +9497,11 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 		}
 
 		// 将当前条目的标准问和相似问加入批次集合
-		batchQuestions[standardQ] = i
+		batchQuestions[standardKey] = i
 		for _, q := range entry.SimilarQuestions {
 			q = strings.TrimSpace(q)
 			if q != "" {
-				batchQuestions[q] = i
+				batchQuestions[scopedFAQQuestion(scopeKey, q)] = i
 			}
 		}
 
@@ -3720,7 +9524,8 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 // validateEntriesForReplaceModeWithProgress 验证 Replace 模式下的条目（带进度更新）
 // 注意：验证阶段不更新 Processed，只有实际导入时才更新
 func (s *knowledgeService) validateEntriesForReplaceModeWithProgress(ctx context.Context,
-	entries []types.FAQEntryPayload, progress *types.FAQImportProgress,
+	scope *faqImportQuestionScope, entries []types.FAQEntryPayload, allowEmptyAnswers bool,
+	faqConfig *types.FAQConfig, progress *types.FAQImportProgress,
 ) []int {
 	validIndices := make([]int, 0, len(entries))
 
@@ -3729,16 +9534,18 @@ func (s *knowledgeService) validateEntriesForReplaceModeWithProgress(ctx context
 
 	for i, entry := range entries {
 		// 验证条目基本格式
-		if err := validateFAQEntryPayloadBasic(&entry); err != nil {
+		if err := validateFAQEntryPayloadBasic(&entry, allowEmptyAnswers, faqConfig); err != nil {
 			progress.FailedCount++
 			progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, err.Error(), &entry))
 			continue
 		}
 
 		standardQ := strings.TrimSpace(entry.StandardQuestion)
+		scopeKey := scope.entryKey(ctx, &entry)
+		standardKey := scopedFAQQuestion(scopeKey, standardQ)
 
 		// 检查标准问是否与同批次重复
-		if firstIdx, exists := batchQuestions[standardQ]; exists {
+		if firstIdx, exists := batchQuestions[standardKey]; exists {
 			progress.FailedCount++
 			progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, fmt.Sprintf("标准问与批次内第 %d 条重复", firstIdx+1), &entry))
 			continue
@@ -3751,7 +9558,7 @@ func (s *knowledgeService) validateEntriesForReplaceModeWithProgress(ctx context
 			if q == "" {
 				continue
 			}
-			if firstIdx, exists := batchQuestions[q]; exists {
+			if firstIdx, exists := batchQuestions[scopedFAQQuestion(scopeKey, q)]; exists {
 				progress.FailedCount++
 				progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, fmt.Sprintf("相似问 \"%s\" 与批次内第 %d 条重复", q, firstIdx+1), &entry))
 				hasDuplicate = true
@@ -3763,11 +9570,11 @@ func (s *knowledgeService) validateEntriesForReplaceModeWithProgress(ctx context
 		}
 
 		// 将当前条目的标准问和相似问加入批次集合
-		batchQuestions[standardQ] = i
+		batchQuestions[standardKey] = i
 		for _, q := range entry.SimilarQuestions {
 			q = strings.TrimSpace(q)
 			if q != "" {
-				batchQuestions[q] = i
+				batchQuestions[scopedFAQQuestion(scopeKey, q)] = i
 			}
 		}
 
@@ -3788,7 +9595,10 @@ func (s *knowledgeService) validateEntriesForReplaceModeWithProgress(ctx context
 }
 
 // validateFAQEntryPayloadBasic 验证 FAQ 条目的基本格式
-func validateFAQEntryPayloadBasic(entry *types.FAQEntryPayload) error {
+// allowEmptyAnswers 为 true 时允许条目没有答案（导入后标记为待补充答案）
+func validateFAQEntryPayloadBasic(entry *types.FAQEntryPayload, allowEmptyAnswers bool,
+	faqConfig *types.FAQConfig,
+) error {
 	if entry == nil {
 		return fmt.Errorf("条目不能为空")
 	}
@@ -3796,6 +9606,12 @@ func validateFAQEntryPayloadBasic(entry *types.FAQEntryPayload) error {
 	if standardQ == "" {
 		return fmt.Errorf("标准问不能为空")
 	}
+	if err := limitFAQStandardQuestion(&types.FAQChunkMetadata{StandardQuestion: standardQ}, faqConfig); err != nil {
+		return err
+	}
+	if allowEmptyAnswers {
+		return nil
+	}
 	if len(entry.Answers) == 0 {
 		return fmt.Errorf("答案不能为空")
 	}
@@ -3815,33 +9631,35 @@ func validateFAQEntryPayloadBasic(entry *types.FAQEntryPayload) error {
 // calculateAppendOperations 计算Append模式下需要处理的条目，跳过已存在且内容相同的条目
 // 同时过滤掉标准问或相似问与同批次或已有知识库中重复的条目
 func (s *knowledgeService) calculateAppendOperations(ctx context.Context,
-	tenantID uint64, kbID string, entries []types.FAQEntryPayload,
-) ([]types.FAQEntryPayload, int, error) {
+	tenantID uint64, kb *types.KnowledgeBase, entries []types.FAQEntryPayload, allowEmptyAnswers bool,
+) ([]types.FAQEntryPayload, []types.FAQSkippedEntry, error) {
 	if len(entries) == 0 {
-		return []types.FAQEntryPayload{}, 0, nil
+		return []types.FAQEntryPayload{}, nil, nil
 	}
 
 	// 1. 查询知识库中已有的所有FAQ chunks的metadata
-	existingChunks, err := s.chunkRepo.ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx, tenantID, kbID)
+	existingChunks, err := s.chunkRepo.ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx, tenantID, kb.ID)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to list existing FAQ chunks: %w", err)
+		return nil, nil, fmt.Errorf("failed to list existing FAQ chunks: %w", err)
 	}
 
-	// 2. 构建已存在的标准问和相似问集合
+	// 2. 构建已存在的标准问和相似问集合（按标签查重时以标签划分范围）
+	scope := s.newFAQImportQuestionScope(tenantID, kb)
 	existingQuestions := make(map[string]bool)
 	for _, chunk := range existingChunks {
 		meta, err := chunk.FAQMetadata()
 		if err != nil || meta == nil {
 			continue
 		}
+		scopeKey := scope.chunkKey(chunk)
 		// 添加标准问
 		if meta.StandardQuestion != "" {
-			existingQuestions[meta.StandardQuestion] = true
+			existingQuestions[scopedFAQQuestion(scopeKey, meta.StandardQuestion)] = true
 		}
 		// 添加相似问
 		for _, q := range meta.SimilarQuestions {
 			if q != "" {
-				existingQuestions[q] = true
+				existingQuestions[scopedFAQQuestion(scopeKey, q)] = true
 			}
 		}
 	}
@@ -3849,20 +9667,27 @@ func (s *knowledgeService) calculateAppendOperations(ctx context.Context,
 	// 3. 构建当前批次的标准问和相似问集合（用于批次内去重）
 	batchQuestions := make(map[string]bool)
 	entriesToProcess := make([]types.FAQEntryPayload, 0, len(entries))
-	skippedCount := 0
+	var skipped []types.FAQSkippedEntry
 
-	for _, entry := range entries {
-		meta, err := sanitizeFAQEntryPayload(&entry)
+	for i, entry := range entries {
+		meta, err := sanitizeFAQEntryPayload(&entry, allowEmptyAnswers, kb.FAQConfig)
 		if err != nil {
 			// 跳过无效条目
-			skippedCount++
+			skipped = append(skipped, buildFAQSkippedEntry(i, types.FAQSkipReasonInvalid, err.Error(), &entry, ""))
 			logger.Warnf(ctx, "Skipping invalid FAQ entry: %v", err)
 			continue
 		}
 
+		scopeKey := scope.entryKey(ctx, &entry)
+		standardKey := scopedFAQQuestion(scopeKey, meta.StandardQuestion)
+
 		// 检查标准问是否重复（与已有或同批次）
-		if existingQuestions[meta.StandardQuestion] || batchQuestions[meta.StandardQuestion] {
-			skippedCount++
+		if existingQuestions[standardKey] || batchQuestions[standardKey] {
+			reason := "标准问与知识库中已有问题重复"
+			if !existingQuestions[standardKey] {
+				reason = "标准问与同批次条目重复"
+			}
+			skipped = append(skipped, buildFAQSkippedEntry(i, types.FAQSkipReasonDuplicateStandardQuestion, reason, &entry, ""))
 			logger.Infof(ctx, "Skipping FAQ entry with duplicate standard question: %s", meta.StandardQuestion)
 			continue
 		}
@@ -3870,49 +9695,187 @@ func (s *knowledgeService) calculateAppendOperations(ctx context.Context,
 		// 检查相似问是否有重复（与已有或同批次）
 		hasDuplicateSimilar := false
 		for _, q := range meta.SimilarQuestions {
-			if existingQuestions[q] || batchQuestions[q] {
+			questionKey := scopedFAQQuestion(scopeKey, q)
+			if existingQuestions[questionKey] || batchQuestions[questionKey] {
 				hasDuplicateSimilar = true
+				reason := "相似问与知识库中已有问题重复"
+				if !existingQuestions[questionKey] {
+					reason = "相似问与同批次条目重复"
+				}
+				skipped = append(skipped, buildFAQSkippedEntry(i, types.FAQSkipReasonDuplicateSimilarQuestion, reason, &entry, q))
 				logger.Infof(ctx, "Skipping FAQ entry with duplicate similar question: %s (standard: %s)", q, meta.StandardQuestion)
 				break
 			}
 		}
 		if hasDuplicateSimilar {
-			skippedCount++
 			continue
 		}
 
 		// 将当前条目的标准问和相似问加入批次集合
-		batchQuestions[meta.StandardQuestion] = true
+		batchQuestions[standardKey] = true
 		for _, q := range meta.SimilarQuestions {
-			batchQuestions[q] = true
+			batchQuestions[scopedFAQQuestion(scopeKey, q)] = true
 		}
 
 		entriesToProcess = append(entriesToProcess, entry)
 	}
 
-	return entriesToProcess, skippedCount, nil
+	return entriesToProcess, skipped, nil
 }
 
-// calculateReplaceOperations 计算Replace模式下需要删除、创建、更新的条目
-// 同时过滤掉同批次内标准问或相似问重复的条目
-func (s *knowledgeService) calculateReplaceOperations(ctx context.Context,
-	tenantID uint64, knowledgeID string, newEntries []types.FAQEntryPayload,
-) ([]types.FAQEntryPayload, []*types.Chunk, int, error) {
-	// 获取 kbID 用于解析 tag
-	var kbID string
-	if len(newEntries) > 0 {
-		// 从 knowledgeID 获取 kbID
-		knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
-		if err != nil {
-			return nil, nil, 0, fmt.Errorf("failed to get knowledge: %w", err)
+const (
+	faqHashBackfillProgressKeyPrefix = "faq_hash_backfill_progress:"
+	faqHashBackfillProgressTTL       = 24 * time.Hour
+	faqHashBackfillBatchSize         = 200
+)
+
+// getFAQHashBackfillProgressKey returns the Redis key for storing content hash backfill progress
+func getFAQHashBackfillProgressKey(taskID string) string {
+	return faqHashBackfillProgressKeyPrefix + taskID
+}
+
+// BackfillChunkContentHashes computes ContentHash for FAQ chunks that were created before
+// content hashing existed. Without a hash, calculateReplaceOperations treats such chunks as
+// stale and deletes them on the next Replace import. Work runs in the background; the
+// returned progress can be polled with GetContentHashBackfillProgress.
+func (s *knowledgeService) BackfillChunkContentHashes(ctx context.Context,
+	kbID string,
+) (*types.FAQContentHashBackfillProgress, error) {
+	if _, err := s.validateFAQKnowledgeBase(ctx, kbID); err != nil {
+		return nil, err
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	chunks, err := s.chunkRepo.ListFAQChunksMissingContentHash(ctx, tenantID, kbID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	progress := &types.FAQContentHashBackfillProgress{
+		KnowledgeTaskProgress: types.KnowledgeTaskProgress{
+			TaskID:    secutils.GenerateTaskID("faq_hash_backfill", tenantID, kbID),
+			TenantID:  tenantID,
+			KBID:      kbID,
+			Status:    types.KnowledgeTaskStatusProcessing,
+			Total:     len(chunks),
+			Message:   "正在回填内容 hash...",
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	if len(chunks) == 0 {
+		progress.Status = types.KnowledgeTaskStatusCompleted
+		progress.Progress = 100
+		progress.Message = "没有缺少内容 hash 的条目"
+	}
+	if err := s.saveFAQHashBackfillProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save content hash backfill progress: %v", err)
+	}
+	if len(chunks) == 0 {
+		return progress, nil
+	}
+
+	logger.Infof(ctx, "Backfilling FAQ content hashes: kb_id=%s, chunks=%d, task_id=%s",
+		kbID, len(chunks), progress.TaskID)
+
+	newCtx := logger.CloneContext(ctx)
+	snapshot := *progress
+	go s.backfillContentHashes(newCtx, tenantID, chunks, &snapshot)
+
+	return progress, nil
+}
+
+// backfillContentHashes computes and persists content hashes batch by batch
+func (s *knowledgeService) backfillContentHashes(ctx context.Context,
+	tenantID uint64, chunks []*types.Chunk, progress *types.FAQContentHashBackfillProgress,
+) {
+	for start := 0; start < len(chunks); start += faqHashBackfillBatchSize {
+		end := min(start+faqHashBackfillBatchSize, len(chunks))
+		hashes := make(map[string]string, end-start)
+		failed := 0
+		for _, chunk := range chunks[start:end] {
+			meta, err := chunk.FAQMetadata()
+			if err != nil {
+				logger.Warnf(ctx, "Failed to parse FAQ metadata for chunk %s: %v", chunk.ID, err)
+				failed++
+				continue
+			}
+			// 与 buildFAQIndexInfoList 一致：缺少元数据时以分块内容作为标准问
+			if meta == nil {
+				meta = &types.FAQChunkMetadata{StandardQuestion: chunk.Content}
+			}
+			hash := types.CalculateFAQContentHash(meta)
+			if hash == "" {
+				failed++
+				continue
+			}
+			hashes[chunk.ID] = hash
 		}
-		if knowledge != nil {
-			kbID = knowledge.KnowledgeBaseID
+
+		if err := s.chunkRepo.UpdateChunkContentHashes(ctx, tenantID, hashes); err != nil {
+			logger.Errorf(ctx, "Failed to update content hashes: %v", err)
+			failed += len(hashes)
+		} else {
+			progress.Updated += len(hashes)
+		}
+		progress.Failed += failed
+		progress.Processed = end
+		progress.Progress = progress.Processed * 100 / progress.Total
+		progress.UpdatedAt = time.Now().Unix()
+		if err := s.saveFAQHashBackfillProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to update content hash backfill progress: %v", err)
+		}
+	}
+
+	progress.Status = types.KnowledgeTaskStatusCompleted
+	if progress.Updated == 0 {
+		progress.Status = types.KnowledgeTaskStatusFailed
+	}
+	progress.Progress = 100
+	progress.Message = fmt.Sprintf("已回填 %d 个条目的内容 hash，失败 %d 个", progress.Updated, progress.Failed)
+	progress.UpdatedAt = time.Now().Unix()
+	if err := s.saveFAQHashBackfillProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save final content hash backfill progress: %v", err)
+	}
+	logger.Infof(ctx, "Content hash backfill finished: task_id=%s, updated=%d, failed=%d",
+		progress.TaskID, progress.Updated, progress.Failed)
+}
+
+// saveFAQHashBackfillProgress saves the content hash backfill progress to Redis
+func (s *knowledgeService) saveFAQHashBackfillProgress(ctx context.Context,
+	progress *types.FAQContentHashBackfillProgress,
+) error {
+	return s.saveTaskProgress(ctx, getFAQHashBackfillProgressKey(progress.TaskID), progress, faqHashBackfillProgressTTL)
+}
+
+// GetContentHashBackfillProgress retrieves the progress of a content hash backfill task
+func (s *knowledgeService) GetContentHashBackfillProgress(ctx context.Context,
+	taskID string,
+) (*types.FAQContentHashBackfillProgress, error) {
+	var progress types.FAQContentHashBackfillProgress
+	if err := s.loadTaskProgress(ctx, getFAQHashBackfillProgressKey(taskID), &progress); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Content hash backfill task not found")
 		}
+		return nil, err
 	}
+	return &progress, nil
+}
+
+// calculateReplaceOperations 计算Replace模式下需要删除、创建、更新的条目
+// 同时过滤掉同批次内标准问或相似问重复的条目
+func (s *knowledgeService) calculateReplaceOperations(ctx context.Context,
+	tenantID uint64, kb *types.KnowledgeBase, knowledgeID string, newEntries []types.FAQEntryPayload,
+	allowEmptyAnswers bool,
+) ([]types.FAQEntryPayload, []*types.Chunk, []types.FAQSkippedEntry, error) {
+	kbID := kb.ID
+	// 批次内查重范围（按标签查重时以标签划分）
+	scope := s.newFAQImportQuestionScope(tenantID, kb)
 
 	// 计算所有新条目的 content hash，并同时构建 hash 到 entry 的映射
 	type entryWithHash struct {
+		index int
 		entry types.FAQEntryPayload
 		hash  string
 		meta  *types.FAQChunkMetadata
@@ -3921,19 +9884,23 @@ func (s *knowledgeService) calculateReplaceOperations(ctx context.Context,
 	newHashSet := make(map[string]bool)
 	// 用于批次内标准问和相似问去重
 	batchQuestions := make(map[string]bool)
-	batchSkippedCount := 0
+	var skipped []types.FAQSkippedEntry
 
-	for _, entry := range newEntries {
-		meta, err := sanitizeFAQEntryPayload(&entry)
+	for i, entry := range newEntries {
+		meta, err := sanitizeFAQEntryPayload(&entry, allowEmptyAnswers, kb.FAQConfig)
 		if err != nil {
-			batchSkippedCount++
+			skipped = append(skipped, buildFAQSkippedEntry(i, types.FAQSkipReasonInvalid, err.Error(), &entry, ""))
 			logger.Warnf(ctx, "Skipping invalid FAQ entry in replace mode: %v", err)
 			continue
 		}
 
+		scopeKey := scope.entryKey(ctx, &entry)
+		standardKey := scopedFAQQuestion(scopeKey, meta.StandardQuestion)
+
 		// 检查标准问是否在同批次中重复
-		if batchQuestions[meta.StandardQuestion] {
-			batchSkippedCount++
+		if batchQuestions[standardKey] {
+			skipped = append(skipped, buildFAQSkippedEntry(i, types.FAQSkipReasonDuplicateStandardQuestion,
+				"标准问与同批次条目重复", &entry, ""))
 			logger.Infof(ctx, "Skipping FAQ entry with duplicate standard question in batch: %s", meta.StandardQuestion)
 			continue
 		}
@@ -3941,26 +9908,27 @@ func (s *knowledgeService) calculateReplaceOperations(ctx context.Context,
 		// 检查相似问是否在同批次中重复
 		hasDuplicateSimilar := false
 		for _, q := range meta.SimilarQuestions {
-			if batchQuestions[q] {
+			if batchQuestions[scopedFAQQuestion(scopeKey, q)] {
 				hasDuplicateSimilar = true
+				skipped = append(skipped, buildFAQSkippedEntry(i, types.FAQSkipReasonDuplicateSimilarQuestion,
+					"相似问与同批次条目重复", &entry, q))
 				logger.Infof(ctx, "Skipping FAQ entry with duplicate similar question in batch: %s (standard: %s)", q, meta.StandardQuestion)
 				break
 			}
 		}
 		if hasDuplicateSimilar {
-			batchSkippedCount++
 			continue
 		}
 
 		// 将当前条目的标准问和相似问加入批次集合
-		batchQuestions[meta.StandardQuestion] = true
+		batchQuestions[standardKey] = true
 		for _, q := range meta.SimilarQuestions {
-			batchQuestions[q] = true
+			batchQuestions[scopedFAQQuestion(scopeKey, q)] = true
 		}
 
 		hash := types.CalculateFAQContentHash(meta)
 		if hash != "" {
-			entriesWithHash = append(entriesWithHash, entryWithHash{entry: entry, hash: hash, meta: meta})
+			entriesWithHash = append(entriesWithHash, entryWithHash{index: i, entry: entry, hash: hash, meta: meta})
 			newHashSet[hash] = true
 		}
 	}
@@ -3968,7 +9936,7 @@ func (s *knowledgeService) calculateReplaceOperations(ctx context.Context,
 	// 查询所有已存在的chunks
 	allExistingChunks, err := s.chunkRepo.ListAllFAQChunksByKnowledgeID(ctx, tenantID, knowledgeID)
 	if err != nil {
-		return nil, nil, 0, fmt.Errorf("failed to list existing chunks: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to list existing chunks: %w", err)
 	}
 
 	// 在内存中过滤出匹配新条目hash的chunks，并构建map
@@ -3993,7 +9961,6 @@ func (s *knowledgeService) calculateReplaceOperations(ctx context.Context,
 
 	// 计算需要创建的条目（利用已经计算好的hash，避免重复计算）
 	entriesToProcess := make([]types.FAQEntryPayload, 0, len(entriesWithHash))
-	skippedCount := batchSkippedCount
 
 	for _, ewh := range entriesWithHash {
 		existingChunk := existingHashMap[ewh.hash]
@@ -4013,7 +9980,8 @@ func (s *knowledgeService) calculateReplaceOperations(ctx context.Context,
 				entriesToProcess = append(entriesToProcess, ewh.entry)
 			} else {
 				// hash 和 tag 都相同，跳过
-				skippedCount++
+				skipped = append(skipped, buildFAQSkippedEntry(ewh.index, types.FAQSkipReasonUnchanged,
+					"内容与分类均未变化", &ewh.entry, ""))
 			}
 			continue
 		}
@@ -4022,13 +9990,15 @@ func (s *knowledgeService) calculateReplaceOperations(ctx context.Context,
 		entriesToProcess = append(entriesToProcess, ewh.entry)
 	}
 
-	return entriesToProcess, chunksToDelete, skippedCount, nil
+	return entriesToProcess, chunksToDelete, skipped, nil
 }
 
 // executeFAQImport 执行实际的FAQ导入逻辑
+// entryIndices maps each entry of payload to its index in the original import file
+// so that skipped entries can be reported against the rows the user uploaded.
 func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string, kbID string,
 	payload *types.FAQBatchUpsertPayload, tenantID uint64, processedCount int,
-	progress *types.FAQImportProgress,
+	progress *types.FAQImportProgress, entryIndices []int,
 ) (err error) {
 	// 保存知识库和embedding模型信息，用于清理索引
 	var kb *types.KnowledgeBase
@@ -4073,15 +10043,17 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 	// 增量更新逻辑：计算需要处理的条目
 	var entriesToProcess []types.FAQEntryPayload
 	var chunksToDelete []*types.Chunk
-	var skippedCount int
+	var skippedEntries []types.FAQSkippedEntry
 
 	if payload.Mode == types.FAQBatchModeReplace {
 		// Replace模式：计算需要删除、创建、更新的条目
-		entriesToProcess, chunksToDelete, skippedCount, err = s.calculateReplaceOperations(
+		entriesToProcess, chunksToDelete, skippedEntries, err = s.calculateReplaceOperations(
 			ctx,
 			tenantID,
+			kb,
 			faqKnowledge.ID,
 			payload.Entries,
+			payload.AllowEmptyAnswers,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to calculate replace operations: %w", err)
@@ -4104,12 +10076,34 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 		}
 	} else {
 		// Append模式：查询已存在的条目，跳过未变化的
-		entriesToProcess, skippedCount, err = s.calculateAppendOperations(ctx, tenantID, kb.ID, payload.Entries)
+		entriesToProcess, skippedEntries, err = s.calculateAppendOperations(ctx, tenantID, kb, payload.Entries, payload.AllowEmptyAnswers)
 		if err != nil {
 			return fmt.Errorf("failed to calculate append operations: %w", err)
 		}
 	}
 
+	// 记录跳过条目及原因，索引映射回原始导入文件中的位置
+	skippedCount := len(skippedEntries)
+	if skippedCount > 0 {
+		for i := range skippedEntries {
+			if idx := skippedEntries[i].Index; idx < len(entryIndices) {
+				skippedEntries[i].Index = entryIndices[idx]
+			}
+		}
+		progress.SkippedCount += skippedCount
+		progress.SkippedEntries = append(progress.SkippedEntries, skippedEntries...)
+		// 超过内联上限时完整列表导出为 CSV，进度中仅保留前 N 条，避免进度数据过大
+		if len(progress.SkippedEntries) > types.MaxInlineFAQSkippedEntries {
+			csvURL, err := s.generateSkippedEntriesCSV(ctx, tenantID, taskID, progress.SkippedEntries)
+			if err != nil {
+				logger.Warnf(ctx, "Failed to generate skipped entries CSV: %v", err)
+			} else {
+				progress.SkippedEntriesURL = csvURL
+			}
+			progress.SkippedEntries = progress.SkippedEntries[:types.MaxInlineFAQSkippedEntries]
+		}
+	}
+
 	logger.Infof(
 		ctx,
 		"FAQ import task %s: total entries: %d, to process: %d, skipped: %d",
@@ -4129,14 +10123,21 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 	remainingEntries := len(entriesToProcess)
 	totalStartTime := time.Now()
 	actualProcessed := skippedCount + processedCount
+	// 失败预算：单批失败数与累计失败率，超过后提前终止导入
+	maxBatchFailures := kb.FAQConfig.GetImportMaxBatchFailures()
+	failureRateThreshold := kb.FAQConfig.GetImportFailureRateThreshold()
+	importFailed, attempted := 0, 0
 
 	logger.Infof(
 		ctx,
-		"FAQ import task %s: starting batch processing, remaining entries: %d, total entries: %d, batch size: %d",
+		"FAQ import task %s: starting batch processing, remaining entries: %d, total entries: %d, batch size: %d, "+
+			"max batch failures: %d, failure rate threshold: %.2f",
 		taskID,
 		remainingEntries,
 		totalEntries,
 		faqImportBatchSize,
+		maxBatchFailures,
+		failureRateThreshold,
 	)
 
 	for i := 0; i < remainingEntries; i += faqImportBatchSize {
@@ -4149,28 +10150,35 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 		batch := entriesToProcess[i:end]
 		logger.Infof(ctx, "FAQ import task %s: processing batch %d-%d (%d entries)", taskID, i+1, end, len(batch))
 
-		// 构建chunks
+		// 构建chunks，单条目的校验/解析错误记为失败条目，不影响同批其他条目
 		buildStartTime := time.Now()
-		chunks := make([]*types.Chunk, 0, len(batch))
-		chunkIds := make([]string, 0, len(batch))
-		for idx, entry := range batch {
-			meta, err := sanitizeFAQEntryPayload(&entry)
+		batchFailures := 0
+		recordFailure := func(item *faqImportItem, reason string) {
+			batchFailures++
+			importFailed++
+			progress.FailedCount++
+			progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(item.index, reason, item.entry))
+			logger.Warnf(ctx, "FAQ import task %s: entry %d failed: %s", taskID, item.index, reason)
+		}
+		items := make([]*faqImportItem, 0, len(batch))
+		for idx := range batch {
+			entry := &batch[idx]
+			item := &faqImportItem{entry: entry, index: i + idx + processedCount}
+			meta, err := sanitizeFAQEntryPayload(entry, payload.AllowEmptyAnswers, kb.FAQConfig)
 			if err != nil {
-				logger.ErrorWithFields(ctx, err, map[string]interface{}{
-					"entry":   entry,
-					"task_id": taskID,
-				})
-				return fmt.Errorf("failed to sanitize entry at index %d: %w", i+idx, err)
+				recordFailure(item, err.Error())
+				continue
 			}
+			meta.ApplyOrder(kb.FAQConfig.GetAnswerOrder())
 
 			// 解析 TagID
-			tagID, err := s.resolveTagID(ctx, kbID, &entry)
+			tagID, err := s.resolveTagID(ctx, kbID, entry)
 			if err != nil {
-				logger.ErrorWithFields(ctx, err, map[string]interface{}{
-					"entry":   entry,
-					"task_id": taskID,
-				})
-				return fmt.Errorf("failed to resolve tag for entry at index %d: %w", i+idx, err)
+				if isSystemicFAQImportError(err) {
+					return fmt.Errorf("failed to resolve tag for entry at index %d: %w", item.index, err)
+				}
+				recordFailure(item, fmt.Sprintf("解析分类失败: %v", err))
+				continue
 			}
 
 			isEnabled := true
@@ -4195,18 +10203,27 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 				chunk.SeqID = *entry.ID
 			}
 			if err := chunk.SetFAQMetadata(meta); err != nil {
-				return fmt.Errorf("failed to set FAQ metadata: %w", err)
+				recordFailure(item, fmt.Sprintf("设置FAQ元数据失败: %v", err))
+				continue
 			}
-			chunks = append(chunks, chunk)
-			chunkIds = append(chunkIds, chunk.ID)
+			item.chunk = chunk
+			items = append(items, item)
 		}
 		buildDuration := time.Since(buildStartTime)
-		logger.Debugf(ctx, "FAQ import task %s: batch %d-%d built %d chunks in %v, chunk IDs: %v",
-			taskID, i+1, end, len(chunks), buildDuration, chunkIds)
+		logger.Debugf(ctx, "FAQ import task %s: batch %d-%d built %d chunks in %v",
+			taskID, i+1, end, len(items), buildDuration)
+		// 尚未写入任何分块时可直接终止；写入后需完成本批索引再检查，
+		// 保证终止时每个条目要么已完整导入，要么已记为失败，不留下未索引的分块
+		if err := checkFAQImportFailureBudget(batchFailures, maxBatchFailures, importFailed,
+			attempted+len(batch), failureRateThreshold); err != nil {
+			return err
+		}
+
 		// 创建chunks
 		createStartTime := time.Now()
-		if err := s.chunkService.CreateChunks(ctx, chunks); err != nil {
-			return fmt.Errorf("failed to create chunks: %w", err)
+		items, err = s.createFAQImportChunks(ctx, taskID, items, recordFailure)
+		if err != nil {
+			return err
 		}
 		createDuration := time.Since(createStartTime)
 		logger.Infof(
@@ -4215,15 +10232,15 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 			taskID,
 			i+1,
 			end,
-			len(chunks),
+			len(items),
 			createDuration,
 		)
 
 		// 索引chunks
 		indexStartTime := time.Now()
-		// 注意：如果索引失败，defer中的recovery机制会自动回滚已创建的chunks和索引数据
-		if err := s.indexFAQChunks(ctx, kb, faqKnowledge, chunks, embeddingModel, true, false); err != nil {
-			return fmt.Errorf("failed to index chunks: %w", err)
+		items, err = s.indexFAQImportChunks(ctx, taskID, kb, faqKnowledge, items, embeddingModel, recordFailure)
+		if err != nil {
+			return err
 		}
 		indexDuration := time.Since(indexStartTime)
 		logger.Infof(
@@ -4232,28 +10249,31 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 			taskID,
 			i+1,
 			end,
-			len(chunks),
+			len(items),
 			indexDuration,
 		)
 
 		// 更新chunks的Status为已索引
-		chunksToUpdate := make([]*types.Chunk, 0, len(chunks))
-		for _, chunk := range chunks {
-			chunk.Status = int(types.ChunkStatusIndexed) // indexed
-			chunksToUpdate = append(chunksToUpdate, chunk)
+		chunksToUpdate := make([]*types.Chunk, 0, len(items))
+		for _, item := range items {
+			item.chunk.Status = int(types.ChunkStatusIndexed) // indexed
+			chunksToUpdate = append(chunksToUpdate, item.chunk)
 		}
 		if err := s.chunkService.UpdateChunks(ctx, chunksToUpdate); err != nil {
 			return fmt.Errorf("failed to update chunks status: %w", err)
 		}
 
 		// 收集成功条目信息
-		for idx, chunk := range chunks {
-			entryIdx := i + idx + processedCount // 原始条目索引
+		for _, item := range items {
+			chunk := item.chunk
 			meta, _ := chunk.FAQMetadata()
 			standardQ := ""
+			unindexed := 0
 			if meta != nil {
 				standardQ = meta.StandardQuestion
+				unindexed = faqUnindexedSimilarQuestionCount(kb, meta.SimilarQuestions)
 			}
+			progress.UnindexedSimilarQuestions += unindexed
 			// 获取 tag info
 			var tagID int64
 			tagName := ""
@@ -4264,20 +10284,27 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 				}
 			}
 			progress.SuccessEntries = append(progress.SuccessEntries, types.FAQSuccessEntry{
-				Index:            entryIdx,
+				Index:            item.index,
 				SeqID:            chunk.SeqID,
 				TagID:            tagID,
 				TagName:          tagName,
 				StandardQuestion: standardQ,
+
+				UnindexedSimilarQuestions: unindexed,
 			})
 		}
 
+		attempted += len(batch)
 		actualProcessed += len(batch)
 		// 更新任务进度
 		progress := int(float64(actualProcessed) / float64(totalEntries) * 100)
 		if err := s.updateFAQImportProgressStatus(ctx, taskID, types.FAQImportStatusProcessing, progress, totalEntries, actualProcessed, fmt.Sprintf("正在处理第 %d/%d 条", actualProcessed, totalEntries), ""); err != nil {
 			logger.Errorf(ctx, "Failed to update task progress: %v", err)
 		}
+		if err := checkFAQImportFailureBudget(batchFailures, maxBatchFailures, importFailed,
+			attempted, failureRateThreshold); err != nil {
+			return err
+		}
 
 		batchDuration := time.Since(batchStartTime)
 		logger.Infof(
@@ -4299,10 +10326,11 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 	totalDuration := time.Since(totalStartTime)
 	logger.Infof(
 		ctx,
-		"FAQ import task %s: all batches completed, processed: %d entries (skipped: %d) in %v, avg: %v per entry",
+		"FAQ import task %s: all batches completed, processed: %d entries (skipped: %d, failed: %d) in %v, avg: %v per entry",
 		taskID,
 		actualProcessed,
 		skippedCount,
+		importFailed,
 		totalDuration,
 		totalDuration/time.Duration(actualProcessed),
 	)
@@ -4310,6 +10338,132 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 	return nil
 }
 
+// faqImportItem 关联导入批次中的条目与其构建出的 chunk
+type faqImportItem struct {
+	entry *types.FAQEntryPayload
+	index int // 原始条目索引
+	chunk *types.Chunk
+}
+
+// isSystemicFAQImportError 判断错误是否为系统性错误（数据库/网络不可用、配额不足、任务取消），
+// 这类错误不应按单条目失败处理，而是终止整个导入并交由任务重试
+func isSystemicFAQImportError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var quotaErr *types.StorageQuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// checkFAQImportFailureBudget 检查导入失败是否超出预算：单批失败数超过上限，或累计失败率超过阈值
+func checkFAQImportFailureBudget(batchFailures, maxBatchFailures, failed, attempted int, rateThreshold float64) error {
+	if batchFailures > maxBatchFailures {
+		return fmt.Errorf("%w: 单批失败 %d 条，超过上限 %d 条",
+			ErrFAQImportFailureBudgetExceeded, batchFailures, maxBatchFailures)
+	}
+	if attempted > 0 && float64(failed)/float64(attempted) > rateThreshold {
+		return fmt.Errorf("%w: 已失败 %d/%d 条，失败率超过阈值 %.0f%%",
+			ErrFAQImportFailureBudgetExceeded, failed, attempted, rateThreshold*100)
+	}
+	return nil
+}
+
+// createFAQImportChunks 批量创建 chunks；批量失败且非系统性错误时逐条重试，以隔离出错的条目
+func (s *knowledgeService) createFAQImportChunks(ctx context.Context, taskID string,
+	items []*faqImportItem, recordFailure func(*faqImportItem, string),
+) ([]*faqImportItem, error) {
+	if len(items) == 0 {
+		return items, nil
+	}
+	chunks := make([]*types.Chunk, 0, len(items))
+	for _, item := range items {
+		chunks = append(chunks, item.chunk)
+	}
+	err := s.chunkService.CreateChunks(ctx, chunks)
+	if err == nil {
+		return items, nil
+	}
+	if isSystemicFAQImportError(err) {
+		return nil, fmt.Errorf("failed to create chunks: %w", err)
+	}
+
+	logger.Warnf(ctx, "FAQ import task %s: batch create failed, retrying %d chunks one by one: %v",
+		taskID, len(items), err)
+	created := make([]*faqImportItem, 0, len(items))
+	for _, item := range items {
+		if err := s.chunkService.CreateChunks(ctx, []*types.Chunk{item.chunk}); err != nil {
+			if isSystemicFAQImportError(err) {
+				return nil, fmt.Errorf("failed to create chunks: %w", err)
+			}
+			recordFailure(item, fmt.Sprintf("创建分块失败: %v", err))
+			continue
+		}
+		created = append(created, item)
+	}
+	return created, nil
+}
+
+// indexFAQImportChunks 批量索引 chunks；批量失败且非系统性错误时逐条重试，
+// 仍失败的条目会删除已创建的 chunk 并记为失败条目
+func (s *knowledgeService) indexFAQImportChunks(ctx context.Context, taskID string,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, items []*faqImportItem,
+	embeddingModel embedding.Embedder, recordFailure func(*faqImportItem, string),
+) ([]*faqImportItem, error) {
+	if len(items) == 0 {
+		return items, nil
+	}
+	chunks := make([]*types.Chunk, 0, len(items))
+	for _, item := range items {
+		chunks = append(chunks, item.chunk)
+	}
+	err := s.indexFAQChunks(ctx, kb, knowledge, chunks, embeddingModel, true, false)
+	if err == nil {
+		return items, nil
+	}
+	if isSystemicFAQImportError(err) {
+		return nil, fmt.Errorf("failed to index chunks: %w", err)
+	}
+
+	logger.Warnf(ctx, "FAQ import task %s: batch index failed, retrying %d chunks one by one: %v",
+		taskID, len(items), err)
+	indexed := make([]*faqImportItem, 0, len(items))
+	failedChunks := make([]*types.Chunk, 0)
+	for _, item := range items {
+		// needDelete 清理批量索引时可能已写入的部分向量
+		if err := s.indexFAQChunks(ctx, kb, knowledge, []*types.Chunk{item.chunk}, embeddingModel, true, true); err != nil {
+			if isSystemicFAQImportError(err) {
+				return nil, fmt.Errorf("failed to index chunks: %w", err)
+			}
+			recordFailure(item, fmt.Sprintf("索引失败: %v", err))
+			failedChunks = append(failedChunks, item.chunk)
+			continue
+		}
+		indexed = append(indexed, item)
+	}
+
+	if len(failedChunks) > 0 {
+		failedIDs := make([]string, 0, len(failedChunks))
+		for _, chunk := range failedChunks {
+			failedIDs = append(failedIDs, chunk.ID)
+		}
+		if err := s.deleteFAQChunkVectors(ctx, kb, knowledge, failedChunks); err != nil {
+			logger.Warnf(ctx, "FAQ import task %s: failed to delete vectors of failed chunks: %v", taskID, err)
+		}
+		if err := s.chunkRepo.DeleteChunks(ctx, knowledge.TenantID, failedIDs); err != nil {
+			return nil, fmt.Errorf("failed to delete failed chunks: %w", err)
+		}
+	}
+	return indexed, nil
+}
+
 // CreateFAQEntry creates a single FAQ entry synchronously.
 func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
 	kbID string, payload *types.FAQEntryPayload,
@@ -4322,15 +10476,19 @@ func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	if err := s.ensureKnowledgeBaseWritable(ctx, kb.ID); err != nil {
+		return nil, err
+	}
 	kb.EnsureDefaults()
 
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 
 	// 验证并清理输入
-	meta, err := sanitizeFAQEntryPayload(payload)
+	meta, err := sanitizeFAQEntryPayload(payload, false, kb.FAQConfig)
 	if err != nil {
 		return nil, err
 	}
+	meta.ApplyOrder(kb.FAQConfig.GetAnswerOrder())
 
 	// 解析 TagID
 	tagID, err := s.resolveTagID(ctx, kbID, payload)
@@ -4339,7 +10497,7 @@ func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
 	}
 
 	// 检查标准问和相似问是否与其他条目重复
-	if err := s.checkFAQQuestionDuplicate(ctx, tenantID, kb.ID, "", meta); err != nil {
+	if err := s.checkFAQQuestionDuplicate(ctx, tenantID, kb.ID, "", newFAQDuplicateScope(kb, tagID), meta); err != nil {
 		return nil, err
 	}
 
@@ -4464,11 +10622,52 @@ func (s *knowledgeService) GetFAQEntry(ctx context.Context,
 		return nil, werrors.NewNotFoundError("FAQ条目不存在")
 	}
 
-	// 验证是FAQ类型
-	if chunk.ChunkType != types.ChunkTypeFAQ {
+	// 验证是FAQ类型
+	if chunk.ChunkType != types.ChunkTypeFAQ {
+		return nil, werrors.NewNotFoundError("FAQ条目不存在")
+	}
+
+	return s.faqChunkToEntry(ctx, tenantID, kb, chunk)
+}
+
+// GetFAQEntryByStandardQuestion retrieves a FAQ entry by its standard question.
+// The question is normalized the same way as on create, so surrounding whitespace is ignored
+// and an over-long question is matched by the part that would have been stored as standard question.
+func (s *knowledgeService) GetFAQEntryByStandardQuestion(ctx context.Context,
+	kbID string, question string,
+) (*types.FAQEntry, error) {
+	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	kb.EnsureDefaults()
+
+	meta := &types.FAQChunkMetadata{StandardQuestion: question}
+	meta.Normalize()
+	if meta.StandardQuestion == "" {
+		return nil, werrors.NewBadRequestError("标准问不能为空")
+	}
+	if err := limitFAQStandardQuestion(meta, kb.FAQConfig); err != nil {
+		// 超长且按配置拒绝的标准问不可能已入库
+		return nil, werrors.NewNotFoundError("FAQ条目不存在")
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	chunk, err := s.chunkRepo.GetFAQChunkByStandardQuestion(ctx, tenantID, kb.ID, meta.StandardQuestion)
+	if err != nil {
+		return nil, err
+	}
+	if chunk == nil {
 		return nil, werrors.NewNotFoundError("FAQ条目不存在")
 	}
 
+	return s.faqChunkToEntry(ctx, tenantID, kb, chunk)
+}
+
+// faqChunkToEntry converts a FAQ chunk to an entry, filling the tag seq_id and tag name
+func (s *knowledgeService) faqChunkToEntry(ctx context.Context,
+	tenantID uint64, kb *types.KnowledgeBase, chunk *types.Chunk,
+) (*types.FAQEntry, error) {
 	// Build tag seq_id map for conversion
 	tagSeqIDMap := make(map[string]int64)
 	if chunk.TagID != "" {
@@ -4519,15 +10718,34 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 	if chunk.ChunkType != types.ChunkTypeFAQ {
 		return nil, werrors.NewBadRequestError("仅支持更新 FAQ 条目")
 	}
-	meta, err := sanitizeFAQEntryPayload(payload)
+	meta, err := sanitizeFAQEntryPayload(payload, false, kb.FAQConfig)
 	if err != nil {
 		return nil, err
 	}
+	meta.ApplyOrder(kb.FAQConfig.GetAnswerOrder())
+
+	// Convert tag seq_id to UUID
+	newTagID := ""
+	if payload.TagID > 0 {
+		tag, tagErr := s.tagRepo.GetBySeqID(ctx, tenantID, payload.TagID)
+		if tagErr != nil {
+			return nil, werrors.NewNotFoundError("标签不存在")
+		}
+		newTagID = tag.ID
+	}
 
 	// 检查标准问和相似问是否与其他条目重复
-	if err := s.checkFAQQuestionDuplicate(ctx, tenantID, kb.ID, chunk.ID, meta); err != nil {
+	if err := s.checkFAQQuestionDuplicate(ctx, tenantID, kb.ID, chunk.ID, newFAQDuplicateScope(kb, newTagID), meta); err != nil {
+		return nil, err
+	}
+
+	// 记录更新前的索引内容和属性，用于判断是否需要重新索引
+	oldIndexInfos, err := s.buildFAQIndexInfoList(ctx, kb, chunk)
+	if err != nil {
 		return nil, err
 	}
+	oldTagID := chunk.TagID
+	oldIsEnabled := chunk.IsEnabled
 
 	// 获取旧的相似问列表，用于增量更新
 	var oldSimilarQuestions []string
@@ -4540,7 +10758,8 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 	if existing, err := chunk.FAQMetadata(); err == nil && existing != nil {
 		meta.Version = existing.Version + 1
 		// 保存旧的内容用于增量比较
-		if questionIndexMode == types.FAQQuestionIndexModeSeparate {
+		if questionIndexMode == types.FAQQuestionIndexModeSeparate ||
+			questionIndexMode == types.FAQQuestionIndexModeHybrid {
 			oldSimilarQuestions = existing.SimilarQuestions
 			oldStandardQuestion = existing.StandardQuestion
 			oldAnswers = existing.Answers
@@ -4555,17 +10774,7 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 		indexMode = kb.FAQConfig.IndexMode
 	}
 	chunk.Content = buildFAQChunkContent(meta, indexMode)
-
-	// Convert tag seq_id to UUID
-	if payload.TagID > 0 {
-		tag, tagErr := s.tagRepo.GetBySeqID(ctx, tenantID, payload.TagID)
-		if tagErr != nil {
-			return nil, werrors.NewNotFoundError("标签不存在")
-		}
-		chunk.TagID = tag.ID
-	} else {
-		chunk.TagID = ""
-	}
+	chunk.TagID = newTagID
 
 	if payload.IsEnabled != nil {
 		chunk.IsEnabled = *payload.IsEnabled
@@ -4583,10 +10792,23 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 		return nil, err
 	}
 
-	// Note: We don't need to call BatchUpdateChunkEnabledStatus here because
+	// Note: We don't need to call BatchUpdateChunkEnabledStatus when re-indexing because
 	// indexFAQChunks will delete old vectors and re-insert with the latest chunk data
 	// (including the updated is_enabled status). Calling both would cause version conflicts.
 
+	newIndexInfos, err := s.buildFAQIndexInfoList(ctx, kb, chunk)
+	if err != nil {
+		return nil, err
+	}
+	if sameFAQIndexContent(oldIndexInfos, newIndexInfos) {
+		// 索引内容未变化（如仅修改标签、启用状态），跳过重新向量化，仅同步属性到检索引擎
+		logger.Debugf(ctx, "UpdateFAQEntry: indexed content unchanged, skipping re-index for chunk %s", chunk.ID)
+		if err := s.syncFAQChunkIndexAttributes(ctx, chunk, oldTagID, oldIsEnabled); err != nil {
+			return nil, err
+		}
+		return s.buildUpdatedFAQEntry(ctx, tenantID, kb, chunk)
+	}
+
 	faqKnowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, chunk.KnowledgeID)
 	if err != nil {
 		return nil, err
@@ -4598,8 +10820,10 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 	}
 
 	// 增量索引优化：只对变化的内容进行索引操作
-	if questionIndexMode == types.FAQQuestionIndexModeSeparate && len(oldSimilarQuestions) > 0 {
-		// 分别索引模式下的增量更新
+	if (questionIndexMode == types.FAQQuestionIndexModeSeparate && len(oldSimilarQuestions) > 0) ||
+		(questionIndexMode == types.FAQQuestionIndexModeHybrid && oldStandardQuestion != "") {
+		// 分别索引/混合索引模式下的增量更新
+		// 混合索引模式下相似问被清空时需要删除合并索引项，因此只要存在旧内容就走增量路径
 		if err := s.incrementalIndexFAQEntry(ctx, kb, faqKnowledge, chunk, embeddingModel,
 			oldStandardQuestion, oldSimilarQuestions, oldAnswers, meta); err != nil {
 			return nil, err
@@ -4609,19 +10833,23 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 		// 增量删除：只删除被移除的相似问索引
 		oldSimilarQuestionCount := len(oldSimilarQuestions)
 		newSimilarQuestionCount := len(meta.SimilarQuestions)
+		sourceIDsToDelete := make([]string, 0)
 		if questionIndexMode == types.FAQQuestionIndexModeSeparate && oldSimilarQuestionCount > newSimilarQuestionCount {
+			for i := newSimilarQuestionCount; i < oldSimilarQuestionCount; i++ {
+				sourceIDsToDelete = append(sourceIDsToDelete, fmt.Sprintf("%s-%d", chunk.ID, i))
+			}
+		}
+		// 被移除或清空的答案独立索引项也需要删除，EFPutDocument 只会覆盖仍然存在的 SourceID
+		if faqIndexesAnswersSeparately(indexMode, questionIndexMode) {
+			sourceIDsToDelete = append(sourceIDsToDelete, staleFAQAnswerSourceIDs(chunk.ID, oldAnswers, meta.Answers)...)
+		}
+		if len(sourceIDsToDelete) > 0 {
 			tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
 			retrieveEngine, engineErr := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
 			if engineErr == nil {
-				sourceIDsToDelete := make([]string, 0, oldSimilarQuestionCount-newSimilarQuestionCount)
-				for i := newSimilarQuestionCount; i < oldSimilarQuestionCount; i++ {
-					sourceIDsToDelete = append(sourceIDsToDelete, fmt.Sprintf("%s-%d", chunk.ID, i))
-				}
-				if len(sourceIDsToDelete) > 0 {
-					logger.Debugf(ctx, "UpdateFAQEntry: incremental delete %d obsolete source IDs", len(sourceIDsToDelete))
-					if delErr := retrieveEngine.DeleteBySourceIDList(ctx, sourceIDsToDelete, embeddingModel.GetDimensions(), types.KnowledgeTypeFAQ); delErr != nil {
-						logger.Warnf(ctx, "UpdateFAQEntry: failed to delete obsolete source IDs: %v", delErr)
-					}
+				logger.Debugf(ctx, "UpdateFAQEntry: incremental delete %d obsolete source IDs", len(sourceIDsToDelete))
+				if delErr := retrieveEngine.DeleteBySourceIDList(ctx, sourceIDsToDelete, embeddingModel.GetDimensions(), types.KnowledgeTypeFAQ); delErr != nil {
+					logger.Warnf(ctx, "UpdateFAQEntry: failed to delete obsolete source IDs: %v", delErr)
 				}
 			}
 		}
@@ -4632,12 +10860,22 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 		}
 	}
 
+	return s.buildUpdatedFAQEntry(ctx, tenantID, kb, chunk)
+}
+
+// buildUpdatedFAQEntry converts an updated FAQ chunk into the FAQEntry returned to callers,
+// resolving its tag seq_id and name.
+func (s *knowledgeService) buildUpdatedFAQEntry(ctx context.Context,
+	tenantID uint64, kb *types.KnowledgeBase, chunk *types.Chunk,
+) (*types.FAQEntry, error) {
 	// Build tag seq_id map for conversion
 	tagSeqIDMap := make(map[string]int64)
+	var tagName string
 	if chunk.TagID != "" {
 		tag, tagErr := s.tagRepo.GetByID(ctx, tenantID, chunk.TagID)
 		if tagErr == nil && tag != nil {
 			tagSeqIDMap[tag.ID] = tag.SeqID
+			tagName = tag.Name
 		}
 	}
 
@@ -4646,50 +10884,89 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	entry.TagName = tagName
 
-	// 查询TagName
-	if chunk.TagID != "" {
-		tag, tagErr := s.tagRepo.GetByID(ctx, tenantID, chunk.TagID)
-		if tagErr == nil && tag != nil {
-			entry.TagName = tag.Name
+	return entry, nil
+}
+
+// sameFAQIndexContent reports whether two FAQ index info lists would produce identical
+// embeddings, i.e. they have the same source IDs with the same content.
+func sameFAQIndexContent(a, b []*types.IndexInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].SourceID != b[i].SourceID || a[i].Content != b[i].Content {
+			return false
 		}
 	}
+	return true
+}
 
-	return entry, nil
+// syncFAQChunkIndexAttributes pushes tag and enabled status changes of an FAQ chunk to the
+// retriever engines without re-embedding its content.
+func (s *knowledgeService) syncFAQChunkIndexAttributes(ctx context.Context,
+	chunk *types.Chunk, oldTagID string, oldIsEnabled bool,
+) error {
+	if chunk.TagID == oldTagID && chunk.IsEnabled == oldIsEnabled {
+		return nil
+	}
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
+		s.retrieveEngine,
+		tenantInfo.GetEffectiveEngines(),
+	)
+	if err != nil {
+		return err
+	}
+	if chunk.IsEnabled != oldIsEnabled {
+		if err := retrieveEngine.BatchUpdateChunkEnabledStatus(ctx, map[string]bool{chunk.ID: chunk.IsEnabled}); err != nil {
+			return err
+		}
+	}
+	if chunk.TagID != oldTagID {
+		if err := retrieveEngine.BatchUpdateChunkTagID(ctx, map[string]string{chunk.ID: chunk.TagID}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // AddSimilarQuestions adds similar questions to a FAQ entry.
 // This will append the new questions to the existing similar questions list.
 func (s *knowledgeService) AddSimilarQuestions(ctx context.Context,
 	kbID string, entrySeqID int64, questions []string,
-) (*types.FAQEntry, error) {
+) (*types.FAQEntry, int, error) {
 	if len(questions) == 0 {
-		return nil, werrors.NewBadRequestError("相似问列表不能为空")
+		return nil, 0, werrors.NewBadRequestError("相似问列表不能为空")
 	}
 
 	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	kb.EnsureDefaults()
+	if maxPerCall := kb.FAQConfig.GetMaxSimilarQuestionsPerCall(); len(questions) > maxPerCall {
+		return nil, 0, werrors.NewBadRequestError(fmt.Sprintf("单次最多添加 %d 个相似问，当前提交 %d 个", maxPerCall, len(questions)))
+	}
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 
 	// Get existing FAQ entry
 	chunk, err := s.chunkRepo.GetChunkBySeqID(ctx, tenantID, entrySeqID)
 	if err != nil {
-		return nil, werrors.NewNotFoundError("FAQ条目不存在")
+		return nil, 0, werrors.NewNotFoundError("FAQ条目不存在")
 	}
 	if chunk.KnowledgeBaseID != kb.ID {
-		return nil, werrors.NewForbiddenError("无权操作该 FAQ 条目")
+		return nil, 0, werrors.NewForbiddenError("无权操作该 FAQ 条目")
 	}
 	if chunk.ChunkType != types.ChunkTypeFAQ {
-		return nil, werrors.NewBadRequestError("仅支持更新 FAQ 条目")
+		return nil, 0, werrors.NewBadRequestError("仅支持更新 FAQ 条目")
 	}
 
 	// Get existing metadata
 	meta, err := chunk.FAQMetadata()
 	if err != nil || meta == nil {
-		return nil, werrors.NewBadRequestError("获取 FAQ 元数据失败")
+		return nil, 0, werrors.NewBadRequestError("获取 FAQ 元数据失败")
 	}
 
 	// Deduplicate and sanitize new questions
@@ -4722,7 +10999,12 @@ func (s *knowledgeService) AddSimilarQuestions(ctx context.Context,
 				tagSeqIDMap[tag.ID] = tag.SeqID
 			}
 		}
-		return s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
+		entry, err := s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
+		return entry, 0, err
+	}
+
+	if err := checkSimilarQuestionsPerEntryLimit(kb, len(meta.SimilarQuestions), len(newQuestions)); err != nil {
+		return nil, 0, err
 	}
 
 	// Check for duplicates with other entries
@@ -4730,8 +11012,8 @@ func (s *knowledgeService) AddSimilarQuestions(ctx context.Context,
 		StandardQuestion: meta.StandardQuestion,
 		SimilarQuestions: append(meta.SimilarQuestions, newQuestions...),
 	}
-	if err := s.checkFAQQuestionDuplicate(ctx, tenantID, kb.ID, chunk.ID, tempMeta); err != nil {
-		return nil, err
+	if err := s.checkFAQQuestionDuplicate(ctx, tenantID, kb.ID, chunk.ID, newFAQDuplicateScope(kb, chunk.TagID), tempMeta); err != nil {
+		return nil, 0, err
 	}
 
 	// Update metadata
@@ -4740,7 +11022,7 @@ func (s *knowledgeService) AddSimilarQuestions(ctx context.Context,
 	meta.Version++
 
 	if err := chunk.SetFAQMetadata(meta); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Update chunk content
@@ -4752,18 +11034,315 @@ func (s *knowledgeService) AddSimilarQuestions(ctx context.Context,
 	chunk.UpdatedAt = time.Now()
 
 	if err := s.chunkService.UpdateChunk(ctx, chunk); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Index new similar questions
 	faqKnowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, chunk.KnowledgeID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	questionIndexMode := types.FAQQuestionIndexModeCombined
+	if kb.FAQConfig != nil && kb.FAQConfig.QuestionIndexMode != "" {
+		questionIndexMode = kb.FAQConfig.QuestionIndexMode
+	}
+
+	if questionIndexMode == types.FAQQuestionIndexModeSeparate ||
+		questionIndexMode == types.FAQQuestionIndexModeHybrid {
+		// Only index the new similar questions
+		if err := s.incrementalIndexFAQEntry(ctx, kb, faqKnowledge, chunk, embeddingModel,
+			meta.StandardQuestion, oldSimilarQuestions, meta.Answers, meta); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		// Combined mode, re-index the whole entry
+		if err := s.indexFAQChunks(ctx, kb, faqKnowledge, []*types.Chunk{chunk}, embeddingModel, false, false); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Build response
+	tagSeqIDMap := make(map[string]int64)
+	if chunk.TagID != "" {
+		tag, tagErr := s.tagRepo.GetByID(ctx, tenantID, chunk.TagID)
+		if tagErr == nil && tag != nil {
+			tagSeqIDMap[tag.ID] = tag.SeqID
+		}
+	}
+
+	entry, err := s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if chunk.TagID != "" {
+		tag, tagErr := s.tagRepo.GetByID(ctx, tenantID, chunk.TagID)
+		if tagErr == nil && tag != nil {
+			entry.TagName = tag.Name
+		}
+	}
+
+	return entry, len(newQuestions), nil
+}
+
+// checkSimilarQuestionsPerEntryLimit 校验追加相似问后条目的相似问总数是否超过上限
+func checkSimilarQuestionsPerEntryLimit(kb *types.KnowledgeBase, existing, added int) error {
+	maxPerEntry := kb.FAQConfig.GetMaxSimilarQuestionsPerEntry()
+	if existing+added > maxPerEntry {
+		return werrors.NewBadRequestError(fmt.Sprintf(
+			"每个条目最多 %d 个相似问，当前已有 %d 个，去重后本次新增 %d 个", maxPerEntry, existing, added))
+	}
+	return nil
+}
+
+// AddSimilarQuestionsBatch adds similar questions to multiple FAQ entries in one call.
+// Duplicates are checked across the whole batch as well as existing entries; an entry that
+// fails validation is reported in FailedEntries without aborting the rest of the batch.
+// Accepted entries are saved in one transaction, and restored if indexing them fails.
+func (s *knowledgeService) AddSimilarQuestionsBatch(ctx context.Context,
+	kbID string, questionsByEntry map[int64][]string,
+) (*types.FAQSimilarQuestionsBatchResult, error) {
+	if len(questionsByEntry) == 0 {
+		return nil, werrors.NewBadRequestError("相似问列表不能为空")
+	}
+
+	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	kb.EnsureDefaults()
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	entrySeqIDs := make([]int64, 0, len(questionsByEntry))
+	for seqID := range questionsByEntry {
+		entrySeqIDs = append(entrySeqIDs, seqID)
+	}
+	slices.Sort(entrySeqIDs)
+
+	chunks, err := s.chunkRepo.ListChunksBySeqID(ctx, tenantID, entrySeqIDs)
 	if err != nil {
 		return nil, err
 	}
+	chunkBySeqID := make(map[int64]*types.Chunk, len(chunks))
+	for _, chunk := range chunks {
+		chunkBySeqID[chunk.SeqID] = chunk
+	}
+
+	// Load existing entries once; accepted entries are written back so that later
+	// entries in the batch are also checked against questions added earlier.
+	existingChunks, err := s.chunkRepo.ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx, tenantID, kb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existing FAQ chunks: %w", err)
+	}
+	existingIndex := make(map[string]int, len(existingChunks))
+	for i, chunk := range existingChunks {
+		existingIndex[chunk.ID] = i
+	}
+
+	indexMode := types.FAQIndexModeQuestionOnly
+	if kb.FAQConfig != nil && kb.FAQConfig.IndexMode != "" {
+		indexMode = kb.FAQConfig.IndexMode
+	}
+
+	result := &types.FAQSimilarQuestionsBatchResult{
+		Entries:       make([]*types.FAQEntry, 0, len(entrySeqIDs)),
+		FailedEntries: make([]types.FAQSimilarQuestionsBatchFailure, 0),
+		AddedCounts:   make(map[int64]int, len(entrySeqIDs)),
+	}
+	maxPerCall := kb.FAQConfig.GetMaxSimilarQuestionsPerCall()
+	fail := func(seqID int64, reason string) {
+		result.FailedEntries = append(result.FailedEntries, types.FAQSimilarQuestionsBatchFailure{
+			EntryID: seqID,
+			Reason:  reason,
+		})
+	}
+
+	updatedChunks := make([]*types.Chunk, 0, len(entrySeqIDs))
+	changedChunks := make([]*types.Chunk, 0, len(entrySeqIDs))
+	originalChunks := make([]*types.Chunk, 0, len(entrySeqIDs))
+	oldSimilarCount := make(map[string]int, len(entrySeqIDs))
+	for _, seqID := range entrySeqIDs {
+		chunk, ok := chunkBySeqID[seqID]
+		if !ok || chunk.KnowledgeBaseID != kb.ID {
+			fail(seqID, "FAQ条目不存在")
+			continue
+		}
+		if chunk.ChunkType != types.ChunkTypeFAQ {
+			fail(seqID, "仅支持更新 FAQ 条目")
+			continue
+		}
+		if len(questionsByEntry[seqID]) > maxPerCall {
+			fail(seqID, fmt.Sprintf("单次最多添加 %d 个相似问，当前提交 %d 个", maxPerCall, len(questionsByEntry[seqID])))
+			continue
+		}
+		meta, err := chunk.FAQMetadata()
+		if err != nil || meta == nil {
+			fail(seqID, "获取 FAQ 元数据失败")
+			continue
+		}
+
+		// Deduplicate and sanitize new questions against the entry itself
+		existingSet := make(map[string]struct{}, len(meta.SimilarQuestions)+1)
+		for _, q := range meta.SimilarQuestions {
+			existingSet[q] = struct{}{}
+		}
+		existingSet[meta.StandardQuestion] = struct{}{}
+		newQuestions := make([]string, 0, len(questionsByEntry[seqID]))
+		for _, q := range questionsByEntry[seqID] {
+			q = strings.TrimSpace(q)
+			if q == "" {
+				continue
+			}
+			if _, exists := existingSet[q]; exists {
+				continue
+			}
+			existingSet[q] = struct{}{}
+			newQuestions = append(newQuestions, q)
+		}
+		if len(newQuestions) == 0 {
+			// Nothing to add, the entry is returned unchanged
+			result.AddedCounts[seqID] = 0
+			updatedChunks = append(updatedChunks, chunk)
+			continue
+		}
+		if err := checkSimilarQuestionsPerEntryLimit(kb, len(meta.SimilarQuestions), len(newQuestions)); err != nil {
+			if appErr, ok := werrors.IsAppError(err); ok {
+				fail(seqID, appErr.Message)
+			} else {
+				fail(seqID, err.Error())
+			}
+			continue
+		}
+
+		// Check for duplicates with other entries, including ones updated earlier in this batch
+		tempMeta := &types.FAQChunkMetadata{
+			StandardQuestion: meta.StandardQuestion,
+			SimilarQuestions: append(slices.Clone(meta.SimilarQuestions), newQuestions...),
+		}
+		if err := checkFAQQuestionDuplicateInChunks(existingChunks, chunk.ID, newFAQDuplicateScope(kb, chunk.TagID), tempMeta); err != nil {
+			if appErr, ok := werrors.IsAppError(err); ok {
+				fail(seqID, appErr.Message)
+			} else {
+				fail(seqID, err.Error())
+			}
+			continue
+		}
+
+		original := *chunk
+		oldCount := len(meta.SimilarQuestions)
+		meta.SimilarQuestions = tempMeta.SimilarQuestions
+		meta.Version++
+		if err := chunk.SetFAQMetadata(meta); err != nil {
+			fail(seqID, err.Error())
+			continue
+		}
+		chunk.Content = buildFAQChunkContent(meta, indexMode)
+		chunk.UpdatedAt = time.Now()
+
+		if i, ok := existingIndex[chunk.ID]; ok {
+			existingChunks[i] = chunk
+		}
+		oldSimilarCount[chunk.ID] = oldCount
+		result.AddedCounts[seqID] = len(newQuestions)
+		updatedChunks = append(updatedChunks, chunk)
+		changedChunks = append(changedChunks, chunk)
+		originalChunks = append(originalChunks, &original)
+	}
+
+	if len(changedChunks) > 0 {
+		if err := s.chunkRepo.SaveChunks(ctx, changedChunks); err != nil {
+			return nil, fmt.Errorf("failed to save FAQ entries: %w", err)
+		}
+		if err := s.indexSimilarQuestionsBatch(ctx, kb, updatedChunks, oldSimilarCount); err != nil {
+			s.restoreSimilarQuestionsBatch(ctx, kb, originalChunks)
+			return nil, err
+		}
+	}
+
+	// Build response with tag information
+	tagIDs := make([]string, 0)
+	tagIDSet := make(map[string]struct{})
+	for _, chunk := range updatedChunks {
+		if chunk.TagID != "" {
+			if _, exists := tagIDSet[chunk.TagID]; !exists {
+				tagIDSet[chunk.TagID] = struct{}{}
+				tagIDs = append(tagIDs, chunk.TagID)
+			}
+		}
+	}
+	tagNameMap := make(map[string]string)
+	tagSeqIDMap := make(map[string]int64)
+	if len(tagIDs) > 0 {
+		tags, err := s.tagRepo.GetByIDs(ctx, tenantID, tagIDs)
+		if err == nil {
+			for _, tag := range tags {
+				tagNameMap[tag.ID] = tag.Name
+				tagSeqIDMap[tag.ID] = tag.SeqID
+			}
+		}
+	}
+	for _, chunk := range updatedChunks {
+		entry, err := s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
+		if err != nil {
+			return nil, err
+		}
+		if chunk.TagID != "" {
+			entry.TagName = tagNameMap[chunk.TagID]
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+
+	logger.Infof(ctx, "AddSimilarQuestionsBatch: kb=%s updated=%d failed=%d",
+		kb.ID, len(oldSimilarCount), len(result.FailedEntries))
+	return result, nil
+}
+
+// restoreSimilarQuestionsBatch 在批量添加相似问的索引失败后恢复条目原有内容，并按原内容重建索引，
+// 避免数据库与检索引擎中残留部分新增的相似问
+func (s *knowledgeService) restoreSimilarQuestionsBatch(ctx context.Context,
+	kb *types.KnowledgeBase, originalChunks []*types.Chunk,
+) {
+	if err := s.chunkRepo.SaveChunks(ctx, originalChunks); err != nil {
+		logger.Errorf(ctx, "AddSimilarQuestionsBatch: failed to restore FAQ entries: %v", err)
+		return
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		logger.Errorf(ctx, "AddSimilarQuestionsBatch: failed to get embedding model for re-sync: %v", err)
+		return
+	}
+	chunksByKnowledge := make(map[string][]*types.Chunk)
+	for _, chunk := range originalChunks {
+		chunksByKnowledge[chunk.KnowledgeID] = append(chunksByKnowledge[chunk.KnowledgeID], chunk)
+	}
+	for knowledgeID, knowledgeChunks := range chunksByKnowledge {
+		faqKnowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+		if err != nil {
+			logger.Errorf(ctx, "AddSimilarQuestionsBatch: failed to get knowledge %s for re-sync: %v", knowledgeID, err)
+			continue
+		}
+		if err := s.indexFAQChunks(ctx, kb, faqKnowledge, knowledgeChunks, embeddingModel, false, true); err != nil {
+			logger.Errorf(ctx, "AddSimilarQuestionsBatch: failed to re-sync index of knowledge %s: %v", knowledgeID, err)
+		}
+	}
+}
 
+// indexSimilarQuestionsBatch 为批量添加相似问后的条目更新索引
+// 分别索引模式下只为新增的相似问计算向量，一起索引和混合索引模式下重建整个条目的索引
+func (s *knowledgeService) indexSimilarQuestionsBatch(ctx context.Context,
+	kb *types.KnowledgeBase, chunks []*types.Chunk, oldSimilarCount map[string]int,
+) error {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	questionIndexMode := types.FAQQuestionIndexModeCombined
@@ -4771,41 +11350,76 @@ func (s *knowledgeService) AddSimilarQuestions(ctx context.Context,
 		questionIndexMode = kb.FAQConfig.QuestionIndexMode
 	}
 
-	if questionIndexMode == types.FAQQuestionIndexModeSeparate {
-		// Only index the new similar questions
-		if err := s.incrementalIndexFAQEntry(ctx, kb, faqKnowledge, chunk, embeddingModel,
-			meta.StandardQuestion, oldSimilarQuestions, meta.Answers, meta); err != nil {
-			return nil, err
-		}
-	} else {
-		// Combined mode, re-index the whole entry
-		if err := s.indexFAQChunks(ctx, kb, faqKnowledge, []*types.Chunk{chunk}, embeddingModel, false, false); err != nil {
-			return nil, err
+	// Group changed chunks by knowledge so each knowledge record is updated once
+	chunksByKnowledge := make(map[string][]*types.Chunk)
+	for _, chunk := range chunks {
+		if _, changed := oldSimilarCount[chunk.ID]; !changed {
+			continue
 		}
+		chunksByKnowledge[chunk.KnowledgeID] = append(chunksByKnowledge[chunk.KnowledgeID], chunk)
 	}
 
-	// Build response
-	tagSeqIDMap := make(map[string]int64)
-	if chunk.TagID != "" {
-		tag, tagErr := s.tagRepo.GetByID(ctx, tenantID, chunk.TagID)
-		if tagErr == nil && tag != nil {
-			tagSeqIDMap[tag.ID] = tag.SeqID
+	if questionIndexMode != types.FAQQuestionIndexModeSeparate {
+		for knowledgeID, knowledgeChunks := range chunksByKnowledge {
+			faqKnowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+			if err != nil {
+				return err
+			}
+			if err := s.indexFAQChunks(ctx, kb, faqKnowledge, knowledgeChunks, embeddingModel, false, true); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
 
-	entry, err := s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if chunk.TagID != "" {
-		tag, tagErr := s.tagRepo.GetByID(ctx, tenantID, chunk.TagID)
-		if tagErr == nil && tag != nil {
-			entry.TagName = tag.Name
+	indexInfoList := make([]*types.IndexInfo, 0)
+	for _, knowledgeChunks := range chunksByKnowledge {
+		for _, chunk := range knowledgeChunks {
+			infoList, err := s.buildFAQIndexInfoList(ctx, kb, chunk)
+			if err != nil {
+				return err
+			}
+			// The standard question and existing similar questions are unchanged,
+			// only the appended similar questions need new vectors.
+			newSourceIDs := make(map[string]struct{})
+			meta, _ := chunk.FAQMetadata()
+			if meta != nil {
+				for i := oldSimilarCount[chunk.ID]; i < len(meta.SimilarQuestions); i++ {
+					newSourceIDs[fmt.Sprintf("%s-%d", chunk.ID, i)] = struct{}{}
+				}
+			}
+			for _, info := range infoList {
+				if _, ok := newSourceIDs[info.SourceID]; ok {
+					info.IsRecommended = chunk.Flags.HasFlag(types.ChunkFlagRecommended)
+					indexInfoList = append(indexInfoList, info)
+				}
+			}
 		}
 	}
+	if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList); err != nil {
+		return err
+	}
+	logger.Debugf(ctx, "indexSimilarQuestionsBatch: indexed %d new similar questions", len(indexInfoList))
 
-	return entry, nil
+	now := time.Now()
+	for knowledgeID := range chunksByKnowledge {
+		faqKnowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
+		if err != nil {
+			return err
+		}
+		faqKnowledge.UpdatedAt = now
+		faqKnowledge.ProcessedAt = &now
+		if err := s.repo.UpdateKnowledge(ctx, faqKnowledge); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // UpdateFAQEntryStatus updates enable status for a FAQ entry.
@@ -4913,6 +11527,14 @@ func (s *knowledgeService) UpdateFAQEntryFieldsBatch(ctx context.Context,
 				}
 			}
 
+			if newTagUUID != nil {
+				if err := s.checkFAQTagMoveDuplicate(ctx, tenantID, kb, func(c *types.Chunk) (string, bool) {
+					return *newTagUUID, c.TagID == tag.ID && !slices.Contains(excludeUUIDs, c.ID)
+				}); err != nil {
+					return err
+				}
+			}
+
 			// Update all chunks with this tag
 			affectedIDs, err := s.chunkRepo.UpdateChunkFieldsByTagID(
 				ctx, tenantID, kb.ID, tag.ID,
@@ -4958,6 +11580,7 @@ func (s *knowledgeService) UpdateFAQEntryFieldsBatch(ctx context.Context,
 		setFlags := make(map[string]types.ChunkFlags)
 		clearFlags := make(map[string]types.ChunkFlags)
 		chunksToUpdate := make([]*types.Chunk, 0)
+		tagMoves := make(map[string]string)
 
 		for entrySeqID, update := range req.ByID {
 			chunk, exists := chunkBySeqID[entrySeqID]
@@ -5002,6 +11625,7 @@ func (s *knowledgeService) UpdateFAQEntryFieldsBatch(ctx context.Context,
 				if chunk.TagID != newTagID {
 					chunk.TagID = newTagID
 					tagUpdates[chunk.ID] = newTagID
+					tagMoves[chunk.ID] = newTagID
 					needUpdate = true
 				}
 			}
@@ -5012,6 +11636,15 @@ func (s *knowledgeService) UpdateFAQEntryFieldsBatch(ctx context.Context,
 			}
 		}
 
+		if len(tagMoves) > 0 {
+			if err := s.checkFAQTagMoveDuplicate(ctx, tenantID, kb, func(c *types.Chunk) (string, bool) {
+				tagID, ok := tagMoves[c.ID]
+				return tagID, ok
+			}); err != nil {
+				return err
+			}
+		}
+
 		// Batch update chunks (for IsEnabled and TagID)
 		if len(chunksToUpdate) > 0 {
 			if err := s.chunkRepo.UpdateChunks(ctx, chunksToUpdate); err != nil {
@@ -5052,8 +11685,71 @@ func (s *knowledgeService) UpdateFAQEntryFieldsBatch(ctx context.Context,
 	return nil
 }
 
+// UpdateFAQEntryFieldsByQuery resolves the FAQ entries matching the keyword filter of the FAQ list and
+// applies the same field update to all of them through UpdateFAQEntryFieldsBatch. With DryRun only the
+// matched entries are returned so the affected set can be reviewed before committing.
+func (s *knowledgeService) UpdateFAQEntryFieldsByQuery(ctx context.Context,
+	kbID string, req *types.FAQEntryQueryUpdate,
+) (*types.FAQEntryQueryUpdateResult, error) {
+	if req == nil || strings.TrimSpace(req.Keyword) == "" {
+		return nil, werrors.NewValidationError("关键词不能为空")
+	}
+	if !req.DryRun && req.Update.IsEnabled == nil && req.Update.IsRecommended == nil && req.Update.TagID == nil {
+		return nil, werrors.NewValidationError("至少需要指定一个更新字段")
+	}
+
+	matched := make([]*types.FAQEntry, 0)
+	const pageSize = 100
+	for page := 1; ; page++ {
+		result, err := s.ListFAQEntries(ctx, kbID, &types.Pagination{Page: page, PageSize: pageSize},
+			req.TagID, req.Keyword, req.SearchField, "")
+		if err != nil {
+			return nil, err
+		}
+		entries, _ := result.Data.([]*types.FAQEntry)
+		matched = append(matched, entries...)
+		if len(matched) > types.FAQEntryQueryUpdateMaxEntries {
+			return nil, werrors.NewBadRequestError(fmt.Sprintf(
+				"命中条目超过 %d 条，请缩小搜索范围", types.FAQEntryQueryUpdateMaxEntries))
+		}
+		if len(entries) < pageSize {
+			break
+		}
+	}
+
+	preview := matched
+	if len(preview) > types.FAQEntryQueryUpdatePreviewLimit {
+		preview = preview[:types.FAQEntryQueryUpdatePreviewLimit]
+	}
+	result := &types.FAQEntryQueryUpdateResult{
+		DryRun:  req.DryRun,
+		Matched: len(matched),
+		Entries: preview,
+	}
+	if req.DryRun || len(matched) == 0 {
+		return result, nil
+	}
+
+	byID := make(map[int64]types.FAQEntryFieldsUpdate, len(matched))
+	for _, entry := range matched {
+		byID[entry.ID] = req.Update
+	}
+	if err := s.UpdateFAQEntryFieldsBatch(ctx, kbID, &types.FAQEntryFieldsBatchUpdate{ByID: byID}); err != nil {
+		return nil, err
+	}
+	result.Updated = len(byID)
+	logger.Infof(ctx, "Updated fields of %d FAQ entries matching keyword %q in KB %s", result.Updated, req.Keyword, kbID)
+	return result, nil
+}
+
 // UpdateKnowledgeTag updates the tag assigned to a knowledge document.
 func (s *knowledgeService) UpdateKnowledgeTag(ctx context.Context, knowledgeID string, tagID *string) error {
+	lock, err := s.lockKnowledge(ctx, knowledgeID)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledgeID)
 	if err != nil {
@@ -5099,6 +11795,11 @@ func (s *knowledgeService) UpdateKnowledgeTagBatch(ctx context.Context, updates
 	if err != nil {
 		return err
 	}
+	unlock, err := s.lockKnowledgeList(ctx, knowledgeList, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	// Build tag ID map for validation
 	tagIDSet := make(map[string]bool)
@@ -5170,22 +11871,20 @@ func (s *knowledgeService) UpdateFAQEntryTag(ctx context.Context, kbID string, e
 		return werrors.NewBadRequestError("仅支持更新 FAQ 条目标签")
 	}
 
-	var resolvedTagID string
-	if tagID != nil && *tagID != "" {
-		tag, err := s.tagRepo.GetByID(ctx, tenantID, *tagID)
-		if err != nil {
-			return err
-		}
-		if tag.KnowledgeBaseID != kb.ID {
-			return werrors.NewBadRequestError("标签不属于当前知识库")
-		}
-		resolvedTagID = tag.ID
+	resolvedTagID, err := s.resolveKBTagID(ctx, tenantID, kb.ID, tagID)
+	if err != nil {
+		return err
 	}
 
 	// Check if tag actually changed
 	if chunk.TagID == resolvedTagID {
 		return nil
 	}
+	if err := s.checkFAQTagMoveDuplicate(ctx, tenantID, kb, func(c *types.Chunk) (string, bool) {
+		return resolvedTagID, c.ID == chunk.ID
+	}); err != nil {
+		return err
+	}
 
 	chunk.TagID = resolvedTagID
 	chunk.UpdatedAt = time.Now()
@@ -5215,106 +11914,345 @@ func (s *knowledgeService) UpdateFAQEntryTagBatch(ctx context.Context, kbID stri
 	if err != nil {
 		return err
 	}
-	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	// Get all chunks in batch by seq_id
+	entrySeqIDs := make([]int64, 0, len(updates))
+	for entrySeqID := range updates {
+		entrySeqIDs = append(entrySeqIDs, entrySeqID)
+	}
+	chunks, err := s.chunkRepo.ListChunksBySeqID(ctx, tenantID, entrySeqIDs)
+	if err != nil {
+		return err
+	}
+
+	// Build chunk seq_id to chunk map
+	chunkBySeqID := make(map[int64]*types.Chunk)
+	for _, chunk := range chunks {
+		chunkBySeqID[chunk.SeqID] = chunk
+	}
+
+	// Build tag seq_id set for validation
+	tagSeqIDSet := make(map[int64]bool)
+	for _, tagSeqID := range updates {
+		if tagSeqID != nil && *tagSeqID > 0 {
+			tagSeqIDSet[*tagSeqID] = true
+		}
+	}
+
+	// Validate all tags in batch by seq_id
+	tagMap := make(map[int64]*types.KnowledgeTag)
+	if len(tagSeqIDSet) > 0 {
+		tagSeqIDs := make([]int64, 0, len(tagSeqIDSet))
+		for tagSeqID := range tagSeqIDSet {
+			tagSeqIDs = append(tagSeqIDs, tagSeqID)
+		}
+		tags, err := s.tagRepo.GetBySeqIDs(ctx, tenantID, tagSeqIDs)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if tag.KnowledgeBaseID != kb.ID {
+				return werrors.NewBadRequestError(fmt.Sprintf("标签 %d 不属于当前知识库", tag.SeqID))
+			}
+			tagMap[tag.SeqID] = tag
+		}
+	}
+
+	// Update chunks
+	chunksToUpdate := make([]*types.Chunk, 0)
+	for entrySeqID, tagSeqID := range updates {
+		chunk, exists := chunkBySeqID[entrySeqID]
+		if !exists {
+			continue
+		}
+		if chunk.KnowledgeBaseID != kb.ID || chunk.ChunkType != types.ChunkTypeFAQ {
+			continue
+		}
+
+		var resolvedTagID string
+		if tagSeqID != nil && *tagSeqID > 0 {
+			tag, ok := tagMap[*tagSeqID]
+			if !ok {
+				return werrors.NewBadRequestError(fmt.Sprintf("标签 %d 不存在", *tagSeqID))
+			}
+			resolvedTagID = tag.ID
+		}
+
+		chunk.TagID = resolvedTagID
+		chunk.UpdatedAt = time.Now()
+		chunksToUpdate = append(chunksToUpdate, chunk)
+	}
+
+	if len(chunksToUpdate) > 0 {
+		tagMoves := make(map[string]string, len(chunksToUpdate))
+		for _, chunk := range chunksToUpdate {
+			tagMoves[chunk.ID] = chunk.TagID
+		}
+		if err := s.checkFAQTagMoveDuplicate(ctx, tenantID, kb, func(c *types.Chunk) (string, bool) {
+			tagID, ok := tagMoves[c.ID]
+			return tagID, ok
+		}); err != nil {
+			return err
+		}
+		if err := s.chunkRepo.UpdateChunks(ctx, chunksToUpdate); err != nil {
+			return err
+		}
+
+		// Sync tag updates to retriever engines
+		tagUpdates := make(map[string]string)
+		for _, chunk := range chunksToUpdate {
+			tagUpdates[chunk.ID] = chunk.TagID
+		}
+		tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
+			s.retrieveEngine,
+			tenantInfo.GetEffectiveEngines(),
+		)
+		if err != nil {
+			return err
+		}
+		if err := retrieveEngine.BatchUpdateChunkTagID(ctx, tagUpdates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveKBTagID resolves tagID to a tag of the given knowledge base.
+// A nil or empty tagID resolves to "" (no tag).
+func (s *knowledgeService) resolveKBTagID(ctx context.Context, tenantID uint64, kbID string, tagID *string) (string, error) {
+	if tagID == nil || *tagID == "" {
+		return "", nil
+	}
+	tag, err := s.tagRepo.GetByID(ctx, tenantID, *tagID)
+	if err != nil {
+		return "", err
+	}
+	if tag.KnowledgeBaseID != kbID {
+		return "", werrors.NewBadRequestError("标签不属于当前知识库")
+	}
+	return tag.ID, nil
+}
+
+// SetChunkTag assigns a tag to a single chunk (nil or empty tagID removes the tag).
+// The tag must belong to the chunk's knowledge base.
+func (s *knowledgeService) SetChunkTag(ctx context.Context, chunkID string, tagID *string) error {
+	return s.SetChunkTagBatch(ctx, map[string]*string{chunkID: tagID})
+}
+
+// SetChunkTagBatch assigns tags to chunks in batch.
+// Key: chunk ID, Value: tag ID (nil or empty to remove tag)
+func (s *knowledgeService) SetChunkTagBatch(ctx context.Context, updates map[string]*string) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	chunkIDs := make([]string, 0, len(updates))
+	for chunkID := range updates {
+		chunkIDs = append(chunkIDs, chunkID)
+	}
+	chunks, err := s.chunkRepo.ListChunksByID(ctx, tenantID, chunkIDs)
+	if err != nil {
+		return err
+	}
+	chunkByID := make(map[string]*types.Chunk, len(chunks))
+	for _, chunk := range chunks {
+		chunkByID[chunk.ID] = chunk
+	}
+
+	// Resolve each distinct (kb, tag) pair once
+	type tagKey struct{ kbID, tagID string }
+	resolved := make(map[tagKey]string)
+
+	chunksToUpdate := make([]*types.Chunk, 0, len(updates))
+	for chunkID, tagID := range updates {
+		chunk, ok := chunkByID[chunkID]
+		if !ok {
+			return werrors.NewNotFoundError(fmt.Sprintf("分块 %s 不存在", chunkID))
+		}
+
+		var resolvedTagID string
+		if tagID != nil && *tagID != "" {
+			key := tagKey{kbID: chunk.KnowledgeBaseID, tagID: *tagID}
+			id, ok := resolved[key]
+			if !ok {
+				id, err = s.resolveKBTagID(ctx, tenantID, chunk.KnowledgeBaseID, tagID)
+				if err != nil {
+					return err
+				}
+				resolved[key] = id
+			}
+			resolvedTagID = id
+		}
+
+		if chunk.TagID == resolvedTagID {
+			continue
+		}
+		chunk.TagID = resolvedTagID
+		chunk.UpdatedAt = time.Now()
+		chunksToUpdate = append(chunksToUpdate, chunk)
+	}
+
+	if len(chunksToUpdate) == 0 {
+		return nil
+	}
+	if err := s.chunkRepo.UpdateChunks(ctx, chunksToUpdate); err != nil {
+		return err
+	}
 
-	// Get all chunks in batch by seq_id
-	entrySeqIDs := make([]int64, 0, len(updates))
-	for entrySeqID := range updates {
-		entrySeqIDs = append(entrySeqIDs, entrySeqID)
+	// Sync tag updates to retriever engines
+	tagUpdates := make(map[string]string, len(chunksToUpdate))
+	for _, chunk := range chunksToUpdate {
+		tagUpdates[chunk.ID] = chunk.TagID
 	}
-	chunks, err := s.chunkRepo.ListChunksBySeqID(ctx, tenantID, entrySeqIDs)
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
+		s.retrieveEngine,
+		tenantInfo.GetEffectiveEngines(),
+	)
 	if err != nil {
 		return err
 	}
+	return retrieveEngine.BatchUpdateChunkTagID(ctx, tagUpdates)
+}
 
-	// Build chunk seq_id to chunk map
-	chunkBySeqID := make(map[int64]*types.Chunk)
-	for _, chunk := range chunks {
-		chunkBySeqID[chunk.SeqID] = chunk
+// faqSearchCandidatePoolSize returns how many candidates each priority search should fetch
+// from HybridSearch. It is never smaller than MatchCount and is capped at MaxFAQSearchCandidatePoolSize.
+func faqSearchCandidatePoolSize(req *types.FAQSearchRequest) int {
+	poolSize := req.CandidatePoolSize
+	if poolSize <= 0 {
+		poolSize = req.MatchCount * types.FAQSearchCandidatePoolMultiplier
 	}
-
-	// Build tag seq_id set for validation
-	tagSeqIDSet := make(map[int64]bool)
-	for _, tagSeqID := range updates {
-		if tagSeqID != nil && *tagSeqID > 0 {
-			tagSeqIDSet[*tagSeqID] = true
-		}
+	if poolSize > types.MaxFAQSearchCandidatePoolSize {
+		poolSize = types.MaxFAQSearchCandidatePoolSize
+	}
+	if poolSize < req.MatchCount {
+		poolSize = req.MatchCount
 	}
+	return poolSize
+}
 
-	// Validate all tags in batch by seq_id
-	tagMap := make(map[int64]*types.KnowledgeTag)
-	if len(tagSeqIDSet) > 0 {
-		tagSeqIDs := make([]int64, 0, len(tagSeqIDSet))
-		for tagSeqID := range tagSeqIDSet {
-			tagSeqIDs = append(tagSeqIDs, tagSeqID)
-		}
-		tags, err := s.tagRepo.GetBySeqIDs(ctx, tenantID, tagSeqIDs)
-		if err != nil {
-			return err
-		}
-		for _, tag := range tags {
-			if tag.KnowledgeBaseID != kb.ID {
-				return werrors.NewBadRequestError(fmt.Sprintf("标签 %d 不属于当前知识库", tag.SeqID))
+// mergePrioritySearchResults merges first priority results ahead of second priority ones,
+// dropping chunks that appear in both lists.
+func mergePrioritySearchResults(firstResults, secondResults []*types.SearchResult) []*types.SearchResult {
+	merged := make([]*types.SearchResult, 0, len(firstResults)+len(secondResults))
+	seenChunkIDs := make(map[string]struct{})
+	for _, results := range [][]*types.SearchResult{firstResults, secondResults} {
+		for _, result := range results {
+			if _, exists := seenChunkIDs[result.ID]; !exists {
+				seenChunkIDs[result.ID] = struct{}{}
+				merged = append(merged, result)
 			}
-			tagMap[tag.SeqID] = tag
 		}
 	}
+	return merged
+}
 
-	// Update chunks
-	chunksToUpdate := make([]*types.Chunk, 0)
-	for entrySeqID, tagSeqID := range updates {
-		chunk, exists := chunkBySeqID[entrySeqID]
-		if !exists {
-			continue
-		}
-		if chunk.KnowledgeBaseID != kb.ID || chunk.ChunkType != types.ChunkTypeFAQ {
-			continue
+// sortFAQEntriesByPriority sorts entries by priority level (lower level = higher priority),
+// then by score descending within the same level.
+// priorityOf returns: 0 = first priority, 1 = second priority, 2 = no priority
+func sortFAQEntriesByPriority(entries []*types.FAQEntry, priorityOf func(*types.FAQEntry) int) {
+	slices.SortStableFunc(entries, func(a, b *types.FAQEntry) int {
+		if aPriority, bPriority := priorityOf(a), priorityOf(b); aPriority != bPriority {
+			return aPriority - bPriority
 		}
-
-		var resolvedTagID string
-		if tagSeqID != nil && *tagSeqID > 0 {
-			tag, ok := tagMap[*tagSeqID]
-			if !ok {
-				return werrors.NewBadRequestError(fmt.Sprintf("标签 %d 不存在", *tagSeqID))
-			}
-			resolvedTagID = tag.ID
+		if b.Score > a.Score {
+			return 1
+		} else if b.Score < a.Score {
+			return -1
 		}
+		return 0
+	})
+}
 
-		chunk.TagID = resolvedTagID
-		chunk.UpdatedAt = time.Now()
-		chunksToUpdate = append(chunksToUpdate, chunk)
+// applyFAQMatchedSource sets the matched question of a FAQ entry from the SourceID of the
+// index entry that matched. Similar questions are indexed as chunkID-i, answers as
+// chunkID-answer-i and the standard question as chunkID (see buildFAQIndexInfoList).
+// When an answer matched, the part of it overlapping queryText is highlighted.
+func applyFAQMatchedSource(entry *types.FAQEntry, chunkID, sourceID, queryText string) {
+	if sourceID == "" {
+		return
 	}
-
-	if len(chunksToUpdate) > 0 {
-		if err := s.chunkRepo.UpdateChunks(ctx, chunksToUpdate); err != nil {
-			return err
+	if suffix, ok := strings.CutPrefix(sourceID, chunkID+"-answer-"); ok {
+		index, err := strconv.Atoi(suffix)
+		if err != nil || index < 0 || index >= len(entry.Answers) {
+			return
 		}
+		// The matched content is the answer text, not a question
+		entry.MatchedQuestion = ""
+		entry.MatchedAnswerIndex = &index
+		entry.MatchedAnswerHighlight = faqAnswerHighlight(entry.Answers[index], queryText)
+		return
+	}
+	if sourceID == chunkID {
+		entry.MatchedQuestion = entry.StandardQuestion
+		return
+	}
+	suffix, ok := strings.CutPrefix(sourceID, chunkID+"-")
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(suffix)
+	if err != nil || index < 0 || index >= len(entry.SimilarQuestions) {
+		return
+	}
+	entry.MatchedSimilarQuestionIndex = &index
+	entry.MatchedQuestion = entry.SimilarQuestions[index]
+}
 
-		// Sync tag updates to retriever engines
-		tagUpdates := make(map[string]string)
-		for _, chunk := range chunksToUpdate {
-			tagUpdates[chunk.ID] = chunk.TagID
-		}
-		tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
-			s.retrieveEngine,
-			tenantInfo.GetEffectiveEngines(),
-		)
-		if err != nil {
-			return err
+// faqAnswerHighlight returns the longest run of text shared by answer and query, compared case-insensitively,
+// as a rune span of answer. Runs shorter than two runes (or the whole query when shorter) are not highlighted.
+func faqAnswerHighlight(answer, query string) *types.FAQTextSpan {
+	lowerRunes := func(s string) []rune {
+		runes := []rune(s)
+		for i, r := range runes {
+			runes[i] = unicode.ToLower(r)
 		}
-		if err := retrieveEngine.BatchUpdateChunkTagID(ctx, tagUpdates); err != nil {
-			return err
+		return runes
+	}
+	answerRunes := lowerRunes(answer)
+	queryRunes := lowerRunes(strings.TrimSpace(query))
+	if len(answerRunes) == 0 || len(queryRunes) == 0 {
+		return nil
+	}
+	// Longest common substring, keeping one row of the DP table
+	prev := make([]int, len(queryRunes)+1)
+	curr := make([]int, len(queryRunes)+1)
+	bestLen, bestEnd := 0, 0
+	for i := 1; i <= len(answerRunes); i++ {
+		for j := 1; j <= len(queryRunes); j++ {
+			if answerRunes[i-1] == queryRunes[j-1] {
+				curr[j] = prev[j-1] + 1
+				if curr[j] > bestLen {
+					bestLen, bestEnd = curr[j], i
+				}
+			} else {
+				curr[j] = 0
+			}
 		}
+		prev, curr = curr, prev
 	}
-
-	return nil
+	start, end := bestEnd-bestLen, bestEnd
+	for start < end && unicode.IsSpace(answerRunes[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(answerRunes[end-1]) {
+		end--
+	}
+	if end-start < min(2, len(queryRunes)) {
+		return nil
+	}
+	return &types.FAQTextSpan{Start: start, End: end}
 }
 
 // SearchFAQEntries searches FAQ entries using hybrid search.
+// If one priority search fails the results of the other are still returned, marked as partial.
 func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 	kbID string, req *types.FAQSearchRequest,
-) ([]*types.FAQEntry, error) {
+) (*types.FAQSearchResult, error) {
 	// Validate FAQ knowledge base
 	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
 	if err != nil {
@@ -5331,6 +12269,11 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 	if req.MatchCount > 50 {
 		req.MatchCount = 50
 	}
+	if req.MaxAnswers <= 0 {
+		req.MaxAnswers = kb.FAQConfig.GetMaxDisplayedAnswers()
+	}
+	// 每个优先级检索单独拉取更大的候选池，避免 HybridSearch 内部截断导致优先级排序失去意义
+	candidatePoolSize := faqSearchCandidatePoolSize(req)
 
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 
@@ -5377,6 +12320,7 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 	// Perform separate searches for each priority level to ensure FirstPriority results
 	// are not crowded out by higher-scoring SecondPriority results in TopK truncation
 	var searchResults []*types.SearchResult
+	partial := false
 
 	if hasPriorityFilter {
 		// Use goroutines to search both priority levels concurrently
@@ -5395,7 +12339,7 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 				firstParams := types.SearchParams{
 					QueryText:            secutils.SanitizeForLog(req.QueryText),
 					VectorThreshold:      req.VectorThreshold,
-					MatchCount:           req.MatchCount,
+					MatchCount:           candidatePoolSize,
 					DisableKeywordsMatch: true,
 					TagIDs:               firstPriorityTagUUIDs,
 					OnlyRecommended:      req.OnlyRecommended,
@@ -5411,7 +12355,7 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 				secondParams := types.SearchParams{
 					QueryText:            secutils.SanitizeForLog(req.QueryText),
 					VectorThreshold:      req.VectorThreshold,
-					MatchCount:           req.MatchCount,
+					MatchCount:           candidatePoolSize,
 					DisableKeywordsMatch: true,
 					TagIDs:               secondPriorityTagUUIDs,
 					OnlyRecommended:      req.OnlyRecommended,
@@ -5422,45 +12366,41 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 
 		wg.Wait()
 
-		// Check errors
-		if firstErr != nil {
+		// Degrade to the successful priority level when only one of them fails
+		if firstErr != nil && (!hasSecondPriority || secondErr != nil) {
 			return nil, firstErr
 		}
-		if secondErr != nil {
+		if secondErr != nil && !hasFirstPriority {
 			return nil, secondErr
 		}
-
-		// Merge results: FirstPriority first, then SecondPriority (deduplicated)
-		seenChunkIDs := make(map[string]struct{})
-		for _, result := range firstResults {
-			if _, exists := seenChunkIDs[result.ID]; !exists {
-				seenChunkIDs[result.ID] = struct{}{}
-				searchResults = append(searchResults, result)
-			}
+		if firstErr != nil {
+			logger.Warnf(ctx, "First priority FAQ search failed, returning second priority results only: %v", firstErr)
+			firstResults, partial = nil, true
 		}
-		for _, result := range secondResults {
-			if _, exists := seenChunkIDs[result.ID]; !exists {
-				seenChunkIDs[result.ID] = struct{}{}
-				searchResults = append(searchResults, result)
-			}
+		if secondErr != nil {
+			logger.Warnf(ctx, "Second priority FAQ search failed, returning first priority results only: %v", secondErr)
+			secondResults, partial = nil, true
 		}
+
+		// Merge results: FirstPriority first, then SecondPriority (deduplicated)
+		searchResults = mergePrioritySearchResults(firstResults, secondResults)
 	} else {
 		// No priority filter, search all
 		searchParams := types.SearchParams{
 			QueryText:            secutils.SanitizeForLog(req.QueryText),
 			VectorThreshold:      req.VectorThreshold,
-			MatchCount:           req.MatchCount,
+			MatchCount:           candidatePoolSize,
 			DisableKeywordsMatch: true,
 		}
 		var err error
-		searchResults, err = s.kbService.HybridSearch(ctx, kbID, searchParams)
+		searchResults, err = s.hybridSearchWithRetry(ctx, kbID, searchParams)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if len(searchResults) == 0 {
-		return []*types.FAQEntry{}, nil
+		return s.applyFAQNoMatchFallback(ctx, kb, req, newFAQSearchResult(req, []*types.FAQEntry{}, partial)), nil
 	}
 
 	// Extract chunk IDs and build score/match type/matched content maps
@@ -5468,6 +12408,7 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 	chunkScores := make(map[string]float64)
 	chunkMatchTypes := make(map[string]types.MatchType)
 	chunkMatchedContents := make(map[string]string)
+	chunkMatchedSourceIDs := make(map[string]string)
 	for _, result := range searchResults {
 		// SearchResult.ID is the chunk ID
 		chunkID := result.ID
@@ -5475,6 +12416,7 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 		chunkScores[chunkID] = result.Score
 		chunkMatchTypes[chunkID] = result.MatchType
 		chunkMatchedContents[chunkID] = result.MatchedContent
+		chunkMatchedSourceIDs[chunkID] = result.MatchedSourceID
 	}
 
 	// Batch fetch chunks
@@ -5506,6 +12448,9 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 
 	// Filter FAQ chunks and convert to FAQEntry
 	kb.EnsureDefaults()
+	separateQuestionIndex := kb.FAQConfig != nil &&
+		(kb.FAQConfig.QuestionIndexMode == types.FAQQuestionIndexModeSeparate ||
+			kb.FAQConfig.QuestionIndexMode == types.FAQQuestionIndexModeHybrid)
 	entries := make([]*types.FAQEntry, 0, len(chunks))
 	for _, chunk := range chunks {
 		// Only process FAQ type chunks
@@ -5521,6 +12466,9 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 			logger.Warnf(ctx, "Failed to convert chunk to FAQ entry: %v", err)
 			continue
 		}
+		if req.ExcludeAnswerPending && entry.AnswerPending {
+			continue
+		}
 
 		// Preserve score and match type from search results
 		// Note: Negative question filtering is now handled in HybridSearch
@@ -5535,68 +12483,37 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 		if matchedContent, ok := chunkMatchedContents[chunk.ID]; ok && matchedContent != "" {
 			entry.MatchedQuestion = matchedContent
 		}
+		// In separate question index mode, resolve exactly which question matched from the SourceID
+		if separateQuestionIndex {
+			applyFAQMatchedSource(entry, chunk.ID, chunkMatchedSourceIDs[chunk.ID], req.QueryText)
+		}
 
 		entries = append(entries, entry)
 	}
 
 	// Sort entries with two-level priority tag support
 	if hasPriorityFilter {
-		// getPriorityLevel returns: 0 = first priority, 1 = second priority, 2 = no priority
-		// Use chunk.TagID (UUID) for comparison
-		getPriorityLevel := func(chunk *types.Chunk) int {
-			if _, ok := firstPrioritySet[chunk.TagID]; ok {
-				return 0
-			}
-			if _, ok := secondPrioritySet[chunk.TagID]; ok {
-				return 1
-			}
-			return 2
-		}
-
-		// Build chunk map for priority lookup
+		// Build chunk map for priority lookup, using chunk.TagID (UUID) for comparison
 		chunkMap := make(map[int64]*types.Chunk)
 		for _, chunk := range chunks {
 			chunkMap[chunk.SeqID] = chunk
 		}
-
-		slices.SortFunc(entries, func(a, b *types.FAQEntry) int {
-			aChunk := chunkMap[a.ID]
-			bChunk := chunkMap[b.ID]
-			var aPriority, bPriority int
-			if aChunk != nil {
-				aPriority = getPriorityLevel(aChunk)
-			} else {
-				aPriority = 2
+		sortFAQEntriesByPriority(entries, func(entry *types.FAQEntry) int {
+			chunk := chunkMap[entry.ID]
+			if chunk == nil {
+				return 2
 			}
-			if bChunk != nil {
-				bPriority = getPriorityLevel(bChunk)
-			} else {
-				bPriority = 2
-			}
-
-			// Compare by priority level first
-			if aPriority != bPriority {
-				return aPriority - bPriority // Lower level = higher priority
+			if _, ok := firstPrioritySet[chunk.TagID]; ok {
+				return 0
 			}
-
-			// Same priority level, sort by score descending
-			if b.Score > a.Score {
+			if _, ok := secondPrioritySet[chunk.TagID]; ok {
 				return 1
-			} else if b.Score < a.Score {
-				return -1
 			}
-			return 0
+			return 2
 		})
 	} else {
 		// No priority tags, sort by score only
-		slices.SortFunc(entries, func(a, b *types.FAQEntry) int {
-			if b.Score > a.Score {
-				return 1
-			} else if b.Score < a.Score {
-				return -1
-			}
-			return 0
-		})
+		sortFAQEntriesByPriority(entries, func(*types.FAQEntry) int { return 0 })
 	}
 
 	// Limit results to requested match count
@@ -5642,7 +12559,102 @@ func (s *knowledgeService) SearchFAQEntries(ctx context.Context,
 		}
 	}
 
-	return entries, nil
+	return s.applyFAQNoMatchFallback(ctx, kb, req, newFAQSearchResult(req, entries, partial)), nil
+}
+
+// newFAQSearchResult builds the search result, resolving the answers to show per entry in answers mode.
+// Entries keep all their answers; only the resolved answers are capped by req.MaxAnswers.
+func newFAQSearchResult(req *types.FAQSearchRequest, entries []*types.FAQEntry, partial bool) *types.FAQSearchResult {
+	result := &types.FAQSearchResult{Entries: entries, Partial: partial}
+	if req.ResponseMode != types.FAQSearchResponseModeAnswers {
+		return result
+	}
+	result.Answers = make([]*types.FAQAnswerMatch, 0, len(entries))
+	for _, entry := range entries {
+		result.Answers = append(result.Answers, newFAQAnswerMatch(req, entry))
+	}
+	return result
+}
+
+// newFAQAnswerMatch resolves the answers to show for a single entry in answers mode.
+func newFAQAnswerMatch(req *types.FAQSearchRequest, entry *types.FAQEntry) *types.FAQAnswerMatch {
+	answers := entry.SelectAnswers(req.AnswerSeed, req.MaxAnswers)
+	return &types.FAQAnswerMatch{
+		EntryID:          entry.ID,
+		StandardQuestion: entry.StandardQuestion,
+		MatchedQuestion:  entry.MatchedQuestion,
+		TagID:            entry.TagID,
+		TagName:          entry.TagName,
+		Answers:          answers,
+		Score:            entry.Score,
+		MatchType:        entry.MatchType,
+		ContentFormat:    entry.ContentFormat,
+		TotalAnswers:     len(entry.Answers),
+		HasMoreAnswers: entry.AnswerStrategy != types.AnswerStrategyRandom &&
+			len(answers) < len(entry.Answers),
+	}
+}
+
+// applyFAQNoMatchFallback applies the knowledge base's no-match fallback to a search result without entries.
+// A missing or disabled fallback entry only leaves the result flagged as no match.
+func (s *knowledgeService) applyFAQNoMatchFallback(ctx context.Context,
+	kb *types.KnowledgeBase, req *types.FAQSearchRequest, result *types.FAQSearchResult,
+) *types.FAQSearchResult {
+	if len(result.Entries) > 0 {
+		return result
+	}
+	var fallback *types.FAQEntry
+	switch kb.FAQConfig.GetNoMatchFallback() {
+	case types.FAQNoMatchFallbackNone:
+		return result
+	case types.FAQNoMatchFallbackEntry:
+		entry, err := s.GetFAQEntry(ctx, kb.ID, kb.FAQConfig.FallbackEntryID)
+		switch {
+		case err != nil:
+			logger.Warnf(ctx, "Failed to load FAQ fallback entry %d: %v", kb.FAQConfig.FallbackEntryID, err)
+		case !entry.IsEnabled:
+			logger.Warnf(ctx, "FAQ fallback entry %d is disabled, skipping", entry.ID)
+		default:
+			fallback = entry
+		}
+	}
+	setFAQSearchFallback(req, result, fallback)
+	return result
+}
+
+// setFAQSearchFallback marks a search result as having no match and attaches the fallback entry if any.
+func setFAQSearchFallback(req *types.FAQSearchRequest, result *types.FAQSearchResult, fallback *types.FAQEntry) {
+	result.NoMatch = true
+	if fallback == nil {
+		return
+	}
+	result.Fallback = fallback
+	if req.ResponseMode == types.FAQSearchResponseModeAnswers {
+		result.FallbackAnswer = newFAQAnswerMatch(req, fallback)
+	}
+}
+
+// faqSearchRetryDelay 无优先级 FAQ 检索失败后重试前的等待时间
+const faqSearchRetryDelay = 200 * time.Millisecond
+
+// hybridSearchWithRetry runs HybridSearch and retries once on errors that are not caused by the request
+func (s *knowledgeService) hybridSearchWithRetry(ctx context.Context,
+	kbID string, params types.SearchParams,
+) ([]*types.SearchResult, error) {
+	results, err := s.kbService.HybridSearch(ctx, kbID, params)
+	if err == nil {
+		return results, nil
+	}
+	if _, ok := werrors.IsAppError(err); ok {
+		return nil, err
+	}
+	logger.Warnf(ctx, "FAQ search failed, retrying once: %v", err)
+	select {
+	case <-ctx.Done():
+		return nil, err
+	case <-time.After(faqSearchRetryDelay):
+	}
+	return s.kbService.HybridSearch(ctx, kbID, params)
 }
 
 // DeleteFAQEntries deletes FAQ entries in batch by seq_id.
@@ -5687,112 +12699,307 @@ func (s *knowledgeService) DeleteFAQEntries(ctx context.Context,
 			return err
 		}
 	}
-	return nil
+	return nil
+}
+
+// faqExportPageSize is the number of FAQ chunks fetched per page when streaming an export.
+const faqExportPageSize = 1000
+
+// faqExportHeaders are the CSV/XLSX header columns, matching the import example format.
+var faqExportHeaders = []string{
+	"分类(必填)",
+	"问题(必填)",
+	"相似问题(选填-多个用##分隔)",
+	"反例问题(选填-多个用##分隔)",
+	"机器人回答(必填-多个用##分隔)",
+	"是否全部回复(选填-默认FALSE)",
+	"是否停用(选填-默认FALSE)",
+	"是否禁止被推荐(选填-默认False 可被推荐)",
+	"答案格式(选填-plain/markdown/html)",
+}
+
+// ExportFAQEntries exports all FAQ entries for a knowledge base in the given format.
+// It buffers the whole export in memory and is intended for small knowledge bases;
+// use ExportFAQEntriesStream for large ones.
+// 已停用条目仅在 includeDisabled 为 true 时导出。
+func (s *knowledgeService) ExportFAQEntries(ctx context.Context,
+	kbID string, includeDisabled bool, format types.FAQExportFormat,
+) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.ExportFAQEntriesStream(ctx, kbID, includeDisabled, format, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportFAQEntriesStream writes all indexed FAQ entries for a knowledge base to w,
+// paging through the chunks in creation order instead of loading them all into memory.
+// CSV and XLSX match the import example format with 9 columns:
+// 分类(必填), 问题(必填), 相似问题(选填-多个用##分隔), 反例问题(选填-多个用##分隔),
+// 机器人回答(必填-多个用##分隔), 是否全部回复(选填-默认FALSE), 是否停用(选填-默认FALSE),
+// 是否禁止被推荐(选填-默认False 可被推荐), 答案格式(选填-plain/markdown/html)
+// JSON is an array of FAQEntry. An empty format means CSV.
+// Nothing is written to w if the knowledge base or format fails validation.
+func (s *knowledgeService) ExportFAQEntriesStream(ctx context.Context,
+	kbID string, includeDisabled bool, format types.FAQExportFormat, w io.Writer,
+) error {
+	if format == "" {
+		format = types.FAQExportFormatCSV
+	}
+	if !format.IsValid() {
+		return werrors.NewBadRequestError("不支持的导出格式: " + string(format))
+	}
+	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
+	if err != nil {
+		return err
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	faqKnowledge, err := s.findFAQKnowledge(ctx, tenantID, kb.ID)
+	if err != nil {
+		return err
+	}
+
+	// Build tag map for tag_id -> tag conversion once for the whole export
+	var tagMap map[string]*types.KnowledgeTag
+	if faqKnowledge != nil {
+		tagMap, err = s.buildTagMap(ctx, tenantID, kbID)
+		if err != nil {
+			return fmt.Errorf("failed to build tag map: %w", err)
+		}
+	}
+
+	encoder, err := s.newFAQExportEncoder(format, w, kb, tagMap)
+	if err != nil {
+		return err
+	}
+	if faqKnowledge == nil {
+		// Empty export with headers only
+		return encoder.Close()
+	}
+
+	var afterCreatedAt time.Time
+	afterID := ""
+	for {
+		chunks, err := s.chunkRepo.ListFAQChunksForExport(
+			ctx, tenantID, faqKnowledge.ID, afterCreatedAt, afterID, faqExportPageSize,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to list FAQ chunks: %w", err)
+		}
+		for _, chunk := range chunks {
+			if !includeDisabled && !chunk.IsEnabled {
+				continue
+			}
+			if err := encoder.WriteChunk(chunk); err != nil {
+				return err
+			}
+		}
+		if len(chunks) < faqExportPageSize {
+			break
+		}
+		last := chunks[len(chunks)-1]
+		afterCreatedAt, afterID = last.CreatedAt, last.ID
+	}
+	return encoder.Close()
+}
+
+// buildTagMap builds a map from tag_id to tag for the given knowledge base.
+func (s *knowledgeService) buildTagMap(ctx context.Context,
+	tenantID uint64, kbID string,
+) (map[string]*types.KnowledgeTag, error) {
+	// Get all tags for this knowledge base (no pagination limit)
+	page := &types.Pagination{Page: 1, PageSize: 10000}
+	tags, _, err := s.tagRepo.ListByKB(ctx, tenantID, kbID, page, "")
+	if err != nil {
+		return nil, err
+	}
+
+	tagMap := make(map[string]*types.KnowledgeTag, len(tags))
+	for _, tag := range tags {
+		if tag != nil {
+			tagMap[tag.ID] = tag
+		}
+	}
+	return tagMap, nil
+}
+
+// faqExportEncoder writes exported FAQ chunks in one output format.
+// Close must be called once all chunks are written to finish the output.
+type faqExportEncoder interface {
+	WriteChunk(chunk *types.Chunk) error
+	Close() error
+}
+
+// newFAQExportEncoder creates the encoder for format and writes its header, if any.
+func (s *knowledgeService) newFAQExportEncoder(format types.FAQExportFormat,
+	w io.Writer, kb *types.KnowledgeBase, tagMap map[string]*types.KnowledgeTag,
+) (faqExportEncoder, error) {
+	switch format {
+	case types.FAQExportFormatJSON:
+		tagSeqIDMap := make(map[string]int64, len(tagMap))
+		for id, tag := range tagMap {
+			tagSeqIDMap[id] = tag.SeqID
+		}
+		enc := &faqJSONExportEncoder{w: bufio.NewWriter(w), convert: func(chunk *types.Chunk) (*types.FAQEntry, error) {
+			entry, err := s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
+			if err != nil {
+				return nil, err
+			}
+			if tag, ok := tagMap[chunk.TagID]; ok {
+				entry.TagName = tag.Name
+			}
+			return entry, nil
+		}}
+		if _, err := enc.w.WriteString("["); err != nil {
+			return nil, err
+		}
+		return enc, nil
+	case types.FAQExportFormatXLSX:
+		xw, err := secutils.NewXLSXWriter(w, "FAQ")
+		if err != nil {
+			return nil, err
+		}
+		headers := make([]any, 0, len(faqExportHeaders))
+		for _, header := range faqExportHeaders {
+			headers = append(headers, header)
+		}
+		if err := xw.WriteRow(headers...); err != nil {
+			return nil, err
+		}
+		return &faqXLSXExportEncoder{w: xw, tagMap: tagMap}, nil
+	default:
+		enc := &faqCSVExportEncoder{w: bufio.NewWriter(w), tagMap: tagMap}
+		if _, err := enc.w.WriteString(strings.Join(faqExportHeaders, ",") + "\n"); err != nil {
+			return nil, err
+		}
+		return enc, nil
+	}
 }
 
-// ExportFAQEntries exports all FAQ entries for a knowledge base as CSV data.
-// The CSV format matches the import example format with 8 columns:
-// 分类(必填), 问题(必填), 相似问题(选填-多个用##分隔), 反例问题(选填-多个用##分隔),
-// 机器人回答(必填-多个用##分隔), 是否全部回复(选填-默认FALSE), 是否停用(选填-默认FALSE),
-// 是否禁止被推荐(选填-默认False 可被推荐)
-func (s *knowledgeService) ExportFAQEntries(ctx context.Context, kbID string) ([]byte, error) {
-	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
-	if err != nil {
-		return nil, err
-	}
+// faqCSVExportEncoder writes the import template columns as CSV.
+type faqCSVExportEncoder struct {
+	w      *bufio.Writer
+	tagMap map[string]*types.KnowledgeTag
+}
 
-	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	faqKnowledge, err := s.findFAQKnowledge(ctx, tenantID, kb.ID)
-	if err != nil {
-		return nil, err
-	}
-	if faqKnowledge == nil {
-		// Return empty CSV with headers only
-		return s.buildFAQCSV(nil, nil), nil
+func (e *faqCSVExportEncoder) WriteChunk(chunk *types.Chunk) error {
+	row, ok := newFAQExportRow(chunk, e.tagMap)
+	if !ok {
+		return nil
 	}
+	fields := []string{
+		escapeCSVField(row.TagName),
+		escapeCSVField(row.StandardQuestion),
+		escapeCSVField(row.SimilarQuestions),
+		escapeCSVField(row.NegativeQuestions),
+		escapeCSVField(row.Answers),
+		boolToCSV(row.AnswerAll),
+		boolToCSV(row.Disabled),
+		boolToCSV(row.NotRecommended),
+		row.ContentFormat,
+	}
+	_, err := e.w.WriteString(strings.Join(fields, ",") + "\n")
+	return err
+}
 
-	// Get all FAQ chunks
-	chunks, err := s.chunkRepo.ListAllFAQChunksForExport(ctx, tenantID, faqKnowledge.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list FAQ chunks: %w", err)
-	}
+func (e *faqCSVExportEncoder) Close() error {
+	return e.w.Flush()
+}
 
-	// Build tag map for tag_id -> tag_name conversion
-	tagMap, err := s.buildTagMap(ctx, tenantID, kbID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build tag map: %w", err)
+// faqXLSXExportEncoder writes the import template columns as an XLSX sheet, booleans as TRUE/FALSE cells.
+type faqXLSXExportEncoder struct {
+	w      *secutils.XLSXWriter
+	tagMap map[string]*types.KnowledgeTag
+}
+
+func (e *faqXLSXExportEncoder) WriteChunk(chunk *types.Chunk) error {
+	row, ok := newFAQExportRow(chunk, e.tagMap)
+	if !ok {
+		return nil
 	}
+	return e.w.WriteRow(row.TagName, row.StandardQuestion, row.SimilarQuestions, row.NegativeQuestions,
+		row.Answers, row.AnswerAll, row.Disabled, row.NotRecommended, row.ContentFormat)
+}
 
-	return s.buildFAQCSV(chunks, tagMap), nil
+func (e *faqXLSXExportEncoder) Close() error {
+	return e.w.Close()
 }
 
-// buildTagMap builds a map from tag_id to tag_name for the given knowledge base.
-func (s *knowledgeService) buildTagMap(ctx context.Context, tenantID uint64, kbID string) (map[string]string, error) {
-	// Get all tags for this knowledge base (no pagination limit)
-	page := &types.Pagination{Page: 1, PageSize: 10000}
-	tags, _, err := s.tagRepo.ListByKB(ctx, tenantID, kbID, page, "")
+// faqJSONExportEncoder writes a JSON array of FAQEntry.
+type faqJSONExportEncoder struct {
+	w       *bufio.Writer
+	convert func(chunk *types.Chunk) (*types.FAQEntry, error)
+	written int
+}
+
+func (e *faqJSONExportEncoder) WriteChunk(chunk *types.Chunk) error {
+	entry, err := e.convert(chunk)
 	if err != nil {
-		return nil, err
+		// Same as CSV: chunks without readable FAQ metadata are left out
+		return nil
 	}
-
-	tagMap := make(map[string]string, len(tags))
-	for _, tag := range tags {
-		if tag != nil {
-			tagMap[tag.ID] = tag.Name
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if e.written > 0 {
+		if err := e.w.WriteByte(','); err != nil {
+			return err
 		}
 	}
-	return tagMap, nil
+	e.written++
+	_, err = e.w.Write(data)
+	return err
 }
 
-// buildFAQCSV builds CSV content from FAQ chunks.
-func (s *knowledgeService) buildFAQCSV(chunks []*types.Chunk, tagMap map[string]string) []byte {
-	var buf strings.Builder
+func (e *faqJSONExportEncoder) Close() error {
+	if _, err := e.w.WriteString("]"); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
 
-	// Write CSV header (matching import example format)
-	headers := []string{
-		"分类(必填)",
-		"问题(必填)",
-		"相似问题(选填-多个用##分隔)",
-		"反例问题(选填-多个用##分隔)",
-		"机器人回答(必填-多个用##分隔)",
-		"是否全部回复(选填-默认FALSE)",
-		"是否停用(选填-默认FALSE)",
-		"是否禁止被推荐(选填-默认False 可被推荐)",
-	}
-	buf.WriteString(strings.Join(headers, ","))
-	buf.WriteString("\n")
-
-	// Write data rows
-	for _, chunk := range chunks {
-		meta, err := chunk.FAQMetadata()
-		if err != nil || meta == nil {
-			continue
-		}
+// faqExportRow holds the import template columns of an exported FAQ entry.
+type faqExportRow struct {
+	TagName           string
+	StandardQuestion  string
+	SimilarQuestions  string
+	NegativeQuestions string
+	Answers           string
+	AnswerAll         bool   // 是否全部回复
+	Disabled          bool   // 是否停用：取反
+	NotRecommended    bool   // 是否禁止被推荐：取反
+	ContentFormat     string // 答案格式：为空表示使用知识库默认格式
+}
 
-		// Get tag name
-		tagName := ""
-		if chunk.TagID != "" && tagMap != nil {
-			if name, ok := tagMap[chunk.TagID]; ok {
-				tagName = name
-			}
-		}
+// newFAQExportRow builds the import template columns for an FAQ chunk.
+// It returns false when the chunk has no readable FAQ metadata.
+func newFAQExportRow(chunk *types.Chunk, tagMap map[string]*types.KnowledgeTag) (*faqExportRow, bool) {
+	meta, err := chunk.FAQMetadata()
+	if err != nil || meta == nil {
+		return nil, false
+	}
 
-		// Build row
-		row := []string{
-			escapeCSVField(tagName),
-			escapeCSVField(meta.StandardQuestion),
-			escapeCSVField(strings.Join(meta.SimilarQuestions, "##")),
-			escapeCSVField(strings.Join(meta.NegativeQuestions, "##")),
-			escapeCSVField(strings.Join(meta.Answers, "##")),
-			boolToCSV(meta.AnswerStrategy == types.AnswerStrategyAll),
-			boolToCSV(!chunk.IsEnabled),                                 // 是否停用：取反
-			boolToCSV(!chunk.Flags.HasFlag(types.ChunkFlagRecommended)), // 是否禁止被推荐：取反
+	// Get tag name
+	tagName := ""
+	if chunk.TagID != "" {
+		if tag, ok := tagMap[chunk.TagID]; ok {
+			tagName = tag.Name
 		}
-		buf.WriteString(strings.Join(row, ","))
-		buf.WriteString("\n")
 	}
 
-	return []byte(buf.String())
+	return &faqExportRow{
+		TagName:           tagName,
+		StandardQuestion:  meta.StandardQuestion,
+		SimilarQuestions:  strings.Join(meta.SimilarQuestions, "##"),
+		NegativeQuestions: strings.Join(meta.NegativeQuestions, "##"),
+		Answers:           strings.Join(meta.Answers, "##"),
+		AnswerAll:         meta.AnswerStrategy == types.AnswerStrategyAll,
+		Disabled:          !chunk.IsEnabled,
+		NotRecommended:    !chunk.Flags.HasFlag(types.ChunkFlagRecommended),
+		ContentFormat:     string(meta.ContentFormat),
+	}, true
 }
 
 // escapeCSVField escapes a field for CSV format.
@@ -5906,8 +13113,8 @@ func (s *knowledgeService) updateFAQImportProgressStatus(
 		existingProgress.Error = ""
 	}
 
-	// 任务完成或失败时，清除 running key
-	if status == types.FAQImportStatusCompleted || status == types.FAQImportStatusFailed {
+	// 任务结束时，清除 running key
+	if status.IsFinished() {
 		if existingProgress.KBID != "" {
 			if clearErr := s.clearRunningFAQImportTaskID(ctx, existingProgress.KBID); clearErr != nil {
 				logger.Errorf(ctx, "Failed to clear running FAQ import task ID: %v", clearErr)
@@ -5921,14 +13128,83 @@ func (s *knowledgeService) updateFAQImportProgressStatus(
 // cleanupFAQEntriesFileOnFinalFailure 在任务最终失败时清理对象存储中的 entries 文件
 // 只有当 retryCount >= maxRetry 时才执行清理，否则重试时还需要使用这个文件
 func (s *knowledgeService) cleanupFAQEntriesFileOnFinalFailure(ctx context.Context, entriesURL string, retryCount, maxRetry int) {
-	if entriesURL == "" || retryCount < maxRetry {
+	if retryCount < maxRetry {
+		return
+	}
+	s.deleteOffloadedTaskData(ctx, entriesURL)
+}
+
+// offloadTaskData 将异步任务的大字段序列化后上传到私有桶，返回写入任务 payload 的 URL；
+// 任务成功或最终失败后需调用 deleteOffloadedTaskData 清理
+func (s *knowledgeService) offloadTaskData(ctx context.Context, tenantID uint64, fileName string, data any) (string, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal task data: %w", err)
+	}
+	url, err := s.fileSvc.SaveBytes(ctx, dataBytes, tenantID, fileName, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload task data: %w", err)
+	}
+	logger.Infof(ctx, "Task data (%d bytes) offloaded to object storage: %s", len(dataBytes), url)
+	return url, nil
+}
+
+// loadOffloadedTaskData 从对象存储下载 offloadTaskData 转存的数据并反序列化到 out
+func (s *knowledgeService) loadOffloadedTaskData(ctx context.Context, url string, out any) error {
+	reader, err := s.fileSvc.GetFile(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to download task data: %w", err)
+	}
+	defer reader.Close()
+
+	dataBytes, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read task data: %w", err)
+	}
+	if err := json.Unmarshal(dataBytes, out); err != nil {
+		return fmt.Errorf("failed to unmarshal task data: %w", err)
+	}
+	return nil
+}
+
+// deleteOffloadedTaskData 删除对象存储中转存的任务数据，失败仅记录日志
+func (s *knowledgeService) deleteOffloadedTaskData(ctx context.Context, url string) {
+	if url == "" {
 		return
 	}
-	if err := s.fileSvc.DeleteFile(ctx, entriesURL); err != nil {
-		logger.Warnf(ctx, "Failed to delete FAQ entries file from object storage on final failure: %v", err)
+	if err := s.fileSvc.DeleteFile(ctx, url); err != nil {
+		logger.Warnf(ctx, "Failed to delete offloaded task data from object storage: %v", err)
 	} else {
-		logger.Infof(ctx, "Deleted FAQ entries file from object storage on final failure: %s", entriesURL)
+		logger.Infof(ctx, "Deleted offloaded task data from object storage: %s", url)
+	}
+}
+
+// marshalDocumentProcessPayload 序列化文档处理任务 payload，超过内联大小上限时将 Passages 与
+// PreservedQuestions 转存到对象存储，payload 中只保留 OffloadURL
+func (s *knowledgeService) marshalDocumentProcessPayload(ctx context.Context,
+	payload *types.DocumentProcessPayload,
+) ([]byte, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(payloadBytes) <= s.taskPayloadInlineMaxSize() ||
+		(len(payload.Passages) == 0 && len(payload.PreservedQuestions) == 0) {
+		return payloadBytes, nil
+	}
+
+	fileName := fmt.Sprintf("document_process_%s_%d.json", payload.KnowledgeID, time.Now().UnixNano())
+	url, err := s.offloadTaskData(ctx, payload.TenantID, fileName, types.DocumentProcessOffload{
+		Passages:           payload.Passages,
+		PreservedQuestions: payload.PreservedQuestions,
+	})
+	if err != nil {
+		return nil, err
 	}
+	payload.Passages = nil
+	payload.PreservedQuestions = nil
+	payload.OffloadURL = url
+	return json.Marshal(payload)
 }
 
 // runningFAQImportInfo stores the task ID and enqueued timestamp for uniquely identifying a task instance
@@ -5958,9 +13234,89 @@ func (s *knowledgeService) getRunningFAQImportInfo(ctx context.Context, kbID str
 	return &info, nil
 }
 
-// getRunningFAQImportTaskID checks if there's a running FAQ import task for the given KB
-// Returns the task ID if found, empty string otherwise (for backward compatibility)
-func (s *knowledgeService) getRunningFAQImportTaskID(ctx context.Context, kbID string) (string, error) {
+// asynqTaskID returns the asynq task ID the import was enqueued with
+func (info *runningFAQImportInfo) asynqTaskID() string {
+	return fmt.Sprintf("%s:%d", info.TaskID, info.EnqueuedAt)
+}
+
+// isFAQImportLockStale reports whether the running lock belongs to an import whose asynq task no longer
+// exists or has already ended (e.g. archived after the worker crashed), so the lock can be released
+// without waiting for it to expire. Locks in the old format or younger than faqImportLockGracePeriod
+// (the task may not be enqueued yet) are never considered stale.
+func (s *knowledgeService) isFAQImportLockStale(ctx context.Context, info *runningFAQImportInfo) bool {
+	if s.taskInspector == nil || info.EnqueuedAt == 0 ||
+		time.Since(time.Unix(info.EnqueuedAt, 0)) < faqImportLockGracePeriod {
+		return false
+	}
+	taskInfo, err := s.taskInspector.GetTaskInfo(faqImportQueue, info.asynqTaskID())
+	if err != nil {
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			return true
+		}
+		logger.Warnf(ctx, "Failed to inspect FAQ import task %s: %v", info.asynqTaskID(), err)
+		return false
+	}
+	return taskInfo.State == asynq.TaskStateArchived || taskInfo.State == asynq.TaskStateCompleted
+}
+
+// startFAQImportLockHeartbeat keeps the running lock of an import alive with a short TTL while the task
+// executes, so a crashed worker releases the KB within faqImportLockHeartbeatTTL instead of blocking
+// imports until faqImportProgressTTL. The returned stop function ends the heartbeat; if the lock is still
+// held by this task (it is going to be retried), the long TTL is restored to outlive the retry backoff.
+func (s *knowledgeService) startFAQImportLockHeartbeat(ctx context.Context,
+	kbID string, info runningFAQImportInfo,
+) (stop func()) {
+	refresh := func(ctx context.Context, ttl time.Duration) bool {
+		current, err := s.getRunningFAQImportInfo(ctx, kbID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to read FAQ import lock for KB %s: %v", kbID, err)
+			return true
+		}
+		if current == nil || current.TaskID != info.TaskID || current.EnqueuedAt != info.EnqueuedAt {
+			return false
+		}
+		if err := s.redisClient.Expire(ctx, getFAQImportRunningKey(kbID), ttl).Err(); err != nil {
+			logger.Warnf(ctx, "Failed to refresh FAQ import lock for KB %s: %v", kbID, err)
+		}
+		return true
+	}
+	if !refresh(ctx, faqImportLockHeartbeatTTL) {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(faqImportLockHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !refresh(ctx, faqImportLockHeartbeatTTL) {
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		refresh(context.WithoutCancel(ctx), faqImportProgressTTL)
+	}
+}
+
+// ForceClearFAQImportLock releases the running import lock of a KB regardless of its state, for locks
+// left behind by crashed workers. Returns the task ID that held the lock, or empty if none was held.
+// The task's progress is marked failed unless it already finished.
+func (s *knowledgeService) ForceClearFAQImportLock(ctx context.Context, kbID string) (string, error) {
+	if _, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID); err != nil {
+		return "", err
+	}
 	info, err := s.getRunningFAQImportInfo(ctx, kbID)
 	if err != nil {
 		return "", err
@@ -5968,6 +13324,22 @@ func (s *knowledgeService) getRunningFAQImportTaskID(ctx context.Context, kbID s
 	if info == nil {
 		return "", nil
 	}
+	if !s.isFAQImportLockStale(ctx, info) {
+		logger.Warnf(ctx, "Force clearing FAQ import lock of KB %s while task %s may still be running", kbID, info.TaskID)
+	}
+	if err := s.clearRunningFAQImportTaskID(ctx, kbID); err != nil {
+		return "", fmt.Errorf("failed to clear FAQ import lock: %w", err)
+	}
+
+	progress, err := s.GetFAQImportProgress(ctx, info.TaskID)
+	if err == nil && !progress.Status.IsFinished() {
+		progress.Status = types.FAQImportStatusFailed
+		progress.Error = "导入锁已被强制清除"
+		if err := s.saveFAQImportProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to mark FAQ import %s as failed: %v", info.TaskID, err)
+		}
+	}
+	logger.Infof(ctx, "Force cleared FAQ import lock of KB %s held by task %s", kbID, info.TaskID)
 	return info.TaskID, nil
 }
 
@@ -6001,6 +13373,11 @@ func (s *knowledgeService) chunkToFAQEntry(chunk *types.Chunk, kb *types.Knowled
 		answerStrategy = types.AnswerStrategyAll
 	}
 
+	contentFormat := meta.ContentFormat
+	if contentFormat == "" {
+		contentFormat = kb.FAQConfig.GetAnswerContentFormat()
+	}
+
 	// Get tag seq_id from map
 	var tagSeqID int64
 	if chunk.TagID != "" && tagSeqIDMap != nil {
@@ -6024,6 +13401,10 @@ func (s *knowledgeService) chunkToFAQEntry(chunk *types.Chunk, kb *types.Knowled
 		UpdatedAt:         chunk.UpdatedAt,
 		CreatedAt:         chunk.CreatedAt,
 		ChunkType:         chunk.ChunkType,
+		ContentFormat:     contentFormat,
+		AnswerPending:     meta.AnswerPending,
+
+		UnindexedSimilarQuestions: faqUnindexedSimilarQuestionCount(kb, meta.SimilarQuestions),
 	}
 	return entry, nil
 }
@@ -6048,6 +13429,100 @@ func buildFAQChunkContent(meta *types.FAQChunkMetadata, mode types.FAQIndexMode)
 	return builder.String()
 }
 
+// faqDuplicateScope limits FAQ duplicate detection to the entries sharing a tag
+// when the knowledge base is configured with FAQDuplicateScopeTag
+type faqDuplicateScope struct {
+	perTag bool
+	tagID  string
+}
+
+// newFAQDuplicateScope returns the duplicate detection scope of an entry under the given tag
+func newFAQDuplicateScope(kb *types.KnowledgeBase, tagID string) faqDuplicateScope {
+	return faqDuplicateScope{
+		perTag: kb.FAQConfig.GetDuplicateScope() == types.FAQDuplicateScopeTag,
+		tagID:  tagID,
+	}
+}
+
+// includes reports whether the existing chunk takes part in duplicate detection
+func (sc faqDuplicateScope) includes(chunk *types.Chunk) bool {
+	return !sc.perTag || chunk.TagID == sc.tagID
+}
+
+// faqImportQuestionScope 为导入条目计算查重范围：按标签查重时问题按标签分组，并缓存标签查询结果
+type faqImportQuestionScope struct {
+	perTag   bool
+	tenantID uint64
+	kbID     string
+	tagRepo  interfaces.KnowledgeTagRepository
+	bySeqID  map[int64]string
+	byName   map[string]string
+}
+
+// newFAQImportQuestionScope creates the import duplicate scope of a knowledge base,
+// a nil knowledge base falls back to knowledge base wide detection
+func (s *knowledgeService) newFAQImportQuestionScope(tenantID uint64, kb *types.KnowledgeBase) *faqImportQuestionScope {
+	scope := &faqImportQuestionScope{
+		tenantID: tenantID,
+		tagRepo:  s.tagRepo,
+		bySeqID:  make(map[int64]string),
+		byName:   make(map[string]string),
+	}
+	if kb != nil {
+		scope.perTag = kb.FAQConfig.GetDuplicateScope() == types.FAQDuplicateScopeTag
+		scope.kbID = kb.ID
+	}
+	return scope
+}
+
+// chunkKey returns the scope key of an existing FAQ chunk
+func (sc *faqImportQuestionScope) chunkKey(chunk *types.Chunk) string {
+	if !sc.perTag {
+		return ""
+	}
+	return chunk.TagID
+}
+
+// entryKey returns the scope key of an import entry, resolving its tag like resolveTagID
+// but without creating tags: tags that do not exist yet are keyed by name
+func (sc *faqImportQuestionScope) entryKey(ctx context.Context, entry *types.FAQEntryPayload) string {
+	if !sc.perTag {
+		return ""
+	}
+	if entry.TagID != 0 {
+		if key, ok := sc.bySeqID[entry.TagID]; ok {
+			return key
+		}
+		key := fmt.Sprintf("seq:%d", entry.TagID)
+		if tag, err := sc.tagRepo.GetBySeqID(ctx, sc.tenantID, entry.TagID); err == nil && tag != nil {
+			key = tag.ID
+		}
+		sc.bySeqID[entry.TagID] = key
+		return key
+	}
+	name := entry.TagName
+	if name == "" {
+		name = types.UntaggedTagName
+	}
+	if key, ok := sc.byName[name]; ok {
+		return key
+	}
+	key := "name:" + name
+	if tag, err := sc.tagRepo.GetByName(ctx, sc.tenantID, sc.kbID, name); err == nil && tag != nil {
+		key = tag.ID
+	}
+	sc.byName[name] = key
+	return key
+}
+
+// scopedFAQQuestion 生成带查重范围的问题键，范围为空时即问题本身
+func scopedFAQQuestion(scopeKey, question string) string {
+	if scopeKey == "" {
+		return question
+	}
+	return scopeKey + "\x00" + question
+}
+
 // checkFAQQuestionDuplicate 检查标准问和相似问是否与知识库中其他条目重复
 // excludeChunkID 用于排除当前正在编辑的条目（更新时使用）
 func (s *knowledgeService) checkFAQQuestionDuplicate(
@@ -6055,6 +13530,7 @@ func (s *knowledgeService) checkFAQQuestionDuplicate(
 	tenantID uint64,
 	kbID string,
 	excludeChunkID string,
+	scope faqDuplicateScope,
 	meta *types.FAQChunkMetadata,
 ) error {
 	// 首先检查当前条目自身的相似问是否与标准问重复
@@ -6079,10 +13555,21 @@ func (s *knowledgeService) checkFAQQuestionDuplicate(
 		return fmt.Errorf("failed to list existing FAQ chunks: %w", err)
 	}
 
+	return checkFAQQuestionDuplicateInChunks(existingChunks, excludeChunkID, scope, meta)
+}
+
+// checkFAQQuestionDuplicateInChunks 检查标准问和相似问是否与给定条目列表中的其他条目重复
+// 供需要多次校验的批量操作复用，避免重复查询知识库中的全部FAQ条目
+func checkFAQQuestionDuplicateInChunks(
+	existingChunks []*types.Chunk,
+	excludeChunkID string,
+	scope faqDuplicateScope,
+	meta *types.FAQChunkMetadata,
+) error {
 	// 构建已存在的标准问和相似问集合
 	for _, chunk := range existingChunks {
-		// 排除当前正在编辑的条目
-		if chunk.ID == excludeChunkID {
+		// 排除当前正在编辑的条目，以及不在查重范围内的条目
+		if chunk.ID == excludeChunkID || !scope.includes(chunk) {
 			continue
 		}
 
@@ -6123,6 +13610,43 @@ func (s *knowledgeService) checkFAQQuestionDuplicate(
 	return nil
 }
 
+// checkFAQTagMoveDuplicate 检查移动标签后的FAQ条目是否与目标标签下的条目重复
+// moveTo 返回已有条目移动后的目标标签，未移动的条目返回 false；按知识库查重时移动标签不影响查重结果
+func (s *knowledgeService) checkFAQTagMoveDuplicate(ctx context.Context,
+	tenantID uint64, kb *types.KnowledgeBase, moveTo func(chunk *types.Chunk) (string, bool),
+) error {
+	if kb.FAQConfig.GetDuplicateScope() != types.FAQDuplicateScopeTag {
+		return nil
+	}
+	existingChunks, err := s.chunkRepo.ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx, tenantID, kb.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list existing FAQ chunks: %w", err)
+	}
+
+	// 按移动后的标签比较，同一批移动到相同标签的条目之间也需要查重
+	moved := make([]*types.Chunk, 0)
+	chunks := make([]*types.Chunk, 0, len(existingChunks))
+	for _, chunk := range existingChunks {
+		if tagID, ok := moveTo(chunk); ok && tagID != chunk.TagID {
+			movedChunk := *chunk
+			movedChunk.TagID = tagID
+			chunk = &movedChunk
+			moved = append(moved, chunk)
+		}
+		chunks = append(chunks, chunk)
+	}
+	for _, chunk := range moved {
+		meta, err := chunk.FAQMetadata()
+		if err != nil || meta == nil {
+			continue
+		}
+		if err := checkFAQQuestionDuplicateInChunks(chunks, chunk.ID, newFAQDuplicateScope(kb, chunk.TagID), meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // resolveTagID resolves tag ID (UUID) from payload, prioritizing tag_id (seq_id) over tag_name
 // If no tag is specified, creates or finds the "未分类" tag
 // Returns the internal UUID of the tag
@@ -6155,7 +13679,11 @@ func (s *knowledgeService) resolveTagID(ctx context.Context, kbID string, payloa
 	return tag.ID, nil
 }
 
-func sanitizeFAQEntryPayload(payload *types.FAQEntryPayload) (*types.FAQChunkMetadata, error) {
+// sanitizeFAQEntryPayload 校验并规范化条目，生成 FAQ 元数据。
+// allowEmptyAnswers 为 true 时（导入时开启“允许无答案”）没有答案的条目标记为待补充答案而不报错
+func sanitizeFAQEntryPayload(payload *types.FAQEntryPayload, allowEmptyAnswers bool,
+	faqConfig *types.FAQConfig,
+) (*types.FAQChunkMetadata, error) {
 	// 处理 AnswerStrategy，默认为 all
 	answerStrategy := types.AnswerStrategyAll
 	if payload.AnswerStrategy != nil && *payload.AnswerStrategy != "" {
@@ -6166,6 +13694,14 @@ func sanitizeFAQEntryPayload(payload *types.FAQEntryPayload) (*types.FAQChunkMet
 			return nil, werrors.NewBadRequestError("answer_strategy 必须是 'all' 或 'random'")
 		}
 	}
+	// ContentFormat 为空时不落库，读取时使用知识库默认格式
+	var contentFormat types.AnswerContentFormat
+	if payload.ContentFormat != nil && *payload.ContentFormat != "" {
+		contentFormat = types.AnswerContentFormat(strings.ToLower(strings.TrimSpace(string(*payload.ContentFormat))))
+		if !contentFormat.IsValid() {
+			return nil, werrors.NewBadRequestError("content_format 必须是 'plain'、'markdown' 或 'html'")
+		}
+	}
 	meta := &types.FAQChunkMetadata{
 		StandardQuestion:  strings.TrimSpace(payload.StandardQuestion),
 		SimilarQuestions:  payload.SimilarQuestions,
@@ -6174,17 +13710,74 @@ func sanitizeFAQEntryPayload(payload *types.FAQEntryPayload) (*types.FAQChunkMet
 		AnswerStrategy:    answerStrategy,
 		Version:           1,
 		Source:            "faq",
+		ContentFormat:     contentFormat,
 	}
 	meta.Normalize()
 	if meta.StandardQuestion == "" {
 		return nil, werrors.NewBadRequestError("标准问不能为空")
 	}
+	if err := limitFAQStandardQuestion(meta, faqConfig); err != nil {
+		return nil, werrors.NewBadRequestError(err.Error())
+	}
 	if len(meta.Answers) == 0 {
-		return nil, werrors.NewBadRequestError("至少提供一个答案")
+		if !allowEmptyAnswers {
+			return nil, werrors.NewBadRequestError("至少提供一个答案")
+		}
+		meta.AnswerPending = true
 	}
 	return meta, nil
 }
 
+// limitFAQStandardQuestion 校验标准问长度，超长时按知识库配置拒绝，或按句拆分为标准问加相似问
+func limitFAQStandardQuestion(meta *types.FAQChunkMetadata, faqConfig *types.FAQConfig) error {
+	maxLen := faqConfig.GetMaxStandardQuestionLength()
+	if utf8.RuneCountInString(meta.StandardQuestion) <= maxLen {
+		return nil
+	}
+	tooLong := fmt.Errorf("标准问超过 %d 个字符，请精简为单个问题，补充说明可放入相似问或答案", maxLen)
+	if faqConfig.GetLongQuestionStrategy() != types.FAQLongQuestionStrategySplit {
+		return tooLong
+	}
+	sentences := splitFAQQuestionSentences(meta.StandardQuestion)
+	if len(sentences) < 2 {
+		return tooLong
+	}
+	for _, sentence := range sentences {
+		if utf8.RuneCountInString(sentence) > maxLen {
+			return tooLong
+		}
+	}
+	meta.StandardQuestion = sentences[0]
+	meta.SimilarQuestions = append(sentences[1:], meta.SimilarQuestions...)
+	meta.Normalize()
+	return nil
+}
+
+// splitFAQQuestionSentences 按换行和句末标点拆分问题，句末标点保留在句子中
+func splitFAQQuestionSentences(question string) []string {
+	var sentences []string
+	var current strings.Builder
+	flush := func() {
+		if sentence := strings.TrimSpace(current.String()); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		current.Reset()
+	}
+	for _, r := range question {
+		switch r {
+		case '\n', '\r':
+			flush()
+		case '。', '？', '！', '；', '?', '!', ';':
+			current.WriteRune(r)
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return sentences
+}
+
 func buildFAQIndexContent(meta *types.FAQChunkMetadata, mode types.FAQIndexMode) string {
 	var builder strings.Builder
 	builder.WriteString(meta.StandardQuestion)
@@ -6201,7 +13794,80 @@ func buildFAQIndexContent(meta *types.FAQChunkMetadata, mode types.FAQIndexMode)
 	return builder.String()
 }
 
-// buildFAQIndexInfoList 构建FAQ索引信息列表，支持分别索引模式
+// faqSimilarQuestionsSourceID 混合索引模式下相似问合并索引项的 SourceID
+func faqSimilarQuestionsSourceID(chunkID string) string {
+	return chunkID + "-similar"
+}
+
+// faqAnswerSourceID 答案独立索引项的 SourceID
+func faqAnswerSourceID(chunkID string, index int) string {
+	return fmt.Sprintf("%s-answer-%d", chunkID, index)
+}
+
+// faqIndexesAnswersSeparately 问答索引模式下分别/混合索引时，每个答案额外建立独立索引项，用于定位命中的答案
+func faqIndexesAnswersSeparately(indexMode types.FAQIndexMode, questionIndexMode types.FAQQuestionIndexMode) bool {
+	return indexMode == types.FAQIndexModeQuestionAnswer && questionIndexMode != types.FAQQuestionIndexModeCombined
+}
+
+// buildFAQAnswerIndexInfo 构建单个答案的独立索引项，空答案不建立索引
+func buildFAQAnswerIndexInfo(chunk *types.Chunk, answers []string, index int) *types.IndexInfo {
+	if index >= len(answers) || strings.TrimSpace(answers[index]) == "" {
+		return nil
+	}
+	return &types.IndexInfo{
+		Content:         answers[index],
+		SourceID:        faqAnswerSourceID(chunk.ID, index),
+		SourceType:      types.ChunkSourceType,
+		ChunkID:         chunk.ID,
+		KnowledgeID:     chunk.KnowledgeID,
+		KnowledgeBaseID: chunk.KnowledgeBaseID,
+		ChunkType:       chunk.ChunkType,
+		KnowledgeType:   types.KnowledgeTypeFAQ,
+		TagID:           chunk.TagID,
+		IsEnabled:       chunk.IsEnabled,
+		IsRecommended:   chunk.Flags.HasFlag(types.ChunkFlagRecommended),
+	}
+}
+
+// staleFAQAnswerSourceIDs 返回旧答案中已被移除或清空的答案独立索引项 SourceID
+func staleFAQAnswerSourceIDs(chunkID string, oldAnswers, newAnswers []string) []string {
+	sourceIDs := make([]string, 0)
+	for i, answer := range oldAnswers {
+		if strings.TrimSpace(answer) == "" {
+			continue
+		}
+		if i >= len(newAnswers) || strings.TrimSpace(newAnswers[i]) == "" {
+			sourceIDs = append(sourceIDs, faqAnswerSourceID(chunkID, i))
+		}
+	}
+	return sourceIDs
+}
+
+// buildFAQAnswerIndexInfoList 构建所有答案的独立索引项
+func buildFAQAnswerIndexInfoList(chunk *types.Chunk, answers []string) []*types.IndexInfo {
+	indexInfoList := make([]*types.IndexInfo, 0, len(answers))
+	for i := range answers {
+		if indexInfo := buildFAQAnswerIndexInfo(chunk, answers, i); indexInfo != nil {
+			indexInfoList = append(indexInfoList, indexInfo)
+		}
+	}
+	return indexInfoList
+}
+
+// buildFAQSimilarQuestionsIndexContent 构建混合索引模式下相似问合并索引项的内容
+func buildFAQSimilarQuestionsIndexContent(similarQuestions []string, answers []string, mode types.FAQIndexMode) string {
+	var builder strings.Builder
+	builder.WriteString(strings.Join(similarQuestions, "\n"))
+	if mode == types.FAQIndexModeQuestionAnswer {
+		for _, ans := range answers {
+			builder.WriteString("\n")
+			builder.WriteString(ans)
+		}
+	}
+	return builder.String()
+}
+
+// buildFAQIndexInfoList 构建FAQ索引信息列表，支持分别索引和混合索引模式
 func (s *knowledgeService) buildFAQIndexInfoList(
 	ctx context.Context,
 	kb *types.KnowledgeBase,
@@ -6237,6 +13903,7 @@ func (s *knowledgeService) buildFAQIndexInfoList(
 				ChunkID:         chunk.ID,
 				KnowledgeID:     chunk.KnowledgeID,
 				KnowledgeBaseID: chunk.KnowledgeBaseID,
+				ChunkType:       chunk.ChunkType,
 				KnowledgeType:   types.KnowledgeTypeFAQ,
 				TagID:           chunk.TagID,
 				IsEnabled:       chunk.IsEnabled,
@@ -6265,13 +13932,42 @@ func (s *knowledgeService) buildFAQIndexInfoList(
 		ChunkID:         chunk.ID,
 		KnowledgeID:     chunk.KnowledgeID,
 		KnowledgeBaseID: chunk.KnowledgeBaseID,
+		ChunkType:       chunk.ChunkType,
 		KnowledgeType:   types.KnowledgeTypeFAQ,
 		TagID:           chunk.TagID,
 		IsEnabled:       chunk.IsEnabled,
 	})
 
-	// 每个相似问创建一个索引项
-	for i, similarQ := range meta.SimilarQuestions {
+	// 混合索引模式：标准问独立索引，所有相似问合并为一个索引项
+	if questionIndexMode == types.FAQQuestionIndexModeHybrid {
+		if len(meta.SimilarQuestions) > 0 {
+			indexInfoList = append(indexInfoList, &types.IndexInfo{
+				Content:         buildFAQSimilarQuestionsIndexContent(meta.SimilarQuestions, meta.Answers, indexMode),
+				SourceID:        faqSimilarQuestionsSourceID(chunk.ID),
+				SourceType:      types.ChunkSourceType,
+				ChunkID:         chunk.ID,
+				KnowledgeID:     chunk.KnowledgeID,
+				KnowledgeBaseID: chunk.KnowledgeBaseID,
+				ChunkType:       chunk.ChunkType,
+				KnowledgeType:   types.KnowledgeTypeFAQ,
+				TagID:           chunk.TagID,
+				IsEnabled:       chunk.IsEnabled,
+			})
+		}
+		if faqIndexesAnswersSeparately(indexMode, questionIndexMode) {
+			indexInfoList = append(indexInfoList, buildFAQAnswerIndexInfoList(chunk, meta.Answers)...)
+		}
+		return indexInfoList, nil
+	}
+
+	// 每个相似问创建一个索引项（受单条目索引上限约束）
+	positions, truncated := faqIndexedSimilarQuestionPositions(kb, meta.SimilarQuestions)
+	if truncated > 0 {
+		logger.Warnf(ctx, "FAQ chunk %s has %d similar questions, %d are stored but not indexed (limit %d)",
+			chunk.ID, len(meta.SimilarQuestions), truncated, kb.FAQConfig.GetMaxIndexedSimilarQuestions())
+	}
+	for _, i := range positions {
+		similarQ := meta.SimilarQuestions[i]
 		similarContent := similarQ
 		if indexMode == types.FAQIndexModeQuestionAnswer && len(meta.Answers) > 0 {
 			var builder strings.Builder
@@ -6290,15 +13986,59 @@ func (s *knowledgeService) buildFAQIndexInfoList(
 			ChunkID:         chunk.ID,
 			KnowledgeID:     chunk.KnowledgeID,
 			KnowledgeBaseID: chunk.KnowledgeBaseID,
+			ChunkType:       chunk.ChunkType,
 			KnowledgeType:   types.KnowledgeTypeFAQ,
 			TagID:           chunk.TagID,
 			IsEnabled:       chunk.IsEnabled,
 		})
 	}
 
+	// 每个答案创建一个索引项，检索命中时可定位到具体答案
+	if faqIndexesAnswersSeparately(indexMode, questionIndexMode) {
+		indexInfoList = append(indexInfoList, buildFAQAnswerIndexInfoList(chunk, meta.Answers)...)
+	}
+
 	return indexInfoList, nil
 }
 
+// faqIndexedSimilarQuestionPositions returns the positions in similarQuestions that get their own index
+// entry in separate mode. With a limit configured, repeated questions are skipped and only the first
+// distinct ones up to the limit are indexed. Positions are kept so source IDs still map back to
+// SimilarQuestions. truncated is the number of similar questions left unindexed.
+func faqIndexedSimilarQuestionPositions(kb *types.KnowledgeBase, similarQuestions []string) ([]int, int) {
+	positions := make([]int, 0, len(similarQuestions))
+	limit := kb.FAQConfig.GetMaxIndexedSimilarQuestions()
+	if limit <= 0 {
+		for i := range similarQuestions {
+			positions = append(positions, i)
+		}
+		return positions, 0
+	}
+	seen := make(map[string]struct{}, len(similarQuestions))
+	for i, q := range similarQuestions {
+		if len(positions) >= limit {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(q))
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		positions = append(positions, i)
+	}
+	return positions, len(similarQuestions) - len(positions)
+}
+
+// faqUnindexedSimilarQuestionCount returns how many similar questions of an entry are stored but not
+// indexed because of the per-entry limit, which only applies when questions are indexed separately
+func faqUnindexedSimilarQuestionCount(kb *types.KnowledgeBase, similarQuestions []string) int {
+	if kb.FAQConfig == nil || kb.FAQConfig.QuestionIndexMode != types.FAQQuestionIndexModeSeparate {
+		return 0
+	}
+	_, truncated := faqIndexedSimilarQuestionPositions(kb, similarQuestions)
+	return truncated
+}
+
 // incrementalIndexFAQEntry 增量更新FAQ条目的索引
 // 只对内容变化的部分进行embedding计算和索引更新，跳过未变化的部分
 func (s *knowledgeService) incrementalIndexFAQEntry(
@@ -6321,8 +14061,14 @@ func (s *knowledgeService) incrementalIndexFAQEntry(
 	}
 
 	indexMode := types.FAQIndexModeQuestionAnswer
-	if kb.FAQConfig != nil && kb.FAQConfig.IndexMode != "" {
-		indexMode = kb.FAQConfig.IndexMode
+	questionIndexMode := types.FAQQuestionIndexModeSeparate
+	if kb.FAQConfig != nil {
+		if kb.FAQConfig.IndexMode != "" {
+			indexMode = kb.FAQConfig.IndexMode
+		}
+		if kb.FAQConfig.QuestionIndexMode != "" {
+			questionIndexMode = kb.FAQConfig.QuestionIndexMode
+		}
 	}
 
 	// 构建旧的内容（用于比较）
@@ -6370,6 +14116,7 @@ func (s *knowledgeService) incrementalIndexFAQEntry(
 			ChunkID:         chunk.ID,
 			KnowledgeID:     chunk.KnowledgeID,
 			KnowledgeBaseID: chunk.KnowledgeBaseID,
+			ChunkType:       chunk.ChunkType,
 			KnowledgeType:   types.KnowledgeTypeFAQ,
 			TagID:           chunk.TagID,
 			IsEnabled:       chunk.IsEnabled,
@@ -6377,61 +14124,130 @@ func (s *knowledgeService) incrementalIndexFAQEntry(
 		})
 	}
 
-	// 2. 检查每个相似问是否需要更新
+	// 2. 检查相似问是否需要更新
 	oldCount := len(oldSimilarQuestions)
 	newCount := len(newMeta.SimilarQuestions)
-
-	for i, newQ := range newMeta.SimilarQuestions {
-		needUpdate := false
-		if i >= oldCount {
-			// 新增的相似问
-			needUpdate = true
+	totalEntries := 1 + newCount
+
+	if questionIndexMode == types.FAQQuestionIndexModeHybrid {
+		// 混合索引模式：所有相似问合并为一个索引项，任一相似问或答案变化时整体重建
+		totalEntries = 1
+		similarSourceID := faqSimilarQuestionsSourceID(chunk.ID)
+		if newCount == 0 {
+			if oldCount > 0 {
+				logger.Debugf(ctx, "incrementalIndexFAQEntry: deleting combined similar question entry %s", similarSourceID)
+				if delErr := retrieveEngine.DeleteBySourceIDList(ctx, []string{similarSourceID}, embeddingModel.GetDimensions(), types.KnowledgeTypeFAQ); delErr != nil {
+					logger.Warnf(ctx, "incrementalIndexFAQEntry: failed to delete combined similar question entry: %v", delErr)
+				}
+			}
 		} else {
-			// 已存在的相似问，检查内容是否变化
-			oldQ := oldSimilarQuestions[i]
-			if oldQ != newQ || answersChanged {
+			totalEntries = 2
+			oldContent := buildFAQSimilarQuestionsIndexContent(oldSimilarQuestions, oldAnswers, indexMode)
+			newContent := buildFAQSimilarQuestionsIndexContent(newMeta.SimilarQuestions, newMeta.Answers, indexMode)
+			if oldCount == 0 || oldContent != newContent {
+				indexInfoToUpdate = append(indexInfoToUpdate, &types.IndexInfo{
+					Content:         newContent,
+					SourceID:        similarSourceID,
+					SourceType:      types.ChunkSourceType,
+					ChunkID:         chunk.ID,
+					KnowledgeID:     chunk.KnowledgeID,
+					KnowledgeBaseID: chunk.KnowledgeBaseID,
+					ChunkType:       chunk.ChunkType,
+					KnowledgeType:   types.KnowledgeTypeFAQ,
+					TagID:           chunk.TagID,
+					IsEnabled:       chunk.IsEnabled,
+					IsRecommended:   chunk.Flags.HasFlag(types.ChunkFlagRecommended),
+				})
+			}
+		}
+	} else {
+		// 分别索引模式：逐个检查相似问是否需要更新（仅上限内实际索引的相似问）
+		oldPositions, _ := faqIndexedSimilarQuestionPositions(kb, oldSimilarQuestions)
+		newPositions, _ := faqIndexedSimilarQuestionPositions(kb, newMeta.SimilarQuestions)
+		totalEntries = 1 + len(newPositions)
+		oldIndexed := make(map[int]bool, len(oldPositions))
+		for _, i := range oldPositions {
+			oldIndexed[i] = true
+		}
+		newIndexed := make(map[int]bool, len(newPositions))
+		for _, i := range newPositions {
+			newIndexed[i] = true
+			newQ := newMeta.SimilarQuestions[i]
+			needUpdate := false
+			if !oldIndexed[i] {
+				// 新增（或新进入索引范围）的相似问
 				needUpdate = true
+			} else {
+				// 已存在的相似问，检查内容是否变化
+				oldQ := oldSimilarQuestions[i]
+				if oldQ != newQ || answersChanged {
+					needUpdate = true
+				}
+			}
+
+			if needUpdate {
+				sourceID := fmt.Sprintf("%s-%d", chunk.ID, i)
+				indexInfoToUpdate = append(indexInfoToUpdate, &types.IndexInfo{
+					Content:         buildNewContent(newQ),
+					SourceID:        sourceID,
+					SourceType:      types.ChunkSourceType,
+					ChunkID:         chunk.ID,
+					KnowledgeID:     chunk.KnowledgeID,
+					KnowledgeBaseID: chunk.KnowledgeBaseID,
+					ChunkType:       chunk.ChunkType,
+					KnowledgeType:   types.KnowledgeTypeFAQ,
+					TagID:           chunk.TagID,
+					IsEnabled:       chunk.IsEnabled,
+					IsRecommended:   chunk.Flags.HasFlag(types.ChunkFlagRecommended),
+				})
 			}
 		}
 
-		if needUpdate {
-			sourceID := fmt.Sprintf("%s-%d", chunk.ID, i)
-			indexInfoToUpdate = append(indexInfoToUpdate, &types.IndexInfo{
-				Content:         buildNewContent(newQ),
-				SourceID:        sourceID,
-				SourceType:      types.ChunkSourceType,
-				ChunkID:         chunk.ID,
-				KnowledgeID:     chunk.KnowledgeID,
-				KnowledgeBaseID: chunk.KnowledgeBaseID,
-				KnowledgeType:   types.KnowledgeTypeFAQ,
-				TagID:           chunk.TagID,
-				IsEnabled:       chunk.IsEnabled,
-				IsRecommended:   chunk.Flags.HasFlag(types.ChunkFlagRecommended),
-			})
+		// 3. 删除不再索引的旧相似问
+		sourceIDsToDelete := make([]string, 0)
+		for _, i := range oldPositions {
+			if !newIndexed[i] {
+				sourceIDsToDelete = append(sourceIDsToDelete, fmt.Sprintf("%s-%d", chunk.ID, i))
+			}
+		}
+		if len(sourceIDsToDelete) > 0 {
+			logger.Debugf(ctx, "incrementalIndexFAQEntry: deleting %d obsolete source IDs", len(sourceIDsToDelete))
+			if delErr := retrieveEngine.DeleteBySourceIDList(ctx, sourceIDsToDelete, embeddingModel.GetDimensions(), types.KnowledgeTypeFAQ); delErr != nil {
+				logger.Warnf(ctx, "incrementalIndexFAQEntry: failed to delete obsolete source IDs: %v", delErr)
+			}
 		}
 	}
 
-	// 3. 删除多余的旧相似问索引
-	if oldCount > newCount {
-		sourceIDsToDelete := make([]string, 0, oldCount-newCount)
-		for i := newCount; i < oldCount; i++ {
-			sourceIDsToDelete = append(sourceIDsToDelete, fmt.Sprintf("%s-%d", chunk.ID, i))
+	// 答案独立索引项：重新索引新增或变化的答案，删除已移除或被清空的答案
+	if faqIndexesAnswersSeparately(indexMode, questionIndexMode) {
+		for i := range newMeta.Answers {
+			newInfo := buildFAQAnswerIndexInfo(chunk, newMeta.Answers, i)
+			if newInfo == nil {
+				continue
+			}
+			totalEntries++
+			if i >= len(oldAnswers) || oldAnswers[i] != newMeta.Answers[i] {
+				indexInfoToUpdate = append(indexInfoToUpdate, newInfo)
+			}
 		}
-		logger.Debugf(ctx, "incrementalIndexFAQEntry: deleting %d obsolete source IDs", len(sourceIDsToDelete))
-		if delErr := retrieveEngine.DeleteBySourceIDList(ctx, sourceIDsToDelete, embeddingModel.GetDimensions(), types.KnowledgeTypeFAQ); delErr != nil {
-			logger.Warnf(ctx, "incrementalIndexFAQEntry: failed to delete obsolete source IDs: %v", delErr)
+		answerSourceIDsToDelete := staleFAQAnswerSourceIDs(chunk.ID, oldAnswers, newMeta.Answers)
+		if len(answerSourceIDsToDelete) > 0 {
+			logger.Debugf(ctx, "incrementalIndexFAQEntry: deleting %d obsolete answer source IDs", len(answerSourceIDsToDelete))
+			if delErr := retrieveEngine.DeleteBySourceIDList(ctx, answerSourceIDsToDelete, embeddingModel.GetDimensions(), types.KnowledgeTypeFAQ); delErr != nil {
+				logger.Warnf(ctx, "incrementalIndexFAQEntry: failed to delete obsolete answer source IDs: %v", delErr)
+			}
 		}
 	}
 
 	// 4. 批量索引需要更新的内容
 	if len(indexInfoToUpdate) > 0 {
 		logger.Debugf(ctx, "incrementalIndexFAQEntry: updating %d index entries (skipped %d unchanged)",
-			len(indexInfoToUpdate), 1+newCount-len(indexInfoToUpdate))
+			len(indexInfoToUpdate), totalEntries-len(indexInfoToUpdate))
 		if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoToUpdate); err != nil {
 			return err
 		}
 	} else {
-		logger.Debugf(ctx, "incrementalIndexFAQEntry: all %d entries unchanged, skipping index update", 1+newCount)
+		logger.Debugf(ctx, "incrementalIndexFAQEntry: all %d entries unchanged, skipping index update", totalEntries)
 	}
 
 	// 5. 更新 knowledge 记录
@@ -6444,7 +14260,7 @@ func (s *knowledgeService) incrementalIndexFAQEntry(
 
 	totalDuration := time.Since(indexStartTime)
 	logger.Debugf(ctx, "incrementalIndexFAQEntry: completed in %v, updated %d/%d entries",
-		totalDuration, len(indexInfoToUpdate), 1+newCount)
+		totalDuration, len(indexInfoToUpdate), totalEntries)
 
 	return nil
 }
@@ -6512,13 +14328,15 @@ func (s *knowledgeService) indexFAQChunks(ctx context.Context,
 	}
 
 	// 批量索引（这里可能是性能瓶颈）
+	// 同一批次内归一化后内容相同的索引项（如多个条目共用的相似问）只生成一次向量，各索引项仍保留自己的 SourceID
 	batchIndexStartTime := time.Now()
-	if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfo); err != nil {
+	dedupModel := newDedupEmbedder(embeddingModel)
+	if err := retrieveEngine.BatchIndex(ctx, dedupModel, indexInfo); err != nil {
 		return err
 	}
 	batchIndexDuration := time.Since(batchIndexStartTime)
-	logger.Debugf(ctx, "indexFAQChunks: batch indexed %d index info entries in %v (avg: %v per entry)",
-		len(indexInfo), batchIndexDuration, batchIndexDuration/time.Duration(len(indexInfo)))
+	logger.Debugf(ctx, "indexFAQChunks: batch indexed %d index info entries in %v (avg: %v per entry, reused %d embeddings)",
+		len(indexInfo), batchIndexDuration, batchIndexDuration/time.Duration(len(indexInfo)), dedupModel.Reused())
 
 	if adjustStorage && size > 0 {
 		adjustStartTime := time.Now()
@@ -6557,6 +14375,106 @@ func (s *knowledgeService) indexFAQChunks(ctx context.Context,
 	return err
 }
 
+// dedupEmbedder wraps an embedder and reuses the vector of texts that are identical after
+// whitespace normalization, keyed by a content hash. It is meant to live for a single
+// indexing call, e.g. one FAQ import batch, so the cache never outgrows that batch.
+type dedupEmbedder struct {
+	embedding.Embedder
+	mu     sync.Mutex
+	cache  map[string][]float32
+	reused int
+}
+
+func newDedupEmbedder(embedder embedding.Embedder) *dedupEmbedder {
+	return &dedupEmbedder{Embedder: embedder, cache: make(map[string][]float32)}
+}
+
+// embeddingCacheKey returns the content hash of text with surrounding and repeated whitespace collapsed
+func embeddingCacheKey(text string) string {
+	return chunkContentHash(strings.Join(strings.Fields(text), " "))
+}
+
+// Reused returns how many texts were served from the cache instead of being embedded
+func (d *dedupEmbedder) Reused() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.reused
+}
+
+func (d *dedupEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := d.embed([]string{text}, func(texts []string) ([][]float32, error) {
+		vector, err := d.Embedder.Embed(ctx, texts[0])
+		return [][]float32{vector}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (d *dedupEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	return d.embed(texts, func(texts []string) ([][]float32, error) {
+		return d.Embedder.BatchEmbed(ctx, texts)
+	})
+}
+
+func (d *dedupEmbedder) BatchEmbedWithPool(ctx context.Context,
+	_ embedding.Embedder, texts []string,
+) ([][]float32, error) {
+	return d.embed(texts, func(texts []string) ([][]float32, error) {
+		return d.Embedder.BatchEmbedWithPool(ctx, d.Embedder, texts)
+	})
+}
+
+// embed returns the vectors of texts in order, calling embedMissing once with the
+// distinct texts that are not cached yet. Every returned vector is a copy of the cached
+// one, so a vector store that modifies a vector in place cannot affect the cache or the
+// entries sharing it.
+func (d *dedupEmbedder) embed(texts []string,
+	embedMissing func(texts []string) ([][]float32, error),
+) ([][]float32, error) {
+	keys := make([]string, len(texts))
+	missing := make([]string, 0, len(texts))
+	fresh := make(map[string]bool)
+	d.mu.Lock()
+	for i, text := range texts {
+		keys[i] = embeddingCacheKey(text)
+		if _, ok := d.cache[keys[i]]; ok {
+			continue
+		}
+		if _, ok := fresh[keys[i]]; ok {
+			continue
+		}
+		fresh[keys[i]] = true
+		missing = append(missing, text)
+	}
+	d.mu.Unlock()
+
+	var vectors [][]float32
+	if len(missing) > 0 {
+		var err error
+		vectors, err = embedMissing(missing)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(missing) {
+			return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(missing))
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, text := range missing {
+		d.cache[embeddingCacheKey(text)] = vectors[i]
+	}
+	d.reused += len(texts) - len(missing)
+	result := make([][]float32, len(texts))
+	for i, key := range keys {
+		result[i] = slices.Clone(d.cache[key])
+	}
+	return result, nil
+}
+
 func (s *knowledgeService) deleteFAQChunkVectors(ctx context.Context,
 	kb *types.KnowledgeBase, knowledge *types.Knowledge, chunks []*types.Chunk,
 ) error {
@@ -6618,7 +14536,20 @@ func ensureManualFileName(title string) string {
 
 func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
 	kb *types.KnowledgeBase, knowledge *types.Knowledge, content string, sync bool,
+	opts ...ProcessChunksOptions,
 ) {
+	var options ProcessChunksOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	// 解析阶段提前返回时释放交接过来的知识锁，进入分块处理后由处理结束时释放
+	started := false
+	defer func() {
+		if !started {
+			s.releaseKnowledgeLock(ctx, knowledge.ID, options.LockToken)
+		}
+	}()
+
 	clean := strings.TrimSpace(content)
 	if clean == "" {
 		return
@@ -6630,7 +14561,7 @@ func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
 	fileType := "md"
 
 	// 检查是否需要启用多模态（对于手动内容通常不需要，但保持一致性）
-	enableMultimodel := kb.IsMultimodalEnabled() && kb.StorageConfig.Provider != ""
+	enableMultimodel := s.applyMultimodalKillSwitch(ctx, kb.IsMultimodalEnabled() && kb.StorageConfig.Provider != "")
 
 	var vlmConfig *proto.VLMConfig
 	if enableMultimodel {
@@ -6696,13 +14627,23 @@ func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
 		return
 	}
 
+	// 发布后是否启用由手工知识元数据决定，默认启用
+	if meta, err := knowledge.ManualMetadata(); err == nil && meta != nil {
+		options.KeepDisabled = !meta.ShouldEnableAfterPublish()
+	}
+
+	started = true
 	if sync {
-		s.processChunks(ctx, kb, knowledge, resp.Chunks)
+		defer s.releaseKnowledgeLock(ctx, knowledge.ID, options.LockToken)
+		s.processChunks(ctx, kb, knowledge, resp.Chunks, options)
 		return
 	}
 
 	newCtx := logger.CloneContext(ctx)
-	go s.processChunks(newCtx, kb, knowledge, resp.Chunks)
+	go func() {
+		defer s.releaseKnowledgeLock(newCtx, knowledge.ID, options.LockToken)
+		s.processChunks(newCtx, kb, knowledge, resp.Chunks, options)
+	}()
 }
 
 func (s *knowledgeService) cleanupKnowledgeResources(ctx context.Context, knowledge *types.Knowledge) error {
@@ -6738,67 +14679,373 @@ func (s *knowledgeService) cleanupKnowledgeResources(ctx context.Context, knowle
 		}
 	}
 
-	if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Error("Failed to delete manual knowledge chunks")
-		cleanupErr = errors.Join(cleanupErr, err)
-	}
-
-	namespace := types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID}
-	if err := s.graphEngine.DelGraph(ctx, []types.NameSpace{namespace}); err != nil {
-		logger.GetLogger(ctx).WithField("error", err).Error("Failed to delete manual knowledge graph data")
-		cleanupErr = errors.Join(cleanupErr, err)
-	}
-
-	if knowledge.StorageSize > 0 {
-		tenantInfo.StorageUsed -= knowledge.StorageSize
-		if tenantInfo.StorageUsed < 0 {
-			tenantInfo.StorageUsed = 0
-		}
-		if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, -knowledge.StorageSize); err != nil {
-			logger.GetLogger(ctx).WithField("error", err).Error("Failed to adjust storage usage during manual cleanup")
-			cleanupErr = errors.Join(cleanupErr, err)
+	if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Error("Failed to delete manual knowledge chunks")
+		cleanupErr = errors.Join(cleanupErr, err)
+	}
+
+	namespace := types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID}
+	if err := s.graphEngine.DelGraph(ctx, []types.NameSpace{namespace}); err != nil {
+		logger.GetLogger(ctx).WithField("error", err).Error("Failed to delete manual knowledge graph data")
+		cleanupErr = errors.Join(cleanupErr, err)
+	}
+
+	if knowledge.StorageSize > 0 {
+		tenantInfo.StorageUsed -= knowledge.StorageSize
+		if tenantInfo.StorageUsed < 0 {
+			tenantInfo.StorageUsed = 0
+		}
+		if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, -knowledge.StorageSize); err != nil {
+			logger.GetLogger(ctx).WithField("error", err).Error("Failed to adjust storage usage during manual cleanup")
+			cleanupErr = errors.Join(cleanupErr, err)
+		}
+		knowledge.StorageSize = 0
+	}
+
+	return cleanupErr
+}
+
+func (s *knowledgeService) getVLMProtoConfig(ctx context.Context, kb *types.KnowledgeBase) (*proto.VLMConfig, error) {
+	if kb == nil {
+		return nil, nil
+	}
+	// 兼容老版本：直接使用 ModelName 和 BaseURL
+	if kb.VLMConfig.ModelName != "" && kb.VLMConfig.BaseURL != "" {
+		return &proto.VLMConfig{
+			ModelName:     kb.VLMConfig.ModelName,
+			BaseUrl:       kb.VLMConfig.BaseURL,
+			ApiKey:        kb.VLMConfig.APIKey,
+			InterfaceType: kb.VLMConfig.InterfaceType,
+		}, nil
+	}
+
+	// 新版本：未启用或无模型ID时返回nil
+	if !kb.VLMConfig.Enabled || kb.VLMConfig.ModelID == "" {
+		return nil, nil
+	}
+
+	model, err := s.modelService.GetModelByID(ctx, kb.VLMConfig.ModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaceType := model.Parameters.InterfaceType
+	if interfaceType == "" {
+		interfaceType = "openai"
+	}
+
+	return &proto.VLMConfig{
+		ModelName:     model.Name,
+		BaseUrl:       model.Parameters.BaseURL,
+		ApiKey:        model.Parameters.APIKey,
+		InterfaceType: interfaceType,
+	}, nil
+}
+
+// buildDocReaderReadConfig 根据知识库配置组装下发给 docreader 的 ReadConfig
+func buildDocReaderReadConfig(kb *types.KnowledgeBase, enableMultimodal bool,
+	vlmConfig *proto.VLMConfig,
+) *proto.ReadConfig {
+	return &proto.ReadConfig{
+		ChunkSize:        int32(kb.ChunkingConfig.ChunkSize),
+		ChunkOverlap:     int32(kb.ChunkingConfig.ChunkOverlap),
+		Separators:       kb.ChunkingConfig.Separators,
+		EnableMultimodal: enableMultimodal,
+		StorageConfig: &proto.StorageConfig{
+			Provider:        proto.StorageProvider(proto.StorageProvider_value[strings.ToUpper(kb.StorageConfig.Provider)]),
+			Region:          kb.StorageConfig.Region,
+			BucketName:      kb.StorageConfig.BucketName,
+			AccessKeyId:     kb.StorageConfig.SecretID,
+			SecretAccessKey: kb.StorageConfig.SecretKey,
+			AppId:           kb.StorageConfig.AppID,
+			PathPrefix:      kb.StorageConfig.PathPrefix,
+		},
+		VlmConfig:           vlmConfig,
+		ParseTimeoutSeconds: int32(min(max(kb.ChunkingConfig.ParseTimeoutSeconds, 0), types.MaxParseTimeoutSeconds)),
+		AllowPartialResult:  kb.ChunkingConfig.AcceptPartialResult,
+	}
+}
+
+// escalateParseTimeout doubles the parse timeout of readConfig for every retry, up to types.MaxParseTimeoutSeconds
+func escalateParseTimeout(readConfig *proto.ReadConfig, retryCount int) {
+	timeout := int(readConfig.ParseTimeoutSeconds)
+	for i := 0; i < retryCount && timeout > 0 && timeout < types.MaxParseTimeoutSeconds; i++ {
+		timeout *= 2
+	}
+	readConfig.ParseTimeoutSeconds = int32(min(timeout, types.MaxParseTimeoutSeconds))
+}
+
+// checkPartialReadResponse handles a docreader response cut short by the parse timeout. Knowledge bases
+// that accept partial results go on with the returned chunks; otherwise the knowledge fails, as it would
+// have if docreader had returned an error. Returns false if processing should stop.
+func (s *knowledgeService) checkPartialReadResponse(ctx context.Context,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, resp *proto.ReadResponse,
+) bool {
+	if !resp.GetPartial() {
+		return true
+	}
+	if !kb.ChunkingConfig.AcceptPartialResult || len(resp.GetChunks()) == 0 {
+		logger.Warnf(ctx, "DocReader returned a partial result for knowledge %s, rejecting", knowledge.ID)
+		s.failKnowledgeProcessing(ctx, knowledge, "文档解析超时")
+		return false
+	}
+	logger.Warnf(ctx, "DocReader returned a partial result for knowledge %s with %d chunks",
+		knowledge.ID, len(resp.GetChunks()))
+	return true
+}
+
+// ListKnowledgeLinks lists hyperlinks extracted from the documents of a knowledge base
+func (s *knowledgeService) ListKnowledgeLinks(ctx context.Context, kbID string) ([]*types.KnowledgeLink, error) {
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	chunks, err := s.chunkRepo.ListTextChunksWithLinksByKnowledgeBaseID(ctx, tenantID, kb.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	knowledgeIDSet := make(map[string]struct{})
+	for _, chunk := range chunks {
+		knowledgeIDSet[chunk.KnowledgeID] = struct{}{}
+	}
+	knowledgeTitles := make(map[string]string, len(knowledgeIDSet))
+	if len(knowledgeIDSet) > 0 {
+		knowledgeIDs := make([]string, 0, len(knowledgeIDSet))
+		for id := range knowledgeIDSet {
+			knowledgeIDs = append(knowledgeIDs, id)
+		}
+		knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, tenantID, knowledgeIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, knowledge := range knowledgeList {
+			knowledgeTitles[knowledge.ID] = knowledge.Title
+		}
+	}
+
+	links := make([]*types.KnowledgeLink, 0)
+	for _, chunk := range chunks {
+		title, ok := knowledgeTitles[chunk.KnowledgeID]
+		if !ok {
+			// Knowledge has been deleted
+			continue
+		}
+		meta, err := chunk.DocumentMetadata()
+		if err != nil || meta == nil {
+			continue
+		}
+		for _, link := range meta.Links {
+			links = append(links, &types.KnowledgeLink{
+				URL:            link.URL,
+				AnchorText:     link.AnchorText,
+				KnowledgeID:    chunk.KnowledgeID,
+				KnowledgeTitle: title,
+				ChunkID:        chunk.ID,
+				ChunkIndex:     chunk.ChunkIndex,
+			})
+		}
+	}
+	return links, nil
+}
+
+const (
+	// generatedQuestionExportPageSize 导出自动生成问题时每页读取的分块数
+	generatedQuestionExportPageSize = 500
+	// generatedQuestionExcerptLength 导出时分块摘录的最大字符数
+	generatedQuestionExcerptLength = 200
+)
+
+// ExportGeneratedQuestions streams the questions generated for document chunks of a knowledge base
+// to w as CSV or a JSON array, one row per question. Chunks are read page by page so memory use does
+// not grow with the size of the knowledge base.
+func (s *knowledgeService) ExportGeneratedQuestions(ctx context.Context,
+	kbID string, filter *types.GeneratedQuestionExportFilter, w io.Writer,
+) error {
+	format := filter.Format
+	if format == "" {
+		format = types.GeneratedQuestionExportFormatCSV
+	}
+	if format != types.GeneratedQuestionExportFormatCSV && format != types.GeneratedQuestionExportFormatJSON {
+		return werrors.NewBadRequestError("导出格式仅支持 csv 或 json")
+	}
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return err
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	writeRow := func(row *types.GeneratedQuestionExportRow) error {
+		fields := []string{
+			row.KnowledgeID, row.KnowledgeTitle, row.ChunkID, strconv.Itoa(row.ChunkIndex),
+			row.ChunkExcerpt, row.QuestionID, row.Question,
+		}
+		for i, field := range fields {
+			fields[i] = escapeCSVField(field)
+		}
+		_, err := io.WriteString(w, strings.Join(fields, ",")+"\n")
+		return err
+	}
+	if format == types.GeneratedQuestionExportFormatCSV {
+		if _, err := io.WriteString(w,
+			"knowledge_id,knowledge_title,chunk_id,chunk_index,chunk_excerpt,question_id,question\n"); err != nil {
+			return err
+		}
+	} else {
+		if _, err := io.WriteString(w, "["); err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		written := 0
+		writeRow = func(row *types.GeneratedQuestionExportRow) error {
+			if written > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			written++
+			return encoder.Encode(row)
+		}
+	}
+
+	knowledgeTitles := make(map[string]string)
+	exported := 0
+	var afterSeqID int64
+	for {
+		chunks, err := s.chunkRepo.ListGeneratedQuestionChunks(ctx, tenantID, kb.ID,
+			filter.KnowledgeID, filter.TagID, afterSeqID, generatedQuestionExportPageSize)
+		if err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			break
+		}
+		afterSeqID = chunks[len(chunks)-1].SeqID
+
+		missing := make([]string, 0)
+		for _, chunk := range chunks {
+			if _, ok := knowledgeTitles[chunk.KnowledgeID]; !ok && !slices.Contains(missing, chunk.KnowledgeID) {
+				missing = append(missing, chunk.KnowledgeID)
+			}
+		}
+		if len(missing) > 0 {
+			knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, tenantID, missing)
+			if err != nil {
+				return err
+			}
+			for _, id := range missing {
+				// Deleted knowledge is remembered with an empty title and skipped below
+				knowledgeTitles[id] = ""
+			}
+			for _, knowledge := range knowledgeList {
+				knowledgeTitles[knowledge.ID] = cmp.Or(knowledge.Title, knowledge.FileName, knowledge.ID)
+			}
+		}
+
+		for _, chunk := range chunks {
+			title := knowledgeTitles[chunk.KnowledgeID]
+			if title == "" {
+				continue
+			}
+			meta, err := chunk.DocumentMetadata()
+			if err != nil || meta == nil {
+				continue
+			}
+			excerpt := []rune(strings.TrimSpace(chunk.Content))
+			if len(excerpt) > generatedQuestionExcerptLength {
+				excerpt = append(excerpt[:generatedQuestionExcerptLength], []rune("...")...)
+			}
+			for _, question := range meta.GeneratedQuestions {
+				if err := writeRow(&types.GeneratedQuestionExportRow{
+					KnowledgeID:    chunk.KnowledgeID,
+					KnowledgeTitle: title,
+					ChunkID:        chunk.ID,
+					ChunkIndex:     chunk.ChunkIndex,
+					ChunkExcerpt:   string(excerpt),
+					QuestionID:     question.ID,
+					Question:       question.Question,
+				}); err != nil {
+					return err
+				}
+				exported++
+			}
+		}
+		if len(chunks) < generatedQuestionExportPageSize {
+			break
+		}
+	}
+
+	if format == types.GeneratedQuestionExportFormatJSON {
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return err
 		}
-		knowledge.StorageSize = 0
 	}
-
-	return cleanupErr
+	logger.Infof(ctx, "Exported %d generated questions from knowledge base %s", exported, kb.ID)
+	return nil
 }
 
-func (s *knowledgeService) getVLMProtoConfig(ctx context.Context, kb *types.KnowledgeBase) (*proto.VLMConfig, error) {
-	if kb == nil {
-		return nil, nil
-	}
-	// 兼容老版本：直接使用 ModelName 和 BaseURL
-	if kb.VLMConfig.ModelName != "" && kb.VLMConfig.BaseURL != "" {
-		return &proto.VLMConfig{
-			ModelName:     kb.VLMConfig.ModelName,
-			BaseUrl:       kb.VLMConfig.BaseURL,
-			ApiKey:        kb.VLMConfig.APIKey,
-			InterfaceType: kb.VLMConfig.InterfaceType,
-		}, nil
-	}
-
-	// 新版本：未启用或无模型ID时返回nil
-	if !kb.VLMConfig.Enabled || kb.VLMConfig.ModelID == "" {
-		return nil, nil
-	}
-
-	model, err := s.modelService.GetModelByID(ctx, kb.VLMConfig.ModelID)
+// GetEffectiveReadConfig resolves the ReadConfig that ProcessDocument would send to docreader
+// for a document of the given type, without parsing anything. Secrets are not included.
+func (s *knowledgeService) GetEffectiveReadConfig(ctx context.Context,
+	kbID string, fileType string, overrides *types.ReadConfigOverrides,
+) (*types.EffectiveReadConfig, error) {
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
 	if err != nil {
 		return nil, err
 	}
+	fileType = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileType), "."))
 
-	interfaceType := model.Parameters.InterfaceType
-	if interfaceType == "" {
-		interfaceType = "openai"
+	// 与 CreateKnowledgeFromFile 一致：覆盖参数优先，否则使用知识库配置
+	enableMultimodal := kb.IsMultimodalEnabled()
+	if overrides != nil && overrides.EnableMultimodel != nil {
+		enableMultimodal = *overrides.EnableMultimodel
 	}
 
-	return &proto.VLMConfig{
-		ModelName:     model.Name,
-		BaseUrl:       model.Parameters.BaseURL,
-		ApiKey:        model.Parameters.APIKey,
-		InterfaceType: interfaceType,
-	}, nil
+	var warnings []string
+	if enableMultimodal && s.multimodalGloballyDisabled() {
+		enableMultimodal = false
+		warnings = append(warnings, "多模态处理已被全局关闭，文档将按纯文本解析")
+	}
+	var vlmConfig *proto.VLMConfig
+	if enableMultimodal {
+		vlmConfig, err = s.getVLMProtoConfig(ctx, kb)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("VLM 配置解析失败: %v", err))
+		} else if vlmConfig == nil {
+			warnings = append(warnings, "已开启多模态但未配置 VLM 模型，图片不会生成描述")
+		}
+		if kb.StorageConfig.Provider == "" {
+			warnings = append(warnings, "知识库未配置存储，图片将上传到 docreader 默认存储")
+		}
+	} else if IsImageType(fileType) {
+		warnings = append(warnings, ErrImageNotParse.Error())
+	}
+
+	readConfig := buildDocReaderReadConfig(kb, enableMultimodal, vlmConfig)
+	result := &types.EffectiveReadConfig{
+		KnowledgeBaseID:     kb.ID,
+		FileType:            fileType,
+		ChunkSize:           int(readConfig.ChunkSize),
+		ChunkOverlap:        int(readConfig.ChunkOverlap),
+		Separators:          readConfig.Separators,
+		ParseTimeoutSeconds: int(readConfig.ParseTimeoutSeconds),
+		AllowPartialResult:  readConfig.AllowPartialResult,
+		EnableMultimodal:    readConfig.EnableMultimodal,
+		ImageCaptionEnabled: readConfig.EnableMultimodal && readConfig.VlmConfig != nil,
+		StorageProvider:     kb.StorageConfig.Provider,
+		Warnings:            warnings,
+	}
+	if readConfig.VlmConfig != nil {
+		result.VLMConfig = &types.EffectiveVLMConfig{
+			ModelName:     readConfig.VlmConfig.ModelName,
+			BaseURL:       readConfig.VlmConfig.BaseUrl,
+			InterfaceType: readConfig.VlmConfig.InterfaceType,
+		}
+	}
+	return result, nil
 }
 
 func IsImageType(fileType string) bool {
@@ -6810,17 +15057,77 @@ func IsImageType(fileType string) bool {
 	}
 }
 
-// downloadFileFromURL downloads a remote file to a temp file and returns its binary content.
+// downloadFileFromURL downloads a remote file while holding a slot of the global download
+// concurrency limit, and records its duration, size and outcome on a trace span and in the log.
+// payloadFileName and payloadFileType are in/out pointers, see fetchFileFromURL.
+// maxSize is the max file size in bytes, see types.ImportConfig.GetMaxURLFileSize.
+// It does NOT perform SSRF validation — callers are responsible for that.
+func (s *knowledgeService) downloadFileFromURL(ctx context.Context,
+	fileURL string, maxSize int64, payloadFileName, payloadFileType *string,
+) ([]byte, error) {
+	ctx, span := tracing.ContextWithSpan(ctx, "knowledgeService.downloadFileFromURL")
+	defer span.End()
+	host := ""
+	if u, err := url.Parse(fileURL); err == nil {
+		host = u.Hostname()
+	}
+	span.SetAttributes(attribute.String("host", host), attribute.String("file_type", *payloadFileType))
+
+	waitStart := time.Now()
+	if s.downloadSem != nil {
+		select {
+		case s.downloadSem <- struct{}{}:
+		case <-ctx.Done():
+			span.RecordError(ctx.Err())
+			return nil, ctx.Err()
+		}
+		defer func() { <-s.downloadSem }()
+	}
+	waitDuration := time.Since(waitStart)
+
+	timeout := s.fileURLDownloadTimeout(*payloadFileType)
+	downloadCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	content, err := fetchFileFromURL(downloadCtx, fileURL, maxSize, payloadFileName, payloadFileType)
+	duration := time.Since(start)
+	span.SetAttributes(
+		attribute.Int64("wait_ms", waitDuration.Milliseconds()),
+		attribute.Int64("duration_ms", duration.Milliseconds()),
+		attribute.Int("size_bytes", len(content)),
+		attribute.Bool("success", err == nil),
+	)
+	fields := map[string]interface{}{
+		"host":        host,
+		"file_type":   *payloadFileType,
+		"wait_ms":     waitDuration.Milliseconds(),
+		"duration_ms": duration.Milliseconds(),
+		"timeout":     timeout.String(),
+	}
+	if err != nil {
+		span.RecordError(err)
+		fields["error"] = err.Error()
+		logger.GetLogger(ctx).WithFields(fields).Warn("file_url download failed")
+		return nil, err
+	}
+	fields["size_bytes"] = len(content)
+	logger.GetLogger(ctx).WithFields(fields).Info("file_url download finished")
+	return content, nil
+}
+
+// fetchFileFromURL downloads a remote file to a temp file and returns its binary content.
 // payloadFileName and payloadFileType are in/out pointers: if they point to an empty string,
 // the function resolves the value from Content-Disposition / URL path and writes it back.
-// It does NOT perform SSRF validation — callers are responsible for that.
-func downloadFileFromURL(ctx context.Context, fileURL string, payloadFileName, payloadFileType *string) ([]byte, error) {
-	httpClient := &http.Client{Timeout: 60 * time.Second}
+// Files larger than maxSize bytes are rejected.
+func fetchFileFromURL(ctx context.Context,
+	fileURL string, maxSize int64, payloadFileName, payloadFileType *string,
+) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for file URL: %w", err)
 	}
-	resp, err := httpClient.Do(req)
+	resp, err := fileURLHTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file from URL: %w", err)
 	}
@@ -6831,8 +15138,8 @@ func downloadFileFromURL(ctx context.Context, fileURL string, payloadFileName, p
 	}
 
 	// Reject oversized files early via Content-Length
-	if contentLength := resp.ContentLength; contentLength > maxFileURLSize {
-		return nil, fmt.Errorf("file size %d bytes exceeds limit of %d bytes (10MB)", contentLength, maxFileURLSize)
+	if contentLength := resp.ContentLength; contentLength > maxSize {
+		return nil, fmt.Errorf("file size %d bytes exceeds limit of %d bytes", contentLength, maxSize)
 	}
 
 	// Resolve fileName: payload > Content-Disposition > URL path
@@ -6848,7 +15155,7 @@ func downloadFileFromURL(ctx context.Context, fileURL string, payloadFileName, p
 		*payloadFileType = getFileType(*payloadFileName)
 	}
 
-	// Stream response body into a temp file, capped at maxFileURLSize
+	// Stream response body into a temp file, capped at maxSize
 	tmpFile, err := os.CreateTemp("", "weknora-fileurl-*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp file: %w", err)
@@ -6856,14 +15163,14 @@ func downloadFileFromURL(ctx context.Context, fileURL string, payloadFileName, p
 	tmpPath := tmpFile.Name()
 	defer os.Remove(tmpPath)
 
-	limiter := &io.LimitedReader{R: resp.Body, N: maxFileURLSize + 1}
+	limiter := &io.LimitedReader{R: resp.Body, N: maxSize + 1}
 	written, err := io.Copy(tmpFile, limiter)
 	tmpFile.Close()
 	if err != nil {
 		return nil, fmt.Errorf("failed to write temp file: %w", err)
 	}
-	if written > maxFileURLSize {
-		return nil, fmt.Errorf("file size exceeds limit of 10MB")
+	if written > maxSize {
+		return nil, fmt.Errorf("file size exceeds limit of %d bytes", maxSize)
 	}
 
 	contentBytes, err := os.ReadFile(tmpPath)
@@ -6875,7 +15182,7 @@ func downloadFileFromURL(ctx context.Context, fileURL string, payloadFileName, p
 }
 
 // ProcessDocument handles Asynq document processing tasks
-func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) error {
+func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) (err error) {
 	var payload types.DocumentProcessPayload
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
 		logger.Errorf(ctx, "failed to unmarshal document process task payload: %v", err)
@@ -6891,6 +15198,16 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	maxRetry, _ := asynq.GetMaxRetry(ctx)
 	isLastRetry := retryCount >= maxRetry
 
+	// 重新解析交接过来的知识锁在处理期间续期，任务结束（成功或不再重试）后释放
+	if payload.LockToken != "" {
+		s.extendKnowledgeLock(ctx, payload.KnowledgeID, payload.LockToken)
+		defer func() {
+			if err == nil || isLastRetry {
+				s.releaseKnowledgeLock(ctx, payload.KnowledgeID, payload.LockToken)
+			}
+		}()
+	}
+
 	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
 	if err != nil {
 		logger.Errorf(ctx, "failed to get tenant: %v", err)
@@ -6898,6 +15215,15 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	}
 	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
 
+	// 大字段转存在对象存储中时，任务结束（成功或不再重试）后清理，重试时仍需使用该文件
+	if payload.OffloadURL != "" {
+		defer func() {
+			if err == nil || isLastRetry {
+				s.deleteOffloadedTaskData(ctx, payload.OffloadURL)
+			}
+		}()
+	}
+
 	logger.Infof(ctx, "Processing document task: knowledge_id=%s, file_path=%s, retry=%d/%d",
 		payload.KnowledgeID, payload.FilePath, retryCount, maxRetry)
 
@@ -6918,6 +15244,12 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 		return nil
 	}
 
+	// 用户已取消解析（入队后、执行前取消），保持 cancelled 状态直接退出
+	if knowledge.ParseStatus == types.ParseStatusCancelled {
+		logger.Infof(ctx, "Knowledge processing was cancelled, skipping: %s", payload.KnowledgeID)
+		return nil
+	}
+
 	// 检查任务状态 - 幂等性处理
 	if knowledge.ParseStatus == types.ParseStatusCompleted {
 		logger.Infof(ctx, "Document already completed, skipping: %s", payload.KnowledgeID)
@@ -6947,20 +15279,52 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
 	if err != nil {
 		logger.Errorf(ctx, "failed to get knowledge base: %v", err)
-		knowledge.ParseStatus = "failed"
-		knowledge.ErrorMessage = fmt.Sprintf("failed to get knowledge base: %v", err)
-		knowledge.UpdatedAt = time.Now()
-		s.repo.UpdateKnowledge(ctx, knowledge)
+		s.failKnowledgeProcessing(ctx, knowledge, fmt.Sprintf("failed to get knowledge base: %v", err))
 		return nil
 	}
 
+	// 上传时指定了分块预设的文档，用预设覆盖知识库的分块参数
+	if knowledge.ChunkingPreset != "" {
+		if preset := tenantInfo.ChunkingPresets.Find(knowledge.ChunkingPreset); preset != nil {
+			preset.Override(&kb.ChunkingConfig)
+		} else {
+			logger.Warnf(ctx, "Chunking preset %s of knowledge %s no longer exists, using knowledge base chunking config",
+				knowledge.ChunkingPreset, knowledge.ID)
+		}
+	}
+	// 重新解析时记住的文档解析参数优先于分块预设，仅本次生效的解析参数又优先于记住的参数
+	profile := knowledge.ParseProfile()
+	if payload.ParseProfile != nil {
+		profile = payload.ParseProfile
+	}
+	profile.ApplyChunking(&kb.ChunkingConfig)
+
 	knowledge.ParseStatus = "processing"
+	knowledge.ParseWarning = ""
+	knowledge.ProcessingReport = nil
 	knowledge.UpdatedAt = time.Now()
 	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
 		logger.Errorf(ctx, "failed to update knowledge status to processing: %v", err)
 		return nil
 	}
 
+	// 下载转存到对象存储的段落与保留问题
+	if payload.OffloadURL != "" {
+		var offload types.DocumentProcessOffload
+		if err := s.loadOffloadedTaskData(ctx, payload.OffloadURL, &offload); err != nil {
+			logger.Errorf(ctx, "Failed to load offloaded document process data: %v", err)
+			if isLastRetry {
+				s.failKnowledgeProcessing(ctx, knowledge, err.Error())
+			}
+			return err
+		}
+		payload.Passages = offload.Passages
+		payload.PreservedQuestions = offload.PreservedQuestions
+	}
+
+	// 任务入队后才开启全局多模态开关时，按纯文本处理
+	payload.EnableMultimodel = s.applyMultimodalKillSwitch(ctx, payload.EnableMultimodel)
+
 	// 构建VLM配置（如果需要）
 	var vlmConfig *proto.VLMConfig
 	if payload.EnableMultimodel {
@@ -6979,23 +15343,22 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	if payload.FilePath != "" && !payload.EnableMultimodel && IsImageType(payload.FileType) {
 		logger.GetLogger(ctx).WithField("knowledge_id", knowledge.ID).
 			WithField("error", ErrImageNotParse).Errorf("processDocument image without enable multimodel")
-		knowledge.ParseStatus = "failed"
-		knowledge.ErrorMessage = ErrImageNotParse.Error()
-		knowledge.UpdatedAt = time.Now()
-		s.repo.UpdateKnowledge(ctx, knowledge)
+		s.failKnowledgeProcessing(ctx, knowledge, ErrImageNotParse.Error())
 		return nil
 	}
 
+	// 解析超时时间随重试次数逐次翻倍，避免大文档在相同超时下反复失败
+	readConfig := buildDocReaderReadConfig(kb, payload.EnableMultimodel, vlmConfig)
+	escalateParseTimeout(readConfig, retryCount)
+
 	// 处理不同类型的导入：文件、URL、文本段落
 	var chunks []*proto.Chunk
+	var partial bool
 	if payload.FileURL != "" {
 		// file_url 导入：再次 SSRF 校验（防 DNS 重绑定），下载到临时文件，传二进制给 docreader
 		if safe, reason := secutils.IsSSRFSafeURL(payload.FileURL); !safe {
 			logger.Errorf(ctx, "File URL rejected for SSRF protection in ProcessDocument: %s, reason: %s", payload.FileURL, reason)
-			knowledge.ParseStatus = "failed"
-			knowledge.ErrorMessage = "File URL is not allowed for security reasons"
-			knowledge.UpdatedAt = time.Now()
-			s.repo.UpdateKnowledge(ctx, knowledge)
+			s.failKnowledgeProcessing(ctx, knowledge, "File URL is not allowed for security reasons")
 			return nil
 		}
 
@@ -7003,14 +15366,12 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 		// payloadFileName/payloadFileType are in/out: resolved values are written back if empty.
 		resolvedFileName := payload.FileName
 		resolvedFileType := payload.FileType
-		contentBytes, err := downloadFileFromURL(ctx, payload.FileURL, &resolvedFileName, &resolvedFileType)
+		contentBytes, err := s.downloadFileFromURL(ctx, payload.FileURL, kb.ImportConfig.GetMaxURLFileSize(),
+			&resolvedFileName, &resolvedFileType)
 		if err != nil {
 			logger.Errorf(ctx, "Failed to download file from URL: %s, error: %v", payload.FileURL, err)
 			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
+				s.failKnowledgeProcessing(ctx, knowledge, err.Error())
 			}
 			return fmt.Errorf("failed to download file from URL: %w", err)
 		}
@@ -7018,10 +15379,13 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 		// Validate resolved file type against whitelist
 		if resolvedFileType != "" && !allowedFileURLExtensions[strings.ToLower(resolvedFileType)] {
 			logger.Errorf(ctx, "Unsupported file type resolved from file URL: %s", resolvedFileType)
-			knowledge.ParseStatus = "failed"
-			knowledge.ErrorMessage = fmt.Sprintf("unsupported file type: %s", resolvedFileType)
-			knowledge.UpdatedAt = time.Now()
-			s.repo.UpdateKnowledge(ctx, knowledge)
+			s.failKnowledgeProcessing(ctx, knowledge, fmt.Sprintf("unsupported file type: %s", resolvedFileType))
+			return nil
+		}
+		if !payload.EnableMultimodel && IsImageType(strings.ToLower(resolvedFileType)) {
+			logger.GetLogger(ctx).WithField("knowledge_id", knowledge.ID).
+				WithField("error", ErrImageNotParse).Errorf("processDocument image file url without enable multimodel")
+			s.failKnowledgeProcessing(ctx, knowledge, ErrImageNotParse.Error())
 			return nil
 		}
 
@@ -7038,98 +15402,57 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 			FileContent: contentBytes,
 			FileName:    resolvedFileName,
 			FileType:    resolvedFileType,
-			ReadConfig: &proto.ReadConfig{
-				ChunkSize:        int32(kb.ChunkingConfig.ChunkSize),
-				ChunkOverlap:     int32(kb.ChunkingConfig.ChunkOverlap),
-				Separators:       kb.ChunkingConfig.Separators,
-				EnableMultimodal: payload.EnableMultimodel,
-				StorageConfig: &proto.StorageConfig{
-					Provider:        proto.StorageProvider(proto.StorageProvider_value[strings.ToUpper(kb.StorageConfig.Provider)]),
-					Region:          kb.StorageConfig.Region,
-					BucketName:      kb.StorageConfig.BucketName,
-					AccessKeyId:     kb.StorageConfig.SecretID,
-					SecretAccessKey: kb.StorageConfig.SecretKey,
-					AppId:           kb.StorageConfig.AppID,
-					PathPrefix:      kb.StorageConfig.PathPrefix,
-				},
-				VlmConfig: vlmConfig,
-			},
-			RequestId: payload.RequestId,
+			ReadConfig:  readConfig,
+			RequestId:   payload.RequestId,
 		})
 		if err != nil {
 			logger.Errorf(ctx, "Failed to read file from docreader (file_url): %v", err)
 			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
+				s.failKnowledgeProcessing(ctx, knowledge, err.Error())
 				return nil
 			}
 			return fmt.Errorf("failed to read file from docreader: %w", err)
 		}
 		if fileResp.Error != "" {
 			logger.Errorf(ctx, "DocReader returned error (file): %s", fileResp.Error)
-			knowledge.ParseStatus = "failed"
-			knowledge.ErrorMessage = fileResp.Error
-			knowledge.UpdatedAt = time.Now()
-			s.repo.UpdateKnowledge(ctx, knowledge)
+			s.failKnowledgeProcessing(ctx, knowledge, fileResp.Error)
+			return nil
+		}
+		if !s.checkPartialReadResponse(ctx, kb, knowledge, fileResp) {
 			return nil
 		}
-		chunks = fileResp.Chunks
+		chunks, partial = fileResp.Chunks, fileResp.Partial
 	} else if payload.URL != "" {
 		// URL导入 - 再次进行 SSRF 验证（防止 DNS 重绑定攻击）
 		if safe, reason := secutils.IsSSRFSafeURL(payload.URL); !safe {
 			logger.Errorf(ctx, "URL rejected for SSRF protection in ProcessDocument: %s, reason: %s", payload.URL, reason)
-			knowledge.ParseStatus = "failed"
-			knowledge.ErrorMessage = "URL is not allowed for security reasons"
-			knowledge.UpdatedAt = time.Now()
-			s.repo.UpdateKnowledge(ctx, knowledge)
+			s.failKnowledgeProcessing(ctx, knowledge, "URL is not allowed for security reasons")
 			return nil
 		}
 
 		urlResp, err := s.docReaderClient.ReadFromURL(ctx, &proto.ReadFromURLRequest{
-			Url:   payload.URL,
-			Title: knowledge.Title,
-			ReadConfig: &proto.ReadConfig{
-				ChunkSize:        int32(kb.ChunkingConfig.ChunkSize),
-				ChunkOverlap:     int32(kb.ChunkingConfig.ChunkOverlap),
-				Separators:       kb.ChunkingConfig.Separators,
-				EnableMultimodal: payload.EnableMultimodel,
-				StorageConfig: &proto.StorageConfig{
-					Provider: proto.StorageProvider(
-						proto.StorageProvider_value[strings.ToUpper(kb.StorageConfig.Provider)],
-					),
-					Region:          kb.StorageConfig.Region,
-					BucketName:      kb.StorageConfig.BucketName,
-					AccessKeyId:     kb.StorageConfig.SecretID,
-					SecretAccessKey: kb.StorageConfig.SecretKey,
-					AppId:           kb.StorageConfig.AppID,
-					PathPrefix:      kb.StorageConfig.PathPrefix,
-				},
-				VlmConfig: vlmConfig,
-			},
-			RequestId: payload.RequestId,
+			Url:        payload.URL,
+			Title:      knowledge.Title,
+			ReadConfig: readConfig,
+			RequestId:  payload.RequestId,
 		})
 		if err != nil {
 			// 如果是最后一次重试，更新状态为失败
 			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
+				s.failKnowledgeProcessing(ctx, knowledge, err.Error())
 				return nil
 			}
 			return fmt.Errorf("failed to read from URL: %w", err)
 		}
 		if urlResp.Error != "" {
 			logger.Errorf(ctx, "DocReader returned error (URL): %s", urlResp.Error)
-			knowledge.ParseStatus = "failed"
-			knowledge.ErrorMessage = urlResp.Error
-			knowledge.UpdatedAt = time.Now()
-			s.repo.UpdateKnowledge(ctx, knowledge)
+			s.failKnowledgeProcessing(ctx, knowledge, urlResp.Error)
+			return nil
+		}
+		if !s.checkPartialReadResponse(ctx, kb, knowledge, urlResp) {
 			return nil
 		}
-		chunks = urlResp.Chunks
+		chunks, partial = urlResp.Chunks, urlResp.Partial
 	} else if len(payload.Passages) > 0 {
 		// 文本段落导入
 		chunks := make([]*proto.Chunk, 0, len(payload.Passages))
@@ -7159,10 +15482,7 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 				WithField("error", err).Errorf("processDocument get file failed")
 			// 如果是最后一次重试，更新状态为失败
 			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
+				s.failKnowledgeProcessing(ctx, knowledge, err.Error())
 			}
 			return fmt.Errorf("failed to get file: %w", err)
 		}
@@ -7173,10 +15493,7 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 		if err != nil {
 			// 如果是最后一次重试，更新状态为失败
 			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
+				s.failKnowledgeProcessing(ctx, knowledge, err.Error())
 			}
 			return fmt.Errorf("failed to read file: %w", err)
 		}
@@ -7186,52 +15503,37 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 			FileContent: contentBytes,
 			FileName:    payload.FileName,
 			FileType:    payload.FileType,
-			ReadConfig: &proto.ReadConfig{
-				ChunkSize:        int32(kb.ChunkingConfig.ChunkSize),
-				ChunkOverlap:     int32(kb.ChunkingConfig.ChunkOverlap),
-				Separators:       kb.ChunkingConfig.Separators,
-				EnableMultimodal: payload.EnableMultimodel,
-				StorageConfig: &proto.StorageConfig{
-					Provider:        proto.StorageProvider(proto.StorageProvider_value[strings.ToUpper(kb.StorageConfig.Provider)]),
-					Region:          kb.StorageConfig.Region,
-					BucketName:      kb.StorageConfig.BucketName,
-					AccessKeyId:     kb.StorageConfig.SecretID,
-					SecretAccessKey: kb.StorageConfig.SecretKey,
-					AppId:           kb.StorageConfig.AppID,
-					PathPrefix:      kb.StorageConfig.PathPrefix,
-				},
-				VlmConfig: vlmConfig,
-			},
-			RequestId: payload.RequestId,
+			ReadConfig:  readConfig,
+			RequestId:   payload.RequestId,
 		})
 		if err != nil {
 			logger.GetLogger(ctx).WithField("knowledge_id", knowledge.ID).
 				WithField("error", err).Errorf("processDocument read file failed")
 			// 如果是最后一次重试，更新状态为失败
 			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
+				s.failKnowledgeProcessing(ctx, knowledge, err.Error())
 				return nil
 			}
 			return fmt.Errorf("failed to read file from docreader: %w", err)
 		}
 		if fileResp.Error != "" {
 			logger.Errorf(ctx, "DocReader returned error (file): %s", fileResp.Error)
-			knowledge.ParseStatus = "failed"
-			knowledge.ErrorMessage = fileResp.Error
-			knowledge.UpdatedAt = time.Now()
-			s.repo.UpdateKnowledge(ctx, knowledge)
+			s.failKnowledgeProcessing(ctx, knowledge, fileResp.Error)
+			return nil
+		}
+		if !s.checkPartialReadResponse(ctx, kb, knowledge, fileResp) {
 			return nil
 		}
-		chunks = fileResp.Chunks
+		chunks, partial = fileResp.Chunks, fileResp.Partial
 	}
 
 	// 处理chunks（这会更新状态为completed）
 	s.processChunks(ctx, kb, knowledge, chunks, ProcessChunksOptions{
 		EnableQuestionGeneration: payload.EnableQuestionGeneration,
 		QuestionCount:            payload.QuestionCount,
+		SkipSummary:              payload.SkipSummary,
+		PreservedQuestions:       payload.PreservedQuestions,
+		PartialResult:            partial,
 	})
 
 	return nil
@@ -7254,6 +15556,13 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 	maxRetry, _ := asynq.GetMaxRetry(ctx)
 	isLastRetry := retryCount >= maxRetry
 
+	// 执行期间为运行锁续期，任务结束（完成/失败）时锁由 updateFAQImportProgressStatus 清除
+	stopLockHeartbeat := s.startFAQImportLockHeartbeat(ctx, payload.KBID, runningFAQImportInfo{
+		TaskID:     payload.TaskID,
+		EnqueuedAt: payload.EnqueuedAt,
+	})
+	defer stopLockHeartbeat()
+
 	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
 	if err != nil {
 		logger.Errorf(ctx, "failed to get tenant: %v", err)
@@ -7264,23 +15573,10 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 	// 如果 entries 存储在对象存储中，先下载
 	if payload.EntriesURL != "" && len(payload.Entries) == 0 {
 		logger.Infof(ctx, "Downloading FAQ entries from object storage: %s", payload.EntriesURL)
-		reader, err := s.fileSvc.GetFile(ctx, payload.EntriesURL)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to download FAQ entries from object storage: %v", err)
-			return fmt.Errorf("failed to download entries: %w", err)
-		}
-		defer reader.Close()
-
-		entriesData, err := io.ReadAll(reader)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to read FAQ entries data: %v", err)
-			return fmt.Errorf("failed to read entries data: %w", err)
-		}
-
 		var entries []types.FAQEntryPayload
-		if err := json.Unmarshal(entriesData, &entries); err != nil {
-			logger.Errorf(ctx, "Failed to unmarshal FAQ entries: %v", err)
-			return fmt.Errorf("failed to unmarshal entries: %w", err)
+		if err := s.loadOffloadedTaskData(ctx, payload.EntriesURL, &entries); err != nil {
+			logger.Errorf(ctx, "Failed to load FAQ entries from object storage: %v", err)
+			return err
 		}
 
 		payload.Entries = entries
@@ -7390,7 +15686,8 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 	// 检查任务状态 - 幂等性处理（复用之前获取的 existingProgress）
 	var processedCount int
 	if existingProgress != nil {
-		if existingProgress.Status == types.FAQImportStatusCompleted {
+		if existingProgress.Status == types.FAQImportStatusCompleted ||
+			existingProgress.Status == types.FAQImportStatusPartial {
 			logger.Infof(ctx, "FAQ import already completed, skipping: %s", payload.TaskID)
 			return nil // 幂等：已完成的任务直接返回
 		}
@@ -7457,12 +15754,20 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 
 	// 构建FAQBatchUpsertPayload（使用验证通过的有效条目）
 	faqPayload := &types.FAQBatchUpsertPayload{
-		Entries: entriesToImport,
-		Mode:    importMode,
+		Entries:           entriesToImport,
+		Mode:              importMode,
+		AllowEmptyAnswers: payload.AllowEmptyAnswers,
 	}
 
 	// 执行FAQ导入（传入已处理的偏移量，用于进度计算）
-	if err := s.executeFAQImport(ctx, payload.TaskID, payload.KBID, faqPayload, payload.TenantID, progress.FailedCount+processedCount, progress); err != nil {
+	if err := s.executeFAQImport(ctx, payload.TaskID, payload.KBID, faqPayload, payload.TenantID,
+		progress.FailedCount+processedCount, progress, validEntryIndices[len(validEntries)-len(entriesToImport):]); err != nil {
+		if errors.Is(err, ErrFAQImportFailureBudgetExceeded) {
+			// 失败条目超出预算：重试也无法成功，直接终止并报告部分导入，保留失败条目供排查
+			logger.Warnf(ctx, "FAQ import task aborted: %s, error: %v", payload.TaskID, err)
+			s.abortFAQImport(ctx, &payload, progress, originalTotalEntries, err)
+			return nil
+		}
 		logger.Errorf(ctx, "FAQ import task failed: %s, error: %v", payload.TaskID, err)
 		// 如果是最后一次重试，更新状态为失败
 		if isLastRetry {
@@ -7482,30 +15787,68 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 	return s.finalizeFAQValidation(ctx, &payload, progress, originalTotalEntries)
 }
 
+// abortFAQImport 因失败条目超出预算提前终止导入：导出失败条目，标记任务为部分导入且不再重试，
+// 已成功导入的条目保留
+func (s *knowledgeService) abortFAQImport(ctx context.Context, payload *types.FAQImportPayload,
+	progress *types.FAQImportProgress, originalTotalEntries int, cause error,
+) {
+	processed := progress.Processed
+	if current, err := s.GetFAQImportProgress(ctx, payload.TaskID); err == nil && current != nil {
+		processed = current.Processed
+	}
+	percent := 0
+	if originalTotalEntries > 0 {
+		percent = processed * 100 / originalTotalEntries
+	}
+
+	s.exportFAQImportFailedEntries(ctx, payload.TenantID, progress)
+	progress.UpdatedAt = time.Now().Unix()
+	if err := s.saveFAQImportProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save aborted FAQ import progress: %v", err)
+	}
+	if err := s.updateFAQImportProgressStatus(ctx, payload.TaskID, types.FAQImportStatusPartial,
+		percent, originalTotalEntries, processed, "导入失败条目过多，已提前终止，此前导入的条目已保留", cause.Error()); err != nil {
+		logger.Errorf(ctx, "Failed to update task status to partial: %v", err)
+	}
+
+	s.deleteOffloadedTaskData(ctx, payload.EntriesURL)
+}
+
+// exportFAQImportFailedEntries 将失败条目导出为 CSV 并清空进度中的内联数据，返回是否已导出。
+// 失败条目另存一份到 Redis（与进度相同的过期时间），供按失败条目分页查询进度时使用
+func (s *knowledgeService) exportFAQImportFailedEntries(ctx context.Context,
+	tenantID uint64, progress *types.FAQImportProgress,
+) bool {
+	if len(progress.FailedEntries) == 0 {
+		return false
+	}
+	csvURL, err := s.generateFailedEntriesCSV(ctx, tenantID, progress.TaskID, progress.FailedEntries)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to generate failed entries CSV: %v", err)
+		return false
+	}
+	if data, err := json.Marshal(progress.FailedEntries); err != nil {
+		logger.Warnf(ctx, "Failed to marshal FAQ import failed entries: %v", err)
+	} else if err := s.redisClient.Set(ctx, getFAQImportFailedEntriesKey(progress.TaskID), data,
+		faqImportProgressTTL).Err(); err != nil {
+		logger.Warnf(ctx, "Failed to save FAQ import failed entries: %v", err)
+	}
+	progress.FailedEntriesURL = csvURL
+	progress.FailedEntries = nil // 清空内联数据，使用 URL
+	return true
+}
+
 // finalizeFAQValidation 完成 FAQ 验证/导入任务，生成失败条目 CSV（如果有）
 func (s *knowledgeService) finalizeFAQValidation(ctx context.Context, payload *types.FAQImportPayload,
 	progress *types.FAQImportProgress, originalTotalEntries int,
 ) error {
 	// 清理对象存储中的 entries 文件（如果有）
-	if payload.EntriesURL != "" {
-		if err := s.fileSvc.DeleteFile(ctx, payload.EntriesURL); err != nil {
-			logger.Warnf(ctx, "Failed to delete FAQ entries file from object storage: %v", err)
-		} else {
-			logger.Infof(ctx, "Deleted FAQ entries file from object storage: %s", payload.EntriesURL)
-		}
-	}
+	s.deleteOffloadedTaskData(ctx, payload.EntriesURL)
 	progress.UpdatedAt = time.Now().Unix()
 
 	// 如果有失败条目，生成 CSV 文件
-	if len(progress.FailedEntries) > 0 {
-		csvURL, err := s.generateFailedEntriesCSV(ctx, payload.TenantID, payload.TaskID, progress.FailedEntries)
-		if err != nil {
-			logger.Warnf(ctx, "Failed to generate failed entries CSV: %v", err)
-		} else {
-			progress.FailedEntriesURL = csvURL
-			progress.FailedEntries = nil // 清空内联数据，使用 URL
-			progress.Message += " (失败记录已导出为CSV)"
-		}
+	if s.exportFAQImportFailedEntries(ctx, payload.TenantID, progress) {
+		progress.Message += " (失败记录已导出为CSV)"
 	}
 
 	// 如果不是 dry run 模式，保存导入结果统计到数据库
@@ -7558,6 +15901,15 @@ const (
 	faqImportProgressKeyPrefix = "faq_import_progress:"
 	faqImportRunningKeyPrefix  = "faq_import_running:"
 	faqImportProgressTTL       = 3 * time.Hour
+	faqImportQueue             = "default"
+	// faqImportFailedKeyPrefix 导出 CSV 后从进度中移出的失败条目
+	faqImportFailedKeyPrefix = "faq_import_failed:"
+	// faqImportLockHeartbeatTTL 导入任务执行期间运行锁的 TTL，由心跳持续续期，worker 崩溃后锁在该时长内自动失效
+	faqImportLockHeartbeatTTL = 2 * time.Minute
+	// faqImportLockHeartbeatInterval 运行锁心跳续期间隔
+	faqImportLockHeartbeatInterval = 30 * time.Second
+	// faqImportLockGracePeriod 运行锁创建后的宽限期，期间任务可能尚未入队，不做残留锁检测
+	faqImportLockGracePeriod = time.Minute
 )
 
 // getFAQImportProgressKey returns the Redis key for storing FAQ import progress
@@ -7565,12 +15917,19 @@ func getFAQImportProgressKey(taskID string) string {
 	return faqImportProgressKeyPrefix + taskID
 }
 
+// getFAQImportFailedEntriesKey returns the Redis key for storing the failed entries of an FAQ import
+// once they have been moved out of the progress
+func getFAQImportFailedEntriesKey(taskID string) string {
+	return faqImportFailedKeyPrefix + taskID
+}
+
 // getFAQImportRunningKey returns the Redis key for storing running task ID by KB ID
 func getFAQImportRunningKey(kbID string) string {
 	return faqImportRunningKeyPrefix + kbID
 }
 
-// saveFAQImportProgress saves the FAQ import progress to Redis
+// saveFAQImportProgress saves the FAQ import progress to Redis.
+// Once the task has ended the progress is also archived to the database when configured.
 func (s *knowledgeService) saveFAQImportProgress(ctx context.Context, progress *types.FAQImportProgress) error {
 	key := getFAQImportProgressKey(progress.TaskID)
 	progress.UpdatedAt = time.Now().Unix()
@@ -7578,7 +15937,91 @@ func (s *knowledgeService) saveFAQImportProgress(ctx context.Context, progress *
 	if err != nil {
 		return fmt.Errorf("failed to marshal FAQ import progress: %w", err)
 	}
-	return s.redisClient.Set(ctx, key, data, faqImportProgressTTL).Err()
+	if err := s.redisClient.Set(ctx, key, data, faqImportProgressTTL).Err(); err != nil {
+		return err
+	}
+	if progress.Status.IsFinished() {
+		s.archiveFAQImportProgress(ctx, progress)
+	}
+	return nil
+}
+
+// faqImportArchiveRetention returns how long archived FAQ import progress is kept, 0 when archiving is disabled
+func (s *knowledgeService) faqImportArchiveRetention() time.Duration {
+	if s.config == nil || s.config.KnowledgeBase == nil || s.config.KnowledgeBase.FAQImportArchive == nil ||
+		!s.config.KnowledgeBase.FAQImportArchive.Enabled {
+		return 0
+	}
+	if retention := s.config.KnowledgeBase.FAQImportArchive.Retention; retention > 0 {
+		return retention
+	}
+	return defaultFAQImportArchiveRetention
+}
+
+// archiveFAQImportProgress persists the final progress of an FAQ import, including the failed entries
+// moved out of the progress when they were exported to CSV. Failures are only logged, Redis stays the
+// source of the live progress.
+func (s *knowledgeService) archiveFAQImportProgress(ctx context.Context, progress *types.FAQImportProgress) {
+	retention := s.faqImportArchiveRetention()
+	if retention <= 0 {
+		return
+	}
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	if tenantID == 0 {
+		logger.Warnf(ctx, "Skip archiving FAQ import %s without tenant in context", progress.TaskID)
+		return
+	}
+
+	archived := *progress
+	if len(archived.FailedEntries) == 0 && archived.FailedEntriesURL != "" {
+		data, err := s.redisClient.Get(ctx, getFAQImportFailedEntriesKey(progress.TaskID)).Bytes()
+		if err == nil {
+			if err := json.Unmarshal(data, &archived.FailedEntries); err != nil {
+				logger.Warnf(ctx, "Failed to unmarshal FAQ import failed entries for archive: %v", err)
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			logger.Warnf(ctx, "Failed to get FAQ import failed entries for archive: %v", err)
+		}
+	}
+	data, err := json.Marshal(&archived)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to marshal FAQ import progress for archive: %v", err)
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(retention)
+	if err := s.repo.SaveFAQImportArchive(ctx, &types.FAQImportArchive{
+		TaskID:          progress.TaskID,
+		TenantID:        tenantID,
+		KnowledgeBaseID: progress.KBID,
+		KnowledgeID:     progress.KnowledgeID,
+		Status:          progress.Status,
+		Progress:        types.JSON(data),
+		ArchivedAt:      now,
+		ExpiresAt:       &expiresAt,
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to archive FAQ import progress %s: %v", progress.TaskID, err)
+	}
+}
+
+// GetFAQImportProgressArchived retrieves the archived final progress of an FAQ import task, available
+// after the progress in Redis has expired until the archive retention ends. The archive is looked up by
+// task ID only, since imports into a shared knowledge base are archived under the owner tenant;
+// callers must check access to the returned progress's knowledge base.
+func (s *knowledgeService) GetFAQImportProgressArchived(ctx context.Context, taskID string) (*types.FAQImportProgress, error) {
+	archive, err := s.repo.GetFAQImportArchive(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get FAQ import archive: %w", err)
+	}
+	if archive == nil || (archive.ExpiresAt != nil && !archive.ExpiresAt.After(time.Now())) {
+		return nil, werrors.NewNotFoundError("FAQ导入归档不存在或已过期")
+	}
+	var progress types.FAQImportProgress
+	if err := json.Unmarshal(archive.Progress, &progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal archived FAQ import progress: %w", err)
+	}
+	return &progress, nil
 }
 
 // GetFAQImportProgress retrieves the progress of an FAQ import task
@@ -7615,6 +16058,72 @@ func (s *knowledgeService) GetFAQImportProgress(ctx context.Context, taskID stri
 	return &progress, nil
 }
 
+// GetFAQImportProgressView retrieves the progress of an FAQ import task shaped by filter, so that large
+// result sets can be paged instead of returned inline
+func (s *knowledgeService) GetFAQImportProgressView(ctx context.Context, taskID string,
+	filter types.FAQImportResultFilter, page *types.Pagination,
+) (*types.FAQImportProgressView, error) {
+	if filter == "" {
+		filter = types.FAQImportResultFilterAll
+	}
+	if !filter.IsValid() {
+		return nil, werrors.NewBadRequestError("filter 必须是 all、summary、failed 或 success")
+	}
+	progress, err := s.GetFAQImportProgress(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if filter == types.FAQImportResultFilterAll {
+		return &types.FAQImportProgressView{FAQImportProgress: progress, Filter: filter}, nil
+	}
+
+	failed := progress.FailedEntries
+	if filter == types.FAQImportResultFilterFailed && len(failed) == 0 && progress.FailedEntriesURL != "" {
+		data, err := s.redisClient.Get(ctx, getFAQImportFailedEntriesKey(taskID)).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to get FAQ import failed entries from Redis: %w", err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &failed); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal FAQ import failed entries: %w", err)
+			}
+		}
+	}
+	return buildFAQImportProgressView(progress, failed, filter, page), nil
+}
+
+// buildFAQImportProgressView strips the entry details from the progress and attaches the requested page
+// of failed or successful entries
+func buildFAQImportProgressView(progress *types.FAQImportProgress, failed []types.FAQFailedEntry,
+	filter types.FAQImportResultFilter, page *types.Pagination,
+) *types.FAQImportProgressView {
+	if page == nil {
+		page = &types.Pagination{}
+	}
+	summary := *progress
+	summary.FailedEntries = nil
+	summary.SuccessEntries = nil
+	summary.SkippedEntries = nil
+	summary.ValidEntryIndices = nil
+	view := &types.FAQImportProgressView{FAQImportProgress: &summary, Filter: filter}
+
+	switch filter {
+	case types.FAQImportResultFilterFailed:
+		view.Entries = types.NewPageResult(int64(len(failed)), page, pageSlice(failed, page))
+	case types.FAQImportResultFilterSuccess:
+		view.Entries = types.NewPageResult(int64(len(progress.SuccessEntries)), page,
+			pageSlice(progress.SuccessEntries, page))
+	}
+	return view
+}
+
+// pageSlice returns the items of the requested page, never nil
+func pageSlice[T any](items []T, page *types.Pagination) []T {
+	start := min(page.Offset(), len(items))
+	end := min(start+page.Limit(), len(items))
+	return append(make([]T, 0, end-start), items[start:end]...)
+}
+
 // UpdateLastFAQImportResultDisplayStatus updates the display status of FAQ import result
 func (s *knowledgeService) UpdateLastFAQImportResultDisplayStatus(ctx context.Context, kbID string, displayStatus string) error {
 	// 验证displayStatus参数
@@ -7729,9 +16238,11 @@ func (s *knowledgeService) ProcessKBClone(ctx context.Context, t *asynq.Task) er
 		return err
 	}
 
+	includeDisabled := payload.ShouldIncludeDisabled()
+
 	// Use different sync strategies based on knowledge base type
 	if srcKB.Type == types.KnowledgeBaseTypeFAQ {
-		return s.cloneFAQKnowledgeBase(ctx, srcKB, dstKB, progress, handleError)
+		return s.cloneFAQKnowledgeBase(ctx, srcKB, dstKB, includeDisabled, progress, handleError)
 	}
 
 	// Document type: use Knowledge-level diff based on file_hash
@@ -7757,39 +16268,40 @@ func (s *knowledgeService) ProcessKBClone(ctx context.Context, t *asynq.Task) er
 
 	logger.Infof(ctx, "Knowledge after update to add: %d, delete: %d", len(addKnowledge), len(delKnowledge))
 
+	// Both phases run concurrently, progressMu guards processedCount and progress
+	var progressMu sync.Mutex
 	processedCount := 0
-	batch := 10
-
-	// Delete knowledge in target that doesn't exist in source
-	g, gctx := errgroup.WithContext(ctx)
-	for ids := range slices.Chunk(delKnowledge, batch) {
-		g.Go(func() error {
-			err := s.DeleteKnowledgeList(gctx, ids)
-			if err != nil {
-				logger.Errorf(gctx, "delete partial knowledge %v: %v", ids, err)
-				return err
-			}
-			return nil
-		})
+	reportProgress := func(done int, message func() string) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		processedCount += done
+		if totalOperations > 0 {
+			progress.Progress = processedCount * 100 / totalOperations
+		}
+		progress.Processed = processedCount
+		progress.Message = message()
+		progress.UpdatedAt = time.Now().Unix()
+		_ = s.saveKBCloneProgress(ctx, progress)
 	}
-	if err := g.Wait(); err != nil {
+
+	// Delete knowledge in target that doesn't exist in source
+	if err := s.deleteCloneStaleKnowledge(ctx, delKnowledge, func(deleted int) {
+		reportProgress(deleted, func() string {
+			return fmt.Sprintf("Deleted %d/%d knowledge", processedCount, len(delKnowledge))
+		})
+	}); err != nil {
 		logger.Errorf(ctx, "delete total knowledge %d: %v", len(delKnowledge), err)
 		handleError(progress, err, "Failed to delete knowledge")
 		return err
 	}
 
-	processedCount += len(delKnowledge)
-	if totalOperations > 0 {
-		progress.Progress = processedCount * 100 / totalOperations
-	}
-	progress.Processed = processedCount
-	progress.Message = fmt.Sprintf("Deleted %d knowledge, cloning %d...", len(delKnowledge), len(addKnowledge))
-	progress.UpdatedAt = time.Now().Unix()
-	_ = s.saveKBCloneProgress(ctx, progress)
+	reportProgress(0, func() string {
+		return fmt.Sprintf("Deleted %d knowledge, cloning %d...", len(delKnowledge), len(addKnowledge))
+	})
 
 	// Clone knowledge from source to target
-	g, gctx = errgroup.WithContext(ctx)
-	g.SetLimit(batch)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.cloneAddConcurrency())
 	for _, knowledge := range addKnowledge {
 		g.Go(func() error {
 			srcKn, err := s.repo.GetKnowledgeByID(gctx, srcKB.TenantID, knowledge)
@@ -7797,21 +16309,16 @@ func (s *knowledgeService) ProcessKBClone(ctx context.Context, t *asynq.Task) er
 				logger.Errorf(gctx, "get knowledge %s: %v", knowledge, err)
 				return err
 			}
-			err = s.cloneKnowledge(gctx, srcKn, dstKB)
+			err = s.cloneKnowledge(gctx, srcKn, dstKB, includeDisabled, payload.EnableStatusPolicy)
 			if err != nil {
 				logger.Errorf(gctx, "clone knowledge %s: %v", knowledge, err)
 				return err
 			}
 
 			// Update progress
-			processedCount++
-			if totalOperations > 0 {
-				progress.Progress = processedCount * 100 / totalOperations
-			}
-			progress.Processed = processedCount
-			progress.Message = fmt.Sprintf("Cloned %d/%d knowledge", processedCount-len(delKnowledge), len(addKnowledge))
-			progress.UpdatedAt = time.Now().Unix()
-			_ = s.saveKBCloneProgress(ctx, progress)
+			reportProgress(1, func() string {
+				return fmt.Sprintf("Cloned %d/%d knowledge", processedCount-len(delKnowledge), len(addKnowledge))
+			})
 
 			return nil
 		})
@@ -7840,6 +16347,7 @@ func (s *knowledgeService) ProcessKBClone(ctx context.Context, t *asynq.Task) er
 func (s *knowledgeService) cloneFAQKnowledgeBase(
 	ctx context.Context,
 	srcKB, dstKB *types.KnowledgeBase,
+	includeDisabled bool,
 	progress *types.KBCloneProgress,
 	handleError func(*types.KBCloneProgress, error, string),
 ) error {
@@ -7957,38 +16465,10 @@ func (s *knowledgeService) cloneFAQKnowledgeBase(
 		}
 
 		// Create new chunks for destination
-		newChunks := make([]*types.Chunk, 0, len(srcChunks))
-		for _, srcChunk := range srcChunks {
-			// Map TagID to target knowledge base
-			targetTagID := ""
-			if srcChunk.TagID != "" {
-				if mappedTagID, ok := tagIDMapping[srcChunk.TagID]; ok {
-					targetTagID = mappedTagID
-				} else {
-					// Try to find or create the tag in target knowledge base
-					targetTagID = s.getOrCreateTagInTarget(ctx, srcKB.TenantID, dstKB.TenantID, dstKB.ID, srcChunk.TagID, tagIDMapping)
-				}
-			}
-
-			newChunk := &types.Chunk{
-				ID:              uuid.New().String(),
-				TenantID:        dstKB.TenantID,
-				KnowledgeID:     dstKnowledge.ID,
-				KnowledgeBaseID: dstKB.ID,
-				TagID:           targetTagID,
-				Content:         srcChunk.Content,
-				ChunkIndex:      srcChunk.ChunkIndex,
-				IsEnabled:       srcChunk.IsEnabled,
-				Flags:           srcChunk.Flags,
-				ChunkType:       types.ChunkTypeFAQ,
-				Metadata:        srcChunk.Metadata,
-				ContentHash:     srcChunk.ContentHash,
-				ImageInfo:       srcChunk.ImageInfo,
-				Status:          int(types.ChunkStatusStored), // Initially stored, will be indexed
-				CreatedAt:       time.Now(),
-				UpdatedAt:       time.Now(),
-			}
-			newChunks = append(newChunks, newChunk)
+		newChunks := s.buildFAQCloneChunks(ctx, srcKB, dstKB, dstKnowledge, srcChunks, includeDisabled, tagIDMapping)
+		if len(newChunks) == 0 {
+			processedCount += len(batchIDs)
+			continue
 		}
 
 		// Save to database
@@ -8038,6 +16518,56 @@ func (s *knowledgeService) cloneFAQKnowledgeBase(
 	return nil
 }
 
+// buildFAQCloneChunks copies source FAQ chunks into the destination knowledge, mapping tags to
+// the target knowledge base. Disabled entries stay disabled in the target, or are skipped when
+// includeDisabled is false.
+func (s *knowledgeService) buildFAQCloneChunks(
+	ctx context.Context,
+	srcKB, dstKB *types.KnowledgeBase,
+	dstKnowledge *types.Knowledge,
+	srcChunks []*types.Chunk,
+	includeDisabled bool,
+	tagIDMapping map[string]string,
+) []*types.Chunk {
+	newChunks := make([]*types.Chunk, 0, len(srcChunks))
+	for _, srcChunk := range srcChunks {
+		if !includeDisabled && !srcChunk.IsEnabled {
+			continue
+		}
+		// Map TagID to target knowledge base
+		targetTagID := ""
+		if srcChunk.TagID != "" {
+			if mappedTagID, ok := tagIDMapping[srcChunk.TagID]; ok {
+				targetTagID = mappedTagID
+			} else {
+				// Try to find or create the tag in target knowledge base
+				targetTagID = s.getOrCreateTagInTarget(ctx, srcKB.TenantID, dstKB.TenantID, dstKB.ID, srcChunk.TagID, tagIDMapping)
+			}
+		}
+
+		newChunk := &types.Chunk{
+			ID:              uuid.New().String(),
+			TenantID:        dstKB.TenantID,
+			KnowledgeID:     dstKnowledge.ID,
+			KnowledgeBaseID: dstKB.ID,
+			TagID:           targetTagID,
+			Content:         srcChunk.Content,
+			ChunkIndex:      srcChunk.ChunkIndex,
+			IsEnabled:       srcChunk.IsEnabled,
+			Flags:           srcChunk.Flags,
+			ChunkType:       types.ChunkTypeFAQ,
+			Metadata:        srcChunk.Metadata,
+			ContentHash:     srcChunk.ContentHash,
+			ImageInfo:       srcChunk.ImageInfo,
+			Status:          int(types.ChunkStatusStored), // Initially stored, will be indexed
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		}
+		newChunks = append(newChunks, newChunk)
+	}
+	return newChunks
+}
+
 // getOrCreateFAQKnowledge gets or creates the FAQ knowledge entry for a knowledge base
 // If srcKnowledge is provided, it will copy relevant fields from source when creating new knowledge
 func (s *knowledgeService) getOrCreateFAQKnowledge(ctx context.Context, kb *types.KnowledgeBase, srcKnowledge *types.Knowledge) (*types.Knowledge, error) {
@@ -8244,3 +16774,474 @@ func (s *knowledgeService) ProcessKnowledgeListDelete(ctx context.Context, t *as
 	logger.Infof(ctx, "Successfully deleted %d knowledge items", len(payload.KnowledgeIDs))
 	return nil
 }
+
+// SetKnowledgeExpiry sets the expiration time of a knowledge item, nil clears it.
+// Changing the expiration time re-arms the pre-expiry notification.
+func (s *knowledgeService) SetKnowledgeExpiry(ctx context.Context,
+	id string, expiresAt *time.Time,
+) (*types.Knowledge, error) {
+	lock, err := s.lockKnowledge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, werrors.NewBadRequestError("过期时间必须晚于当前时间")
+	}
+	knowledge.ExpiresAt = expiresAt
+	knowledge.ExpiryNotifiedAt = nil
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "Set expiry of knowledge %s to %v", id, expiresAt)
+	return knowledge, nil
+}
+
+// knowledgeExpiryBatchSize 每轮到期清理/通知最多处理的知识数，剩余的留到下一轮
+const knowledgeExpiryBatchSize = 200
+
+// knowledgeExpiryWebhookTimeout 到期通知请求的超时时间
+const knowledgeExpiryWebhookTimeout = 10 * time.Second
+
+// ProcessKnowledgeExpiry deletes knowledge past its expiration time and notifies the
+// knowledge base webhook about knowledge that is about to expire. It runs periodically.
+func (s *knowledgeService) ProcessKnowledgeExpiry(ctx context.Context, t *asynq.Task) error {
+	now := time.Now()
+	tenants := make(map[uint64]*types.Tenant)
+	tenantContext := func(tenantID uint64) (context.Context, error) {
+		tenant, ok := tenants[tenantID]
+		if !ok {
+			var err error
+			if tenant, err = s.tenantRepo.GetTenantByID(ctx, tenantID); err != nil {
+				return nil, err
+			}
+			tenants[tenantID] = tenant
+		}
+		tenantCtx := context.WithValue(ctx, types.TenantIDContextKey, tenantID)
+		return context.WithValue(tenantCtx, types.TenantInfoContextKey, tenant), nil
+	}
+
+	expired, err := s.repo.ListKnowledgeExpiringBefore(ctx, now, false, knowledgeExpiryBatchSize)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list expired knowledge: %v", err)
+		return err
+	}
+	deleted := 0
+	for _, knowledge := range expired {
+		tenantCtx, err := tenantContext(knowledge.TenantID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to get tenant %d for expired knowledge %s: %v", knowledge.TenantID, knowledge.ID, err)
+			continue
+		}
+		if err := s.DeleteKnowledge(tenantCtx, knowledge.ID); err != nil {
+			logger.Warnf(ctx, "Failed to delete expired knowledge %s: %v", knowledge.ID, err)
+			continue
+		}
+		deleted++
+	}
+
+	notified := s.notifyExpiringKnowledge(ctx, now)
+	if deleted > 0 || notified > 0 {
+		logger.Infof(ctx, "Knowledge expiry processed: deleted=%d, notified=%d", deleted, notified)
+	}
+
+	// 到期的 FAQ 导入归档也在该周期任务中清除
+	if purged, err := s.repo.DeleteExpiredFAQImportArchives(ctx, now); err != nil {
+		logger.Warnf(ctx, "Failed to delete expired FAQ import archives: %v", err)
+	} else if purged > 0 {
+		logger.Infof(ctx, "Deleted %d expired FAQ import archives", purged)
+	}
+	return nil
+}
+
+// notifyExpiringKnowledge posts a notice for each knowledge item that enters its knowledge base's
+// notification window, and returns the number of notices sent. Failed notices are retried next round.
+func (s *knowledgeService) notifyExpiringKnowledge(ctx context.Context, now time.Time) int {
+	candidates, err := s.repo.ListKnowledgeExpiringBefore(ctx,
+		now.Add(types.MaxExpiryNotifyBeforeHours*time.Hour), true, knowledgeExpiryBatchSize)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list expiring knowledge: %v", err)
+		return 0
+	}
+
+	client := secutils.NewSSRFSafeHTTPClient(secutils.SSRFSafeHTTPClientConfig{
+		Timeout:      knowledgeExpiryWebhookTimeout,
+		MaxRedirects: 3,
+	})
+	kbs := make(map[string]*types.KnowledgeBase)
+	notified := 0
+	for _, knowledge := range candidates {
+		if knowledge.IsExpired(now) {
+			continue
+		}
+		kb, ok := kbs[knowledge.KnowledgeBaseID]
+		if !ok {
+			kb, err = s.kbService.GetKnowledgeBaseByIDOnly(ctx, knowledge.KnowledgeBaseID)
+			if err != nil {
+				logger.Warnf(ctx, "Failed to get knowledge base %s for expiry notice: %v", knowledge.KnowledgeBaseID, err)
+			}
+			kbs[knowledge.KnowledgeBaseID] = kb
+		}
+		if kb == nil || kb.RetentionConfig == nil || kb.RetentionConfig.ExpiryWebhookURL == "" {
+			continue
+		}
+		window := time.Duration(kb.RetentionConfig.GetNotifyBeforeHours()) * time.Hour
+		if knowledge.ExpiresAt.After(now.Add(window)) {
+			continue
+		}
+
+		notice := &types.KnowledgeExpiryNotice{
+			Event:           types.KnowledgeExpiryEventExpiring,
+			TenantID:        knowledge.TenantID,
+			KnowledgeBaseID: knowledge.KnowledgeBaseID,
+			KnowledgeID:     knowledge.ID,
+			Title:           knowledge.Title,
+			ExpiresAt:       *knowledge.ExpiresAt,
+		}
+		if err := postKnowledgeExpiryNotice(ctx, client, kb.RetentionConfig.ExpiryWebhookURL, notice); err != nil {
+			logger.Warnf(ctx, "Failed to send expiry notice for knowledge %s: %v", knowledge.ID, err)
+			continue
+		}
+		if err := s.repo.UpdateKnowledgeColumn(ctx, knowledge.ID, "expiry_notified_at", now); err != nil {
+			logger.Warnf(ctx, "Failed to mark expiry notice sent for knowledge %s: %v", knowledge.ID, err)
+		}
+		notified++
+	}
+	return notified
+}
+
+// postKnowledgeExpiryNotice sends the notice as JSON, any non-2xx response is treated as a failure
+func postKnowledgeExpiryNotice(ctx context.Context,
+	client *http.Client, webhookURL string, notice *types.KnowledgeExpiryNotice,
+) error {
+	if safe, reason := secutils.IsSSRFSafeURL(webhookURL); !safe {
+		return fmt.Errorf("webhook URL rejected: %s", reason)
+	}
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+const (
+	// minKnowledgeRefreshIntervalMinutes URL 知识定时刷新允许的最短间隔
+	minKnowledgeRefreshIntervalMinutes = 10
+	// knowledgeRefreshBatchSize 每轮定时刷新最多检查的知识数，剩余的留到下一轮
+	knowledgeRefreshBatchSize = 100
+	// knowledgeRefreshCheckTimeout 检查源内容是否变化的请求超时时间
+	knowledgeRefreshCheckTimeout = 30 * time.Second
+)
+
+// SetKnowledgeRefreshSchedule sets how often the source of url/file_url knowledge is re-fetched,
+// 0 disables the periodic refresh. The first refresh is due one interval from now.
+func (s *knowledgeService) SetKnowledgeRefreshSchedule(ctx context.Context,
+	id string, intervalMinutes int,
+) (*types.Knowledge, error) {
+	lock, err := s.lockKnowledge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if knowledge.Type != "url" && knowledge.Type != "file_url" {
+		return nil, werrors.NewBadRequestError("仅支持从 URL 导入的知识设置定时刷新")
+	}
+	if intervalMinutes < 0 || (intervalMinutes > 0 && intervalMinutes < minKnowledgeRefreshIntervalMinutes) {
+		return nil, werrors.NewBadRequestError(
+			fmt.Sprintf("刷新间隔不能小于 %d 分钟，设置为 0 表示关闭定时刷新", minKnowledgeRefreshIntervalMinutes))
+	}
+
+	knowledge.RefreshIntervalMinutes = intervalMinutes
+	knowledge.NextRefreshAt = nil
+	if intervalMinutes > 0 {
+		next := time.Now().Add(time.Duration(intervalMinutes) * time.Minute)
+		knowledge.NextRefreshAt = &next
+	}
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "Set refresh interval of knowledge %s to %d minutes", id, intervalMinutes)
+	return knowledge, nil
+}
+
+// ProcessKnowledgeRefresh re-fetches the sources of url/file_url knowledge whose refresh is due and
+// reparses those that changed. Unchanged sources are detected with a conditional request and skipped.
+func (s *knowledgeService) ProcessKnowledgeRefresh(ctx context.Context, t *asynq.Task) error {
+	now := time.Now()
+	due, err := s.repo.ListKnowledgeDueForRefresh(ctx, now, knowledgeRefreshBatchSize)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list knowledge due for refresh: %v", err)
+		return err
+	}
+
+	// 刷新与导入一样要防止 SSRF：校验重定向目标，连接时再次校验解析出的地址以防 DNS 重绑定
+	clientConfig := secutils.DefaultSSRFSafeHTTPClientConfig()
+	clientConfig.Timeout = knowledgeRefreshCheckTimeout
+	client := secutils.NewSSRFSafeHTTPClient(clientConfig)
+	tenants := make(map[uint64]*types.Tenant)
+	refreshed, unchanged := 0, 0
+	for _, knowledge := range due {
+		// 先推进下次刷新时间，检查或重新解析失败时等到下个周期再试
+		next := now.Add(time.Duration(knowledge.RefreshIntervalMinutes) * time.Minute)
+		if err := s.repo.UpdateKnowledgeColumn(ctx, knowledge.ID, "next_refresh_at", next); err != nil {
+			logger.Warnf(ctx, "Failed to advance next refresh time of knowledge %s: %v", knowledge.ID, err)
+			continue
+		}
+
+		if allowed, reason := s.checkKnowledgeRefreshSource(knowledge.Source); !allowed {
+			logger.Warnf(ctx, "Skip refreshing knowledge %s, source rejected: %s", knowledge.ID, reason)
+			continue
+		}
+		changed, err := checkKnowledgeSourceChanged(ctx, client, knowledge)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to check source of knowledge %s: %v", knowledge.ID, err)
+			continue
+		}
+		if !changed {
+			s.saveKnowledgeSourceValidators(ctx, knowledge)
+			unchanged++
+			continue
+		}
+
+		tenant, ok := tenants[knowledge.TenantID]
+		if !ok {
+			if tenant, err = s.tenantRepo.GetTenantByID(ctx, knowledge.TenantID); err != nil {
+				logger.Warnf(ctx, "Failed to get tenant %d for knowledge refresh %s: %v", knowledge.TenantID, knowledge.ID, err)
+				continue
+			}
+			tenants[knowledge.TenantID] = tenant
+		}
+		tenantCtx := context.WithValue(ctx, types.TenantIDContextKey, knowledge.TenantID)
+		tenantCtx = context.WithValue(tenantCtx, types.TenantInfoContextKey, tenant)
+		if _, err := s.ReparseKnowledge(tenantCtx, knowledge.ID); err != nil {
+			logger.Warnf(ctx, "Failed to reparse refreshed knowledge %s: %v", knowledge.ID, err)
+			continue
+		}
+		// 重新解析成功后再记录新的校验值，失败时下个周期仍会判定为已变化
+		s.saveKnowledgeSourceValidators(ctx, knowledge)
+		refreshed++
+	}
+	if len(due) > 0 {
+		logger.Infof(ctx, "Knowledge refresh finished: %d due, %d reparsed, %d unchanged", len(due), refreshed, unchanged)
+	}
+	return nil
+}
+
+// saveKnowledgeSourceValidators persists the ETag/Last-Modified seen at the last source check
+func (s *knowledgeService) saveKnowledgeSourceValidators(ctx context.Context, knowledge *types.Knowledge) {
+	if err := s.repo.UpdateKnowledgeColumn(ctx, knowledge.ID, "source_etag", knowledge.SourceETag); err != nil {
+		logger.Warnf(ctx, "Failed to save source ETag of knowledge %s: %v", knowledge.ID, err)
+	}
+	if err := s.repo.UpdateKnowledgeColumn(ctx, knowledge.ID, "source_last_modified", knowledge.SourceLastModified); err != nil {
+		logger.Warnf(ctx, "Failed to save source Last-Modified of knowledge %s: %v", knowledge.ID, err)
+	}
+}
+
+// checkKnowledgeRefreshSource re-validates a URL source before every refresh: the URL import
+// allow-list may have been tightened since the import, and the host may now resolve to an internal address
+func (s *knowledgeService) checkKnowledgeRefreshSource(rawURL string) (bool, string) {
+	if allowed, reason := s.checkURLImportAllowList(rawURL); !allowed {
+		return false, "URL import allow-list: " + reason
+	}
+	if safe, reason := secutils.IsSSRFSafeURL(rawURL); !safe {
+		return false, "SSRF protection: " + reason
+	}
+	return true, ""
+}
+
+// checkKnowledgeSourceChanged sends a conditional GET for the knowledge source using the validators
+// saved at the last refresh and stores the validators of the response on the knowledge. The source is
+// reported unchanged only when the server answers 304 or returns the same ETag/Last-Modified.
+func checkKnowledgeSourceChanged(ctx context.Context, client *http.Client, knowledge *types.Knowledge) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, knowledge.Source, nil)
+	if err != nil {
+		return false, err
+	}
+	if knowledge.SourceETag != "" {
+		req.Header.Set("If-None-Match", knowledge.SourceETag)
+	}
+	if knowledge.SourceLastModified != "" {
+		req.Header.Set("If-Modified-Since", knowledge.SourceLastModified)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	// 只需要响应头，不读取正文
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("remote server returned status %d", resp.StatusCode)
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	unchanged := (etag != "" && etag == knowledge.SourceETag) ||
+		(etag == "" && lastModified != "" && lastModified == knowledge.SourceLastModified)
+	knowledge.SourceETag, knowledge.SourceLastModified = etag, lastModified
+	return !unchanged, nil
+}
+
+// RecomputeTenantStorage resets StorageUsed of the tenant to the actual storage size of all its knowledge,
+// repairing drift left by missed incremental adjustments.
+func (s *knowledgeService) RecomputeTenantStorage(ctx context.Context,
+	tenantID uint64,
+) (*types.TenantStorageReconcileResult, error) {
+	previous, actual, err := s.tenantRepo.RecomputeStorageUsed(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.TenantStorageReconcileResult{
+		TenantID: tenantID,
+		Previous: previous,
+		Actual:   actual,
+		Delta:    actual - previous,
+	}
+	if result.Delta != 0 {
+		logger.Warnf(ctx, "Corrected storage used of tenant %d: %d -> %d (delta %d)",
+			tenantID, result.Previous, result.Actual, result.Delta)
+	}
+	return result, nil
+}
+
+// ProcessTenantStorageReconcile recomputes the storage used of all tenants. It runs periodically.
+func (s *knowledgeService) ProcessTenantStorageReconcile(ctx context.Context, t *asynq.Task) error {
+	tenants, err := s.tenantRepo.ListTenants(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list tenants for storage reconcile: %v", err)
+		return err
+	}
+	corrected := 0
+	for _, tenant := range tenants {
+		result, err := s.RecomputeTenantStorage(ctx, tenant.ID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to recompute storage of tenant %d: %v", tenant.ID, err)
+			continue
+		}
+		if result.Delta != 0 {
+			corrected++
+		}
+	}
+	logger.Infof(ctx, "Tenant storage reconcile finished: %d tenants checked, %d corrected", len(tenants), corrected)
+	return nil
+}
+
+// dependencyCheckTimeout 单个依赖自检的超时时间
+const dependencyCheckTimeout = 5 * time.Second
+
+// CheckDependencies verifies that docreader, the default embedding model and the vector stores
+// are reachable, so misconfiguration surfaces before the first real document fails.
+func (s *knowledgeService) CheckDependencies(ctx context.Context) (*types.DependencyCheckResult, error) {
+	result := &types.DependencyCheckResult{Healthy: true}
+	record := func(name string, check func(ctx context.Context) error) {
+		checkCtx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+		defer cancel()
+		start := time.Now()
+		err := check(checkCtx)
+		status := types.DependencyStatus{
+			Name:      name,
+			Healthy:   err == nil,
+			LatencyMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			status.Error = err.Error()
+			result.Healthy = false
+			logger.Warnf(ctx, "Dependency check %s failed: %v", name, err)
+		}
+		result.Dependencies = append(result.Dependencies, status)
+	}
+
+	record("docreader", func(ctx context.Context) error {
+		if s.docReaderClient == nil {
+			return errors.New("docreader client is not configured")
+		}
+		return s.docReaderClient.Ping(ctx)
+	})
+
+	// 向量存储的 no-op 操作需要维度，优先使用嵌入模型的维度
+	var dimensions int
+	record("embedding", func(ctx context.Context) error {
+		embedder, err := s.getDefaultEmbeddingModel(ctx)
+		if err != nil {
+			return err
+		}
+		dimensions = embedder.GetDimensions()
+		vector, err := embedder.Embed(ctx, "ping")
+		if err != nil {
+			return err
+		}
+		if len(vector) == 0 {
+			return errors.New("embedding model returned an empty vector")
+		}
+		if dimensions == 0 {
+			dimensions = len(vector)
+		}
+		return nil
+	})
+
+	record("vector_store", func(ctx context.Context) error {
+		tenantInfo, ok := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+		if !ok || tenantInfo == nil {
+			return errors.New("tenant info not found in context")
+		}
+		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+		if err != nil {
+			return err
+		}
+		// 删除一个不存在的分块 ID，不影响数据但会真实访问各个向量存储
+		return retrieveEngine.DeleteByChunkIDList(ctx, []string{uuid.New().String()}, dimensions, "")
+	})
+
+	return result, nil
+}
+
+// getDefaultEmbeddingModel returns the tenant's default embedding model, falling back to the first one
+func (s *knowledgeService) getDefaultEmbeddingModel(ctx context.Context) (embedding.Embedder, error) {
+	models, err := s.modelService.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var selected *types.Model
+	for _, m := range models {
+		if m == nil || m.Type != types.ModelTypeEmbedding {
+			continue
+		}
+		if selected == nil || (m.IsDefault && !selected.IsDefault) {
+			selected = m
+		}
+	}
+	if selected == nil {
+		return nil, errors.New("no embedding model configured")
+	}
+	return s.modelService.GetEmbeddingModel(ctx, selected.ID)
+}
@@ -0,0 +1,1618 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/docreader/client"
+	"github.com/Tencent/WeKnora/docreader/proto"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/tracing"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+)
+
+func TestBuildDocumentChunksNoIndexCollision(t *testing.T) {
+	images := make([]*proto.Image, 150)
+	for i := range images {
+		images[i] = &proto.Image{
+			Url:     fmt.Sprintf("https://example.com/%d.png", i),
+			OcrText: fmt.Sprintf("ocr %d", i),
+			Caption: fmt.Sprintf("caption %d", i),
+		}
+	}
+	chunks := []*proto.Chunk{
+		{Seq: 0, Content: "first", Images: images},
+		{Seq: 1, Content: "second", Images: images[:2]},
+		{Seq: 2, Content: "third"},
+	}
+	knowledge := &types.Knowledge{ID: "k1", KnowledgeBaseID: "kb1"}
+
+	got := buildDocumentChunks(context.Background(), knowledge, chunks)
+	if want := 3 + (150+2)*2; len(got) != want {
+		t.Fatalf("len(chunks) = %d, want %d", len(got), want)
+	}
+
+	seen := make(map[int]bool, len(got))
+	for i, chunk := range got {
+		if seen[chunk.ChunkIndex] {
+			t.Fatalf("duplicate ChunkIndex %d", chunk.ChunkIndex)
+		}
+		seen[chunk.ChunkIndex] = true
+		if i > 0 && got[i-1].ChunkIndex >= chunk.ChunkIndex {
+			t.Fatalf("chunks not sorted at %d", i)
+		}
+	}
+	for i, content := range []string{"first", "second", "third"} {
+		if got[i].ChunkType != types.ChunkTypeText || got[i].Content != content || got[i].ChunkIndex != i {
+			t.Errorf("text chunk %d = {%s %q %d}, want text chunk %q with index %d",
+				i, got[i].ChunkType, got[i].Content, got[i].ChunkIndex, content, i)
+		}
+	}
+}
+
+func TestRestoreGeneratedQuestionsForUnchangedChunks(t *testing.T) {
+	old := &types.Chunk{ID: "old", Content: "unchanged", ChunkType: types.ChunkTypeText}
+	questions := []types.GeneratedQuestion{{ID: "q1", Question: "what?"}}
+	if err := old.SetDocumentMetadata(&types.DocumentChunkMetadata{GeneratedQuestions: questions}); err != nil {
+		t.Fatal(err)
+	}
+	preserved := collectGeneratedQuestions([]*types.Chunk{old})
+
+	kept := &types.Chunk{ID: "new-1", KnowledgeID: "k1", Content: "unchanged", ChunkType: types.ChunkTypeText}
+	changed := &types.Chunk{ID: "new-2", KnowledgeID: "k1", Content: "edited", ChunkType: types.ChunkTypeText}
+	if n := restoreGeneratedQuestions(context.Background(), []*types.Chunk{kept, changed}, preserved); n != 1 {
+		t.Fatalf("restored = %d, want 1", n)
+	}
+
+	infos := buildGeneratedQuestionIndexInfoList(kept)
+	if len(infos) != 1 || infos[0].SourceID != "new-1-q1" || infos[0].Content != "what?" {
+		t.Errorf("question index infos = %+v", infos)
+	}
+	if infos := buildGeneratedQuestionIndexInfoList(changed); len(infos) != 0 {
+		t.Errorf("changed chunk should have no questions, got %+v", infos)
+	}
+}
+
+func TestBuildChunkSnapshot(t *testing.T) {
+	knowledge := &types.Knowledge{ID: "k-1", ParseStatus: "completed"}
+	chunks := []*types.Chunk{
+		{ChunkIndex: 2, ChunkType: types.ChunkTypeText, StartAt: 20, EndAt: 30, Content: "c"},
+		{ChunkIndex: 0, ChunkType: types.ChunkTypeText, StartAt: 0, EndAt: 10, Content: "a"},
+		{ChunkIndex: 1, ChunkType: types.ChunkTypeText, StartAt: 10, EndAt: 20, Content: "b"},
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	snapshot := buildChunkSnapshot(knowledge, types.ChunkingConfig{ChunkSize: 512}, chunks, now, time.Hour)
+	if snapshot.KnowledgeID != "k-1" || snapshot.ChunkingConfig.ChunkSize != 512 {
+		t.Fatalf("unexpected snapshot header: %+v", snapshot)
+	}
+	if !snapshot.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected expiry %v, got %v", now.Add(time.Hour), snapshot.ExpiresAt)
+	}
+	for i, item := range snapshot.Chunks {
+		if item.ChunkIndex != i || item.StartAt != i*10 || item.Content != string(rune('a'+i)) {
+			t.Errorf("chunk %d: unexpected item %+v", i, item)
+		}
+	}
+}
+
+func TestOversizedChunkIndexing(t *testing.T) {
+	var builder strings.Builder
+	for builder.Len() < 50000 {
+		builder.WriteString("func main() { fmt.Println(\"hello\") }\n")
+	}
+	content := builder.String()
+	chunk := &types.Chunk{ID: "chunk-1", KnowledgeID: "k-1", KnowledgeBaseID: "kb-1",
+		ChunkType: types.ChunkTypeText, Content: content}
+
+	kb := &types.KnowledgeBase{ChunkingConfig: types.ChunkingConfig{ChunkSize: 512}}
+	infos, oversized := buildChunkContentIndexInfoList(kb, chunk, "")
+	if !oversized || len(infos) < 2 {
+		t.Fatalf("expected oversized chunk to be split, got %d parts (oversized=%v)", len(infos), oversized)
+	}
+	var joined strings.Builder
+	seen := make(map[string]bool)
+	for i, info := range infos {
+		if n := len([]rune(info.Content)); n > 512 {
+			t.Errorf("part %d has %d runes, exceeding chunk size", i, n)
+		}
+		if seen[info.SourceID] || info.ChunkID != chunk.ID {
+			t.Errorf("part %d: duplicate source id %q or wrong chunk id %q", i, info.SourceID, info.ChunkID)
+		}
+		seen[info.SourceID] = true
+		joined.WriteString(info.Content)
+	}
+	if infos[0].SourceID != chunk.ID {
+		t.Errorf("first part must keep the chunk ID as source ID, got %q", infos[0].SourceID)
+	}
+	if joined.String() != content {
+		t.Error("split parts must cover the original content")
+	}
+	if chunk.Content != content {
+		t.Error("chunk content must stay unchanged")
+	}
+
+	kb.ChunkingConfig.OversizedChunkStrategy = types.OversizedChunkStrategyTruncate
+	infos, oversized = buildChunkContentIndexInfoList(kb, chunk, "")
+	if !oversized || len(infos) != 1 || len([]rune(infos[0].Content)) != kb.ChunkingConfig.GetMaxIndexTokens() {
+		t.Fatalf("expected a single truncated part of %d runes, got %d parts", kb.ChunkingConfig.GetMaxIndexTokens(), len(infos))
+	}
+
+	small := &types.Chunk{ID: "chunk-2", ChunkType: types.ChunkTypeText, Content: "short"}
+	if infos, oversized := buildChunkContentIndexInfoList(kb, small, ""); oversized || len(infos) != 1 || infos[0].Content != "short" {
+		t.Fatalf("small chunk must be indexed as-is, got %+v", infos)
+	}
+}
+
+func TestDropDocumentImagesOverLimit(t *testing.T) {
+	newChunks := func() []*proto.Chunk {
+		return []*proto.Chunk{
+			{Seq: 0, Content: "first", Images: []*proto.Image{{Url: "a.png"}, {Url: "b.png"}}},
+			{Seq: 1, Content: "second", Images: []*proto.Image{{Url: "c.png"}}},
+		}
+	}
+
+	chunks := newChunks()
+	if dropDocumentImages(chunks, 3) {
+		t.Fatalf("expected images to be kept at the limit")
+	}
+	if dropDocumentImages(chunks, 0) {
+		t.Fatalf("expected unlimited when maxImages is 0")
+	}
+
+	chunks = newChunks()
+	if !dropDocumentImages(chunks, 2) {
+		t.Fatalf("expected images to be dropped over the limit")
+	}
+	knowledge := &types.Knowledge{ID: "k1", KnowledgeBaseID: "kb1"}
+	for _, c := range buildDocumentChunks(context.Background(), knowledge, chunks) {
+		if c.ChunkType != types.ChunkTypeText {
+			t.Fatalf("expected text-only chunks, got %s", c.ChunkType)
+		}
+		if c.ImageInfo != "" {
+			t.Fatalf("expected no image info, got %s", c.ImageInfo)
+		}
+	}
+
+	if got := (types.ImageProcessingConfig{}).GetMaxImagesPerDocument(); got != types.DefaultMaxImagesPerDocument {
+		t.Fatalf("expected default limit, got %d", got)
+	}
+	if got := (types.ImageProcessingConfig{MaxImagesPerDocument: -1}).GetMaxImagesPerDocument(); got != 0 {
+		t.Fatalf("expected unlimited, got %d", got)
+	}
+}
+
+func TestKnowledgeProcessingReport(t *testing.T) {
+	chunks := []*proto.Chunk{
+		{Seq: 0, Content: "first", Images: []*proto.Image{{Url: "a.png", OcrText: "ocr"}, {Url: "b.png"}}},
+		{Seq: 1, Content: "  \n", Images: []*proto.Image{{Url: "c.png", Caption: "lost"}}},
+		{Seq: 2, Content: "second", Images: []*proto.Image{{Url: "d.png", Caption: "caption"}}},
+	}
+	report := newKnowledgeProcessingReport("k1", chunks, 4, false)
+	if report.ParsedChunks != 3 || report.TotalImages != 4 || report.ProcessedImages != 2 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+	if report.ImageSkipReasons[types.ProcessingSkipNoImageText] != 1 ||
+		report.ImageSkipReasons[types.ProcessingSkipEmptyParentChunk] != 1 {
+		t.Fatalf("unexpected image skip reasons: %v", report.ImageSkipReasons)
+	}
+	if len(report.SkippedChunks) != 1 || report.SkippedChunks[0].ChunkIndex != 1 ||
+		report.SkippedChunks[0].Reason != types.ProcessingSkipEmptyContent {
+		t.Fatalf("unexpected skipped chunks: %+v", report.SkippedChunks)
+	}
+
+	report = newKnowledgeProcessingReport("k1", []*proto.Chunk{{Seq: 0, Content: "text"}}, 5, true)
+	if report.ProcessedImages != 0 || report.ImageSkipReasons[types.ProcessingSkipMaxImagesExceeded] != 5 {
+		t.Fatalf("expected all images skipped over the limit: %+v", report)
+	}
+
+	for i := 0; i < types.MaxProcessingReportItems+10; i++ {
+		report.AddSkippedChunk(types.ChunkDiagnostic{ChunkIndex: i, Reason: types.ProcessingSkipBelowMinLength})
+	}
+	if len(report.SkippedChunks) != types.MaxProcessingReportItems ||
+		report.ChunkSkipReasons[types.ProcessingSkipBelowMinLength] != types.MaxProcessingReportItems+10 {
+		t.Fatalf("expected capped items with complete counts, got %d items, %v",
+			len(report.SkippedChunks), report.ChunkSkipReasons)
+	}
+
+	knowledge := &types.Knowledge{ID: "k1"}
+	if err := knowledge.SetProcessingReport(report); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := knowledge.GetProcessingReport()
+	if err != nil || restored.ImageSkipReasons[types.ProcessingSkipMaxImagesExceeded] != 5 {
+		t.Fatalf("report round trip failed: %+v, %v", restored, err)
+	}
+}
+
+func TestResolveCitations(t *testing.T) {
+	svc := &knowledgeService{
+		repo: newFakeKnowledgeRepo(&types.Knowledge{ID: "k1", TenantID: 1}),
+		chunkRepo: &fakeChunkRepo{chunks: []*types.Chunk{
+			{ID: "c2", KnowledgeID: "k1", ChunkType: types.ChunkTypeText, ChunkIndex: 1, StartAt: 90, EndAt: 200},
+			{ID: "c1", KnowledgeID: "k1", ChunkType: types.ChunkTypeText, ChunkIndex: 0, StartAt: 0, EndAt: 100},
+			{ID: "ocr", KnowledgeID: "k1", ChunkType: types.ChunkTypeImageOCR, StartAt: 10, EndAt: 20},
+		}},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	chunk, err := svc.GetChunkByOffset(ctx, "k1", 95)
+	if err != nil || chunk.ID != "c1" {
+		t.Fatalf("GetChunkByOffset(95) = %v, %v, want c1", chunk, err)
+	}
+	if chunk, err = svc.GetChunkByOffset(ctx, "k1", 150); err != nil || chunk.ID != "c2" {
+		t.Fatalf("GetChunkByOffset(150) = %v, %v, want c2", chunk, err)
+	}
+	if _, err = svc.GetChunkByOffset(ctx, "k1", 200); err != ErrChunkNotFound {
+		t.Fatalf("expected ErrChunkNotFound past the last chunk, got %v", err)
+	}
+
+	results, err := svc.ResolveCitations(ctx, "k1", []types.CitationSpan{
+		{Start: 10, End: 20}, {Start: 80, End: 120}, {Start: 300, End: 310},
+	})
+	if err != nil {
+		t.Fatalf("ResolveCitations() error = %v", err)
+	}
+	want := [][]string{{"c1"}, {"c1", "c2"}, {}}
+	for i, r := range results {
+		if strings.Join(r.ChunkIDs, ",") != strings.Join(want[i], ",") {
+			t.Fatalf("span %d resolved to %v, want %v", i, r.ChunkIDs, want[i])
+		}
+	}
+
+	if _, err := svc.ResolveCitations(ctx, "k1", []types.CitationSpan{{Start: 5, End: 5}}); err == nil {
+		t.Fatal("expected empty span to be rejected")
+	}
+}
+
+func TestGetChunksWithKnowledge(t *testing.T) {
+	svc := &knowledgeService{
+		repo: newFakeKnowledgeRepo(
+			&types.Knowledge{ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Title: "手册", Type: "file", FileName: "manual.pdf", FileType: "pdf"},
+			&types.Knowledge{ID: "k2", TenantID: 1, KnowledgeBaseID: "kb1", Title: "官网", Type: "url", Source: "https://example.com"},
+			&types.Knowledge{ID: "k3", TenantID: 2, KnowledgeBaseID: "kb2", Title: "其他租户"},
+		),
+		chunkRepo: &fakeChunkRepo{chunks: []*types.Chunk{
+			{ID: "c1", TenantID: 1, KnowledgeID: "k1"},
+			{ID: "c2", TenantID: 1, KnowledgeID: "k2"},
+			{ID: "c3", TenantID: 2, KnowledgeID: "k3"},
+		}},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	results, err := svc.GetChunksWithKnowledge(ctx, []string{"c2", "c3", "missing", "c1", "c2"})
+	if err != nil {
+		t.Fatalf("GetChunksWithKnowledge() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Chunk.ID != "c2" || results[1].Chunk.ID != "c1" {
+		t.Fatalf("expected c2, c1 in request order, got %+v", results)
+	}
+	if k := results[0].Knowledge; k.Title != "官网" || k.Source != "https://example.com" || k.Type != "url" {
+		t.Fatalf("unexpected knowledge for c2: %+v", k)
+	}
+	if k := results[1].Knowledge; k.FileName != "manual.pdf" || k.KnowledgeBaseID != "kb1" {
+		t.Fatalf("unexpected knowledge for c1: %+v", k)
+	}
+
+	if _, err := svc.GetChunkWithKnowledge(ctx, "c3"); err != ErrChunkNotFound {
+		t.Fatalf("expected chunk of another tenant to be hidden, got %v", err)
+	}
+}
+
+func TestIndexContentTemplate(t *testing.T) {
+	chunks := []*types.Chunk{
+		{ID: "c1", ChunkType: types.ChunkTypeText, Content: "# 安装\n下载安装包"},
+		{ID: "c2", ChunkType: types.ChunkTypeText, Content: "双击运行\n## 配置\n修改端口"},
+		{ID: "c3", ChunkType: types.ChunkTypeText, Content: "重启服务"},
+	}
+	if err := assignChunkSections(chunks); err != nil {
+		t.Fatalf("assignChunkSections() error = %v", err)
+	}
+	wantSections := []string{"安装", "安装", "配置"}
+	for i, chunk := range chunks {
+		meta, _ := chunk.DocumentMetadata()
+		if meta == nil || meta.Section != wantSections[i] {
+			t.Fatalf("chunk %s section = %+v, want %q", chunk.ID, meta, wantSections[i])
+		}
+	}
+
+	kb := &types.KnowledgeBase{ChunkingConfig: types.ChunkingConfig{
+		IndexContentTemplate: "{{title}} / {{section}}\n{{content}}",
+	}}
+	infos, _ := buildChunkContentIndexInfoList(kb, chunks[2], "部署手册")
+	if len(infos) != 1 || infos[0].Content != "部署手册 / 配置\n重启服务" {
+		t.Fatalf("unexpected index content: %+v", infos)
+	}
+	if chunks[2].Content != "重启服务" {
+		t.Fatalf("stored chunk content must stay unchanged, got %q", chunks[2].Content)
+	}
+
+	// Default template indexes the raw content
+	kb.ChunkingConfig.IndexContentTemplate = ""
+	if infos, _ := buildChunkContentIndexInfoList(kb, chunks[2], "部署手册"); infos[0].Content != "重启服务" {
+		t.Fatalf("expected raw content without template, got %q", infos[0].Content)
+	}
+	if err := validateIndexContentTemplate("{{title}}"); err == nil {
+		t.Fatal("expected template without {{content}} to be rejected")
+	}
+}
+
+func TestBuildParsedMarkdown(t *testing.T) {
+	chunks := []*proto.Chunk{
+		{Seq: 1, Content: "二段内容。\n## 三", Start: 9, End: 19},
+		{Seq: 0, Content: "# 标题\n第一段。二段内容。", Start: 0, End: 14},
+		{Seq: 2, Content: "## 三\n尾部", Start: 15, End: 22},
+	}
+	want := "# 标题\n第一段。二段内容。\n## 三\n尾部"
+	if got := buildParsedMarkdown(chunks); got != want {
+		t.Fatalf("unexpected markdown:\n%q\nwant\n%q", got, want)
+	}
+
+	noOffsets := []*proto.Chunk{{Seq: 0, Content: "a"}, {Seq: 1, Content: "b"}}
+	if got := buildParsedMarkdown(noOffsets); got != "a\n\nb" {
+		t.Fatalf("expected chunks without offsets to be joined, got %q", got)
+	}
+}
+
+func TestClassifyImageChildChunks(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	child := func(id string, chunkType types.ChunkType, url string, offset time.Duration) *types.Chunk {
+		return &types.Chunk{
+			ID:        id,
+			ChunkType: chunkType,
+			ImageInfo: fmt.Sprintf(`[{"original_url":%q}]`, url),
+			CreatedAt: base.Add(offset),
+		}
+	}
+	children := []*types.Chunk{
+		child("ocr-new-dup", types.ChunkTypeImageOCR, "new.png", 2*time.Minute),
+		child("ocr-new", types.ChunkTypeImageOCR, "new.png", time.Minute),
+		child("caption-new", types.ChunkTypeImageCaption, "new.png", time.Minute),
+		child("ocr-old", types.ChunkTypeImageOCR, "old.png", 0),
+		{ID: "caption-broken", ChunkType: types.ChunkTypeImageCaption, ImageInfo: "not json"},
+		{ID: "text", ChunkType: types.ChunkTypeText},
+	}
+
+	kept, redundant := classifyImageChildChunks(map[string]struct{}{"new.png": {}}, children)
+
+	var keptIDs []string
+	for _, c := range kept {
+		keptIDs = append(keptIDs, c.ID)
+	}
+	if !slices.Equal(keptIDs, []string{"ocr-new", "caption-new", "text"}) {
+		t.Fatalf("unexpected kept chunks %v", keptIDs)
+	}
+	reasons := make(map[string]string)
+	for c, reason := range redundant {
+		reasons[c.ID] = reason
+	}
+	want := map[string]string{
+		"ocr-new-dup":    types.ImageChunkIssueDuplicate,
+		"ocr-old":        types.ImageChunkIssueOrphaned,
+		"caption-broken": types.ImageChunkIssueOrphaned,
+	}
+	if len(reasons) != len(want) {
+		t.Fatalf("unexpected redundant chunks %v", reasons)
+	}
+	for id, reason := range want {
+		if reasons[id] != reason {
+			t.Fatalf("chunk %s: expected %s, got %q", id, reason, reasons[id])
+		}
+	}
+}
+
+func TestIsolateIndexFailures(t *testing.T) {
+	entries := make([]*types.IndexInfo, 10)
+	for i := range entries {
+		entries[i] = &types.IndexInfo{ChunkID: fmt.Sprintf("c%d", i), KnowledgeID: "k1"}
+	}
+	bad := map[string]bool{"c3": true, "c7": true}
+	indexed := make(map[string]int)
+	index := func(batch []*types.IndexInfo) error {
+		for _, entry := range batch {
+			if bad[entry.ChunkID] {
+				return fmt.Errorf("token limit exceeded: %s", entry.ChunkID)
+			}
+		}
+		for _, entry := range batch {
+			indexed[entry.ChunkID]++
+		}
+		return nil
+	}
+
+	failed, err := isolateIndexFailures(entries, 20, index)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(failed) != 2 || failed["c3"] == nil || failed["c7"] == nil {
+		t.Fatalf("expected c3 and c7 to fail, got %v", failed)
+	}
+	for _, entry := range entries {
+		want := 1
+		if bad[entry.ChunkID] {
+			want = 0
+		}
+		if indexed[entry.ChunkID] != want {
+			t.Errorf("chunk %s indexed %d times, want %d", entry.ChunkID, indexed[entry.ChunkID], want)
+		}
+	}
+
+	if _, err := isolateIndexFailures(entries, 1, index); err == nil {
+		t.Error("expected error when failures exceed the cap")
+	}
+	alwaysFail := func([]*types.IndexInfo) error { return fmt.Errorf("embedding service unavailable") }
+	if _, err := isolateIndexFailures(entries[:3], 20, alwaysFail); err == nil {
+		t.Error("expected error when every chunk fails")
+	}
+}
+
+func TestCheckChunkChain(t *testing.T) {
+	newChain := func() []*types.Chunk {
+		chunks := make([]*types.Chunk, 4)
+		for i := range chunks {
+			chunks[i] = &types.Chunk{ID: fmt.Sprintf("c%d", i), ChunkIndex: i, ChunkType: types.ChunkTypeText}
+		}
+		rebuildChunkChain(chunks)
+		return chunks
+	}
+	issues := func(report *types.ChunkChainReport) []string {
+		found := make([]string, 0, len(report.Anomalies))
+		for _, anomaly := range report.Anomalies {
+			found = append(found, anomaly.Type+":"+anomaly.ChunkID)
+		}
+		slices.Sort(found)
+		return found
+	}
+
+	if report := checkChunkChain(newChain()); !report.Valid || report.AnomalyCount != 0 {
+		t.Fatalf("expected valid chain, got %v", issues(report))
+	}
+
+	// c1 -> missing chunk: c1 dangles, c2 loses its way back and c2, c3 become unreachable
+	chunks := newChain()
+	chunks[1].NextChunkID = "gone"
+	report := checkChunkChain(chunks)
+	want := []string{"broken:c2", "dangling:c1", "orphan:c2", "orphan:c3"}
+	if got := issues(report); !slices.Equal(got, want) {
+		t.Fatalf("dangling: got %v, want %v", got, want)
+	}
+
+	// c3 -> c1 closes a cycle and forks c1
+	chunks = newChain()
+	chunks[3].NextChunkID = "c1"
+	report = checkChunkChain(chunks)
+	for _, issue := range []string{"cycle:c1", "fork:c3", "out_of_order:c3", "broken:c3"} {
+		if !slices.Contains(issues(report), issue) {
+			t.Errorf("cycle: missing %s in %v", issue, issues(report))
+		}
+	}
+
+	changed := rebuildChunkChain(chunks)
+	if len(changed) != 1 || changed[0].ID != "c3" || chunks[3].NextChunkID != "" {
+		t.Fatalf("expected only c3 to be relinked, got %d changed", len(changed))
+	}
+	if report := checkChunkChain(chunks); !report.Valid {
+		t.Fatalf("expected repaired chain to be valid, got %v", issues(report))
+	}
+}
+
+func TestRankKnowledgeByRelevance(t *testing.T) {
+	results := []*types.SearchResult{
+		{ID: "a1", KnowledgeID: "a", Score: 0.5, Content: "a low"},
+		{ID: "b1", KnowledgeID: "b", Score: 0.7, Content: strings.Repeat("长", 300)},
+		{ID: "a2", KnowledgeID: "a", Score: 0.9, Content: "a best"},
+		{ID: "c1", KnowledgeID: "c", Score: 0.6, Content: "c only"},
+	}
+
+	hits := rankKnowledgeByRelevance(results)
+	got := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		got = append(got, hit.Knowledge.ID)
+	}
+	if !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("expected ranking [a b c], got %v", got)
+	}
+	if hits[0].MatchedChunkID != "a2" || hits[0].Snippet != "a best" || hits[0].MatchedChunks != 2 {
+		t.Fatalf("unexpected top hit: %+v", hits[0])
+	}
+	if n := len([]rune(hits[1].Snippet)); n != knowledgeRelevanceSnippetRunes+3 {
+		t.Fatalf("expected snippet truncated to %d runes, got %d", knowledgeRelevanceSnippetRunes+3, n)
+	}
+
+	k := &types.Knowledge{Type: "url", FileType: "html", TagID: "t1"}
+	if !knowledgeMatchesListFilter(k, "t1", "url") || !knowledgeMatchesListFilter(k, "", "html") {
+		t.Fatal("expected knowledge to match tag and file type filters")
+	}
+	if knowledgeMatchesListFilter(k, "t2", "") || knowledgeMatchesListFilter(k, "", "manual") {
+		t.Fatal("expected knowledge not to match other tag or type")
+	}
+}
+
+func TestCloneKnowledgeEnableStatus(t *testing.T) {
+	// No retriever engines configured, index operations are no-ops
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	targetKB := &types.KnowledgeBase{ID: "kb-dst", TenantID: 1, EmbeddingModelID: "embedding-model"}
+	disabled := &types.Knowledge{
+		ID: "k-disabled", TenantID: 1, KnowledgeBaseID: "kb-src", Type: "file",
+		ParseStatus: types.ParseStatusCompleted, EnableStatus: "disabled",
+	}
+
+	cloneWith := func(policy types.CloneEnableStatusPolicy) (*types.Knowledge, *fakeChunkRepo) {
+		t.Helper()
+		knowledgeRepo := newFakeKnowledgeRepo()
+		chunkRepo := &fakeChunkRepo{chunks: []*types.Chunk{
+			{ID: "c1", KnowledgeID: disabled.ID, ChunkType: types.ChunkTypeText, IsEnabled: false},
+			{ID: "c2", KnowledgeID: disabled.ID, ChunkType: types.ChunkTypeText, IsEnabled: false},
+		}}
+		svc := &knowledgeService{
+			repo:         knowledgeRepo,
+			chunkRepo:    chunkRepo,
+			tenantRepo:   &fakeStorageTenantRepo{tenant: &types.Tenant{ID: 1}},
+			modelService: newFakeReembedModelService("embedding-model"),
+		}
+		if err := svc.cloneKnowledge(ctx, disabled, targetKB, true, policy); err != nil {
+			t.Fatalf("cloneKnowledge(%q) error = %v", policy, err)
+		}
+		if len(knowledgeRepo.knowledge) != 1 {
+			t.Fatalf("expected one cloned knowledge, got %d", len(knowledgeRepo.knowledge))
+		}
+		for _, k := range knowledgeRepo.knowledge {
+			return k, chunkRepo
+		}
+		return nil, nil
+	}
+
+	// Default: the disabled document stays disabled and its chunks are left as cloned
+	dst, chunkRepo := cloneWith("")
+	if dst.EnableStatus != "disabled" || dst.ParseStatus != types.ParseStatusCompleted {
+		t.Fatalf("preserve: got enable_status=%q parse_status=%q", dst.EnableStatus, dst.ParseStatus)
+	}
+	if chunkRepo.updated != 0 {
+		t.Fatalf("preserve: expected no chunk updates, got %d", chunkRepo.updated)
+	}
+
+	dst, chunkRepo = cloneWith(types.CloneEnableStatusEnableAll)
+	if dst.EnableStatus != "enabled" || chunkRepo.updated != 2 {
+		t.Fatalf("enable_all: got enable_status=%q, %d chunks updated", dst.EnableStatus, chunkRepo.updated)
+	}
+	for _, chunk := range chunkRepo.chunks {
+		if chunk.KnowledgeID == dst.ID && !chunk.IsEnabled {
+			t.Fatalf("enable_all: cloned chunk %s still disabled", chunk.ID)
+		}
+	}
+
+	if status, sync := clonedEnableStatus(&types.Knowledge{EnableStatus: "enabled"}, types.CloneEnableStatusDisableAll); status != "disabled" || !sync {
+		t.Fatalf("disable_all: got %q, sync=%v", status, sync)
+	}
+	if status, sync := clonedEnableStatus(&types.Knowledge{}, types.CloneEnableStatusPreserve); status != "enabled" || sync {
+		t.Fatalf("preserve without status: got %q, sync=%v", status, sync)
+	}
+}
+
+// fakeProcessChunkService stores the chunks created by processChunks in memory
+type fakeProcessChunkService struct {
+	interfaces.ChunkService
+	chunks []*types.Chunk
+}
+
+func (s *fakeProcessChunkService) CreateChunks(_ context.Context, chunks []*types.Chunk) error {
+	s.chunks = append(s.chunks, chunks...)
+	return nil
+}
+
+func (s *fakeProcessChunkService) DeleteChunksByKnowledgeID(_ context.Context, _ string) error {
+	s.chunks = nil
+	return nil
+}
+
+func (s *fakeProcessChunkService) UpdateChunks(_ context.Context, chunks []*types.Chunk) error {
+	for _, chunk := range chunks {
+		for i, stored := range s.chunks {
+			if stored.ID == chunk.ID {
+				s.chunks[i] = chunk
+			}
+		}
+	}
+	return nil
+}
+
+func (s *fakeProcessChunkService) ListChunksByKnowledgeID(_ context.Context, _ string) ([]*types.Chunk, error) {
+	return s.chunks, nil
+}
+
+type fakeProcessGraphRepo struct {
+	interfaces.RetrieveGraphRepository
+}
+
+func (r *fakeProcessGraphRepo) DelGraph(_ context.Context, _ []types.NameSpace) error { return nil }
+
+func TestProcessChunksSkipSummaryKeepsSummaryChunk(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	process := func(description string) []*types.Chunk {
+		t.Helper()
+		knowledge := &types.Knowledge{
+			ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", FileName: "manual.pdf",
+			ParseStatus: types.ParseStatusProcessing, Description: description,
+			SummaryStatus: types.SummaryStatusCompleted,
+		}
+		chunkService := &fakeProcessChunkService{}
+		svc := &knowledgeService{
+			repo:         newFakeKnowledgeRepo(knowledge),
+			tenantRepo:   &fakeStorageTenantRepo{tenant: tenant},
+			chunkService: chunkService,
+			modelService: newFakeReembedModelService("embedding-model"),
+			graphEngine:  &fakeProcessGraphRepo{},
+		}
+		kb := &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+		}
+		svc.processChunks(ctx, kb, knowledge, []*proto.Chunk{{Seq: 0, Content: "第一段"}, {Seq: 1, Content: "第二段"}},
+			ProcessChunksOptions{SkipSummary: true})
+		if knowledge.ParseStatus != types.ParseStatusCompleted || knowledge.SummaryStatus != types.SummaryStatusCompleted {
+			t.Fatalf("expected completed with the summary kept, got %q / %q", knowledge.ParseStatus, knowledge.SummaryStatus)
+		}
+		return chunkService.chunks
+	}
+
+	chunks := process("产品使用手册摘要")
+	var summary *types.Chunk
+	for _, chunk := range chunks {
+		if chunk.ChunkType == types.ChunkTypeSummary {
+			summary = chunk
+		}
+	}
+	if summary == nil || !strings.Contains(summary.Content, "产品使用手册摘要") ||
+		!strings.Contains(summary.Content, "manual.pdf") || summary.ParentChunkID != chunks[0].ID {
+		t.Fatalf("expected summary chunk to be re-created from the description, got %+v", summary)
+	}
+	if summary.ChunkIndex <= chunks[1].ChunkIndex {
+		t.Fatalf("expected summary chunk after the text chunks, got index %d", summary.ChunkIndex)
+	}
+
+	for _, chunk := range process("") {
+		if chunk.ChunkType == types.ChunkTypeSummary {
+			t.Fatal("expected no summary chunk without a description")
+		}
+	}
+}
+
+func TestProcessChunksKeepDisabled(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := fakeRetrieveEngineTenant(1)
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	knowledge := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "manual", FileName: "draft.md",
+		ParseStatus: types.ParseStatusProcessing, EnableStatus: "disabled", Description: "草稿摘要",
+		SummaryStatus: types.SummaryStatusCompleted,
+	}
+	chunkService := &fakeProcessChunkService{}
+	engine := &fakeRetrieveEngine{}
+	svc := &knowledgeService{
+		repo:           newFakeKnowledgeRepo(knowledge),
+		tenantRepo:     &fakeStorageTenantRepo{tenant: tenant},
+		chunkService:   chunkService,
+		modelService:   newFakeReembedModelService("embedding-model"),
+		graphEngine:    &fakeProcessGraphRepo{},
+		retrieveEngine: &fakeRetrieveEngineRegistry{engine: engine},
+	}
+	kb := &types.KnowledgeBase{ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model"}
+	svc.processChunks(ctx, kb, knowledge, []*proto.Chunk{{Seq: 0, Content: "第一段"}, {Seq: 1, Content: "第二段"}},
+		ProcessChunksOptions{KeepDisabled: true, SkipSummary: true})
+
+	if knowledge.ParseStatus != types.ParseStatusCompleted || knowledge.EnableStatus != "disabled" {
+		t.Fatalf("expected completed and still disabled, got %q / %q", knowledge.ParseStatus, knowledge.EnableStatus)
+	}
+	if len(chunkService.chunks) != 3 {
+		t.Fatalf("expected two text chunks and the summary chunk, got %d", len(chunkService.chunks))
+	}
+	for _, chunk := range chunkService.chunks {
+		if chunk.IsEnabled {
+			t.Fatalf("expected %s chunk to be stored disabled", chunk.ChunkType)
+		}
+	}
+	if len(engine.indexed) != 3 {
+		t.Fatalf("expected three index entries, got %d", len(engine.indexed))
+	}
+	for _, info := range engine.indexed {
+		if info.IsEnabled {
+			t.Fatalf("expected index entry %s to be written disabled", info.SourceID)
+		}
+	}
+	if slices.Contains(engine.ops, "update_enabled") {
+		t.Fatalf("expected no enabled status switch after indexing, got %v", engine.ops)
+	}
+}
+
+func TestProcessChunksRequireIndexableContent(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	// No retriever engines configured, index operations are no-ops
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+	// The document only chunks to whitespace
+	whitespace := []*proto.Chunk{{Seq: 0, Content: "   "}, {Seq: 1, Content: "\n\t\n"}}
+
+	process := func(strict bool, chunks []*proto.Chunk) (*types.Knowledge, *fakeProcessChunkService) {
+		t.Helper()
+		knowledge := &types.Knowledge{
+			ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusProcessing,
+		}
+		chunkService := &fakeProcessChunkService{}
+		svc := &knowledgeService{
+			repo:         newFakeKnowledgeRepo(knowledge),
+			tenantRepo:   &fakeStorageTenantRepo{tenant: &types.Tenant{ID: 1}},
+			chunkService: chunkService,
+			modelService: newFakeReembedModelService("embedding-model"),
+			graphEngine:  &fakeProcessGraphRepo{},
+		}
+		kb := &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+			ChunkingConfig: types.ChunkingConfig{RequireIndexableContent: strict, MinIndexContentLength: 5},
+		}
+		svc.processChunks(ctx, kb, knowledge, chunks, ProcessChunksOptions{SkipSummary: true})
+		return knowledge, chunkService
+	}
+
+	// Default behavior: completes without content
+	knowledge, _ := process(false, whitespace)
+	if knowledge.ParseStatus != types.ParseStatusCompleted {
+		t.Fatalf("non-strict: expected completed, got %q (%s)", knowledge.ParseStatus, knowledge.ErrorMessage)
+	}
+
+	knowledge, _ = process(true, whitespace)
+	if knowledge.ParseStatus != types.ParseStatusFailed || knowledge.ErrorMessage != ErrNoIndexableContent.Error() {
+		t.Fatalf("strict: expected failed with %q, got %q (%s)",
+			ErrNoIndexableContent, knowledge.ParseStatus, knowledge.ErrorMessage)
+	}
+
+	// Chunks below the minimum index length are kept for display only, which is not indexable content either
+	knowledge, chunkService := process(true, []*proto.Chunk{{Seq: 0, Content: "12"}})
+	if knowledge.ParseStatus != types.ParseStatusFailed || len(chunkService.chunks) != 0 {
+		t.Fatalf("strict: expected failure and chunk cleanup, got %q with %d chunks",
+			knowledge.ParseStatus, len(chunkService.chunks))
+	}
+
+	knowledge, chunkService = process(true, []*proto.Chunk{{Seq: 0, Content: "12"}, {Seq: 1, Content: "real content"}})
+	if knowledge.ParseStatus != types.ParseStatusCompleted || len(chunkService.chunks) != 2 {
+		t.Fatalf("strict: expected completed with 2 chunks, got %q with %d chunks",
+			knowledge.ParseStatus, len(chunkService.chunks))
+	}
+	if n := indexedChunkCount(&types.KnowledgeBase{}, chunkService.chunks,
+		map[string]error{chunkService.chunks[0].ID: fmt.Errorf("index failed")}); n != 1 {
+		t.Fatalf("expected failed chunks not to count as indexed, got %d", n)
+	}
+}
+
+// fakeDocReader records the file read requests and returns fixed chunks.
+type fakeDocReader struct {
+	proto.DocReaderClient
+	requests []*proto.ReadFromFileRequest
+	chunks   []*proto.Chunk
+	// onRead, when set and returning a response, replaces the default response
+	onRead func() *proto.ReadResponse
+}
+
+func (r *fakeDocReader) ReadFromFile(_ context.Context, in *proto.ReadFromFileRequest,
+	_ ...grpc.CallOption,
+) (*proto.ReadResponse, error) {
+	r.requests = append(r.requests, in)
+	if r.onRead != nil {
+		if resp := r.onRead(); resp != nil {
+			return resp, nil
+		}
+	}
+	return &proto.ReadResponse{Chunks: r.chunks}, nil
+}
+
+func TestProcessDocumentPresentationFile(t *testing.T) {
+	for _, name := range []string{"slides.pptx", "legacy.PPT", "book.epub"} {
+		if !isValidFileType(name) {
+			t.Fatalf("expected %s to be a valid file type", name)
+		}
+	}
+	if isValidFileType("slides.key") {
+		t.Fatal("expected key to be rejected")
+	}
+
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	ctx := context.Background()
+	knowledge := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file",
+		FileName: "slides.pptx", FileType: getFileType("slides.pptx"), ParseStatus: types.ParseStatusPending,
+	}
+	fileSvc := &fakeOffloadFileService{files: map[string][]byte{"local://slides.pptx": []byte("pptx content")}}
+	docReader := &fakeDocReader{chunks: []*proto.Chunk{
+		{Seq: 0, Content: "第一页：产品介绍"},
+		{Seq: 1, Content: "第二页：价格说明"},
+	}}
+	chunkService := &fakeProcessChunkService{}
+	svc := &knowledgeService{
+		repo:       newFakeKnowledgeRepo(knowledge),
+		tenantRepo: &fakeStorageTenantRepo{tenant: &types.Tenant{ID: 1}},
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+		}},
+		fileSvc:         fileSvc,
+		docReaderClient: &client.Client{DocReaderClient: docReader},
+		chunkService:    chunkService,
+		modelService:    newFakeReembedModelService("embedding-model"),
+		graphEngine:     &fakeProcessGraphRepo{},
+	}
+
+	payload, err := json.Marshal(types.DocumentProcessPayload{
+		TenantID: 1, KnowledgeID: knowledge.ID, KnowledgeBaseID: "kb1",
+		FilePath: "local://slides.pptx", FileName: knowledge.FileName, FileType: knowledge.FileType,
+		SkipSummary: true,
+	})
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	if err := svc.ProcessDocument(ctx, asynq.NewTask(types.TypeDocumentProcess, payload)); err != nil {
+		t.Fatalf("ProcessDocument() error = %v", err)
+	}
+
+	if len(docReader.requests) != 1 || docReader.requests[0].FileType != "pptx" ||
+		string(docReader.requests[0].FileContent) != "pptx content" {
+		t.Fatalf("expected a single pptx read request, got %+v", docReader.requests)
+	}
+	if knowledge.ParseStatus != types.ParseStatusCompleted {
+		t.Fatalf("expected completed, got %q (%s)", knowledge.ParseStatus, knowledge.ErrorMessage)
+	}
+	if len(chunkService.chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(chunkService.chunks))
+	}
+}
+
+func TestCancelKnowledgeProcessing(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	knowledge := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusProcessing,
+	}
+	repo := newFakeKnowledgeRepo(knowledge)
+	chunkService := &fakeProcessChunkService{chunks: []*types.Chunk{{ID: "c1", KnowledgeID: knowledge.ID}}}
+	docReader := &fakeDocReader{}
+	svc := &knowledgeService{
+		repo:       repo,
+		tenantRepo: &fakeStorageTenantRepo{tenant: tenant},
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+		}},
+		docReaderClient: &client.Client{DocReaderClient: docReader},
+		chunkService:    chunkService,
+		modelService:    newFakeReembedModelService("embedding-model"),
+		graphEngine:     &fakeProcessGraphRepo{},
+	}
+
+	cancelled, err := svc.CancelKnowledgeProcessing(ctx, knowledge.ID)
+	if err != nil {
+		t.Fatalf("CancelKnowledgeProcessing() error = %v", err)
+	}
+	if cancelled.ParseStatus != types.ParseStatusCancelled || len(chunkService.chunks) != 0 {
+		t.Fatalf("expected cancelled knowledge without chunks, got %q with %d chunks",
+			cancelled.ParseStatus, len(chunkService.chunks))
+	}
+	if _, err := svc.CancelKnowledgeProcessing(ctx, knowledge.ID); err == nil {
+		t.Fatal("expected cancelling a cancelled knowledge to fail")
+	}
+
+	// A running task holds its own copy of the knowledge, it must stop without completing it
+	running := *knowledge
+	running.ParseStatus = types.ParseStatusProcessing
+	svc.processChunks(ctx, &types.KnowledgeBase{ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument},
+		&running, []*proto.Chunk{{Seq: 0, Content: "content"}}, ProcessChunksOptions{SkipSummary: true})
+	if running.ParseStatus == types.ParseStatusCompleted || len(chunkService.chunks) != 0 {
+		t.Fatalf("expected processing to abort, got %q with %d chunks", running.ParseStatus, len(chunkService.chunks))
+	}
+	if repo.knowledge[knowledge.ID].ParseStatus != types.ParseStatusCancelled {
+		t.Fatalf("expected knowledge to stay cancelled, got %q", repo.knowledge[knowledge.ID].ParseStatus)
+	}
+
+	// A queued task for a cancelled knowledge is skipped
+	payload, err := json.Marshal(types.DocumentProcessPayload{
+		TenantID: 1, KnowledgeID: knowledge.ID, KnowledgeBaseID: "kb1", FilePath: "local://big.pdf", FileType: "pdf",
+	})
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	if err := svc.ProcessDocument(ctx, asynq.NewTask(types.TypeDocumentProcess, payload)); err != nil {
+		t.Fatalf("ProcessDocument() error = %v", err)
+	}
+	if len(docReader.requests) != 0 || repo.knowledge[knowledge.ID].ParseStatus != types.ParseStatusCancelled {
+		t.Fatalf("expected cancelled knowledge to be skipped, got %d reads and status %q",
+			len(docReader.requests), repo.knowledge[knowledge.ID].ParseStatus)
+	}
+}
+
+func TestCancelKnowledgeProcessingDuringParse(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	knowledge := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", FileName: "big.pdf", FileType: "pdf",
+		FilePath: "local://big.pdf", ParseStatus: types.ParseStatusPending,
+	}
+	repo := newFakeKnowledgeRepo(knowledge)
+	chunkService := &fakeProcessChunkService{}
+	docReader := &fakeDocReader{chunks: []*proto.Chunk{{Seq: 0, Content: "content"}}}
+	svc := &knowledgeService{
+		repo:       repo,
+		tenantRepo: &fakeStorageTenantRepo{tenant: tenant},
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+		}},
+		fileSvc:         &fakeOffloadFileService{files: map[string][]byte{"local://big.pdf": []byte("pdf content")}},
+		docReaderClient: &client.Client{DocReaderClient: docReader},
+		chunkService:    chunkService,
+		modelService:    newFakeReembedModelService("embedding-model"),
+		graphEngine:     &fakeProcessGraphRepo{},
+		task:            asynq.NewClient(asynq.RedisClientOpt{Addr: "127.0.0.1:1"}),
+	}
+	defer svc.task.Close()
+
+	// The user cancels while docreader is parsing, and the interrupted parse returns an error
+	docReader.onRead = func() *proto.ReadResponse {
+		if _, err := svc.CancelKnowledgeProcessing(ctx, knowledge.ID); err != nil {
+			t.Fatalf("CancelKnowledgeProcessing() error = %v", err)
+		}
+		return &proto.ReadResponse{Error: "parse interrupted"}
+	}
+	payload, err := json.Marshal(types.DocumentProcessPayload{
+		TenantID: 1, KnowledgeID: knowledge.ID, KnowledgeBaseID: "kb1",
+		FilePath: knowledge.FilePath, FileName: knowledge.FileName, FileType: knowledge.FileType, SkipSummary: true,
+	})
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	task := asynq.NewTask(types.TypeDocumentProcess, payload)
+	if err := svc.ProcessDocument(ctx, task); err != nil {
+		t.Fatalf("ProcessDocument() error = %v", err)
+	}
+	if got := repo.knowledge[knowledge.ID]; got.ParseStatus != types.ParseStatusCancelled || got.ErrorMessage != "" {
+		t.Fatalf("expected knowledge to stay cancelled, got %q (%s)", got.ParseStatus, got.ErrorMessage)
+	}
+
+	// The cancelled knowledge can be re-parsed
+	docReader.onRead = nil
+	reparsed, err := svc.ReparseKnowledge(ctx, knowledge.ID)
+	if err != nil {
+		t.Fatalf("ReparseKnowledge() error = %v", err)
+	}
+	if reparsed.ParseStatus != types.ParseStatusPending {
+		t.Fatalf("expected reparse to reset the status to pending, got %q", reparsed.ParseStatus)
+	}
+	if err := svc.ProcessDocument(ctx, task); err != nil {
+		t.Fatalf("ProcessDocument() error = %v", err)
+	}
+	if got := repo.knowledge[knowledge.ID]; got.ParseStatus != types.ParseStatusCompleted || len(chunkService.chunks) != 1 {
+		t.Fatalf("expected reparse to complete with 1 chunk, got %q with %d chunks",
+			got.ParseStatus, len(chunkService.chunks))
+	}
+}
+
+func TestProcessDocumentPartialResult(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	for _, accept := range []bool{false, true} {
+		knowledge := &types.Knowledge{
+			ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", FileName: "big.pdf", FileType: "pdf",
+			FilePath: "local://big.pdf", ParseStatus: types.ParseStatusPending,
+		}
+		repo := newFakeKnowledgeRepo(knowledge)
+		chunkService := &fakeProcessChunkService{}
+		// docreader timed out after the text was extracted, before the images of the second chunk were parsed
+		docReader := &fakeDocReader{onRead: func() *proto.ReadResponse {
+			return &proto.ReadResponse{Chunks: []*proto.Chunk{
+				{Seq: 0, Content: "first"}, {Seq: 1, Content: "second ![](images/a.png)"},
+			}, Partial: true}
+		}}
+		kb := &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+			ChunkingConfig: types.ChunkingConfig{ParseTimeoutSeconds: 300, AcceptPartialResult: accept},
+		}
+		svc := &knowledgeService{
+			repo:            repo,
+			tenantRepo:      &fakeStorageTenantRepo{tenant: tenant},
+			kbService:       &fakeTagKBService{kb: kb},
+			fileSvc:         &fakeOffloadFileService{files: map[string][]byte{"local://big.pdf": []byte("pdf content")}},
+			docReaderClient: &client.Client{DocReaderClient: docReader},
+			chunkService:    chunkService,
+			modelService:    newFakeReembedModelService("embedding-model"),
+			graphEngine:     &fakeProcessGraphRepo{},
+			task:            asynq.NewClient(asynq.RedisClientOpt{Addr: "127.0.0.1:1"}),
+		}
+
+		payload, err := json.Marshal(types.DocumentProcessPayload{
+			TenantID: 1, KnowledgeID: knowledge.ID, KnowledgeBaseID: "kb1",
+			FilePath: knowledge.FilePath, FileName: knowledge.FileName, FileType: knowledge.FileType, SkipSummary: true,
+		})
+		if err != nil {
+			t.Fatalf("marshal payload: %v", err)
+		}
+		if err := svc.ProcessDocument(ctx, asynq.NewTask(types.TypeDocumentProcess, payload)); err != nil {
+			t.Fatalf("ProcessDocument() error = %v", err)
+		}
+		svc.task.Close()
+
+		if len(docReader.requests) != 1 {
+			t.Fatalf("expected 1 docreader request, got %d", len(docReader.requests))
+		}
+		readConfig := docReader.requests[0].ReadConfig
+		if readConfig.ParseTimeoutSeconds != 300 || readConfig.AllowPartialResult != accept {
+			t.Fatalf("unexpected read config timeout=%d allow_partial=%v",
+				readConfig.ParseTimeoutSeconds, readConfig.AllowPartialResult)
+		}
+		got := repo.knowledge[knowledge.ID]
+		if !accept {
+			if got.ParseStatus != types.ParseStatusFailed || len(chunkService.chunks) != 0 {
+				t.Fatalf("expected the partial result to be rejected, got %q with %d chunks",
+					got.ParseStatus, len(chunkService.chunks))
+			}
+			continue
+		}
+		if got.ParseStatus != types.ParseStatusCompleted || len(chunkService.chunks) != 2 {
+			t.Fatalf("expected the partial result to complete with 2 chunks, got %q with %d chunks",
+				got.ParseStatus, len(chunkService.chunks))
+		}
+		if !strings.Contains(got.ParseWarning, "解析超时") {
+			t.Fatalf("expected a parse timeout warning, got %q", got.ParseWarning)
+		}
+	}
+}
+
+func TestEscalateParseTimeout(t *testing.T) {
+	cases := []struct {
+		timeout, retryCount int
+		want                int32
+	}{
+		{timeout: 0, retryCount: 3, want: 0},
+		{timeout: 300, retryCount: 0, want: 300},
+		{timeout: 300, retryCount: 2, want: 1200},
+		{timeout: 300, retryCount: 10, want: types.MaxParseTimeoutSeconds},
+	}
+	for _, c := range cases {
+		readConfig := &proto.ReadConfig{ParseTimeoutSeconds: int32(c.timeout)}
+		escalateParseTimeout(readConfig, c.retryCount)
+		if readConfig.ParseTimeoutSeconds != c.want {
+			t.Fatalf("escalateParseTimeout(%d, %d) = %d, want %d",
+				c.timeout, c.retryCount, readConfig.ParseTimeoutSeconds, c.want)
+		}
+	}
+}
+
+func TestKnowledgeLockHeldByProcessing(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	knowledge := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusProcessing,
+	}
+	repo := newFakeKnowledgeRepo(knowledge)
+	redisClient, _ := newFakeRedisClient()
+	svc := &knowledgeService{
+		repo:         repo,
+		tenantRepo:   &fakeStorageTenantRepo{tenant: tenant},
+		chunkService: &fakeProcessChunkService{},
+		modelService: newFakeReembedModelService("embedding-model"),
+		graphEngine:  &fakeProcessGraphRepo{},
+		redisClient:  redisClient,
+	}
+
+	// The reparse hands its lock off to the document processing task
+	lock, err := svc.lockKnowledge(ctx, knowledge.ID)
+	if err != nil {
+		t.Fatalf("lockKnowledge() error = %v", err)
+	}
+	token := lock.HandOff()
+	lock.Unlock()
+
+	expiresAt := time.Now().Add(time.Hour)
+	_, err = svc.SetKnowledgeExpiry(ctx, knowledge.ID, &expiresAt)
+	if appErr, ok := werrors.IsAppError(err); !ok || appErr.Code != werrors.ErrConflict {
+		t.Fatalf("expected a conflict while processing holds the lock, got %v", err)
+	}
+	if _, err := svc.CancelKnowledgeProcessing(ctx, knowledge.ID); err != nil {
+		t.Fatalf("expected cancel to override the processing lock, got %v", err)
+	}
+
+	// The task stops at the cancellation and releases the lock
+	payload, _ := json.Marshal(types.DocumentProcessPayload{
+		TenantID: 1, KnowledgeID: knowledge.ID, KnowledgeBaseID: "kb1", FilePath: "local://a.pdf", LockToken: token,
+	})
+	if err := svc.ProcessDocument(ctx, asynq.NewTask(types.TypeDocumentProcess, payload)); err != nil {
+		t.Fatalf("ProcessDocument() error = %v", err)
+	}
+	if _, err := svc.SetKnowledgeExpiry(ctx, knowledge.ID, &expiresAt); err != nil {
+		t.Fatalf("expected the lock to be released after processing, got %v", err)
+	}
+
+	// Without Redis the operation is refused rather than run unlocked
+	svc.redisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	_, err = svc.SetKnowledgeExpiry(ctx, knowledge.ID, &expiresAt)
+	if appErr, ok := werrors.IsAppError(err); !ok || appErr.Code != werrors.ErrInternalServer {
+		t.Fatalf("expected an internal error when Redis is unreachable, got %v", err)
+	}
+}
+
+func TestDeleteAllKnowledgeInKBLocks(t *testing.T) {
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	knowledge := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusCompleted,
+	}
+	repo := newFakeKnowledgeRepo(knowledge)
+	redisClient, store := newFakeRedisClient()
+	svc := &knowledgeService{
+		repo:        repo,
+		tenantRepo:  &fakeStorageTenantRepo{tenant: tenant},
+		kbService:   &fakeTagKBService{kb: &types.KnowledgeBase{ID: "kb1", TenantID: 1}},
+		redisClient: redisClient,
+	}
+
+	// A knowledge locked by another operation, e.g. a move, rejects the reset
+	lock, err := svc.lockKnowledge(ctx, knowledge.ID)
+	if err != nil {
+		t.Fatalf("lockKnowledge() error = %v", err)
+	}
+	_, err = svc.DeleteAllKnowledgeInKB(ctx, "kb1")
+	if !isKnowledgeLockConflict(err) {
+		t.Fatalf("expected a conflict while the knowledge is locked, got %v", err)
+	}
+	lock.Unlock()
+	if knowledge.ParseStatus != types.ParseStatusCompleted {
+		t.Fatalf("expected the rejected reset to leave the status alone, got %q", knowledge.ParseStatus)
+	}
+	if _, ok := store.values[getKBReadOnlyKey("kb1")]; ok {
+		t.Fatal("expected the read-only mark to be lifted after the rejected reset")
+	}
+
+	// Without Redis the reset is refused rather than run without the read-only mark
+	svc.redisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	_, err = svc.DeleteAllKnowledgeInKB(ctx, "kb1")
+	if appErr, ok := werrors.IsAppError(err); !ok || appErr.Code != werrors.ErrInternalServer {
+		t.Fatalf("expected an internal error when Redis is unreachable, got %v", err)
+	}
+}
+
+// fakeRetrieveEngine records the index operations issued by the knowledge service
+type fakeRetrieveEngine struct {
+	interfaces.RetrieveEngineService
+	mu       sync.Mutex
+	ops      []string
+	indexed  []*types.IndexInfo
+	disabled map[string]bool
+	indexErr error
+	// retrieveTopK records the TopK of every retrieval
+	retrieveTopK []int
+}
+
+func (e *fakeRetrieveEngine) EngineType() types.RetrieverEngineType {
+	return types.PostgresRetrieverEngineType
+}
+
+func (e *fakeRetrieveEngine) Support() []types.RetrieverType {
+	return []types.RetrieverType{types.VectorRetrieverType}
+}
+
+func (e *fakeRetrieveEngine) BatchIndex(_ context.Context,
+	_ embedding.Embedder, indexInfoList []*types.IndexInfo, _ []types.RetrieverType,
+) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ops = append(e.ops, "index")
+	if e.indexErr != nil {
+		return e.indexErr
+	}
+	e.indexed = append(e.indexed, indexInfoList...)
+	return nil
+}
+
+func (e *fakeRetrieveEngine) EstimateStorageSize(_ context.Context,
+	_ embedding.Embedder, _ []*types.IndexInfo, _ []types.RetrieverType,
+) int64 {
+	return 0
+}
+
+func (e *fakeRetrieveEngine) DeleteByKnowledgeIDList(_ context.Context, _ []string, _ int, _ string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ops = append(e.ops, "delete_knowledge")
+	return nil
+}
+
+func (e *fakeRetrieveEngine) DeleteByChunkIDList(_ context.Context, _ []string, _ int, _ string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ops = append(e.ops, "delete_chunks")
+	return nil
+}
+
+func (e *fakeRetrieveEngine) BatchUpdateChunkEnabledStatus(_ context.Context, chunkStatusMap map[string]bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ops = append(e.ops, "update_enabled")
+	if e.disabled == nil {
+		e.disabled = make(map[string]bool)
+	}
+	for id, enabled := range chunkStatusMap {
+		e.disabled[id] = !enabled
+	}
+	return nil
+}
+
+func (e *fakeRetrieveEngine) Retrieve(_ context.Context, params types.RetrieveParams) ([]*types.RetrieveResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.retrieveTopK = append(e.retrieveTopK, params.TopK)
+	return nil, nil
+}
+
+// indexedChunkIDs returns the sorted chunk IDs of the indexed entries
+func (e *fakeRetrieveEngine) indexedChunkIDs() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := make([]string, 0, len(e.indexed))
+	for _, info := range e.indexed {
+		ids = append(ids, info.ChunkID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+type fakeRetrieveEngineRegistry struct {
+	interfaces.RetrieveEngineRegistry
+	engine *fakeRetrieveEngine
+}
+
+func (r *fakeRetrieveEngineRegistry) GetRetrieveEngineService(
+	_ types.RetrieverEngineType,
+) (interfaces.RetrieveEngineService, error) {
+	return r.engine, nil
+}
+
+// fakeRetrieveEngineTenant returns a tenant whose index is stored in the fake retrieve engine
+func fakeRetrieveEngineTenant(id uint64) *types.Tenant {
+	tenant := &types.Tenant{ID: id}
+	tenant.RetrieverEngines.Engines = []types.RetrieverEngineParams{{
+		RetrieverEngineType: types.PostgresRetrieverEngineType,
+		RetrieverType:       types.VectorRetrieverType,
+	}}
+	return tenant
+}
+
+func TestReindexKnowledge(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	// The knowledge base is shared: the caller's tenant has no engines, the index lives with the owner
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, &types.Tenant{ID: 2})
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	knowledge := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusCompleted,
+	}
+	chunks := []*types.Chunk{
+		{ID: "c1", KnowledgeID: "k1", ChunkType: types.ChunkTypeText, Content: "text", IsEnabled: true},
+		{ID: "c2", KnowledgeID: "k1", ChunkType: types.ChunkTypeSummary, Content: "summary", IsEnabled: true},
+		{ID: "c3", KnowledgeID: "k1", ChunkType: types.ChunkTypeEntity, Content: "entity", IsEnabled: true},
+		{ID: "c4", KnowledgeID: "k1", ChunkType: types.ChunkTypeText, Content: "disabled", IsEnabled: false},
+	}
+	chunkService := &fakeProcessChunkService{chunks: chunks}
+	engine := &fakeRetrieveEngine{}
+	// No docreader client or file service: reindexing must not parse the document again
+	svc := &knowledgeService{
+		repo: newFakeKnowledgeRepo(knowledge),
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+		}},
+		chunkService:   chunkService,
+		modelService:   newFakeReembedModelService("embedding-model"),
+		tenantRepo:     &fakeStorageTenantRepo{tenant: fakeRetrieveEngineTenant(1)},
+		retrieveEngine: &fakeRetrieveEngineRegistry{engine: engine},
+	}
+
+	reindexed, err := svc.ReindexKnowledge(ctx, knowledge.ID)
+	if err != nil {
+		t.Fatalf("ReindexKnowledge() error = %v", err)
+	}
+	if reindexed.EmbeddingModelID != "embedding-model" {
+		t.Fatalf("expected missing embedding model to fall back to the knowledge base's, got %q",
+			reindexed.EmbeddingModelID)
+	}
+	if len(chunkService.chunks) != len(chunks) {
+		t.Fatalf("expected chunks to be kept, got %d", len(chunkService.chunks))
+	}
+	if got := engine.indexedChunkIDs(); !slices.Equal(got, []string{"c1", "c2", "c4"}) {
+		t.Fatalf("expected text and summary chunks to be indexed without graph chunks, got %v", got)
+	}
+	for _, info := range engine.indexed {
+		if info.IsEnabled != (info.ChunkID != "c4") {
+			t.Fatalf("expected only the disabled chunk to be indexed disabled, got %s enabled=%v",
+				info.ChunkID, info.IsEnabled)
+		}
+	}
+	if slices.Contains(engine.ops, "update_enabled") {
+		t.Fatalf("expected enabled status to be written with the index, got %v", engine.ops)
+	}
+
+	knowledge.ParseStatus = types.ParseStatusProcessing
+	if _, err := svc.ReindexKnowledge(ctx, knowledge.ID); err == nil {
+		t.Fatal("expected reindexing a knowledge that is still processing to fail")
+	}
+}
+
+func TestProcessKnowledgeReindex(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	completed := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusCompleted,
+		EmbeddingModelID: "embedding-model",
+	}
+	pending := &types.Knowledge{
+		ID: "k2", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusPending,
+	}
+	engine := &fakeRetrieveEngine{}
+	redisClient, _ := newFakeRedisClient()
+	svc := &knowledgeService{
+		repo: newFakeKnowledgeRepo(completed, pending),
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+		}},
+		chunkService: &fakeProcessChunkService{chunks: []*types.Chunk{
+			{ID: "c1", KnowledgeID: "k1", ChunkType: types.ChunkTypeText, Content: "text", IsEnabled: true},
+		}},
+		modelService:   newFakeReembedModelService("embedding-model"),
+		tenantRepo:     &fakeStorageTenantRepo{tenant: fakeRetrieveEngineTenant(1)},
+		retrieveEngine: &fakeRetrieveEngineRegistry{engine: engine},
+		redisClient:    redisClient,
+	}
+
+	payload, _ := json.Marshal(types.KnowledgeReindexPayload{TenantID: 1, TaskID: "task-1", KBID: "kb1"})
+	if err := svc.ProcessKnowledgeReindex(context.Background(),
+		asynq.NewTask(types.TypeKnowledgeReindex, payload)); err != nil {
+		t.Fatalf("ProcessKnowledgeReindex() error = %v", err)
+	}
+	if got := engine.indexedChunkIDs(); !slices.Equal(got, []string{"c1"}) {
+		t.Fatalf("expected only the parsed knowledge to be reindexed, got %v", got)
+	}
+}
+
+type fakeReembedEmbedder struct {
+	embedding.Embedder
+	id  string
+	err error
+}
+
+func (e *fakeReembedEmbedder) GetDimensions() int { return 8 }
+
+func (e *fakeReembedEmbedder) GetModelID() string { return e.id }
+
+func (e *fakeReembedEmbedder) GetModelName() string { return e.id }
+
+func (e *fakeReembedEmbedder) Embed(_ context.Context, _ string) ([]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return make([]float32, 8), nil
+}
+
+func (e *fakeReembedEmbedder) BatchEmbedWithPool(_ context.Context,
+	_ embedding.Embedder, texts []string,
+) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return make([][]float32, len(texts)), nil
+}
+
+type fakeReembedModelService struct {
+	interfaces.ModelService
+	models map[string]embedding.Embedder
+}
+
+// newFakeReembedModelService serves an embedding model for each of ids
+func newFakeReembedModelService(ids ...string) *fakeReembedModelService {
+	models := make(map[string]embedding.Embedder, len(ids))
+	for _, id := range ids {
+		models[id] = &fakeReembedEmbedder{id: id}
+	}
+	return &fakeReembedModelService{models: models}
+}
+
+func (s *fakeReembedModelService) GetEmbeddingModel(_ context.Context, id string) (embedding.Embedder, error) {
+	if model, ok := s.models[id]; ok {
+		return model, nil
+	}
+	return nil, fmt.Errorf("model %s not found", id)
+}
+
+func (s *fakeReembedModelService) GetModelByID(_ context.Context, id string) (*types.Model, error) {
+	return nil, fmt.Errorf("model %s not found", id)
+}
+
+func TestProcessKnowledgeReembed(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+
+	newService := func(newModel *fakeReembedEmbedder) (*knowledgeService, *fakeRetrieveEngine, *types.Knowledge) {
+		knowledge := &types.Knowledge{
+			ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusCompleted,
+			EmbeddingModelID: "old-model",
+		}
+		engine := &fakeRetrieveEngine{}
+		return &knowledgeService{
+			repo: newFakeKnowledgeRepo(knowledge),
+			kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+				ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument,
+			}},
+			chunkService: &fakeProcessChunkService{chunks: []*types.Chunk{
+				{ID: "c1", KnowledgeID: "k1", ChunkType: types.ChunkTypeText, Content: "text", IsEnabled: true},
+				{ID: "c2", KnowledgeID: "k1", ChunkType: types.ChunkTypeText, Content: "off", IsEnabled: false},
+			}},
+			modelService: &fakeReembedModelService{models: map[string]embedding.Embedder{
+				"old-model": &fakeReembedEmbedder{id: "old-model"},
+				"new-model": newModel,
+			}},
+			tenantRepo:     &fakeStorageTenantRepo{tenant: fakeRetrieveEngineTenant(1)},
+			retrieveEngine: &fakeRetrieveEngineRegistry{engine: engine},
+		}, engine, knowledge
+	}
+	payload, _ := json.Marshal(types.KnowledgeReembedPayload{TenantID: 1, KnowledgeID: "k1", ModelID: "new-model"})
+	task := asynq.NewTask(types.TypeKnowledgeReembed, payload)
+
+	t.Run("replaces the old index", func(t *testing.T) {
+		svc, engine, knowledge := newService(&fakeReembedEmbedder{id: "new-model"})
+		if err := svc.ProcessKnowledgeReembed(context.Background(), task); err != nil {
+			t.Fatalf("ProcessKnowledgeReembed() error = %v", err)
+		}
+		if !slices.Equal(engine.ops, []string{"delete_knowledge", "index"}) {
+			t.Fatalf("expected the old index to be replaced, got %v", engine.ops)
+		}
+		if got := engine.indexedChunkIDs(); !slices.Equal(got, []string{"c1", "c2"}) {
+			t.Fatalf("expected all chunks to be indexed, got %v", got)
+		}
+		for _, info := range engine.indexed {
+			if info.IsEnabled != (info.ChunkID == "c1") {
+				t.Fatalf("expected chunk %s to keep its enabled status, got %v", info.ChunkID, info.IsEnabled)
+			}
+		}
+		if knowledge.EmbeddingModelID != "new-model" {
+			t.Fatalf("expected embedding model to be switched, got %q", knowledge.EmbeddingModelID)
+		}
+	})
+
+	t.Run("embedding failure keeps the old index", func(t *testing.T) {
+		svc, engine, knowledge := newService(&fakeReembedEmbedder{id: "new-model", err: errors.New("quota exceeded")})
+		if err := svc.ProcessKnowledgeReembed(context.Background(), task); err == nil {
+			t.Fatal("expected embedding failure to be retried")
+		}
+		if len(engine.ops) != 0 {
+			t.Fatalf("expected the old index to be untouched, got %v", engine.ops)
+		}
+		if knowledge.EmbeddingModelID != "old-model" {
+			t.Fatalf("expected embedding model to be kept, got %q", knowledge.EmbeddingModelID)
+		}
+	})
+
+	t.Run("write failure restores the old index", func(t *testing.T) {
+		svc, engine, knowledge := newService(&fakeReembedEmbedder{id: "new-model"})
+		engine.indexErr = errors.New("vector store unavailable")
+		if err := svc.ProcessKnowledgeReembed(context.Background(), task); err == nil {
+			t.Fatal("expected write failure to be retried")
+		}
+		if !slices.Equal(engine.ops, []string{"delete_knowledge", "index", "delete_knowledge", "index"}) {
+			t.Fatalf("expected partial index cleanup and restore with the old model, got %v", engine.ops)
+		}
+		if knowledge.EmbeddingModelID != "old-model" {
+			t.Fatalf("expected embedding model to be kept, got %q", knowledge.EmbeddingModelID)
+		}
+	})
+}
+
+func TestProcessEmbeddingModelAlign(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+
+	misaligned := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusCompleted,
+		EmbeddingModelID: "old-model",
+	}
+	aligned := &types.Knowledge{
+		ID: "k2", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusCompleted,
+		EmbeddingModelID: "new-model",
+	}
+	redisClient, _ := newFakeRedisClient()
+	svc := &knowledgeService{
+		repo: newFakeKnowledgeRepo(misaligned, aligned),
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "new-model",
+		}},
+		chunkService: &fakeProcessChunkService{chunks: []*types.Chunk{
+			{ID: "c1", KnowledgeID: "k1", ChunkType: types.ChunkTypeText, Content: "text", IsEnabled: true},
+		}},
+		modelService:   newFakeReembedModelService("old-model", "new-model"),
+		tenantRepo:     &fakeStorageTenantRepo{tenant: fakeRetrieveEngineTenant(1)},
+		retrieveEngine: &fakeRetrieveEngineRegistry{engine: &fakeRetrieveEngine{}},
+		redisClient:    redisClient,
+	}
+
+	payload, _ := json.Marshal(types.EmbeddingAlignPayload{TenantID: 1, TaskID: "task-1", KBID: "kb1"})
+	if err := svc.ProcessEmbeddingModelAlign(context.Background(),
+		asynq.NewTask(types.TypeEmbeddingAlign, payload)); err != nil {
+		t.Fatalf("ProcessEmbeddingModelAlign() error = %v", err)
+	}
+	if misaligned.EmbeddingModelID != "new-model" {
+		t.Fatalf("expected the misaligned knowledge to be re-embedded, got %q", misaligned.EmbeddingModelID)
+	}
+	progress, err := svc.GetEmbeddingModelAlignProgress(context.Background(), "task-1")
+	if err != nil {
+		t.Fatalf("GetEmbeddingModelAlignProgress() error = %v", err)
+	}
+	if progress.Status != types.KnowledgeTaskStatusCompleted || progress.TenantID != 1 ||
+		progress.EmbeddingModelID != "new-model" || progress.Total != 1 || progress.Reembedded != 1 {
+		t.Fatalf("unexpected progress %+v", progress)
+	}
+}
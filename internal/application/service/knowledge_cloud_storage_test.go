@@ -0,0 +1,108 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestParseCloudStoragePath(t *testing.T) {
+	tests := []struct {
+		path                string
+		scheme, bucket, key string
+		wantErr             bool
+	}{
+		{path: "s3://team-docs/handbook/a.pdf", scheme: "s3", bucket: "team-docs", key: "handbook/a.pdf"},
+		{path: " COS://docs-1250000000/faq/ ", scheme: "cos", bucket: "docs-1250000000", key: "faq/"},
+		{path: "s3://team-docs", scheme: "s3", bucket: "team-docs", key: ""},
+		{path: "team-docs/a.pdf", wantErr: true},
+		{path: "gs://team-docs/a.pdf", wantErr: true},
+		{path: "s3://Team_Docs/a.pdf", wantErr: true},
+		{path: "s3://a..b/a.pdf", wantErr: true},
+		{path: "s3://team-docs/../other/a.pdf", wantErr: true},
+		{path: "s3://team-docs/a\r\n.pdf", wantErr: true},
+	}
+	for _, tt := range tests {
+		scheme, bucket, key, err := parseCloudStoragePath(tt.path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseCloudStoragePath(%q) expected error", tt.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCloudStoragePath(%q) error = %v", tt.path, err)
+			continue
+		}
+		if scheme != tt.scheme || bucket != tt.bucket || key != tt.key {
+			t.Errorf("parseCloudStoragePath(%q) = %q %q %q, want %q %q %q",
+				tt.path, scheme, bucket, key, tt.scheme, tt.bucket, tt.key)
+		}
+	}
+}
+
+func TestNewCloudObjectSourceValidation(t *testing.T) {
+	useSSL := false
+	tests := []struct {
+		name    string
+		scheme  string
+		creds   *types.CloudStorageCredentials
+		message string
+	}{
+		{name: "no credentials", scheme: "s3", message: "访问凭证不完整"},
+		{name: "missing key", scheme: "s3",
+			creds: &types.CloudStorageCredentials{SecretID: "id"}, message: "访问凭证不完整"},
+		{name: "invalid region", scheme: "s3",
+			creds:   &types.CloudStorageCredentials{SecretID: "id", SecretKey: "key", Region: "us-east-1.evil.com/"},
+			message: "地域名称不合法"},
+		{name: "cos without region", scheme: "cos",
+			creds: &types.CloudStorageCredentials{SecretID: "id", SecretKey: "key"}, message: "region"},
+		{name: "endpoint with scheme", scheme: "s3",
+			creds:   &types.CloudStorageCredentials{SecretID: "id", SecretKey: "key", Endpoint: "http://minio:9000"},
+			message: "endpoint 格式错误"},
+		{name: "loopback endpoint", scheme: "s3",
+			creds:   &types.CloudStorageCredentials{SecretID: "id", SecretKey: "key", Endpoint: "localhost:9000"},
+			message: "endpoint 不可访问"},
+		{name: "private ip endpoint", scheme: "s3",
+			creds: &types.CloudStorageCredentials{
+				SecretID: "id", SecretKey: "key", Endpoint: "10.0.0.8:9000", UseSSL: &useSSL,
+			},
+			message: "endpoint 不可访问"},
+	}
+	for _, tt := range tests {
+		_, err := newCloudObjectSource(tt.scheme, "team-docs", tt.creds)
+		appErr, ok := werrors.IsAppError(err)
+		if !ok || appErr.Code != werrors.ErrBadRequest || !strings.Contains(appErr.Message, tt.message) {
+			t.Errorf("%s: expected bad request containing %q, got %v", tt.name, tt.message, err)
+		}
+	}
+}
+
+func TestTaskSecretRoundTrip(t *testing.T) {
+	original := apiKeySecret
+	apiKeySecret = func() []byte { return []byte("0123456789abcdef0123456789abcdef") }
+	defer func() { apiKeySecret = original }()
+
+	creds := &types.CloudStorageCredentials{SecretID: "id", SecretKey: "key", Region: "ap-guangzhou"}
+	sealed, err := sealTaskSecret(creds)
+	if err != nil {
+		t.Fatalf("sealTaskSecret() error = %v", err)
+	}
+	if strings.Contains(sealed, "ap-guangzhou") {
+		t.Fatalf("sealed secret leaks plain text: %s", sealed)
+	}
+	var opened types.CloudStorageCredentials
+	if err := openTaskSecret(sealed, &opened); err != nil {
+		t.Fatalf("openTaskSecret() error = %v", err)
+	}
+	if opened.SecretID != "id" || opened.SecretKey != "key" || opened.Region != "ap-guangzhou" {
+		t.Fatalf("unexpected credentials %+v", opened)
+	}
+	tampered := []byte(sealed)
+	tampered[len(tampered)/2] ^= 1
+	if err := openTaskSecret(string(tampered), &opened); err == nil {
+		t.Fatal("expected tampered secret to be rejected")
+	}
+}
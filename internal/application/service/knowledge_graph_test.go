@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/tracing"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedisStore answers GET, SET, EXISTS and the knowledge lock scripts from memory so task progress
+// and locking can be checked without a Redis server
+type fakeRedisStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// fakeRedisError is a server reply error, so go-redis falls back from EVALSHA to EVAL on NOSCRIPT
+type fakeRedisError string
+
+func (e fakeRedisError) Error() string { return string(e) }
+
+func (e fakeRedisError) RedisError() {}
+
+func newFakeRedisClient() (*redis.Client, *fakeRedisStore) {
+	store := &fakeRedisStore{values: make(map[string]string)}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	client.AddHook(store)
+	return client, store
+}
+
+func (s *fakeRedisStore) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (s *fakeRedisStore) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (s *fakeRedisStore) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		args := cmd.Args()
+		switch cmd.Name() {
+		case "set":
+			key := args[1].(string)
+			if boolCmd, ok := cmd.(*redis.BoolCmd); ok && slices.Contains(args, any("nx")) {
+				if _, exists := s.values[key]; exists {
+					boolCmd.SetVal(false)
+					return nil
+				}
+				boolCmd.SetVal(true)
+			}
+			switch v := args[2].(type) {
+			case []byte:
+				s.values[key] = string(v)
+			default:
+				s.values[key] = fmt.Sprint(v)
+			}
+			if statusCmd, ok := cmd.(*redis.StatusCmd); ok {
+				statusCmd.SetVal("OK")
+			}
+		case "evalsha":
+			err := fakeRedisError("NOSCRIPT No matching script")
+			cmd.SetErr(err)
+			return err
+		case "eval":
+			// Only the compare-and-delete and compare-and-expire scripts of the knowledge lock are supported
+			key, token := args[3].(string), fmt.Sprint(args[4])
+			var result int64
+			if s.values[key] == token {
+				if strings.Contains(args[1].(string), "DEL") {
+					delete(s.values, key)
+				}
+				result = 1
+			}
+			cmd.(*redis.Cmd).SetVal(result)
+		case "exists":
+			var count int64
+			for _, key := range args[1:] {
+				if _, ok := s.values[key.(string)]; ok {
+					count++
+				}
+			}
+			cmd.(*redis.IntCmd).SetVal(count)
+		case "get":
+			v, ok := s.values[args[1].(string)]
+			if !ok {
+				cmd.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			cmd.(*redis.StringCmd).SetVal(v)
+		default:
+			return fmt.Errorf("unsupported command %s", cmd.Name())
+		}
+		return nil
+	}
+}
+
+// fakeGraphChatModel answers graph extraction with one entity, and fails for content containing "fail"
+type fakeGraphChatModel struct {
+	fakeFailingChatModel
+}
+
+func (m *fakeGraphChatModel) Chat(_ context.Context, messages []chat.Message, _ *chat.ChatOptions) (*types.ChatResponse, error) {
+	if strings.Contains(messages[len(messages)-1].Content, "fail") {
+		return nil, errors.New("model unavailable")
+	}
+	return &types.ChatResponse{Content: "```json\n[{\"entity\": \"彗星\"}]\n```"}, nil
+}
+
+type fakeGraphModelService struct {
+	interfaces.ModelService
+}
+
+func (s *fakeGraphModelService) GetChatModel(_ context.Context, _ string) (chat.Chat, error) {
+	return &fakeGraphChatModel{}, nil
+}
+
+type fakeRebuildGraphRepo struct {
+	interfaces.RetrieveGraphRepository
+	mu      sync.Mutex
+	deleted []types.NameSpace
+	added   []string
+}
+
+func (r *fakeRebuildGraphRepo) DelGraph(_ context.Context, namespaces []types.NameSpace) error {
+	r.deleted = append(r.deleted, namespaces...)
+	return nil
+}
+
+func (r *fakeRebuildGraphRepo) AddGraph(_ context.Context, namespace types.NameSpace, graphs []*types.GraphData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, graph := range graphs {
+		for _, node := range graph.Node {
+			r.added = append(r.added, namespace.Knowledge+"/"+strings.Join(node.Chunks, ","))
+		}
+	}
+	return nil
+}
+
+func TestProcessKnowledgeGraphRebuild(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	completed := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusCompleted,
+	}
+	pending := &types.Knowledge{
+		ID: "k2", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", ParseStatus: types.ParseStatusPending,
+	}
+	graphRepo := &fakeRebuildGraphRepo{}
+	redisClient, _ := newFakeRedisClient()
+	svc := &knowledgeService{
+		config: &config.Config{ExtractManager: &config.ExtractManagerConfig{
+			ExtractGraph: &types.PromptTemplateStructured{Description: "extract"},
+		}},
+		repo: newFakeKnowledgeRepo(completed, pending),
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument,
+			ExtractConfig: &types.ExtractConfig{Enabled: true},
+		}},
+		chunkService: &fakeProcessChunkService{chunks: []*types.Chunk{
+			{ID: "c1", KnowledgeID: "k1", KnowledgeBaseID: "kb1", ChunkType: types.ChunkTypeText, Content: "彗星"},
+			{ID: "c2", KnowledgeID: "k1", KnowledgeBaseID: "kb1", ChunkType: types.ChunkTypeText, Content: "fail"},
+			{ID: "c3", KnowledgeID: "k1", KnowledgeBaseID: "kb1", ChunkType: types.ChunkTypeImageCaption},
+		}},
+		modelService: &fakeGraphModelService{},
+		tenantRepo:   &fakeStorageTenantRepo{tenant: &types.Tenant{ID: 1}},
+		graphEngine:  graphRepo,
+		redisClient:  redisClient,
+	}
+
+	payload, _ := json.Marshal(types.GraphRebuildPayload{TenantID: 1, TaskID: "task-1", KBID: "kb1"})
+	if err := svc.ProcessKnowledgeGraphRebuild(context.Background(),
+		asynq.NewTask(types.TypeGraphRebuild, payload)); err != nil {
+		t.Fatalf("ProcessKnowledgeGraphRebuild() error = %v", err)
+	}
+
+	if len(graphRepo.deleted) != 2 {
+		t.Fatalf("expected the graph of every knowledge to be deleted, got %v", graphRepo.deleted)
+	}
+	if !slices.Equal(graphRepo.added, []string{"k1/c1"}) {
+		t.Fatalf("expected the graph of the text chunk to be added, got %v", graphRepo.added)
+	}
+	progress, err := svc.GetKnowledgeGraphRebuildProgress(context.Background(), "task-1")
+	if err != nil {
+		t.Fatalf("GetKnowledgeGraphRebuildProgress() error = %v", err)
+	}
+	if progress.Status != types.KnowledgeTaskStatusCompleted || progress.TenantID != 1 || progress.KBID != "kb1" {
+		t.Fatalf("unexpected progress %+v", progress)
+	}
+	if progress.Knowledge != 1 || progress.Total != 2 || progress.Processed != 2 || progress.Failed != 1 {
+		t.Fatalf("expected both text chunks to be extracted with one failure, got %+v", progress)
+	}
+}
+
+func TestProcessKnowledgeGraphRebuildExtractionNotEnabled(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	graphRepo := &fakeRebuildGraphRepo{}
+	redisClient, _ := newFakeRedisClient()
+	svc := &knowledgeService{
+		repo: newFakeKnowledgeRepo(),
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument,
+		}},
+		tenantRepo:  &fakeStorageTenantRepo{tenant: &types.Tenant{ID: 1}},
+		graphEngine: graphRepo,
+		redisClient: redisClient,
+	}
+
+	payload, _ := json.Marshal(types.GraphRebuildPayload{TenantID: 1, TaskID: "task-1", KBID: "kb1"})
+	if err := svc.ProcessKnowledgeGraphRebuild(context.Background(),
+		asynq.NewTask(types.TypeGraphRebuild, payload)); err != nil {
+		t.Fatalf("ProcessKnowledgeGraphRebuild() error = %v", err)
+	}
+	if len(graphRepo.deleted) != 0 {
+		t.Fatalf("expected the graph to be kept, got deletes %v", graphRepo.deleted)
+	}
+	progress, err := svc.GetKnowledgeGraphRebuildProgress(context.Background(), "task-1")
+	if err != nil {
+		t.Fatalf("GetKnowledgeGraphRebuildProgress() error = %v", err)
+	}
+	if progress.Status != types.KnowledgeTaskStatusFailed {
+		t.Fatalf("expected the task to fail, got %+v", progress)
+	}
+}
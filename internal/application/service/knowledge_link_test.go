@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/tracing"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestExtractChunkLinks(t *testing.T) {
+	content := "参见[价格页](https://Example.com/Pricing#plans \"title\")和![图](https://example.com/a.png)。\n" +
+		"<a href=\"https://docs.example.com/guide\">使用<b>指南</b></a>，" +
+		"更多信息：https://example.com/pricing, 以及 ftp://example.com/file 和 [相对链接](/docs)。\n" +
+		"再次引用 https://docs.example.com/guide"
+
+	want := []types.ChunkLink{
+		{URL: "https://example.com/Pricing", AnchorText: "价格页"},
+		{URL: "https://docs.example.com/guide", AnchorText: "使用指南"},
+		{URL: "https://example.com/pricing"},
+	}
+	if got := extractChunkLinks(content); !slices.Equal(got, want) {
+		t.Errorf("extractChunkLinks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildChunkLinkIndexInfoList(t *testing.T) {
+	chunk := &types.Chunk{ID: "chunk-1", ChunkType: types.ChunkTypeText}
+	if err := chunk.SetDocumentMetadata(&types.DocumentChunkMetadata{Links: []types.ChunkLink{
+		{URL: "https://example.com/pricing", AnchorText: "价格页"},
+		{URL: "https://example.com/raw"},
+	}}); err != nil {
+		t.Fatalf("SetDocumentMetadata() error = %v", err)
+	}
+
+	kb := &types.KnowledgeBase{}
+	if infos := buildChunkLinkIndexInfoList(kb, chunk); len(infos) != 0 {
+		t.Fatalf("expected no link index entries when disabled, got %d", len(infos))
+	}
+
+	kb.ChunkingConfig.IndexLinkAnchors = true
+	infos := buildChunkLinkIndexInfoList(kb, chunk)
+	if len(infos) != 1 {
+		t.Fatalf("expected 1 link index entry, got %d", len(infos))
+	}
+	if infos[0].SourceID != "chunk-1-link-0" || infos[0].ChunkID != "chunk-1" {
+		t.Errorf("unexpected link index entry: %+v", infos[0])
+	}
+}
+
+func TestUpdateChunkVectorReindexesLinks(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := fakeRetrieveEngineTenant(1)
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	chunk := &types.Chunk{
+		ID: "c1", KnowledgeID: "k1", KnowledgeBaseID: "kb1", ChunkType: types.ChunkTypeText, IsEnabled: true,
+		Content: "参见[旧页面](https://example.com/old)",
+	}
+	if err := chunk.SetDocumentMetadata(&types.DocumentChunkMetadata{Links: extractChunkLinks(chunk.Content)}); err != nil {
+		t.Fatalf("SetDocumentMetadata() error = %v", err)
+	}
+	chunkService := &fakeProcessChunkService{chunks: []*types.Chunk{chunk}}
+	engine := &fakeRetrieveEngine{}
+	kb := &types.KnowledgeBase{ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model"}
+	kb.ChunkingConfig.IndexLinkAnchors = true
+	svc := &knowledgeService{
+		kbService:      &fakeTagKBService{kb: kb},
+		chunkService:   chunkService,
+		modelService:   newFakeReembedModelService("embedding-model"),
+		retrieveEngine: &fakeRetrieveEngineRegistry{engine: engine},
+	}
+
+	// The chunk is edited: the old link is replaced by two new ones
+	chunk.Content = "参见[价格页](https://example.com/pricing)和[指南](https://example.com/guide)"
+	if err := svc.updateChunkVector(ctx, "kb1", []*types.Chunk{chunk}); err != nil {
+		t.Fatalf("updateChunkVector() error = %v", err)
+	}
+
+	indexed := make(map[string]string)
+	for _, info := range engine.indexed {
+		indexed[info.SourceID] = info.Content
+	}
+	sourceIDs := slices.Sorted(maps.Keys(indexed))
+	if want := []string{"c1", "c1-link-0", "c1-link-1"}; !slices.Equal(sourceIDs, want) {
+		t.Fatalf("expected the chunk and its new link anchors to be indexed, got %v", sourceIDs)
+	}
+	if !strings.HasPrefix(indexed["c1-link-0"], "价格页") {
+		t.Fatalf("expected the link entry to index the new anchor text, got %q", indexed["c1-link-0"])
+	}
+	meta, err := chunkService.chunks[0].DocumentMetadata()
+	if err != nil || meta == nil || len(meta.Links) != 2 || meta.Links[0].URL != "https://example.com/pricing" {
+		t.Fatalf("expected the stored links to follow the new content, got %+v (%v)", meta, err)
+	}
+}
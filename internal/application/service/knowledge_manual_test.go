@@ -0,0 +1,16 @@
+package service
+
+import "testing"
+
+func TestNormalizeManualContent(t *testing.T) {
+	input := "\uFEFF# 标题\r\n\r\n第一段  \r\n第二段\r旧式换行\r\n\r\n  \r\n"
+	want := "# 标题\n\n第一段  \n第二段\n旧式换行"
+
+	got := normalizeManualContent(input)
+	if got != want {
+		t.Fatalf("normalizeManualContent() = %q, want %q", got, want)
+	}
+	if again := normalizeManualContent(got); again != got {
+		t.Errorf("normalization is not stable: %q -> %q", got, again)
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/tracing"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+func TestKnowledgeParseProfileRemembered(t *testing.T) {
+	knowledge := &types.Knowledge{Type: "file", Metadata: types.JSON(`{"author":"alice"}`)}
+	size, overlap, multimodal := 800, 100, false
+
+	profile := knowledge.ParseProfile().Merge(&types.KnowledgeParseProfile{ChunkSize: &size, ChunkOverlap: &overlap})
+	if err := knowledge.SetParseProfile(profile); err != nil {
+		t.Fatalf("set parse profile: %v", err)
+	}
+	// A later reparse only overrides multimodal, the chunking tuning is kept
+	profile = knowledge.ParseProfile().Merge(&types.KnowledgeParseProfile{EnableMultimodel: &multimodal})
+	if err := knowledge.SetParseProfile(profile); err != nil {
+		t.Fatalf("set parse profile: %v", err)
+	}
+
+	stored := knowledge.ParseProfile()
+	if stored == nil || *stored.ChunkSize != 800 || *stored.ChunkOverlap != 100 || stored.ResolveEnableMultimodel(true) {
+		raw, _ := json.Marshal(stored)
+		t.Fatalf("unexpected stored profile %s", raw)
+	}
+	if meta := knowledge.GetMetadata(); meta["author"] != "alice" || len(meta) != 1 {
+		t.Fatalf("expected user metadata to be kept and profile hidden, got %v", meta)
+	}
+
+	cfg := types.ChunkingConfig{ChunkSize: 512, ChunkOverlap: 50, Separators: []string{"\n"}}
+	stored.ApplyChunking(&cfg)
+	if cfg.ChunkSize != 800 || cfg.ChunkOverlap != 100 || len(cfg.Separators) != 1 {
+		t.Fatalf("unexpected chunking config %+v", cfg)
+	}
+
+	badOverlap := 900
+	if err := stored.Merge(&types.KnowledgeParseProfile{ChunkOverlap: &badOverlap}).Validate(cfg); err == nil {
+		t.Fatalf("expected overlap larger than chunk size to be rejected")
+	}
+	smallSize := 80
+	if err := (&types.KnowledgeParseProfile{ChunkSize: &smallSize}).Validate(cfg); err == nil {
+		t.Fatalf("expected chunk size below the base overlap to be rejected")
+	}
+
+	if err := knowledge.SetParseProfile(nil); err != nil {
+		t.Fatalf("reset parse profile: %v", err)
+	}
+	if knowledge.ParseProfile() != nil {
+		t.Fatalf("expected parse profile to be removed")
+	}
+}
+
+func TestReparseParseOverridesOnce(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	t.Setenv("RETRIEVE_DRIVER", "")
+	tenant := &types.Tenant{ID: 1}
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, tenant)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+
+	knowledge := &types.Knowledge{
+		ID: "k1", TenantID: 1, KnowledgeBaseID: "kb1", Type: "file", FileName: "a.pdf", FileType: "pdf",
+		FilePath: "local://a.pdf", ParseStatus: types.ParseStatusCompleted,
+	}
+	remembered := 800
+	if err := knowledge.SetParseProfile(&types.KnowledgeParseProfile{ChunkSize: &remembered}); err != nil {
+		t.Fatalf("set parse profile: %v", err)
+	}
+	repo := newFakeKnowledgeRepo(knowledge)
+	svc := &knowledgeService{
+		repo:       repo,
+		tenantRepo: &fakeStorageTenantRepo{tenant: tenant},
+		kbService: &fakeTagKBService{kb: &types.KnowledgeBase{
+			ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument,
+			ChunkingConfig: types.ChunkingConfig{ChunkSize: 512, ChunkOverlap: 50},
+		}},
+		chunkService: &fakeProcessChunkService{},
+		modelService: newFakeReembedModelService("embedding-model"),
+		graphEngine:  &fakeProcessGraphRepo{},
+		task:         asynq.NewClient(asynq.RedisClientOpt{Addr: "127.0.0.1:1"}),
+	}
+	defer svc.task.Close()
+
+	storedChunkSize := func() int {
+		profile := repo.knowledge[knowledge.ID].ParseProfile()
+		if profile == nil || profile.ChunkSize == nil {
+			t.Fatal("expected the parse profile to be kept")
+		}
+		return *profile.ChunkSize
+	}
+
+	override := 600
+	opts := types.ReparseOptions{
+		ParseOverrides:     &types.KnowledgeParseProfile{ChunkSize: &override},
+		ParseOverridesOnce: true,
+	}
+	if _, err := svc.ReparseKnowledgeWithOptions(ctx, knowledge.ID, opts); err != nil {
+		t.Fatalf("ReparseKnowledgeWithOptions() error = %v", err)
+	}
+	if got := storedChunkSize(); got != remembered {
+		t.Fatalf("one-off override must not be stored, chunk size = %d, want %d", got, remembered)
+	}
+
+	opts.ParseOverridesOnce = false
+	if _, err := svc.ReparseKnowledgeWithOptions(ctx, knowledge.ID, opts); err != nil {
+		t.Fatalf("ReparseKnowledgeWithOptions() error = %v", err)
+	}
+	if got := storedChunkSize(); got != override {
+		t.Fatalf("override must be remembered, chunk size = %d, want %d", got, override)
+	}
+}
+
+func TestMisalignedEmbeddingKnowledge(t *testing.T) {
+	knowledgeList := []*types.Knowledge{
+		{ID: "aligned", ParseStatus: types.ParseStatusCompleted, EmbeddingModelID: "m2"},
+		{ID: "stale", ParseStatus: types.ParseStatusCompleted, EmbeddingModelID: "m1"},
+		{ID: "empty", ParseStatus: types.ParseStatusCompleted},
+		{ID: "pending", ParseStatus: types.ParseStatusPending, EmbeddingModelID: "m1"},
+		{ID: "failed", ParseStatus: types.ParseStatusFailed, EmbeddingModelID: "m1"},
+	}
+
+	misaligned := misalignedEmbeddingKnowledge(knowledgeList, "m2")
+	if len(misaligned) != 2 || misaligned[0].ID != "stale" || misaligned[1].ID != "empty" {
+		ids := make([]string, 0, len(misaligned))
+		for _, k := range misaligned {
+			ids = append(ids, k.ID)
+		}
+		t.Fatalf("expected [stale empty], got %v", ids)
+	}
+}
+
+func TestParseExtractedKeywords(t *testing.T) {
+	content := "```json\n" +
+		`{"keywords": ["向量检索", " RAG ", "rag", "", "一个远远超过三十二个字符长度限制的无效关键词结果应该被直接丢弃掉"],` +
+		` "entities": ["腾讯", "WeKnora", "PostgreSQL"]}` + "\n```"
+	keywords, entities, err := parseExtractedKeywords(content, 2)
+	if err != nil {
+		t.Fatalf("parseExtractedKeywords() error = %v", err)
+	}
+	if len(keywords) != 2 || keywords[0] != "向量检索" || keywords[1] != "RAG" {
+		t.Fatalf("unexpected keywords %v", keywords)
+	}
+	if len(entities) != 2 || entities[0] != "腾讯" || entities[1] != "WeKnora" {
+		t.Fatalf("unexpected entities %v", entities)
+	}
+
+	if _, _, err := parseExtractedKeywords("没有提取到关键词", 10); err == nil {
+		t.Fatalf("expected a response without JSON to be rejected")
+	}
+
+	// User metadata under the same plain names is left alone
+	knowledge := &types.Knowledge{Type: "file", Metadata: types.JSON(`{"author":"alice","keywords":"手工标注"}`)}
+	if err := knowledge.SetExtractedKeywords(keywords, entities); err != nil {
+		t.Fatalf("set extracted keywords: %v", err)
+	}
+	storedKeywords, storedEntities := knowledge.ExtractedKeywords()
+	if len(storedKeywords) != 2 || len(storedEntities) != 2 {
+		t.Fatalf("unexpected stored keywords %v, entities %v", storedKeywords, storedEntities)
+	}
+	meta := knowledge.GetMetadata()
+	if meta["author"] != "alice" || meta["keywords"] != "手工标注" || meta[types.KnowledgeMetadataKeywordsKey] == "" {
+		t.Fatalf("unexpected metadata %v", meta)
+	}
+
+	if err := knowledge.SetExtractedKeywords(nil, nil); err != nil {
+		t.Fatalf("clear extracted keywords: %v", err)
+	}
+	if meta := knowledge.GetMetadata(); len(meta) != 2 {
+		t.Fatalf("expected only user metadata to remain, got %v", meta)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestResolveKnowledgeExpiry(t *testing.T) {
+	createdAt := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	kb := &types.KnowledgeBase{RetentionConfig: &types.RetentionConfig{DefaultRetentionDays: 30}}
+
+	got, err := resolveKnowledgeExpiry(kb, nil, createdAt)
+	if err != nil || got == nil || !got.Equal(createdAt.AddDate(0, 0, 30)) {
+		t.Fatalf("default expiry = %v, %v; want %v", got, err, createdAt.AddDate(0, 0, 30))
+	}
+
+	explicit := createdAt.Add(time.Hour)
+	if got, err := resolveKnowledgeExpiry(kb, &explicit, createdAt); err != nil || !got.Equal(explicit) {
+		t.Errorf("explicit expiry = %v, %v; want %v", got, err, explicit)
+	}
+
+	past := createdAt.Add(-time.Hour)
+	if _, err := resolveKnowledgeExpiry(kb, &past, createdAt); err == nil {
+		t.Error("expected error for expiry before creation")
+	}
+
+	if got, err := resolveKnowledgeExpiry(&types.KnowledgeBase{}, nil, createdAt); err != nil || got != nil {
+		t.Errorf("expiry without retention config = %v, %v; want nil", got, err)
+	}
+}
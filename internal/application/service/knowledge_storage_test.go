@@ -0,0 +1,319 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+)
+
+// fakeStorageTenantRepo keeps a single tenant and applies storage adjustments to it.
+type fakeStorageTenantRepo struct {
+	interfaces.TenantRepository
+	tenant  *types.Tenant
+	adjusts int
+	// knowledgeTotal is the total storage size of the tenant's knowledge used by RecomputeStorageUsed
+	knowledgeTotal int64
+}
+
+func (r *fakeStorageTenantRepo) GetTenantByID(_ context.Context, _ uint64) (*types.Tenant, error) {
+	copied := *r.tenant
+	return &copied, nil
+}
+
+func (r *fakeStorageTenantRepo) AdjustStorageUsed(_ context.Context, _ uint64, delta int64) error {
+	r.adjusts++
+	r.tenant.StorageUsed += delta
+	return nil
+}
+
+func (r *fakeStorageTenantRepo) RecomputeStorageUsed(_ context.Context, _ uint64) (int64, int64, error) {
+	previous := r.tenant.StorageUsed
+	r.tenant.StorageUsed = r.knowledgeTotal
+	return previous, r.tenant.StorageUsed, nil
+}
+
+func TestRecomputeTenantStorage(t *testing.T) {
+	tenantRepo := &fakeStorageTenantRepo{tenant: &types.Tenant{ID: 1, StorageUsed: 5000}, knowledgeTotal: 1200}
+	svc := &knowledgeService{tenantRepo: tenantRepo}
+
+	result, err := svc.RecomputeTenantStorage(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("RecomputeTenantStorage() error = %v", err)
+	}
+	if result.Previous != 5000 || result.Actual != 1200 || result.Delta != -3800 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if tenantRepo.tenant.StorageUsed != 1200 {
+		t.Fatalf("expected storage used 1200, got %d", tenantRepo.tenant.StorageUsed)
+	}
+
+	// No drift left
+	result, err = svc.RecomputeTenantStorage(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("RecomputeTenantStorage() error = %v", err)
+	}
+	if result.Delta != 0 {
+		t.Fatalf("expected no correction, got %+v", result)
+	}
+	if tenantRepo.adjusts != 0 {
+		t.Fatalf("expected the usage to be recomputed without incremental adjustments, got %d", tenantRepo.adjusts)
+	}
+}
+
+// fakeOffloadFileService keeps saved files in memory.
+type fakeOffloadFileService struct {
+	interfaces.FileService
+	files map[string][]byte
+}
+
+func (f *fakeOffloadFileService) SaveBytes(_ context.Context, data []byte, _ uint64, fileName string, _ bool) (string, error) {
+	url := "local://" + fileName
+	f.files[url] = data
+	return url, nil
+}
+
+func (f *fakeOffloadFileService) GetFile(_ context.Context, filePath string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(f.files[filePath])), nil
+}
+
+func (f *fakeOffloadFileService) DeleteFile(_ context.Context, filePath string) error {
+	delete(f.files, filePath)
+	return nil
+}
+
+func (f *fakeOffloadFileService) GetFileURL(_ context.Context, filePath string) (string, error) {
+	return filePath, nil
+}
+
+func TestMarshalDocumentProcessPayload(t *testing.T) {
+	ctx := context.Background()
+	fileSvc := &fakeOffloadFileService{files: map[string][]byte{}}
+	svc := &knowledgeService{
+		config:  &config.Config{KnowledgeBase: &config.KnowledgeBaseConfig{TaskPayloadInlineMaxSize: 1024}},
+		fileSvc: fileSvc,
+	}
+
+	// Small payloads stay inline
+	small := &types.DocumentProcessPayload{KnowledgeID: "k1", Passages: []string{"hello"}}
+	if _, err := svc.marshalDocumentProcessPayload(ctx, small); err != nil {
+		t.Fatalf("marshalDocumentProcessPayload() error = %v", err)
+	}
+	if small.OffloadURL != "" || len(fileSvc.files) != 0 {
+		t.Fatalf("expected small payload to stay inline, got url %q", small.OffloadURL)
+	}
+
+	// Large payloads move passages and preserved questions to object storage
+	passages := []string{strings.Repeat("a", 800), strings.Repeat("b", 800)}
+	large := &types.DocumentProcessPayload{
+		KnowledgeID:        "k2",
+		Passages:           passages,
+		PreservedQuestions: map[string][]types.GeneratedQuestion{"h": {{ID: "q1", Question: "why?"}}},
+	}
+	payloadBytes, err := svc.marshalDocumentProcessPayload(ctx, large)
+	if err != nil {
+		t.Fatalf("marshalDocumentProcessPayload() error = %v", err)
+	}
+	if large.OffloadURL == "" || len(large.Passages) != 0 || large.PreservedQuestions != nil {
+		t.Fatalf("expected large payload to be offloaded, got %+v", large)
+	}
+	if len(payloadBytes) > 1024 {
+		t.Fatalf("expected offloaded payload within limit, got %d bytes", len(payloadBytes))
+	}
+
+	var offload types.DocumentProcessOffload
+	if err := svc.loadOffloadedTaskData(ctx, large.OffloadURL, &offload); err != nil {
+		t.Fatalf("loadOffloadedTaskData() error = %v", err)
+	}
+	if len(offload.Passages) != 2 || offload.Passages[1] != passages[1] ||
+		offload.PreservedQuestions["h"][0].Question != "why?" {
+		t.Fatalf("unexpected offloaded data %+v", offload)
+	}
+
+	svc.deleteOffloadedTaskData(ctx, large.OffloadURL)
+	if len(fileSvc.files) != 0 {
+		t.Fatalf("expected offloaded file to be deleted, got %d files", len(fileSvc.files))
+	}
+}
+
+func TestKnowledgeFileHashHidden(t *testing.T) {
+	visible := &types.Knowledge{ID: "k1", TenantID: 1, FileHash: "abc"}
+	hidden := &types.Knowledge{ID: "k2", TenantID: 2, FileHash: "def", FileHashHidden: true}
+	data, err := json.Marshal([]any{
+		visible,
+		*hidden,
+		&types.KnowledgeFileUploadResult{FileName: "a.pdf", Status: "duplicate", Knowledge: hidden},
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"file_hash":"abc"`) {
+		t.Fatalf("expected file hash of tenant without privacy config to be kept, got %s", data)
+	}
+	if strings.Contains(string(data), "def") {
+		t.Fatalf("expected file hash of opted out tenant to be hidden, got %s", data)
+	}
+	if hidden.FileHash != "def" {
+		t.Fatalf("expected file hash to be kept for filtering, got %q", hidden.FileHash)
+	}
+}
+
+// fakeUploadFileService accepts every uploaded file without storing it.
+type fakeUploadFileService struct {
+	interfaces.FileService
+}
+
+func (f *fakeUploadFileService) SaveFile(_ context.Context, file *multipart.FileHeader, _ uint64, knowledgeID string,
+) (string, error) {
+	return "local://" + knowledgeID + "/" + file.Filename, nil
+}
+
+// newUploadFileHeaders builds multipart file headers named after the keys of contents, in the given order.
+func newUploadFileHeaders(t *testing.T, names []string, contents map[string]string) []*multipart.FileHeader {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, name := range names {
+		part, err := writer.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatalf("CreateFormFile() error = %v", err)
+		}
+		part.Write([]byte(contents[name]))
+	}
+	writer.Close()
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("ReadForm() error = %v", err)
+	}
+	return form.File["files"]
+}
+
+func TestCreateKnowledgeFromFiles(t *testing.T) {
+	contents := map[string]string{
+		"old.md":   "already uploaded",
+		"a.md":     "new document",
+		"copy.md":  "new document",
+		"b.txt":    "another document",
+		"evil.exe": "binary",
+	}
+	files := newUploadFileHeaders(t, []string{"old.md", "a.md", "copy.md", "b.txt", "evil.exe"}, contents)
+	oldSum := md5.Sum([]byte(contents["old.md"]))
+	repo := newFakeKnowledgeRepo(&types.Knowledge{ID: "k-old", FileHash: hex.EncodeToString(oldSum[:])})
+	kb := &types.KnowledgeBase{ID: "kb-1", TenantID: 1}
+	svc := &knowledgeService{
+		repo:      repo,
+		kbService: &fakeTagKBService{kb: kb},
+		fileSvc:   &fakeUploadFileService{},
+		task:      asynq.NewClient(asynq.RedisClientOpt{Addr: "127.0.0.1:1"}),
+	}
+	defer svc.task.Close()
+
+	// The two new distinct files need more than the remaining quota, so nothing is created
+	tenant := &types.Tenant{ID: 1, StorageQuota: 100, StorageUsed: 80}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+	if _, err := svc.CreateKnowledgeFromFiles(ctx, kb.ID, files, nil); err == nil {
+		t.Fatal("expected batch above the storage quota to be rejected")
+	}
+	if len(repo.created) != 0 {
+		t.Fatalf("expected no knowledge to be created, got %d", len(repo.created))
+	}
+
+	tenant.StorageUsed = 0
+	results, err := svc.CreateKnowledgeFromFiles(ctx, kb.ID, files, nil)
+	if err != nil {
+		t.Fatalf("CreateKnowledgeFromFiles() error = %v", err)
+	}
+	if repo.hashQueries != 2 {
+		t.Fatalf("expected a single existence query per batch, got %d", repo.hashQueries)
+	}
+	statuses := make([]string, 0, len(results))
+	for _, r := range results {
+		statuses = append(statuses, r.Status)
+	}
+	want := []string{
+		types.KnowledgeFileUploadStatusDuplicate,
+		types.KnowledgeFileUploadStatusCreated,
+		types.KnowledgeFileUploadStatusDuplicate,
+		types.KnowledgeFileUploadStatusCreated,
+		types.KnowledgeFileUploadStatusError,
+	}
+	if strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected statuses %v", statuses)
+	}
+	if results[0].Knowledge.ID != "k-old" || results[2].Knowledge != results[1].Knowledge {
+		t.Fatalf("expected duplicates to point at the existing and the first batch knowledge")
+	}
+	if len(repo.created) != 2 || results[1].Knowledge.FilePath == "" {
+		t.Fatalf("expected two saved knowledge, got %d", len(repo.created))
+	}
+}
+
+func TestCountQueueTasksByType(t *testing.T) {
+	total := processingQueueStatsPageSize + 10
+	pages := 0
+	list := func(string, ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+		pages++
+		start := (pages - 1) * processingQueueStatsPageSize
+		var tasks []*asynq.TaskInfo
+		for i := start; i < total && i < start+processingQueueStatsPageSize; i++ {
+			taskType := types.TypeDocumentProcess
+			if i%2 == 1 {
+				taskType = types.TypeFAQImport
+			}
+			tasks = append(tasks, &asynq.TaskInfo{Type: taskType})
+		}
+		return tasks, nil
+	}
+	counts := map[string]int{}
+	complete, err := countQueueTasksByType("default", list, func(task *asynq.TaskInfo) { counts[task.Type]++ })
+	if err != nil || !complete {
+		t.Fatalf("countQueueTasksByType() = %v, %v", complete, err)
+	}
+	if pages != 2 || counts[types.TypeDocumentProcess] != total/2 || counts[types.TypeFAQImport] != total/2 {
+		t.Fatalf("unexpected pages %d and counts %v", pages, counts)
+	}
+
+	// Too many tasks are only partially scanned
+	total, pages = processingQueueStatsMaxTasks+1, 0
+	complete, err = countQueueTasksByType("default", list, func(*asynq.TaskInfo) {})
+	if err != nil || complete {
+		t.Fatalf("expected scan to be truncated, got %v, %v", complete, err)
+	}
+
+	missing := func(string, ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+		return nil, asynq.ErrQueueNotFound
+	}
+	if complete, err := countQueueTasksByType("low", missing, func(*asynq.TaskInfo) {}); err != nil || !complete {
+		t.Fatalf("expected missing queue to count as empty, got %v, %v", complete, err)
+	}
+}
+
+func TestEffectiveTenantInfo(t *testing.T) {
+	caller := &types.Tenant{ID: 1, StorageUsed: 10}
+	owner := &types.Tenant{ID: 2, StorageUsed: 500}
+	svc := &knowledgeService{tenantRepo: &fakeStorageTenantRepo{tenant: owner}}
+
+	ctx := context.WithValue(context.Background(), types.TenantInfoContextKey, caller)
+	own := context.WithValue(ctx, types.TenantIDContextKey, uint64(1))
+	if tenant, err := svc.effectiveTenantInfo(own); err != nil || tenant != caller {
+		t.Fatalf("expected the caller tenant for its own knowledge base, got %+v, %v", tenant, err)
+	}
+
+	// A shared knowledge base is addressed with the owner tenant ID, the owner is loaded
+	shared := context.WithValue(ctx, types.TenantIDContextKey, uint64(2))
+	tenant, err := svc.effectiveTenantInfo(shared)
+	if err != nil || tenant.ID != 2 || tenant.StorageUsed != 500 {
+		t.Fatalf("expected the owner tenant for a shared knowledge base, got %+v, %v", tenant, err)
+	}
+}
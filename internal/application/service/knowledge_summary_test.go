@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+)
+
+// fakeSummaryChunkService serves the text chunks of the knowledge and fails if a summary chunk is created.
+type fakeSummaryChunkService struct {
+	interfaces.ChunkService
+	chunks  []*types.Chunk
+	created int
+}
+
+func (s *fakeSummaryChunkService) ListChunksByKnowledgeID(_ context.Context, _ string) ([]*types.Chunk, error) {
+	return s.chunks, nil
+}
+
+func (s *fakeSummaryChunkService) CreateChunks(_ context.Context, chunks []*types.Chunk) error {
+	s.created += len(chunks)
+	return errors.New("summary chunk should not be created")
+}
+
+// fakeFailingChatModel simulates an unavailable summary model.
+type fakeFailingChatModel struct{}
+
+func (m *fakeFailingChatModel) Chat(_ context.Context, _ []chat.Message, _ *chat.ChatOptions) (*types.ChatResponse, error) {
+	return nil, errors.New("model unavailable")
+}
+
+func (m *fakeFailingChatModel) ChatStream(_ context.Context, _ []chat.Message, _ *chat.ChatOptions) (<-chan types.StreamResponse, error) {
+	return nil, errors.New("model unavailable")
+}
+
+func (m *fakeFailingChatModel) GetModelName() string { return "fake" }
+
+func (m *fakeFailingChatModel) GetModelID() string { return "fake" }
+
+type fakeSummaryModelService struct {
+	interfaces.ModelService
+}
+
+func (s *fakeSummaryModelService) GetChatModel(_ context.Context, _ string) (chat.Chat, error) {
+	return &fakeFailingChatModel{}, nil
+}
+
+func TestProcessSummaryGenerationModelFailure(t *testing.T) {
+	content := strings.Repeat("彗星是由冰和尘埃组成的小天体。", 40)
+	chunkService := &fakeSummaryChunkService{chunks: []*types.Chunk{
+		{ID: "c1", ChunkType: types.ChunkTypeText, Content: content, EndAt: len([]rune(content))},
+	}}
+	knowledgeRepo := newFakeKnowledgeRepo(&types.Knowledge{ID: "k1", TenantID: 1, FileName: "comet.txt"})
+	svc := &knowledgeService{
+		config:       &config.Config{Conversation: &config.ConversationConfig{}},
+		kbService:    &fakeTagKBService{kb: &types.KnowledgeBase{ID: "kb-1", TenantID: 1}},
+		repo:         knowledgeRepo,
+		chunkService: chunkService,
+		modelService: &fakeSummaryModelService{},
+		tenantRepo:   &fakeStorageTenantRepo{tenant: &types.Tenant{ID: 1}},
+	}
+	payload, _ := json.Marshal(types.SummaryGenerationPayload{TenantID: 1, KnowledgeBaseID: "kb-1", KnowledgeID: "k1"})
+
+	// Default: no truncated content is stored, the summary is reported as failed
+	if err := svc.ProcessSummaryGeneration(context.Background(), asynq.NewTask(types.TypeSummaryGeneration, payload)); err != nil {
+		t.Fatalf("ProcessSummaryGeneration() error = %v", err)
+	}
+	if got := knowledgeRepo.knowledge["k1"]; got.Description != "" || got.SummaryStatus != types.SummaryStatusFailed {
+		t.Fatalf("expected empty description and failed status, got %q / %s", got.Description, got.SummaryStatus)
+	}
+	if chunkService.created != 0 {
+		t.Fatalf("expected no summary chunk, got %d", chunkService.created)
+	}
+
+	// Opt-in truncation keeps at most summaryFallbackMaxRunes characters without splitting runes
+	truncated := truncateSummaryFallback(content)
+	if n := len([]rune(truncated)); n != summaryFallbackMaxRunes || !strings.HasPrefix(content, truncated) {
+		t.Fatalf("unexpected truncation: %d runes", n)
+	}
+	if (*types.DocumentSummaryConfig)(nil).GetFallbackMode() != types.SummaryFallbackFail {
+		t.Fatal("expected fail to be the default fallback mode")
+	}
+}
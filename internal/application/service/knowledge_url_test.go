@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/tracing"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestCreateKnowledgeFromFileURLImageRequiresVLM(t *testing.T) {
+	kb := &types.KnowledgeBase{ID: "kb-1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument}
+	svc := &knowledgeService{kbService: &fakeTagKBService{kb: kb}}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	rawURL := "https://example.com/images/diagram.png"
+	if !isFileURL(rawURL, "", "") {
+		t.Fatalf("expected image URL to be imported as file_url")
+	}
+
+	enable := true
+	_, err := svc.CreateKnowledgeFromURL(ctx, kb.ID, rawURL, "", "", &enable, "", "", nil, "")
+	appErr, ok := werrors.IsAppError(err)
+	if !ok {
+		t.Fatalf("expected app error, got %v", err)
+	}
+	if appErr.Message != "上传图片文件需要设置VLM模型" {
+		t.Fatalf("unexpected error message %q", appErr.Message)
+	}
+}
+
+func TestMultimodalKillSwitch(t *testing.T) {
+	kb := &types.KnowledgeBase{ID: "kb-1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument}
+	svc := &knowledgeService{kbService: &fakeTagKBService{kb: kb}, config: &config.Config{KnowledgeBase: &config.KnowledgeBaseConfig{}}}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	enable := true
+	if !svc.resolveEnableMultimodel(ctx, kb, &enable) {
+		t.Fatal("expected multimodal override to be honored without the kill-switch")
+	}
+
+	svc.config.KnowledgeBase.DisableMultimodal = true
+	if svc.resolveEnableMultimodel(ctx, kb, &enable) {
+		t.Fatal("expected the kill-switch to force multimodal off")
+	}
+	if err := svc.validateImageMultimodalConfig(ctx, kb, "pdf"); err != nil {
+		t.Fatalf("expected documents to be accepted as text only, got %v", err)
+	}
+
+	_, err := svc.CreateKnowledgeFromURL(ctx, kb.ID, "https://example.com/images/diagram.png", "", "", &enable, "", "", nil, "")
+	appErr, ok := werrors.IsAppError(err)
+	if !ok || appErr.Message != "多模态处理已被管理员临时关闭，暂时无法上传图片文件" {
+		t.Fatalf("expected image to be rejected while multimodal is disabled, got %v", err)
+	}
+}
+
+func TestFileURLTypeFromHeader(t *testing.T) {
+	tests := []struct {
+		contentType, disposition string
+		wantName, wantType       string
+	}{
+		{"application/pdf", "", "", "pdf"},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document; charset=binary", "", "", "docx"},
+		{"text/markdown; charset=utf-8", `attachment; filename="notes"`, "notes", "md"},
+		{"application/octet-stream", `attachment; filename="report.pptx"`, "report.pptx", "pptx"},
+		{"application/octet-stream", `attachment; filename="setup.exe"`, "", ""},
+		{"text/html; charset=utf-8", "", "", ""},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set("Content-Type", tt.contentType)
+		if tt.disposition != "" {
+			header.Set("Content-Disposition", tt.disposition)
+		}
+		name, fileType := fileURLTypeFromHeader(header)
+		if name != tt.wantName || fileType != tt.wantType {
+			t.Errorf("%s / %s: got (%q, %q), want (%q, %q)",
+				tt.contentType, tt.disposition, name, fileType, tt.wantName, tt.wantType)
+		}
+	}
+
+	// Probing is opt-in, without the config no request is sent
+	svc := &knowledgeService{config: &config.Config{KnowledgeBase: &config.KnowledgeBaseConfig{}}}
+	if _, _, ok := svc.probeFileURL(context.Background(), "https://example.com/download?id=123"); ok {
+		t.Fatal("expected probing to be disabled by default")
+	}
+}
+
+func TestLegacyKnowledgeCreateResult(t *testing.T) {
+	existing := &types.Knowledge{ID: "k-1", Source: "https://example.com/a"}
+
+	knowledge, err := legacyKnowledgeCreateResult(
+		types.NewDuplicateKnowledgeResult(existing), nil, types.NewDuplicateURLError)
+	dupErr, ok := err.(*types.DuplicateKnowledgeError)
+	if !ok || knowledge != existing || dupErr.Knowledge != existing {
+		t.Fatalf("expected duplicate error carrying the existing knowledge, got %v, %v", knowledge, err)
+	}
+
+	created := &types.Knowledge{ID: "k-2"}
+	knowledge, err = legacyKnowledgeCreateResult(
+		&types.KnowledgeCreateResult{Knowledge: created}, nil, types.NewDuplicateURLError)
+	if err != nil || knowledge != created {
+		t.Fatalf("expected created knowledge, got %v, %v", knowledge, err)
+	}
+}
+
+func TestResolveKnowledgeFileName(t *testing.T) {
+	svc := &knowledgeService{repo: newFakeKnowledgeRepo(
+		&types.Knowledge{ID: "k1", KnowledgeBaseID: "kb-1", FileName: "report.pdf"},
+		&types.Knowledge{ID: "k2", KnowledgeBaseID: "kb-1", FileName: "report (2).pdf"},
+		&types.Knowledge{ID: "k3", KnowledgeBaseID: "kb-1", FileName: "notes.md"},
+	)}
+	ctx := context.Background()
+
+	got, err := svc.resolveKnowledgeFileName(ctx, 1, "kb-1", "report.pdf", types.KnowledgeNameConflictRename)
+	if err != nil || got != "report (3).pdf" {
+		t.Fatalf("rename resolved to %q, %v", got, err)
+	}
+	if got, err = svc.resolveKnowledgeFileName(ctx, 1, "kb-1", "summary.pdf", types.KnowledgeNameConflictRename); err != nil || got != "summary.pdf" {
+		t.Fatalf("unused name resolved to %q, %v", got, err)
+	}
+	if got, err = svc.resolveKnowledgeFileName(ctx, 1, "kb-1", "notes.md", ""); err != nil || got != "notes.md" {
+		t.Fatalf("default policy resolved to %q, %v", got, err)
+	}
+
+	_, err = svc.resolveKnowledgeFileName(ctx, 1, "kb-1", "notes.md", types.KnowledgeNameConflictReject)
+	if appErr, ok := werrors.IsAppError(err); !ok || appErr.Code != werrors.ErrConflict {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	if _, err = svc.resolveKnowledgeFileName(ctx, 1, "kb-1", "notes.md", "overwrite"); err == nil {
+		t.Fatal("expected unknown policy to be rejected")
+	}
+}
+
+func TestCheckKnowledgeSourceChanged(t *testing.T) {
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	knowledge := &types.Knowledge{ID: "k1", Type: "url", Source: server.URL}
+	ctx := context.Background()
+
+	// No validators yet, the source counts as changed and the ETag is recorded
+	changed, err := checkKnowledgeSourceChanged(ctx, server.Client(), knowledge)
+	if err != nil || !changed || knowledge.SourceETag != etag {
+		t.Fatalf("first check = %v, %v, etag %q", changed, err, knowledge.SourceETag)
+	}
+
+	if changed, err = checkKnowledgeSourceChanged(ctx, server.Client(), knowledge); err != nil || changed {
+		t.Fatalf("expected 304 to report unchanged, got %v, %v", changed, err)
+	}
+
+	etag = `"v2"`
+	if changed, err = checkKnowledgeSourceChanged(ctx, server.Client(), knowledge); err != nil || !changed {
+		t.Fatalf("expected new ETag to report changed, got %v, %v", changed, err)
+	}
+	if knowledge.SourceETag != `"v2"` {
+		t.Fatalf("expected ETag to be updated, got %q", knowledge.SourceETag)
+	}
+}
+
+func TestDownloadFileFromURLConcurrencyAndTimeout(t *testing.T) {
+	if _, err := tracing.InitTracer(); err != nil {
+		t.Fatalf("init tracer: %v", err)
+	}
+	var inFlight, maxInFlight int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		if strings.HasSuffix(r.URL.Path, ".pdf") {
+			time.Sleep(200 * time.Millisecond)
+		} else {
+			<-release
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{KnowledgeBase: &config.KnowledgeBaseConfig{
+		FileURLDownload: &config.FileURLDownloadConfig{
+			MaxConcurrent:     2,
+			Timeout:           5 * time.Second,
+			TimeoutByFileType: map[string]time.Duration{"pdf": 50 * time.Millisecond},
+		},
+	}}
+	svc := &knowledgeService{config: cfg, downloadSem: make(chan struct{}, fileURLDownloadConcurrency(cfg))}
+	if got := svc.fileURLDownloadTimeout("PDF"); got != 50*time.Millisecond {
+		t.Fatalf("expected pdf timeout override, got %s", got)
+	}
+	if got := svc.fileURLDownloadTimeout("docx"); got != 5*time.Second {
+		t.Fatalf("expected default timeout, got %s", got)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name, fileType := "", "txt"
+			content, err := svc.downloadFileFromURL(context.Background(), server.URL+"/a.txt",
+				types.DefaultMaxURLFileSize, &name, &fileType)
+			if err != nil || string(content) != "hello" {
+				t.Errorf("unexpected download result %q, %v", content, err)
+			}
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if maxInFlight != 2 {
+		t.Fatalf("expected at most 2 concurrent downloads, got %d", maxInFlight)
+	}
+
+	name, fileType := "", "pdf"
+	if _, err := svc.downloadFileFromURL(context.Background(), server.URL+"/slow.pdf",
+		types.DefaultMaxURLFileSize, &name, &fileType); err == nil {
+		t.Fatalf("expected pdf download to hit its per-type timeout")
+	}
+}
+
+func TestFetchFileFromURLMaxSize(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chunked") != "" {
+			// No Content-Length, the size is only known while streaming
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	for _, rawURL := range []string{server.URL + "/a.txt", server.URL + "/a.txt?chunked=1"} {
+		name, fileType := "", ""
+		if _, err := fetchFileFromURL(context.Background(), rawURL, 1024, &name, &fileType); err == nil {
+			t.Errorf("%s: expected a file over the limit to be rejected", rawURL)
+		}
+		content, err := fetchFileFromURL(context.Background(), rawURL, 4096, &name, &fileType)
+		if err != nil || len(content) != len(body) {
+			t.Errorf("%s: expected the file to be downloaded under a larger limit, got %d bytes, %v", rawURL, len(content), err)
+		}
+	}
+
+	var cfg *types.ImportConfig
+	if cfg.GetMaxURLFileSize() != types.DefaultMaxURLFileSize {
+		t.Fatal("expected the default limit when the knowledge base sets none")
+	}
+	if err := validateImportConfig(&types.ImportConfig{MaxURLFileSize: 40 * 1024 * 1024}); err != nil {
+		t.Fatalf("expected 40MB to be accepted, got %v", err)
+	}
+	for _, size := range []int64{-1, types.MaxURLFileSizeCeiling + 1} {
+		if err := validateImportConfig(&types.ImportConfig{MaxURLFileSize: size}); err == nil {
+			t.Errorf("expected max URL file size %d to be rejected", size)
+		}
+	}
+}
+
+func TestCheckKnowledgeRefreshSource(t *testing.T) {
+	svc := &knowledgeService{config: &config.Config{KnowledgeBase: &config.KnowledgeBaseConfig{
+		URLImport: &config.URLImportConfig{AllowedDomains: []string{"docs.example.com"}},
+	}}}
+
+	// A source imported before the allow-list was tightened is no longer refreshed
+	if allowed, reason := svc.checkKnowledgeRefreshSource("https://blog.example.org/post"); allowed ||
+		!strings.Contains(reason, "allow-list") {
+		t.Fatalf("expected allow-list rejection, got %v %q", allowed, reason)
+	}
+	if allowed, reason := svc.checkKnowledgeRefreshSource("https://docs.example.com.internal.local/page"); allowed {
+		t.Fatalf("expected rejection, got %q", reason)
+	}
+
+	svc.config.KnowledgeBase.URLImport = nil
+	if allowed, reason := svc.checkKnowledgeRefreshSource("http://127.0.0.1:8080/admin"); allowed ||
+		!strings.Contains(reason, "SSRF") {
+		t.Fatalf("expected SSRF rejection, got %v %q", allowed, reason)
+	}
+}
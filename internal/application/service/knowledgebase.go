@@ -4,15 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/embedding"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 )
@@ -84,7 +88,27 @@ func (s *knowledgeBaseService) CreateKnowledgeBase(ctx context.Context,
 	kb.CreatedAt = time.Now()
 	kb.TenantID = ctx.Value(types.TenantIDContextKey).(uint64)
 	kb.UpdatedAt = time.Now()
+	if kb.ChunkingPreset != "" {
+		tenant, _ := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+		if tenant == nil {
+			return nil, werrors.NewBadRequestError("无法获取租户信息")
+		}
+		preset := tenant.ChunkingPresets.Find(kb.ChunkingPreset)
+		if preset == nil {
+			return nil, werrors.NewBadRequestError(fmt.Sprintf("分块预设「%s」不存在", kb.ChunkingPreset))
+		}
+		preset.ApplyDefaults(&kb.ChunkingConfig)
+	}
 	kb.EnsureDefaults()
+	if err := validateRetentionConfig(kb.RetentionConfig); err != nil {
+		return nil, err
+	}
+	if err := validateImportConfig(kb.ImportConfig); err != nil {
+		return nil, err
+	}
+	if err := validateIndexContentTemplate(kb.ChunkingConfig.IndexContentTemplate); err != nil {
+		return nil, err
+	}
 
 	logger.Infof(ctx, "Creating knowledge base, ID: %s, tenant ID: %d, name: %s", kb.ID, kb.TenantID, kb.Name)
 
@@ -291,12 +315,40 @@ func (s *knowledgeBaseService) UpdateKnowledgeBase(ctx context.Context,
 	// Update the knowledge base properties
 	kb.Name = name
 	kb.Description = description
+	if err := validateIndexContentTemplate(config.ChunkingConfig.IndexContentTemplate); err != nil {
+		return nil, err
+	}
 	kb.ChunkingConfig = config.ChunkingConfig
 	kb.ImageProcessingConfig = config.ImageProcessingConfig
 	// Update FAQ config if provided
 	if config.FAQConfig != nil {
+		if err := s.validateFAQFallbackEntry(ctx, kb, config.FAQConfig); err != nil {
+			return nil, err
+		}
 		kb.FAQConfig = config.FAQConfig
 	}
+	// Update retrieval config if provided
+	if config.RetrievalConfig != nil {
+		kb.RetrievalConfig = config.RetrievalConfig
+	}
+	// Update retention config if provided
+	if config.RetentionConfig != nil {
+		if err := validateRetentionConfig(config.RetentionConfig); err != nil {
+			return nil, err
+		}
+		kb.RetentionConfig = config.RetentionConfig
+	}
+	// Update keyword extraction config if provided
+	if config.KeywordExtractionConfig != nil {
+		kb.KeywordExtractionConfig = config.KeywordExtractionConfig
+	}
+	// Update import config if provided
+	if config.ImportConfig != nil {
+		if err := validateImportConfig(config.ImportConfig); err != nil {
+			return nil, err
+		}
+		kb.ImportConfig = config.ImportConfig
+	}
 	kb.UpdatedAt = time.Now()
 	kb.EnsureDefaults()
 
@@ -312,6 +364,77 @@ func (s *knowledgeBaseService) UpdateKnowledgeBase(ctx context.Context,
 	return kb, nil
 }
 
+// validateRetentionConfig checks the retention period and that the expiry webhook is a safe public URL
+func validateRetentionConfig(cfg *types.RetentionConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.DefaultRetentionDays < 0 {
+		return werrors.NewValidationError("默认保留天数不能为负数")
+	}
+	if cfg.NotifyBeforeHours < 0 || cfg.NotifyBeforeHours > types.MaxExpiryNotifyBeforeHours {
+		return werrors.NewValidationError(
+			fmt.Sprintf("到期通知提前时间需在 0 到 %d 小时之间", types.MaxExpiryNotifyBeforeHours))
+	}
+	if cfg.ExpiryWebhookURL != "" {
+		if !isValidURL(cfg.ExpiryWebhookURL) || !secutils.IsValidURL(cfg.ExpiryWebhookURL) {
+			return werrors.NewValidationError("到期通知地址格式错误")
+		}
+		if safe, reason := secutils.IsSSRFSafeURL(cfg.ExpiryWebhookURL); !safe {
+			return werrors.NewValidationError(fmt.Sprintf("到期通知地址不安全: %s", reason))
+		}
+	}
+	return nil
+}
+
+// validateFAQFallbackEntry checks that the no-match fallback entry, when used, is an FAQ entry of the knowledge base
+func (s *knowledgeBaseService) validateFAQFallbackEntry(ctx context.Context,
+	kb *types.KnowledgeBase, cfg *types.FAQConfig,
+) error {
+	if cfg.FallbackEntryID < 0 {
+		return werrors.NewValidationError("兜底条目 ID 不合法")
+	}
+	if cfg.GetNoMatchFallback() != types.FAQNoMatchFallbackEntry {
+		return nil
+	}
+	chunk, err := s.chunkRepo.GetChunkBySeqID(ctx, kb.TenantID, cfg.FallbackEntryID)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to get FAQ fallback entry %d: %v", cfg.FallbackEntryID, err)
+		return werrors.NewValidationError("兜底条目不存在")
+	}
+	if chunk.KnowledgeBaseID != kb.ID || chunk.ChunkType != types.ChunkTypeFAQ {
+		return werrors.NewValidationError("兜底条目不存在")
+	}
+	return nil
+}
+
+// validateImportConfig checks that the max file size of URL imports, when set, is positive and under the ceiling
+func validateImportConfig(cfg *types.ImportConfig) error {
+	if cfg == nil || cfg.MaxURLFileSize == 0 {
+		return nil
+	}
+	if cfg.MaxURLFileSize < 0 || cfg.MaxURLFileSize > types.MaxURLFileSizeCeiling {
+		return werrors.NewValidationError(fmt.Sprintf("URL 导入文件大小上限需在 1 到 %d 字节（%dMB）之间",
+			types.MaxURLFileSizeCeiling, types.MaxURLFileSizeCeiling/(1024*1024)))
+	}
+	return nil
+}
+
+// validateIndexContentTemplate checks that a non-empty index content template keeps the chunk content
+func validateIndexContentTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(template) > types.MaxIndexContentTemplateLength {
+		return werrors.NewValidationError(
+			fmt.Sprintf("索引内容模板不能超过 %d 个字符", types.MaxIndexContentTemplateLength))
+	}
+	if !strings.Contains(template, types.IndexTemplateContent) {
+		return werrors.NewValidationError("索引内容模板必须包含 {{content}} 占位符")
+	}
+	return nil
+}
+
 // DeleteKnowledgeBase deletes a knowledge base by its ID
 // This method marks the knowledge base as deleted and enqueues an async task
 // to handle the heavy cleanup operations (embeddings, chunks, files, graph data)
@@ -615,7 +738,9 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 		return nil, err
 	}
 
-	matchCount := params.MatchCount * 3
+	// matchCount is the internal TopK of every retriever; results are merged and trimmed to
+	// params.MatchCount afterwards, the pool is widened by the session or knowledge base RetrieveTopK.
+	matchCount := params.EffectiveRetrieveTopK(kb.RetrievalConfig.GetRetrieveTopK())
 
 	// Add vector retrieval params if supported
 	if retrieveEngine.SupportRetriever(types.VectorRetrieverType) && !params.DisableVectorMatch {
@@ -723,6 +848,12 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 		logger.Info(ctx, "No search results found")
 		return nil, nil
 	}
+
+	// Apply per-chunk-type score weights (document knowledge bases only)
+	if kb.Type != types.KnowledgeBaseTypeFAQ && kb.RetrievalConfig != nil && len(kb.RetrievalConfig.ChunkTypeWeights) > 0 {
+		applyChunkTypeWeights(kb.RetrievalConfig, vectorResults)
+		applyChunkTypeWeights(kb.RetrievalConfig, keywordResults)
+	}
 	logger.Infof(ctx, "Result count before fusion: vector=%d, keyword=%d", len(vectorResults), len(keywordResults))
 
 	var deduplicatedChunks []*types.IndexWithScore
@@ -852,12 +983,151 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 		logger.Infof(ctx, "Result count after negative question filtering: %d", len(deduplicatedChunks))
 	}
 
+	// Collapse adjacent chunks that share most of their text (opt-in, document knowledge bases only)
+	if kb.Type != types.KnowledgeBaseTypeFAQ && kb.RetrievalConfig.OverlapDedupEnabled() {
+		deduplicatedChunks = s.collapseOverlappingChunks(ctx, kb.RetrievalConfig.OverlapDedupThreshold, deduplicatedChunks)
+		logger.Infof(ctx, "Result count after overlap deduplication: %d", len(deduplicatedChunks))
+	}
+
+	// Drop results of knowledge past its retention period, the expiry sweeper deletes them later
+	deduplicatedChunks = s.filterExpiredKnowledge(ctx, deduplicatedChunks)
+
 	// Limit to MatchCount
 	if len(deduplicatedChunks) > params.MatchCount {
 		deduplicatedChunks = deduplicatedChunks[:params.MatchCount]
 	}
 
-	return s.processSearchResults(ctx, deduplicatedChunks)
+	results, err := s.processSearchResults(ctx, deduplicatedChunks)
+	if err != nil {
+		return nil, err
+	}
+	if params.IncludeImages {
+		types.AttachImages(results)
+	}
+	return results, nil
+}
+
+// filterExpiredKnowledge removes results whose knowledge has expired, so expired documents
+// stop being retrieved as soon as they expire rather than when they are physically deleted
+func (s *knowledgeBaseService) filterExpiredKnowledge(ctx context.Context,
+	results []*types.IndexWithScore,
+) []*types.IndexWithScore {
+	if len(results) == 0 {
+		return results
+	}
+	knowledgeIDs := make([]string, 0, len(results))
+	seen := make(map[string]struct{}, len(results))
+	for _, r := range results {
+		if _, ok := seen[r.KnowledgeID]; ok || r.KnowledgeID == "" {
+			continue
+		}
+		seen[r.KnowledgeID] = struct{}{}
+		knowledgeIDs = append(knowledgeIDs, r.KnowledgeID)
+	}
+	expiredIDs, err := s.kgRepo.ListExpiredKnowledgeIDs(ctx, knowledgeIDs, time.Now())
+	if err != nil {
+		logger.Warnf(ctx, "Failed to check knowledge expiry, skipping filter: %v", err)
+		return results
+	}
+	if len(expiredIDs) == 0 {
+		return results
+	}
+	expired := make(map[string]struct{}, len(expiredIDs))
+	for _, id := range expiredIDs {
+		expired[id] = struct{}{}
+	}
+	filtered := make([]*types.IndexWithScore, 0, len(results))
+	for _, r := range results {
+		if _, ok := expired[r.KnowledgeID]; !ok {
+			filtered = append(filtered, r)
+		}
+	}
+	logger.Infof(ctx, "Result count after expired knowledge filtering: %d", len(filtered))
+	return filtered
+}
+
+// collapseOverlappingChunks drops a result when an adjacent chunk (linked through PreChunkID/NextChunkID)
+// with a higher score is already kept and the two overlap by at least threshold of the shorter chunk.
+// Results must be sorted by score in descending order.
+func (s *knowledgeBaseService) collapseOverlappingChunks(ctx context.Context,
+	threshold float64, results []*types.IndexWithScore,
+) []*types.IndexWithScore {
+	if len(results) < 2 {
+		return results
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	chunkIDs := make([]string, 0, len(results))
+	for _, r := range results {
+		chunkIDs = append(chunkIDs, r.ChunkID)
+	}
+	chunks, err := s.listChunksByIDWithShared(ctx, tenantID, chunkIDs)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to load chunks for overlap deduplication, skipping: %v", err)
+		return results
+	}
+	chunkMap := make(map[string]*types.Chunk, len(chunks))
+	for _, chunk := range chunks {
+		chunkMap[chunk.ID] = chunk
+	}
+
+	kept := make(map[string]bool, len(results))
+	collapsed := make([]*types.IndexWithScore, 0, len(results))
+	for _, r := range results {
+		chunk := chunkMap[r.ChunkID]
+		if chunk != nil {
+			overlapsKept := false
+			for _, neighborID := range []string{chunk.PreChunkID, chunk.NextChunkID} {
+				if neighborID == "" || !kept[neighborID] {
+					continue
+				}
+				if chunkOverlapRatio(chunk, chunkMap[neighborID]) >= threshold {
+					logger.Debugf(ctx, "Collapsing chunk %s into adjacent chunk %s", chunk.ID, neighborID)
+					overlapsKept = true
+					break
+				}
+			}
+			if overlapsKept {
+				continue
+			}
+		}
+		kept[r.ChunkID] = true
+		collapsed = append(collapsed, r)
+	}
+	return collapsed
+}
+
+// chunkOverlapRatio returns the overlapping length of two chunks relative to the shorter one,
+// based on their start/end positions in the source document
+func chunkOverlapRatio(a, b *types.Chunk) float64 {
+	if a == nil || b == nil {
+		return 0
+	}
+	overlap := min(a.EndAt, b.EndAt) - max(a.StartAt, b.StartAt)
+	shorter := min(a.EndAt-a.StartAt, b.EndAt-b.StartAt)
+	if overlap <= 0 || shorter <= 0 {
+		return 0
+	}
+	return float64(overlap) / float64(shorter)
+}
+
+// applyChunkTypeWeights multiplies each result score by its chunk type weight and re-sorts the results,
+// so that both score-based ordering and rank-based fusion (RRF) reflect the weights
+func applyChunkTypeWeights(cfg *types.RetrievalConfig, results []*types.IndexWithScore) {
+	if len(results) == 0 {
+		return
+	}
+	for _, r := range results {
+		r.Score *= cfg.ChunkTypeWeight(r.RetrievalWeightKey())
+	}
+	slices.SortStableFunc(results, func(a, b *types.IndexWithScore) int {
+		if a.Score > b.Score {
+			return -1
+		} else if a.Score < b.Score {
+			return 1
+		}
+		return 0
+	})
 }
 
 // iterativeRetrieveWithDeduplication performs iterative retrieval until enough unique chunks are found
@@ -871,8 +1141,8 @@ func (s *knowledgeBaseService) iterativeRetrieveWithDeduplication(ctx context.Co
 ) []*types.IndexWithScore {
 	maxIterations := 5
 	// Start with a larger TopK since we're called when first retrieval wasn't enough
-	// The first retrieval already used matchCount*3, so start from there
-	currentTopK := matchCount * 3
+	// The first retrieval already used matchCount*DefaultRetrieveTopKMultiplier, so start from there
+	currentTopK := matchCount * types.DefaultRetrieveTopKMultiplier
 	uniqueChunks := make(map[string]*types.IndexWithScore)
 	// Cache chunk data to avoid repeated DB queries across iterations
 	chunkDataCache := make(map[string]*types.Chunk)
@@ -1112,6 +1382,7 @@ func (s *knowledgeBaseService) processSearchResults(ctx context.Context,
 	chunkScores := make(map[string]float64)
 	chunkMatchTypes := make(map[string]types.MatchType)
 	chunkMatchedContents := make(map[string]string)
+	chunkMatchedSourceIDs := make(map[string]string)
 	processedKnowledgeIDs := make(map[string]bool)
 
 	// Collect all knowledge and chunk IDs
@@ -1125,6 +1396,7 @@ func (s *knowledgeBaseService) processSearchResults(ctx context.Context,
 		chunkScores[chunk.ChunkID] = chunk.Score
 		chunkMatchTypes[chunk.ChunkID] = chunk.MatchType
 		chunkMatchedContents[chunk.ChunkID] = chunk.Content
+		chunkMatchedSourceIDs[chunk.ChunkID] = chunk.SourceID
 	}
 
 	// Batch fetch knowledge data (include shared KB so cross-tenant retrieval works)
@@ -1226,7 +1498,9 @@ func (s *knowledgeBaseService) processSearchResults(ctx context.Context,
 		if knowledge, ok := knowledgeMap[chunk.KnowledgeID]; ok {
 			matchType := chunkMatchTypes[chunk.ID]
 			matchedContent := chunkMatchedContents[chunk.ID]
-			searchResults = append(searchResults, s.buildSearchResult(chunk, knowledge, score, matchType, matchedContent))
+			result := s.buildSearchResult(chunk, knowledge, score, matchType, matchedContent)
+			result.MatchedSourceID = chunkMatchedSourceIDs[chunk.ID]
+			searchResults = append(searchResults, result)
 			addedChunkIDs[chunk.ID] = true
 		} else {
 			logger.Warnf(ctx, "Knowledge not found for chunk: %s, knowledge_id: %s", chunk.ID, chunk.KnowledgeID)
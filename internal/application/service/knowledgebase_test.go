@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeCreateKBRepo records the knowledge base passed to CreateKnowledgeBase.
+type fakeCreateKBRepo struct {
+	interfaces.KnowledgeBaseRepository
+	created *types.KnowledgeBase
+}
+
+func (r *fakeCreateKBRepo) CreateKnowledgeBase(_ context.Context, kb *types.KnowledgeBase) error {
+	r.created = kb
+	return nil
+}
+
+func TestCreateKnowledgeBaseWithChunkingPreset(t *testing.T) {
+	tenant := &types.Tenant{
+		ID: 1,
+		ChunkingPresets: types.ChunkingPresets{
+			{Name: "code", ChunkSize: 2000, ChunkOverlap: 100, Separators: []string{"\n\n", "\n"}},
+		},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	repo := &fakeCreateKBRepo{}
+	svc := &knowledgeBaseService{repo: repo}
+
+	// Explicit request values win over the preset, unset ones come from it
+	kb, err := svc.CreateKnowledgeBase(ctx, &types.KnowledgeBase{
+		Name:           "kb",
+		ChunkingPreset: "code",
+		ChunkingConfig: types.ChunkingConfig{ChunkOverlap: 300},
+	})
+	if err != nil {
+		t.Fatalf("CreateKnowledgeBase() error = %v", err)
+	}
+	cfg := kb.ChunkingConfig
+	if cfg.ChunkSize != 2000 || cfg.ChunkOverlap != 300 || !slices.Equal(cfg.Separators, []string{"\n\n", "\n"}) {
+		t.Fatalf("unexpected chunking config %+v", cfg)
+	}
+
+	if _, err := svc.CreateKnowledgeBase(ctx, &types.KnowledgeBase{Name: "kb", ChunkingPreset: "prose"}); err == nil {
+		t.Fatal("expected unknown preset to be rejected")
+	}
+}
+
+func TestChunkingPresetsValidate(t *testing.T) {
+	valid := types.ChunkingPresets{{Name: "prose", ChunkSize: 500, ChunkOverlap: 50}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	for name, presets := range map[string]types.ChunkingPresets{
+		"empty name":      {{ChunkSize: 500}},
+		"duplicate name":  {{Name: "a", ChunkSize: 500}, {Name: "a", ChunkSize: 800}},
+		"zero chunk size": {{Name: "a"}},
+		"overlap too big": {{Name: "a", ChunkSize: 500, ChunkOverlap: 500}},
+	} {
+		if err := presets.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+// fakeSearchKBRepo serves a single knowledge base to HybridSearch.
+type fakeSearchKBRepo struct {
+	interfaces.KnowledgeBaseRepository
+	kb *types.KnowledgeBase
+}
+
+func (r *fakeSearchKBRepo) GetKnowledgeBaseByID(_ context.Context, _ string) (*types.KnowledgeBase, error) {
+	return r.kb, nil
+}
+
+func TestHybridSearchRetrieveTopK(t *testing.T) {
+	tenant := fakeRetrieveEngineTenant(1)
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	search := func(kbConfig *types.RetrievalConfig, params types.SearchParams) int {
+		t.Helper()
+		engine := &fakeRetrieveEngine{}
+		svc := &knowledgeBaseService{
+			repo: &fakeSearchKBRepo{kb: &types.KnowledgeBase{
+				ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument, EmbeddingModelID: "embedding-model",
+				RetrievalConfig: kbConfig,
+			}},
+			modelService:   newFakeReembedModelService("embedding-model"),
+			retrieveEngine: &fakeRetrieveEngineRegistry{engine: engine},
+		}
+		params.QueryText = "退款"
+		if _, err := svc.HybridSearch(ctx, "kb1", params); err != nil {
+			t.Fatalf("HybridSearch() error = %v", err)
+		}
+		if len(engine.retrieveTopK) != 1 {
+			t.Fatalf("expected one retrieval, got %v", engine.retrieveTopK)
+		}
+		return engine.retrieveTopK[0]
+	}
+
+	if got := search(nil, types.SearchParams{MatchCount: 10}); got != 30 {
+		t.Fatalf("expected the default pool of 3x match count, got %d", got)
+	}
+	if got := search(&types.RetrievalConfig{RetrieveTopK: 80}, types.SearchParams{MatchCount: 10}); got != 80 {
+		t.Fatalf("expected the knowledge base pool, got %d", got)
+	}
+	if got := search(&types.RetrievalConfig{RetrieveTopK: 80},
+		types.SearchParams{MatchCount: 10, RetrieveTopK: 120}); got != 120 {
+		t.Fatalf("expected the session pool, got %d", got)
+	}
+	if got := search(&types.RetrievalConfig{RetrieveTopK: 20}, types.SearchParams{MatchCount: 10}); got != 30 {
+		t.Fatalf("expected a smaller pool not to shrink the default, got %d", got)
+	}
+	if got := search(nil, types.SearchParams{MatchCount: 10, RetrieveTopK: 5000}); got != types.MaxRetrieveTopK {
+		t.Fatalf("expected the pool to be capped, got %d", got)
+	}
+}
+
+// fakeUpdateKBRepo serves a single knowledge base and records its updates.
+type fakeUpdateKBRepo struct {
+	interfaces.KnowledgeBaseRepository
+	kb      *types.KnowledgeBase
+	updated int
+}
+
+func (r *fakeUpdateKBRepo) GetKnowledgeBaseByID(_ context.Context, _ string) (*types.KnowledgeBase, error) {
+	return r.kb, nil
+}
+
+func (r *fakeUpdateKBRepo) UpdateKnowledgeBase(_ context.Context, _ *types.KnowledgeBase) error {
+	r.updated++
+	return nil
+}
+
+func TestUpdateKnowledgeBaseValidatesFAQFallbackEntry(t *testing.T) {
+	repo := &fakeUpdateKBRepo{kb: &types.KnowledgeBase{ID: "kb1", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ}}
+	svc := &knowledgeBaseService{
+		repo: repo,
+		chunkRepo: &fakeChunkRepo{chunks: []*types.Chunk{
+			{ID: "c1", SeqID: 7, KnowledgeBaseID: "kb1", ChunkType: types.ChunkTypeFAQ},
+			{ID: "c2", SeqID: 8, KnowledgeBaseID: "kb2", ChunkType: types.ChunkTypeFAQ},
+		}},
+	}
+	update := func(cfg *types.FAQConfig) error {
+		_, err := svc.UpdateKnowledgeBase(context.Background(), "kb1", "FAQ", "",
+			&types.KnowledgeBaseConfig{FAQConfig: cfg})
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *types.FAQConfig
+		wantErr bool
+	}{
+		{name: "entry of the knowledge base", cfg: &types.FAQConfig{NoMatchFallback: types.FAQNoMatchFallbackEntry, FallbackEntryID: 7}},
+		{name: "entry without id is a flag", cfg: &types.FAQConfig{NoMatchFallback: types.FAQNoMatchFallbackEntry}},
+		{name: "unused id", cfg: &types.FAQConfig{NoMatchFallback: types.FAQNoMatchFallbackFlag, FallbackEntryID: 99}},
+		{name: "missing entry", cfg: &types.FAQConfig{NoMatchFallback: types.FAQNoMatchFallbackEntry, FallbackEntryID: 99}, wantErr: true},
+		{name: "entry of another knowledge base", cfg: &types.FAQConfig{NoMatchFallback: types.FAQNoMatchFallbackEntry, FallbackEntryID: 8}, wantErr: true},
+		{name: "negative id", cfg: &types.FAQConfig{FallbackEntryID: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		before := repo.updated
+		err := update(tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: UpdateKnowledgeBase() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr && repo.updated != before {
+			t.Errorf("%s: expected the invalid config not to be saved", tt.name)
+		}
+	}
+}
+
+func TestApplyChunkTypeWeights(t *testing.T) {
+	cfg := &types.RetrievalConfig{ChunkTypeWeights: map[string]float64{
+		types.ChunkTypeText: 1, types.RetrievalWeightKeyQuestion: 0.5,
+	}}
+	results := []*types.IndexWithScore{
+		{SourceID: "c1-q1760000000000000000", ChunkID: "c1", ChunkType: types.ChunkTypeText, Score: 0.9},
+		{SourceID: "c2-part-1", ChunkID: "c2", ChunkType: types.ChunkTypeText, Score: 0.8},
+		{SourceID: "c3", ChunkID: "c3", Score: 0.7},
+		{SourceID: "c4-link-0", ChunkID: "c4", ChunkType: types.ChunkTypeText, Score: 0.6},
+	}
+	applyChunkTypeWeights(cfg, results)
+
+	// Only the generated question is down-weighted, split parts and link anchors keep the text weight
+	var got []string
+	for _, r := range results {
+		got = append(got, r.SourceID)
+	}
+	if want := []string{"c2-part-1", "c3", "c4-link-0", "c1-q1760000000000000000"}; !slices.Equal(got, want) {
+		t.Fatalf("applyChunkTypeWeights() order = %v, want %v", got, want)
+	}
+	if results[3].Score != 0.45 {
+		t.Fatalf("expected the question hit to be weighted to 0.45, got %v", results[3].Score)
+	}
+}
@@ -314,6 +314,41 @@ func (c *CompositeRetrieveEngine) DeleteByKnowledgeIDList(ctx context.Context,
 	})
 }
 
+// knowledgeBaseIndexDeleter is implemented by engines that may support deleting a whole knowledge base
+type knowledgeBaseIndexDeleter interface {
+	DeleteByKnowledgeBaseID(ctx context.Context,
+		knowledgeBaseID string, dimension int, knowledgeType string) (handled bool, err error)
+}
+
+// DeleteByKnowledgeBaseID deletes all vector embeddings of a knowledge base from all registered repositories.
+// Repositories without a knowledge base scoped delete fall back to deleting by knowledgeIDList.
+func (c *CompositeRetrieveEngine) DeleteByKnowledgeBaseID(ctx context.Context,
+	knowledgeBaseID string, knowledgeIDList []string, dimension int, knowledgeType string,
+) error {
+	return c.concurrentExecWithError(ctx, func(ctx context.Context, engineInfo *engineInfo) error {
+		if deleter, ok := engineInfo.retrieveEngine.(knowledgeBaseIndexDeleter); ok {
+			handled, err := deleter.DeleteByKnowledgeBaseID(ctx, knowledgeBaseID, dimension, knowledgeType)
+			if err != nil {
+				logger.GetLogger(ctx).Errorf("Repository %s failed to delete knowledge base: %v",
+					engineInfo.retrieveEngine.EngineType(), err)
+				return err
+			}
+			if handled {
+				return nil
+			}
+		}
+		if len(knowledgeIDList) == 0 {
+			return nil
+		}
+		if err := engineInfo.retrieveEngine.DeleteByKnowledgeIDList(ctx, knowledgeIDList, dimension, knowledgeType); err != nil {
+			logger.GetLogger(ctx).Errorf("Repository %s failed to delete knowledge ID list: %v",
+				engineInfo.retrieveEngine.EngineType(), err)
+			return err
+		}
+		return nil
+	})
+}
+
 // EstimateStorageSize estimates the storage size required for the provided index information
 func (c *CompositeRetrieveEngine) EstimateStorageSize(ctx context.Context,
 	embedder embedding.Embedder, indexInfoList []*types.IndexInfo,
@@ -45,7 +45,7 @@ func (v *KeywordsVectorHybridRetrieveEngineService) Retrieve(ctx context.Context
 func (v *KeywordsVectorHybridRetrieveEngineService) Index(ctx context.Context,
 	embedder embedding.Embedder, indexInfo *types.IndexInfo, retrieverTypes []types.RetrieverType,
 ) error {
-	params := make(map[string]any)
+	params := newSaveParams([]*types.IndexInfo{indexInfo})
 	embeddingMap := make(map[string][]float32)
 	if slices.Contains(retrieverTypes, types.VectorRetrieverType) {
 		embedding, err := embedder.Embed(ctx, indexInfo.Content)
@@ -111,6 +111,16 @@ func (v *KeywordsVectorHybridRetrieveEngineService) BatchIndex(ctx context.Conte
 	return v.boundedConcurrentBatchSaveNoEmbedding(ctx, chunks, maxConcurrency)
 }
 
+// newSaveParams builds the repository save params carrying the enabled status of each chunk,
+// so entries of disabled chunks are written disabled instead of being switched off afterwards
+func newSaveParams(indexInfoList []*types.IndexInfo) map[string]any {
+	chunkEnabled := make(map[string]bool, len(indexInfoList))
+	for _, indexInfo := range indexInfoList {
+		chunkEnabled[indexInfo.ChunkID] = indexInfo.IsEnabled
+	}
+	return map[string]any{"chunk_enabled": chunkEnabled}
+}
+
 // concurrentBatchSave saves all batches concurrently without concurrency limit
 func (v *KeywordsVectorHybridRetrieveEngineService) concurrentBatchSave(
 	ctx context.Context,
@@ -121,7 +131,7 @@ func (v *KeywordsVectorHybridRetrieveEngineService) concurrentBatchSave(
 	g, ctx := errgroup.WithContext(ctx)
 	for i, indexChunk := range chunks {
 		g.Go(func() error {
-			params := make(map[string]any)
+			params := newSaveParams(indexChunk)
 			embeddingMap := make(map[string][]float32)
 			for j, indexInfo := range indexChunk {
 				embeddingMap[indexInfo.SourceID] = embeddings[i*batchSize+j]
@@ -153,7 +163,7 @@ func (v *KeywordsVectorHybridRetrieveEngineService) boundedConcurrentBatchSave(
 				return ctx.Err()
 			}
 
-			params := make(map[string]any)
+			params := newSaveParams(indexChunk)
 			embeddingMap := make(map[string][]float32)
 			for j, indexInfo := range indexChunk {
 				embeddingMap[indexInfo.SourceID] = embeddings[i*batchSize+j]
@@ -173,7 +183,7 @@ func (v *KeywordsVectorHybridRetrieveEngineService) concurrentBatchSaveNoEmbeddi
 	g, ctx := errgroup.WithContext(ctx)
 	for _, indexChunk := range chunks {
 		g.Go(func() error {
-			params := make(map[string]any)
+			params := newSaveParams(indexChunk)
 			return v.indexRepository.BatchSave(ctx, indexChunk, params)
 		})
 	}
@@ -198,7 +208,7 @@ func (v *KeywordsVectorHybridRetrieveEngineService) boundedConcurrentBatchSaveNo
 				return ctx.Err()
 			}
 
-			params := make(map[string]any)
+			params := newSaveParams(indexChunk)
 			return v.indexRepository.BatchSave(ctx, indexChunk, params)
 		})
 	}
@@ -226,6 +236,18 @@ func (v *KeywordsVectorHybridRetrieveEngineService) DeleteByKnowledgeIDList(ctx
 	return v.indexRepository.DeleteByKnowledgeIDList(ctx, knowledgeIDList, dimension, knowledgeType)
 }
 
+// DeleteByKnowledgeBaseID deletes all vectors of a knowledge base when the index repository
+// supports a knowledge base scoped delete; handled is false otherwise
+func (v *KeywordsVectorHybridRetrieveEngineService) DeleteByKnowledgeBaseID(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string,
+) (handled bool, err error) {
+	deleter, ok := v.indexRepository.(interfaces.KnowledgeBaseIndexDeleter)
+	if !ok {
+		return false, nil
+	}
+	return true, deleter.DeleteByKnowledgeBaseID(ctx, knowledgeBaseID, dimension, knowledgeType)
+}
+
 // Support returns the retriever types supported by this engine
 func (v *KeywordsVectorHybridRetrieveEngineService) Support() []types.RetrieverType {
 	return v.indexRepository.Support()
@@ -488,6 +488,7 @@ func (s *sessionService) KnowledgeQA(
 	vectorThreshold := s.cfg.Conversation.VectorThreshold
 	keywordThreshold := s.cfg.Conversation.KeywordThreshold
 	embeddingTopK := s.cfg.Conversation.EmbeddingTopK
+	retrieveTopK := 0
 	rerankTopK := s.cfg.Conversation.RerankTopK
 	rerankThreshold := s.cfg.Conversation.RerankThreshold
 	maxRounds := s.cfg.Conversation.MaxRounds
@@ -554,6 +555,9 @@ func (s *sessionService) KnowledgeQA(
 		if customAgent.Config.EmbeddingTopK > 0 {
 			embeddingTopK = customAgent.Config.EmbeddingTopK
 		}
+		if customAgent.Config.RetrieveTopK > 0 {
+			retrieveTopK = customAgent.Config.RetrieveTopK
+		}
 		if customAgent.Config.KeywordThreshold > 0 {
 			keywordThreshold = customAgent.Config.KeywordThreshold
 		}
@@ -657,6 +661,7 @@ func (s *sessionService) KnowledgeQA(
 		VectorThreshold:      vectorThreshold,
 		KeywordThreshold:     keywordThreshold,
 		EmbeddingTopK:        embeddingTopK,
+		RetrieveTopK:         retrieveTopK,
 		RerankModelID:        rerankModelID,
 		RerankTopK:           rerankTopK,
 		RerankThreshold:      rerankThreshold,
@@ -221,7 +221,9 @@ func (s *knowledgeTagService) UpdateTag(
 // DeleteTag deletes a tag. When force=true, also deletes all chunks under this tag.
 // For document-type knowledge bases, also deletes all knowledge files under this tag.
 // When contentOnly=true, only deletes the content under the tag but keeps the tag itself.
-func (s *knowledgeTagService) DeleteTag(ctx context.Context, id string, force bool, contentOnly bool, excludeIDs []string) error {
+func (s *knowledgeTagService) DeleteTag(
+	ctx context.Context, id string, force bool, contentOnly bool, reassign bool, excludeIDs []string,
+) error {
 	if id == "" {
 		return werrors.NewBadRequestError("标签ID不能为空")
 	}
@@ -230,6 +232,14 @@ func (s *knowledgeTagService) DeleteTag(ctx context.Context, id string, force bo
 	if err != nil {
 		return err
 	}
+	if reassign {
+		if force || contentOnly {
+			return werrors.NewBadRequestError("reassign 不能与 force 或 content_only 同时使用")
+		}
+		if tag.Name == types.UntaggedTagName {
+			return werrors.NewBadRequestError("未分类标签不能重新分配后删除")
+		}
+	}
 
 	// Get KB info for embedding model
 	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, tag.KnowledgeBaseID)
@@ -313,23 +323,42 @@ func (s *knowledgeTagService) DeleteTag(ctx context.Context, id string, force bo
 		return nil
 	}
 
-	if !force && (kCount > 0 || cCount > 0) {
-		return werrors.NewBadRequestError("标签仍有知识或FAQ条目引用，无法删除")
-	}
-
-	// When force=true, delete all content under this tag first
-	if force {
-		// For document-type KB, delete knowledge files first (which will also delete chunks)
-		if kb.Type == types.KnowledgeBaseTypeDocument && kCount > 0 {
-			if err := enqueueKnowledgeDeleteTask(); err != nil {
-				return err
-			}
-		} else if cCount > 0 {
-			// For FAQ-type KB, only delete chunks
-			if err := deleteChunksAndEnqueueIndexDelete(); err != nil {
+	// reassign mode: move content to the untagged tag, then delete the tag
+	if reassign {
+		if kCount > 0 || cCount > 0 {
+			if err := s.reassignToUntagged(ctx, tenantID, kb.ID, tag.ID, tenantInfo); err != nil {
 				return err
 			}
 		}
+		return s.repo.Delete(ctx, tenantID, id)
+	}
+
+	if !force {
+		if kCount > 0 || cCount > 0 {
+			return werrors.NewBadRequestError("标签仍有知识或FAQ条目引用，无法删除")
+		}
+		// Content may be tagged between the count and the delete; re-check atomically.
+		deleted, err := s.repo.DeleteIfUnused(ctx, tenantID, kb.ID, id)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return werrors.NewBadRequestError("标签仍有知识或FAQ条目引用，无法删除")
+		}
+		return nil
+	}
+
+	// force=true: delete all content under this tag first
+	// For document-type KB, delete knowledge files first (which will also delete chunks)
+	if kb.Type == types.KnowledgeBaseTypeDocument && kCount > 0 {
+		if err := enqueueKnowledgeDeleteTask(); err != nil {
+			return err
+		}
+	} else if cCount > 0 {
+		// For FAQ-type KB, only delete chunks
+		if err := deleteChunksAndEnqueueIndexDelete(); err != nil {
+			return err
+		}
 	}
 
 	// If there are excludeIDs, we cannot delete the tag itself as it still has content
@@ -339,6 +368,36 @@ func (s *knowledgeTagService) DeleteTag(ctx context.Context, id string, force bo
 	return s.repo.Delete(ctx, tenantID, id)
 }
 
+// reassignToUntagged moves all knowledges and chunks under tagID to the KB's "未分类" tag
+// and syncs the new tag ID to retriever indices.
+func (s *knowledgeTagService) reassignToUntagged(
+	ctx context.Context, tenantID uint64, kbID string, tagID string, tenantInfo *types.Tenant,
+) error {
+	untagged, err := s.FindOrCreateTagByName(ctx, kbID, types.UntaggedTagName)
+	if err != nil {
+		return err
+	}
+	chunkIDs, err := s.repo.ReassignReferences(ctx, tenantID, kbID, tagID, untagged.ID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to reassign content of tag %s: %v", tagID, err)
+		return werrors.NewInternalServerError("重新分配标签下的数据失败")
+	}
+	logger.Infof(ctx, "Reassigned %d chunks from tag %s to untagged tag %s", len(chunkIDs), tagID, untagged.ID)
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+
+	tagUpdates := make(map[string]string, len(chunkIDs))
+	for _, chunkID := range chunkIDs {
+		tagUpdates[chunkID] = untagged.ID
+	}
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err != nil {
+		return err
+	}
+	return retrieveEngine.BatchUpdateChunkTagID(ctx, tagUpdates)
+}
+
 // enqueueIndexDeleteTask enqueues an async task for index deletion (low priority)
 func (s *knowledgeTagService) enqueueIndexDeleteTask(ctx context.Context,
 	tenantID uint64, kbID, embeddingModelID, kbType string, chunkIDs []string, effectiveEngines []types.RetrieverEngineParams,
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// fakeTagRepo is an in-memory tag repository with fixed reference counts.
+type fakeTagRepo struct {
+	interfaces.KnowledgeTagRepository
+	tags           map[string]*types.KnowledgeTag
+	knowledgeCount int64
+	chunkCount     int64
+	reassignedTo   string
+}
+
+func (r *fakeTagRepo) GetByID(_ context.Context, _ uint64, id string) (*types.KnowledgeTag, error) {
+	if tag, ok := r.tags[id]; ok {
+		return tag, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeTagRepo) GetByIDs(_ context.Context, _ uint64, ids []string) ([]*types.KnowledgeTag, error) {
+	var tags []*types.KnowledgeTag
+	for _, id := range ids {
+		if tag, ok := r.tags[id]; ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (r *fakeTagRepo) GetBySeqIDs(_ context.Context, _ uint64, seqIDs []int64) ([]*types.KnowledgeTag, error) {
+	var tags []*types.KnowledgeTag
+	for _, tag := range r.tags {
+		if slices.Contains(seqIDs, tag.SeqID) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (r *fakeTagRepo) GetByName(_ context.Context, _ uint64, kbID string, name string) (*types.KnowledgeTag, error) {
+	for _, tag := range r.tags {
+		if tag.KnowledgeBaseID == kbID && tag.Name == name {
+			return tag, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeTagRepo) ListByKB(_ context.Context, _ uint64, kbID string,
+	_ *types.Pagination, _ string,
+) ([]*types.KnowledgeTag, int64, error) {
+	var tags []*types.KnowledgeTag
+	for _, tag := range r.tags {
+		if tag.KnowledgeBaseID == kbID {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, int64(len(tags)), nil
+}
+
+func (r *fakeTagRepo) Create(_ context.Context, tag *types.KnowledgeTag) error {
+	r.tags[tag.ID] = tag
+	return nil
+}
+
+func (r *fakeTagRepo) CountReferences(_ context.Context, _ uint64, _ string, _ string) (int64, int64, error) {
+	return r.knowledgeCount, r.chunkCount, nil
+}
+
+func (r *fakeTagRepo) Delete(_ context.Context, _ uint64, id string) error {
+	delete(r.tags, id)
+	return nil
+}
+
+func (r *fakeTagRepo) DeleteIfUnused(_ context.Context, _ uint64, _ string, id string) (bool, error) {
+	if r.knowledgeCount > 0 || r.chunkCount > 0 {
+		return false, nil
+	}
+	delete(r.tags, id)
+	return true, nil
+}
+
+func (r *fakeTagRepo) ReassignReferences(_ context.Context, _ uint64, _ string, _ string, toTagID string) ([]string, error) {
+	r.reassignedTo = toTagID
+	r.knowledgeCount, r.chunkCount = 0, 0
+	return nil, nil
+}
+
+type fakeTagKBService struct {
+	interfaces.KnowledgeBaseService
+	kb *types.KnowledgeBase
+}
+
+func (s *fakeTagKBService) GetKnowledgeBaseByID(_ context.Context, _ string) (*types.KnowledgeBase, error) {
+	return s.kb, nil
+}
+
+func newTagTestService(knowledgeCount int64) (*knowledgeTagService, *fakeTagRepo, context.Context) {
+	kb := &types.KnowledgeBase{ID: "kb-1", TenantID: 1, Type: types.KnowledgeBaseTypeDocument}
+	repo := &fakeTagRepo{
+		tags: map[string]*types.KnowledgeTag{
+			"tag-1": {ID: "tag-1", TenantID: 1, KnowledgeBaseID: kb.ID, Name: "产品"},
+		},
+		knowledgeCount: knowledgeCount,
+	}
+	svc := &knowledgeTagService{kbService: &fakeTagKBService{kb: kb}, repo: repo}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, &types.Tenant{ID: 1})
+	return svc, repo, ctx
+}
+
+func TestDeleteTagInUse(t *testing.T) {
+	svc, repo, ctx := newTagTestService(2)
+
+	err := svc.DeleteTag(ctx, "tag-1", false, false, false, nil)
+	if err == nil {
+		t.Fatal("expected deleting an in-use tag to fail")
+	}
+	if appErr, ok := werrors.IsAppError(err); !ok || appErr.Code != werrors.ErrBadRequest {
+		t.Fatalf("expected bad request error, got %v", err)
+	}
+	if _, ok := repo.tags["tag-1"]; !ok {
+		t.Fatal("in-use tag must not be deleted")
+	}
+}
+
+func TestDeleteTagInUseReassign(t *testing.T) {
+	svc, repo, ctx := newTagTestService(2)
+
+	if err := svc.DeleteTag(ctx, "tag-1", false, false, true, nil); err != nil {
+		t.Fatalf("reassign delete failed: %v", err)
+	}
+	if _, ok := repo.tags["tag-1"]; ok {
+		t.Fatal("expected tag to be deleted after reassign")
+	}
+	untagged, ok := repo.tags[repo.reassignedTo]
+	if !ok || untagged.Name != types.UntaggedTagName {
+		t.Fatalf("expected content reassigned to the untagged tag, got %q", repo.reassignedTo)
+	}
+
+	// The untagged tag itself cannot be reassigned.
+	if err := svc.DeleteTag(ctx, untagged.ID, false, false, true, nil); err == nil {
+		t.Fatal("expected reassigning the untagged tag to fail")
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// sealTaskSecret encrypts v with the tenant AES key so secrets can travel in asynq task payloads
+// without being stored in plain text in Redis
+func sealTaskSecret(v any) (string, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	aesgcm, err := newTaskSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aesgcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// openTaskSecret decrypts a value sealed by sealTaskSecret into v
+func openTaskSecret(sealed string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return errors.New("invalid task secret encoding")
+	}
+	aesgcm, err := newTaskSecretCipher()
+	if err != nil {
+		return err
+	}
+	if len(data) < aesgcm.NonceSize() {
+		return errors.New("invalid task secret length")
+	}
+	nonce, ciphertext := data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():]
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return errors.New("task secret is invalid or has been tampered with")
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+func newTaskSecretCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(apiKeySecret())
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
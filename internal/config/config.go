@@ -96,6 +96,66 @@ type KnowledgeBaseConfig struct {
 	SplitMarkers    []string               `yaml:"split_markers"    json:"split_markers"`
 	KeepSeparator   bool                   `yaml:"keep_separator"   json:"keep_separator"`
 	ImageProcessing *ImageProcessingConfig `yaml:"image_processing" json:"image_processing"`
+	URLImport       *URLImportConfig       `yaml:"url_import"       json:"url_import"`
+	// DeleteConcurrency 全局允许同时执行的知识删除子任务数（向量/分块/文件/图谱），<=0 时使用默认值 16
+	DeleteConcurrency int `yaml:"delete_concurrency" json:"delete_concurrency"`
+	// ManualContentMaxLength 手工知识内容允许的最大字符数，<=0 时使用默认值 200000
+	ManualContentMaxLength int `yaml:"manual_content_max_length" json:"manual_content_max_length"`
+	// ChunkSnapshot 重新解析前保留旧分块快照，便于对比新旧分块效果，默认关闭
+	ChunkSnapshot *ChunkSnapshotConfig `yaml:"chunk_snapshot" json:"chunk_snapshot"`
+	// ReparseCooldown 同一知识两次重新解析请求之间的最短间隔，用于拦截重复提交；0 时使用默认值 10s，负数表示关闭
+	ReparseCooldown time.Duration `yaml:"reparse_cooldown" json:"reparse_cooldown"`
+	// FileURLDownload file_url 导入时下载远程文件的并发与超时配置
+	FileURLDownload *FileURLDownloadConfig `yaml:"file_url_download" json:"file_url_download"`
+	// StoreParsedMarkdown 解析完成后将文档的完整 Markdown 存入对象存储，供查看解析结果；默认关闭以节省存储空间
+	StoreParsedMarkdown bool `yaml:"store_parsed_markdown" json:"store_parsed_markdown"`
+	// DisableMultimodal 全局关闭多模态（VLM）处理，所有导入与重新解析按纯文本处理，用于 VLM 服务故障时的应急降级
+	DisableMultimodal bool `yaml:"disable_multimodal" json:"disable_multimodal"`
+	// TaskPayloadInlineMaxSize 异步任务 payload 允许内联的最大字节数，超出时大字段转存到对象存储；<=0 时使用默认值 50KB
+	TaskPayloadInlineMaxSize int `yaml:"task_payload_inline_max_size" json:"task_payload_inline_max_size"`
+	// CloneDeleteConcurrency 知识库复制时删除目标中多余知识的并发批次数（每批 10 个知识）；<=0 时使用默认值 10
+	CloneDeleteConcurrency int `yaml:"clone_delete_concurrency" json:"clone_delete_concurrency"`
+	// CloneAddConcurrency 知识库复制时并发复制（重新向量化）的知识数；<=0 时使用默认值 10
+	CloneAddConcurrency int `yaml:"clone_add_concurrency" json:"clone_add_concurrency"`
+	// FAQImportArchive FAQ 导入结束后将完整进度归档到数据库，供 Redis 进度过期后事后排查，默认关闭
+	FAQImportArchive *FAQImportArchiveConfig `yaml:"faq_import_archive" json:"faq_import_archive"`
+}
+
+// FAQImportArchiveConfig FAQ 导入进度归档配置
+type FAQImportArchiveConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Retention 归档保留时长，<=0 时使用默认值 720h（30 天）
+	Retention time.Duration `yaml:"retention" json:"retention"`
+}
+
+// FileURLDownloadConfig file_url 导入下载配置
+type FileURLDownloadConfig struct {
+	// MaxConcurrent 全局同时进行的下载数，超出的下载排队等待；<=0 时使用默认值 8
+	MaxConcurrent int `yaml:"max_concurrent" json:"max_concurrent"`
+	// Timeout 单次下载超时；<=0 时使用默认值 60s
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// TimeoutByFileType 按文件类型（扩展名，如 pdf、pptx）覆盖下载超时
+	TimeoutByFileType map[string]time.Duration `yaml:"timeout_by_file_type" json:"timeout_by_file_type"`
+	// ProbeContentType URL 路径没有可识别的扩展名时，先发送 HEAD 请求根据 Content-Type/Content-Disposition
+	// 判断是否为文件下载；请求失败时按网页处理
+	ProbeContentType bool `yaml:"probe_content_type" json:"probe_content_type"`
+}
+
+// ChunkSnapshotConfig 重新解析前分块快照配置
+type ChunkSnapshotConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// TTL 快照保留时长，<=0 时使用默认值 24h
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+}
+
+// URLImportConfig URL 导入白名单配置，各项为空时不做限制（协议默认仅允许 http/https）
+type URLImportConfig struct {
+	// AllowedSchemes 允许的协议，如 ["https"]
+	AllowedSchemes []string `yaml:"allowed_schemes" json:"allowed_schemes"`
+	// AllowedPorts 允许的端口，未显式指定端口时按协议默认端口（80/443）判断
+	AllowedPorts []int `yaml:"allowed_ports" json:"allowed_ports"`
+	// AllowedDomains 允许的域名，同时匹配其子域名，如 "example.com" 匹配 "docs.example.com"
+	AllowedDomains []string `yaml:"allowed_domains" json:"allowed_domains"`
 }
 
 // ImageProcessingConfig 图像处理配置
@@ -118,6 +118,112 @@ func (h *ChunkHandler) GetChunkByIDOnly(c *gin.Context) {
 	})
 }
 
+// GetChunkWithKnowledge godoc
+// @Summary      获取分块及其所属知识
+// @Description  通过分块ID获取分块内容以及所属知识的标题、类型、来源和文件名，用于将检索结果展开为引用；支持共享知识库
+// @Tags         分块管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "分块ID"
+// @Success      200  {object}  map[string]interface{}  "分块及所属知识"
+// @Failure      404  {object}  errors.AppError         "分块不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /chunks/by-id/{id}/knowledge [get]
+func (h *ChunkHandler) GetChunkWithKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	chunkID := secutils.SanitizeForLog(c.Param("id"))
+	if chunkID == "" {
+		c.Error(errors.NewBadRequestError("Chunk ID cannot be empty"))
+		return
+	}
+
+	chunk, err := h.service.GetChunkByIDOnly(ctx, chunkID)
+	if err != nil {
+		if err == service.ErrChunkNotFound {
+			c.Error(errors.NewNotFoundError("Chunk not found"))
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	effCtx, err := h.effectiveCtxForKnowledge(c, chunk.KnowledgeID, types.OrgRoleViewer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.kgService.GetChunkWithKnowledge(effCtx, chunkID)
+	if err != nil {
+		if err == service.ErrChunkNotFound {
+			c.Error(errors.NewNotFoundError("Chunk not found"))
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+	if result.Chunk.Content != "" {
+		result.Chunk.Content = secutils.SanitizeForDisplay(result.Chunk.Content)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetChunksWithKnowledgeRequest defines the request body for batch chunk expansion
+type GetChunksWithKnowledgeRequest struct {
+	ChunkIDs []string `json:"chunk_ids" binding:"required,min=1"`
+}
+
+// GetChunksWithKnowledge godoc
+// @Summary      批量获取分块及其所属知识
+// @Description  批量将检索结果中的分块ID展开为分块及所属知识信息，避免逐条查询；无权访问或不存在的分块会被忽略
+// @Tags         分块管理
+// @Accept       json
+// @Produce      json
+// @Param        request  body      GetChunksWithKnowledgeRequest  true  "分块ID列表"
+// @Success      200      {object}  map[string]interface{}         "分块及所属知识列表"
+// @Failure      400      {object}  errors.AppError                "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /chunks/with-knowledge [post]
+func (h *ChunkHandler) GetChunksWithKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req GetChunksWithKnowledgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+
+	results, err := h.kgService.GetChunksWithKnowledge(ctx, req.ChunkIDs)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+	for _, result := range results {
+		if result.Chunk.Content != "" {
+			result.Chunk.Content = secutils.SanitizeForDisplay(result.Chunk.Content)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    results,
+	})
+}
+
 // ListKnowledgeChunks godoc
 // @Summary      获取知识分块列表
 // @Description  获取指定知识下的所有分块列表，支持分页
@@ -296,6 +402,140 @@ func (h *ChunkHandler) UpdateChunk(c *gin.Context) {
 	})
 }
 
+// SetChunkTagRequest defines the request structure for setting a chunk's tag
+type SetChunkTagRequest struct {
+	TagID *string `json:"tag_id"`
+}
+
+// SetChunkTagBatchRequest defines the request structure for setting chunk tags in batch
+type SetChunkTagBatchRequest struct {
+	// Key: chunk ID, Value: tag ID (null or empty to remove tag)
+	Updates map[string]*string `json:"updates" binding:"required,min=1"`
+}
+
+// SetChunkTag godoc
+// @Summary      设置分块标签
+// @Description  为单个分块设置标签，标签必须属于分块所在知识库；tag_id 为空时清除标签
+// @Tags         分块管理
+// @Accept       json
+// @Produce      json
+// @Param        knowledge_id  path      string              true  "知识ID"
+// @Param        id            path      string              true  "分块ID"
+// @Param        request       body      SetChunkTagRequest  true  "标签"
+// @Success      200           {object}  map[string]interface{}  "设置成功"
+// @Failure      400           {object}  errors.AppError         "请求参数错误"
+// @Failure      404           {object}  errors.AppError         "分块不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /chunks/{knowledge_id}/{id}/tag [put]
+func (h *ChunkHandler) SetChunkTag(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	chunk, knowledgeID, effCtx, err := h.validateAndGetChunk(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req SetChunkTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errorf(ctx, "Failed to parse request parameters: %s", secutils.SanitizeForLog(err.Error()))
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.kgService.SetChunkTag(effCtx, chunk.ID, req.TagID); err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Chunk tag updated, knowledge ID: %s, chunk ID: %s",
+		secutils.SanitizeForLog(knowledgeID), secutils.SanitizeForLog(chunk.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// SetChunkTagBatch godoc
+// @Summary      批量设置分块标签
+// @Description  批量为同一知识下的分块设置标签，标签必须属于分块所在知识库；值为空时清除标签
+// @Tags         分块管理
+// @Accept       json
+// @Produce      json
+// @Param        knowledge_id  path      string                   true  "知识ID"
+// @Param        request       body      SetChunkTagBatchRequest  true  "分块ID到标签ID的映射"
+// @Success      200           {object}  map[string]interface{}   "设置成功"
+// @Failure      400           {object}  errors.AppError          "请求参数错误"
+// @Failure      403           {object}  errors.AppError          "分块不属于该知识"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /chunks/{knowledge_id}/tags [put]
+func (h *ChunkHandler) SetChunkTagBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	knowledgeID := secutils.SanitizeForLog(c.Param("knowledge_id"))
+	if knowledgeID == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+	var req SetChunkTagBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errorf(ctx, "Failed to parse request parameters: %s", secutils.SanitizeForLog(err.Error()))
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	effCtx, err := h.effectiveCtxForKnowledge(c, knowledgeID, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// All chunks must belong to the knowledge in the path
+	chunkIDs := make([]string, 0, len(req.Updates))
+	for chunkID := range req.Updates {
+		chunkIDs = append(chunkIDs, chunkID)
+	}
+	tenantID := effCtx.Value(types.TenantIDContextKey).(uint64)
+	chunks, err := h.service.GetRepository().ListChunksByID(effCtx, tenantID, chunkIDs)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+	if len(chunks) != len(chunkIDs) {
+		c.Error(errors.NewNotFoundError("Chunk not found"))
+		return
+	}
+	for _, chunk := range chunks {
+		if chunk.KnowledgeID != knowledgeID {
+			logger.Warnf(ctx, "Chunk does not belong to knowledge, knowledge ID: %s, chunk ID: %s", knowledgeID, chunk.ID)
+			c.Error(errors.NewForbiddenError("No permission to access this chunk"))
+			return
+		}
+	}
+
+	if err := h.kgService.SetChunkTagBatch(effCtx, req.Updates); err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Chunk tags updated in batch, knowledge ID: %s, count: %d", knowledgeID, len(req.Updates))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
 // DeleteChunk godoc
 // @Summary      删除分块
 // @Description  删除指定的分块
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -37,6 +38,11 @@ func NewFAQHandler(
 	}
 }
 
+// knowledgeAccess returns a knowledge handler sharing this handler's services, for knowledge-level access checks
+func (h *FAQHandler) knowledgeAccess() *KnowledgeHandler {
+	return NewKnowledgeHandler(h.knowledgeService, h.kbService, h.kbShareService, h.agentShareService)
+}
+
 // effectiveCtxForKB validates KB access (owner, shared, or via shared agent when requiredPermission is Viewer) and returns context with effectiveTenantID.
 func (h *FAQHandler) effectiveCtxForKB(c *gin.Context, kbID string, requiredPermission types.OrgMemberRole) (context.Context, error) {
 	ctx := c.Request.Context()
@@ -79,6 +85,16 @@ func (h *FAQHandler) effectiveCtxForKB(c *gin.Context, kbID string, requiredPerm
 	return nil, errors.NewForbiddenError("Permission denied to access this knowledge base")
 }
 
+// validateTaskProgressAccess checks that the caller can access the knowledge base a background task
+// progress belongs to. Progress of other tenants is reported as not found so task IDs can't be probed.
+func (h *FAQHandler) validateTaskProgressAccess(c *gin.Context, progress *types.KnowledgeTaskProgress) error {
+	effCtx, err := h.effectiveCtxForKB(c, progress.KBID, types.OrgRoleViewer)
+	if err != nil || effCtx.Value(types.TenantIDContextKey).(uint64) != progress.TenantID {
+		return errors.NewNotFoundError("Task not found")
+	}
+	return nil
+}
+
 // ListEntries godoc
 // @Summary      获取FAQ条目列表
 // @Description  获取知识库下的FAQ条目列表，支持分页和筛选
@@ -139,11 +155,54 @@ func (h *FAQHandler) ListEntries(c *gin.Context) {
 	})
 }
 
+// ListAnswerPendingEntries godoc
+// @Summary      获取待补充答案的FAQ条目
+// @Description  分页获取以“允许无答案”方式导入、尚未补充答案的FAQ条目
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true   "知识库ID"
+// @Param        page       query     int     false  "页码"
+// @Param        page_size  query     int     false  "每页数量"
+// @Success      200        {object}  map[string]interface{}  "待补充答案的FAQ列表"
+// @Failure      400        {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/answer-pending [get]
+func (h *FAQHandler) ListAnswerPendingEntries(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	effCtx, err := h.effectiveCtxForKB(c, kbID, types.OrgRoleViewer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var page types.Pagination
+	if err := c.ShouldBindQuery(&page); err != nil {
+		logger.Error(ctx, "Failed to bind pagination query", err)
+		c.Error(errors.NewBadRequestError("分页参数不合法").WithDetails(err.Error()))
+		return
+	}
+
+	result, err := h.knowledgeService.ListAnswerPendingFAQEntries(effCtx, kbID, &page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
 // UpsertEntries godoc
 // @Summary      批量更新/插入FAQ条目
 // @Description  异步批量更新或插入FAQ条目。支持 dry_run 模式（设置 dry_run=true），异步验证不实际导入。
 // @Description  dry_run 模式是异步操作，返回 task_id，通过 /faq/import/progress/{task_id} 查询进度和结果。
 // @Description  验证内容包括：1) 条目基本格式 2) 重复问题（批次内和知识库已有） 3) 内容安全检查。
+// @Description  设置 allow_empty_answers=true 时允许导入没有答案的条目，条目仅用于问题匹配并标记为待补充答案。
 // @Tags         FAQ管理
 // @Accept       json
 // @Produce      json
@@ -185,6 +244,148 @@ func (h *FAQHandler) UpsertEntries(c *gin.Context) {
 	})
 }
 
+// convertDocumentToFAQRequest is a request for converting a document knowledge into FAQ entries
+type convertDocumentToFAQRequest struct {
+	KnowledgeID string `json:"knowledge_id" binding:"required"`
+}
+
+// ConvertDocumentToFAQ godoc
+// @Summary      从文档知识抽取FAQ条目
+// @Description  使用对话模型从已上传的文档知识中抽取问答对。任务在后台执行，返回进度供轮询，
+// @Description  完成后进度中包含抽取出的条目及置信度，审阅修改后通过提交接口导入。
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                       true  "目标FAQ知识库ID"
+// @Param        request  body      convertDocumentToFAQRequest  true  "源文档知识ID"
+// @Success      200      {object}  map[string]interface{}       "抽取任务进度"
+// @Failure      400      {object}  errors.AppError              "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/convert [post]
+func (h *FAQHandler) ConvertDocumentToFAQ(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	effCtx, err := h.effectiveCtxForKB(c, kbID, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req convertDocumentToFAQRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to bind FAQ convert payload", err)
+		c.Error(errors.NewBadRequestError("请求参数不合法").WithDetails(err.Error()))
+		return
+	}
+
+	// 调用方需要能读取源知识，共享知识库中的源知识按其所属租户读取
+	source, sourceCtx, err := h.knowledgeAccess().resolveKnowledgeAndValidateKBAccess(
+		c, secutils.SanitizeForLog(req.KnowledgeID), types.OrgRoleViewer,
+	)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	progress, err := h.knowledgeService.ConvertDocumentToFAQ(effCtx, sourceCtx, source.ID, kbID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// commitFAQConversionRequest is a request for importing the reviewed entries of a FAQ conversion
+type commitFAQConversionRequest struct {
+	TaskID  string                  `json:"task_id" binding:"required"`
+	Entries []types.FAQEntryPayload `json:"entries"`
+	DryRun  bool                    `json:"dry_run"`
+}
+
+// CommitFAQConversion godoc
+// @Summary      提交从文档抽取的FAQ条目
+// @Description  将抽取任务审阅后的条目通过常规导入流程写入FAQ知识库，不会重新抽取；entries 为空时导入抽取出的全部条目。
+// @Description  设置 dry_run=true 时仅做校验。
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                      true  "目标FAQ知识库ID"
+// @Param        request  body      commitFAQConversionRequest  true  "抽取任务ID及审阅后的条目"
+// @Success      200      {object}  map[string]interface{}      "导入任务ID"
+// @Failure      400      {object}  errors.AppError             "请求参数错误"
+// @Failure      404      {object}  errors.AppError             "抽取任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/convert/commit [post]
+func (h *FAQHandler) CommitFAQConversion(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	effCtx, err := h.effectiveCtxForKB(c, kbID, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req commitFAQConversionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to bind FAQ convert commit payload", err)
+		c.Error(errors.NewBadRequestError("请求参数不合法").WithDetails(err.Error()))
+		return
+	}
+
+	taskID, err := h.knowledgeService.CommitFAQConversion(effCtx, kbID,
+		secutils.SanitizeForLog(req.TaskID), req.Entries, req.DryRun)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"task_id": taskID,
+		},
+	})
+}
+
+// GetFAQConversionProgress godoc
+// @Summary      获取FAQ抽取进度
+// @Description  获取从文档知识抽取FAQ条目任务的进度，完成后包含抽取出的条目
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "抽取进度"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /faq/convert/progress/{task_id} [get]
+func (h *FAQHandler) GetFAQConversionProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+
+	progress, err := h.knowledgeService.GetFAQConversionProgress(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	if err := h.validateTaskProgressAccess(c, &progress.KnowledgeTaskProgress); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
 // CreateEntry godoc
 // @Summary      创建单个FAQ条目
 // @Description  同步创建单个FAQ条目
@@ -351,6 +552,45 @@ func (h *FAQHandler) UpdateEntryFieldsBatch(c *gin.Context) {
 	})
 }
 
+// UpdateEntryFieldsByQuery godoc
+// @Summary      按搜索条件批量更新FAQ字段
+// @Description  按关键词（可限定分类）筛选FAQ条目，对全部命中条目批量更新 is_enabled / is_recommended / tag_id；dry_run=true 时仅预览命中条目
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                     true  "知识库ID"
+// @Param        request  body      types.FAQEntryQueryUpdate  true  "查询与字段更新"
+// @Success      200      {object}  map[string]interface{}     "命中与更新结果"
+// @Failure      400      {object}  errors.AppError            "请求参数错误或命中条目过多"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/fields/by-query [put]
+func (h *FAQHandler) UpdateEntryFieldsByQuery(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	effCtx, err := h.effectiveCtxForKB(c, kbID, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req types.FAQEntryQueryUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to bind FAQ entry query update payload", err)
+		c.Error(errors.NewBadRequestError("请求参数不合法").WithDetails(err.Error()))
+		return
+	}
+	result, err := h.knowledgeService.UpdateFAQEntryFieldsByQuery(effCtx, kbID, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
 // faqDeleteRequest is a request for deleting FAQ entries in batch
 type faqDeleteRequest struct {
 	IDs []int64 `json:"ids" binding:"required,min=1"`
@@ -367,6 +607,12 @@ type addSimilarQuestionsRequest struct {
 	SimilarQuestions []string `json:"similar_questions" binding:"required,min=1"`
 }
 
+// addSimilarQuestionsBatchRequest is a request for adding similar questions to multiple FAQ entries
+type addSimilarQuestionsBatchRequest struct {
+	// Entries maps entry seq_id to the similar questions to append
+	Entries map[int64][]string `json:"entries" binding:"required,min=1"`
+}
+
 // DeleteEntries godoc
 // @Summary      批量删除FAQ条目
 // @Description  批量删除指定的FAQ条目
@@ -410,7 +656,7 @@ func (h *FAQHandler) DeleteEntries(c *gin.Context) {
 
 // SearchFAQ godoc
 // @Summary      搜索FAQ
-// @Description  使用混合搜索在FAQ中搜索，支持两级优先级标签召回：first_priority_tag_ids优先级最高，second_priority_tag_ids次之
+// @Description  使用混合搜索在FAQ中搜索，支持两级优先级标签召回：first_priority_tag_ids优先级最高，second_priority_tag_ids次之；response_mode=answers时仅返回每个条目按答案策略选出的答案及分数
 // @Tags         FAQ管理
 // @Accept       json
 // @Produce      json
@@ -442,27 +688,45 @@ func (h *FAQHandler) SearchFAQ(c *gin.Context) {
 	if req.MatchCount > 200 {
 		req.MatchCount = 200
 	}
-	entries, err := h.knowledgeService.SearchFAQEntries(effCtx, kbID, &req)
+	result, err := h.knowledgeService.SearchFAQEntries(effCtx, kbID, &req)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"success": true,
-		"data":    entries,
-	})
+		"data":    result.Entries,
+		"partial": result.Partial,
+	}
+	if req.ResponseMode == types.FAQSearchResponseModeAnswers {
+		resp["data"] = result.Answers
+	}
+	// no_match/fallback are only reported when the knowledge base configures a no-match fallback
+	if result.NoMatch {
+		resp["no_match"] = true
+		if req.ResponseMode == types.FAQSearchResponseModeAnswers && result.FallbackAnswer != nil {
+			resp["fallback"] = result.FallbackAnswer
+		} else if req.ResponseMode != types.FAQSearchResponseModeAnswers && result.Fallback != nil {
+			resp["fallback"] = result.Fallback
+		}
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // ExportEntries godoc
 // @Summary      导出FAQ条目
-// @Description  将所有FAQ条目导出为CSV文件
+// @Description  将所有FAQ条目导出为CSV、JSON或XLSX文件
 // @Tags         FAQ管理
 // @Accept       json
 // @Produce      text/csv
-// @Param        id   path      string  true  "知识库ID"
-// @Success      200  {file}    file    "CSV文件"
+// @Produce      application/json
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        id                path      string  true   "知识库ID"
+// @Param        include_disabled  query     bool    false  "是否导出已停用条目，默认true"
+// @Param        format            query     string  false  "导出格式：csv（默认）、json、xlsx"
+// @Success      200  {file}    file    "导出文件"
 // @Failure      400  {object}  errors.AppError  "请求参数错误"
 // @Security     Bearer
 // @Security     ApiKeyAuth
@@ -476,19 +740,78 @@ func (h *FAQHandler) ExportEntries(c *gin.Context) {
 		return
 	}
 
-	csvData, err := h.knowledgeService.ExportFAQEntries(effCtx, kbID)
-	if err != nil {
-		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(err)
+	includeDisabled := types.DefaultFAQExportIncludeDisabled
+	if raw := c.Query("include_disabled"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.Error(errors.NewBadRequestError("include_disabled 参数无效").WithDetails(err.Error()))
+			return
+		}
+		includeDisabled = parsed
+	}
+
+	format := types.FAQExportFormat(strings.ToLower(c.DefaultQuery("format", string(types.FAQExportFormatCSV))))
+	if !format.IsValid() {
+		c.Error(errors.NewBadRequestError("format 参数无效，仅支持 csv、json、xlsx"))
 		return
 	}
 
-	// Set response headers for CSV download
-	c.Header("Content-Type", "text/csv; charset=utf-8")
-	c.Header("Content-Disposition", "attachment; filename=faq_export.csv")
-	// Add BOM for Excel compatibility with UTF-8
-	bom := []byte{0xEF, 0xBB, 0xBF}
-	c.Data(http.StatusOK, "text/csv; charset=utf-8", append(bom, csvData...))
+	w := newFAQExportDownloadWriter(c, format)
+	if err := h.knowledgeService.ExportFAQEntriesStream(effCtx, kbID, includeDisabled, format, w); err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		if !w.started {
+			c.Error(err)
+			return
+		}
+		// The response is already partially written; abort the connection so the client sees a
+		// failed download instead of a truncated file that looks complete
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// downloadWriter streams an attachment to the client. Response headers and the optional
+// prefix are only written on the first Write, so errors raised before any data is
+// produced can still be reported through the regular error middleware.
+type downloadWriter struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	prefix      []byte
+	started     bool
+}
+
+// newFAQExportDownloadWriter returns the download writer for an FAQ export in format
+func newFAQExportDownloadWriter(c *gin.Context, format types.FAQExportFormat) *downloadWriter {
+	switch format {
+	case types.FAQExportFormatJSON:
+		return &downloadWriter{c: c, contentType: "application/json; charset=utf-8", filename: "faq_export.json"}
+	case types.FAQExportFormatXLSX:
+		return &downloadWriter{
+			c:           c,
+			contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			filename:    "faq_export.xlsx",
+		}
+	default:
+		// Add BOM for Excel compatibility with UTF-8, only CSV needs it
+		return &downloadWriter{
+			c: c, contentType: "text/csv; charset=utf-8", filename: "faq_export.csv", prefix: []byte{0xEF, 0xBB, 0xBF},
+		}
+	}
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Header("Content-Disposition", "attachment; filename="+w.filename)
+		w.c.Status(http.StatusOK)
+		if len(w.prefix) > 0 {
+			if _, err := w.c.Writer.Write(w.prefix); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return w.c.Writer.Write(p)
 }
 
 // GetEntry godoc
@@ -532,14 +855,56 @@ func (h *FAQHandler) GetEntry(c *gin.Context) {
 	})
 }
 
+// GetEntryByStandardQuestion godoc
+// @Summary      按标准问查找FAQ条目
+// @Description  按标准问精确查找FAQ条目，标准问会按创建时的规则规范化（去除首尾空白等），
+// @Description  可用于迁移数据前判断条目是否已存在
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id                 path      string  true  "知识库ID"
+// @Param        standard_question  query     string  true  "标准问"
+// @Success      200                {object}  map[string]interface{}  "FAQ条目详情"
+// @Failure      400                {object}  errors.AppError         "请求参数错误"
+// @Failure      404                {object}  errors.AppError         "条目不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/by-question [get]
+func (h *FAQHandler) GetEntryByStandardQuestion(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	effCtx, err := h.effectiveCtxForKB(c, kbID, types.OrgRoleViewer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	entry, err := h.knowledgeService.GetFAQEntryByStandardQuestion(effCtx, kbID, c.Query("standard_question"))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entry,
+	})
+}
+
 // GetImportProgress godoc
 // @Summary      获取FAQ导入进度
-// @Description  获取FAQ导入任务的进度
+// @Description  获取FAQ导入任务的进度。filter 可选 summary（仅统计）、failed（失败条目分页）、success（成功条目分页），
+// @Description  默认 all 返回完整进度
 // @Tags         FAQ管理
 // @Accept       json
 // @Produce      json
-// @Param        task_id  path      string  true  "任务ID"
+// @Param        task_id    path      string  true   "任务ID"
+// @Param        filter     query     string  false  "结果过滤: all, summary, failed, success"
+// @Param        page       query     int     false  "页码（failed/success 时有效）"
+// @Param        page_size  query     int     false  "每页数量（failed/success 时有效）"
 // @Success      200      {object}  map[string]interface{}  "导入进度"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
 // @Failure      404      {object}  errors.AppError         "任务不存在"
 // @Security     Bearer
 // @Security     ApiKeyAuth
@@ -548,6 +913,27 @@ func (h *FAQHandler) GetImportProgress(c *gin.Context) {
 	ctx := c.Request.Context()
 	taskID := secutils.SanitizeForLog(c.Param("task_id"))
 
+	filter := types.FAQImportResultFilter(secutils.SanitizeForLog(c.Query("filter")))
+	if filter != "" && filter != types.FAQImportResultFilterAll {
+		var page types.Pagination
+		if err := c.ShouldBindQuery(&page); err != nil {
+			logger.Error(ctx, "Failed to bind pagination query", err)
+			c.Error(errors.NewBadRequestError("分页参数不合法").WithDetails(err.Error()))
+			return
+		}
+		view, err := h.knowledgeService.GetFAQImportProgressView(ctx, taskID, filter, &page)
+		if err != nil {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    view,
+		})
+		return
+	}
+
 	progress, err := h.knowledgeService.GetFAQImportProgress(ctx, taskID)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
@@ -561,6 +947,143 @@ func (h *FAQHandler) GetImportProgress(c *gin.Context) {
 	})
 }
 
+// GetArchivedImportProgress godoc
+// @Summary      获取已归档的FAQ导入进度
+// @Description  获取导入任务结束时归档的完整进度（含失败条目），Redis 中的进度过期后仍可查询，需开启 faq_import_archive 配置
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string                  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "归档的导入进度"
+// @Failure      404      {object}  errors.AppError         "归档不存在或已过期"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /faq/import/progress/{task_id}/archive [get]
+func (h *FAQHandler) GetArchivedImportProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+
+	progress, err := h.knowledgeService.GetFAQImportProgressArchived(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	// 归档按知识库所属租户保存，需校验调用方对该知识库的访问权限（含共享知识库）
+	if _, err := h.effectiveCtxForKB(c, progress.KBID, types.OrgRoleViewer); err != nil {
+		c.Error(errors.NewNotFoundError("FAQ导入归档不存在或已过期"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// BackfillContentHashes godoc
+// @Summary      回填FAQ条目内容hash
+// @Description  为缺少内容hash的历史FAQ条目计算并写入hash，避免替换模式导入时被误删。任务在后台执行，返回进度供轮询
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "回填任务进度"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/content-hash/backfill [post]
+func (h *FAQHandler) BackfillContentHashes(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	effCtx, err := h.effectiveCtxForKB(c, kbID, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	progress, err := h.knowledgeService.BackfillChunkContentHashes(effCtx, kbID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetContentHashBackfillProgress godoc
+// @Summary      获取FAQ内容hash回填进度
+// @Description  获取FAQ内容hash回填任务的进度
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "回填进度"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /faq/content-hash/backfill/progress/{task_id} [get]
+func (h *FAQHandler) GetContentHashBackfillProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+
+	progress, err := h.knowledgeService.GetContentHashBackfillProgress(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	if err := h.validateTaskProgressAccess(c, &progress.KnowledgeTaskProgress); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// ForceClearImportLock godoc
+// @Summary      强制清除FAQ导入锁
+// @Description  清除知识库残留的导入运行锁（如 worker 崩溃导致新导入一直提示"已有导入任务正在进行中"），未结束的导入任务进度会被标记为失败；需要管理员权限
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "被清除的任务ID，无运行锁时为空"
+// @Failure      403  {object}  errors.AppError         "无权限"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/import/lock [delete]
+func (h *FAQHandler) ForceClearImportLock(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	effCtx, err := h.effectiveCtxForKB(c, kbID, types.OrgRoleAdmin)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	taskID, err := h.knowledgeService.ForceClearFAQImportLock(effCtx, kbID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"task_id": taskID,
+		},
+	})
+}
+
 // updateLastFAQImportResultDisplayStatusRequest is the request payload for UpdateLastImportResultDisplayStatus
 type updateLastFAQImportResultDisplayStatusRequest struct {
 	DisplayStatus string `json:"display_status" binding:"required,oneof=open close"`
@@ -609,7 +1132,7 @@ func (h *FAQHandler) UpdateLastImportResultDisplayStatus(c *gin.Context) {
 
 // AddSimilarQuestions godoc
 // @Summary      添加相似问
-// @Description  向指定的FAQ条目添加相似问题
+// @Description  向指定的FAQ条目添加相似问题，单次提交数量与条目相似问总数受知识库 faq_config 限制，返回去重后实际新增数量 added_count
 // @Tags         FAQ管理
 // @Accept       json
 // @Produce      json
@@ -644,7 +1167,50 @@ func (h *FAQHandler) AddSimilarQuestions(c *gin.Context) {
 		return
 	}
 
-	entry, err := h.knowledgeService.AddSimilarQuestions(effCtx, kbID, entrySeqID, req.SimilarQuestions)
+	entry, addedCount, err := h.knowledgeService.AddSimilarQuestions(effCtx, kbID, entrySeqID, req.SimilarQuestions)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"data":        entry,
+		"added_count": addedCount,
+	})
+}
+
+// AddSimilarQuestionsBatch godoc
+// @Summary      批量添加相似问
+// @Description  一次为多个FAQ条目添加相似问，重复校验覆盖整个批次及已有条目，单个条目失败不影响其他条目
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                           true  "知识库ID"
+// @Param        request  body      addSimilarQuestionsBatchRequest  true  "条目ID(seq_id)到相似问列表的映射"
+// @Success      200      {object}  map[string]interface{}           "更新后的FAQ条目及失败详情"
+// @Failure      400      {object}  errors.AppError                  "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/similar-questions [post]
+func (h *FAQHandler) AddSimilarQuestionsBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	effCtx, err := h.effectiveCtxForKB(c, kbID, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req addSimilarQuestionsBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to bind add similar questions batch payload", err)
+		c.Error(errors.NewBadRequestError("请求参数不合法").WithDetails(err.Error()))
+		return
+	}
+
+	result, err := h.knowledgeService.AddSimilarQuestionsBatch(effCtx, kbID, req.Entries)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(err)
@@ -653,6 +1219,6 @@ func (h *FAQHandler) AddSimilarQuestions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    entry,
+		"data":    result,
 	})
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	goerrors "errors"
 
@@ -222,23 +223,35 @@ func (h *KnowledgeHandler) resolveKnowledgeAndValidateKBAccess(c *gin.Context, k
 	return nil, ctx, errors.NewForbiddenError("Permission denied to access this knowledge")
 }
 
-// handleDuplicateKnowledgeError handles cases where duplicate knowledge is detected
-// Returns true if the error was a duplicate error and was handled, false otherwise
-func (h *KnowledgeHandler) handleDuplicateKnowledgeError(c *gin.Context,
-	err error, knowledge *types.Knowledge, duplicateType string,
+// validateTaskProgressAccess checks that the caller can access the knowledge base a background task
+// progress belongs to. Progress of other tenants is reported as not found so task IDs can't be probed.
+func (h *KnowledgeHandler) validateTaskProgressAccess(c *gin.Context, progress *types.KnowledgeTaskProgress) error {
+	_, _, effectiveTenantID, _, err := h.validateKnowledgeBaseAccessWithKBID(c, progress.KBID)
+	if err != nil || effectiveTenantID != progress.TenantID {
+		return errors.NewNotFoundError("Task not found")
+	}
+	return nil
+}
+
+// handleDuplicateKnowledgeResult responds with 409 when the create result reports an existing duplicate
+// Returns true if the result was a duplicate and was handled, false otherwise
+func (h *KnowledgeHandler) handleDuplicateKnowledgeResult(c *gin.Context,
+	result *types.KnowledgeCreateResult, newDupErr func(*types.Knowledge) *types.DuplicateKnowledgeError,
+	duplicateType string,
 ) bool {
-	if dupErr, ok := err.(*types.DuplicateKnowledgeError); ok {
-		ctx := c.Request.Context()
-		logger.Warnf(ctx, "Detected duplicate %s: %s", duplicateType, secutils.SanitizeForLog(dupErr.Error()))
-		c.JSON(http.StatusConflict, gin.H{
-			"success": false,
-			"message": dupErr.Error(),
-			"data":    knowledge, // knowledge contains the existing document
-			"code":    fmt.Sprintf("duplicate_%s", duplicateType),
-		})
-		return true
+	if !result.WasDuplicate {
+		return false
 	}
-	return false
+	dupErr := newDupErr(result.DuplicateOf)
+	ctx := c.Request.Context()
+	logger.Warnf(ctx, "Detected duplicate %s: %s", duplicateType, secutils.SanitizeForLog(dupErr.Error()))
+	c.JSON(http.StatusConflict, gin.H{
+		"success": false,
+		"message": dupErr.Error(),
+		"data":    result.DuplicateOf, // the existing document
+		"code":    fmt.Sprintf("duplicate_%s", duplicateType),
+	})
+	return true
 }
 
 // CreateKnowledgeFromFile godoc
@@ -252,6 +265,8 @@ func (h *KnowledgeHandler) handleDuplicateKnowledgeError(c *gin.Context,
 // @Param        fileName          formData  string  false  "自定义文件名"
 // @Param        metadata          formData  string  false  "元数据JSON"
 // @Param        enable_multimodel formData  bool    false  "启用多模态处理"
+// @Param        chunking_preset   formData  string  false  "租户分块预设名称"
+// @Param        name_conflict     formData  string  false  "重名处理方式: allow, rename, reject"
 // @Success      200               {object}  map[string]interface{}  "创建的知识"
 // @Failure      400               {object}  errors.AppError         "请求参数错误"
 // @Failure      409               {object}  map[string]interface{}  "文件重复"
@@ -305,42 +320,158 @@ func (h *KnowledgeHandler) CreateKnowledgeFromFile(c *gin.Context) {
 	logger.Infof(ctx, "File upload successful, filename: %s, size: %.2f KB", displayFileName, float64(file.Size)/1024)
 	logger.Infof(ctx, "Creating knowledge, knowledge base ID: %s, filename: %s", kbID, displayFileName)
 
-	// Parse metadata if provided
-	var metadata map[string]string
-	metadataStr := c.PostForm("metadata")
-	if metadataStr != "" {
-		if err := json.Unmarshal([]byte(metadataStr), &metadata); err != nil {
-			logger.Error(ctx, "Failed to parse metadata", err)
-			c.Error(errors.NewBadRequestError("Invalid metadata format").WithDetails(err.Error()))
+	opts, err := parseFileUploadOptions(c)
+	if err != nil {
+		logger.Error(ctx, "Failed to parse file upload options", err)
+		c.Error(err)
+		return
+	}
+
+	// Create knowledge entry from the file
+	result, err := h.kgService.CreateKnowledgeFromFileWithResult(
+		ctx, kbID, file, opts.Metadata, opts.EnableMultimodel, customFileName, opts.TagID, opts.ExpiresAt,
+		opts.ChunkingPreset, opts.NameConflict,
+	)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
 			return
 		}
-		logger.Infof(ctx, "Received file metadata: %s", secutils.SanitizeForLog(fmt.Sprintf("%v", metadata)))
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+	// Check for duplicate knowledge
+	if h.handleDuplicateKnowledgeResult(c, result, types.NewDuplicateFileError, "file") {
+		return
+	}
+	knowledge := result.Knowledge
+
+	logger.Infof(
+		ctx,
+		"Knowledge created successfully, ID: %s, title: %s",
+		secutils.SanitizeForLog(knowledge.ID),
+		secutils.SanitizeForLog(knowledge.Title),
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    knowledge,
+	})
+}
+
+// parseFileUploadOptions reads the form fields shared by single and batch file uploads
+func parseFileUploadOptions(c *gin.Context) (*types.KnowledgeFileUploadOptions, error) {
+	opts := &types.KnowledgeFileUploadOptions{}
+
+	// Parse metadata if provided
+	if metadataStr := c.PostForm("metadata"); metadataStr != "" {
+		if err := json.Unmarshal([]byte(metadataStr), &opts.Metadata); err != nil {
+			return nil, errors.NewBadRequestError("Invalid metadata format").WithDetails(err.Error())
+		}
+		logger.Infof(c.Request.Context(), "Received file metadata: %s",
+			secutils.SanitizeForLog(fmt.Sprintf("%v", opts.Metadata)))
 	}
 
-	enableMultimodelForm := c.PostForm("enable_multimodel")
-	var enableMultimodel *bool
-	if enableMultimodelForm != "" {
+	if enableMultimodelForm := c.PostForm("enable_multimodel"); enableMultimodelForm != "" {
 		parseBool, err := strconv.ParseBool(enableMultimodelForm)
 		if err != nil {
-			logger.Error(ctx, "Failed to parse enable_multimodel", err)
-			c.Error(errors.NewBadRequestError("Invalid enable_multimodel format").WithDetails(err.Error()))
-			return
+			return nil, errors.NewBadRequestError("Invalid enable_multimodel format").WithDetails(err.Error())
 		}
-		enableMultimodel = &parseBool
+		opts.EnableMultimodel = &parseBool
 	}
 
 	// 获取分类ID（如果提供），用于知识分类管理
-	tagID := c.PostForm("tag_id")
 	// 过滤特殊值，空字符串或 "__untagged__" 表示未分类
-	if tagID == "__untagged__" || tagID == "" {
-		tagID = ""
+	if tagID := c.PostForm("tag_id"); tagID != "__untagged__" {
+		opts.TagID = tagID
 	}
 
-	// Create knowledge entry from the file
-	knowledge, err := h.kgService.CreateKnowledgeFromFile(ctx, kbID, file, metadata, enableMultimodel, customFileName, tagID)
-	// Check for duplicate knowledge error
+	// 过期时间（可选，RFC3339 格式）
+	if expiresAtForm := c.PostForm("expires_at"); expiresAtForm != "" {
+		parsed, err := time.Parse(time.RFC3339, expiresAtForm)
+		if err != nil {
+			return nil, errors.NewBadRequestError("Invalid expires_at format, expected RFC3339").WithDetails(err.Error())
+		}
+		opts.ExpiresAt = &parsed
+	}
+
+	// 租户分块预设（可选），覆盖知识库的分块参数
+	opts.ChunkingPreset = strings.TrimSpace(c.PostForm("chunking_preset"))
+
+	// 与知识库中已有文档重名时的处理方式（可选）：allow（默认）、rename、reject
+	opts.NameConflict = strings.TrimSpace(c.PostForm("name_conflict"))
+	return opts, nil
+}
+
+// CreateKnowledgeFromFiles godoc
+// @Summary      批量从文件创建知识
+// @Description  一次上传多个文件并创建知识条目，统一查重并按总大小校验存储配额，返回每个文件的处理结果
+// @Tags         知识管理
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id                path      string  true   "知识库ID"
+// @Param        files             formData  file    true   "上传的文件（可重复）"
+// @Param        metadata          formData  string  false  "元数据JSON，应用于全部文件"
+// @Param        enable_multimodel formData  bool    false  "启用多模态处理"
+// @Param        chunking_preset   formData  string  false  "租户分块预设名称"
+// @Param        name_conflict     formData  string  false  "重名处理方式: allow, rename, reject"
+// @Success      200               {object}  map[string]interface{}  "每个文件的处理结果"
+// @Failure      400               {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/files [post]
+func (h *KnowledgeHandler) CreateKnowledgeFromFiles(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start creating knowledge from files")
+
+	// Validate access to the knowledge base (only owner or admin/editor can create)
+	_, kbID, effectiveTenantID, permission, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	// Check write permission
+	if permission != types.OrgRoleAdmin && permission != types.OrgRoleEditor {
+		c.Error(errors.NewForbiddenError("No permission to create knowledge"))
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		logger.Error(ctx, "File upload failed", err)
+		c.Error(errors.NewBadRequestError("File upload failed").WithDetails(err.Error()))
+		return
+	}
+	files := form.File["files"]
+	if len(files) == 0 {
+		c.Error(errors.NewBadRequestError("至少上传一个文件"))
+		return
+	}
+
+	// Validate file size (configurable via MAX_FILE_SIZE_MB)
+	maxSize := secutils.GetMaxFileSize()
+	for _, file := range files {
+		if file.Size > maxSize {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf("文件 %s 大小不能超过%dMB",
+				secutils.SanitizeForLog(file.Filename), secutils.GetMaxFileSizeMB())))
+			return
+		}
+	}
+
+	opts, err := parseFileUploadOptions(c)
 	if err != nil {
-		if h.handleDuplicateKnowledgeError(c, err, knowledge, "file") {
+		logger.Error(ctx, "Failed to parse file upload options", err)
+		c.Error(err)
+		return
+	}
+
+	results, err := h.kgService.CreateKnowledgeFromFiles(ctx, kbID, files, opts)
+	if err != nil {
+		var quotaErr *types.StorageQuotaExceededError
+		if goerrors.As(err, &quotaErr) {
+			c.Error(errors.NewBadRequestError("上传文件总大小超出存储配额").WithDetails(quotaErr.Message))
 			return
 		}
 		if appErr, ok := errors.IsAppError(err); ok {
@@ -352,15 +483,9 @@ func (h *KnowledgeHandler) CreateKnowledgeFromFile(c *gin.Context) {
 		return
 	}
 
-	logger.Infof(
-		ctx,
-		"Knowledge created successfully, ID: %s, title: %s",
-		secutils.SanitizeForLog(knowledge.ID),
-		secutils.SanitizeForLog(knowledge.Title),
-	)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    knowledge,
+		"data":    results,
 	})
 }
 
@@ -371,7 +496,7 @@ func (h *KnowledgeHandler) CreateKnowledgeFromFile(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id       path      string  true  "知识库ID"
-// @Param        request  body      object{url=string,file_name=string,file_type=string,enable_multimodel=bool,title=string,tag_id=string}  true  "URL请求"
+// @Param        request  body      object{url=string,file_name=string,file_type=string,enable_multimodel=bool,title=string,tag_id=string,expires_at=string,chunking_preset=string}  true  "URL请求"
 // @Success      201      {object}  map[string]interface{}  "创建的知识"
 // @Failure      400      {object}  errors.AppError         "请求参数错误"
 // @Failure      409      {object}  map[string]interface{}  "URL重复"
@@ -398,12 +523,14 @@ func (h *KnowledgeHandler) CreateKnowledgeFromURL(c *gin.Context) {
 
 	// Parse URL from request body
 	var req struct {
-		URL              string `json:"url" binding:"required"`
-		FileName         string `json:"file_name"`
-		FileType         string `json:"file_type"`
-		EnableMultimodel *bool  `json:"enable_multimodel"`
-		Title            string `json:"title"`
-		TagID            string `json:"tag_id"`
+		URL              string     `json:"url" binding:"required"`
+		FileName         string     `json:"file_name"`
+		FileType         string     `json:"file_type"`
+		EnableMultimodel *bool      `json:"enable_multimodel"`
+		Title            string     `json:"title"`
+		TagID            string     `json:"tag_id"`
+		ExpiresAt        *time.Time `json:"expires_at"`
+		ChunkingPreset   string     `json:"chunking_preset"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error(ctx, "Failed to parse URL request", err)
@@ -423,12 +550,11 @@ func (h *KnowledgeHandler) CreateKnowledgeFromURL(c *gin.Context) {
 	)
 
 	// Create knowledge entry from the URL
-	knowledge, err := h.kgService.CreateKnowledgeFromURL(ctx, kbID, req.URL, req.FileName, req.FileType, req.EnableMultimodel, req.Title, req.TagID)
-	// Check for duplicate knowledge error
+	result, err := h.kgService.CreateKnowledgeFromURLWithResult(
+		ctx, kbID, req.URL, req.FileName, req.FileType, req.EnableMultimodel, req.Title, req.TagID, req.ExpiresAt,
+		strings.TrimSpace(req.ChunkingPreset),
+	)
 	if err != nil {
-		if h.handleDuplicateKnowledgeError(c, err, knowledge, "url") {
-			return
-		}
 		if appErr, ok := errors.IsAppError(err); ok {
 			c.Error(appErr)
 			return
@@ -437,6 +563,11 @@ func (h *KnowledgeHandler) CreateKnowledgeFromURL(c *gin.Context) {
 		c.Error(errors.NewInternalServerError(err.Error()))
 		return
 	}
+	// Check for duplicate knowledge
+	if h.handleDuplicateKnowledgeResult(c, result, types.NewDuplicateURLError, "url") {
+		return
+	}
+	knowledge := result.Knowledge
 
 	logger.Infof(
 		ctx,
@@ -450,6 +581,61 @@ func (h *KnowledgeHandler) CreateKnowledgeFromURL(c *gin.Context) {
 	})
 }
 
+// CreateKnowledgeFromCloudStorage godoc
+// @Summary      从云存储路径导入知识
+// @Description  从 s3:// 或 cos:// 路径直接导入对象，路径以 / 结尾时导入该前缀下的全部对象。访问凭证必填，对象由异步任务复制后解析
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                           true  "知识库ID"
+// @Param        request  body      types.CloudStorageImportRequest  true  "云存储导入请求"
+// @Success      201      {object}  map[string]interface{}           "导入结果"
+// @Failure      400      {object}  errors.AppError                  "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/cloud-storage [post]
+func (h *KnowledgeHandler) CreateKnowledgeFromCloudStorage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, kbID, effectiveTenantID, permission, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	if permission != types.OrgRoleAdmin && permission != types.OrgRoleEditor {
+		c.Error(errors.NewForbiddenError("No permission to create knowledge"))
+		return
+	}
+
+	var req types.CloudStorageImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse cloud storage import request", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Importing knowledge from cloud storage, knowledge base ID: %s, path: %s",
+		secutils.SanitizeForLog(kbID), secutils.SanitizeForLog(req.Path))
+
+	result, err := h.kgService.CreateKnowledgeFromCloudStorage(ctx, kbID, &req)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
 // CreateManualKnowledge godoc
 // @Summary      手工创建知识
 // @Description  手工录入Markdown格式的知识内容
@@ -549,6 +735,54 @@ func (h *KnowledgeHandler) GetKnowledge(c *gin.Context) {
 	})
 }
 
+// GetKnowledgeDetail godoc
+// @Summary      获取知识详情及统计
+// @Description  获取知识详情，并附带分块类型统计、图片数量、解析耗时、摘要/问题生成状态，用于文档详情页
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "知识详情及统计"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      404  {object}  errors.AppError         "知识不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/detail [get]
+func (h *KnowledgeHandler) GetKnowledgeDetail(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleViewer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	detail, err := h.kgService.GetKnowledgeDetail(effCtx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": id,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    detail,
+	})
+}
+
 // ListKnowledge godoc
 // @Summary      获取知识列表
 // @Description  获取知识库下的知识列表，支持分页和筛选
@@ -592,21 +826,25 @@ func (h *KnowledgeHandler) ListKnowledge(c *gin.Context) {
 	tagID := c.Query("tag_id")
 	keyword := c.Query("keyword")
 	fileType := c.Query("file_type")
+	extractedKeyword := c.Query("extracted_keyword")
+	fileHash := c.Query("file_hash")
 
 	logger.Infof(
 		ctx,
-		"Retrieving knowledge list under knowledge base, knowledge base ID: %s, tag_id: %s, keyword: %s, file_type: %s, page: %d, page size: %d, effectiveTenantID: %d",
+		"Retrieving knowledge list under knowledge base, knowledge base ID: %s, tag_id: %s, keyword: %s, file_type: %s, extracted_keyword: %s, page: %d, page size: %d, effectiveTenantID: %d",
 		secutils.SanitizeForLog(kbID),
 		secutils.SanitizeForLog(tagID),
 		secutils.SanitizeForLog(keyword),
 		secutils.SanitizeForLog(fileType),
+		secutils.SanitizeForLog(extractedKeyword),
 		pagination.Page,
 		pagination.PageSize,
 		effectiveTenantID,
 	)
 
 	// Retrieve paginated knowledge entries
-	result, err := h.kgService.ListPagedKnowledgeByKnowledgeBaseID(ctx, kbID, &pagination, tagID, keyword, fileType)
+	result, err := h.kgService.ListPagedKnowledgeByKnowledgeBaseID(ctx,
+		kbID, &pagination, tagID, keyword, fileType, extractedKeyword, fileHash)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(errors.NewInternalServerError(err.Error()))
@@ -637,6 +875,7 @@ func (h *KnowledgeHandler) ListKnowledge(c *gin.Context) {
 // @Param        id   path      string  true  "知识ID"
 // @Success      200  {object}  map[string]interface{}  "删除成功"
 // @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      409  {object}  errors.AppError         "知识正在执行其他操作"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /knowledge/{id} [delete]
@@ -660,6 +899,10 @@ func (h *KnowledgeHandler) DeleteKnowledge(c *gin.Context) {
 	logger.Infof(ctx, "Deleting knowledge, ID: %s", secutils.SanitizeForLog(id))
 	err = h.kgService.DeleteKnowledge(effCtx, id)
 	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(errors.NewInternalServerError(err.Error()))
 		return
@@ -851,6 +1094,7 @@ func (h *KnowledgeHandler) GetKnowledgeBatch(c *gin.Context) {
 // @Param        request  body      types.Knowledge true  "知识信息"
 // @Success      200      {object}  map[string]interface{}  "更新成功"
 // @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Failure      409      {object}  errors.AppError         "知识正在执行其他操作"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /knowledge/{id} [put]
@@ -891,22 +1135,22 @@ func (h *KnowledgeHandler) UpdateKnowledge(c *gin.Context) {
 	})
 }
 
-// UpdateManualKnowledge godoc
-// @Summary      更新手工知识
-// @Description  更新手工录入的Markdown知识内容
+// SetKnowledgeExpiry godoc
+// @Summary      设置知识过期时间
+// @Description  设置或清除知识的过期时间，到期后知识将被自动删除；expires_at 为 null 时表示永不过期
 // @Tags         知识管理
 // @Accept       json
 // @Produce      json
-// @Param        id       path      string                       true  "知识ID"
-// @Param        request  body      types.ManualKnowledgePayload true  "手工知识内容"
-// @Success      200      {object}  map[string]interface{}       "更新后的知识"
-// @Failure      400      {object}  errors.AppError              "请求参数错误"
+// @Param        id       path      string                         true  "知识ID"
+// @Param        request  body      object{expires_at=string}      true  "过期时间（RFC3339）"
+// @Success      200      {object}  map[string]interface{}         "更新后的知识"
+// @Failure      400      {object}  errors.AppError                "请求参数错误"
+// @Failure      409      {object}  errors.AppError                "知识正在执行其他操作"
 // @Security     Bearer
 // @Security     ApiKeyAuth
-// @Router       /knowledge/manual/{id} [put]
-func (h *KnowledgeHandler) UpdateManualKnowledge(c *gin.Context) {
+// @Router       /knowledge/{id}/expiry [put]
+func (h *KnowledgeHandler) SetKnowledgeExpiry(c *gin.Context) {
 	ctx := c.Request.Context()
-	logger.Info(ctx, "Start updating manual knowledge")
 
 	id := secutils.SanitizeForLog(c.Param("id"))
 	if id == "" {
@@ -921,49 +1165,48 @@ func (h *KnowledgeHandler) UpdateManualKnowledge(c *gin.Context) {
 		return
 	}
 
-	var req types.ManualKnowledgePayload
+	var req struct {
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logger.Error(ctx, "Failed to parse manual knowledge update request", err)
+		logger.Error(ctx, "Failed to parse request parameters", err)
 		c.Error(errors.NewBadRequestError(err.Error()))
 		return
 	}
 
-	knowledge, err := h.kgService.UpdateManualKnowledge(effCtx, id, &req)
+	knowledge, err := h.kgService.SetKnowledgeExpiry(effCtx, id, req.ExpiresAt)
 	if err != nil {
 		if appErr, ok := errors.IsAppError(err); ok {
 			c.Error(appErr)
 			return
 		}
-		logger.ErrorWithFields(ctx, err, map[string]interface{}{
-			"knowledge_id": id,
-		})
+		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(errors.NewInternalServerError(err.Error()))
 		return
 	}
 
-	logger.Infof(ctx, "Manual knowledge updated successfully, knowledge ID: %s", id)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    knowledge,
 	})
 }
 
-// ReparseKnowledge godoc
-// @Summary      重新解析知识
-// @Description  删除知识中现有的文档内容并重新解析，使用异步任务方式处理
+// SetKnowledgeRefreshSchedule godoc
+// @Summary      设置知识定时刷新
+// @Description  为从 URL 导入的知识设置定时刷新间隔，源内容变化时自动重新解析；interval_minutes 为 0 时关闭定时刷新
 // @Tags         知识管理
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "知识ID"
-// @Success      200  {object}  map[string]interface{}  "重新解析任务已提交"
-// @Failure      400  {object}  errors.AppError         "请求参数错误"
-// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Param        id       path      string                          true  "知识ID"
+// @Param        request  body      object{interval_minutes=int}    true  "刷新间隔（分钟）"
+// @Success      200      {object}  map[string]interface{}          "更新后的知识"
+// @Failure      400      {object}  errors.AppError                 "请求参数错误"
+// @Failure      409      {object}  errors.AppError                 "知识正在执行其他操作"
 // @Security     Bearer
 // @Security     ApiKeyAuth
-// @Router       /knowledge/{id}/reparse [post]
-func (h *KnowledgeHandler) ReparseKnowledge(c *gin.Context) {
+// @Router       /knowledge/{id}/refresh-schedule [put]
+func (h *KnowledgeHandler) SetKnowledgeRefreshSchedule(c *gin.Context) {
 	ctx := c.Request.Context()
-	logger.Info(ctx, "Start re-parsing knowledge")
 
 	id := secutils.SanitizeForLog(c.Param("id"))
 	if id == "" {
@@ -972,54 +1215,1289 @@ func (h *KnowledgeHandler) ReparseKnowledge(c *gin.Context) {
 		return
 	}
 
-	// Validate KB access with editor permission (reparse requires write access)
 	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	// Call service to reparse knowledge
-	knowledge, err := h.kgService.ReparseKnowledge(effCtx, id)
+	var req struct {
+		IntervalMinutes int `json:"interval_minutes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	knowledge, err := h.kgService.SetKnowledgeRefreshSchedule(effCtx, id, req.IntervalMinutes)
 	if err != nil {
 		if appErr, ok := errors.IsAppError(err); ok {
 			c.Error(appErr)
 			return
 		}
-		logger.ErrorWithFields(ctx, err, map[string]interface{}{
-			"knowledge_id": id,
-		})
+		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(errors.NewInternalServerError(err.Error()))
 		return
 	}
 
-	logger.Infof(ctx, "Knowledge reparse task submitted successfully, knowledge ID: %s", id)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Knowledge reparse task submitted",
 		"data":    knowledge,
 	})
 }
 
-type knowledgeTagBatchRequest struct {
-	Updates map[string]*string `json:"updates" binding:"required,min=1"`
-	KBID    string             `json:"kb_id"` // Optional: scope to this KB (validates editor access and uses effective tenant for shared KB)
-}
-
-// UpdateKnowledgeTagBatch godoc
-// @Summary      批量更新知识标签
-// @Description  批量更新知识条目的标签。可选 kb_id：指定时按该知识库校验编辑权限并用于共享知识库的租户解析
+// UpdateManualKnowledge godoc
+// @Summary      更新手工知识
+// @Description  更新手工录入的Markdown知识内容
 // @Tags         知识管理
 // @Accept       json
 // @Produce      json
-// @Param        request  body      object  true  "标签更新请求（updates 必填，kb_id 可选）"
-// @Success      200      {object}  map[string]interface{}  "更新成功"
-// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Param        id       path      string                       true  "知识ID"
+// @Param        request  body      types.ManualKnowledgePayload true  "手工知识内容"
+// @Success      200      {object}  map[string]interface{}       "更新后的知识"
+// @Failure      400      {object}  errors.AppError              "请求参数错误"
+// @Failure      409      {object}  errors.AppError              "知识正在执行其他操作"
 // @Security     Bearer
 // @Security     ApiKeyAuth
-// @Router       /knowledge/tags [put]
-func (h *KnowledgeHandler) UpdateKnowledgeTagBatch(c *gin.Context) {
-	ctx := c.Request.Context()
+// @Router       /knowledge/manual/{id} [put]
+func (h *KnowledgeHandler) UpdateManualKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start updating manual knowledge")
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req types.ManualKnowledgePayload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse manual knowledge update request", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	knowledge, err := h.kgService.UpdateManualKnowledge(effCtx, id, &req)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": id,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Manual knowledge updated successfully, knowledge ID: %s", id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    knowledge,
+	})
+}
+
+// ReparseKnowledge godoc
+// @Summary      重新解析知识
+// @Description  删除知识中现有的文档内容并重新解析，使用异步任务方式处理；可选择跳过摘要/问题生成以保留已有结果
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true   "知识ID"
+// @Param        request  body      types.ReparseOptions  false  "重新解析选项（可选）"
+// @Success      200  {object}  map[string]interface{}  "重新解析任务已提交"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      409  {object}  errors.AppError         "知识正在执行其他操作或处于重新解析冷却期"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/reparse [post]
+func (h *KnowledgeHandler) ReparseKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start re-parsing knowledge")
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	// Validate KB access with editor permission (reparse requires write access)
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Reparse options are optional, an empty body keeps full regeneration
+	var opts types.ReparseOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && !goerrors.Is(err, io.EOF) {
+		logger.Error(ctx, "Failed to parse reparse options", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	// Call service to reparse knowledge
+	knowledge, err := h.kgService.ReparseKnowledgeWithOptions(effCtx, id, opts)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": id,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Knowledge reparse task submitted successfully, knowledge ID: %s", id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Knowledge reparse task submitted",
+		"data":    knowledge,
+	})
+}
+
+// CancelKnowledgeProcessing godoc
+// @Summary      取消知识解析
+// @Description  取消等待解析或解析中的知识，已生成的分块、索引与图谱数据会被清理，知识状态变为 cancelled，之后可通过重新解析接口重新处理
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "取消后的知识"
+// @Failure      400  {object}  errors.AppError         "请求参数错误或知识不在解析中"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      409  {object}  errors.AppError         "知识正在执行其他操作"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/cancel [post]
+func (h *KnowledgeHandler) CancelKnowledgeProcessing(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	knowledge, err := h.kgService.CancelKnowledgeProcessing(effCtx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": id,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Knowledge processing cancelled, knowledge ID: %s", id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    knowledge,
+	})
+}
+
+// GetKnowledgeChunkSnapshot godoc
+// @Summary      获取重新解析前的分块快照
+// @Description  返回最近一次重新解析前保存的旧分块（内容与边界），用于对比新旧分块效果；需在配置中开启 knowledge_base.chunk_snapshot，快照到期自动清除
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "分块快照"
+// @Failure      400  {object}  errors.AppError         "未开启分块快照"
+// @Failure      404  {object}  errors.AppError         "快照不存在或已过期"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/chunk-snapshot [get]
+func (h *KnowledgeHandler) GetKnowledgeChunkSnapshot(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleViewer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	snapshot, err := h.kgService.GetKnowledgeChunkSnapshot(effCtx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    snapshot,
+	})
+}
+
+// GetKnowledgeMarkdown godoc
+// @Summary      获取文档解析后的完整 Markdown
+// @Description  返回最近一次解析时抽取的完整 Markdown；需在配置中开启 knowledge_base.store_parsed_markdown
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "解析结果 Markdown"
+// @Failure      400  {object}  errors.AppError         "未开启解析结果存储"
+// @Failure      404  {object}  errors.AppError         "暂无解析结果"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/markdown [get]
+func (h *KnowledgeHandler) GetKnowledgeMarkdown(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleViewer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	markdown, err := h.kgService.GetKnowledgeMarkdown(effCtx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    markdown,
+	})
+}
+
+// GetKnowledgeProcessingReport godoc
+// @Summary      获取文档处理报告
+// @Description  返回最近一次解析时记录的分块处理诊断信息：跳过的分块及原因、图片处理情况、超长分块拆分情况
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "处理报告"
+// @Failure      404  {object}  errors.AppError         "暂无处理报告"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/processing-report [get]
+func (h *KnowledgeHandler) GetKnowledgeProcessingReport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleViewer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	report, err := h.kgService.GetKnowledgeProcessingReport(effCtx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// ListOrphanedImageChunks godoc
+// @Summary      列出孤立或重复的图片子分块
+// @Description  列出图片已不在父分块上、或同一图片重复生成的 OCR/描述子分块，用于排查检索结果中的过期图片内容
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "孤立或重复的图片子分块"
+// @Failure      404  {object}  errors.AppError         "知识不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/orphaned-image-chunks [get]
+func (h *KnowledgeHandler) ListOrphanedImageChunks(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleViewer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	chunks, err := h.kgService.ListOrphanedImageChunks(effCtx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    chunks,
+	})
+}
+
+// ValidateChunkChain godoc
+// @Summary      校验分块前后关系
+// @Description  校验文本分块 pre_chunk_id/next_chunk_id 链，报告悬空指针、不对称链接、分叉、环、孤立分块与乱序
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "校验报告"
+// @Failure      404  {object}  errors.AppError         "知识不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/chunk-chain [get]
+func (h *KnowledgeHandler) ValidateChunkChain(c *gin.Context) {
+	h.handleChunkChain(c, false)
+}
+
+// RepairChunkChain godoc
+// @Summary      修复分块前后关系
+// @Description  校验文本分块链，存在异常时按 chunk_index 顺序重建前后关系
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "修复前的校验报告及修复结果"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      404  {object}  errors.AppError         "知识不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/chunk-chain/repair [post]
+func (h *KnowledgeHandler) RepairChunkChain(c *gin.Context) {
+	h.handleChunkChain(c, true)
+}
+
+// handleChunkChain validates, and with repair set rebuilds, the text chunk chain of a knowledge
+func (h *KnowledgeHandler) handleChunkChain(c *gin.Context, repair bool) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	role := types.OrgRoleViewer
+	if repair {
+		role = types.OrgRoleEditor
+	}
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	report, err := h.kgService.ValidateChunkChain(effCtx, id, repair)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// EnableKnowledge godoc
+// @Summary      启用知识
+// @Description  启用已完成索引但保持禁用状态的知识（如发布时设置 enable_after_publish=false 的手工知识）
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "启用后的知识"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      409  {object}  errors.AppError         "知识正在执行其他操作"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/enable [post]
+func (h *KnowledgeHandler) EnableKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	knowledge, err := h.kgService.EnableKnowledge(effCtx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": id,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Knowledge enabled successfully, knowledge ID: %s", id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    knowledge,
+	})
+}
+
+// CheckDependencies godoc
+// @Summary      知识处理依赖自检
+// @Description  检查 docreader、默认嵌入模型与向量存储的连通性，返回各依赖的状态与耗时；任一依赖不可用时返回 503
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "全部依赖可用"
+// @Failure      503  {object}  map[string]interface{}  "存在不可用的依赖"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/dependencies/health [get]
+func (h *KnowledgeHandler) CheckDependencies(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	result, err := h.kgService.CheckDependencies(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	status := http.StatusOK
+	if !result.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{
+		"success": result.Healthy,
+		"data":    result,
+	})
+}
+
+// ReembedKnowledge godoc
+// @Summary      重新向量化知识
+// @Description  保留现有分块，仅使用新的嵌入模型重新生成向量，不重新解析文档。任务在后台异步执行
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "知识ID"
+// @Param        request  body      object{model_id=string}  true  "新的嵌入模型ID"
+// @Success      200      {object}  map[string]interface{}  "重新向量化任务已提交"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Failure      403      {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/reembed [post]
+func (h *KnowledgeHandler) ReembedKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start re-embedding knowledge")
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	var request struct {
+		ModelID string `json:"model_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	// Validate KB access with editor permission (reembed requires write access)
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	knowledge, err := h.kgService.ReembedKnowledge(effCtx, id, secutils.SanitizeForLog(request.ModelID))
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": id,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Knowledge reembed task submitted, knowledge ID: %s", id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Knowledge reembed task submitted",
+		"data":    knowledge,
+	})
+}
+
+// ReindexKnowledge godoc
+// @Summary      重建知识索引
+// @Description  根据数据库中已有的分块（含生成的问题、摘要分块等）使用知识当前的嵌入模型重建向量与关键词索引，不重新解析文档，用于向量存储被清空或迁移后的恢复
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识ID"
+// @Success      200  {object}  map[string]interface{}  "重建索引完成"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      409  {object}  errors.AppError         "知识正在执行其他操作"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/{id}/reindex [post]
+func (h *KnowledgeHandler) ReindexKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start reindexing knowledge")
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Knowledge ID is empty")
+		c.Error(errors.NewBadRequestError("Knowledge ID cannot be empty"))
+		return
+	}
+
+	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	knowledge, err := h.kgService.ReindexKnowledge(effCtx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_id": id,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Knowledge reindexed successfully, knowledge ID: %s", id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    knowledge,
+	})
+}
+
+// RegenerateMissingSummaries godoc
+// @Summary      补生成缺失的摘要
+// @Description  为知识库中已解析完成但摘要失败或缺失的知识重新提交摘要生成任务
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "任务已提交"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/summaries/regenerate [post]
+func (h *KnowledgeHandler) RegenerateMissingSummaries(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start regenerating missing summaries")
+
+	_, kbID, effectiveTenantID, permission, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !permission.HasPermission(types.OrgRoleEditor) {
+		c.Error(errors.NewForbiddenError("Permission denied to modify this knowledge base"))
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	progress, err := h.kgService.RegenerateMissingSummaries(ctx, kbID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Summary regeneration task submitted, knowledge base ID: %s, total: %d", kbID, progress.Total)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// RebuildKnowledgeGraph godoc
+// @Summary      重建知识库知识图谱
+// @Description  删除知识库已有的图谱数据，并在后台为所有已解析完成文档的文本分块重新抽取图谱
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "任务已提交"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/graph/rebuild [post]
+func (h *KnowledgeHandler) RebuildKnowledgeGraph(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start rebuilding knowledge graph")
+
+	_, kbID, effectiveTenantID, permission, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !permission.HasPermission(types.OrgRoleEditor) {
+		c.Error(errors.NewForbiddenError("Permission denied to modify this knowledge base"))
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	progress, err := h.kgService.RebuildKnowledgeGraph(ctx, kbID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Knowledge graph rebuild task submitted, knowledge base ID: %s, task ID: %s", kbID, progress.TaskID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// DeleteAllKnowledge godoc
+// @Summary      清空知识库
+// @Description  批量删除知识库下的全部知识（分块、向量、图谱数据、文件），执行期间知识库只读
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "删除成功"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      409  {object}  errors.AppError         "知识库正在执行其他批量操作"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge [delete]
+func (h *KnowledgeHandler) DeleteAllKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start deleting all knowledge in knowledge base")
+
+	_, kbID, effectiveTenantID, permission, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !permission.HasPermission(types.OrgRoleEditor) {
+		c.Error(errors.NewForbiddenError("Permission denied to modify this knowledge base"))
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	deleted, err := h.kgService.DeleteAllKnowledgeInKB(ctx, kbID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "All knowledge deleted, knowledge base ID: %s, count: %d", kbID, deleted)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"deleted": deleted,
+		},
+	})
+}
+
+// GetEffectiveReadConfig godoc
+// @Summary      获取生效的文档解析配置
+// @Description  返回指定知识库与文件类型下，文档解析时实际下发给 docreader 的配置（分块参数、多模态、VLM 等），不执行解析，用于排查分块结果
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id                 path      string  true   "知识库ID"
+// @Param        file_type          query     string  false  "文件类型，如 pdf、docx、png"
+// @Param        enable_multimodel  query     bool    false  "覆盖知识库的多模态开关"
+// @Success      200  {object}  map[string]interface{}  "生效的解析配置"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/read-config [get]
+func (h *KnowledgeHandler) GetEffectiveReadConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, kbID, effectiveTenantID, permission, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !permission.HasPermission(types.OrgRoleEditor) {
+		c.Error(errors.NewForbiddenError("Permission denied to view parse config of this knowledge base"))
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	overrides := &types.ReadConfigOverrides{}
+	if enableMultimodelQuery := c.Query("enable_multimodel"); enableMultimodelQuery != "" {
+		parseBool, err := strconv.ParseBool(enableMultimodelQuery)
+		if err != nil {
+			c.Error(errors.NewBadRequestError("Invalid enable_multimodel format").WithDetails(err.Error()))
+			return
+		}
+		overrides.EnableMultimodel = &parseBool
+	}
+
+	readConfig, err := h.kgService.GetEffectiveReadConfig(ctx, kbID, c.Query("file_type"), overrides)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    readConfig,
+	})
+}
+
+// ListKnowledgeLinks godoc
+// @Summary      获取知识库中的超链接
+// @Description  列出解析时从知识库文档中提取的超链接（URL、锚文本及所在文档与分块）
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "超链接列表"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/links [get]
+func (h *KnowledgeHandler) ListKnowledgeLinks(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, kbID, effectiveTenantID, _, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	links, err := h.kgService.ListKnowledgeLinks(ctx, kbID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    links,
+	})
+}
+
+// ExportGeneratedQuestions godoc
+// @Summary      导出自动生成的问题
+// @Description  以 CSV 或 JSON 流式导出知识库文档分块的自动生成问题，可按知识或分类过滤
+// @Tags         知识管理
+// @Produce      text/csv
+// @Produce      json
+// @Param        id            path   string  true   "知识库ID"
+// @Param        format        query  string  false  "导出格式：csv（默认）或 json"
+// @Param        knowledge_id  query  string  false  "仅导出指定知识"
+// @Param        tag_id        query  string  false  "仅导出指定分类下的知识"
+// @Success      200           {file}    file             "导出文件"
+// @Failure      400           {object}  errors.AppError  "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/generated-questions/export [get]
+func (h *KnowledgeHandler) ExportGeneratedQuestions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, kbID, effectiveTenantID, _, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	var filter types.GeneratedQuestionExportFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	w := &exportResponseWriter{c: c, format: filter.Format}
+	if err := h.kgService.ExportGeneratedQuestions(ctx, kbID, &filter, w); err != nil {
+		if w.started {
+			// Headers are already sent, the truncated body is all the client gets
+			logger.Errorf(ctx, "Generated question export interrupted: %v", err)
+			return
+		}
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+	}
+}
+
+// exportResponseWriter sends the download headers on the first write, so errors raised before any
+// output can still be returned as a regular JSON error response
+type exportResponseWriter struct {
+	c       *gin.Context
+	format  types.GeneratedQuestionExportFormat
+	started bool
+}
+
+func (w *exportResponseWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		if w.format == types.GeneratedQuestionExportFormatJSON {
+			w.c.Header("Content-Type", "application/json; charset=utf-8")
+			w.c.Header("Content-Disposition", "attachment; filename=generated_questions.json")
+		} else {
+			w.c.Header("Content-Type", "text/csv; charset=utf-8")
+			w.c.Header("Content-Disposition", "attachment; filename=generated_questions.csv")
+		}
+		w.c.Status(http.StatusOK)
+		if w.format != types.GeneratedQuestionExportFormatJSON {
+			// Add BOM for Excel compatibility with UTF-8
+			if _, err := w.c.Writer.Write([]byte{0xEF, 0xBB, 0xBF}); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return w.c.Writer.Write(p)
+}
+
+// GetSummaryRegenerationProgress godoc
+// @Summary      获取摘要补生成进度
+// @Description  获取摘要补生成任务的进度
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "进度信息"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/summaries/regenerate/progress/{task_id} [get]
+func (h *KnowledgeHandler) GetSummaryRegenerationProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	if taskID == "" {
+		logger.Error(ctx, "Task ID is empty")
+		c.Error(errors.NewBadRequestError("Task ID cannot be empty"))
+		return
+	}
+
+	progress, err := h.kgService.GetSummaryRegenerationProgress(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	if err := h.validateTaskProgressAccess(c, &progress.KnowledgeTaskProgress); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetKnowledgeGraphRebuildProgress godoc
+// @Summary      获取知识图谱重建进度
+// @Description  获取知识库知识图谱重建任务的进度
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "进度信息"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/graph/rebuild/progress/{task_id} [get]
+func (h *KnowledgeHandler) GetKnowledgeGraphRebuildProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	if taskID == "" {
+		logger.Error(ctx, "Task ID is empty")
+		c.Error(errors.NewBadRequestError("Task ID cannot be empty"))
+		return
+	}
+
+	progress, err := h.kgService.GetKnowledgeGraphRebuildProgress(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	if err := h.validateTaskProgressAccess(c, &progress.KnowledgeTaskProgress); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// AlignKnowledgeEmbeddingModels godoc
+// @Summary      对齐知识的嵌入模型
+// @Description  将嵌入模型与知识库当前模型不一致的已解析知识重新向量化，修复混合维度导致的检索问题
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "任务已提交"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/embedding/align [post]
+func (h *KnowledgeHandler) AlignKnowledgeEmbeddingModels(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start aligning knowledge embedding models")
+
+	_, kbID, effectiveTenantID, permission, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !permission.HasPermission(types.OrgRoleEditor) {
+		c.Error(errors.NewForbiddenError("Permission denied to modify this knowledge base"))
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	progress, err := h.kgService.AlignKnowledgeEmbeddingModels(ctx, kbID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Embedding model alignment task submitted, knowledge base ID: %s, misaligned: %d",
+		kbID, progress.Total)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetEmbeddingModelAlignProgress godoc
+// @Summary      获取嵌入模型对齐进度
+// @Description  获取知识嵌入模型对齐任务的进度
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "进度信息"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/embedding/align/progress/{task_id} [get]
+func (h *KnowledgeHandler) GetEmbeddingModelAlignProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	if taskID == "" {
+		logger.Error(ctx, "Task ID is empty")
+		c.Error(errors.NewBadRequestError("Task ID cannot be empty"))
+		return
+	}
+
+	progress, err := h.kgService.GetEmbeddingModelAlignProgress(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	if err := h.validateTaskProgressAccess(c, &progress.KnowledgeTaskProgress); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// ReindexKnowledgeBase godoc
+// @Summary      重建知识库索引
+// @Description  根据已有分块为知识库中全部解析完成的知识重建检索索引，不重新解析文档，后台异步执行
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "任务已提交"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/reindex [post]
+func (h *KnowledgeHandler) ReindexKnowledgeBase(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start reindexing knowledge base")
+
+	_, kbID, effectiveTenantID, permission, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !permission.HasPermission(types.OrgRoleEditor) {
+		c.Error(errors.NewForbiddenError("Permission denied to modify this knowledge base"))
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	progress, err := h.kgService.ReindexKnowledgeBase(ctx, kbID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Knowledge base reindex task submitted, knowledge base ID: %s, knowledge: %d",
+		kbID, progress.Total)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetKnowledgeReindexProgress godoc
+// @Summary      获取知识库重建索引进度
+// @Description  获取知识库重建索引任务的进度
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "进度信息"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/reindex/progress/{task_id} [get]
+func (h *KnowledgeHandler) GetKnowledgeReindexProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	if taskID == "" {
+		logger.Error(ctx, "Task ID is empty")
+		c.Error(errors.NewBadRequestError("Task ID cannot be empty"))
+		return
+	}
+
+	progress, err := h.kgService.GetKnowledgeReindexProgress(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	if err := h.validateTaskProgressAccess(c, &progress.KnowledgeTaskProgress); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetProcessingQueueStats godoc
+// @Summary      获取知识处理任务队列统计
+// @Description  返回各异步任务队列的积压情况及文档处理、FAQ导入、摘要生成、问题生成任务按状态的数量，仅管理员可用
+// @Tags         知识管理
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "队列统计"
+// @Failure      403  {object}  errors.AppError         "无权限"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/queue-stats [get]
+func (h *KnowledgeHandler) GetProcessingQueueStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Queues are shared by all tenants, only admins may inspect them
+	userVal, exists := c.Get(types.UserContextKey.String())
+	if !exists {
+		c.Error(errors.NewUnauthorizedError("Unauthorized"))
+		return
+	}
+	user, ok := userVal.(*types.User)
+	if !ok || !user.IsAdmin {
+		c.Error(errors.NewForbiddenError("Admin permission required"))
+		return
+	}
+
+	stats, err := h.kgService.GetProcessingQueueStats(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+type knowledgeTagBatchRequest struct {
+	Updates map[string]*string `json:"updates" binding:"required,min=1"`
+	KBID    string             `json:"kb_id"` // Optional: scope to this KB (validates editor access and uses effective tenant for shared KB)
+}
+
+// UpdateKnowledgeTagBatch godoc
+// @Summary      批量更新知识标签
+// @Description  批量更新知识条目的标签。可选 kb_id：指定时按该知识库校验编辑权限并用于共享知识库的租户解析
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "标签更新请求（updates 必填，kb_id 可选）"
+// @Success      200      {object}  map[string]interface{}  "更新成功"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Failure      409      {object}  errors.AppError         "知识正在执行其他操作"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/tags [put]
+func (h *KnowledgeHandler) UpdateKnowledgeTagBatch(c *gin.Context) {
+	ctx := c.Request.Context()
 
 	// Ensure tenant ID is in context (service reads it; may be missing if request context was not set by auth)
 	tenantID := c.GetUint64(types.TenantIDContextKey.String())
@@ -1084,6 +2562,7 @@ func (h *KnowledgeHandler) UpdateKnowledgeTagBatch(c *gin.Context) {
 // @Param        request   body      object{image_info=string}  true  "图像信息"
 // @Success      200       {object}  map[string]interface{}     "更新成功"
 // @Failure      400       {object}  errors.AppError            "请求参数错误"
+// @Failure      409       {object}  errors.AppError            "知识正在执行其他操作"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /knowledge/image/{id}/{chunk_id} [put]
@@ -1123,6 +2602,10 @@ func (h *KnowledgeHandler) UpdateImageInfo(c *gin.Context) {
 	logger.Infof(ctx, "Updating knowledge chunk, knowledge ID: %s, chunk ID: %s", id, chunkID)
 	err = h.kgService.UpdateImageInfo(effCtx, id, chunkID, secutils.SanitizeForLog(request.ImageInfo))
 	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(errors.NewInternalServerError(err.Error()))
 		return
@@ -1135,6 +2618,67 @@ func (h *KnowledgeHandler) UpdateImageInfo(c *gin.Context) {
 	})
 }
 
+// SearchKnowledgeByRelevance godoc
+// @Summary      按相关性检索知识
+// @Description  对知识库分块执行混合检索，按每个知识得分最高的分块排序返回知识及命中片段
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true   "知识库ID"
+// @Param        query      query     string  true   "查询内容"
+// @Param        page       query     int     false  "页码"
+// @Param        page_size  query     int     false  "每页数量"
+// @Param        tag_id     query     string  false  "标签ID筛选"
+// @Param        file_type  query     string  false  "文件类型筛选"
+// @Success      200        {object}  map[string]interface{}  "按相关性排序的知识列表"
+// @Failure      400        {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/knowledge/search [get]
+func (h *KnowledgeHandler) SearchKnowledgeByRelevance(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, kbID, effectiveTenantID, _, err := h.validateKnowledgeBaseAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, effectiveTenantID)
+
+	var pagination types.Pagination
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		logger.Error(ctx, "Failed to parse pagination parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	query := c.Query("query")
+	tagID := c.Query("tag_id")
+	fileType := c.Query("file_type")
+
+	logger.Infof(ctx, "Searching knowledge by relevance, knowledge base ID: %s, query: %s, tag_id: %s, file_type: %s",
+		secutils.SanitizeForLog(kbID), secutils.SanitizeForLog(query),
+		secutils.SanitizeForLog(tagID), secutils.SanitizeForLog(fileType))
+
+	result, err := h.kgService.SearchKnowledgeByRelevance(ctx, kbID, query, &pagination, tagID, fileType)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      result.Data,
+		"total":     result.Total,
+		"page":      result.Page,
+		"page_size": result.PageSize,
+	})
+}
+
 // SearchKnowledge godoc
 // @Summary      Search knowledge
 // @Description  Search knowledge files by keyword. When agent_id is set (shared agent), scope is the agent's configured knowledge bases.
@@ -133,6 +133,10 @@ func (h *KnowledgeBaseHandler) CreateKnowledgeBase(c *gin.Context) {
 	// Create knowledge base using the service
 	kb, err := h.service.CreateKnowledgeBase(ctx, &req)
 	if err != nil {
+		if appErr, ok := apperrors.IsAppError(err); ok {
+			c.Error(appErr)
+			return
+		}
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(apperrors.NewInternalServerError(err.Error()))
 		return
@@ -502,6 +506,10 @@ type CopyKnowledgeBaseRequest struct {
 	TaskID   string `json:"task_id"`
 	SourceID string `json:"source_id" binding:"required"`
 	TargetID string `json:"target_id"`
+	// IncludeDisabled 是否复制已停用的分块/FAQ条目，不传时默认为 true（停用状态保留到目标）
+	IncludeDisabled *bool `json:"include_disabled"`
+	// EnableStatusPolicy 复制后文档知识的启用状态：preserve 保留源状态（默认）、enable_all 全部启用、disable_all 全部停用
+	EnableStatusPolicy types.CloneEnableStatusPolicy `json:"enable_status_policy"`
 }
 
 // CopyKnowledgeBaseResponse defines the response for copy knowledge base
@@ -512,19 +520,66 @@ type CopyKnowledgeBaseResponse struct {
 	Message  string `json:"message"`
 }
 
-// CopyKnowledgeBase godoc
-// @Summary      复制知识库
-// @Description  将一个知识库的内容复制到另一个知识库（异步任务）
+// validateCopyKnowledgeBases checks that the source and the optional target knowledge base exist and
+// belong to the caller's tenant, reporting the error on the context when they do not
+func (h *KnowledgeBaseHandler) validateCopyKnowledgeBases(c *gin.Context,
+	tenantID uint64, sourceID, targetID string,
+) bool {
+	ctx := c.Request.Context()
+	// Validate source knowledge base exists and belongs to caller's tenant (prevent cross-tenant clone)
+	sourceKB, err := h.service.GetKnowledgeBaseByID(ctx, sourceID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrKnowledgeBaseNotFound) {
+			c.Error(errors.NewNotFoundError("Source knowledge base not found"))
+			return false
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return false
+	}
+	if sourceKB.TenantID != tenantID {
+		logger.Warnf(ctx,
+			"Copy rejected: source knowledge base belongs to another tenant, source_id: %s, caller_tenant: %d, kb_tenant: %d",
+			secutils.SanitizeForLog(sourceID), tenantID, sourceKB.TenantID)
+		c.Error(errors.NewForbiddenError("No permission to copy this knowledge base"))
+		return false
+	}
+
+	// If target_id provided, validate target belongs to caller's tenant
+	if targetID != "" {
+		targetKB, err := h.service.GetKnowledgeBaseByID(ctx, targetID)
+		if err != nil {
+			if stderrors.Is(err, repository.ErrKnowledgeBaseNotFound) {
+				c.Error(errors.NewNotFoundError("Target knowledge base not found"))
+				return false
+			}
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError(err.Error()))
+			return false
+		}
+		if targetKB.TenantID != tenantID {
+			logger.Warnf(ctx, "Copy rejected: target knowledge base belongs to another tenant, target_id: %s",
+				secutils.SanitizeForLog(targetID))
+			c.Error(errors.NewForbiddenError("No permission to copy to this knowledge base"))
+			return false
+		}
+	}
+	return true
+}
+
+// PreviewCopyKnowledgeBase godoc
+// @Summary      预览知识库同步差异
+// @Description  计算复制/同步将新增和删除的知识及存储、分块规模，不做任何修改
 // @Tags         知识库
 // @Accept       json
 // @Produce      json
 // @Param        request  body      CopyKnowledgeBaseRequest   true  "复制请求"
-// @Success      200      {object}  map[string]interface{}     "任务ID"
+// @Success      200      {object}  map[string]interface{}     "差异预览"
 // @Failure      400      {object}  errors.AppError            "请求参数错误"
 // @Security     Bearer
 // @Security     ApiKeyAuth
-// @Router       /knowledge-bases/copy [post]
-func (h *KnowledgeBaseHandler) CopyKnowledgeBase(c *gin.Context) {
+// @Router       /knowledge-bases/copy/preview [post]
+func (h *KnowledgeBaseHandler) PreviewCopyKnowledgeBase(c *gin.Context) {
 	ctx := c.Request.Context()
 	var req CopyKnowledgeBaseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -533,7 +588,6 @@ func (h *KnowledgeBaseHandler) CopyKnowledgeBase(c *gin.Context) {
 		return
 	}
 
-	// Get tenant ID from context
 	tenantID, exists := c.Get(types.TenantIDContextKey.String())
 	if !exists {
 		logger.Error(ctx, "Failed to get tenant ID")
@@ -541,43 +595,62 @@ func (h *KnowledgeBaseHandler) CopyKnowledgeBase(c *gin.Context) {
 		return
 	}
 
-	// Validate source knowledge base exists and belongs to caller's tenant (prevent cross-tenant clone)
-	sourceKB, err := h.service.GetKnowledgeBaseByID(ctx, req.SourceID)
+	if !h.validateCopyKnowledgeBases(c, tenantID.(uint64), req.SourceID, req.TargetID) {
+		return
+	}
+
+	preview, err := h.knowledgeService.PreviewCloneKnowledgeBase(ctx, req.SourceID, req.TargetID)
 	if err != nil {
-		if stderrors.Is(err, repository.ErrKnowledgeBaseNotFound) {
-			c.Error(errors.NewNotFoundError("Source knowledge base not found"))
+		if appErr, ok := apperrors.IsAppError(err); ok {
+			c.Error(appErr)
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(errors.NewInternalServerError(err.Error()))
 		return
 	}
-	if sourceKB.TenantID != tenantID.(uint64) {
-		logger.Warnf(ctx,
-			"Copy rejected: source knowledge base belongs to another tenant, source_id: %s, caller_tenant: %d, kb_tenant: %d",
-			secutils.SanitizeForLog(req.SourceID), tenantID.(uint64), sourceKB.TenantID)
-		c.Error(errors.NewForbiddenError("No permission to copy this knowledge base"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    preview,
+	})
+}
+
+// CopyKnowledgeBase godoc
+// @Summary      复制知识库
+// @Description  将一个知识库的内容复制到另一个知识库（异步任务）
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CopyKnowledgeBaseRequest   true  "复制请求"
+// @Success      200      {object}  map[string]interface{}     "任务ID"
+// @Failure      400      {object}  errors.AppError            "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/copy [post]
+func (h *KnowledgeBaseHandler) CopyKnowledgeBase(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req CopyKnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(apperrors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+	if !req.EnableStatusPolicy.IsValid() {
+		c.Error(apperrors.NewBadRequestError("enable_status_policy 仅支持 preserve、enable_all 或 disable_all"))
 		return
 	}
 
-	// If target_id provided, validate target belongs to caller's tenant
-	if req.TargetID != "" {
-		targetKB, err := h.service.GetKnowledgeBaseByID(ctx, req.TargetID)
-		if err != nil {
-			if stderrors.Is(err, repository.ErrKnowledgeBaseNotFound) {
-				c.Error(errors.NewNotFoundError("Target knowledge base not found"))
-				return
-			}
-			logger.ErrorWithFields(ctx, err, nil)
-			c.Error(errors.NewInternalServerError(err.Error()))
-			return
-		}
-		if targetKB.TenantID != tenantID.(uint64) {
-			logger.Warnf(ctx, "Copy rejected: target knowledge base belongs to another tenant, target_id: %s",
-				secutils.SanitizeForLog(req.TargetID))
-			c.Error(errors.NewForbiddenError("No permission to copy to this knowledge base"))
-			return
-		}
+	// Get tenant ID from context
+	tenantID, exists := c.Get(types.TenantIDContextKey.String())
+	if !exists {
+		logger.Error(ctx, "Failed to get tenant ID")
+		c.Error(apperrors.NewUnauthorizedError("Unauthorized"))
+		return
+	}
+
+	if !h.validateCopyKnowledgeBases(c, tenantID.(uint64), req.SourceID, req.TargetID) {
+		return
 	}
 
 	// Generate task ID if not provided
@@ -588,10 +661,12 @@ func (h *KnowledgeBaseHandler) CopyKnowledgeBase(c *gin.Context) {
 
 	// Create KB clone payload
 	payload := types.KBClonePayload{
-		TenantID: tenantID.(uint64),
-		TaskID:   taskID,
-		SourceID: req.SourceID,
-		TargetID: req.TargetID,
+		TenantID:           tenantID.(uint64),
+		TaskID:             taskID,
+		SourceID:           req.SourceID,
+		TargetID:           req.TargetID,
+		IncludeDisabled:    req.IncludeDisabled,
+		EnableStatusPolicy: req.EnableStatusPolicy,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -301,6 +301,10 @@ func (h *Handler) SearchKnowledge(c *gin.Context) {
 		return
 	}
 
+	if request.IncludeImages {
+		types.AttachImages(searchResults)
+	}
+
 	logger.Infof(ctx, "Knowledge search completed, found %d results", len(searchResults))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
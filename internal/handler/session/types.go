@@ -47,6 +47,7 @@ type SearchKnowledgeRequest struct {
 	KnowledgeBaseID  string   `json:"knowledge_base_id"`                     // Single knowledge base ID (for backward compatibility)
 	KnowledgeBaseIDs []string `json:"knowledge_base_ids"`                    // IDs of knowledge bases to search (multi-KB support)
 	KnowledgeIDs     []string `json:"knowledge_ids"`                         // IDs of specific knowledge (files) to search
+	IncludeImages    bool     `json:"include_images"`                        // Attach parsed image info (URL, caption, OCR) to results
 }
 
 // StopSessionRequest represents the stop session request
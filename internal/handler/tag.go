@@ -268,7 +268,7 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 
 // DeleteTag godoc
 // @Summary      删除标签
-// @Description  删除标签，可使用force=true强制删除被引用的标签，content_only=true仅删除标签下的内容而保留标签本身
+// @Description  删除标签，可使用force=true强制删除被引用的标签，content_only=true仅删除标签下的内容而保留标签本身，reassign=true将标签下的内容移至"未分类"后删除标签
 // @Tags         标签管理
 // @Accept       json
 // @Produce      json
@@ -276,6 +276,7 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 // @Param        tag_id        path      string              true   "标签ID (UUID或seq_id)"
 // @Param        force         query     bool                false  "强制删除"
 // @Param        content_only  query     bool                false  "仅删除内容，保留标签"
+// @Param        reassign      query     bool                false  "将内容重新分配到未分类标签后删除"
 // @Param        body          body      DeleteTagRequest    false  "删除选项"
 // @Success      200           {object}  map[string]interface{}  "删除成功"
 // @Failure      400           {object}  errors.AppError         "请求参数错误"
@@ -300,6 +301,7 @@ func (h *TagHandler) DeleteTag(c *gin.Context) {
 
 	force := c.Query("force") == "true"
 	contentOnly := c.Query("content_only") == "true"
+	reassign := c.Query("reassign") == "true"
 
 	var req DeleteTagRequest
 	_ = c.ShouldBindJSON(&req)
@@ -318,7 +320,7 @@ func (h *TagHandler) DeleteTag(c *gin.Context) {
 		}
 	}
 
-	if err := h.tagService.DeleteTag(effCtx, tagID, force, contentOnly, excludeUUIDs); err != nil {
+	if err := h.tagService.DeleteTag(effCtx, tagID, force, contentOnly, reassign, excludeUUIDs); err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
 			"tag_id": tagID,
 		})
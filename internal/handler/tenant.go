@@ -20,23 +20,28 @@ import (
 // Provides functionality for creating, retrieving, updating, and deleting tenants
 // through the REST API endpoints
 type TenantHandler struct {
-	service     interfaces.TenantService
-	userService interfaces.UserService
-	config      *config.Config
+	service          interfaces.TenantService
+	userService      interfaces.UserService
+	knowledgeService interfaces.KnowledgeService
+	config           *config.Config
 }
 
 // NewTenantHandler creates a new tenant handler instance with the provided service
 // Parameters:
 //   - service: An implementation of the TenantService interface for business logic
 //   - userService: An implementation of the UserService interface for user operations
+//   - knowledgeService: An implementation of the KnowledgeService interface for storage reconcile
 //   - config: Application configuration
 //
 // Returns a pointer to the newly created TenantHandler
-func NewTenantHandler(service interfaces.TenantService, userService interfaces.UserService, config *config.Config) *TenantHandler {
+func NewTenantHandler(service interfaces.TenantService, userService interfaces.UserService,
+	knowledgeService interfaces.KnowledgeService, config *config.Config,
+) *TenantHandler {
 	return &TenantHandler{
-		service:     service,
-		userService: userService,
-		config:      config,
+		service:          service,
+		userService:      userService,
+		knowledgeService: knowledgeService,
+		config:           config,
 	}
 }
 
@@ -236,6 +241,70 @@ func (h *TenantHandler) DeleteTenant(c *gin.Context) {
 	})
 }
 
+// ReconcileTenantStorage godoc
+// @Summary      校准租户存储用量
+// @Description  按租户下全部知识的实际大小重新计算存储用量，修正增量统计累计的偏差（仅管理员可用，校准其他租户还需要跨租户访问权限）
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "租户ID"
+// @Success      200  {object}  map[string]interface{}  "校准结果"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/{id}/storage/reconcile [post]
+func (h *TenantHandler) ReconcileTenantStorage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Errorf(ctx, "Invalid tenant ID: %s", secutils.SanitizeForLog(c.Param("id")))
+		c.Error(errors.NewBadRequestError("Invalid tenant ID"))
+		return
+	}
+
+	// Reconciling rewrites the tenant's storage accounting, only admins may run it
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		c.Error(errors.NewUnauthorizedError("Failed to get user information").WithDetails(err.Error()))
+		return
+	}
+	if !user.IsAdmin {
+		logger.Warnf(ctx, "User %s attempted to reconcile storage of tenant %d without admin permission", user.ID, id)
+		c.Error(errors.NewForbiddenError("Admin permission required"))
+		return
+	}
+
+	// Reconciling another tenant also requires cross-tenant access permission
+	if currentTenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64); id != currentTenantID {
+		if h.config == nil || h.config.Tenant == nil || !h.config.Tenant.EnableCrossTenantAccess ||
+			!user.CanAccessAllTenants {
+			logger.Warnf(ctx, "User %s attempted to reconcile storage of tenant %d without permission", user.ID, id)
+			c.Error(errors.NewForbiddenError("Insufficient permissions to access other tenants"))
+			return
+		}
+	}
+
+	result, err := h.knowledgeService.RecomputeTenantStorage(ctx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to reconcile tenant storage: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to reconcile tenant storage").WithDetails(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
 // ListTenants godoc
 // @Summary      获取租户列表
 // @Description  获取当前用户可访问的租户列表
@@ -572,7 +641,7 @@ func (h *TenantHandler) updateTenantAgentConfigInternal(c *gin.Context) {
 
 // GetTenantKV godoc
 // @Summary      获取租户KV配置
-// @Description  获取租户级别的KV配置（支持agent-config、web-search-config、conversation-config）
+// @Description  获取租户级别的KV配置（支持agent-config、web-search-config、conversation-config、summary-config、chunking-presets）
 // @Tags         租户管理
 // @Accept       json
 // @Produce      json
@@ -599,6 +668,15 @@ func (h *TenantHandler) GetTenantKV(c *gin.Context) {
 	case "prompt-templates":
 		h.GetPromptTemplates(c)
 		return
+	case "summary-config":
+		h.GetTenantSummaryConfig(c)
+		return
+	case "chunking-presets":
+		h.GetTenantChunkingPresets(c)
+		return
+	case "privacy-config":
+		h.GetTenantPrivacyConfig(c)
+		return
 	default:
 		logger.Info(ctx, "KV key not supported", "key", key)
 		c.Error(errors.NewBadRequestError("unsupported key"))
@@ -608,7 +686,7 @@ func (h *TenantHandler) GetTenantKV(c *gin.Context) {
 
 // UpdateTenantKV godoc
 // @Summary      更新租户KV配置
-// @Description  更新租户级别的KV配置（支持agent-config、web-search-config、conversation-config）
+// @Description  更新租户级别的KV配置（支持agent-config、web-search-config、conversation-config、summary-config、chunking-presets、privacy-config）
 // @Tags         租户管理
 // @Accept       json
 // @Produce      json
@@ -633,6 +711,15 @@ func (h *TenantHandler) UpdateTenantKV(c *gin.Context) {
 	case "conversation-config":
 		h.updateTenantConversationInternal(c)
 		return
+	case "summary-config":
+		h.updateTenantSummaryConfigInternal(c)
+		return
+	case "chunking-presets":
+		h.updateTenantChunkingPresetsInternal(c)
+		return
+	case "privacy-config":
+		h.updateTenantPrivacyConfigInternal(c)
+		return
 	default:
 		logger.Info(ctx, "KV key not supported", "key", key)
 		c.Error(errors.NewBadRequestError("unsupported key"))
@@ -713,6 +800,222 @@ func (h *TenantHandler) GetTenantWebSearchConfig(c *gin.Context) {
 	})
 }
 
+// GetTenantSummaryConfig godoc
+// @Summary      获取租户文档摘要配置
+// @Description  获取租户的文档摘要生成配置（元数据引导模板）
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "文档摘要配置"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/kv/summary-config [get]
+func (h *TenantHandler) GetTenantSummaryConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	config := tenant.SummaryConfig
+	if config == nil {
+		config = &types.DocumentSummaryConfig{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    config,
+	})
+}
+
+// updateTenantSummaryConfigInternal updates tenant's document summary config
+func (h *TenantHandler) updateTenantSummaryConfigInternal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var cfg types.DocumentSummaryConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+	if len([]rune(cfg.MetadataIntroTemplate)) > 2000 {
+		c.Error(errors.NewBadRequestError("metadata_intro_template must be at most 2000 characters"))
+		return
+	}
+	if !types.IsValidSummaryFallbackMode(cfg.FallbackMode) {
+		c.Error(errors.NewBadRequestError("fallback_mode must be one of fail, retry, truncate"))
+		return
+	}
+
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	tenant.SummaryConfig = &cfg
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant summary config").WithDetails(err.Error()))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updatedTenant.SummaryConfig,
+		"message": "Summary configuration updated successfully",
+	})
+}
+
+// GetTenantPrivacyConfig godoc
+// @Summary      获取租户隐私配置
+// @Description  获取租户的隐私配置（如是否在知识接口响应中隐藏文件校验和）
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "隐私配置"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/kv/privacy-config [get]
+func (h *TenantHandler) GetTenantPrivacyConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	config := tenant.PrivacyConfig
+	if config == nil {
+		config = &types.TenantPrivacyConfig{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    config,
+	})
+}
+
+// updateTenantPrivacyConfigInternal updates tenant's privacy config
+func (h *TenantHandler) updateTenantPrivacyConfigInternal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var cfg types.TenantPrivacyConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	tenant.PrivacyConfig = &cfg
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant privacy config").WithDetails(err.Error()))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updatedTenant.PrivacyConfig,
+		"message": "Privacy configuration updated successfully",
+	})
+}
+
+// GetTenantChunkingPresets godoc
+// @Summary      获取租户分块预设
+// @Description  获取租户定义的命名分块预设，可在创建知识库或上传文档时通过 chunking_preset 引用
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "分块预设列表"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/kv/chunking-presets [get]
+func (h *TenantHandler) GetTenantChunkingPresets(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	presets := tenant.ChunkingPresets
+	if presets == nil {
+		presets = types.ChunkingPresets{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    presets,
+	})
+}
+
+// updateTenantChunkingPresetsInternal replaces tenant's chunking presets
+func (h *TenantHandler) updateTenantChunkingPresetsInternal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var presets types.ChunkingPresets
+	if err := c.ShouldBindJSON(&presets); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+	if err := presets.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	if presets == nil {
+		presets = types.ChunkingPresets{}
+	}
+	tenant.ChunkingPresets = presets
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant chunking presets").WithDetails(err.Error()))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updatedTenant.ChunkingPresets,
+		"message": "Chunking presets updated successfully",
+	})
+}
+
 func (h *TenantHandler) buildDefaultConversationConfig() *types.ConversationConfig {
 	return &types.ConversationConfig{
 		Prompt:               h.config.Conversation.Summary.Prompt,
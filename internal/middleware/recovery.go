@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"net/http"
 	"runtime/debug"
 
 	"github.com/sirupsen/logrus"
@@ -14,6 +15,11 @@ func Recovery() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				// http.ErrAbortHandler 用于中断已写出部分内容的响应，交给 net/http 直接断开连接
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				// Get request ID from context
 				ctx := c.Request.Context()
 				requestID, _ := c.Get("RequestID")
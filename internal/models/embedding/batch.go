@@ -14,13 +14,19 @@ import (
 )
 
 type batchEmbedder struct {
-	pool *ants.Pool
+	pool      *ants.Pool
+	batchSize int
 }
 
 func NewBatchEmbedder(pool *ants.Pool) EmbedderPooler {
 	return &batchEmbedder{pool: pool}
 }
 
+// WithBatchSize returns a pooler sharing the goroutine pool that sends at most batchSize texts per request
+func (e *batchEmbedder) WithBatchSize(batchSize int) EmbedderPooler {
+	return &batchEmbedder{pool: e.pool, batchSize: batchSize}
+}
+
 type textEmbedding struct {
 	text    string
 	results []float32
@@ -31,13 +37,17 @@ func (e *batchEmbedder) BatchEmbedWithPool(ctx context.Context, model Embedder,
 	var wg sync.WaitGroup
 	var mu sync.Mutex  // For synchronizing access to error
 	var firstErr error // Record the first error that occurs
-	batchSizeStr := os.Getenv("BATCH_EMBED_SIZE")
-	if batchSizeStr == "" {
-		batchSizeStr = "5"
-	}
-	batchSize, err := strconv.Atoi(batchSizeStr)
-	if err != nil {
-		return nil, err
+	batchSize := e.batchSize
+	if batchSize <= 0 {
+		batchSizeStr := os.Getenv("BATCH_EMBED_SIZE")
+		if batchSizeStr == "" {
+			batchSizeStr = "5"
+		}
+		var err error
+		batchSize, err = strconv.Atoi(batchSizeStr)
+		if err != nil {
+			return nil, err
+		}
 	}
 	textEmbeddings := utils.MapSlice(texts, func(text string) *textEmbedding {
 		return &textEmbedding{text: text}
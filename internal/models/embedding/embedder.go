@@ -34,6 +34,11 @@ type EmbedderPooler interface {
 	BatchEmbedWithPool(ctx context.Context, model Embedder, texts []string) ([][]float32, error)
 }
 
+// batchSizedPooler is implemented by poolers whose request batch size can be set per model
+type batchSizedPooler interface {
+	WithBatchSize(batchSize int) EmbedderPooler
+}
+
 // EmbedderType represents the embedder type
 type EmbedderType string
 
@@ -47,12 +52,17 @@ type Config struct {
 	Dimensions           int               `json:"dimensions"`
 	ModelID              string            `json:"model_id"`
 	Provider             string            `json:"provider"`
+	// BatchSize is the maximum number of texts sent in one embedding request, 0 uses BATCH_EMBED_SIZE
+	BatchSize int `json:"batch_size"`
 }
 
 // NewEmbedder creates an embedder based on the configuration
 func NewEmbedder(config Config, pooler EmbedderPooler, ollamaService *ollama.OllamaService) (Embedder, error) {
 	var embedder Embedder
 	var err error
+	if sized, ok := pooler.(batchSizedPooler); ok && config.BatchSize > 0 {
+		pooler = sized.WithBatchSize(config.BatchSize)
+	}
 	switch strings.ToLower(string(config.Source)) {
 	case string(types.ModelSourceLocal):
 		embedder, err = NewOllamaEmbedder(config.BaseURL,
@@ -156,12 +156,20 @@ func RegisterChunkRoutes(r *gin.RouterGroup, handler *handler.ChunkHandler) {
 		chunks.GET("/:knowledge_id", handler.ListKnowledgeChunks)
 		// 通过chunk_id获取单个chunk（不需要knowledge_id）
 		chunks.GET("/by-id/:id", handler.GetChunkByIDOnly)
+		// 获取分块及其所属知识（用于将检索结果展开为引用）
+		chunks.GET("/by-id/:id/knowledge", handler.GetChunkWithKnowledge)
+		// 批量获取分块及其所属知识
+		chunks.POST("/with-knowledge", handler.GetChunksWithKnowledge)
 		// 删除分块
 		chunks.DELETE("/:knowledge_id/:id", handler.DeleteChunk)
 		// 删除知识下的所有分块
 		chunks.DELETE("/:knowledge_id", handler.DeleteChunksByKnowledgeID)
 		// 更新分块信息
 		chunks.PUT("/:knowledge_id/:id", handler.UpdateChunk)
+		// 批量设置分块标签
+		chunks.PUT("/:knowledge_id/tags", handler.SetChunkTagBatch)
+		// 设置单个分块标签
+		chunks.PUT("/:knowledge_id/:id/tag", handler.SetChunkTag)
 		// 删除单个生成的问题（通过问题ID）
 		chunks.DELETE("/by-id/:id/questions", handler.DeleteGeneratedQuestion)
 	}
@@ -174,12 +182,34 @@ func RegisterKnowledgeRoutes(r *gin.RouterGroup, handler *handler.KnowledgeHandl
 	{
 		// 从文件创建知识
 		kb.POST("/file", handler.CreateKnowledgeFromFile)
+		// 批量从文件创建知识，统一查重与配额校验
+		kb.POST("/files", handler.CreateKnowledgeFromFiles)
 		// 从URL创建知识（支持网页URL和文件URL，传 file_name/file_type 或 URL 含已知扩展名时自动切换为文件下载模式）
 		kb.POST("/url", handler.CreateKnowledgeFromURL)
+		// 从 s3:// 或 cos:// 云存储路径导入知识，支持按前缀批量导入
+		kb.POST("/cloud-storage", handler.CreateKnowledgeFromCloudStorage)
 		// 手工 Markdown 录入
 		kb.POST("/manual", handler.CreateManualKnowledge)
 		// 获取知识库下的知识列表
 		kb.GET("", handler.ListKnowledge)
+		// 按内容与查询的相关性排序检索知识
+		kb.GET("/search", handler.SearchKnowledgeByRelevance)
+		// 清空知识库下的全部知识
+		kb.DELETE("", handler.DeleteAllKnowledge)
+		// 为摘要缺失或失败的知识补生成摘要
+		kb.POST("/summaries/regenerate", handler.RegenerateMissingSummaries)
+		// 删除已有图谱数据并为全部已解析文档重新提交图谱抽取任务
+		kb.POST("/graph/rebuild", handler.RebuildKnowledgeGraph)
+		// 将嵌入模型与知识库不一致的知识重新向量化
+		kb.POST("/embedding/align", handler.AlignKnowledgeEmbeddingModels)
+		// 根据已有分块重建知识库的检索索引（不重新解析）
+		kb.POST("/reindex", handler.ReindexKnowledgeBase)
+		// 获取文档解析时生效的 ReadConfig（仅用于排查，不执行解析）
+		kb.GET("/read-config", handler.GetEffectiveReadConfig)
+		// 获取知识库文档中提取的超链接
+		kb.GET("/links", handler.ListKnowledgeLinks)
+		// 导出文档分块自动生成的问题
+		kb.GET("/generated-questions/export", handler.ExportGeneratedQuestions)
 	}
 
 	// 知识路由组
@@ -187,24 +217,56 @@ func RegisterKnowledgeRoutes(r *gin.RouterGroup, handler *handler.KnowledgeHandl
 	{
 		// 批量获取知识
 		k.GET("/batch", handler.GetKnowledgeBatch)
+		// 知识处理依赖（docreader、嵌入模型、向量存储）自检
+		k.GET("/dependencies/health", handler.CheckDependencies)
 		// 获取知识详情
 		k.GET("/:id", handler.GetKnowledge)
+		// 获取知识详情及分块统计
+		k.GET("/:id/detail", handler.GetKnowledgeDetail)
 		// 删除知识
 		k.DELETE("/:id", handler.DeleteKnowledge)
 		// 更新知识
 		k.PUT("/:id", handler.UpdateKnowledge)
+		// 设置知识过期时间
+		k.PUT("/:id/expiry", handler.SetKnowledgeExpiry)
+		k.PUT("/:id/refresh-schedule", handler.SetKnowledgeRefreshSchedule)
 		// 更新手工 Markdown 知识
 		k.PUT("/manual/:id", handler.UpdateManualKnowledge)
 		// 重新解析知识
 		k.POST("/:id/reparse", handler.ReparseKnowledge)
+		// 取消正在进行的解析
+		k.POST("/:id/cancel", handler.CancelKnowledgeProcessing)
+		// 获取重新解析前的分块快照
+		k.GET("/:id/chunk-snapshot", handler.GetKnowledgeChunkSnapshot)
+		k.GET("/:id/markdown", handler.GetKnowledgeMarkdown)
+		// 获取最近一次解析的分块处理报告
+		k.GET("/:id/processing-report", handler.GetKnowledgeProcessingReport)
+		// 启用已索引但保持禁用的知识
+		k.POST("/:id/enable", handler.EnableKnowledge)
+		// 使用新的嵌入模型重新向量化知识
+		k.POST("/:id/reembed", handler.ReembedKnowledge)
+		// 根据已有分块重建知识的检索索引（不重新解析）
+		k.POST("/:id/reindex", handler.ReindexKnowledge)
 		// 获取知识文件
 		k.GET("/:id/download", handler.DownloadKnowledgeFile)
 		// 更新图像分块信息
 		k.PUT("/image/:id/:chunk_id", handler.UpdateImageInfo)
+		// 列出孤立或重复的图片子分块
+		k.GET("/:id/orphaned-image-chunks", handler.ListOrphanedImageChunks)
+		// 校验并修复文本分块的前后关系链
+		k.GET("/:id/chunk-chain", handler.ValidateChunkChain)
+		k.POST("/:id/chunk-chain/repair", handler.RepairChunkChain)
 		// 批量更新知识标签
 		k.PUT("/tags", handler.UpdateKnowledgeTagBatch)
 		// 搜索知识
 		k.GET("/search", handler.SearchKnowledge)
+		// 获取摘要补生成任务进度
+		k.GET("/summaries/regenerate/progress/:task_id", handler.GetSummaryRegenerationProgress)
+		k.GET("/graph/rebuild/progress/:task_id", handler.GetKnowledgeGraphRebuildProgress)
+		k.GET("/embedding/align/progress/:task_id", handler.GetEmbeddingModelAlignProgress)
+		k.GET("/reindex/progress/:task_id", handler.GetKnowledgeReindexProgress)
+		// 知识处理异步任务队列积压统计（管理员）
+		k.GET("/queue-stats", handler.GetProcessingQueueStats)
 	}
 }
 
@@ -217,23 +279,41 @@ func RegisterFAQRoutes(r *gin.RouterGroup, handler *handler.FAQHandler) {
 	{
 		faq.GET("/entries", handler.ListEntries)
 		faq.GET("/entries/export", handler.ExportEntries)
+		faq.GET("/entries/answer-pending", handler.ListAnswerPendingEntries)
+		faq.GET("/entries/by-question", handler.GetEntryByStandardQuestion)
 		faq.GET("/entries/:entry_id", handler.GetEntry)
 		faq.POST("/entries", handler.UpsertEntries)
+		faq.POST("/entries/convert", handler.ConvertDocumentToFAQ)
+		faq.POST("/entries/convert/commit", handler.CommitFAQConversion)
 		faq.POST("/entry", handler.CreateEntry)
 		faq.PUT("/entries/:entry_id", handler.UpdateEntry)
 		faq.POST("/entries/:entry_id/similar-questions", handler.AddSimilarQuestions)
+		faq.POST("/entries/similar-questions", handler.AddSimilarQuestionsBatch)
 		// Unified batch update API - supports is_enabled, is_recommended, tag_id
 		faq.PUT("/entries/fields", handler.UpdateEntryFieldsBatch)
+		faq.PUT("/entries/fields/by-query", handler.UpdateEntryFieldsByQuery)
 		faq.PUT("/entries/tags", handler.UpdateEntryTagBatch)
 		faq.DELETE("/entries", handler.DeleteEntries)
 		faq.POST("/search", handler.SearchFAQ)
 		// FAQ import result display status
 		faq.PUT("/import/last-result/display", handler.UpdateLastImportResultDisplayStatus)
+		faq.DELETE("/import/lock", handler.ForceClearImportLock)
+		// Backfill content hash for legacy entries before Replace imports
+		faq.POST("/content-hash/backfill", handler.BackfillContentHashes)
 	}
 	// FAQ import progress route (outside of knowledge-base scope)
 	faqImport := r.Group("/faq/import")
 	{
 		faqImport.GET("/progress/:task_id", handler.GetImportProgress)
+		faqImport.GET("/progress/:task_id/archive", handler.GetArchivedImportProgress)
+	}
+	faqConvert := r.Group("/faq/convert")
+	{
+		faqConvert.GET("/progress/:task_id", handler.GetFAQConversionProgress)
+	}
+	faqHashBackfill := r.Group("/faq/content-hash/backfill")
+	{
+		faqHashBackfill.GET("/progress/:task_id", handler.GetContentHashBackfillProgress)
 	}
 }
 
@@ -256,6 +336,8 @@ func RegisterKnowledgeBaseRoutes(r *gin.RouterGroup, handler *handler.KnowledgeB
 		kb.GET("/:id/hybrid-search", handler.HybridSearch)
 		// 拷贝知识库
 		kb.POST("/copy", handler.CopyKnowledgeBase)
+		// 预览知识库同步差异
+		kb.POST("/copy/preview", handler.PreviewCopyKnowledgeBase)
 		// 获取知识库复制进度
 		kb.GET("/copy/progress/:task_id", handler.GetKBCloneProgress)
 	}
@@ -346,6 +428,7 @@ func RegisterTenantRoutes(r *gin.RouterGroup, handler *handler.TenantHandler) {
 		tenantRoutes.GET("/:id", handler.GetTenant)
 		tenantRoutes.PUT("/:id", handler.UpdateTenant)
 		tenantRoutes.DELETE("/:id", handler.DeleteTenant)
+		tenantRoutes.POST("/:id/storage/reconcile", handler.ReconcileTenantStorage)
 		tenantRoutes.GET("", handler.ListTenants)
 
 		// Generic KV configuration management (tenant-level)
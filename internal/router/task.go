@@ -1,6 +1,7 @@
 package router
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
@@ -51,6 +52,11 @@ func NewAsyncqClient() (*asynq.Client, error) {
 	return client, nil
 }
 
+// NewAsynqInspector creates an inspector used to look up the state of enqueued tasks
+func NewAsynqInspector() *asynq.Inspector {
+	return asynq.NewInspector(getAsynqRedisClientOpt())
+}
+
 func NewAsynqServer() *asynq.Server {
 	opt := getAsynqRedisClientOpt()
 	srv := asynq.NewServer(
@@ -86,6 +92,9 @@ func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Register summary generation handler
 	mux.HandleFunc(types.TypeSummaryGeneration, params.KnowledgeService.ProcessSummaryGeneration)
 
+	// Register keyword extraction handler
+	mux.HandleFunc(types.TypeKeywordExtraction, params.KnowledgeService.ProcessKeywordExtraction)
+
 	// Register KB clone handler
 	mux.HandleFunc(types.TypeKBClone, params.KnowledgeService.ProcessKBClone)
 
@@ -98,11 +107,100 @@ func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Register KB delete handler
 	mux.HandleFunc(types.TypeKBDelete, params.KnowledgeBaseService.ProcessKBDelete)
 
+	// Register knowledge expiry handler (enqueued periodically by the scheduler below)
+	mux.HandleFunc(types.TypeKnowledgeExpiry, params.KnowledgeService.ProcessKnowledgeExpiry)
+
+	// Register knowledge refresh handler (enqueued periodically by the scheduler below)
+	mux.HandleFunc(types.TypeKnowledgeRefresh, params.KnowledgeService.ProcessKnowledgeRefresh)
+
+	// Register tenant storage reconcile handler (enqueued periodically by the scheduler below)
+	mux.HandleFunc(types.TypeStorageReconcile, params.KnowledgeService.ProcessTenantStorageReconcile)
+
+	// Register cloud storage import handler
+	mux.HandleFunc(types.TypeCloudStorageImport, params.KnowledgeService.ProcessCloudStorageImport)
+
+	// Register knowledge base reindex handler
+	mux.HandleFunc(types.TypeKnowledgeReindex, params.KnowledgeService.ProcessKnowledgeReindex)
+
+	// Register knowledge reembed handler
+	mux.HandleFunc(types.TypeKnowledgeReembed, params.KnowledgeService.ProcessKnowledgeReembed)
+
+	// Register knowledge graph rebuild handler
+	mux.HandleFunc(types.TypeGraphRebuild, params.KnowledgeService.ProcessKnowledgeGraphRebuild)
+
+	// Register embedding model align handler
+	mux.HandleFunc(types.TypeEmbeddingAlign, params.KnowledgeService.ProcessEmbeddingModelAlign)
+
+	// Register FAQ conversion handler
+	mux.HandleFunc(types.TypeFAQConvert, params.KnowledgeService.ProcessFAQConversion)
+
 	go func() {
 		// Start the server
 		if err := params.Server.Run(mux); err != nil {
 			log.Fatalf("could not run server: %v", err)
 		}
 	}()
+	go runPeriodicTaskScheduler()
 	return mux
 }
+
+// knowledgeExpiryInterval returns how often expired knowledge is swept,
+// configurable via KNOWLEDGE_EXPIRY_INTERVAL (e.g. "5m"), default 10 minutes
+func knowledgeExpiryInterval() time.Duration {
+	if v := os.Getenv("KNOWLEDGE_EXPIRY_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Minute {
+			return d
+		}
+	}
+	return 10 * time.Minute
+}
+
+// knowledgeRefreshInterval returns how often url/file_url knowledge due for refresh is checked,
+// configurable via KNOWLEDGE_REFRESH_INTERVAL (e.g. "10m"), default 5 minutes
+func knowledgeRefreshInterval() time.Duration {
+	if v := os.Getenv("KNOWLEDGE_REFRESH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Minute {
+			return d
+		}
+	}
+	return 5 * time.Minute
+}
+
+// storageReconcileInterval returns how often tenant storage used is recomputed,
+// configurable via STORAGE_RECONCILE_INTERVAL (e.g. "6h"), default 24 hours
+func storageReconcileInterval() time.Duration {
+	if v := os.Getenv("STORAGE_RECONCILE_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Minute {
+			return d
+		}
+	}
+	return 24 * time.Hour
+}
+
+// runPeriodicTaskScheduler periodically enqueues the knowledge expiry, knowledge refresh and storage reconcile tasks.
+// Each task is unique within its interval so multiple instances do not run it concurrently.
+func runPeriodicTaskScheduler() {
+	scheduler := asynq.NewScheduler(getAsynqRedisClientOpt(), nil)
+	periodicTasks := []struct {
+		taskType string
+		interval time.Duration
+	}{
+		{types.TypeKnowledgeExpiry, knowledgeExpiryInterval()},
+		{types.TypeKnowledgeRefresh, knowledgeRefreshInterval()},
+		{types.TypeStorageReconcile, storageReconcileInterval()},
+	}
+	for _, pt := range periodicTasks {
+		if _, err := scheduler.Register(
+			fmt.Sprintf("@every %s", pt.interval),
+			asynq.NewTask(pt.taskType, nil),
+			asynq.Queue("low"),
+			asynq.Unique(pt.interval),
+		); err != nil {
+			log.Printf("could not register periodic task %s: %v", pt.taskType, err)
+			return
+		}
+	}
+	if err := scheduler.Run(); err != nil {
+		log.Printf("could not run periodic task scheduler: %v", err)
+	}
+}
@@ -18,6 +18,7 @@ type ChatManage struct {
 	VectorThreshold  float64       `json:"vector_threshold"`  // Minimum score threshold for vector search results
 	KeywordThreshold float64       `json:"keyword_threshold"` // Minimum score threshold for keyword search results
 	EmbeddingTopK    int           `json:"embedding_top_k"`   // Number of top results to retrieve from embedding search
+	RetrieveTopK     int           `json:"retrieve_top_k"`    // Candidates fetched by each retriever before merging
 	VectorDatabase   string        `json:"vector_database"`   // Vector database type/name to use
 
 	RerankModelID   string  `json:"rerank_model_id"`  // Model ID for reranking search results
@@ -96,6 +97,7 @@ func (c *ChatManage) Clone() *ChatManage {
 		VectorThreshold:  c.VectorThreshold,
 		KeywordThreshold: c.KeywordThreshold,
 		EmbeddingTopK:    c.EmbeddingTopK,
+		RetrieveTopK:     c.RetrieveTopK,
 		MaxRounds:        c.MaxRounds,
 		VectorDatabase:   c.VectorDatabase,
 		RerankModelID:    c.RerankModelID,
@@ -93,6 +93,50 @@ type ImageInfo struct {
 	OCRText string `json:"ocr_text"`
 }
 
+// ChunkLink 表示从 Chunk 内容中提取的超链接
+type ChunkLink struct {
+	// 规范化后的链接地址
+	URL string `json:"url"`
+	// 链接锚文本，裸链接为空
+	AnchorText string `json:"anchor_text,omitempty"`
+}
+
+// KnowledgeLink 表示知识库中某个文档分块引用的超链接
+type KnowledgeLink struct {
+	URL            string `json:"url"`
+	AnchorText     string `json:"anchor_text,omitempty"`
+	KnowledgeID    string `json:"knowledge_id"`
+	KnowledgeTitle string `json:"knowledge_title"`
+	ChunkID        string `json:"chunk_id"`
+	ChunkIndex     int    `json:"chunk_index"`
+}
+
+// GeneratedQuestionExportFormat 自动生成问题的导出格式
+type GeneratedQuestionExportFormat string
+
+const (
+	GeneratedQuestionExportFormatCSV  GeneratedQuestionExportFormat = "csv"
+	GeneratedQuestionExportFormatJSON GeneratedQuestionExportFormat = "json"
+)
+
+// GeneratedQuestionExportFilter 导出自动生成问题时的过滤条件
+type GeneratedQuestionExportFilter struct {
+	KnowledgeID string                        `form:"knowledge_id"`
+	TagID       string                        `form:"tag_id"`
+	Format      GeneratedQuestionExportFormat `form:"format"`
+}
+
+// GeneratedQuestionExportRow 导出的一条自动生成问题及其来源分块
+type GeneratedQuestionExportRow struct {
+	KnowledgeID    string `json:"knowledge_id"`
+	KnowledgeTitle string `json:"knowledge_title"`
+	ChunkID        string `json:"chunk_id"`
+	ChunkIndex     int    `json:"chunk_index"`
+	ChunkExcerpt   string `json:"chunk_excerpt"`
+	QuestionID     string `json:"question_id"`
+	Question       string `json:"question"`
+}
+
 // Chunk represents a document chunk
 // Chunks are meaningful text segments extracted from original documents
 // and are the basic units of knowledge base retrieval
@@ -152,3 +196,91 @@ type Chunk struct {
 	// Soft delete marker, supports data recovery
 	DeletedAt gorm.DeletedAt `json:"deleted_at"               gorm:"index"`
 }
+
+// CitationSpan is a rune range [Start, End) of a source document cited by a generated answer
+type CitationSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// CitationResolution maps a cited span to the text chunks covering it, ordered by position
+type CitationResolution struct {
+	CitationSpan
+	ChunkIDs []string `json:"chunk_ids"`
+}
+
+const (
+	// ImageChunkIssueOrphaned 子分块对应的图片已不在父分块上（或父分块已不存在）
+	ImageChunkIssueOrphaned = "orphaned"
+	// ImageChunkIssueDuplicate 同一图片存在多个同类型的子分块，仅保留最早创建的一个
+	ImageChunkIssueDuplicate = "duplicate"
+)
+
+// OrphanedImageChunk is an image OCR/caption child chunk that is still stored and indexed
+// although it no longer belongs to an image of its parent chunk
+type OrphanedImageChunk struct {
+	ChunkID       string    `json:"chunk_id"`
+	ParentChunkID string    `json:"parent_chunk_id"`
+	ChunkType     ChunkType `json:"chunk_type"`
+	ImageURL      string    `json:"image_url"`
+	Content       string    `json:"content"`
+	// Reason is ImageChunkIssueOrphaned or ImageChunkIssueDuplicate
+	Reason string `json:"reason"`
+}
+
+const (
+	// ChunkChainIssueDangling 前后分块指针指向不存在（或非文本）的分块
+	ChunkChainIssueDangling = "dangling"
+	// ChunkChainIssueBroken 前后指针不对称，如 A 的下一个是 B，但 B 的上一个不是 A
+	ChunkChainIssueBroken = "broken"
+	// ChunkChainIssueFork 多个分块指向同一个下一个（或上一个）分块
+	ChunkChainIssueFork = "fork"
+	// ChunkChainIssueCycle 沿下一个分块指针会回到已访问的分块
+	ChunkChainIssueCycle = "cycle"
+	// ChunkChainIssueOrphan 分块无法从链首沿下一个分块指针到达
+	ChunkChainIssueOrphan = "orphan"
+	// ChunkChainIssueOutOfOrder 下一个分块的 ChunkIndex 不大于当前分块
+	ChunkChainIssueOutOfOrder = "out_of_order"
+)
+
+// ChunkChainAnomaly is one problem found in the PreChunkID/NextChunkID chain of a knowledge's text chunks
+type ChunkChainAnomaly struct {
+	Type           string `json:"type"`
+	ChunkID        string `json:"chunk_id"`
+	ChunkIndex     int    `json:"chunk_index"`
+	RelatedChunkID string `json:"related_chunk_id,omitempty"`
+	Detail         string `json:"detail"`
+}
+
+// ChunkChainReport is the result of validating (and optionally repairing) the text chunk chain of a knowledge
+type ChunkChainReport struct {
+	KnowledgeID string `json:"knowledge_id"`
+	TextChunks  int    `json:"text_chunks"`
+	Valid       bool   `json:"valid"`
+	// AnomalyCount 异常总数，Anomalies 最多返回前 MaxChunkChainAnomalies 个
+	AnomalyCount int                  `json:"anomaly_count"`
+	Anomalies    []*ChunkChainAnomaly `json:"anomalies"`
+	// Repaired 是否已按 ChunkIndex 顺序重建链，RepairedChunks 为前后指针被修改的分块数
+	Repaired       bool `json:"repaired"`
+	RepairedChunks int  `json:"repaired_chunks"`
+}
+
+// MaxChunkChainAnomalies 分块链校验报告中返回的异常明细上限
+const MaxChunkChainAnomalies = 200
+
+// ChunkKnowledgeInfo is the metadata of the knowledge a chunk belongs to, enough to render a citation
+type ChunkKnowledgeInfo struct {
+	ID              string `json:"id"`
+	KnowledgeBaseID string `json:"knowledge_base_id"`
+	Title           string `json:"title"`
+	Type            string `json:"type"`
+	Source          string `json:"source"`
+	FileName        string `json:"file_name"`
+	FileType        string `json:"file_type"`
+}
+
+// ChunkWithKnowledge is a chunk together with its parent knowledge, e.g. a retrieval hit expanded into a citation
+type ChunkWithKnowledge struct {
+	Chunk     *Chunk              `json:"chunk"`
+	Knowledge *ChunkKnowledgeInfo `json:"knowledge"`
+}
@@ -138,6 +138,8 @@ type CustomAgentConfig struct {
 	// ===== Retrieval Strategy Settings (for both modes) =====
 	// Embedding/Vector retrieval top K
 	EmbeddingTopK int `yaml:"embedding_top_k" json:"embedding_top_k"`
+	// Candidates fetched by each retriever before merging, 0 uses 3x EmbeddingTopK
+	RetrieveTopK int `yaml:"retrieve_top_k" json:"retrieve_top_k"`
 	// Keyword retrieval threshold
 	KeywordThreshold float64 `yaml:"keyword_threshold" json:"keyword_threshold"`
 	// Vector retrieval threshold
@@ -6,11 +6,21 @@ const (
 	TypeFAQImport           = "faq:import"            // FAQ导入任务（包含dry run模式）
 	TypeQuestionGeneration  = "question:generation"   // 问题生成任务
 	TypeSummaryGeneration   = "summary:generation"    // 摘要生成任务
+	TypeKeywordExtraction   = "keyword:extraction"    // 文档关键词/实体提取任务
 	TypeKBClone             = "kb:clone"              // 知识库复制任务
 	TypeIndexDelete         = "index:delete"          // 索引删除任务
 	TypeKBDelete            = "kb:delete"             // 知识库删除任务
 	TypeKnowledgeListDelete = "knowledge:list_delete" // 批量删除知识任务
 	TypeDataTableSummary    = "datatable:summary"     // 表格摘要任务
+	TypeKnowledgeExpiry     = "knowledge:expiry"      // 知识到期通知与清理任务（周期执行）
+	TypeKnowledgeRefresh    = "knowledge:refresh"     // URL 知识定时刷新任务（周期执行）
+	TypeStorageReconcile    = "storage:reconcile"     // 租户存储用量校准任务（周期执行）
+	TypeCloudStorageImport  = "cloud_storage:import"  // 云存储对象导入任务
+	TypeKnowledgeReindex    = "knowledge:reindex"     // 知识库重建索引任务
+	TypeKnowledgeReembed    = "knowledge:reembed"     // 知识重新向量化任务
+	TypeGraphRebuild        = "graph:rebuild"         // 知识库知识图谱重建任务
+	TypeEmbeddingAlign      = "embedding:align"       // 知识嵌入模型对齐任务
+	TypeFAQConvert          = "faq:convert"           // 从文档知识抽取FAQ条目任务
 )
 
 // ExtractChunkPayload represents the extract chunk task payload
@@ -35,6 +45,77 @@ type DocumentProcessPayload struct {
 	EnableMultimodel         bool     `json:"enable_multimodel"`
 	EnableQuestionGeneration bool     `json:"enable_question_generation"` // 是否启用问题生成
 	QuestionCount            int      `json:"question_count,omitempty"`   // 每个chunk生成的问题数量
+	// SkipSummary 跳过文档摘要生成，保留已有摘要（重新解析时使用）
+	SkipSummary bool `json:"skip_summary,omitempty"`
+	// PreservedQuestions 重新解析时保留的已生成问题，按分块内容 hash 索引，内容未变的分块直接复用
+	PreservedQuestions map[string][]GeneratedQuestion `json:"preserved_questions,omitempty"`
+	// ParseProfile 仅对本次解析生效的解析参数，设置时替代文档记住的解析参数
+	ParseProfile *KnowledgeParseProfile `json:"parse_profile,omitempty"`
+	// OffloadURL payload 超过内联大小上限时，Passages 与 PreservedQuestions 转存到对象存储，这里存储 URL
+	OffloadURL string `json:"offload_url,omitempty"`
+	// LockToken 重新解析交接给本任务的知识锁，任务结束（成功或最后一次重试失败）后释放
+	LockToken string `json:"lock_token,omitempty"`
+}
+
+// CloudStorageImportPayload represents the task payload copying one cloud storage object into file storage
+type CloudStorageImportPayload struct {
+	TenantID        uint64 `json:"tenant_id"`
+	KnowledgeID     string `json:"knowledge_id"`
+	KnowledgeBaseID string `json:"knowledge_base_id"`
+	Scheme          string `json:"scheme"`
+	Bucket          string `json:"bucket"`
+	Key             string `json:"key"`
+	// SealedCredentials 加密后的访问凭证，任务结束后随任务一起过期
+	SealedCredentials string `json:"sealed_credentials"`
+	EnableMultimodel  bool   `json:"enable_multimodel"`
+}
+
+// KnowledgeReindexPayload represents the task payload rebuilding the index of a knowledge base
+type KnowledgeReindexPayload struct {
+	TenantID uint64 `json:"tenant_id"`
+	TaskID   string `json:"task_id"`
+	KBID     string `json:"kb_id"`
+}
+
+// GraphRebuildPayload represents the task payload rebuilding the knowledge graph of a knowledge base
+type GraphRebuildPayload struct {
+	TenantID uint64 `json:"tenant_id"`
+	TaskID   string `json:"task_id"`
+	KBID     string `json:"kb_id"`
+}
+
+// EmbeddingAlignPayload represents the task payload re-embedding the knowledge of a knowledge base whose
+// embedding model differs from the knowledge base's
+type EmbeddingAlignPayload struct {
+	TenantID uint64 `json:"tenant_id"`
+	TaskID   string `json:"task_id"`
+	KBID     string `json:"kb_id"`
+}
+
+// FAQConvertPayload represents the task payload extracting FAQ entries from a document knowledge.
+// The source knowledge and the chat model may belong to other tenants than the target FAQ knowledge base
+// when shared through an organization.
+type FAQConvertPayload struct {
+	TenantID          uint64 `json:"tenant_id"`
+	TaskID            string `json:"task_id"`
+	KBID              string `json:"kb_id"`
+	SourceTenantID    uint64 `json:"source_tenant_id"`
+	SourceKnowledgeID string `json:"source_knowledge_id"`
+	ChatModelID       string `json:"chat_model_id"`
+	ChatModelTenantID uint64 `json:"chat_model_tenant_id"`
+}
+
+// KnowledgeReembedPayload represents the task payload re-embedding a knowledge with a new embedding model
+type KnowledgeReembedPayload struct {
+	TenantID    uint64 `json:"tenant_id"`
+	KnowledgeID string `json:"knowledge_id"`
+	ModelID     string `json:"model_id"`
+}
+
+// DocumentProcessOffload 文档处理任务中转存到对象存储的大字段
+type DocumentProcessOffload struct {
+	Passages           []string                       `json:"passages,omitempty"`
+	PreservedQuestions map[string][]GeneratedQuestion `json:"preserved_questions,omitempty"`
 }
 
 // FAQImportPayload represents the FAQ import task payload (including dry run mode)
@@ -49,6 +130,8 @@ type FAQImportPayload struct {
 	Mode        string            `json:"mode"`
 	DryRun      bool              `json:"dry_run"`     // dry run 模式只验证不导入
 	EnqueuedAt  int64             `json:"enqueued_at"` // 任务入队时间戳，用于区分同一 TaskID 的不同次提交
+	// AllowEmptyAnswers 允许导入没有答案的条目
+	AllowEmptyAnswers bool `json:"allow_empty_answers,omitempty"`
 }
 
 // QuestionGenerationPayload represents the question generation task payload
@@ -66,12 +149,57 @@ type SummaryGenerationPayload struct {
 	KnowledgeID     string `json:"knowledge_id"`
 }
 
+// KeywordExtractionPayload represents the document keyword/entity extraction task payload
+type KeywordExtractionPayload struct {
+	TenantID        uint64 `json:"tenant_id"`
+	KnowledgeBaseID string `json:"knowledge_base_id"`
+	KnowledgeID     string `json:"knowledge_id"`
+}
+
 // KBClonePayload represents the knowledge base clone task payload
 type KBClonePayload struct {
 	TenantID uint64 `json:"tenant_id"`
 	TaskID   string `json:"task_id"`
 	SourceID string `json:"source_id"`
 	TargetID string `json:"target_id"`
+	// IncludeDisabled controls whether disabled chunks and FAQ entries are cloned.
+	// nil means DefaultCloneIncludeDisabled.
+	IncludeDisabled *bool `json:"include_disabled,omitempty"`
+	// EnableStatusPolicy controls the enable status of cloned document knowledge, empty means preserve
+	EnableStatusPolicy CloneEnableStatusPolicy `json:"enable_status_policy,omitempty"`
+}
+
+// CloneEnableStatusPolicy 定义复制知识库时文档知识启用状态的处理方式
+type CloneEnableStatusPolicy string
+
+const (
+	// CloneEnableStatusPreserve 保留源知识的启用状态（默认）
+	CloneEnableStatusPreserve CloneEnableStatusPolicy = "preserve"
+	// CloneEnableStatusEnableAll 复制后的知识全部启用
+	CloneEnableStatusEnableAll CloneEnableStatusPolicy = "enable_all"
+	// CloneEnableStatusDisableAll 复制后的知识全部停用
+	CloneEnableStatusDisableAll CloneEnableStatusPolicy = "disable_all"
+)
+
+// IsValid reports whether the policy is empty (preserve) or one of the supported policies
+func (p CloneEnableStatusPolicy) IsValid() bool {
+	switch p {
+	case "", CloneEnableStatusPreserve, CloneEnableStatusEnableAll, CloneEnableStatusDisableAll:
+		return true
+	}
+	return false
+}
+
+// DefaultCloneIncludeDisabled clones behave like backups by default: disabled chunks and
+// FAQ entries are copied and stay disabled in the target.
+const DefaultCloneIncludeDisabled = true
+
+// ShouldIncludeDisabled returns whether disabled content should be cloned
+func (p KBClonePayload) ShouldIncludeDisabled() bool {
+	if p.IncludeDisabled == nil {
+		return DefaultCloneIncludeDisabled
+	}
+	return *p.IncludeDisabled
 }
 
 // IndexDeletePayload represents the index delete task payload
@@ -122,6 +250,26 @@ type KBCloneProgress struct {
 	UpdatedAt int64             `json:"updated_at"` // 最后更新时间
 }
 
+// KBClonePreview describes what a knowledge base clone would change in the target without performing it
+type KBClonePreview struct {
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+	// AddKnowledgeIDs 源知识库中将被复制到目标的知识
+	AddKnowledgeIDs []string `json:"add_knowledge_ids"`
+	// DeleteKnowledgeIDs 目标知识库中将被删除的知识（源中不存在相同文件）
+	DeleteKnowledgeIDs []string `json:"delete_knowledge_ids"`
+	// SkippedKnowledgeIDs 待复制但尚未解析完成的知识，复制时会被跳过
+	SkippedKnowledgeIDs []string `json:"skipped_knowledge_ids"`
+	// AddStorageBytes 复制后目标新增的存储占用
+	AddStorageBytes int64 `json:"add_storage_bytes"`
+	// DeleteStorageBytes 删除后释放的存储占用
+	DeleteStorageBytes int64 `json:"delete_storage_bytes"`
+	// AddChunkCount 将被复制的分块数，即需要写入的向量索引条目数
+	AddChunkCount int64 `json:"add_chunk_count"`
+	// DeleteChunkCount 将被删除的分块数
+	DeleteChunkCount int64 `json:"delete_chunk_count"`
+}
+
 // ChunkContext represents chunk content with surrounding context
 type ChunkContext struct {
 	ChunkID     string `json:"chunk_id"`
@@ -173,3 +321,36 @@ func (n NameSpace) Labels() []string {
 	}
 	return res
 }
+
+// ProcessingQueueStats 知识处理相关异步任务的队列积压统计
+type ProcessingQueueStats struct {
+	Queues []*ProcessingQueueStat `json:"queues"`
+	// GeneratedAt 统计时间（Unix 秒）
+	GeneratedAt int64 `json:"generated_at"`
+}
+
+// ProcessingQueueStat 单个 asynq 队列的任务统计
+type ProcessingQueueStat struct {
+	Queue  string `json:"queue"`
+	Paused bool   `json:"paused"`
+	// 队列内全部任务（含非知识处理任务）按状态的数量，Dead 对应 asynq 的 archived 状态
+	Pending int `json:"pending"`
+	Active  int `json:"active"`
+	Retry   int `json:"retry"`
+	Dead    int `json:"dead"`
+	// OldestPendingAgeSeconds 最早一个待处理任务已等待的秒数，持续增长说明队列可能卡住
+	OldestPendingAgeSeconds float64 `json:"oldest_pending_age_seconds"`
+	// TaskTypes 知识处理任务按类型的数量
+	TaskTypes []*ProcessingTaskTypeStat `json:"task_types"`
+	// Truncated 任务过多时按类型计数只扫描了部分任务
+	Truncated bool `json:"truncated"`
+}
+
+// ProcessingTaskTypeStat 队列中某种任务类型按状态的数量
+type ProcessingTaskTypeStat struct {
+	TaskType string `json:"task_type"`
+	Pending  int    `json:"pending"`
+	Active   int    `json:"active"`
+	Retry    int    `json:"retry"`
+	Dead     int    `json:"dead"`
+}
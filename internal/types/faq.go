@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"math/rand/v2"
 	"sort"
 	"strings"
 	"time"
@@ -18,6 +19,10 @@ type FAQChunkMetadata struct {
 	AnswerStrategy    AnswerStrategy `json:"answer_strategy,omitempty"`
 	Version           int            `json:"version,omitempty"`
 	Source            string         `json:"source,omitempty"`
+	// ContentFormat 答案的内容格式，为空时使用知识库 FAQConfig 的默认格式
+	ContentFormat AnswerContentFormat `json:"content_format,omitempty"`
+	// AnswerPending 条目以无答案方式导入，仅用于问题匹配，待补充答案；补充答案后清除
+	AnswerPending bool `json:"answer_pending,omitempty"`
 }
 
 // GeneratedQuestion 表示AI生成的单个问题
@@ -27,11 +32,17 @@ type GeneratedQuestion struct {
 }
 
 // DocumentChunkMetadata 定义文档 Chunk 的元数据结构
-// 用于存储AI生成的问题等增强信息
+// 用于存储AI生成的问题、超链接等增强信息
 type DocumentChunkMetadata struct {
 	// GeneratedQuestions 存储AI为该Chunk生成的相关问题
 	// 这些问题会被独立索引以提高召回率
 	GeneratedQuestions []GeneratedQuestion `json:"generated_questions,omitempty"`
+	// Links 存储解析时从该Chunk中提取的超链接
+	Links []ChunkLink `json:"links,omitempty"`
+	// Section 解析时记录的分块所在章节标题，供索引内容模板的 {{section}} 使用
+	Section string `json:"section,omitempty"`
+	// IndexError 分块写入检索索引失败时的原因，此时分块仅保存不参与检索
+	IndexError string `json:"index_error,omitempty"`
 }
 
 // GetQuestionStrings 返回问题内容字符串列表（兼容旧代码）
@@ -75,7 +86,17 @@ func (c *Chunk) SetDocumentMetadata(meta *DocumentChunkMetadata) error {
 	return nil
 }
 
-// Normalize 清理空白与重复项
+// ApplyOrder 按配置调整列表顺序：preserve 模式保持原有顺序，sorted 模式对相似问、反例与答案排序
+func (m *FAQChunkMetadata) ApplyOrder(order FAQAnswerOrder) {
+	if m == nil || order != FAQAnswerOrderSorted {
+		return
+	}
+	sort.Strings(m.SimilarQuestions)
+	sort.Strings(m.NegativeQuestions)
+	sort.Strings(m.Answers)
+}
+
+// Normalize 清理空白与重复项，保持原有顺序（去重不会改变列表顺序）
 func (m *FAQChunkMetadata) Normalize() {
 	if m == nil {
 		return
@@ -173,6 +194,49 @@ const (
 	AnswerStrategyRandom AnswerStrategy = "random"
 )
 
+// AnswerContentFormat 定义答案内容的格式，供客户端决定如何渲染答案
+type AnswerContentFormat string
+
+const (
+	// AnswerContentFormatPlain 纯文本
+	AnswerContentFormatPlain AnswerContentFormat = "plain"
+	// AnswerContentFormatMarkdown Markdown
+	AnswerContentFormatMarkdown AnswerContentFormat = "markdown"
+	// AnswerContentFormatHTML HTML
+	AnswerContentFormatHTML AnswerContentFormat = "html"
+)
+
+// IsValid reports whether the format is one of the supported answer content formats
+func (f AnswerContentFormat) IsValid() bool {
+	switch f {
+	case AnswerContentFormatPlain, AnswerContentFormatMarkdown, AnswerContentFormatHTML:
+		return true
+	}
+	return false
+}
+
+// SelectAnswers 按条目的答案策略确定实际展示的答案：all 返回全部答案，random 随机返回一个。
+// seed 不为空时随机选择可复现（同一 seed 与条目总是选中同一答案）。
+// limit > 0 时 all 策略最多返回前 limit 个答案。
+func (e *FAQEntry) SelectAnswers(seed *int64, limit int) []string {
+	if len(e.Answers) == 0 {
+		return nil
+	}
+	if e.AnswerStrategy != AnswerStrategyRandom || len(e.Answers) == 1 {
+		if limit > 0 && len(e.Answers) > limit {
+			return e.Answers[:limit]
+		}
+		return e.Answers
+	}
+	var idx int
+	if seed != nil {
+		idx = rand.New(rand.NewPCG(uint64(*seed), uint64(e.ID))).IntN(len(e.Answers))
+	} else {
+		idx = rand.IntN(len(e.Answers))
+	}
+	return []string{e.Answers[idx]}
+}
+
 // FAQEntry 表示返回给前端的 FAQ 条目
 type FAQEntry struct {
 	ID                int64          `json:"id"`
@@ -197,6 +261,27 @@ type FAQEntry struct {
 	// MatchedQuestion is the actual question text that was matched in FAQ search
 	// Could be the standard question or one of the similar questions
 	MatchedQuestion string `json:"matched_question,omitempty"`
+	// MatchedSimilarQuestionIndex is the index in SimilarQuestions of the matched similar question
+	// Only set when the knowledge base indexes questions separately and a similar question matched
+	MatchedSimilarQuestionIndex *int `json:"matched_similar_question_index,omitempty"`
+	// MatchedAnswerIndex is the index in Answers of the matched answer
+	// Only set when answers are indexed on their own (question_answer index mode with separate or hybrid
+	// question indexing) and the match came from an answer
+	MatchedAnswerIndex *int `json:"matched_answer_index,omitempty"`
+	// MatchedAnswerHighlight is the part of the matched answer that overlaps the query
+	MatchedAnswerHighlight *FAQTextSpan `json:"matched_answer_highlight,omitempty"`
+	// ContentFormat 答案的内容格式（plain/markdown/html），未单独设置时为知识库默认格式
+	ContentFormat AnswerContentFormat `json:"content_format"`
+	// AnswerPending 条目尚无答案，待补充
+	AnswerPending bool `json:"answer_pending"`
+	// UnindexedSimilarQuestions 受 max_indexed_similar_questions 限制，已保存但未建立索引的相似问数量
+	UnindexedSimilarQuestions int `json:"unindexed_similar_questions,omitempty"`
+}
+
+// FAQTextSpan is a rune range [Start, End) within a FAQ text such as an answer
+type FAQTextSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
 
 // FAQEntryPayload 用于创建/更新 FAQ 条目的 payload
@@ -212,6 +297,8 @@ type FAQEntryPayload struct {
 	TagName           string          `json:"tag_name"`
 	IsEnabled         *bool           `json:"is_enabled,omitempty"`
 	IsRecommended     *bool           `json:"is_recommended,omitempty"`
+	// ContentFormat 答案的内容格式（plain/markdown/html），为空时使用知识库默认格式
+	ContentFormat *AnswerContentFormat `json:"content_format,omitempty"`
 }
 
 const (
@@ -226,6 +313,8 @@ type FAQBatchUpsertPayload struct {
 	KnowledgeID string            `json:"knowledge_id"`
 	TaskID      string            `json:"task_id"` // 可选，如果不传则自动生成UUID
 	DryRun      bool              `json:"dry_run"` // 仅验证，不实际导入
+	// AllowEmptyAnswers 允许导入没有答案的条目（标记为待补充答案），默认不允许，无答案的条目导入失败
+	AllowEmptyAnswers bool `json:"allow_empty_answers"`
 }
 
 // FAQFailedEntry 表示导入/验证失败的条目
@@ -241,6 +330,57 @@ type FAQFailedEntry struct {
 	IsDisabled        bool     `json:"is_disabled,omitempty"`        // 是否停用
 }
 
+// FAQSkipReason 表示导入时跳过条目的原因类型
+type FAQSkipReason string
+
+const (
+	// FAQSkipReasonInvalid 条目内容无效
+	FAQSkipReasonInvalid FAQSkipReason = "invalid"
+	// FAQSkipReasonDuplicateStandardQuestion 标准问与知识库或同批次已有问题重复
+	FAQSkipReasonDuplicateStandardQuestion FAQSkipReason = "duplicate_standard_question"
+	// FAQSkipReasonDuplicateSimilarQuestion 相似问与知识库或同批次已有问题重复
+	FAQSkipReasonDuplicateSimilarQuestion FAQSkipReason = "duplicate_similar_question"
+	// FAQSkipReasonUnchanged 内容 hash 与分类均未变化（replace 模式）
+	FAQSkipReasonUnchanged FAQSkipReason = "unchanged"
+)
+
+// MaxInlineFAQSkippedEntries 导入进度中内联返回的跳过条目上限，超出部分导出为 CSV
+const MaxInlineFAQSkippedEntries = 100
+
+// DefaultFAQExportIncludeDisabled FAQ 导出默认包含已停用条目（通过“是否停用”列保留状态），
+// 保证导出结果可以原样导入恢复
+const DefaultFAQExportIncludeDisabled = true
+
+// FAQExportFormat FAQ 导出文件格式
+type FAQExportFormat string
+
+const (
+	// FAQExportFormatCSV 与导入模板相同列的 CSV（默认）
+	FAQExportFormatCSV FAQExportFormat = "csv"
+	// FAQExportFormatJSON FAQEntry 列表
+	FAQExportFormatJSON FAQExportFormat = "json"
+	// FAQExportFormatXLSX 与导入模板相同列的 Excel 表格
+	FAQExportFormatXLSX FAQExportFormat = "xlsx"
+)
+
+// IsValid reports whether the export format is supported
+func (f FAQExportFormat) IsValid() bool {
+	switch f {
+	case FAQExportFormatCSV, FAQExportFormatJSON, FAQExportFormatXLSX:
+		return true
+	}
+	return false
+}
+
+// FAQSkippedEntry 表示导入时被跳过的条目
+type FAQSkippedEntry struct {
+	Index             int           `json:"index"`                        // 条目在批次中的索引（从0开始）
+	ReasonCode        FAQSkipReason `json:"reason_code"`                  // 跳过原因类型
+	Reason            string        `json:"reason"`                       // 跳过原因描述
+	StandardQuestion  string        `json:"standard_question"`            // 标准问题
+	DuplicateQuestion string        `json:"duplicate_question,omitempty"` // 重复的问题（相似问重复时）
+}
+
 // FAQSuccessEntry 表示导入成功的条目简单信息
 type FAQSuccessEntry struct {
 	Index            int    `json:"index"`              // 条目在批次中的索引（从0开始）
@@ -248,6 +388,8 @@ type FAQSuccessEntry struct {
 	TagID            int64  `json:"tag_id,omitempty"`   // 分类ID（seq_id）
 	TagName          string `json:"tag_name,omitempty"` // 分类名称
 	StandardQuestion string `json:"standard_question"`  // 标准问题
+	// UnindexedSimilarQuestions 受单条目索引上限限制，已保存但未建立索引的相似问数量
+	UnindexedSimilarQuestions int `json:"unindexed_similar_questions,omitempty"`
 }
 
 // FAQDryRunResult 表示 dry_run 模式的验证结果
@@ -259,6 +401,36 @@ type FAQDryRunResult struct {
 	FailedEntries []FAQFailedEntry `json:"failed_entries"`    // 失败条目详情
 }
 
+// FAQSimilarQuestionsBatchFailure 表示批量添加相似问时处理失败的条目
+type FAQSimilarQuestionsBatchFailure struct {
+	EntryID int64  `json:"entry_id"` // 条目ID（seq_id）
+	Reason  string `json:"reason"`   // 失败原因
+}
+
+// FAQSimilarQuestionsBatchResult 表示批量添加相似问的结果
+type FAQSimilarQuestionsBatchResult struct {
+	Entries       []*FAQEntry                       `json:"entries"`        // 更新后的条目
+	FailedEntries []FAQSimilarQuestionsBatchFailure `json:"failed_entries"` // 失败条目详情
+	AddedCounts   map[int64]int                     `json:"added_counts"`   // 各条目去重后实际新增的相似问数量
+}
+
+// FAQExtractedEntry 表示从文档中抽取出的 FAQ 条目
+type FAQExtractedEntry struct {
+	FAQEntryPayload
+	Confidence    float64 `json:"confidence"`      // 抽取置信度（0-1）
+	SourceChunkID string  `json:"source_chunk_id"` // 来源分块ID
+}
+
+// FAQConversionProgress represents the progress of extracting FAQ entries from a document knowledge,
+// stored in Redis. Total, Processed and Failed count the text chunks of the source knowledge.
+// KBID is the target FAQ knowledge base; the entries are only imported once committed after review.
+type FAQConversionProgress struct {
+	KnowledgeTaskProgress
+	SourceKnowledgeID string              `json:"source_knowledge_id"`          // 源文档知识ID
+	Entries           []FAQExtractedEntry `json:"entries,omitempty"`            // 抽取出的条目（完成后），审阅修改后通过提交接口导入
+	AverageConfidence float64             `json:"average_confidence,omitempty"` // 平均置信度
+}
+
 // FAQSearchRequest FAQ检索请求参数
 type FAQSearchRequest struct {
 	QueryText            string  `json:"query_text"             binding:"required"`
@@ -267,8 +439,69 @@ type FAQSearchRequest struct {
 	FirstPriorityTagIDs  []int64 `json:"first_priority_tag_ids"`  // 第一优先级标签ID列表，限定命中范围，优先级最高
 	SecondPriorityTagIDs []int64 `json:"second_priority_tag_ids"` // 第二优先级标签ID列表，限定命中范围，优先级低于第一优先级
 	OnlyRecommended      bool    `json:"only_recommended"`        // 是否仅返回推荐的条目
+	// CandidatePoolSize 每个优先级检索返回的候选数量，合并排序后再截断到 MatchCount，
+	// 默认为 MatchCount 的 FAQSearchCandidatePoolMultiplier 倍，最大 MaxFAQSearchCandidatePoolSize
+	CandidatePoolSize int `json:"candidate_pool_size"`
+	// ResponseMode 结果返回形式，默认 entries 返回完整条目；answers 仅返回每个命中条目按答案策略选出的答案及分数
+	ResponseMode FAQSearchResponseMode `json:"response_mode" binding:"omitempty,oneof=entries answers"`
+	// AnswerSeed answers 模式下随机答案策略的种子，指定后选择结果可复现
+	AnswerSeed *int64 `json:"answer_seed,omitempty"`
+	// ExcludeAnswerPending 不返回待补充答案的条目
+	ExcludeAnswerPending bool `json:"exclude_answer_pending"`
+	// MaxAnswers answers 模式下单个条目最多返回的答案数，覆盖知识库 FAQConfig 的 max_displayed_answers，<=0 时使用知识库配置
+	MaxAnswers int `json:"max_answers"`
+}
+
+// FAQSearchResponseMode 定义 FAQ 搜索结果的返回形式
+type FAQSearchResponseMode string
+
+const (
+	// FAQSearchResponseModeEntries 返回完整的 FAQ 条目（默认）
+	FAQSearchResponseModeEntries FAQSearchResponseMode = "entries"
+	// FAQSearchResponseModeAnswers 返回每个命中条目应展示的答案及匹配分数
+	FAQSearchResponseModeAnswers FAQSearchResponseMode = "answers"
+)
+
+// FAQAnswerMatch answers 模式下单个命中条目的答案
+type FAQAnswerMatch struct {
+	EntryID          int64     `json:"entry_id"`
+	StandardQuestion string    `json:"standard_question"`
+	MatchedQuestion  string    `json:"matched_question,omitempty"`
+	TagID            int64     `json:"tag_id"`
+	TagName          string    `json:"tag_name,omitempty"`
+	Answers          []string  `json:"answers"`
+	Score            float64   `json:"score"`
+	MatchType        MatchType `json:"match_type,omitempty"`
+	// ContentFormat 答案的内容格式，客户端据此渲染答案
+	ContentFormat AnswerContentFormat `json:"content_format"`
+	// TotalAnswers 条目的答案总数
+	TotalAnswers int `json:"total_answers"`
+	// HasMoreAnswers 答案因数量上限被截断，可通过条目详情接口获取全部答案
+	HasMoreAnswers bool `json:"has_more_answers,omitempty"`
 }
 
+// FAQSearchResult FAQ 搜索结果
+type FAQSearchResult struct {
+	Entries []*FAQEntry `json:"entries"`
+	// Answers 仅在 answers 模式下填充，与 Entries 一一对应且顺序一致
+	Answers []*FAQAnswerMatch `json:"answers,omitempty"`
+	// Partial 为 true 表示部分优先级检索失败，结果仅来自检索成功的优先级
+	Partial bool `json:"partial"`
+	// NoMatch 为 true 表示没有条目超过阈值，仅在知识库配置了 no_match_fallback 时设置
+	NoMatch bool `json:"no_match,omitempty"`
+	// Fallback 无命中时返回的兜底条目，仅在 no_match_fallback 为 entry 时填充
+	Fallback *FAQEntry `json:"fallback,omitempty"`
+	// FallbackAnswer answers 模式下兜底条目按答案策略选出的答案
+	FallbackAnswer *FAQAnswerMatch `json:"fallback_answer,omitempty"`
+}
+
+const (
+	// FAQSearchCandidatePoolMultiplier FAQ 搜索默认候选池相对 MatchCount 的倍数
+	FAQSearchCandidatePoolMultiplier = 3
+	// MaxFAQSearchCandidatePoolSize FAQ 搜索候选池上限
+	MaxFAQSearchCandidatePoolSize = 200
+)
+
 // UntaggedTagName is the default tag name for entries without a tag
 const UntaggedTagName = "未分类"
 
@@ -293,6 +526,38 @@ type FAQEntryFieldsBatchUpdate struct {
 	ExcludeIDs []int64 `json:"exclude_ids,omitempty"`
 }
 
+// FAQEntryQueryUpdateMaxEntries 按查询批量更新时单次允许命中的最大条目数
+const FAQEntryQueryUpdateMaxEntries = 5000
+
+// FAQEntryQueryUpdatePreviewLimit 按查询批量更新时返回的命中条目预览数量上限
+const FAQEntryQueryUpdatePreviewLimit = 100
+
+// FAQEntryQueryUpdate 按搜索条件批量更新FAQ条目字段的请求
+// 先按关键词（及可选的分类）筛选出条目，再对全部命中条目应用同一更新；DryRun 时只返回命中情况
+type FAQEntryQueryUpdate struct {
+	// Keyword 关键词，匹配规则与FAQ列表的关键词筛选一致
+	Keyword string `json:"keyword" binding:"required"`
+	// SearchField 搜索字段: standard_question, similar_questions, answers, 为空时搜索全部
+	SearchField string `json:"search_field"`
+	// TagID 仅在该分类 (seq_id) 下筛选，0 表示不限
+	TagID int64 `json:"tag_id"`
+	// Update 对命中条目应用的字段更新
+	Update FAQEntryFieldsUpdate `json:"update"`
+	// DryRun 只预览命中条目，不执行更新
+	DryRun bool `json:"dry_run"`
+}
+
+// FAQEntryQueryUpdateResult 按搜索条件批量更新的结果
+type FAQEntryQueryUpdateResult struct {
+	DryRun bool `json:"dry_run"`
+	// Matched 命中条目总数
+	Matched int `json:"matched"`
+	// Updated 实际提交更新的条目数，DryRun 时为 0
+	Updated int `json:"updated"`
+	// Entries 命中条目预览，最多 FAQEntryQueryUpdatePreviewLimit 条
+	Entries []*FAQEntry `json:"entries"`
+}
+
 // FAQImportTaskStatus 导入任务状态
 type FAQImportTaskStatus string
 
@@ -305,8 +570,23 @@ const (
 	FAQImportStatusCompleted FAQImportTaskStatus = "completed"
 	// FAQImportStatusFailed represents the failed status of the FAQ import task
 	FAQImportStatusFailed FAQImportTaskStatus = "failed"
+	// FAQImportStatusPartial represents an FAQ import task aborted by its failure budget,
+	// the entries imported before the abort are kept
+	FAQImportStatusPartial FAQImportTaskStatus = "partial"
 )
 
+// IsFinished reports whether the FAQ import task has ended and won't be run again
+func (s FAQImportTaskStatus) IsFinished() bool {
+	return s == FAQImportStatusCompleted || s == FAQImportStatusFailed || s == FAQImportStatusPartial
+}
+
+// FAQContentHashBackfillProgress represents the progress of a content hash backfill task stored in Redis.
+// Total counts the chunks missing a content hash.
+type FAQContentHashBackfillProgress struct {
+	KnowledgeTaskProgress
+	Updated int `json:"updated"` // 已回填 hash 的分块数
+}
+
 // FAQImportProgress represents the progress of an FAQ import task stored in Redis
 // When Status is "completed", the result fields (SkippedCount, ImportMode, ImportedAt, DisplayStatus, ProcessingTime) are populated.
 type FAQImportProgress struct {
@@ -320,6 +600,8 @@ type FAQImportProgress struct {
 	SuccessCount      int                 `json:"success_count"`                 // 成功导入/验证通过的条目数
 	FailedCount       int                 `json:"failed_count"`                  // 失败的条目数
 	SkippedCount      int                 `json:"skipped_count,omitempty"`       // 跳过的条目数（如重复等）
+	SkippedEntries    []FAQSkippedEntry   `json:"skipped_entries,omitempty"`     // 跳过条目详情（最多 MaxInlineFAQSkippedEntries 条）
+	SkippedEntriesURL string              `json:"skipped_entries_url,omitempty"` // 跳过条目超出内联上限时导出的完整 CSV 下载URL
 	FailedEntries     []FAQFailedEntry    `json:"failed_entries,omitempty"`      // 失败条目详情（少量时直接返回）
 	FailedEntriesURL  string              `json:"failed_entries_url,omitempty"`  // 失败条目CSV下载URL（大量时返回URL）
 	SuccessEntries    []FAQSuccessEntry   `json:"success_entries,omitempty"`     // 成功条目简单信息（少量时直接返回）
@@ -329,6 +611,8 @@ type FAQImportProgress struct {
 	CreatedAt         int64               `json:"created_at"`                    // Task creation timestamp
 	UpdatedAt         int64               `json:"updated_at"`                    // Last update timestamp
 	DryRun            bool                `json:"dry_run,omitempty"`             // 是否为 dry run 模式
+	// UnindexedSimilarQuestions 成功导入的条目中受单条目索引上限限制、已保存但未建立索引的相似问总数
+	UnindexedSimilarQuestions int `json:"unindexed_similar_questions,omitempty"`
 
 	// Result fields (populated when Status == "completed")
 	ImportMode     string    `json:"import_mode,omitempty"`     // 导入模式：append 或 replace
@@ -337,6 +621,57 @@ type FAQImportProgress struct {
 	ProcessingTime int64     `json:"processing_time,omitempty"` // 处理耗时（毫秒）
 }
 
+// FAQImportArchive 导入任务结束后归档到数据库的完整进度，Redis 中的进度过期后仍可按 task_id 查询
+type FAQImportArchive struct {
+	TaskID          string              `json:"task_id"           gorm:"type:varchar(255);primaryKey"`
+	TenantID        uint64              `json:"tenant_id"`
+	KnowledgeBaseID string              `json:"knowledge_base_id"`
+	KnowledgeID     string              `json:"knowledge_id"`
+	Status          FAQImportTaskStatus `json:"status"`
+	// Progress 归档时的 FAQImportProgress，导出到 CSV 的失败条目也一并保存
+	Progress   JSON      `json:"progress"          gorm:"type:jsonb"`
+	ArchivedAt time.Time `json:"archived_at"`
+	// ExpiresAt 归档到期时间，到期后由周期任务清除
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// TableName returns the table name of FAQImportArchive
+func (FAQImportArchive) TableName() string {
+	return "faq_import_archives"
+}
+
+// FAQImportResultFilter 获取导入进度时对结果条目的过滤方式
+type FAQImportResultFilter string
+
+const (
+	// FAQImportResultFilterAll 返回完整进度（默认）
+	FAQImportResultFilterAll FAQImportResultFilter = "all"
+	// FAQImportResultFilterSummary 仅返回统计信息，不含条目明细
+	FAQImportResultFilterSummary FAQImportResultFilter = "summary"
+	// FAQImportResultFilterFailed 统计信息 + 分页的失败条目
+	FAQImportResultFilterFailed FAQImportResultFilter = "failed"
+	// FAQImportResultFilterSuccess 统计信息 + 分页的成功条目
+	FAQImportResultFilterSuccess FAQImportResultFilter = "success"
+)
+
+// IsValid reports whether the filter is supported
+func (f FAQImportResultFilter) IsValid() bool {
+	switch f {
+	case FAQImportResultFilterAll, FAQImportResultFilterSummary,
+		FAQImportResultFilterFailed, FAQImportResultFilterSuccess:
+		return true
+	}
+	return false
+}
+
+// FAQImportProgressView 按过滤方式裁剪后的导入进度，条目明细（失败/成功/跳过）均不内联返回，
+// failed/success 过滤时对应条目分页放在 Entries 中
+type FAQImportProgressView struct {
+	*FAQImportProgress
+	Filter  FAQImportResultFilter `json:"filter"`
+	Entries *PageResult           `json:"entries,omitempty"`
+}
+
 // FAQImportMetadata 存储在Knowledge.Metadata中的FAQ导入任务信息
 // Deprecated: Use FAQImportProgress with Redis storage instead
 type FAQImportMetadata struct {
@@ -362,6 +697,13 @@ type FAQImportResult struct {
 	// 失败详情URL（失败条目较多时提供下载链接）
 	FailedEntriesURL string `json:"failed_entries_url,omitempty"` // 失败条目CSV下载URL
 
+	// 跳过详情（跳过条目较多时提供完整下载链接）
+	SkippedEntries    []FAQSkippedEntry `json:"skipped_entries,omitempty"`     // 跳过条目详情（最多 MaxInlineFAQSkippedEntries 条）
+	SkippedEntriesURL string            `json:"skipped_entries_url,omitempty"` // 跳过条目CSV下载URL
+
+	// 索引截断：受单条目索引上限限制、已保存但未建立索引的相似问总数
+	UnindexedSimilarQuestions int `json:"unindexed_similar_questions,omitempty"`
+
 	// 显示控制
 	DisplayStatus string `json:"display_status"` // 显示状态：open 或 close
 
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 )
@@ -56,21 +57,60 @@ type ChunkRepository interface {
 	DeleteChunksByKnowledgeID(ctx context.Context, tenantID uint64, knowledgeID string) error
 	// DeleteByKnowledgeList deletes all chunks for a knowledge list
 	DeleteByKnowledgeList(ctx context.Context, tenantID uint64, knowledgeIDs []string) error
+	// DeleteChunksByKnowledgeBaseID deletes all chunks in a knowledge base
+	DeleteChunksByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) error
 	// DeleteChunksByTagID deletes all chunks with the specified tag ID
 	// Returns the IDs of deleted chunks for index cleanup
 	DeleteChunksByTagID(ctx context.Context, tenantID uint64, kbID string, tagID string, excludeIDs []string) ([]string, error)
 	// CountChunksByKnowledgeBaseID counts the number of chunks in a knowledge base.
 	CountChunksByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) (int64, error)
+	// CountChunksByKnowledgeIDs counts chunks of the given types belonging to the given knowledge items.
+	CountChunksByKnowledgeIDs(
+		ctx context.Context,
+		tenantID uint64,
+		knowledgeIDs []string,
+		chunkTypes []types.ChunkType,
+	) (int64, error)
+	// HasChunksOfType reports whether a knowledge item has at least one chunk of the given type
+	HasChunksOfType(ctx context.Context, tenantID uint64, knowledgeID string, chunkType types.ChunkType) (bool, error)
 	// DeleteUnindexedChunks deletes unindexed chunks by knowledge id and chunk index range
 	DeleteUnindexedChunks(ctx context.Context, tenantID uint64, knowledgeID string) ([]*types.Chunk, error)
 	// ListAllFAQChunksByKnowledgeID lists all FAQ chunks for a knowledge ID
 	// only ID and ContentHash fields for efficiency
 	ListAllFAQChunksByKnowledgeID(ctx context.Context, tenantID uint64, knowledgeID string) ([]*types.Chunk, error)
 	// ListAllFAQChunksWithMetadataByKnowledgeBaseID lists all FAQ chunks for a knowledge base ID
-	// returns ID and Metadata fields for duplicate question checking
+	// returns ID, TagID and Metadata fields for duplicate question checking
 	ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) ([]*types.Chunk, error)
-	// ListAllFAQChunksForExport lists all FAQ chunks for export with full metadata, tag_id, is_enabled, and flags
-	ListAllFAQChunksForExport(ctx context.Context, tenantID uint64, knowledgeID string) ([]*types.Chunk, error)
+	// GetFAQChunkByStandardQuestion finds an indexed FAQ chunk of a knowledge base by exact standard question,
+	// returning nil without error if none matches
+	GetFAQChunkByStandardQuestion(ctx context.Context,
+		tenantID uint64, kbID string, question string) (*types.Chunk, error)
+	// ListAnswerPendingFAQChunks lists a page of FAQ chunks of a knowledge imported without answers,
+	// ordered by updated_at descending
+	ListAnswerPendingFAQChunks(ctx context.Context,
+		tenantID uint64, knowledgeID string, page *types.Pagination) ([]*types.Chunk, int64, error)
+	// ListTextChunksWithLinksByKnowledgeBaseID lists text chunks of a knowledge base whose metadata contains
+	// extracted hyperlinks, returning ID, KnowledgeID, ChunkIndex and Metadata fields
+	ListTextChunksWithLinksByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) ([]*types.Chunk, error)
+	// ListGeneratedQuestionChunks lists a page of text chunks carrying generated questions, ordered by seq_id
+	// and starting after afterSeqID. knowledgeID and tagID (tag of the knowledge) are optional filters.
+	// Returns ID, SeqID, KnowledgeID, ChunkIndex, Content and Metadata fields
+	ListGeneratedQuestionChunks(ctx context.Context, tenantID uint64, kbID, knowledgeID, tagID string,
+		afterSeqID int64, limit int) ([]*types.Chunk, error)
+	// GetKnowledgeChunkStats aggregates chunk counts by type, image count and chunks with generated questions
+	// for a knowledge item without loading the chunks
+	GetKnowledgeChunkStats(ctx context.Context, tenantID uint64, knowledgeID string) (*types.KnowledgeChunkStats, error)
+	// ListFAQChunksForExport lists a page of indexed FAQ chunks for export, ordered by (created_at, id) and
+	// starting after the (afterCreatedAt, afterID) cursor; an empty afterID starts from the beginning
+	ListFAQChunksForExport(ctx context.Context, tenantID uint64, knowledgeID string,
+		afterCreatedAt time.Time, afterID string, limit int) ([]*types.Chunk, error)
+	// ListFAQChunksMissingContentHash lists FAQ chunks of a knowledge base whose content_hash is empty
+	// returns ID, Content and Metadata fields for hash computation
+	ListFAQChunksMissingContentHash(ctx context.Context, tenantID uint64, kbID string) ([]*types.Chunk, error)
+	// UpdateChunkContentHashes sets content_hash for multiple chunks (map of chunk ID to hash)
+	UpdateChunkContentHashes(ctx context.Context, tenantID uint64, hashes map[string]string) error
+	// SaveChunks saves all fields of multiple chunks in a single transaction
+	SaveChunks(ctx context.Context, chunks []*types.Chunk) error
 	// UpdateChunkFlagsBatch updates flags for multiple chunks in batch using a single SQL statement.
 	// setFlags: map of chunk ID to flags to set (OR operation)
 	// clearFlags: map of chunk ID to flags to clear (AND NOT operation)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"mime/multipart"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/hibiken/asynq"
@@ -13,6 +14,10 @@ import (
 type KnowledgeService interface {
 	// CreateKnowledgeFromFile creates knowledge from a file.
 	// tagID is optional - when provided, the file will be assigned to the specified tag/category.
+	// expiresAt is optional - when nil, the knowledge base default retention applies.
+	// chunkingPreset is optional - names a tenant chunking preset overriding the knowledge base chunking config.
+	// nameConflict is optional - how to handle a display name already used in the knowledge base
+	// (types.KnowledgeNameConflictAllow by default, Rename or Reject).
 	CreateKnowledgeFromFile(
 		ctx context.Context,
 		kbID string,
@@ -21,7 +26,33 @@ type KnowledgeService interface {
 		enableMultimodel *bool,
 		customFileName string,
 		tagID string,
+		expiresAt *time.Time,
+		chunkingPreset string,
+		nameConflict string,
 	) (*types.Knowledge, error)
+	// CreateKnowledgeFromFileWithResult is CreateKnowledgeFromFile reporting an existing duplicate file
+	// in the result instead of as a *types.DuplicateKnowledgeError; the error is left for real failures.
+	CreateKnowledgeFromFileWithResult(
+		ctx context.Context,
+		kbID string,
+		file *multipart.FileHeader,
+		metadata map[string]string,
+		enableMultimodel *bool,
+		customFileName string,
+		tagID string,
+		expiresAt *time.Time,
+		chunkingPreset string,
+		nameConflict string,
+	) (*types.KnowledgeCreateResult, error)
+	// CreateKnowledgeFromFiles creates knowledge from a batch of files sharing the same options.
+	// Duplicates are checked with one query and the storage quota against the aggregate size,
+	// the result reports per file whether it was created, a duplicate or failed.
+	CreateKnowledgeFromFiles(
+		ctx context.Context,
+		kbID string,
+		files []*multipart.FileHeader,
+		opts *types.KnowledgeFileUploadOptions,
+	) ([]*types.KnowledgeFileUploadResult, error)
 	// CreateKnowledgeFromURL creates knowledge from a URL.
 	// When fileName or fileType is provided (or the URL path has a known file extension),
 	// the URL is treated as a direct file download instead of a web page crawl.
@@ -35,7 +66,32 @@ type KnowledgeService interface {
 		enableMultimodel *bool,
 		title string,
 		tagID string,
+		expiresAt *time.Time,
+		chunkingPreset string,
 	) (*types.Knowledge, error)
+	// CreateKnowledgeFromURLWithResult is CreateKnowledgeFromURL reporting an existing duplicate URL
+	// in the result instead of as a *types.DuplicateKnowledgeError; the error is left for real failures.
+	CreateKnowledgeFromURLWithResult(
+		ctx context.Context,
+		kbID string,
+		url string,
+		fileName string,
+		fileType string,
+		enableMultimodel *bool,
+		title string,
+		tagID string,
+		expiresAt *time.Time,
+		chunkingPreset string,
+	) (*types.KnowledgeCreateResult, error)
+	// CreateKnowledgeFromCloudStorage imports objects from an s3:// or cos:// path.
+	// A path ending with "/" imports every object under the prefix.
+	CreateKnowledgeFromCloudStorage(
+		ctx context.Context,
+		kbID string,
+		req *types.CloudStorageImportRequest,
+	) (*types.CloudStorageImportResult, error)
+	// ProcessCloudStorageImport handles the task copying a cloud storage object into file storage
+	ProcessCloudStorageImport(ctx context.Context, t *asynq.Task) error
 	// CreateKnowledgeFromPassage creates knowledge from text passages.
 	CreateKnowledgeFromPassage(ctx context.Context, kbID string, passage []string) (*types.Knowledge, error)
 	// CreateKnowledgeFromPassageSync creates knowledge from text passages and waits until chunks are indexed.
@@ -48,6 +104,16 @@ type KnowledgeService interface {
 	) (*types.Knowledge, error)
 	// GetKnowledgeByID retrieves knowledge by ID (uses tenant from context).
 	GetKnowledgeByID(ctx context.Context, id string) (*types.Knowledge, error)
+	// GetChunkByOffset returns the text chunk of the knowledge covering the given rune offset of the source document.
+	GetChunkByOffset(ctx context.Context, knowledgeID string, runeOffset int) (*types.Chunk, error)
+	// ResolveCitations maps rune ranges of the source document to the IDs of the text chunks covering them.
+	ResolveCitations(ctx context.Context, knowledgeID string, spans []types.CitationSpan) ([]*types.CitationResolution, error)
+	// GetChunkWithKnowledge returns a chunk together with the metadata of the knowledge it belongs to.
+	GetChunkWithKnowledge(ctx context.Context, chunkID string) (*types.ChunkWithKnowledge, error)
+	// GetChunksWithKnowledge is the batch form of GetChunkWithKnowledge; inaccessible IDs are skipped.
+	GetChunksWithKnowledge(ctx context.Context, chunkIDs []string) ([]*types.ChunkWithKnowledge, error)
+	// GetKnowledgeDetail retrieves knowledge with derived chunk statistics for the detail page.
+	GetKnowledgeDetail(ctx context.Context, id string) (*types.KnowledgeDetail, error)
 	// GetKnowledgeByIDOnly retrieves knowledge by ID without tenant filter (for permission resolution).
 	GetKnowledgeByIDOnly(ctx context.Context, id string) (*types.Knowledge, error)
 	// GetKnowledgeBatch retrieves a batch of knowledge by IDs.
@@ -60,6 +126,9 @@ type KnowledgeService interface {
 	// When tagID is non-empty, results are filtered by tag_id.
 	// When keyword is non-empty, results are filtered by file_name.
 	// When fileType is non-empty, results are filtered by file_type or type.
+	// When extractedKeyword is non-empty, results are filtered by the keywords/entities extracted at parse time.
+	// When fileHash is non-empty, results are filtered by file checksum.
+	// File checksums are left out of the results when the owning tenant opted out of exposing them.
 	ListPagedKnowledgeByKnowledgeBaseID(
 		ctx context.Context,
 		kbID string,
@@ -67,6 +136,8 @@ type KnowledgeService interface {
 		tagID string,
 		keyword string,
 		fileType string,
+		extractedKeyword string,
+		fileHash string,
 	) (*types.PageResult, error)
 	// DeleteKnowledge deletes knowledge by ID.
 	DeleteKnowledge(ctx context.Context, id string) error
@@ -82,12 +153,52 @@ type KnowledgeService interface {
 		knowledgeID string,
 		payload *types.ManualKnowledgePayload,
 	) (*types.Knowledge, error)
+	// CheckDependencies verifies docreader, embedding and vector store connectivity.
+	CheckDependencies(ctx context.Context) (*types.DependencyCheckResult, error)
+	// EnableKnowledge enables a parsed knowledge item that was kept disabled after publishing.
+	EnableKnowledge(ctx context.Context, knowledgeID string) (*types.Knowledge, error)
+	// SetKnowledgeExpiry sets the expiration time of a knowledge item, nil clears it.
+	SetKnowledgeExpiry(ctx context.Context, knowledgeID string, expiresAt *time.Time) (*types.Knowledge, error)
+	// CancelKnowledgeProcessing aborts the parsing of a pending or processing knowledge item and
+	// removes the data created so far, keeping the knowledge record for a later reparse.
+	CancelKnowledgeProcessing(ctx context.Context, knowledgeID string) (*types.Knowledge, error)
 	// ReparseKnowledge deletes existing document content and re-parses the knowledge asynchronously.
 	ReparseKnowledge(ctx context.Context, knowledgeID string) (*types.Knowledge, error)
+	// ReparseKnowledgeWithOptions re-parses the knowledge, optionally skipping summary and question generation.
+	ReparseKnowledgeWithOptions(
+		ctx context.Context, knowledgeID string, opts types.ReparseOptions,
+	) (*types.Knowledge, error)
+	// GetKnowledgeChunkSnapshot returns the chunks saved before the last reparse, when
+	// chunk snapshots are enabled and the snapshot has not expired yet.
+	GetKnowledgeChunkSnapshot(ctx context.Context, knowledgeID string) (*types.KnowledgeChunkSnapshot, error)
+	// GetKnowledgeMarkdown returns the full markdown extracted at the last parse, when storing
+	// parsed markdown is enabled.
+	GetKnowledgeMarkdown(ctx context.Context, knowledgeID string) (*types.KnowledgeMarkdown, error)
+	// GetKnowledgeProcessingReport returns the diagnostics recorded while chunking the document at its last parse:
+	// skipped chunks and why, image handling and oversized chunks that were re-split or truncated.
+	GetKnowledgeProcessingReport(ctx context.Context, knowledgeID string) (*types.KnowledgeProcessingReport, error)
+	// ReembedKnowledge re-embeds existing chunks with a new embedding model without re-parsing the document.
+	ReembedKnowledge(ctx context.Context, knowledgeID string, newModelID string) (*types.Knowledge, error)
+	// ReindexKnowledge rebuilds the index of a knowledge item from its stored chunks with its current
+	// embedding model, without re-parsing the document.
+	ReindexKnowledge(ctx context.Context, knowledgeID string) (*types.Knowledge, error)
 	// CloneKnowledgeBase clones knowledge to another knowledge base.
 	CloneKnowledgeBase(ctx context.Context, srcID, dstID string) error
+	// ExportGeneratedQuestions streams the generated questions of a knowledge base to w as CSV or JSON.
+	ExportGeneratedQuestions(ctx context.Context, kbID string, filter *types.GeneratedQuestionExportFilter,
+		w io.Writer) error
+	// PreviewCloneKnowledgeBase reports what CloneKnowledgeBase would add and delete, without side effects.
+	PreviewCloneKnowledgeBase(ctx context.Context, srcID, dstID string) (*types.KBClonePreview, error)
 	// UpdateImageInfo updates image information for a knowledge chunk.
 	UpdateImageInfo(ctx context.Context, knowledgeID string, chunkID string, imageInfo string) error
+	// ListOrphanedImageChunks lists the image OCR/caption child chunks of a knowledge whose image is no
+	// longer on the parent chunk, or which duplicate another child of the same type for the same image.
+	ListOrphanedImageChunks(ctx context.Context, knowledgeID string) ([]*types.OrphanedImageChunk, error)
+	// ValidateChunkChain reports anomalies in the PreChunkID/NextChunkID chain of a knowledge's text
+	// chunks, and with repair set rebuilds the chain in ChunkIndex order when anomalies are found.
+	ValidateChunkChain(ctx context.Context, knowledgeID string, repair bool) (*types.ChunkChainReport, error)
+	// ListAnswerPendingFAQEntries lists the FAQ entries imported without answers, so they can be completed.
+	ListAnswerPendingFAQEntries(ctx context.Context, kbID string, page *types.Pagination) (*types.PageResult, error)
 	// ListFAQEntries lists FAQ entries under a FAQ knowledge base.
 	// When tagSeqID is non-zero, results are filtered by tag seq_id on FAQ chunks.
 	// searchField: specifies which field to search in ("standard_question", "similar_questions", "answers", "" for all)
@@ -105,28 +216,64 @@ type KnowledgeService interface {
 	// When DryRun is true, only validates entries without actually importing.
 	// Returns task ID (Knowledge ID) for tracking import progress.
 	UpsertFAQEntries(ctx context.Context, kbID string, payload *types.FAQBatchUpsertPayload) (string, error)
+	// ConvertDocumentToFAQ starts extracting Q/A pairs from a document knowledge for a FAQ knowledge base.
+	// sourceCtx carries the tenant resolved from the caller's access to the source knowledge.
+	ConvertDocumentToFAQ(
+		ctx context.Context, sourceCtx context.Context, sourceKnowledgeID string, targetFAQKbID string,
+	) (*types.FAQConversionProgress, error)
+	// GetFAQConversionProgress retrieves the progress and, once completed, the extracted entries of a FAQ conversion.
+	GetFAQConversionProgress(ctx context.Context, taskID string) (*types.FAQConversionProgress, error)
+	// CommitFAQConversion imports the reviewed entries of a completed FAQ conversion, returning the import task ID.
+	CommitFAQConversion(ctx context.Context, kbID string, taskID string,
+		entries []types.FAQEntryPayload, dryRun bool) (string, error)
 	// CreateFAQEntry creates a single FAQ entry synchronously.
 	CreateFAQEntry(ctx context.Context, kbID string, payload *types.FAQEntryPayload) (*types.FAQEntry, error)
 	// GetFAQEntry retrieves a single FAQ entry by seq_id.
 	GetFAQEntry(ctx context.Context, kbID string, entrySeqID int64) (*types.FAQEntry, error)
+	// GetFAQEntryByStandardQuestion retrieves a single FAQ entry by its standard question.
+	GetFAQEntryByStandardQuestion(ctx context.Context, kbID string, question string) (*types.FAQEntry, error)
 	// UpdateFAQEntry updates a single FAQ entry.
 	UpdateFAQEntry(ctx context.Context, kbID string, entrySeqID int64, payload *types.FAQEntryPayload) (*types.FAQEntry, error)
 	// AddSimilarQuestions adds similar questions to a FAQ entry.
-	AddSimilarQuestions(ctx context.Context, kbID string, entrySeqID int64, questions []string) (*types.FAQEntry, error)
+	// It also returns the number of questions actually added after deduplication.
+	AddSimilarQuestions(ctx context.Context, kbID string, entrySeqID int64, questions []string) (*types.FAQEntry, int, error)
+	// AddSimilarQuestionsBatch adds similar questions to multiple FAQ entries, keyed by entry seq_id.
+	AddSimilarQuestionsBatch(
+		ctx context.Context, kbID string, questionsByEntry map[int64][]string,
+	) (*types.FAQSimilarQuestionsBatchResult, error)
 	// UpdateFAQEntryFieldsBatch updates multiple fields for FAQ entries in batch.
 	// Supports updating is_enabled, is_recommended, tag_id, and other fields in a single call.
 	UpdateFAQEntryFieldsBatch(ctx context.Context, kbID string, req *types.FAQEntryFieldsBatchUpdate) error
+	// UpdateFAQEntryFieldsByQuery applies a field update to all FAQ entries matching a keyword search,
+	// or only previews the matched entries when DryRun is set.
+	UpdateFAQEntryFieldsByQuery(
+		ctx context.Context, kbID string, req *types.FAQEntryQueryUpdate,
+	) (*types.FAQEntryQueryUpdateResult, error)
 	// DeleteFAQEntries deletes FAQ entries in batch by seq_id.
 	DeleteFAQEntries(ctx context.Context, kbID string, entrySeqIDs []int64) error
 	// SearchFAQEntries searches FAQ entries using hybrid search.
-	SearchFAQEntries(ctx context.Context, kbID string, req *types.FAQSearchRequest) ([]*types.FAQEntry, error)
-	// ExportFAQEntries exports all FAQ entries for a knowledge base as CSV data.
-	ExportFAQEntries(ctx context.Context, kbID string) ([]byte, error)
+	// When one of the priority searches fails, the other's results are returned and marked partial.
+	SearchFAQEntries(ctx context.Context, kbID string, req *types.FAQSearchRequest) (*types.FAQSearchResult, error)
+	// ExportFAQEntries exports all FAQ entries for a knowledge base as CSV, JSON or XLSX data.
+	// Disabled entries are only exported when includeDisabled is true.
+	ExportFAQEntries(
+		ctx context.Context, kbID string, includeDisabled bool, format types.FAQExportFormat,
+	) ([]byte, error)
+	// ExportFAQEntriesStream writes all FAQ entries for a knowledge base to w as CSV, JSON or XLSX,
+	// paging through chunks instead of buffering the whole export.
+	ExportFAQEntriesStream(
+		ctx context.Context, kbID string, includeDisabled bool, format types.FAQExportFormat, w io.Writer,
+	) error
 	// UpdateKnowledgeTagBatch updates tag for document knowledge items in batch.
 	UpdateKnowledgeTagBatch(ctx context.Context, updates map[string]*string) error
 	// UpdateFAQEntryTagBatch updates tag for FAQ entries in batch.
 	// Key: entry seq_id, Value: tag seq_id (nil to remove tag)
 	UpdateFAQEntryTagBatch(ctx context.Context, kbID string, updates map[int64]*int64) error
+	// SetChunkTag assigns a tag to a single chunk (nil or empty tagID removes the tag).
+	SetChunkTag(ctx context.Context, chunkID string, tagID *string) error
+	// SetChunkTagBatch assigns tags to chunks in batch.
+	// Key: chunk ID, Value: tag ID (nil or empty to remove tag)
+	SetChunkTagBatch(ctx context.Context, updates map[string]*string) error
 	// GetRepository gets the knowledge repository
 	GetRepository() KnowledgeRepository
 	// ProcessDocument handles Asynq document processing tasks
@@ -137,21 +284,91 @@ type KnowledgeService interface {
 	ProcessQuestionGeneration(ctx context.Context, t *asynq.Task) error
 	// ProcessSummaryGeneration handles Asynq summary generation tasks
 	ProcessSummaryGeneration(ctx context.Context, t *asynq.Task) error
+	// ProcessKeywordExtraction handles Asynq document keyword/entity extraction tasks
+	ProcessKeywordExtraction(ctx context.Context, t *asynq.Task) error
 	// ProcessKBClone handles Asynq knowledge base clone tasks
 	ProcessKBClone(ctx context.Context, t *asynq.Task) error
 	// ProcessKnowledgeListDelete handles Asynq knowledge list delete tasks
 	ProcessKnowledgeListDelete(ctx context.Context, t *asynq.Task) error
+	// ProcessKnowledgeReindex handles Asynq knowledge base reindex tasks
+	ProcessKnowledgeReindex(ctx context.Context, t *asynq.Task) error
+	// ProcessKnowledgeReembed handles Asynq knowledge reembed tasks
+	ProcessKnowledgeReembed(ctx context.Context, t *asynq.Task) error
+	// ProcessKnowledgeGraphRebuild handles Asynq knowledge graph rebuild tasks
+	ProcessKnowledgeGraphRebuild(ctx context.Context, t *asynq.Task) error
+	// ProcessEmbeddingModelAlign handles Asynq embedding model align tasks
+	ProcessEmbeddingModelAlign(ctx context.Context, t *asynq.Task) error
+	// ProcessFAQConversion handles Asynq FAQ conversion tasks
+	ProcessFAQConversion(ctx context.Context, t *asynq.Task) error
+	// ProcessKnowledgeExpiry handles the periodic task deleting expired knowledge and sending expiry notices
+	ProcessKnowledgeExpiry(ctx context.Context, t *asynq.Task) error
+	// SetKnowledgeRefreshSchedule sets the periodic refresh interval of url/file_url knowledge, 0 disables it
+	SetKnowledgeRefreshSchedule(ctx context.Context, id string, intervalMinutes int) (*types.Knowledge, error)
+	// ProcessKnowledgeRefresh handles the periodic task refreshing url/file_url knowledge whose source changed
+	ProcessKnowledgeRefresh(ctx context.Context, t *asynq.Task) error
+	// RecomputeTenantStorage resets the tenant's storage used to the actual total size of its knowledge
+	RecomputeTenantStorage(ctx context.Context, tenantID uint64) (*types.TenantStorageReconcileResult, error)
+	// ProcessTenantStorageReconcile handles the periodic task recomputing the storage used of all tenants
+	ProcessTenantStorageReconcile(ctx context.Context, t *asynq.Task) error
+	// RegenerateMissingSummaries re-enqueues summary generation for completed knowledge
+	// whose summary failed or is missing. Returns the task progress for polling.
+	RegenerateMissingSummaries(ctx context.Context, kbID string) (*types.SummaryRegenerationProgress, error)
+	// GetSummaryRegenerationProgress retrieves the progress of a summary regeneration task
+	GetSummaryRegenerationProgress(ctx context.Context, taskID string) (*types.SummaryRegenerationProgress, error)
+	// RebuildKnowledgeGraph deletes the graph data of a knowledge base and re-extracts the graph of
+	// all parsed documents in a background task. Returns the task progress for polling.
+	RebuildKnowledgeGraph(ctx context.Context, kbID string) (*types.KnowledgeGraphRebuildProgress, error)
+	// GetKnowledgeGraphRebuildProgress retrieves the progress of a knowledge graph rebuild task
+	GetKnowledgeGraphRebuildProgress(ctx context.Context, taskID string) (*types.KnowledgeGraphRebuildProgress, error)
+	// AlignKnowledgeEmbeddingModels re-embeds the parsed knowledge whose embedding model differs from
+	// the knowledge base's current model. Returns the task progress for polling.
+	AlignKnowledgeEmbeddingModels(ctx context.Context, kbID string) (*types.EmbeddingModelAlignProgress, error)
+	// GetEmbeddingModelAlignProgress retrieves the progress of an embedding model alignment task
+	GetEmbeddingModelAlignProgress(ctx context.Context, taskID string) (*types.EmbeddingModelAlignProgress, error)
+	// ReindexKnowledgeBase rebuilds the index of every parsed knowledge in a knowledge base from its
+	// stored chunks in the background.
+	ReindexKnowledgeBase(ctx context.Context, kbID string) (*types.KnowledgeReindexProgress, error)
+	// GetKnowledgeReindexProgress retrieves the progress of a knowledge base reindex task
+	GetKnowledgeReindexProgress(ctx context.Context, taskID string) (*types.KnowledgeReindexProgress, error)
+	// GetProcessingQueueStats returns the asynq queue backlogs with per task type counts of knowledge processing tasks
+	GetProcessingQueueStats(ctx context.Context) (*types.ProcessingQueueStats, error)
+	// DeleteAllKnowledgeInKB deletes all knowledge of a knowledge base in bulk, keeping the
+	// knowledge base read-only while it runs. Returns the number of deleted knowledge entries.
+	DeleteAllKnowledgeInKB(ctx context.Context, kbID string) (int, error)
+	// GetEffectiveReadConfig resolves the ReadConfig that would be used to parse a document of the given type
+	GetEffectiveReadConfig(ctx context.Context,
+		kbID string, fileType string, overrides *types.ReadConfigOverrides) (*types.EffectiveReadConfig, error)
+	// ListKnowledgeLinks lists hyperlinks extracted from the documents of a knowledge base
+	ListKnowledgeLinks(ctx context.Context, kbID string) ([]*types.KnowledgeLink, error)
+	// BackfillChunkContentHashes computes ContentHash for FAQ chunks missing it so that
+	// Replace imports do not treat them as stale. Returns the task progress for polling.
+	BackfillChunkContentHashes(ctx context.Context, kbID string) (*types.FAQContentHashBackfillProgress, error)
+	// GetContentHashBackfillProgress retrieves the progress of a content hash backfill task
+	GetContentHashBackfillProgress(ctx context.Context, taskID string) (*types.FAQContentHashBackfillProgress, error)
 	// GetKBCloneProgress retrieves the progress of a knowledge base clone task
 	GetKBCloneProgress(ctx context.Context, taskID string) (*types.KBCloneProgress, error)
 	// SaveKBCloneProgress saves the progress of a knowledge base clone task
 	SaveKBCloneProgress(ctx context.Context, progress *types.KBCloneProgress) error
+	// ForceClearFAQImportLock releases a stuck running-import lock of a KB and returns the task ID that held it
+	ForceClearFAQImportLock(ctx context.Context, kbID string) (string, error)
 	// GetFAQImportProgress retrieves the progress of an FAQ import task
 	GetFAQImportProgress(ctx context.Context, taskID string) (*types.FAQImportProgress, error)
+	// GetFAQImportProgressView retrieves the progress of an FAQ import task with only the summary counts,
+	// or the summary plus one page of failed or successful entries
+	GetFAQImportProgressView(ctx context.Context, taskID string,
+		filter types.FAQImportResultFilter, page *types.Pagination) (*types.FAQImportProgressView, error)
+	// GetFAQImportProgressArchived retrieves the archived final progress of an FAQ import task,
+	// available after the progress in Redis has expired when knowledge_base.faq_import_archive is enabled
+	GetFAQImportProgressArchived(ctx context.Context, taskID string) (*types.FAQImportProgress, error)
 	// UpdateLastFAQImportResultDisplayStatus updates the display status of FAQ import result
 	UpdateLastFAQImportResultDisplayStatus(ctx context.Context, kbID string, displayStatus string) error
 	// SearchKnowledge searches knowledge items by keyword across the tenant.
 	// fileTypes: optional list of file extensions to filter by (e.g., ["csv", "xlsx"])
 	SearchKnowledge(ctx context.Context, keyword string, offset, limit int, fileTypes []string) ([]*types.Knowledge, bool, error)
+	// SearchKnowledgeByRelevance ranks the knowledge of a knowledge base by the retrieval score of their
+	// best matching chunk for query, supporting the same tag and file type filters as the listing.
+	SearchKnowledgeByRelevance(ctx context.Context, kbID string, query string,
+		page *types.Pagination, tagID string, fileType string) (*types.PageResult, error)
 	// SearchKnowledgeForScopes searches knowledge within the given (tenant_id, kb_id) scopes (e.g. for shared agent context).
 	SearchKnowledgeForScopes(ctx context.Context, scopes []types.KnowledgeSearchScope, keyword string, offset, limit int, fileTypes []string) ([]*types.Knowledge, bool, error)
 }
@@ -162,19 +379,26 @@ type KnowledgeRepository interface {
 	GetKnowledgeByID(ctx context.Context, tenantID uint64, id string) (*types.Knowledge, error)
 	// GetKnowledgeByIDOnly returns knowledge by ID without tenant filter (for permission resolution).
 	GetKnowledgeByIDOnly(ctx context.Context, id string) (*types.Knowledge, error)
+	// GetKnowledgeByIDsOnly returns knowledge by IDs without tenant filter (for permission resolution).
+	GetKnowledgeByIDsOnly(ctx context.Context, ids []string) ([]*types.Knowledge, error)
 	ListKnowledgeByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) ([]*types.Knowledge, error)
 	// ListPagedKnowledgeByKnowledgeBaseID lists all knowledge in a knowledge base with pagination.
 	// When tagID is non-empty, results are filtered by tag_id.
 	// When keyword is non-empty, results are filtered by file_name.
 	// When fileType is non-empty, results are filtered by file_type or type.
+	// When extractedKeyword is non-empty, results are filtered by the keywords/entities extracted at parse time.
+	// When fileHash is non-empty, results are filtered by file_hash.
 	ListPagedKnowledgeByKnowledgeBaseID(ctx context.Context,
 		tenantID uint64, kbID string, page *types.Pagination, tagID string, keyword string, fileType string,
+		extractedKeyword string, fileHash string,
 	) ([]*types.Knowledge, int64, error)
 	UpdateKnowledge(ctx context.Context, knowledge *types.Knowledge) error
 	// UpdateKnowledgeBatch updates knowledge items in batch
 	UpdateKnowledgeBatch(ctx context.Context, knowledgeList []*types.Knowledge) error
 	DeleteKnowledge(ctx context.Context, tenantID uint64, id string) error
 	DeleteKnowledgeList(ctx context.Context, tenantID uint64, ids []string) error
+	// DeleteKnowledgeByKnowledgeBaseID deletes all knowledge in a knowledge base
+	DeleteKnowledgeByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) error
 	GetKnowledgeBatch(ctx context.Context, tenantID uint64, ids []string) ([]*types.Knowledge, error)
 	// CheckKnowledgeExists checks if knowledge already exists.
 	// For file types, check by fileHash or (fileName+fileSize).
@@ -186,6 +410,9 @@ type KnowledgeRepository interface {
 		kbID string,
 		params *types.KnowledgeCheckParams,
 	) (bool, *types.Knowledge, error)
+	// ListKnowledgeByFileHashes lists the non-failed knowledge of a knowledge base matching any of the file hashes,
+	// letting batch uploads check duplicates with a single query.
+	ListKnowledgeByFileHashes(ctx context.Context, tenantID uint64, kbID string, hashes []string) ([]*types.Knowledge, error)
 	// AminusB returns the difference set of A and B.
 	AminusB(ctx context.Context, Atenant uint64, A string, Btenant uint64, B string) ([]string, error)
 	UpdateKnowledgeColumn(ctx context.Context, id string, column string, value interface{}) error
@@ -200,4 +427,21 @@ type KnowledgeRepository interface {
 	SearchKnowledgeInScopes(ctx context.Context, scopes []types.KnowledgeSearchScope, keyword string, offset, limit int, fileTypes []string) ([]*types.Knowledge, bool, error)
 	// ListIDsByTagID returns all knowledge IDs that have the specified tag ID.
 	ListIDsByTagID(ctx context.Context, tenantID uint64, kbID, tagID string) ([]string, error)
+	// ListExpiredKnowledgeIDs returns the IDs among knowledgeIDs whose expiration time is not after now.
+	ListExpiredKnowledgeIDs(ctx context.Context, knowledgeIDs []string, now time.Time) ([]string, error)
+	// ListKnowledgeExpiringBefore lists knowledge of all tenants expiring no later than before, earliest first.
+	// Knowledge being deleted is skipped; pendingNotice limits the result to knowledge without a sent notification
+	// in knowledge bases with an expiry webhook.
+	ListKnowledgeExpiringBefore(ctx context.Context, before time.Time, pendingNotice bool, limit int) ([]*types.Knowledge, error)
+	// ListKnowledgeDueForRefresh lists url/file_url knowledge of all tenants whose scheduled refresh is due, earliest first.
+	// Knowledge still being processed or deleted is skipped.
+	ListKnowledgeDueForRefresh(ctx context.Context, now time.Time, limit int) ([]*types.Knowledge, error)
+	// ListFileNamesByPrefix lists the file names in the knowledge base starting with prefix.
+	ListFileNamesByPrefix(ctx context.Context, tenantID uint64, kbID string, prefix string) ([]string, error)
+	// SaveFAQImportArchive creates or replaces the archived progress of an FAQ import task.
+	SaveFAQImportArchive(ctx context.Context, archive *types.FAQImportArchive) error
+	// GetFAQImportArchive returns the archived progress of an FAQ import task, nil when there is none.
+	GetFAQImportArchive(ctx context.Context, taskID string) (*types.FAQImportArchive, error)
+	// DeleteExpiredFAQImportArchives deletes the FAQ import archives of all tenants expired no later than before.
+	DeleteExpiredFAQImportArchives(ctx context.Context, before time.Time) (int64, error)
 }
@@ -100,6 +100,8 @@ type KBShareService interface {
 	// Permission Check
 	CheckUserKBPermission(ctx context.Context, kbID string, userID string) (types.OrgMemberRole, bool, error)
 	HasKBPermission(ctx context.Context, kbID string, userID string, requiredRole types.OrgMemberRole) (bool, error)
+	// HasKBPermissionBatch is the batched HasKBPermission, returning the result per KB ID.
+	HasKBPermissionBatch(ctx context.Context, kbIDs []string, userID string, requiredRole types.OrgMemberRole) (map[string]bool, error)
 
 	// Get source tenant for cross-tenant embedding
 	GetKBSourceTenant(ctx context.Context, kbID string) (uint64, error)
@@ -125,6 +127,8 @@ type KBShareRepository interface {
 
 	// List
 	ListByKnowledgeBase(ctx context.Context, kbID string) ([]*types.KnowledgeBaseShare, error)
+	// ListByKnowledgeBases lists share records for multiple knowledge bases (batch).
+	ListByKnowledgeBases(ctx context.Context, kbIDs []string) ([]*types.KnowledgeBaseShare, error)
 	ListByOrganization(ctx context.Context, orgID string) ([]*types.KnowledgeBaseShare, error)
 	ListByOrganizations(ctx context.Context, orgIDs []string) ([]*types.KnowledgeBaseShare, error)
 	CountByOrganizations(ctx context.Context, orgIDs []string) (map[string]int64, error)
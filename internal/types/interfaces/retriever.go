@@ -64,6 +64,13 @@ type RetrieveEngineRepository interface {
 	RetrieveEngine
 }
 
+// KnowledgeBaseIndexDeleter is optionally implemented by index repositories that can
+// delete all indices of a knowledge base in a single operation
+type KnowledgeBaseIndexDeleter interface {
+	// DeleteByKnowledgeBaseID deletes the index info of a knowledge base
+	DeleteByKnowledgeBaseID(ctx context.Context, knowledgeBaseID string, dimension int, knowledgeType string) error
+}
+
 // RetrieveEngineRegistry defines the retrieve engine registry interface
 type RetrieveEngineRegistry interface {
 	// Register registers the retrieve engine service
@@ -18,7 +18,9 @@ type KnowledgeTagService interface {
 	// DeleteTag deletes a tag.
 	// When contentOnly=true, only deletes the content under the tag but keeps the tag itself.
 	// excludeIDs: IDs of chunks to exclude from deletion (only valid when deleting chunks)
-	DeleteTag(ctx context.Context, id string, force bool, contentOnly bool, excludeIDs []string) error
+	// When reassign=true, knowledges and chunks under the tag are moved to the "未分类" tag
+	// before the tag is deleted.
+	DeleteTag(ctx context.Context, id string, force bool, contentOnly bool, reassign bool, excludeIDs []string) error
 	// FindOrCreateTagByName finds a tag by name or creates it if not exists.
 	FindOrCreateTagByName(ctx context.Context, kbID string, name string) (*types.KnowledgeTag, error)
 	// ProcessIndexDelete handles async index deletion task
@@ -62,4 +64,9 @@ type KnowledgeTagRepository interface {
 	) (map[string]types.TagReferenceCounts, error)
 	// DeleteUnusedTags deletes tags that are not referenced by any knowledge or chunk.
 	DeleteUnusedTags(ctx context.Context, tenantID uint64, kbID string) (int64, error)
+	// DeleteIfUnused deletes the tag only when nothing references it; returns whether it was deleted.
+	DeleteIfUnused(ctx context.Context, tenantID uint64, kbID string, id string) (bool, error)
+	// ReassignReferences moves knowledges and chunks from one tag to another.
+	// Returns the IDs of the reassigned chunks.
+	ReassignReferences(ctx context.Context, tenantID uint64, kbID string, fromTagID string, toTagID string) ([]string, error)
 }
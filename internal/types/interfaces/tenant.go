@@ -46,4 +46,7 @@ type TenantRepository interface {
 	DeleteTenant(ctx context.Context, id uint64) error
 	// AdjustStorageUsed adjusts the storage used for a tenant
 	AdjustStorageUsed(ctx context.Context, tenantID uint64, delta int64) error
+	// RecomputeStorageUsed resets the storage used of a tenant to the total storage size of its knowledge,
+	// returning the storage used before and after
+	RecomputeStorageUsed(ctx context.Context, tenantID uint64) (previous int64, actual int64, err error)
 }
@@ -28,6 +28,8 @@ const (
 	ParseStatusFailed = "failed"
 	// ParseStatusDeleting indicates the knowledge is being deleted (used to prevent async task conflicts)
 	ParseStatusDeleting = "deleting"
+	// ParseStatusCancelled indicates the processing was cancelled by the user, the knowledge can be re-parsed later
+	ParseStatusCancelled = "cancelled"
 )
 
 // Summary status constants for async summary generation
@@ -44,6 +46,17 @@ const (
 	SummaryStatusFailed = "failed"
 )
 
+// Name conflict policies applied when an uploaded file's display name collides
+// with an existing document of the same knowledge base
+const (
+	// KnowledgeNameConflictAllow keeps the name even if it collides (default)
+	KnowledgeNameConflictAllow = "allow"
+	// KnowledgeNameConflictRename appends a sequence suffix, e.g. "report (2).pdf"
+	KnowledgeNameConflictRename = "rename"
+	// KnowledgeNameConflictReject rejects the upload
+	KnowledgeNameConflictReject = "reject"
+)
+
 // ManualKnowledgeFormat represents the format of the manual knowledge
 const (
 	ManualKnowledgeFormatMarkdown = "markdown"
@@ -87,12 +100,16 @@ type Knowledge struct {
 	FileSize int64 `json:"file_size"`
 	// File hash of the knowledge
 	FileHash string `json:"file_hash"`
+	// Whether the owning tenant opted out of exposing FileHash, resolved when the knowledge is loaded or created
+	FileHashHidden bool `json:"-"                  gorm:"-"`
 	// File path of the knowledge
 	FilePath string `json:"file_path"`
 	// Storage size of the knowledge
 	StorageSize int64 `json:"storage_size"`
 	// Metadata of the knowledge
 	Metadata JSON `json:"metadata"           gorm:"type:json"`
+	// Tenant chunking preset overriding the knowledge base chunk size, overlap and separators when parsing
+	ChunkingPreset string `json:"chunking_preset,omitempty" gorm:"type:varchar(64)"`
 	// Last FAQ import result (for FAQ type knowledge only)
 	LastFAQImportResult JSON `json:"last_faq_import_result" gorm:"type:json"`
 	// Creation time of the knowledge
@@ -103,6 +120,24 @@ type Knowledge struct {
 	ProcessedAt *time.Time `json:"processed_at"`
 	// Error message of the knowledge
 	ErrorMessage string `json:"error_message"`
+	// Parse warning of the knowledge, e.g. only part of the document was parsed before timeout
+	ParseWarning string `json:"parse_warning"`
+	// Expiration time, expired knowledge is excluded from retrieval and removed by the retention sweeper
+	ExpiresAt *time.Time `json:"expires_at"         gorm:"index"`
+	// Time the pre-expiry notification was sent
+	ExpiryNotifiedAt *time.Time `json:"expiry_notified_at"`
+	// Refresh interval (minutes) of url/file_url knowledge, the source is re-fetched and reparsed when changed; 0 disables it
+	RefreshIntervalMinutes int `json:"refresh_interval_minutes" gorm:"default:0"`
+	// Next scheduled refresh time
+	NextRefreshAt *time.Time `json:"next_refresh_at"    gorm:"index"`
+	// ETag of the source seen at the last refresh, sent as If-None-Match
+	SourceETag string `json:"-"                  gorm:"column:source_etag"`
+	// Last-Modified of the source seen at the last refresh, sent as If-Modified-Since
+	SourceLastModified string `json:"-"`
+	// Storage path of the full parsed markdown, only kept when knowledge_base.store_parsed_markdown is enabled
+	ParsedMarkdownPath string `json:"-"`
+	// Diagnostics recorded while chunking the document at the last parse, see KnowledgeProcessingReport
+	ProcessingReport JSON `json:"-"                  gorm:"type:json"`
 	// Deletion time of the knowledge
 	DeletedAt gorm.DeletedAt `json:"deleted_at"         gorm:"index"`
 	// Knowledge base name (not stored in database, populated on query)
@@ -117,11 +152,110 @@ func (k *Knowledge) GetMetadata() map[string]string {
 		return nil
 	}
 	for k, v := range metadataMap {
+		if k == KnowledgeMetadataParseProfileKey {
+			continue
+		}
 		metadata[k] = fmt.Sprintf("%v", v)
 	}
 	return metadata
 }
 
+// ExtractedKeywords returns the keywords and entities extracted from the document at parse time
+func (k *Knowledge) ExtractedKeywords() (keywords []string, entities []string) {
+	if len(k.Metadata) == 0 {
+		return nil, nil
+	}
+	var holder struct {
+		Keywords []string `json:"extracted_keywords"`
+		Entities []string `json:"extracted_entities"`
+	}
+	if err := json.Unmarshal(k.Metadata, &holder); err != nil {
+		return nil, nil
+	}
+	return holder.Keywords, holder.Entities
+}
+
+// SetExtractedKeywords stores the extracted keywords and entities in the knowledge metadata,
+// keeping the other metadata keys. Empty lists remove the stored ones.
+func (k *Knowledge) SetExtractedKeywords(keywords []string, entities []string) error {
+	metadataMap, err := k.Metadata.Map()
+	if err != nil {
+		return fmt.Errorf("knowledge metadata is not an object: %w", err)
+	}
+	if metadataMap == nil {
+		metadataMap = make(map[string]interface{})
+	}
+	for key, values := range map[string][]string{
+		KnowledgeMetadataKeywordsKey: keywords,
+		KnowledgeMetadataEntitiesKey: entities,
+	} {
+		if len(values) == 0 {
+			delete(metadataMap, key)
+		} else {
+			metadataMap[key] = values
+		}
+	}
+	if len(metadataMap) == 0 {
+		k.Metadata = nil
+		return nil
+	}
+	data, err := json.Marshal(metadataMap)
+	if err != nil {
+		return err
+	}
+	k.Metadata = JSON(data)
+	return nil
+}
+
+// ParseProfile returns the parsing overrides remembered for the document, or nil if none are stored
+func (k *Knowledge) ParseProfile() *KnowledgeParseProfile {
+	if len(k.Metadata) == 0 || k.IsManual() {
+		return nil
+	}
+	var holder struct {
+		Profile *KnowledgeParseProfile `json:"parse_profile"`
+	}
+	if err := json.Unmarshal(k.Metadata, &holder); err != nil {
+		return nil
+	}
+	return holder.Profile
+}
+
+// SetParseProfile stores the parsing overrides in the knowledge metadata, keeping the other metadata keys.
+// An empty profile removes the stored one.
+func (k *Knowledge) SetParseProfile(profile *KnowledgeParseProfile) error {
+	metadataMap, err := k.Metadata.Map()
+	if err != nil {
+		return fmt.Errorf("knowledge metadata is not an object: %w", err)
+	}
+	if metadataMap == nil {
+		metadataMap = make(map[string]interface{})
+	}
+	if profile.IsEmpty() {
+		if _, ok := metadataMap[KnowledgeMetadataParseProfileKey]; !ok {
+			return nil
+		}
+		delete(metadataMap, KnowledgeMetadataParseProfileKey)
+	} else {
+		metadataMap[KnowledgeMetadataParseProfileKey] = profile
+	}
+	if len(metadataMap) == 0 {
+		k.Metadata = nil
+		return nil
+	}
+	data, err := json.Marshal(metadataMap)
+	if err != nil {
+		return err
+	}
+	k.Metadata = JSON(data)
+	return nil
+}
+
+// IsExpired reports whether the knowledge has passed its retention period at the given time.
+func (k *Knowledge) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
+
 // BeforeCreate hook generates a UUID for new Knowledge entities before they are created.
 func (k *Knowledge) BeforeCreate(tx *gorm.DB) (err error) {
 	if k.ID == "" {
@@ -130,6 +264,50 @@ func (k *Knowledge) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// AfterFind hook resolves whether the file hash of loaded knowledge may be exposed.
+func (k *Knowledge) AfterFind(tx *gorm.DB) (err error) {
+	k.resolveFileHashHidden(tx)
+	return nil
+}
+
+// AfterCreate hook resolves whether the file hash of created knowledge may be exposed.
+func (k *Knowledge) AfterCreate(tx *gorm.DB) (err error) {
+	k.resolveFileHashHidden(tx)
+	return nil
+}
+
+// resolveFileHashHidden loads the privacy config of the owning tenant once per statement.
+// The file hash is hidden when the tenant cannot be loaded.
+func (k *Knowledge) resolveFileHashHidden(tx *gorm.DB) {
+	if k.FileHash == "" {
+		return
+	}
+	key := fmt.Sprintf("knowledge:file_hash_hidden:%d", k.TenantID)
+	if hidden, ok := tx.Statement.Settings.Load(key); ok {
+		k.FileHashHidden = hidden.(bool)
+		return
+	}
+	var tenant Tenant
+	hidden := true
+	if err := tx.Session(&gorm.Session{NewDB: true}).
+		Select("id", "privacy_config").Where("id = ?", k.TenantID).Take(&tenant).Error; err == nil {
+		hidden = tenant.PrivacyConfig.HidesFileHash()
+	}
+	tx.Statement.Settings.Store(key, hidden)
+	k.FileHashHidden = hidden
+}
+
+// MarshalJSON leaves the file hash out of the response when the owning tenant opted out of exposing it.
+// FileHash itself is kept, so filtering and duplicate detection by checksum keep working.
+func (k Knowledge) MarshalJSON() ([]byte, error) {
+	type knowledgeJSON Knowledge
+	out := knowledgeJSON(k)
+	if out.FileHashHidden {
+		out.FileHash = ""
+	}
+	return json.Marshal(out)
+}
+
 // ManualKnowledgeMetadata stores metadata for manual Markdown knowledge content.
 type ManualKnowledgeMetadata struct {
 	Content   string `json:"content"`
@@ -137,6 +315,13 @@ type ManualKnowledgeMetadata struct {
 	Status    string `json:"status"`
 	Version   int    `json:"version"`
 	UpdatedAt string `json:"updated_at"`
+	// EnableAfterPublish 发布（索引）完成后是否自动启用，nil 表示默认启用
+	EnableAfterPublish *bool `json:"enable_after_publish,omitempty"`
+}
+
+// ShouldEnableAfterPublish reports whether the knowledge should be enabled once indexing completes.
+func (m *ManualKnowledgeMetadata) ShouldEnableAfterPublish() bool {
+	return m == nil || m.EnableAfterPublish == nil || *m.EnableAfterPublish
 }
 
 // ManualKnowledgePayload represents the payload for manual knowledge operations.
@@ -145,6 +330,10 @@ type ManualKnowledgePayload struct {
 	Content string `json:"content"`
 	Status  string `json:"status"`
 	TagID   string `json:"tag_id"`
+	// EnableAfterPublish 发布后是否自动启用；为 false 时仅建立索引，保持禁用直到单独调用启用接口
+	EnableAfterPublish *bool `json:"enable_after_publish,omitempty"`
+	// ExpiresAt 过期时间，仅创建时生效，为空时使用知识库默认保留期
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 // KnowledgeSearchScope defines a (tenant_id, knowledge_base_id) scope for knowledge search (e.g. own KBs + shared KBs).
@@ -249,6 +438,32 @@ func (k *Knowledge) GetLastFAQImportResult() (*FAQImportResult, error) {
 	return &result, nil
 }
 
+// SetProcessingReport stores the processing report in the dedicated field.
+func (k *Knowledge) SetProcessingReport(report *KnowledgeProcessingReport) error {
+	if report == nil {
+		k.ProcessingReport = nil
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	k.ProcessingReport = JSON(data)
+	return nil
+}
+
+// GetProcessingReport parses the processing report from the dedicated field, nil when none was recorded.
+func (k *Knowledge) GetProcessingReport() (*KnowledgeProcessingReport, error) {
+	if len(k.ProcessingReport) == 0 {
+		return nil, nil
+	}
+	var report KnowledgeProcessingReport
+	if err := json.Unmarshal(k.ProcessingReport, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
 // IsManual returns true if the knowledge item is manual Markdown knowledge.
 func (k *Knowledge) IsManual() bool {
 	return k != nil && k.Type == KnowledgeTypeManual
@@ -275,6 +490,230 @@ func (p ManualKnowledgePayload) IsDraft() bool {
 	return p.Status == "" || p.Status == ManualKnowledgeStatusDraft
 }
 
+// ReparseOptions controls which LLM-backed steps are re-run when re-parsing a knowledge item.
+// The zero value keeps the full regeneration behavior.
+type ReparseOptions struct {
+	// SkipSummary 跳过摘要生成，保留已有摘要
+	SkipSummary bool `json:"skip_summary"`
+	// SkipQuestionGeneration 跳过问题生成，内容未变化的分块沿用已生成的问题
+	SkipQuestionGeneration bool `json:"skip_question_generation"`
+	// ParseOverrides 本次解析参数覆盖，与文档已记住的解析参数合并后保存，之后的重新解析自动沿用
+	ParseOverrides *KnowledgeParseProfile `json:"parse_overrides,omitempty"`
+	// ResetParseProfile 清除文档记住的解析参数，恢复使用知识库配置（先于 ParseOverrides 生效）
+	ResetParseProfile bool `json:"reset_parse_profile"`
+	// ParseOverridesOnce 解析参数覆盖仅对本次解析生效，不写入文档记住的解析参数
+	ParseOverridesOnce bool `json:"parse_overrides_once"`
+}
+
+// KnowledgeMetadataParseProfileKey is the metadata key holding the KnowledgeParseProfile of a document
+const KnowledgeMetadataParseProfileKey = "parse_profile"
+
+const (
+	// KnowledgeMetadataKeywordsKey is the metadata key holding the keywords extracted from a document
+	KnowledgeMetadataKeywordsKey = "extracted_keywords"
+	// KnowledgeMetadataEntitiesKey is the metadata key holding the entities extracted from a document
+	KnowledgeMetadataEntitiesKey = "extracted_entities"
+)
+
+// KnowledgeParseProfile holds the per-document parsing overrides used at the last reparse. It is kept
+// in the knowledge metadata so following reparses (manual or scheduled refresh) reuse the same tuning.
+// Unset fields fall back to the knowledge base configuration.
+type KnowledgeParseProfile struct {
+	ChunkSize        *int     `json:"chunk_size,omitempty"`
+	ChunkOverlap     *int     `json:"chunk_overlap,omitempty"`
+	Separators       []string `json:"separators,omitempty"`
+	EnableMultimodel *bool    `json:"enable_multimodel,omitempty"`
+}
+
+// IsEmpty reports whether the profile overrides nothing
+func (p *KnowledgeParseProfile) IsEmpty() bool {
+	return p == nil || (p.ChunkSize == nil && p.ChunkOverlap == nil && len(p.Separators) == 0 && p.EnableMultimodel == nil)
+}
+
+// Merge returns a copy of p with the fields set in overrides replacing the remembered ones
+func (p *KnowledgeParseProfile) Merge(overrides *KnowledgeParseProfile) *KnowledgeParseProfile {
+	merged := &KnowledgeParseProfile{}
+	if p != nil {
+		*merged = *p
+	}
+	if overrides == nil {
+		return merged
+	}
+	if overrides.ChunkSize != nil {
+		merged.ChunkSize = overrides.ChunkSize
+	}
+	if overrides.ChunkOverlap != nil {
+		merged.ChunkOverlap = overrides.ChunkOverlap
+	}
+	if len(overrides.Separators) > 0 {
+		merged.Separators = overrides.Separators
+	}
+	if overrides.EnableMultimodel != nil {
+		merged.EnableMultimodel = overrides.EnableMultimodel
+	}
+	return merged
+}
+
+// Validate checks the chunking parameters of the profile against the base chunking configuration
+func (p *KnowledgeParseProfile) Validate(base ChunkingConfig) error {
+	if p == nil {
+		return nil
+	}
+	cfg := base
+	p.ApplyChunking(&cfg)
+	if p.ChunkSize != nil && cfg.ChunkSize <= 0 {
+		return fmt.Errorf("chunk_size must be positive")
+	}
+	// Overriding either side can break the ordering, e.g. a smaller chunk_size below the KB overlap
+	if (p.ChunkSize != nil || p.ChunkOverlap != nil) &&
+		(cfg.ChunkOverlap < 0 || (cfg.ChunkSize > 0 && cfg.ChunkOverlap >= cfg.ChunkSize)) {
+		return fmt.Errorf("chunk_overlap must be between 0 and chunk_size")
+	}
+	return nil
+}
+
+// ApplyChunking overrides the chunk size, overlap and separators of cfg with the values set in the profile
+func (p *KnowledgeParseProfile) ApplyChunking(cfg *ChunkingConfig) {
+	if p == nil {
+		return
+	}
+	if p.ChunkSize != nil {
+		cfg.ChunkSize = *p.ChunkSize
+	}
+	if p.ChunkOverlap != nil {
+		cfg.ChunkOverlap = *p.ChunkOverlap
+	}
+	if len(p.Separators) > 0 {
+		cfg.Separators = p.Separators
+	}
+}
+
+// ResolveEnableMultimodel returns the multimodal override of the profile, or kbDefault when unset
+func (p *KnowledgeParseProfile) ResolveEnableMultimodel(kbDefault bool) bool {
+	if p == nil || p.EnableMultimodel == nil {
+		return kbDefault
+	}
+	return *p.EnableMultimodel
+}
+
+// KnowledgeChunkSnapshot is the copy of a knowledge's chunks taken right before a reparse
+// wiped them, kept for a short window so old and new chunking can be compared.
+type KnowledgeChunkSnapshot struct {
+	KnowledgeID    string               `json:"knowledge_id"`
+	ParseStatus    string               `json:"parse_status"`
+	ChunkingConfig ChunkingConfig       `json:"chunking_config"`
+	Chunks         []KnowledgeChunkItem `json:"chunks"`
+	CreatedAt      time.Time            `json:"created_at"`
+	ExpiresAt      time.Time            `json:"expires_at"`
+}
+
+// KnowledgeChunkItem is a single chunk in a KnowledgeChunkSnapshot: content and boundaries only.
+type KnowledgeChunkItem struct {
+	ChunkIndex    int       `json:"chunk_index"`
+	ChunkType     ChunkType `json:"chunk_type"`
+	ParentChunkID string    `json:"parent_chunk_id,omitempty"`
+	StartAt       int       `json:"start_at"`
+	EndAt         int       `json:"end_at"`
+	Content       string    `json:"content"`
+}
+
+// KnowledgeMarkdown is the full markdown docreader extracted from a knowledge's document.
+type KnowledgeMarkdown struct {
+	KnowledgeID string `json:"knowledge_id"`
+	Content     string `json:"content"`
+}
+
+// CloudStorageImportRequest describes a knowledge import from a user-owned object storage bucket.
+// Path is s3://bucket/key or cos://bucket-appid/key; a path ending with "/" imports every object under that prefix.
+type CloudStorageImportRequest struct {
+	Path string `json:"path" binding:"required"`
+	// Credentials 访问凭证，必填；不会复用平台或知识库的存储配置
+	Credentials      *CloudStorageCredentials `json:"credentials,omitempty"`
+	EnableMultimodel *bool                    `json:"enable_multimodel"`
+	TagID            string                   `json:"tag_id"`
+	// ExpiresAt 导入知识的过期时间，为空时使用知识库默认保留期
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CloudStorageCredentials holds the credentials used to read a bucket during import.
+// They travel encrypted in the import task payload and are never persisted with the knowledge.
+type CloudStorageCredentials struct {
+	SecretID  string `json:"secret_id"`
+	SecretKey string `json:"secret_key"`
+	Region    string `json:"region"`
+	// Endpoint S3 兼容服务地址（如 minio.example.com:9000），仅 s3:// 路径使用
+	Endpoint string `json:"endpoint"`
+	// UseSSL 访问 Endpoint 时是否使用 HTTPS，默认 true
+	UseSSL *bool `json:"use_ssl"`
+}
+
+// CloudStorageImportFailure records an object that could not be imported
+type CloudStorageImportFailure struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// CloudStorageImportResult is the outcome of a cloud storage import
+type CloudStorageImportResult struct {
+	Imported []*Knowledge `json:"imported"`
+	// Duplicated 已存在相同文件的对象，值为已有知识 ID
+	Duplicated map[string]string            `json:"duplicated"`
+	Failed     []*CloudStorageImportFailure `json:"failed"`
+}
+
+// KnowledgeCreateResult is the outcome of creating knowledge from a file or URL. A duplicate is not an error:
+// WasDuplicate is set and DuplicateOf holds the existing knowledge, which is also returned as Knowledge.
+type KnowledgeCreateResult struct {
+	Knowledge    *Knowledge `json:"knowledge"`
+	WasDuplicate bool       `json:"was_duplicate"`
+	DuplicateOf  *Knowledge `json:"duplicate_of,omitempty"`
+}
+
+// NewDuplicateKnowledgeResult returns the create result reporting knowledge as an existing duplicate
+func NewDuplicateKnowledgeResult(knowledge *Knowledge) *KnowledgeCreateResult {
+	return &KnowledgeCreateResult{Knowledge: knowledge, WasDuplicate: true, DuplicateOf: knowledge}
+}
+
+// KnowledgeFileUploadOptions holds the creation options shared by the files of an upload
+type KnowledgeFileUploadOptions struct {
+	Metadata         map[string]string
+	EnableMultimodel *bool
+	TagID            string
+	ExpiresAt        *time.Time
+	ChunkingPreset   string
+	NameConflict     string
+}
+
+// 批量上传中单个文件的处理结果
+const (
+	KnowledgeFileUploadStatusCreated   = "created"
+	KnowledgeFileUploadStatusDuplicate = "duplicate"
+	KnowledgeFileUploadStatusError     = "error"
+)
+
+// KnowledgeFileUploadResult is the outcome of one file of a batch upload
+type KnowledgeFileUploadResult struct {
+	FileName string `json:"file_name"`
+	// Status created、duplicate 或 error
+	Status string `json:"status"`
+	// Knowledge 新建的知识，重复时为已有知识
+	Knowledge *Knowledge `json:"knowledge,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// KnowledgeExpiryEventExpiring is the webhook event sent before a knowledge item expires
+const KnowledgeExpiryEventExpiring = "knowledge.expiring"
+
+// KnowledgeExpiryNotice is the payload posted to a knowledge base's expiry webhook
+type KnowledgeExpiryNotice struct {
+	Event           string    `json:"event"`
+	TenantID        uint64    `json:"tenant_id"`
+	KnowledgeBaseID string    `json:"knowledge_base_id"`
+	KnowledgeID     string    `json:"knowledge_id"`
+	Title           string    `json:"title"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
 // KnowledgeCheckParams defines parameters used to check if knowledge already exists.
 type KnowledgeCheckParams struct {
 	// File parameters
@@ -288,3 +727,200 @@ type KnowledgeCheckParams struct {
 	// Knowledge type
 	Type string
 }
+
+// KnowledgeTaskStatus represents the status of a background task processing the knowledge of a knowledge base
+type KnowledgeTaskStatus string
+
+const (
+	KnowledgeTaskStatusProcessing KnowledgeTaskStatus = "processing"
+	KnowledgeTaskStatusCompleted  KnowledgeTaskStatus = "completed"
+	KnowledgeTaskStatusFailed     KnowledgeTaskStatus = "failed"
+)
+
+// KnowledgeTaskProgress holds the progress shared by background tasks processing the knowledge of a
+// knowledge base. TenantID and KBID record the owner, the progress is only visible to callers with
+// access to that knowledge base.
+type KnowledgeTaskProgress struct {
+	TaskID    string              `json:"task_id"`
+	TenantID  uint64              `json:"tenant_id"`
+	KBID      string              `json:"kb_id"`
+	Status    KnowledgeTaskStatus `json:"status"`
+	Progress  int                 `json:"progress"`   // 0-100
+	Total     int                 `json:"total"`      // 待处理数
+	Processed int                 `json:"processed"`  // 已处理数
+	Failed    int                 `json:"failed"`     // 处理失败数
+	Message   string              `json:"message"`    // 状态消息
+	CreatedAt int64               `json:"created_at"` // 任务创建时间
+	UpdatedAt int64               `json:"updated_at"` // 最后更新时间
+}
+
+// SummaryRegenerationProgress represents the progress of a bulk summary regeneration task.
+// Total, Processed and Failed count the knowledge whose summary is missing.
+type SummaryRegenerationProgress struct {
+	KnowledgeTaskProgress
+	Enqueued int `json:"enqueued"` // 已提交摘要任务数
+	Skipped  int `json:"skipped"`  // 无文本分块而跳过的数量
+}
+
+// KnowledgeGraphRebuildProgress represents the progress of a knowledge base wide graph rebuild task.
+// Total, Processed and Failed count the text chunks whose graph is extracted.
+type KnowledgeGraphRebuildProgress struct {
+	KnowledgeTaskProgress
+	Knowledge int `json:"knowledge"` // 参与重建的知识数
+}
+
+// EmbeddingModelAlignProgress represents the progress of re-embedding the knowledge whose embedding
+// model differs from the knowledge base's current model. Total counts the misaligned knowledge.
+type EmbeddingModelAlignProgress struct {
+	KnowledgeTaskProgress
+	EmbeddingModelID string            `json:"embedding_model_id"`         // 知识库当前的嵌入模型
+	Reembedded       int               `json:"reembedded"`                 // 已重新向量化数
+	FailedKnowledge  map[string]string `json:"failed_knowledge,omitempty"` // 失败的知识ID及原因
+}
+
+// KnowledgeReindexProgress represents the progress of rebuilding the index of a knowledge base
+// from the chunks stored in the database
+type KnowledgeReindexProgress struct {
+	KnowledgeTaskProgress
+	Reindexed       int               `json:"reindexed"`                  // 已重建索引数
+	FailedKnowledge map[string]string `json:"failed_knowledge,omitempty"` // 失败的知识ID及原因
+}
+
+// KnowledgeRelevanceHit is a knowledge ranked by how relevant its content is to a query
+type KnowledgeRelevanceHit struct {
+	Knowledge *Knowledge `json:"knowledge"`
+	// Score 知识中得分最高的分块的检索得分
+	Score float64 `json:"score"`
+	// MatchedChunkID / Snippet 得分最高的分块及其内容片段
+	MatchedChunkID string `json:"matched_chunk_id"`
+	Snippet        string `json:"snippet"`
+	// MatchedChunks 知识中被检索命中的分块数
+	MatchedChunks int `json:"matched_chunks"`
+}
+
+// DependencyStatus describes the reachability of one knowledge pipeline dependency
+type DependencyStatus struct {
+	Name      string `json:"name"`            // docreader / embedding / vector_store
+	Healthy   bool   `json:"healthy"`         // 是否可用
+	LatencyMs int64  `json:"latency_ms"`      // 检查耗时（毫秒）
+	Error     string `json:"error,omitempty"` // 失败原因
+}
+
+// DependencyCheckResult aggregates the status of all knowledge pipeline dependencies
+type DependencyCheckResult struct {
+	Healthy      bool               `json:"healthy"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// KnowledgeChunkStats holds chunk statistics of a knowledge item computed by aggregate queries
+type KnowledgeChunkStats struct {
+	TotalChunks      int64            `json:"total_chunks"`
+	ChunkCountByType map[string]int64 `json:"chunk_count_by_type"`
+	// ImageCount 图片数量，按图片 OCR/描述子分块去重统计
+	ImageCount int64 `json:"image_count"`
+	// QuestionChunkCount 含自动生成问题的文本分块数
+	QuestionChunkCount int64 `json:"question_chunk_count"`
+}
+
+// KnowledgeDetail is a knowledge item enriched with derived statistics for the document detail page
+type KnowledgeDetail struct {
+	*Knowledge
+	Stats *KnowledgeChunkStats `json:"stats"`
+	// ParseDurationMs 解析耗时（ProcessedAt - CreatedAt），未完成解析时为 0
+	ParseDurationMs int64 `json:"parse_duration_ms"`
+	// QuestionStatus 问题生成状态：none 表示没有分块生成了问题，completed 表示已有分块生成问题
+	QuestionStatus string `json:"question_status"`
+}
+
+// ProcessingSkipReason explains why a chunk or image was skipped or not indexed during document processing
+type ProcessingSkipReason string
+
+const (
+	// ProcessingSkipEmptyContent 分块内容为空白，未保存
+	ProcessingSkipEmptyContent ProcessingSkipReason = "empty_content"
+	// ProcessingSkipBelowMinLength 文本分块短于 min_index_content_length，仅保存用于展示，不写入索引
+	ProcessingSkipBelowMinLength ProcessingSkipReason = "below_min_length"
+	// ProcessingSkipExcludedByConfig 图片 OCR/描述分块按图片处理配置不写入索引
+	ProcessingSkipExcludedByConfig ProcessingSkipReason = "excluded_by_config"
+	// ProcessingSkipIndexFailed 分块写入索引失败（isolate_index_failures 开启时）
+	ProcessingSkipIndexFailed ProcessingSkipReason = "index_failed"
+	// ProcessingSkipMaxImagesExceeded 文档图片总数超过上限，全部图片按纯文本处理
+	ProcessingSkipMaxImagesExceeded ProcessingSkipReason = "max_images_exceeded"
+	// ProcessingSkipEmptyParentChunk 图片所在分块内容为空，随分块一起被跳过
+	ProcessingSkipEmptyParentChunk ProcessingSkipReason = "empty_parent_chunk"
+	// ProcessingSkipNoImageText 图片没有 OCR 文本和描述，未生成子分块
+	ProcessingSkipNoImageText ProcessingSkipReason = "no_ocr_or_caption"
+)
+
+// MaxProcessingReportItems caps the per-chunk items kept in a processing report; counts are always complete
+const MaxProcessingReportItems = 500
+
+// ChunkDiagnostic describes a single chunk noted in a processing report
+type ChunkDiagnostic struct {
+	// ChunkIndex 分块序号，被跳过的空分块为解析服务返回的 seq
+	ChunkIndex int       `json:"chunk_index"`
+	ChunkID    string    `json:"chunk_id,omitempty"`
+	ChunkType  ChunkType `json:"chunk_type"`
+	// Length 分块内容字符数
+	Length int                  `json:"length"`
+	Reason ProcessingSkipReason `json:"reason,omitempty"`
+	// IndexParts 超长分块写入索引的片段数，截断时为 1
+	IndexParts int `json:"index_parts,omitempty"`
+}
+
+// KnowledgeProcessingReport holds the structured diagnostics recorded while chunking and indexing a document,
+// explaining why chunks were skipped, how images were handled and which chunks were re-split or truncated.
+type KnowledgeProcessingReport struct {
+	KnowledgeID string    `json:"knowledge_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// ParsedChunks 解析服务返回的分块数
+	ParsedChunks int `json:"parsed_chunks"`
+	// CreatedChunks 实际保存的分块数（含图片 OCR/描述子分块）
+	CreatedChunks int `json:"created_chunks"`
+	// IndexedChunks 内容写入检索索引的分块数
+	IndexedChunks int `json:"indexed_chunks"`
+	// TotalImages 解析服务返回的图片数
+	TotalImages int `json:"total_images"`
+	// ProcessedImages 生成了 OCR 或描述子分块的图片数
+	ProcessedImages int `json:"processed_images"`
+	// ImageSkipReasons 未生成子分块的图片数，按原因统计
+	ImageSkipReasons map[ProcessingSkipReason]int `json:"image_skip_reasons,omitempty"`
+	// ChunkSkipReasons 被跳过或未写入索引的分块数，按原因统计
+	ChunkSkipReasons map[ProcessingSkipReason]int `json:"chunk_skip_reasons,omitempty"`
+	// SkippedChunks 被跳过或未写入索引的分块明细，最多 MaxProcessingReportItems 条
+	SkippedChunks []ChunkDiagnostic `json:"skipped_chunks"`
+	// OversizedChunkCount 超过索引长度上限被拆分或截断的分块数
+	OversizedChunkCount int `json:"oversized_chunk_count"`
+	// OversizedChunks 超长分块明细，最多 MaxProcessingReportItems 条
+	OversizedChunks []ChunkDiagnostic `json:"oversized_chunks"`
+}
+
+// AddSkippedChunk records a chunk that was skipped or kept out of the index
+func (r *KnowledgeProcessingReport) AddSkippedChunk(d ChunkDiagnostic) {
+	if r.ChunkSkipReasons == nil {
+		r.ChunkSkipReasons = make(map[ProcessingSkipReason]int)
+	}
+	r.ChunkSkipReasons[d.Reason]++
+	if len(r.SkippedChunks) < MaxProcessingReportItems {
+		r.SkippedChunks = append(r.SkippedChunks, d)
+	}
+}
+
+// AddOversizedChunk records a chunk that exceeded the index length limit
+func (r *KnowledgeProcessingReport) AddOversizedChunk(d ChunkDiagnostic) {
+	r.OversizedChunkCount++
+	if len(r.OversizedChunks) < MaxProcessingReportItems {
+		r.OversizedChunks = append(r.OversizedChunks, d)
+	}
+}
+
+// SkipImages records count images that produced no OCR or caption chunks
+func (r *KnowledgeProcessingReport) SkipImages(reason ProcessingSkipReason, count int) {
+	if count <= 0 {
+		return
+	}
+	if r.ImageSkipReasons == nil {
+		r.ImageSkipReasons = make(map[ProcessingSkipReason]int)
+	}
+	r.ImageSkipReasons[reason] += count
+}
@@ -3,7 +3,9 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 )
@@ -33,6 +35,29 @@ const (
 	FAQQuestionIndexModeCombined FAQQuestionIndexMode = "combined"
 	// FAQQuestionIndexModeSeparate index questions and similar questions separately
 	FAQQuestionIndexModeSeparate FAQQuestionIndexMode = "separate"
+	// FAQQuestionIndexModeHybrid index the standard question on its own and all similar questions
+	// together in one combined entry
+	FAQQuestionIndexModeHybrid FAQQuestionIndexMode = "hybrid"
+)
+
+// FAQAnswerOrder controls how FAQ answers and question lists are ordered when stored
+type FAQAnswerOrder string
+
+const (
+	// FAQAnswerOrderPreserve keeps the order provided by the user, only removing blanks and exact duplicates (default)
+	FAQAnswerOrderPreserve FAQAnswerOrder = "preserve"
+	// FAQAnswerOrderSorted sorts answers and questions before storing, making entries easier to compare and dedup
+	FAQAnswerOrderSorted FAQAnswerOrder = "sorted"
+)
+
+// FAQDuplicateScope controls the scope in which FAQ standard and similar questions must be unique
+type FAQDuplicateScope string
+
+const (
+	// FAQDuplicateScopeKnowledgeBase requires questions to be unique across the whole knowledge base (default)
+	FAQDuplicateScopeKnowledgeBase FAQDuplicateScope = "kb"
+	// FAQDuplicateScopeTag only requires questions to be unique within the same tag
+	FAQDuplicateScopeTag FAQDuplicateScope = "tag"
 )
 
 // KnowledgeBase represents a knowledge base entity
@@ -67,6 +92,14 @@ type KnowledgeBase struct {
 	FAQConfig *FAQConfig `yaml:"faq_config"              json:"faq_config"              gorm:"column:faq_config;type:json"`
 	// QuestionGenerationConfig stores question generation configuration for document knowledge bases
 	QuestionGenerationConfig *QuestionGenerationConfig `yaml:"question_generation_config" json:"question_generation_config" gorm:"column:question_generation_config;type:json"`
+	// RetrievalConfig stores retrieval tuning options such as per-chunk-type score weights
+	RetrievalConfig *RetrievalConfig `yaml:"retrieval_config"        json:"retrieval_config"        gorm:"column:retrieval_config;type:json"`
+	// RetentionConfig stores the default retention period and pre-expiry notification settings
+	RetentionConfig *RetentionConfig `yaml:"retention_config"        json:"retention_config"        gorm:"column:retention_config;type:json"`
+	// KeywordExtractionConfig stores the document keyword/entity extraction options
+	KeywordExtractionConfig *KeywordExtractionConfig `yaml:"keyword_extraction_config" json:"keyword_extraction_config" gorm:"column:keyword_extraction_config;type:json"`
+	// ImportConfig stores the knowledge import options, e.g. the max file size of URL imports
+	ImportConfig *ImportConfig `yaml:"import_config"           json:"import_config"           gorm:"column:import_config;type:json"`
 	// Creation time of the knowledge base
 	CreatedAt time.Time `yaml:"created_at"              json:"created_at"`
 	// Last updated time of the knowledge base
@@ -83,6 +116,8 @@ type KnowledgeBase struct {
 	ProcessingCount int64 `yaml:"processing_count"        json:"processing_count"        gorm:"-"`
 	// ShareCount indicates the number of organizations this knowledge base is shared with (not stored in database)
 	ShareCount int64 `yaml:"share_count"             json:"share_count"             gorm:"-"`
+	// ChunkingPreset names a tenant chunking preset used as defaults for unset ChunkingConfig fields (request only, not stored)
+	ChunkingPreset string `yaml:"chunking_preset"         json:"chunking_preset,omitempty" gorm:"-"`
 }
 
 // KnowledgeBaseConfig represents the knowledge base configuration
@@ -93,6 +128,14 @@ type KnowledgeBaseConfig struct {
 	ImageProcessingConfig ImageProcessingConfig `yaml:"image_processing_config" json:"image_processing_config"`
 	// FAQ configuration (only for FAQ type knowledge bases)
 	FAQConfig *FAQConfig `yaml:"faq_config"              json:"faq_config"`
+	// Retrieval configuration
+	RetrievalConfig *RetrievalConfig `yaml:"retrieval_config"        json:"retrieval_config"`
+	// Retention configuration
+	RetentionConfig *RetentionConfig `yaml:"retention_config"        json:"retention_config"`
+	// Keyword extraction configuration
+	KeywordExtractionConfig *KeywordExtractionConfig `yaml:"keyword_extraction_config" json:"keyword_extraction_config"`
+	// Import configuration
+	ImportConfig *ImportConfig `yaml:"import_config"           json:"import_config"`
 }
 
 // ChunkingConfig represents the document splitting configuration
@@ -105,6 +148,167 @@ type ChunkingConfig struct {
 	Separators []string `yaml:"separators"    json:"separators"`
 	// EnableMultimodal (deprecated, kept for backward compatibility with old data)
 	EnableMultimodal bool `yaml:"enable_multimodal,omitempty" json:"enable_multimodal,omitempty"`
+	// ParseTimeoutSeconds docreader 解析超时时间（秒），每次重试翻倍，最长 MaxParseTimeoutSeconds；0 表示不限制
+	ParseTimeoutSeconds int `yaml:"parse_timeout_seconds,omitempty" json:"parse_timeout_seconds,omitempty"`
+	// AcceptPartialResult 解析超时时接受 docreader 返回的部分结果（部分分块的图片未解析），文档带警告完成；
+	// 默认关闭，超时即解析失败
+	AcceptPartialResult bool `yaml:"accept_partial_result,omitempty" json:"accept_partial_result,omitempty"`
+	// IndexLinkAnchors 是否为分块中提取的超链接单独建立索引（锚文本 + URL），便于按链接检索文档
+	IndexLinkAnchors bool `yaml:"index_link_anchors,omitempty" json:"index_link_anchors,omitempty"`
+	// MinIndexContentLength 文本分块去除首尾空白后的最少字符数，低于该值的分块仅保存（用于展示和上下文拼接）不写入检索索引，0 表示不限制
+	MinIndexContentLength int `yaml:"min_index_content_length,omitempty" json:"min_index_content_length,omitempty"`
+	// MaxIndexTokens 单个分块写入检索索引的最大 token 数（按字符数近似估算），超过时按 OversizedChunkStrategy 处理，
+	// 0 表示使用默认值 ChunkSize 的 DefaultOversizedChunkFactor 倍
+	MaxIndexTokens int `yaml:"max_index_tokens,omitempty" json:"max_index_tokens,omitempty"`
+	// OversizedChunkStrategy 超长分块（如 docreader 无法拆分的大代码块）的索引方式，默认 split；分块本身始终保存原文
+	OversizedChunkStrategy OversizedChunkStrategy `yaml:"oversized_chunk_strategy,omitempty" json:"oversized_chunk_strategy,omitempty"`
+	// IndexContentTemplate 文本分块写入检索索引的内容模板，支持 {{title}}、{{section}}、{{content}} 占位符，
+	// 用于为短分块补充文档标题或章节上下文；分块本身保存的内容不变。为空表示仅索引分块内容
+	IndexContentTemplate string `yaml:"index_content_template,omitempty" json:"index_content_template,omitempty"`
+	// IsolateIndexFailures 批量写入检索索引失败时逐步拆小批次重试，仅跳过持续失败的分块（保存但不索引），
+	// 文档带警告完成；默认关闭，任一批次失败则整篇文档失败
+	IsolateIndexFailures bool `yaml:"isolate_index_failures,omitempty" json:"isolate_index_failures,omitempty"`
+	// RequireIndexableContent 严格模式：文档没有任何分块写入检索索引（内容为空、全部低于最小长度或索引失败）时
+	// 标记为解析失败；默认关闭，此时文档以空内容完成
+	RequireIndexableContent bool `yaml:"require_indexable_content,omitempty" json:"require_indexable_content,omitempty"`
+}
+
+// Placeholders supported by ChunkingConfig.IndexContentTemplate
+const (
+	IndexTemplateTitle   = "{{title}}"
+	IndexTemplateSection = "{{section}}"
+	IndexTemplateContent = "{{content}}"
+)
+
+// MaxIndexContentTemplateLength 索引内容模板的最大字符数
+const MaxIndexContentTemplateLength = 500
+
+// UsesIndexSection reports whether the index content template references the section heading
+func (c ChunkingConfig) UsesIndexSection() bool {
+	return strings.Contains(c.IndexContentTemplate, IndexTemplateSection)
+}
+
+// RenderIndexContent applies IndexContentTemplate to a piece of chunk content.
+// An empty template returns content unchanged.
+func (c ChunkingConfig) RenderIndexContent(title, section, content string) string {
+	if c.IndexContentTemplate == "" {
+		return content
+	}
+	return strings.NewReplacer(
+		IndexTemplateTitle, title,
+		IndexTemplateSection, section,
+		IndexTemplateContent, content,
+	).Replace(c.IndexContentTemplate)
+}
+
+// OversizedChunkStrategy 定义超长分块写入检索索引时的处理方式
+type OversizedChunkStrategy string
+
+const (
+	// OversizedChunkStrategySplit 按 ChunkSize 拆分为多个索引条目（默认）
+	OversizedChunkStrategySplit OversizedChunkStrategy = "split"
+	// OversizedChunkStrategyTruncate 截断到 MaxIndexTokens 后索引，并在知识上记录解析警告
+	OversizedChunkStrategyTruncate OversizedChunkStrategy = "truncate"
+)
+
+const (
+	// DefaultOversizedChunkFactor 未配置 MaxIndexTokens 时，超长分块阈值相对 ChunkSize 的倍数
+	DefaultOversizedChunkFactor = 4
+	// DefaultMaxIndexTokens 未配置 MaxIndexTokens 且 ChunkSize 为 0 时的超长分块阈值
+	DefaultMaxIndexTokens = 4096
+	// MaxParseTimeoutSeconds 解析超时时间的上限，重试翻倍后也不超过该值
+	MaxParseTimeoutSeconds = 3600
+)
+
+// GetMaxIndexTokens returns the token limit above which a chunk is considered oversized.
+func (c ChunkingConfig) GetMaxIndexTokens() int {
+	if c.MaxIndexTokens > 0 {
+		return c.MaxIndexTokens
+	}
+	if c.ChunkSize > 0 {
+		return c.ChunkSize * DefaultOversizedChunkFactor
+	}
+	return DefaultMaxIndexTokens
+}
+
+// IndexContents returns the contents to write to the retrieval index for a chunk.
+// Chunks within GetMaxIndexTokens are indexed as-is; oversized chunks are split into
+// ChunkSize pieces (preferring line breaks) or truncated according to OversizedChunkStrategy.
+// Tokens are approximated by runes, which over-estimates English text and keeps the pieces safe.
+func (c ChunkingConfig) IndexContents(content string) (parts []string, oversized bool) {
+	maxTokens := c.GetMaxIndexTokens()
+	runes := []rune(content)
+	if len(runes) <= maxTokens {
+		return []string{content}, false
+	}
+	if c.OversizedChunkStrategy == OversizedChunkStrategyTruncate {
+		return []string{string(runes[:maxTokens])}, true
+	}
+
+	pieceSize := maxTokens
+	if c.ChunkSize > 0 && c.ChunkSize < pieceSize {
+		pieceSize = c.ChunkSize
+	}
+	for start := 0; start < len(runes); {
+		end := min(start+pieceSize, len(runes))
+		if end < len(runes) {
+			// Cut after the last line break in the second half of the window, if any
+			for i := end - 1; i > start+pieceSize/2; i-- {
+				if runes[i] == '\n' {
+					end = i + 1
+					break
+				}
+			}
+		}
+		if piece := string(runes[start:end]); strings.TrimSpace(piece) != "" {
+			parts = append(parts, piece)
+		}
+		start = end
+	}
+	return parts, true
+}
+
+// ShouldIndexChunk reports whether a chunk should be written to the retrieval index.
+// Text chunks shorter than MinIndexContentLength (page numbers, stray characters) are kept but not indexed.
+func (c ChunkingConfig) ShouldIndexChunk(chunkType ChunkType, content string) bool {
+	if chunkType != ChunkTypeText || c.MinIndexContentLength <= 0 {
+		return true
+	}
+	return utf8.RuneCountInString(strings.TrimSpace(content)) >= c.MinIndexContentLength
+}
+
+// ReadConfigOverrides 导入文档时可覆盖知识库默认解析配置的参数
+type ReadConfigOverrides struct {
+	// EnableMultimodel 覆盖知识库的多模态开关，为空时使用知识库配置
+	EnableMultimodel *bool `json:"enable_multimodel,omitempty"`
+}
+
+// EffectiveVLMConfig 解析时实际使用的 VLM 配置（不含 API Key）
+type EffectiveVLMConfig struct {
+	ModelName     string `json:"model_name"`
+	BaseURL       string `json:"base_url"`
+	InterfaceType string `json:"interface_type"`
+}
+
+// EffectiveReadConfig 描述文档解析时将下发给 docreader 的 ReadConfig，用于排查分块结果
+type EffectiveReadConfig struct {
+	KnowledgeBaseID string   `json:"knowledge_base_id"`
+	FileType        string   `json:"file_type"`
+	ChunkSize       int      `json:"chunk_size"`
+	ChunkOverlap    int      `json:"chunk_overlap"`
+	Separators      []string `json:"separators"`
+	// ParseTimeoutSeconds 首次解析的超时时间（秒），重试时翻倍，0 表示不限制
+	ParseTimeoutSeconds int `json:"parse_timeout_seconds"`
+	// AllowPartialResult 超时时 docreader 是否返回部分结果
+	AllowPartialResult bool `json:"allow_partial_result"`
+	// EnableMultimodal 是否会执行多模态处理（知识库配置与覆盖参数合并后下发给 docreader 的开关）
+	EnableMultimodal bool `json:"enable_multimodal"`
+	// ImageCaptionEnabled 是否会调用 VLM 生成图片描述，多模态开启但缺少 VLM 配置时只做 OCR
+	ImageCaptionEnabled bool                `json:"image_caption_enabled"`
+	StorageProvider     string              `json:"storage_provider"`
+	VLMConfig           *EffectiveVLMConfig `json:"vlm_config,omitempty"`
+	// Warnings 配置不一致导致的提示，例如开启多模态但缺少 VLM 配置
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // COSConfig represents the COS configuration
@@ -144,6 +348,39 @@ func (c *StorageConfig) Scan(value interface{}) error {
 type ImageProcessingConfig struct {
 	// Model ID
 	ModelID string `yaml:"model_id" json:"model_id"`
+	// ExcludeOCRFromIndex 不将图片 OCR 分块写入检索索引（分块仍会保存用于展示）
+	ExcludeOCRFromIndex bool `yaml:"exclude_ocr_from_index" json:"exclude_ocr_from_index"`
+	// ExcludeCaptionFromIndex 不将图片描述分块写入检索索引（分块仍会保存用于展示）
+	ExcludeCaptionFromIndex bool `yaml:"exclude_caption_from_index" json:"exclude_caption_from_index"`
+	// MaxImagesPerDocument 单个文档允许处理的图片总数，超过时整篇文档按纯文本处理（跳过全部图片并记录解析警告），
+	// 0 表示使用默认值 DefaultMaxImagesPerDocument，负数表示不限制
+	MaxImagesPerDocument int `yaml:"max_images_per_document" json:"max_images_per_document,omitempty"`
+}
+
+// DefaultMaxImagesPerDocument is the default total number of images processed per document
+const DefaultMaxImagesPerDocument = 1000
+
+// GetMaxImagesPerDocument returns the total image limit per document, 0 means unlimited
+func (c ImageProcessingConfig) GetMaxImagesPerDocument() int {
+	if c.MaxImagesPerDocument < 0 {
+		return 0
+	}
+	if c.MaxImagesPerDocument == 0 {
+		return DefaultMaxImagesPerDocument
+	}
+	return c.MaxImagesPerDocument
+}
+
+// ShouldIndexChunk reports whether a chunk of the given type should be written to the retrieval index.
+// Image OCR and caption chunks can be excluded independently; all other types are always indexed.
+func (c ImageProcessingConfig) ShouldIndexChunk(chunkType ChunkType) bool {
+	switch chunkType {
+	case ChunkTypeImageOCR:
+		return !c.ExcludeOCRFromIndex
+	case ChunkTypeImageCaption:
+		return !c.ExcludeCaptionFromIndex
+	}
+	return true
 }
 
 // Value implements the driver.Valuer interface, used to convert ChunkingConfig to database value
@@ -218,6 +455,37 @@ type QuestionGenerationConfig struct {
 	Enabled bool `yaml:"enabled"  json:"enabled"`
 	// Number of questions to generate per chunk (default: 3, max: 10)
 	QuestionCount int `yaml:"question_count" json:"question_count"`
+	// Concurrency 同时调用模型生成问题的分块数（默认 1，最大 8）
+	Concurrency int `yaml:"concurrency" json:"concurrency,omitempty"`
+	// IndexWindowSize 每处理多少个分块写入一次索引（默认 20，最大 200）
+	IndexWindowSize int `yaml:"index_window_size" json:"index_window_size,omitempty"`
+}
+
+const (
+	// DefaultQuestionGenerationConcurrency is the default number of chunks generating questions in parallel
+	DefaultQuestionGenerationConcurrency = 1
+	// MaxQuestionGenerationConcurrency caps parallel question generation to protect the chat model
+	MaxQuestionGenerationConcurrency = 8
+	// DefaultQuestionIndexWindowSize is the default number of chunks whose questions are indexed together
+	DefaultQuestionIndexWindowSize = 20
+	// MaxQuestionIndexWindowSize bounds the memory held by a single index window
+	MaxQuestionIndexWindowSize = 200
+)
+
+// GetConcurrency returns the number of chunks generating questions in parallel
+func (c *QuestionGenerationConfig) GetConcurrency() int {
+	if c == nil || c.Concurrency <= 0 {
+		return DefaultQuestionGenerationConcurrency
+	}
+	return min(c.Concurrency, MaxQuestionGenerationConcurrency)
+}
+
+// GetIndexWindowSize returns the number of chunks whose generated questions are indexed together
+func (c *QuestionGenerationConfig) GetIndexWindowSize() int {
+	if c == nil || c.IndexWindowSize <= 0 {
+		return DefaultQuestionIndexWindowSize
+	}
+	return min(c.IndexWindowSize, MaxQuestionIndexWindowSize)
 }
 
 // Value implements the driver.Valuer interface
@@ -280,10 +548,288 @@ func (e *ExtractConfig) Scan(value interface{}) error {
 	return json.Unmarshal(b, e)
 }
 
+// RetrievalWeightKeyQuestion is the chunk type weight key for hits on generated questions
+const RetrievalWeightKeyQuestion = "question"
+
+// RetrievalConfig 存储知识库的检索调优配置
+type RetrievalConfig struct {
+	// ChunkTypeWeights 按分块类型设置的得分倍数，如 {"summary": 1.2, "text": 1.0, "question": 0.8}
+	// 未配置的类型默认为 1.0
+	ChunkTypeWeights map[string]float64 `yaml:"chunk_type_weights" json:"chunk_type_weights,omitempty"`
+	// OverlapDedupThreshold 相邻分块重叠去重阈值，取值 (0, 1]
+	// 检索结果中两个相邻分块（PreChunkID/NextChunkID）的重叠比例达到该值时只保留得分较高的一个，0 表示关闭
+	OverlapDedupThreshold float64 `yaml:"overlap_dedup_threshold" json:"overlap_dedup_threshold,omitempty"`
+	// RetrieveTopK 每个检索器召回的候选数，合并去重后再截断到请求的返回数量
+	// 0 表示使用返回数量的 DefaultRetrieveTopKMultiplier 倍，小于该值时不生效
+	RetrieveTopK int `yaml:"retrieve_top_k" json:"retrieve_top_k,omitempty"`
+}
+
+// ChunkTypeWeight returns the score multiplier for the given weight key, defaulting to 1.0
+func (c *RetrievalConfig) ChunkTypeWeight(key string) float64 {
+	if c == nil || len(c.ChunkTypeWeights) == 0 {
+		return 1.0
+	}
+	if w, ok := c.ChunkTypeWeights[key]; ok && w > 0 {
+		return w
+	}
+	return 1.0
+}
+
+// GetRetrieveTopK returns the configured per-retriever candidate count, 0 when unset
+func (c *RetrievalConfig) GetRetrieveTopK() int {
+	if c == nil {
+		return 0
+	}
+	return c.RetrieveTopK
+}
+
+// OverlapDedupEnabled reports whether adjacent overlapping chunks should be collapsed in retrieval results
+func (c *RetrievalConfig) OverlapDedupEnabled() bool {
+	return c != nil && c.OverlapDedupThreshold > 0
+}
+
+// Value implements driver.Valuer
+func (c RetrievalConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *RetrievalConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// Retention notification window defaults and bounds
+const (
+	DefaultExpiryNotifyBeforeHours = 24
+	MaxExpiryNotifyBeforeHours     = 168
+)
+
+// RetentionConfig 存储知识库的知识保留期配置
+type RetentionConfig struct {
+	// DefaultRetentionDays 新建知识的默认保留天数，到期后自动删除，0 表示永久保留
+	DefaultRetentionDays int `yaml:"default_retention_days" json:"default_retention_days,omitempty"`
+	// ExpiryWebhookURL 知识到期前的通知地址，为空时不发送通知
+	ExpiryWebhookURL string `yaml:"expiry_webhook_url" json:"expiry_webhook_url,omitempty"`
+	// NotifyBeforeHours 到期前多少小时发送通知，默认 24，最大 168
+	NotifyBeforeHours int `yaml:"notify_before_hours" json:"notify_before_hours,omitempty"`
+}
+
+// DefaultExpiresAt returns the expiration time for knowledge created at from, or nil when retention is unlimited
+func (c *RetentionConfig) DefaultExpiresAt(from time.Time) *time.Time {
+	if c == nil || c.DefaultRetentionDays <= 0 {
+		return nil
+	}
+	expiresAt := from.AddDate(0, 0, c.DefaultRetentionDays)
+	return &expiresAt
+}
+
+// GetNotifyBeforeHours returns the pre-expiry notification window in hours
+func (c *RetentionConfig) GetNotifyBeforeHours() int {
+	if c == nil || c.NotifyBeforeHours <= 0 {
+		return DefaultExpiryNotifyBeforeHours
+	}
+	return min(c.NotifyBeforeHours, MaxExpiryNotifyBeforeHours)
+}
+
+// Value implements driver.Valuer
+func (c RetentionConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *RetentionConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
 // FAQConfig 存储 FAQ 知识库的特有配置
 type FAQConfig struct {
 	IndexMode         FAQIndexMode         `yaml:"index_mode"          json:"index_mode"`
 	QuestionIndexMode FAQQuestionIndexMode `yaml:"question_index_mode" json:"question_index_mode"`
+	// AnswerOrder 答案与问题列表的存储顺序，默认保留用户提供的顺序
+	AnswerOrder FAQAnswerOrder `yaml:"answer_order" json:"answer_order,omitempty"`
+	// ImportMaxBatchFailures 导入时单批允许记录为失败并跳过的条目数，超过后终止导入，<=0 时使用默认值
+	ImportMaxBatchFailures int `yaml:"import_max_batch_failures" json:"import_max_batch_failures,omitempty"`
+	// ImportFailureRateThreshold 导入失败率阈值（0-1），累计失败率超过后提前终止导入，<=0 时使用默认值
+	ImportFailureRateThreshold float64 `yaml:"import_failure_rate_threshold" json:"import_failure_rate_threshold,omitempty"`
+	// MaxSimilarQuestionsPerCall 单次添加相似问接口允许提交的相似问数量，<=0 时使用默认值
+	MaxSimilarQuestionsPerCall int `yaml:"max_similar_questions_per_call" json:"max_similar_questions_per_call,omitempty"`
+	// MaxSimilarQuestionsPerEntry 通过添加相似问接口追加后单个条目允许的相似问总数，<=0 时使用默认值
+	MaxSimilarQuestionsPerEntry int `yaml:"max_similar_questions_per_entry" json:"max_similar_questions_per_entry,omitempty"`
+	// AnswerContentFormat 未单独设置内容格式的条目使用的默认答案格式，默认 plain
+	AnswerContentFormat AnswerContentFormat `yaml:"answer_content_format" json:"answer_content_format,omitempty"`
+	// DuplicateScope 问题查重范围：kb 表示整个知识库内唯一（默认），tag 表示仅在同一标签内唯一
+	DuplicateScope FAQDuplicateScope `yaml:"duplicate_scope" json:"duplicate_scope,omitempty"`
+	// MaxIndexedSimilarQuestions 分别索引模式下单个条目最多建立索引的相似问数量（去重后取前 N 个），
+	// 超出的相似问仍保存在条目中但不参与检索，<=0 表示全部索引
+	MaxIndexedSimilarQuestions int `yaml:"max_indexed_similar_questions" json:"max_indexed_similar_questions,omitempty"`
+	// MaxDisplayedAnswers 检索结果按答案策略解析展示答案时单个条目最多返回的答案数（按存储顺序取前 N 个），
+	// 只影响返回内容，不限制条目可保存的答案数，<=0 表示不限制
+	MaxDisplayedAnswers int `yaml:"max_displayed_answers" json:"max_displayed_answers,omitempty"`
+	// MaxStandardQuestionLength 标准问最大字符数，<=0 时使用默认值
+	MaxStandardQuestionLength int `yaml:"max_standard_question_length" json:"max_standard_question_length,omitempty"`
+	// LongQuestionStrategy 标准问超长时的处理方式：reject 拒绝（默认），split 按句拆分为标准问与相似问
+	LongQuestionStrategy FAQLongQuestionStrategy `yaml:"long_question_strategy" json:"long_question_strategy,omitempty"`
+	// NoMatchFallback 检索无命中时的兜底方式：none 返回空结果（默认），flag 在结果中标记 no_match，
+	// entry 在标记 no_match 的同时返回 FallbackEntryID 指定的兜底条目
+	NoMatchFallback FAQNoMatchFallback `yaml:"no_match_fallback" json:"no_match_fallback,omitempty"`
+	// FallbackEntryID NoMatchFallback 为 entry 时返回的兜底条目 ID (seq_id)
+	FallbackEntryID int64 `yaml:"fallback_entry_id" json:"fallback_entry_id,omitempty"`
+}
+
+// FAQNoMatchFallback FAQ 检索没有条目超过阈值时的兜底方式
+type FAQNoMatchFallback string
+
+const (
+	// FAQNoMatchFallbackNone 返回空结果，由调用方自行处理
+	FAQNoMatchFallbackNone FAQNoMatchFallback = "none"
+	// FAQNoMatchFallbackFlag 在结果中标记 no_match
+	FAQNoMatchFallbackFlag FAQNoMatchFallback = "flag"
+	// FAQNoMatchFallbackEntry 标记 no_match 并返回指定的兜底条目
+	FAQNoMatchFallbackEntry FAQNoMatchFallback = "entry"
+)
+
+// FAQLongQuestionStrategy 标准问超过长度上限时的处理方式
+type FAQLongQuestionStrategy string
+
+const (
+	// FAQLongQuestionStrategyReject 拒绝超长标准问
+	FAQLongQuestionStrategyReject FAQLongQuestionStrategy = "reject"
+	// FAQLongQuestionStrategySplit 按句拆分，第一句作为标准问，其余作为相似问
+	FAQLongQuestionStrategySplit FAQLongQuestionStrategy = "split"
+)
+
+const (
+	// DefaultFAQImportMaxBatchFailures is the default number of failed entries tolerated per import batch
+	DefaultFAQImportMaxBatchFailures = 10
+	// DefaultFAQImportFailureRateThreshold is the default failure rate at which an import is aborted
+	DefaultFAQImportFailureRateThreshold = 0.2
+	// DefaultFAQMaxSimilarQuestionsPerCall is the default number of similar questions accepted per add call
+	DefaultFAQMaxSimilarQuestionsPerCall = 100
+	// DefaultFAQMaxSimilarQuestionsPerEntry is the default total of similar questions an entry may reach via add calls
+	DefaultFAQMaxSimilarQuestionsPerEntry = 500
+	// DefaultFAQMaxStandardQuestionLength is the default maximum length of a standard question in characters
+	DefaultFAQMaxStandardQuestionLength = 1000
+)
+
+// GetAnswerOrder returns the configured answer order, defaulting to FAQAnswerOrderPreserve
+func (f *FAQConfig) GetAnswerOrder() FAQAnswerOrder {
+	if f == nil || f.AnswerOrder != FAQAnswerOrderSorted {
+		return FAQAnswerOrderPreserve
+	}
+	return FAQAnswerOrderSorted
+}
+
+// GetImportMaxBatchFailures returns the number of failed entries tolerated per import batch
+func (f *FAQConfig) GetImportMaxBatchFailures() int {
+	if f == nil || f.ImportMaxBatchFailures <= 0 {
+		return DefaultFAQImportMaxBatchFailures
+	}
+	return f.ImportMaxBatchFailures
+}
+
+// GetImportFailureRateThreshold returns the failure rate above which an import is aborted
+func (f *FAQConfig) GetImportFailureRateThreshold() float64 {
+	if f == nil || f.ImportFailureRateThreshold <= 0 || f.ImportFailureRateThreshold > 1 {
+		return DefaultFAQImportFailureRateThreshold
+	}
+	return f.ImportFailureRateThreshold
+}
+
+// GetMaxSimilarQuestionsPerCall returns the number of similar questions accepted per add call
+func (f *FAQConfig) GetMaxSimilarQuestionsPerCall() int {
+	if f == nil || f.MaxSimilarQuestionsPerCall <= 0 {
+		return DefaultFAQMaxSimilarQuestionsPerCall
+	}
+	return f.MaxSimilarQuestionsPerCall
+}
+
+// GetMaxSimilarQuestionsPerEntry returns the total number of similar questions an entry may reach via add calls
+func (f *FAQConfig) GetMaxSimilarQuestionsPerEntry() int {
+	if f == nil || f.MaxSimilarQuestionsPerEntry <= 0 {
+		return DefaultFAQMaxSimilarQuestionsPerEntry
+	}
+	return f.MaxSimilarQuestionsPerEntry
+}
+
+// GetMaxIndexedSimilarQuestions returns the number of similar questions indexed per entry in separate mode, 0 means all
+func (f *FAQConfig) GetMaxIndexedSimilarQuestions() int {
+	if f == nil || f.MaxIndexedSimilarQuestions <= 0 {
+		return 0
+	}
+	return f.MaxIndexedSimilarQuestions
+}
+
+// GetMaxDisplayedAnswers returns the number of answers shown per entry when resolving answers, 0 means all
+func (f *FAQConfig) GetMaxDisplayedAnswers() int {
+	if f == nil || f.MaxDisplayedAnswers <= 0 {
+		return 0
+	}
+	return f.MaxDisplayedAnswers
+}
+
+// GetMaxStandardQuestionLength returns the maximum length of a standard question in characters
+func (f *FAQConfig) GetMaxStandardQuestionLength() int {
+	if f == nil || f.MaxStandardQuestionLength <= 0 {
+		return DefaultFAQMaxStandardQuestionLength
+	}
+	return f.MaxStandardQuestionLength
+}
+
+// GetLongQuestionStrategy returns how overly long standard questions are handled, defaulting to reject
+func (f *FAQConfig) GetLongQuestionStrategy() FAQLongQuestionStrategy {
+	if f == nil || f.LongQuestionStrategy != FAQLongQuestionStrategySplit {
+		return FAQLongQuestionStrategyReject
+	}
+	return FAQLongQuestionStrategySplit
+}
+
+// GetNoMatchFallback returns how searches without any match are reported, defaulting to FAQNoMatchFallbackNone.
+// The entry fallback degrades to a flag when no fallback entry is configured.
+func (f *FAQConfig) GetNoMatchFallback() FAQNoMatchFallback {
+	if f == nil {
+		return FAQNoMatchFallbackNone
+	}
+	switch f.NoMatchFallback {
+	case FAQNoMatchFallbackFlag:
+		return FAQNoMatchFallbackFlag
+	case FAQNoMatchFallbackEntry:
+		if f.FallbackEntryID <= 0 {
+			return FAQNoMatchFallbackFlag
+		}
+		return FAQNoMatchFallbackEntry
+	default:
+		return FAQNoMatchFallbackNone
+	}
+}
+
+// GetDuplicateScope returns the question duplicate detection scope, defaulting to FAQDuplicateScopeKnowledgeBase
+func (f *FAQConfig) GetDuplicateScope() FAQDuplicateScope {
+	if f == nil || f.DuplicateScope != FAQDuplicateScopeTag {
+		return FAQDuplicateScopeKnowledgeBase
+	}
+	return FAQDuplicateScopeTag
+}
+
+// GetAnswerContentFormat returns the default answer content format, defaulting to plain
+func (f *FAQConfig) GetAnswerContentFormat() AnswerContentFormat {
+	if f == nil || !f.AnswerContentFormat.IsValid() {
+		return AnswerContentFormatPlain
+	}
+	return f.AnswerContentFormat
 }
 
 // Value implements driver.Valuer
@@ -347,3 +893,86 @@ func (kb *KnowledgeBase) IsMultimodalEnabled() bool {
 	}
 	return false
 }
+
+// KeywordExtractionConfig 文档关键词/实体提取配置
+// 开启后文档解析完成时使用摘要模型提取关键词与实体，存入知识 metadata，可在知识列表中按关键词筛选
+type KeywordExtractionConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// MaxKeywords 每篇文档最多保留的关键词数与实体数（默认 10，最大 30）
+	MaxKeywords int `yaml:"max_keywords" json:"max_keywords,omitempty"`
+}
+
+const (
+	// DefaultMaxExtractedKeywords is the default number of keywords (and entities) kept per document
+	DefaultMaxExtractedKeywords = 10
+	// MaxExtractedKeywords caps the keywords (and entities) kept per document
+	MaxExtractedKeywords = 30
+)
+
+// IsEnabled reports whether keyword extraction is turned on
+func (c *KeywordExtractionConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// GetMaxKeywords returns the number of keywords (and entities) kept per document
+func (c *KeywordExtractionConfig) GetMaxKeywords() int {
+	if c == nil || c.MaxKeywords <= 0 {
+		return DefaultMaxExtractedKeywords
+	}
+	return min(c.MaxKeywords, MaxExtractedKeywords)
+}
+
+// Value implements driver.Valuer
+func (c KeywordExtractionConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *KeywordExtractionConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// ImportConfig 知识导入配置
+type ImportConfig struct {
+	// MaxURLFileSize file_url 导入时允许下载的最大文件字节数，未设置时为 10MB，最大 200MB
+	MaxURLFileSize int64 `yaml:"max_url_file_size" json:"max_url_file_size,omitempty"`
+}
+
+const (
+	// DefaultMaxURLFileSize is the max file size of URL imports when the knowledge base does not set one
+	DefaultMaxURLFileSize int64 = 10 * 1024 * 1024
+	// MaxURLFileSizeCeiling caps the configurable max file size of URL imports, as downloads are held in memory
+	MaxURLFileSizeCeiling int64 = 200 * 1024 * 1024
+)
+
+// GetMaxURLFileSize returns the max file size of URL imports in bytes
+func (c *ImportConfig) GetMaxURLFileSize() int64 {
+	if c == nil || c.MaxURLFileSize <= 0 {
+		return DefaultMaxURLFileSize
+	}
+	return min(c.MaxURLFileSize, MaxURLFileSizeCeiling)
+}
+
+// Value implements driver.Valuer
+func (c ImportConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *ImportConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
@@ -52,6 +52,8 @@ const (
 type EmbeddingParameters struct {
 	Dimension            int `yaml:"dimension"              json:"dimension"`
 	TruncatePromptTokens int `yaml:"truncate_prompt_tokens" json:"truncate_prompt_tokens"`
+	// BatchSize 单次请求嵌入服务的最大文本数，超过时拆分为多次请求；0 表示使用 BATCH_EMBED_SIZE
+	BatchSize int `yaml:"batch_size" json:"batch_size,omitempty"`
 }
 
 type ModelParameters struct {
@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 // RetrieverEngineType represents the type of retriever engine
 type RetrieverEngineType string
 
@@ -76,6 +78,8 @@ type IndexWithScore struct {
 	KnowledgeBaseID string
 	// Tag ID
 	TagID string
+	// Chunk type (empty for indices created before chunk type was stored)
+	ChunkType string
 	// Score
 	Score float64
 	// Match type
@@ -89,6 +93,36 @@ func (i *IndexWithScore) GetScore() float64 {
 	return i.Score
 }
 
+// RetrievalWeightKey returns the chunk type weight key of the hit.
+// Hits on generated questions use RetrievalWeightKeyQuestion, while the other entries indexed for a
+// chunk (split parts, link anchors) weigh as the chunk itself; indices created before chunk type was
+// stored are treated as text.
+func (i *IndexWithScore) RetrievalWeightKey() string {
+	chunkType := i.ChunkType
+	if chunkType == "" {
+		chunkType = ChunkTypeText
+	}
+	if chunkType == ChunkTypeText && isGeneratedQuestionSourceID(i.SourceID, i.ChunkID) {
+		return RetrievalWeightKeyQuestion
+	}
+	return chunkType
+}
+
+// isGeneratedQuestionSourceID reports whether sourceID is the source ID of a question generated for
+// the chunk, "{chunk_id}-q{n}" with n the numeric part of the question ID
+func isGeneratedQuestionSourceID(sourceID, chunkID string) bool {
+	n, ok := strings.CutPrefix(sourceID, chunkID+"-q")
+	if !ok || n == "" {
+		return false
+	}
+	for _, r := range n {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // RetrieveResult represents the result of retrieval
 type RetrieveResult struct {
 	Results             []*IndexWithScore   // Retrieval results
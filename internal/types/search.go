@@ -126,6 +126,30 @@ type SearchResult struct {
 	// MatchedContent is the actual content that was matched in vector search
 	// For FAQ: this is the matched question text (standard or similar question)
 	MatchedContent string `json:"matched_content,omitempty"`
+
+	// MatchedSourceID is the SourceID of the index entry that produced the match
+	// For FAQ with separate question indexing: chunkID for the standard question,
+	// chunkID-i for the i-th similar question
+	MatchedSourceID string `json:"matched_source_id,omitempty"`
+
+	// Images is the parsed ImageInfo (URL, caption, OCR text) of the chunk.
+	// Only populated when the caller opts in via include_images.
+	Images []ImageInfo `json:"images,omitempty"`
+}
+
+// AttachImages parses ImageInfo of each result into Images.
+// Results with empty or malformed ImageInfo are left without images.
+func AttachImages(results []*SearchResult) {
+	for _, result := range results {
+		if result == nil || result.ImageInfo == "" {
+			continue
+		}
+		var images []ImageInfo
+		if err := json.Unmarshal([]byte(result.ImageInfo), &images); err != nil || len(images) == 0 {
+			continue
+		}
+		result.Images = images
+	}
 }
 
 // SearchParams represents the search parameters
@@ -139,6 +163,27 @@ type SearchParams struct {
 	KnowledgeIDs         []string `json:"knowledge_ids"`
 	TagIDs               []string `json:"tag_ids"` // Tag IDs for filtering (used for FAQ priority filtering)
 	OnlyRecommended      bool     `json:"only_recommended"`
+	// RetrieveTopK is the number of candidates each retriever fetches before merging and
+	// truncating to MatchCount. Defaults to MatchCount * DefaultRetrieveTopKMultiplier, the larger
+	// of this value and the knowledge base RetrievalConfig.RetrieveTopK is used.
+	RetrieveTopK int `json:"retrieve_top_k"`
+	// IncludeImages attaches the parsed image info (URL, caption, OCR) of matched chunks to each result
+	IncludeImages bool `json:"include_images"`
+}
+
+const (
+	// DefaultRetrieveTopKMultiplier is the default ratio between the per-retriever TopK and MatchCount
+	DefaultRetrieveTopKMultiplier = 3
+	// MaxRetrieveTopK caps the per-retriever TopK set by configuration
+	MaxRetrieveTopK = 1000
+)
+
+// EffectiveRetrieveTopK returns the per-retriever TopK of a search: MatchCount *
+// DefaultRetrieveTopKMultiplier widened by RetrieveTopK and the knowledge base configured value
+func (p SearchParams) EffectiveRetrieveTopK(kbRetrieveTopK int) int {
+	topK := p.MatchCount * DefaultRetrieveTopKMultiplier
+	configured := min(max(p.RetrieveTopK, kbRetrieveTopK), MaxRetrieveTopK)
+	return max(topK, configured)
 }
 
 // Value implements the driver.Valuer interface, used to convert SearchResult to database value
@@ -3,6 +3,7 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"os"
 	"strings"
 	"time"
@@ -85,6 +86,12 @@ type Tenant struct {
 	// Deprecated: ConversationConfig is deprecated, use CustomAgent (builtin-quick-answer) config instead.
 	// This field is kept for backward compatibility and will be removed in future versions.
 	ConversationConfig *ConversationConfig `yaml:"conversation_config" json:"conversation_config" gorm:"type:jsonb"`
+	// Document summary generation configuration for this tenant
+	SummaryConfig *DocumentSummaryConfig `yaml:"summary_config"      json:"summary_config"      gorm:"type:jsonb"`
+	// Named chunking presets that can be applied when creating knowledge bases or documents
+	ChunkingPresets ChunkingPresets `yaml:"chunking_presets"    json:"chunking_presets"    gorm:"type:jsonb"`
+	// Privacy options controlling which knowledge attributes are exposed in API responses
+	PrivacyConfig *TenantPrivacyConfig `yaml:"privacy_config"      json:"privacy_config"      gorm:"type:jsonb"`
 	// Creation time
 	CreatedAt time.Time `yaml:"created_at"          json:"created_at"`
 	// Last updated time
@@ -185,3 +192,199 @@ func (c *ConversationConfig) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(b, c)
 }
+
+// DocumentSummaryConfig represents the document summary generation configuration for a tenant
+type DocumentSummaryConfig struct {
+	// MetadataIntroTemplate is prepended to the document content before summarizing.
+	// Supports {{file_type}}, {{file_name}} and {{knowledge_type}} placeholders.
+	// Empty means the built-in Chinese intro is used.
+	MetadataIntroTemplate string `json:"metadata_intro_template"`
+	// DisableMetadataIntro summarizes the raw document content without any metadata intro
+	DisableMetadataIntro bool `json:"disable_metadata_intro"`
+	// FallbackMode decides what happens when the summary model fails, see SummaryFallback*.
+	// Empty means SummaryFallbackFail.
+	FallbackMode string `json:"fallback_mode"`
+}
+
+// Summary fallback modes used when the summary model fails to generate a summary
+const (
+	// SummaryFallbackFail leaves the description untouched and marks the summary as failed
+	SummaryFallbackFail = "fail"
+	// SummaryFallbackRetry retries the summary task later, marking it failed after the last retry
+	SummaryFallbackRetry = "retry"
+	// SummaryFallbackTruncate stores the beginning of the first chunk as the description (opt-in)
+	SummaryFallbackTruncate = "truncate"
+)
+
+// GetFallbackMode returns the configured summary fallback mode, defaulting to SummaryFallbackFail
+func (c *DocumentSummaryConfig) GetFallbackMode() string {
+	if c == nil || c.FallbackMode == "" {
+		return SummaryFallbackFail
+	}
+	return c.FallbackMode
+}
+
+// IsValidSummaryFallbackMode reports whether mode is empty or one of the SummaryFallback* values
+func IsValidSummaryFallbackMode(mode string) bool {
+	switch mode {
+	case "", SummaryFallbackFail, SummaryFallbackRetry, SummaryFallbackTruncate:
+		return true
+	}
+	return false
+}
+
+// Value implements the driver.Valuer interface, used to convert DocumentSummaryConfig to database value
+func (c *DocumentSummaryConfig) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to DocumentSummaryConfig
+func (c *DocumentSummaryConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// TenantPrivacyConfig represents the privacy options of a tenant
+type TenantPrivacyConfig struct {
+	// HideFileHash keeps the file checksum of knowledge out of API responses.
+	// Filtering the knowledge list by checksum keeps working.
+	HideFileHash bool `json:"hide_file_hash"`
+}
+
+// HidesFileHash reports whether the file checksum of knowledge should be left out of responses
+func (c *TenantPrivacyConfig) HidesFileHash() bool {
+	return c != nil && c.HideFileHash
+}
+
+// Value implements the driver.Valuer interface, used to convert TenantPrivacyConfig to database value
+func (c *TenantPrivacyConfig) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to TenantPrivacyConfig
+func (c *TenantPrivacyConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// MaxChunkingPresetNameLength is the maximum length of a chunking preset name
+const MaxChunkingPresetNameLength = 64
+
+// ChunkingPreset is a named set of chunking parameters a tenant defines once (e.g. "code", "prose")
+// and reuses when creating knowledge bases or uploading documents
+type ChunkingPreset struct {
+	Name         string   `json:"name"`
+	ChunkSize    int      `json:"chunk_size"`
+	ChunkOverlap int      `json:"chunk_overlap"`
+	Separators   []string `json:"separators"`
+}
+
+// ApplyDefaults fills the chunk size, overlap and separators left unset in cfg from the preset,
+// so explicitly provided values keep precedence over the preset
+func (p *ChunkingPreset) ApplyDefaults(cfg *ChunkingConfig) {
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = p.ChunkSize
+	}
+	if cfg.ChunkOverlap == 0 {
+		cfg.ChunkOverlap = p.ChunkOverlap
+	}
+	if len(cfg.Separators) == 0 {
+		cfg.Separators = p.Separators
+	}
+}
+
+// Override replaces the chunk size, overlap and separators of cfg with the preset values
+func (p *ChunkingPreset) Override(cfg *ChunkingConfig) {
+	cfg.ChunkSize = p.ChunkSize
+	cfg.ChunkOverlap = p.ChunkOverlap
+	if len(p.Separators) > 0 {
+		cfg.Separators = p.Separators
+	}
+}
+
+// ChunkingPresets is the list of chunking presets of a tenant
+type ChunkingPresets []ChunkingPreset
+
+// Find returns the preset with the given name, or nil if it does not exist
+func (p ChunkingPresets) Find(name string) *ChunkingPreset {
+	for i := range p {
+		if p[i].Name == name {
+			return &p[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks that preset names are unique and the chunking parameters are usable
+func (p ChunkingPresets) Validate() error {
+	seen := make(map[string]struct{}, len(p))
+	for _, preset := range p {
+		name := strings.TrimSpace(preset.Name)
+		if name == "" {
+			return fmt.Errorf("preset name cannot be empty")
+		}
+		if name != preset.Name || len([]rune(name)) > MaxChunkingPresetNameLength {
+			return fmt.Errorf("invalid preset name %q", preset.Name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate preset name %q", name)
+		}
+		seen[name] = struct{}{}
+		if preset.ChunkSize <= 0 {
+			return fmt.Errorf("preset %q: chunk_size must be positive", name)
+		}
+		if preset.ChunkOverlap < 0 || preset.ChunkOverlap >= preset.ChunkSize {
+			return fmt.Errorf("preset %q: chunk_overlap must be between 0 and chunk_size", name)
+		}
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface, used to convert ChunkingPresets to database value
+func (p ChunkingPresets) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to ChunkingPresets
+func (p *ChunkingPresets) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, p)
+}
+
+// TenantStorageReconcileResult is the result of recomputing a tenant's storage used
+type TenantStorageReconcileResult struct {
+	TenantID uint64 `json:"tenant_id"`
+	// Previous 校准前记录的存储用量（字节）
+	Previous int64 `json:"previous_storage_used"`
+	// Actual 按知识实际大小汇总的存储用量（字节）
+	Actual int64 `json:"storage_used"`
+	// Delta 校准修正量，Actual - Previous
+	Delta int64 `json:"delta"`
+}
@@ -0,0 +1,14 @@
+-- Remove retrieval_config column from knowledge_bases table
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS retrieval_config;
+
+-- Remove chunk_type column from embeddings table
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns 
+        WHERE table_name = 'embeddings' AND column_name = 'chunk_type'
+    ) THEN
+        ALTER TABLE embeddings DROP COLUMN chunk_type;
+        RAISE NOTICE '[Migration 000015 Rollback] Removed chunk_type column from embeddings table';
+    END IF;
+END $$;
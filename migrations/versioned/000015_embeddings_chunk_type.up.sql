@@ -0,0 +1,20 @@
+-- Add chunk_type column to embeddings table for chunk-type-aware retrieval weighting
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'embeddings') THEN
+        IF NOT EXISTS (
+            SELECT 1 FROM information_schema.columns 
+            WHERE table_name = 'embeddings' AND column_name = 'chunk_type'
+        ) THEN
+            ALTER TABLE embeddings ADD COLUMN chunk_type VARCHAR(32);
+            RAISE NOTICE '[Migration 000015] Added chunk_type column to embeddings table';
+        ELSE
+            RAISE NOTICE '[Migration 000015] chunk_type column already exists in embeddings table, skipping';
+        END IF;
+    ELSE
+        RAISE NOTICE '[Migration 000015] embeddings table does not exist, skipping';
+    END IF;
+END $$;
+
+-- Add retrieval_config column to knowledge_bases table
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS retrieval_config JSONB NULL;
@@ -0,0 +1,2 @@
+-- Remove parse_warning column from knowledges table
+ALTER TABLE knowledges DROP COLUMN IF EXISTS parse_warning;
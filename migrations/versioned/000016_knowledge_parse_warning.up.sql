@@ -0,0 +1,13 @@
+-- Add parse_warning column to knowledges table for documents that were only partially parsed
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'knowledges' AND column_name = 'parse_warning'
+    ) THEN
+        ALTER TABLE knowledges ADD COLUMN parse_warning TEXT NULL;
+        RAISE NOTICE '[Migration 000016] Added parse_warning column to knowledges table';
+    ELSE
+        RAISE NOTICE '[Migration 000016] parse_warning column already exists in knowledges table, skipping';
+    END IF;
+END $$;
@@ -0,0 +1,2 @@
+-- Remove summary_config column from tenants table
+ALTER TABLE tenants DROP COLUMN IF EXISTS summary_config;
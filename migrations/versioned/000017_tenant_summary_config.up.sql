@@ -0,0 +1,13 @@
+-- Add summary_config column to tenants table for configurable document summary generation
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'tenants' AND column_name = 'summary_config'
+    ) THEN
+        ALTER TABLE tenants ADD COLUMN summary_config JSONB DEFAULT NULL;
+        RAISE NOTICE '[Migration 000017] Added summary_config column to tenants table';
+    ELSE
+        RAISE NOTICE '[Migration 000017] summary_config column already exists in tenants table, skipping';
+    END IF;
+END $$;
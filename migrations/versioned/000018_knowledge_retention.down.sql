@@ -0,0 +1,6 @@
+-- Remove retention columns
+DROP INDEX IF EXISTS idx_knowledges_expires_at;
+ALTER TABLE knowledges DROP COLUMN IF EXISTS expiry_notified_at;
+ALTER TABLE knowledges DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS retention_config;
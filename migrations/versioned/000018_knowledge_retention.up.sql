@@ -0,0 +1,6 @@
+-- Add retention columns: per-knowledge expiration time and per-knowledge-base retention config
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE NULL;
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS expiry_notified_at TIMESTAMP WITH TIME ZONE NULL;
+CREATE INDEX IF NOT EXISTS idx_knowledges_expires_at ON knowledges(expires_at) WHERE expires_at IS NOT NULL;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS retention_config JSONB NULL;
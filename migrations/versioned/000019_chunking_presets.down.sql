@@ -0,0 +1,3 @@
+-- Remove chunking presets columns
+ALTER TABLE knowledges DROP COLUMN IF EXISTS chunking_preset;
+ALTER TABLE tenants DROP COLUMN IF EXISTS chunking_presets;
@@ -0,0 +1,3 @@
+-- Add tenant-level chunking presets and the preset a knowledge was uploaded with
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS chunking_presets JSONB DEFAULT NULL;
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS chunking_preset VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+-- Remove periodic source refresh columns
+DROP INDEX IF EXISTS idx_knowledges_next_refresh_at;
+ALTER TABLE knowledges DROP COLUMN IF EXISTS source_last_modified;
+ALTER TABLE knowledges DROP COLUMN IF EXISTS source_etag;
+ALTER TABLE knowledges DROP COLUMN IF EXISTS next_refresh_at;
+ALTER TABLE knowledges DROP COLUMN IF EXISTS refresh_interval_minutes;
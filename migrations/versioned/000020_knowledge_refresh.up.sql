@@ -0,0 +1,6 @@
+-- Add periodic source refresh schedule and conditional request validators for url/file_url knowledge
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS refresh_interval_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS next_refresh_at TIMESTAMP WITH TIME ZONE NULL;
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS source_etag TEXT NOT NULL DEFAULT '';
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS source_last_modified TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_knowledges_next_refresh_at ON knowledges(next_refresh_at) WHERE next_refresh_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Remove storage path of the full parsed markdown
+ALTER TABLE knowledges DROP COLUMN IF EXISTS parsed_markdown_path;
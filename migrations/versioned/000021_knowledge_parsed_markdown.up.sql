@@ -0,0 +1,2 @@
+-- Add storage path of the full parsed markdown of a knowledge
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS parsed_markdown_path TEXT NOT NULL DEFAULT '';
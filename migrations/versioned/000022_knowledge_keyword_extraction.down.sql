@@ -0,0 +1,2 @@
+-- Remove document keyword/entity extraction config
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS keyword_extraction_config;
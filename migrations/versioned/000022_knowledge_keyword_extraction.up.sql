@@ -0,0 +1,2 @@
+-- Add per-knowledge-base document keyword/entity extraction config
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS keyword_extraction_config JSONB NULL;
@@ -0,0 +1,2 @@
+-- Remove tenant-level privacy config
+ALTER TABLE tenants DROP COLUMN IF EXISTS privacy_config;
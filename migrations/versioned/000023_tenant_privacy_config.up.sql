@@ -0,0 +1,2 @@
+-- Add tenant-level privacy config (e.g. hiding knowledge file checksums in API responses)
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS privacy_config JSONB DEFAULT NULL;
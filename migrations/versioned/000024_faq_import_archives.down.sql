@@ -0,0 +1,2 @@
+-- Remove FAQ import archives
+DROP TABLE IF EXISTS faq_import_archives;
@@ -0,0 +1,13 @@
+-- Keep the final progress of FAQ imports after the Redis copy expires, for post-mortem analysis
+CREATE TABLE IF NOT EXISTS faq_import_archives (
+    task_id VARCHAR(255) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    knowledge_base_id VARCHAR(36) NOT NULL DEFAULT '',
+    knowledge_id VARCHAR(36) NOT NULL DEFAULT '',
+    status VARCHAR(32) NOT NULL DEFAULT '',
+    progress JSONB NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NULL
+);
+CREATE INDEX IF NOT EXISTS idx_faq_import_archives_tenant_id ON faq_import_archives(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_faq_import_archives_expires_at ON faq_import_archives(expires_at) WHERE expires_at IS NOT NULL;
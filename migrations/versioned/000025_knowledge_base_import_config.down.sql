@@ -0,0 +1,2 @@
+-- Remove knowledge base import config
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS import_config;
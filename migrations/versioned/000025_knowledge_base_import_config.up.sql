@@ -0,0 +1,2 @@
+-- Add per-knowledge-base import config, e.g. the max file size of URL imports
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS import_config JSONB NULL;
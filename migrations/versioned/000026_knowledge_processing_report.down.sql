@@ -0,0 +1,2 @@
+-- Remove processing_report column from knowledges table
+ALTER TABLE knowledges DROP COLUMN IF EXISTS processing_report;
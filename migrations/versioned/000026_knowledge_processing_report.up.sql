@@ -0,0 +1,2 @@
+-- Add processing_report column to knowledges table for per-document chunking diagnostics
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS processing_report JSONB NULL;
@@ -0,0 +1,2 @@
+-- No-op: task IDs longer than 36 characters cannot be narrowed back
+SELECT 1;
@@ -0,0 +1,2 @@
+-- Task IDs from GenerateTaskID are longer than 36 characters; widen the column for databases created by 000024
+ALTER TABLE faq_import_archives ALTER COLUMN task_id TYPE VARCHAR(255);